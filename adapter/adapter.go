@@ -0,0 +1,195 @@
+// Package adapter provides thin wrappers exposing the caches of the lru package
+// through the method sets of widely used cache libraries, so they can be dropped
+// into projects already coded against those abstractions.
+//
+// The adapters mirror the shape of the original interfaces without importing them,
+// keeping this module free of their dependencies.
+package adapter
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"caching/lru"
+)
+
+// ErrNotFound is returned by the context-aware adapters when a key is not in the cache.
+var ErrNotFound = errors.New("adapter: key not found")
+
+// keyString converts the keys of the adapted APIs, which accept any comparable value, to cache keys.
+// Strings are used as is, other keys must implement fmt.Stringer.
+func keyString(key any) (string, error) {
+	switch k := key.(type) {
+	case string:
+		return k, nil
+	case interface{ String() string }:
+		return k.String(), nil
+	default:
+		return "", errors.New("adapter: key must be a string or implement fmt.Stringer")
+	}
+}
+
+// Ristretto adapts a cache to the method set of dgraph-io/ristretto.
+// Costs are ignored since the caches are bounded by item count, and writes are applied
+// immediately so Wait is a no-op.
+type Ristretto struct {
+	cache lru.Cache
+}
+
+// NewRistretto adapts the cache to the method set of ristretto.
+func NewRistretto(cache lru.Cache) *Ristretto {
+	return &Ristretto{cache: cache}
+}
+
+// Get returns the value of the key and whether it was found.
+func (r *Ristretto) Get(key string) (any, bool) {
+	return r.cache.Get(key)
+}
+
+// Set adds or updates the key without expiration. Like ristretto, it returns false if the item was dropped:
+// refused by the cache, disabled, or throttled by the eviction rate limit.
+func (r *Ristretto) Set(key string, value any, cost int64) bool {
+	return stored(r.cache.Set(key, value))
+}
+
+// SetWithTTL adds or updates the key expiring after ttl. As with ristretto, a ttl of zero or less means
+// the item does not expire. It returns false if the item was dropped, like Set.
+func (r *Ristretto) SetWithTTL(key string, value any, cost int64, ttl time.Duration) bool {
+	if ttl <= 0 {
+		return r.Set(key, value, cost)
+	}
+	return stored(r.cache.SetWithTTL(key, value, ttl))
+}
+
+// stored returns whether the write kept the item in the cache, or queued it.
+func stored(result lru.SetResult) bool {
+	switch result.Status {
+	case lru.SetRejected, lru.SetDenied, lru.SetDisabled, lru.SetThrottled, lru.SetExpired:
+		return false
+	}
+	return true
+}
+
+// Del removes the key from the cache.
+func (r *Ristretto) Del(key string) {
+	r.cache.Remove(key)
+}
+
+// Wait blocks until the pending writes are applied. Writes are synchronous, so it returns immediately.
+func (r *Ristretto) Wait() {}
+
+// Store adapts a cache to the context-aware, error-returning style of eko/gocache stores.
+type Store struct {
+	cache lru.Cache
+}
+
+func NewStore(cache lru.Cache) *Store {
+	return &Store{cache: cache}
+}
+
+// StoreOption configures a Set on a Store.
+type StoreOption func(*storeOptions)
+
+type storeOptions struct {
+	expiration time.Duration // Zero means the item does not expire
+}
+
+// WithExpiration sets the TTL of the item being stored.
+func WithExpiration(ttl time.Duration) StoreOption {
+	return func(o *storeOptions) {
+		o.expiration = ttl
+	}
+}
+
+// Get returns the value of the key, or ErrNotFound.
+func (s *Store) Get(ctx context.Context, key any) (any, error) {
+	k, err := keyString(key)
+	if err != nil {
+		return nil, err
+	}
+	if value, found := s.cache.Get(k); found {
+		return value, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Set adds or updates the key, expiring it if WithExpiration is given.
+func (s *Store) Set(ctx context.Context, key any, value any, opts ...StoreOption) error {
+	k, err := keyString(key)
+	if err != nil {
+		return err
+	}
+
+	o := storeOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.expiration > 0 {
+		s.cache.SetWithTTL(k, value, o.expiration)
+	} else {
+		s.cache.Set(k, value)
+	}
+	return nil
+}
+
+// Delete removes the key from the cache.
+func (s *Store) Delete(ctx context.Context, key any) error {
+	k, err := keyString(key)
+	if err != nil {
+		return err
+	}
+	s.cache.Remove(k)
+	return nil
+}
+
+// GetType returns the type of the store.
+func (s *Store) GetType() string {
+	return "lru"
+}
+
+// Getter is the read side of groupcache-style byte caches.
+type Getter interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// Setter is the write side of groupcache-style byte caches.
+type Setter interface {
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Bytes adapts a cache to the Getter and Setter interfaces.
+// Values are copied in and out, so callers can't mutate the cached bytes.
+type Bytes struct {
+	cache lru.Cache
+}
+
+var (
+	_ Getter = (*Bytes)(nil) // Ensure Bytes implements the Getter interface
+	_ Setter = (*Bytes)(nil) // Ensure Bytes implements the Setter interface
+)
+
+func NewBytes(cache lru.Cache) *Bytes {
+	return &Bytes{cache: cache}
+}
+
+// Get returns a copy of the bytes of the key, or ErrNotFound.
+func (b *Bytes) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, found := b.cache.Get(key); found {
+		if data, ok := value.([]byte); ok {
+			return append([]byte(nil), data...), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Set stores a copy of the bytes under the key, expiring after ttl when it is positive.
+func (b *Bytes) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	data := append([]byte(nil), value...)
+	if ttl > 0 {
+		b.cache.SetWithTTL(key, data, ttl)
+	} else {
+		b.cache.Set(key, data)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"caching/lru"
+)
+
+type stringerKey int
+
+func (k stringerKey) String() string {
+	return "key"
+}
+
+func TestRistretto(t *testing.T) {
+	cache := NewRistretto(lru.NewSafeLRUCache(5))
+	assert.True(t, cache.Set("key1", "value1", 1))
+	assert.True(t, cache.SetWithTTL("key2", "value2", 1, 0), "Expected a ttl of zero to never expire, like ristretto")
+	assert.True(t, cache.SetWithTTL("key3", "value3", 1, time.Minute))
+	cache.Wait()
+	_, found := cache.Get("key2")
+	assert.True(t, found)
+
+	value, found := cache.Get("key1")
+	assert.True(t, found)
+	assert.Equal(t, "value1", value)
+
+	cache.Del("key1")
+	_, found = cache.Get("key1")
+	assert.False(t, found)
+
+	disabled := NewRistretto(lru.NewSafeLRUCache(0))
+	assert.False(t, disabled.Set("key1", "value1", 1), "Expected a dropped item to be reported")
+	assert.False(t, disabled.SetWithTTL("key1", "value1", 1, time.Minute))
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(lru.NewSafeLRUCache(5))
+
+	assert.NoError(t, store.Set(ctx, "key1", "value1", WithExpiration(time.Minute)))
+	value, err := store.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, "value1", value)
+
+	assert.NoError(t, store.Set(ctx, stringerKey(1), "value2"))
+	value, err = store.Get(ctx, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "value2", value)
+
+	assert.NoError(t, store.Delete(ctx, "key1"))
+	_, err = store.Get(ctx, "key1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.Error(t, store.Set(ctx, 42, "value3"))
+}
+
+func TestBytes(t *testing.T) {
+	ctx := context.Background()
+	cache := NewBytes(lru.NewSafeLRUCache(5))
+
+	data := []byte("value1")
+	assert.NoError(t, cache.Set(ctx, "key1", data, 0))
+	data[0] = 'X' // Mutating the original must not change the cached value
+
+	value, err := cache.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+
+	_, err = cache.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,102 @@
+// Package admin lets an application register its caches by name, to inspect them over HTTP:
+// their stats, their items and their keys, e.g. from the visualizer attached to a production service.
+//
+//	admin.Register("sessions", sessions)
+//	mux.Handle("/admin/", http.StripPrefix("/admin", admin.Handler()))
+//
+// Any lru.Cache can be registered. The endpoints a cache does not support, e.g. listing the items
+// of a cache that is not an lru.Snapshotter, answer 501 Not Implemented.
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"caching/lru"
+)
+
+var (
+	// ErrInvalidName is returned when registering a cache with an empty name or a name containing a slash.
+	ErrInvalidName = errors.New("admin: invalid cache name")
+	// ErrDuplicateName is returned when registering a cache under the name of another registered cache.
+	ErrDuplicateName = errors.New("admin: cache name already registered")
+)
+
+// Registry holds the caches exposed by a Handler, by name. It is thread-safe.
+type Registry struct {
+	mutex  sync.RWMutex
+	caches map[string]lru.Cache
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{caches: make(map[string]lru.Cache)}
+}
+
+// DefaultRegistry is the Registry of the package level functions.
+var DefaultRegistry = NewRegistry()
+
+// Register exposes the cache under the name, which must be unique and not contain a slash as it is part of the URLs.
+func (registry *Registry) Register(name string, cache lru.Cache) error {
+	if name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if _, found := registry.caches[name]; found {
+		return fmt.Errorf("%w: %q", ErrDuplicateName, name)
+	}
+	registry.caches[name] = cache
+	return nil
+}
+
+// Unregister stops exposing the cache registered under the name, if any, e.g. before closing it.
+func (registry *Registry) Unregister(name string) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	delete(registry.caches, name)
+}
+
+// Lookup returns the cache registered under the name.
+func (registry *Registry) Lookup(name string) (lru.Cache, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	cache, found := registry.caches[name]
+	return cache, found
+}
+
+// Names returns the names of the registered caches, sorted.
+func (registry *Registry) Names() []string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	names := make([]string, 0, len(registry.caches))
+	for name := range registry.caches {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Register exposes the cache under the name in the DefaultRegistry.
+func Register(name string, cache lru.Cache) error {
+	return DefaultRegistry.Register(name, cache)
+}
+
+// Unregister stops exposing the cache registered under the name in the DefaultRegistry.
+func Unregister(name string) {
+	DefaultRegistry.Unregister(name)
+}
+
+// Handler returns the Handler of the DefaultRegistry, configured by opts.
+func Handler(opts ...Option) http.Handler {
+	return NewHandler(DefaultRegistry, opts...)
+}
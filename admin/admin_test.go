@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"caching/lru"
+)
+
+// get serves a GET request of the path with the handler of the registry, decoding the JSON response into result.
+func get(t *testing.T, registry *Registry, path string, result any) int {
+	t.Helper()
+	response := httptest.NewRecorder()
+	NewHandler(registry).ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(result))
+	return response.Code
+}
+
+func TestRegister(t *testing.T) {
+	registry := NewRegistry()
+	assert.NoError(t, registry.Register("sessions", lru.NewSafeLRUCache(5)))
+	assert.NoError(t, registry.Register("users", lru.NewSafeLRUCache(5)))
+
+	assert.ErrorIs(t, registry.Register("sessions", lru.NewSafeLRUCache(5)), ErrDuplicateName)
+	assert.ErrorIs(t, registry.Register("", lru.NewSafeLRUCache(5)), ErrInvalidName)
+	assert.ErrorIs(t, registry.Register("a/b", lru.NewSafeLRUCache(5)), ErrInvalidName)
+	assert.Equal(t, []string{"sessions", "users"}, registry.Names())
+
+	registry.Unregister("sessions")
+	_, found := registry.Lookup("sessions")
+	assert.False(t, found)
+	assert.Equal(t, []string{"users"}, registry.Names())
+}
+
+func TestHandlerListsCaches(t *testing.T) {
+	registry := NewRegistry()
+	sessions := lru.NewSafeLRUCache(5)
+	sessions.Set("a", 1)
+	registry.Register("sessions", sessions)
+	registry.Register("hashed", lru.NewHashedKeys(lru.NewSafeLRUCache(3), lru.SHA256Keys([]byte("secret"), 8)))
+
+	var summaries []cacheSummary
+	assert.Equal(t, http.StatusOK, get(t, registry, "/caches", &summaries))
+	assert.Equal(t, []cacheSummary{
+		{Name: "hashed", Len: 0, Capacity: 3, Capabilities: []string{}},
+		{Name: "sessions", Len: 1, Capacity: 5, Capabilities: []string{"stats", "items", "keys"}},
+	}, summaries)
+}
+
+func TestHandlerStats(t *testing.T) {
+	registry := NewRegistry()
+	cache := lru.NewSafeLRUCache(5)
+	cache.Set("a", 1)
+	cache.Get("a")
+	cache.Get("b")
+	registry.Register("sessions", cache)
+
+	var stats statsResponse
+	assert.Equal(t, http.StatusOK, get(t, registry, "/caches/sessions", &stats))
+	assert.Equal(t, "sessions", stats.Name)
+	assert.Equal(t, 1, stats.Len)
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, 0.5, stats.HitRatio)
+
+	var failure errorResponse
+	assert.Equal(t, http.StatusNotFound, get(t, registry, "/caches/unknown", &failure))
+	assert.Equal(t, "not_found", failure.Code)
+}
+
+func TestHandlerItems(t *testing.T) {
+	registry := NewRegistry()
+	cache := lru.NewSafeLRUCache(5)
+	for _, key := range []string{"user:1", "user:2", "order:1", "user:3"} {
+		cache.Set(key, key+"-value")
+	}
+	registry.Register("sessions", cache)
+
+	var items itemsResponse
+	assert.Equal(t, http.StatusOK, get(t, registry, "/caches/sessions/items?prefix=user:&offset=1&limit=1", &items))
+	assert.Equal(t, 3, items.Total)
+	assert.Equal(t, []item{{Key: "user:2", Value: "user:2-value"}}, items.Items, "The items should be in eviction order")
+
+	var withoutValues itemsResponse
+	assert.Equal(t, http.StatusOK, get(t, registry, "/caches/sessions/items?values=false&limit=1", &withoutValues))
+	assert.Equal(t, []item{{Key: "user:3"}}, withoutValues.Items)
+
+	var failure errorResponse
+	assert.Equal(t, http.StatusBadRequest, get(t, registry, "/caches/sessions/items?limit=5000", &failure))
+	assert.Equal(t, "invalid_parameter", failure.Code)
+}
+
+func TestHandlerItemsRenderer(t *testing.T) {
+	registry := NewRegistry()
+	cache := lru.NewSafeLRUCache(5)
+	cache.SetWithTTL("token:1", "secret", time.Minute)
+	registry.Register("sessions", cache)
+	renderer := lru.Redact(nil, func(key string) bool { return strings.HasPrefix(key, "token:") })
+
+	response := httptest.NewRecorder()
+	NewHandler(registry, WithValueRenderer(renderer)).ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/caches/sessions/items", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"value":"[redacted]"`)
+	assert.Contains(t, response.Body.String(), `"expiresAt":`)
+	assert.NotContains(t, response.Body.String(), "secret")
+}
+
+func TestHandlerKeys(t *testing.T) {
+	registry := NewRegistry()
+	cache := lru.NewSafeLRUCache(5)
+	for _, key := range []string{"user:1", "user:2", "order:1"} {
+		cache.Set(key, 1)
+	}
+	registry.Register("sessions", cache)
+	registry.Register("hashed", lru.NewHashedKeys(lru.NewSafeLRUCache(3), lru.SHA256Keys([]byte("secret"), 8)))
+
+	var keys keysResponse
+	assert.Equal(t, http.StatusOK, get(t, registry, "/caches/sessions/keys?prefix=user:", &keys))
+	assert.ElementsMatch(t, []string{"user:1", "user:2"}, keys.Keys)
+	assert.False(t, keys.Truncated)
+
+	assert.Equal(t, http.StatusOK, get(t, registry, "/caches/sessions/keys?limit=2", &keys))
+	assert.Len(t, keys.Keys, 2)
+	assert.True(t, keys.Truncated)
+
+	var failure errorResponse
+	assert.Equal(t, http.StatusNotImplemented, get(t, registry, "/caches/hashed/keys", &failure))
+	assert.Equal(t, "not_implemented", failure.Code)
+}
@@ -0,0 +1,252 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"caching/lru"
+)
+
+const (
+	defaultPageSize = 100  // Items or keys returned when the request sets no limit
+	maxPageSize     = 1000 // Items or keys returned at most, so a large cache is not dumped at once
+)
+
+// cacheSummary describes a registered cache in the response of GET /caches.
+type cacheSummary struct {
+	Name         string   `json:"name"`
+	Len          int      `json:"len"`
+	Capacity     int      `json:"capacity"`
+	Capabilities []string `json:"capabilities"` // The optional endpoints supported by the cache: stats, items and keys
+}
+
+// statsResponse is the response of GET /caches/{name}.
+type statsResponse struct {
+	Name string `json:"name"`
+	lru.Stats
+	HitRatio float64 `json:"hitRatio"`
+}
+
+// item is an item of the cache in the response of GET /caches/{name}/items.
+type item struct {
+	Key       string    `json:"key"`
+	Value     string    `json:"value,omitempty"`    // Rendered by WithValueRenderer, or formatted with %v, empty with values=false
+	ExpiresAt time.Time `json:"expiresAt,omitzero"` // Zero if the item does not expire
+}
+
+// itemsResponse is the response of GET /caches/{name}/items.
+type itemsResponse struct {
+	Total int    `json:"total"` // Number of items matching the prefix, across all pages
+	Items []item `json:"items"` // The page of items, in eviction order, the next candidate last
+}
+
+// keysResponse is the response of GET /caches/{name}/keys.
+type keysResponse struct {
+	Keys      []string `json:"keys"`
+	Truncated bool     `json:"truncated"` // Whether more keys match the prefix than the limit
+}
+
+// errorResponse is the body of the failed requests.
+type errorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// options holds the optional configuration of the handler of NewHandler.
+type options struct {
+	values lru.ValueRenderer // Renders the values of the items, nil to format them with %v
+}
+
+// Option configures the handler of NewHandler.
+type Option func(*options)
+
+// WithValueRenderer renders the values of GET /caches/{name}/items, e.g. with lru.Redact to hide the sensitive ones.
+// They are formatted with %v by default.
+func WithValueRenderer(renderer lru.ValueRenderer) Option {
+	return func(o *options) {
+		o.values = renderer
+	}
+}
+
+// NewHandler returns the handler exposing the caches of the registry:
+//   - GET /caches lists the registered caches with their length, capacity and capabilities
+//   - GET /caches/{name} returns the stats of the cache, only its length and capacity if it is not an lru.StatsReporter
+//   - GET /caches/{name}/items returns a page of the items of an lru.Snapshotter, filtered by the prefix parameter,
+//     paginated by offset and limit, without the values with values=false
+//   - GET /caches/{name}/keys returns the keys starting with the prefix parameter, up to limit,
+//     of an lru.KeyspaceScanner or an lru.Snapshotter
+func NewHandler(registry *Registry, opts ...Option) http.Handler {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	renderer := o.values
+	if renderer == nil {
+		renderer = func(_ string, value any) string { return fmt.Sprintf("%v", value) }
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /caches", listHandler(registry))
+	mux.HandleFunc("GET /caches/{name}", withCache(registry, statsHandler))
+	mux.HandleFunc("GET /caches/{name}/items", withCache(registry, itemsHandler(renderer)))
+	mux.HandleFunc("GET /caches/{name}/keys", withCache(registry, keysHandler))
+	return mux
+}
+
+// capabilities returns the optional endpoints supported by the cache.
+func capabilities(cache lru.Cache) []string {
+	capabilities := []string{}
+	if _, ok := cache.(lru.StatsReporter); ok {
+		capabilities = append(capabilities, "stats")
+	}
+	if _, ok := cache.(lru.Snapshotter); ok {
+		capabilities = append(capabilities, "items", "keys")
+	} else if _, ok := cache.(lru.KeyspaceScanner); ok {
+		capabilities = append(capabilities, "keys")
+	}
+	return capabilities
+}
+
+func listHandler(registry *Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		summaries := []cacheSummary{}
+		for _, name := range registry.Names() {
+			cache, found := registry.Lookup(name)
+			if !found { // Unregistered meanwhile
+				continue
+			}
+			summaries = append(summaries, cacheSummary{
+				Name:         name,
+				Len:          cache.Len(),
+				Capacity:     cache.Capacity(),
+				Capabilities: capabilities(cache),
+			})
+		}
+		writeJSON(w, http.StatusOK, summaries)
+	}
+}
+
+// withCache calls the handler with the cache named by the path, answering 404 if it is not registered.
+func withCache(registry *Registry, handler func(w http.ResponseWriter, r *http.Request, name string, cache lru.Cache)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		cache, found := registry.Lookup(name)
+		if !found {
+			writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("no cache is registered as %q", name))
+			return
+		}
+		handler(w, r, name, cache)
+	}
+}
+
+func statsHandler(w http.ResponseWriter, r *http.Request, name string, cache lru.Cache) {
+	stats := lru.Stats{Len: cache.Len(), Capacity: cache.Capacity()}
+	if reporter, ok := cache.(lru.StatsReporter); ok {
+		stats = reporter.Stats()
+	}
+	writeJSON(w, http.StatusOK, statsResponse{Name: name, Stats: stats, HitRatio: stats.HitRatio()})
+}
+
+// itemsHandler returns a page of the items of the cache, their values rendered by renderer.
+func itemsHandler(renderer lru.ValueRenderer) func(w http.ResponseWriter, r *http.Request, name string, cache lru.Cache) {
+	return func(w http.ResponseWriter, r *http.Request, name string, cache lru.Cache) {
+		snapshotter, ok := cache.(lru.Snapshotter)
+		if !ok {
+			notImplemented(w, name, "listing its items")
+			return
+		}
+		offset, ok := intParameter(w, r, "offset", 0, math.MaxInt)
+		if !ok {
+			return
+		}
+		limit, ok := intParameter(w, r, "limit", defaultPageSize, maxPageSize)
+		if !ok {
+			return
+		}
+		prefix, values := r.URL.Query().Get("prefix"), r.URL.Query().Get("values") != "false"
+
+		response := itemsResponse{Items: []item{}}
+		snapshotter.Snapshot().Range(func(entry lru.EntryInfo) bool {
+			if !strings.HasPrefix(entry.Key, prefix) {
+				return true
+			}
+			response.Total++
+			if response.Total > offset && len(response.Items) < limit {
+				page := item{Key: entry.Key, ExpiresAt: entry.ExpiresAt}
+				if values {
+					page.Value = renderer(entry.Key, entry.Value)
+				}
+				response.Items = append(response.Items, page)
+			}
+			return true
+		})
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+func keysHandler(w http.ResponseWriter, r *http.Request, name string, cache lru.Cache) {
+	limit, ok := intParameter(w, r, "limit", defaultPageSize, maxPageSize)
+	if !ok {
+		return
+	}
+	prefix := r.URL.Query().Get("prefix")
+
+	response := keysResponse{Keys: []string{}}
+	collect := func(key string) bool {
+		if len(response.Keys) == limit {
+			response.Truncated = true
+			return false
+		}
+		response.Keys = append(response.Keys, key)
+		return true
+	}
+	// Prefer the scanner, which only visits the matching keys with a key index
+	if scanner, ok := cache.(lru.KeyspaceScanner); ok {
+		scanner.ScanPrefix(prefix, func(key string, _ any) bool { return collect(key) })
+	} else if snapshotter, ok := cache.(lru.Snapshotter); ok {
+		snapshotter.Snapshot().Range(func(entry lru.EntryInfo) bool {
+			return !strings.HasPrefix(entry.Key, prefix) || collect(entry.Key)
+		})
+	} else {
+		notImplemented(w, name, "listing its keys")
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// intParameter parses the query parameter as an integer between 0 and max, answering 400 if it is not.
+func intParameter(w http.ResponseWriter, r *http.Request, name string, fallback int, max int) (int, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return fallback, true
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 || value > max {
+		message := fmt.Sprintf("%s must be an integer between 0 and %d", name, max)
+		if max == math.MaxInt {
+			message = name + " must be a non-negative integer"
+		}
+		writeError(w, http.StatusBadRequest, "invalid_parameter", message)
+		return 0, false
+	}
+	return value, true
+}
+
+func notImplemented(w http.ResponseWriter, name string, what string) {
+	writeError(w, http.StatusNotImplemented, "not_implemented", fmt.Sprintf("cache %q does not support %s", name, what))
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, errorResponse{Code: code, Message: message})
+}
@@ -0,0 +1,228 @@
+// Package audit records the writes to a cache in an audit log, for the compliance reviews of what was cached and when:
+// every set and removal, with its time, its key, its outcome and the actor who made it.
+//
+//	auditLog := audit.New(sink, audit.WithHashedKeys(secret))
+//	ctx = audit.ContextWithActor(ctx, user.Name)
+//	auditLog.Cache(ctx, sessions).Set("session:42", session)
+//
+// The writes are recorded when they are made through the view returned by Log.Cache, with the evictions they cause.
+// The expirations are not recorded, as they follow from the expiration recorded with the sets.
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"caching/lru"
+)
+
+const (
+	OpSet    = "set"    // An item was written, see Record.Reason for the outcome
+	OpRemove = "remove" // An item was removed, see Record.Reason for why
+)
+
+// Record is an entry of the audit log.
+type Record struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"op"`                  // OpSet or OpRemove
+	Key       string    `json:"key"`                 // The key, or its hash with WithHashedKeys
+	Reason    string    `json:"reason"`              // The lru.SetStatus of a set, "manual" or "evicted" for a removal
+	ExpiresAt time.Time `json:"expires_at,omitzero"` // Expiration of the item written, zero if it never expires or was not stored
+	Actor     string    `json:"actor,omitempty"`     // Who made the write, see ContextWithActor
+}
+
+// options holds the optional configuration of a Log.
+type options struct {
+	clock      lru.Clock // Source of the time of the records
+	hashKeys   bool      // Whether the keys are hashed
+	hashSecret []byte    // Key of the HMAC hashing the keys, nil for a plain SHA-256
+}
+
+// Option configures a Log at construction time.
+type Option func(*options)
+
+// WithHashedKeys records the hex encoded HMAC-SHA256 of the keys with the secret instead of the keys,
+// so the log does not disclose them while the writes to a given key can still be found.
+// A nil secret records a plain SHA-256, which is open to guessing the keys of a small keyspace.
+func WithHashedKeys(secret []byte) Option {
+	return func(o *options) {
+		o.hashKeys = true
+		o.hashSecret = secret
+	}
+}
+
+// WithClock sets the clock giving the time of the records. Defaults to the system clock.
+func WithClock(clock lru.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// Log writes the records of the audited writes to a Sink, one at a time and in order. It is thread-safe.
+type Log struct {
+	sink  Sink
+	opts  options
+	mutex sync.Mutex // Orders the writes to the sink
+	err   error      // First error writing to the sink
+}
+
+// New creates a Log writing its records to the sink.
+func New(sink Sink, opts ...Option) *Log {
+	o := options{clock: systemClock{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Log{sink: sink, opts: o}
+}
+
+// systemClock is the default clock, backed by time.Now.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// record writes a record about the key to the sink, keeping the first error.
+func (log *Log) record(operation, key, reason string, expiresAt time.Time, actor string) {
+	record := Record{
+		Time:      log.opts.clock.Now(),
+		Operation: operation,
+		Key:       log.hash(key),
+		Reason:    reason,
+		ExpiresAt: expiresAt,
+		Actor:     actor,
+	}
+
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	if err := log.sink.Write(record); err != nil && log.err == nil {
+		log.err = err
+	}
+}
+
+// hash returns the key as recorded in the log.
+func (log *Log) hash(key string) string {
+	if !log.opts.hashKeys {
+		return key
+	}
+	if log.opts.hashSecret == nil {
+		sum := sha256.Sum256([]byte(key))
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, log.opts.hashSecret)
+	mac.Write([]byte(key))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Err returns the first error met writing to the sink, nil if none.
+// The records are not retried, so an audit log that must be complete should check it.
+func (log *Log) Err() error {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	return log.err
+}
+
+// actorKey is the context key of the actor.
+type actorKey struct{}
+
+// ContextWithActor returns a copy of the context carrying the actor, e.g. the authenticated user of a request,
+// recorded with the writes made through the views created from the context.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by the context, empty if none.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// Cache returns a view of the cache recording the writes made through it, and the evictions they cause,
+// on behalf of the actor of the context. The view is cheap and meant to live as long as the context, e.g. a request.
+func (log *Log) Cache(ctx context.Context, cache lru.Cache) lru.Cache {
+	return &auditedCache{cache: cache, log: log, actor: ActorFromContext(ctx)}
+}
+
+// auditedCache is a view of a cache recording its writes on behalf of an actor.
+type auditedCache struct {
+	cache lru.Cache
+	log   *Log
+	actor string
+}
+
+var _ lru.Cache = (*auditedCache)(nil) // Ensure auditedCache implements the Cache interface
+
+// Get retrieves an item from the cache, reads are not recorded.
+func (audited *auditedCache) Get(key string) (any, bool) {
+	return audited.cache.Get(key)
+}
+
+// Set writes the item and records the write with its outcome and expiration, e.g. the one of lru.WithTTLPolicy.
+func (audited *auditedCache) Set(key string, value any) lru.SetResult {
+	result := audited.cache.Set(key, value)
+	audited.recordSet(key, result, audited.expiresAt(key, 0))
+	return result
+}
+
+// SetWithTTL writes the item with a ttl and records the write with its outcome and expiration.
+func (audited *auditedCache) SetWithTTL(key string, value any, ttl time.Duration) lru.SetResult {
+	result := audited.cache.SetWithTTL(key, value, ttl)
+	audited.recordSet(key, result, audited.expiresAt(key, ttl))
+	return result
+}
+
+// expiresAt returns the expiration of the item just written, read from the cache so it follows the clock of the cache
+// and the ttl of lru.WithTTLPolicy, zero if the item does not expire or was not stored. A cache that is neither an
+// lru.MetadataCache nor an lru.TTLReader cannot report it, so the ttl of the write is counted from the clock of the log.
+// A concurrent write to the key may be reported instead.
+func (audited *auditedCache) expiresAt(key string, ttl time.Duration) time.Time {
+	switch cache := audited.cache.(type) {
+	case lru.MetadataCache:
+		info, _ := cache.EntryInfo(key)
+		return info.ExpiresAt
+	case lru.TTLReader:
+		if remaining, found := cache.TTL(key); found && remaining > 0 {
+			return audited.log.opts.clock.Now().Add(remaining)
+		}
+		return time.Time{}
+	}
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return audited.log.opts.clock.Now().Add(ttl)
+}
+
+// recordSet records a write, then the eviction it caused, if any.
+func (audited *auditedCache) recordSet(key string, result lru.SetResult, expiresAt time.Time) {
+	audited.log.record(OpSet, key, string(result.Status), expiresAt, audited.actor)
+	if result.Evicted {
+		audited.log.record(OpRemove, result.EvictedKey, reasonEvicted, time.Time{}, audited.actor)
+	}
+}
+
+// Remove removes the item and records the removal, whether the key was in the cache or not.
+func (audited *auditedCache) Remove(key string) {
+	audited.cache.Remove(key)
+	audited.log.record(OpRemove, key, reasonManual, time.Time{}, audited.actor)
+}
+
+// Len returns the number of items in the cache.
+func (audited *auditedCache) Len() int {
+	return audited.cache.Len()
+}
+
+// Capacity returns the capacity of the cache.
+func (audited *auditedCache) Capacity() int {
+	return audited.cache.Capacity()
+}
+
+const (
+	reasonManual  = "manual"  // Removed by a Remove
+	reasonEvicted = "evicted" // Removed to make room for a set
+)
@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"caching/lru"
+)
+
+// collect returns a Sink appending the records to the slice.
+func collect(records *[]Record) Sink {
+	return SinkFunc(func(record Record) error {
+		*records = append(*records, record)
+		return nil
+	})
+}
+
+func TestLogRecordsWrites(t *testing.T) {
+	clock := lru.NewManualClock(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	var records []Record
+	auditLog := New(collect(&records), WithClock(clock))
+	cache := lru.NewLRUCache(1, lru.WithClock(clock), lru.WithoutMetrics())
+
+	view := auditLog.Cache(ContextWithActor(context.Background(), "ada"), cache)
+	view.Set("a", 1)
+	view.SetWithTTL("b", 2, time.Minute) // Evicts a
+	view.Get("b")
+	auditLog.Cache(context.Background(), cache).Remove("b")
+
+	now := clock.Now()
+	assert.Equal(t, []Record{
+		{Time: now, Operation: OpSet, Key: "a", Reason: string(lru.SetAdded), Actor: "ada"},
+		{Time: now, Operation: OpSet, Key: "b", Reason: string(lru.SetAdded), ExpiresAt: now.Add(time.Minute), Actor: "ada"},
+		{Time: now, Operation: OpRemove, Key: "a", Reason: reasonEvicted, Actor: "ada"},
+		{Time: now, Operation: OpRemove, Key: "b", Reason: reasonManual},
+	}, records)
+	assert.NoError(t, auditLog.Err())
+}
+
+func TestLogRecordsTheExpirationOfTheCache(t *testing.T) {
+	logClock := lru.NewManualClock(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	cacheClock := lru.NewManualClock(logClock.Now().Add(time.Hour))
+	var records []Record
+	auditLog := New(collect(&records), WithClock(logClock))
+	policy := lru.WithTTLPolicy(func(string, any) time.Duration { return 10 * time.Minute })
+	view := auditLog.Cache(context.Background(), lru.NewLRUCache(5, lru.WithClock(cacheClock), policy, lru.WithoutMetrics()))
+
+	view.Set("a", 1)
+	view.SetWithTTL("b", 2, time.Minute)
+	assert.Equal(t, cacheClock.Now().Add(10*time.Minute), records[0].ExpiresAt, "Expected the ttl of the policy")
+	assert.Equal(t, cacheClock.Now().Add(time.Minute), records[1].ExpiresAt, "Expected the clock of the cache")
+
+	records = nil
+	view = auditLog.Cache(context.Background(), lru.NewLFUCache(5, lru.WithClock(logClock), policy, lru.WithoutMetrics()))
+	view.Set("a", 1)
+	assert.Equal(t, logClock.Now().Add(10*time.Minute), records[0].ExpiresAt, "Expected the ttl read with TTLReader")
+}
+
+func TestLogHashesKeys(t *testing.T) {
+	var records []Record
+	cache := lru.NewLRUCache(2, lru.WithoutMetrics())
+	New(collect(&records), WithHashedKeys(nil)).Cache(context.Background(), cache).Set("a", 1)
+	New(collect(&records), WithHashedKeys([]byte("secret"))).Cache(context.Background(), cache).Set("a", 1)
+
+	assert.Equal(t, "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb", records[0].Key)
+	assert.Len(t, records[1].Key, 64)
+	assert.NotEqual(t, records[0].Key, records[1].Key, "Expected the secret to change the hashes")
+}
+
+func TestLogKeepsFirstError(t *testing.T) {
+	first := errors.New("first")
+	errs := []error{first, errors.New("second")}
+	auditLog := New(SinkFunc(func(record Record) error {
+		err := errs[0]
+		errs = errs[1:]
+		return err
+	}))
+	view := auditLog.Cache(context.Background(), lru.NewLRUCache(2, lru.WithoutMetrics()))
+	view.Set("a", 1)
+	view.Remove("a")
+
+	assert.ErrorIs(t, auditLog.Err(), first)
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for _, actor := range []string{"ada", "grace"} { // Reopening appends
+		sink, err := OpenFile(path)
+		assert.NoError(t, err)
+		New(sink).Cache(ContextWithActor(context.Background(), actor), lru.NewLRUCache(2, lru.WithoutMetrics())).Set("a", 1)
+		assert.NoError(t, sink.Close())
+	}
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	var actors []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		actors = append(actors, record.Actor)
+	}
+	assert.Equal(t, []string{"ada", "grace"}, actors)
+}
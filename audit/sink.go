@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// Sink receives the records of a Log, one at a time and in order.
+type Sink interface {
+	Write(record Record) error
+}
+
+// SinkFunc is a Sink calling a function with every record, e.g. to forward them to a compliance service.
+type SinkFunc func(record Record) error
+
+// Write calls the function with the record.
+func (fn SinkFunc) Write(record Record) error {
+	return fn(record)
+}
+
+// writerSink is a Sink writing the records to an io.Writer as JSON lines.
+type writerSink struct {
+	encoder *json.Encoder
+}
+
+// NewWriterSink returns a Sink writing the records to the writer as JSON lines, one write per record.
+func NewWriterSink(writer io.Writer) Sink {
+	return &writerSink{encoder: json.NewEncoder(writer)}
+}
+
+// Write encodes the record as a JSON line.
+func (sink *writerSink) Write(record Record) error {
+	return sink.encoder.Encode(record)
+}
+
+// FileSink is a Sink appending the records to a file as JSON lines.
+type FileSink struct {
+	file *os.File
+	Sink
+}
+
+var _ Sink = (*FileSink)(nil) // Ensure FileSink implements the Sink interface
+
+// OpenFile opens the file at the path, creating it if needed, to append the records to it as JSON lines.
+// Every record is written as it comes, without buffering, so none is lost when the process stops.
+func OpenFile(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file, Sink: NewWriterSink(file)}, nil
+}
+
+// Sync flushes the file to the disk.
+func (sink *FileSink) Sync() error {
+	return sink.file.Sync()
+}
+
+// Close closes the file. The records written afterwards fail.
+func (sink *FileSink) Close() error {
+	return sink.file.Close()
+}
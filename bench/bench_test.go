@@ -0,0 +1,192 @@
+package bench
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dgraph-io/ristretto/v2"
+	hashicorp "github.com/hashicorp/golang-lru/v2"
+	"github.com/stretchr/testify/assert"
+
+	"caching/lru"
+)
+
+const (
+	capacity  = 10_000  // Capacity of the benchmarked caches
+	keySpace  = 100_000 // Distinct keys in the traces, ten times the capacity
+	traceSize = 1 << 20 // Length of the precomputed traces
+)
+
+var (
+	zipfTrace    = ZipfKeys(traceSize, keySpace, 1.01, 1)
+	uniformTrace = UniformKeys(traceSize, keySpace, 1)
+)
+
+// target adapts a cache to the Target interface.
+type target struct {
+	get func(key string) (any, bool)
+	set func(key string, value any)
+}
+
+func (t target) Get(key string) (any, bool) {
+	return t.get(key)
+}
+
+func (t target) Set(key string, value any) {
+	t.set(key, value)
+}
+
+// cacheTarget adapts a cache of the lru package.
+func cacheTarget(cache lru.Cache) Target {
+	return target{
+		get: cache.Get,
+		set: func(key string, value any) { cache.Set(key, value) },
+	}
+}
+
+// namedTarget creates a fresh instance of one of the caches being compared.
+type namedTarget struct {
+	name      string
+	newTarget func() Target
+}
+
+// targets returns every thread-safe cache being compared, in a stable order.
+func targets() []namedTarget {
+	return []namedTarget{
+		{"SafeLRUCache", func() Target {
+			return cacheTarget(lru.NewSafeLRUCache(capacity))
+		}},
+		{"SafeLRUCache/AccessBuffer", func() Target {
+			return cacheTarget(lru.NewSafeLRUCache(capacity, lru.WithAccessBuffer(64)))
+		}},
+		{"ShardedCache", func() Target {
+			return cacheTarget(lru.NewShardedCache(16, capacity))
+		}},
+		{"ShardedCache/AccessBuffer", func() Target {
+			return cacheTarget(lru.NewShardedCache(16, capacity, lru.WithAccessBuffer(64)))
+		}},
+		{"hashicorp/golang-lru", func() Target {
+			cache, _ := hashicorp.New[string, any](capacity)
+			return target{
+				get: cache.Get,
+				set: func(key string, value any) { cache.Add(key, value) },
+			}
+		}},
+		{"ristretto", func() Target {
+			cache, _ := ristretto.NewCache(&ristretto.Config[string, any]{
+				NumCounters: capacity * 10, // Ten times the number of items, as recommended
+				MaxCost:     capacity,      // Every item costs 1, so this bounds the item count
+				BufferItems: 64,
+			})
+			return target{
+				get: cache.Get,
+				set: func(key string, value any) { cache.Set(key, value, 1) },
+			}
+		}},
+	}
+}
+
+// runParallel replays the trace from every goroutine, each starting at a different offset.
+// Reads that miss are filled in, as a cache-aside application would. The hit ratio is reported.
+func runParallel(b *testing.B, cache Target, trace []string, readRatio float64) {
+	reads := Operations(len(trace), readRatio, 2)
+	var offset, hits, lookups atomic.Int64
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(offset.Add(7919)) // Spread the goroutines over the trace
+		localHits, localLookups := int64(0), int64(0)
+		for pb.Next() {
+			position := i % len(trace)
+			key := trace[position]
+			if reads[position] {
+				localLookups++
+				if _, found := cache.Get(key); found {
+					localHits++
+					i++
+					continue
+				}
+			}
+			cache.Set(key, position)
+			i++
+		}
+		hits.Add(localHits)
+		lookups.Add(localLookups)
+	})
+
+	if lookups.Load() > 0 {
+		b.ReportMetric(float64(hits.Load())/float64(lookups.Load()), "hit-ratio")
+	}
+}
+
+func BenchmarkParallel(b *testing.B) {
+	workloads := []struct {
+		name  string
+		trace []string
+	}{
+		{"zipf", zipfTrace},
+		{"uniform", uniformTrace},
+	}
+	mixes := []float64{0.9, 0.5, 0.1}
+
+	for _, workload := range workloads {
+		for _, readRatio := range mixes {
+			for _, target := range targets() {
+				b.Run(fmt.Sprintf("%s/reads=%.0f%%/%s", workload.name, readRatio*100, target.name), func(b *testing.B) {
+					runParallel(b, target.newTarget(), workload.trace, readRatio)
+				})
+			}
+		}
+	}
+}
+
+func BenchmarkLRUCacheGet(b *testing.B) {
+	cache := lru.NewLRUCache(capacity)
+	for i := range capacity {
+		cache.Set(zipfTrace[i], i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		cache.Get(zipfTrace[i%len(zipfTrace)])
+	}
+}
+
+func BenchmarkLRUCacheSet(b *testing.B) {
+	cache := lru.NewLRUCache(capacity)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		cache.Set(uniformTrace[i%len(uniformTrace)], i)
+	}
+}
+
+func BenchmarkLFUCacheGet(b *testing.B) {
+	cache := lru.NewLFUCache(capacity)
+	for i := range capacity {
+		cache.Set(zipfTrace[i], i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		cache.Get(zipfTrace[i%len(zipfTrace)])
+	}
+}
+
+func TestTraces(t *testing.T) {
+	assert.Len(t, ZipfKeys(100, 10, 1.1, 1), 100)
+	assert.Equal(t, ZipfKeys(10, 10, 1.1, 1), ZipfKeys(10, 10, 1.1, 1), "Traces should be reproducible")
+
+	reads := 0
+	for _, read := range Operations(10_000, 0.9, 1) {
+		if read {
+			reads++
+		}
+	}
+	assert.InDelta(t, 9000, reads, 200)
+}
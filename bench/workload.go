@@ -0,0 +1,55 @@
+// Package bench holds the benchmarks of the caches, comparing them with each other and with
+// popular third party implementations on realistic workloads.
+//
+// Run them with:
+//
+//	go test ./bench -bench . -benchmem
+//
+// The workloads are precomputed traces of keys, so generating them is not part of the measurements.
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Target is the subset of cache operations exercised by the benchmarks.
+type Target interface {
+	Get(key string) (any, bool)
+	Set(key string, value any)
+}
+
+// ZipfKeys returns a trace of n keys drawn from keySpace distinct keys following a Zipf distribution
+// with parameter s (> 1), where a few keys are very popular, as seen in most real caches.
+func ZipfKeys(n int, keySpace int, s float64, seed int64) []string {
+	random := rand.New(rand.NewSource(seed))
+	zipf := rand.NewZipf(random, s, 1, uint64(keySpace-1))
+
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", zipf.Uint64())
+	}
+	return keys
+}
+
+// UniformKeys returns a trace of n keys drawn uniformly from keySpace distinct keys.
+func UniformKeys(n int, keySpace int, seed int64) []string {
+	random := rand.New(rand.NewSource(seed))
+
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", random.Intn(keySpace))
+	}
+	return keys
+}
+
+// Operations returns a trace of n booleans, true for reads, with the given ratio of reads.
+func Operations(n int, readRatio float64, seed int64) []bool {
+	random := rand.New(rand.NewSource(seed))
+
+	reads := make([]bool, n)
+	for i := range reads {
+		reads[i] = random.Float64() < readRatio
+	}
+	return reads
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"caching/lru"
+)
+
+// Cache exposes a Client through the lru.Cache interface, e.g. as the secondary cache of an lru.DualWriteCache
+// mirroring the writes of a local cache to the servers. The values are stored as bytes: Get returns []byte, and
+// the writes of values other than strings and []byte are rejected. The failed reads are reported as misses, and
+// the failed writes as rejected.
+type Cache struct {
+	client  *Client
+	timeout time.Duration // Timeout of every operation, zero for no limit
+}
+
+var _ lru.Cache = (*Cache)(nil) // Ensure Cache implements the lru.Cache interface
+
+// Cache returns a view of the client implementing lru.Cache, every operation timing out after timeout if positive.
+func (client *Client) Cache(timeout time.Duration) *Cache {
+	return &Cache{client: client, timeout: timeout}
+}
+
+// context returns the context of an operation, with the timeout of the view.
+func (cache *Cache) context() (context.Context, context.CancelFunc) {
+	if cache.timeout <= 0 {
+		return context.Background(), func() {}
+	}
+	return context.WithTimeout(context.Background(), cache.timeout)
+}
+
+// Get returns the value of the key as []byte, and whether it was found.
+func (cache *Cache) Get(key string) (any, bool) {
+	ctx, cancel := cache.context()
+	defer cancel()
+
+	value, found, err := cache.client.Get(ctx, key)
+	if err != nil || !found {
+		return nil, false
+	}
+	return value, true
+}
+
+// Set stores the value under key with no expiration.
+func (cache *Cache) Set(key string, value any) lru.SetResult {
+	return cache.set(key, value, 0)
+}
+
+// SetWithTTL stores the value under key, expiring after ttl. Like the caches, it removes the key if ttl is not positive.
+func (cache *Cache) SetWithTTL(key string, value any, ttl time.Duration) lru.SetResult {
+	if ttl <= 0 {
+		cache.Remove(key)
+		return lru.SetResult{Status: lru.SetExpired}
+	}
+	return cache.set(key, value, ttl)
+}
+
+// set stores the value under key, expiring after ttl, or never if ttl is zero.
+func (cache *Cache) set(key string, value any, ttl time.Duration) lru.SetResult {
+	var data []byte
+	switch value := value.(type) {
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return lru.SetResult{Status: lru.SetRejected}
+	}
+
+	ctx, cancel := cache.context()
+	defer cancel()
+	if err := cache.client.Set(ctx, key, data, ttl); err != nil {
+		return lru.SetResult{Status: lru.SetRejected}
+	}
+	return lru.SetResult{Status: lru.SetAdded} // The servers do not tell the additions from the updates
+}
+
+// Remove deletes the key from the servers.
+func (cache *Cache) Remove(key string) {
+	ctx, cancel := cache.context()
+	defer cancel()
+
+	cache.client.Remove(ctx, key)
+}
+
+// Len returns 0, as the number of keys of the servers is not known.
+func (cache *Cache) Len() int {
+	return 0
+}
+
+// Capacity returns -1, as the capacity of the servers is not known.
+func (cache *Cache) Capacity() int {
+	return -1
+}
@@ -0,0 +1,449 @@
+// Package client spreads keys over several cache servers speaking RESP, such as the ones of the server package.
+// Keys are placed on a consistent hash ring with virtual nodes, so adding or removing a server only moves
+// a fraction of the keys. Each key can be written to several replicas, and reads fall back to the next
+// replica when a server is down. Servers failing a request or a periodic health check are skipped until
+// they answer again. The servers can change at runtime with SetNodes, e.g. following a gossip membership.
+// With WithNearCache, the values read are also kept in the client and invalidated by the servers.
+// SetWithToken and GetAtLeast read a write back even after a replica missed it.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoNodes is returned when every server responsible for a key is unhealthy.
+var ErrNoNodes = errors.New("client: no healthy node")
+
+// ErrStale is returned by GetAtLeast when none of the replicas that acknowledged the write can be read,
+// so the copies that can may be older than the write.
+var ErrStale = errors.New("client: no replica acknowledged the write")
+
+// leaseRetryDelay is how long GetOrLoad waits before asking again for a key filled by another client.
+const leaseRetryDelay = 10 * time.Millisecond
+
+// ServerError is an error reply of a server.
+type ServerError struct {
+	Addr    string // Address of the server
+	Message string // Error message sent by the server, e.g. "ERR syntax error"
+}
+
+func (err *ServerError) Error() string {
+	return fmt.Sprintf("client: %s: %s", err.Addr, err.Message)
+}
+
+// options holds the optional configuration of the client.
+type options struct {
+	virtualNodes        int           // Points of every node on the ring
+	replicas            int           // Nodes holding every key
+	healthCheckInterval time.Duration // Interval between the health checks, zero disables them
+	dialTimeout         time.Duration // Timeout of the connection attempts and health checks
+	poolSize            int           // Idle connections kept per node
+	nearCapacity        int           // Values kept in the near cache of every node, zero disables it
+	nearTTL             time.Duration // Longest time a value is served from the near cache, zero for no limit
+}
+
+// Option configures a Client at construction time.
+type Option func(*options)
+
+// WithVirtualNodes sets how many points every server has on the ring. More points spread
+// the keys more evenly, at the cost of a larger ring. Defaults to 160.
+func WithVirtualNodes(n int) Option {
+	return func(o *options) {
+		o.virtualNodes = n
+	}
+}
+
+// WithReplicas sets on how many servers every key is written. Reads are served by the first
+// healthy replica. Defaults to 1.
+func WithReplicas(n int) Option {
+	return func(o *options) {
+		o.replicas = n
+	}
+}
+
+// WithHealthCheck sets the interval at which every server is pinged. Unhealthy servers are skipped
+// until they answer again. Defaults to 5 seconds, zero disables the health checks, in which case
+// failing servers are never skipped and reads just fall back to the next replica.
+func WithHealthCheck(interval time.Duration) Option {
+	return func(o *options) {
+		o.healthCheckInterval = interval
+	}
+}
+
+// WithDialTimeout sets the timeout of the connection attempts and of the health checks. Defaults to 1 second.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.dialTimeout = timeout
+	}
+}
+
+// WithPoolSize sets how many idle connections are kept open to every server. Defaults to 8.
+func WithPoolSize(size int) Option {
+	return func(o *options) {
+		o.poolSize = size
+	}
+}
+
+// WithNearCache keeps up to capacity of the values read from every server in the client, so repeated
+// reads of hot keys don't reach the network. The servers push an invalidation when a key read by the
+// client changes, over a dedicated connection per server; while it is down nothing is cached locally.
+// A write racing with a read may still leave a stale copy until it expires after ttl, zero for no limit.
+// Requires servers supporting CLIENT TRACKING, such as the ones of the server package or Redis 6.
+func WithNearCache(capacity int, ttl time.Duration) Option {
+	return func(o *options) {
+		o.nearCapacity = capacity
+		o.nearTTL = ttl
+	}
+}
+
+// Client spreads keys over several cache servers. It is safe for concurrent use.
+type Client struct {
+	ring     atomic.Pointer[ring] // The current ring, replaced by SetNodes
+	ringLock sync.Mutex           // Serializes the ring updates
+	opts     options
+	replicas int
+	failover bool          // Whether unhealthy nodes are skipped, only when health checks can revive them
+	stop     chan struct{} // Closed to stop the health checks
+	stopOnce sync.Once
+	wg       sync.WaitGroup // Tracks the health check goroutine
+}
+
+var _ io.Closer = (*Client)(nil) // Ensure Client can be closed
+
+// New creates a client for the servers at the given TCP addresses. No connection is opened until needed.
+func New(addrs []string, opts ...Option) *Client {
+	o := options{
+		virtualNodes:        160,
+		replicas:            1,
+		healthCheckInterval: 5 * time.Second,
+		dialTimeout:         time.Second,
+		poolSize:            8,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	client := &Client{
+		opts:     o,
+		replicas: max(o.replicas, 1),
+		stop:     make(chan struct{}),
+	}
+	client.SetNodes(addrs)
+
+	if o.healthCheckInterval > 0 {
+		client.failover = true
+		client.wg.Add(1)
+		go client.checkHealth(o.healthCheckInterval, o.dialTimeout)
+	}
+	return client
+}
+
+// Token records which replicas acknowledged a write, so a later read can insist on seeing it.
+// It is returned by SetWithToken and accepted by GetAtLeast. Its exported fields can be serialized,
+// e.g. in a session cookie, to read the own writes from another client.
+type Token struct {
+	Key   string   // The key written
+	Nodes []string // Addresses of the replicas that acknowledged the write
+
+	issuer *Client // The client that wrote, whose near cache was invalidated by the write
+}
+
+// Get returns the value stored under key, reading from the first replica that answers.
+func (client *Client) Get(ctx context.Context, key string) (value []byte, found bool, err error) {
+	nodes := client.ring.Load().lookup(key, client.replicas, client.failover)
+	if len(nodes) == 0 {
+		return nil, false, ErrNoNodes
+	}
+	return client.read(ctx, key, nodes, true)
+}
+
+// GetAtLeast returns the value stored under key, or a newer one, as of the write that returned the token.
+// It only reads from the replicas that acknowledged the write, in ring order, so a replica that missed it,
+// e.g. while it was down, cannot answer with an older value; it returns ErrStale if none of them can be read.
+// The near cache is skipped unless the token was returned by this client, whose copies are invalidated by its writes.
+// A token of another key reads like Get.
+func (client *Client) GetAtLeast(ctx context.Context, key string, token Token) (value []byte, found bool, err error) {
+	if token.Key != key || len(token.Nodes) == 0 {
+		return client.Get(ctx, key)
+	}
+	nodes := client.ring.Load().lookup(key, client.replicas, client.failover)
+	nodes = slices.DeleteFunc(nodes, func(node *node) bool { return !slices.Contains(token.Nodes, node.addr) })
+	if len(nodes) == 0 {
+		return nil, false, ErrStale
+	}
+	return client.read(ctx, key, nodes, token.issuer == client)
+}
+
+// TTL returns the remaining time to live of the key, zero if it does not expire, and whether it was found,
+// asking the first replica that answers with PTTL, e.g. to refresh a value before it expires.
+func (client *Client) TTL(ctx context.Context, key string) (ttl time.Duration, found bool, err error) {
+	nodes := client.ring.Load().lookup(key, client.replicas, client.failover)
+	if len(nodes) == 0 {
+		return 0, false, ErrNoNodes
+	}
+	var errs []error
+	for _, node := range nodes {
+		reply, err := node.do(ctx, "PTTL", key)
+		if err != nil {
+			errs = append(errs, err)
+			continue // Try the next replica
+		}
+		switch {
+		case reply.Int == -2:
+			return 0, false, nil
+		case reply.Int == -1:
+			return 0, true, nil
+		default:
+			return time.Duration(reply.Int) * time.Millisecond, true, nil
+		}
+	}
+	return 0, false, errors.Join(errs...)
+}
+
+// read returns the value stored under key, reading from the first of the nodes that answers,
+// and from the near caches too if useNear is set.
+func (client *Client) read(ctx context.Context, key string, nodes []*node, useNear bool) (value []byte, found bool, err error) {
+	var errs []error
+	for _, node := range nodes {
+		var generation uint64
+		if node.near != nil {
+			if value, found := node.near.get(key); found && useNear {
+				return value, true, nil
+			}
+			generation = node.near.invalidations.Load()
+		}
+		reply, err := node.do(ctx, "GET", key)
+		if err != nil {
+			errs = append(errs, err)
+			continue // Try the next replica
+		}
+		if reply.Null {
+			return nil, false, nil
+		}
+		if node.near != nil {
+			node.near.set(key, []byte(reply.Str), generation)
+		}
+		return []byte(reply.Str), true, nil
+	}
+	return nil, false, errors.Join(errs...)
+}
+
+// GetOrLoad returns the value stored under key, or calls load and stores its result for ttl when the key is missing.
+// The server hands out a lease to a single client per missing key, so load runs once across the clients
+// instead of in every one of them: the others get the value deleted last if the server still has it,
+// or wait until the key is filled. Requires servers supporting LGET and LSET, such as the ones of the server package.
+func (client *Client) GetOrLoad(ctx context.Context, key string, ttl time.Duration, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	for {
+		nodes := client.ring.Load().lookup(key, client.replicas, client.failover)
+		if len(nodes) == 0 {
+			return nil, ErrNoNodes
+		}
+		primary := nodes[0] // The leases of a key are handed out by a single server
+		var generation uint64
+		if primary.near != nil {
+			if value, found := primary.near.get(key); found {
+				return value, nil
+			}
+			generation = primary.near.invalidations.Load()
+		}
+
+		reply, err := primary.do(ctx, "LGET", key)
+		if err != nil {
+			return nil, err
+		}
+		if len(reply.Array) != 2 {
+			return nil, fmt.Errorf("client: %s: unexpected LGET reply", primary.addr)
+		}
+		value, token := reply.Array[0], reply.Array[1].Int
+		switch {
+		case token == 0: // Hit
+			if primary.near != nil {
+				primary.near.set(key, []byte(value.Str), generation)
+			}
+			return []byte(value.Str), nil
+		case token > 0: // The lease is ours
+			return client.fill(ctx, nodes, key, token, ttl, load)
+		case !value.Null: // Another client is filling the key, use the deleted value meanwhile
+			return []byte(value.Str), nil
+		}
+
+		select {
+		case <-time.After(leaseRetryDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// fill loads the value of a key whose lease is held with the given token, stores it on the primary node
+// with the token, and on the other replicas without.
+func (client *Client) fill(ctx context.Context, nodes []*node, key string, token int64, ttl time.Duration, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	value, err := load(ctx)
+	if err != nil {
+		return nil, err // The lease expires on the server, letting another client try
+	}
+
+	var expiration []string
+	if ttl > 0 {
+		expiration = []string{"PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10)}
+	}
+	reply, err := nodes[0].do(ctx, append([]string{"LSET", key, string(value), strconv.FormatInt(token, 10)}, expiration...)...)
+	if err != nil || reply.Null {
+		return value, nil // The key was written meanwhile, or the lease expired: don't override it
+	}
+	for _, node := range nodes[1:] {
+		node.do(ctx, append([]string{"SET", key, string(value)}, expiration...)...)
+	}
+	return value, nil
+}
+
+// Set stores the value under key on every replica, expiring after ttl, or never if ttl is zero or less.
+// It only fails if no replica stored the value.
+func (client *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := client.broadcast(ctx, key, setArgs(key, value, ttl)...)
+	return err
+}
+
+// SetWithToken stores the value like Set, and returns a token recording the replicas that stored it,
+// to read it back with GetAtLeast.
+func (client *Client) SetWithToken(ctx context.Context, key string, value []byte, ttl time.Duration) (Token, error) {
+	acked, err := client.broadcast(ctx, key, setArgs(key, value, ttl)...)
+	if err != nil {
+		return Token{}, err
+	}
+	return Token{Key: key, Nodes: acked, issuer: client}, nil
+}
+
+// setArgs returns the SET command storing the value under key, expiring after ttl if positive.
+func setArgs(key string, value []byte, ttl time.Duration) []string {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	}
+	return args
+}
+
+// Remove deletes the key from every replica. It only fails if no replica could be reached.
+func (client *Client) Remove(ctx context.Context, key string) error {
+	_, err := client.broadcast(ctx, key, "DEL", key)
+	return err
+}
+
+// broadcast sends the command to every replica of the key concurrently, and returns the addresses
+// of the replicas that succeeded. It succeeds if at least one replica succeeded.
+func (client *Client) broadcast(ctx context.Context, key string, args ...string) ([]string, error) {
+	nodes := client.ring.Load().lookup(key, client.replicas, client.failover)
+	if len(nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = node.do(ctx, args...)
+		}()
+	}
+	wg.Wait()
+	for _, node := range nodes {
+		if node.near != nil {
+			node.near.invalidate(key) // Read your writes without waiting for the pushed invalidation
+		}
+	}
+
+	var acked []string
+	for i, err := range errs {
+		if err == nil {
+			acked = append(acked, nodes[i].addr)
+		}
+	}
+	if len(acked) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return acked, nil
+}
+
+// checkHealth pings every node at the given interval until the client is closed.
+func (client *Client) checkHealth(interval time.Duration, timeout time.Duration) {
+	defer client.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, node := range client.ring.Load().nodes {
+				node.check(timeout)
+			}
+		case <-client.stop:
+			return
+		}
+	}
+}
+
+// Close stops the health checks and closes the idle connections.
+func (client *Client) Close() error {
+	client.stopOnce.Do(func() { close(client.stop) })
+	client.wg.Wait()
+
+	var errs []error
+	for _, node := range client.ring.Load().nodes {
+		errs = append(errs, node.close())
+	}
+	return errors.Join(errs...)
+}
+
+// SetNodes replaces the servers the keys are spread over. The servers kept keep their connections
+// and health, the keys of the removed servers move to the next servers of the ring,
+// and the new servers take over about 1/n of the keys each.
+func (client *Client) SetNodes(addrs []string) {
+	client.ringLock.Lock()
+	defer client.ringLock.Unlock()
+
+	current := make(map[string]*node)
+	if ring := client.ring.Load(); ring != nil {
+		for _, node := range ring.nodes {
+			current[node.addr] = node
+		}
+	}
+
+	nodes := make([]*node, 0, len(addrs))
+	for _, addr := range addrs {
+		if node, found := current[addr]; found {
+			nodes = append(nodes, node)
+			delete(current, addr)
+		} else {
+			node := newNode(addr, client.opts.poolSize, client.opts.dialTimeout)
+			if client.opts.nearCapacity > 0 {
+				node.near = newNearCache(node, client.opts.nearCapacity, client.opts.nearTTL)
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	client.ring.Store(newRing(nodes, max(client.opts.virtualNodes, 1)))
+
+	for _, node := range current { // Removed nodes
+		node.removed.Store(true)
+		node.close()
+	}
+}
+
+// Nodes returns the addresses of the servers the keys are spread over.
+func (client *Client) Nodes() []string {
+	nodes := client.ring.Load().nodes
+	addrs := make([]string, len(nodes))
+	for i, node := range nodes {
+		addrs[i] = node.addr
+	}
+	return addrs
+}
@@ -0,0 +1,282 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"caching/lru"
+	"caching/server"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startServers serves n caches on random local ports, returning the servers and their addresses.
+func startServers(t *testing.T, n int) ([]*server.Server, []string) {
+	servers := make([]*server.Server, n)
+	addrs := make([]string, n)
+	for i := range n {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		servers[i] = server.New(lru.NewSafeLRUCache(1000))
+		addrs[i] = listener.Addr().String()
+		go servers[i].Serve(listener)
+		t.Cleanup(func() { servers[i].Close() })
+	}
+	return servers, addrs
+}
+
+func TestClientSetAndGet(t *testing.T) {
+	_, addrs := startServers(t, 3)
+	client := New(addrs)
+	defer client.Close()
+	ctx := context.Background()
+
+	assert.NoError(t, client.Set(ctx, "key1", []byte("value1"), time.Minute))
+	value, found, err := client.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value1"), value)
+
+	assert.NoError(t, client.Remove(ctx, "key1"))
+	_, found, err = client.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestClientTTL(t *testing.T) {
+	_, addrs := startServers(t, 2)
+	client := New(addrs)
+	defer client.Close()
+	ctx := context.Background()
+
+	assert.NoError(t, client.Set(ctx, "key1", []byte("value1"), time.Minute))
+	assert.NoError(t, client.Set(ctx, "key2", []byte("value2"), 0))
+	ttl, found, err := client.TTL(ctx, "key1")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+	ttl, found, err = client.TTL(ctx, "key2")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Zero(t, ttl)
+	_, found, err = client.TTL(ctx, "missing")
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestClientSpreadsKeys(t *testing.T) {
+	_, addrs := startServers(t, 3)
+	client := New(addrs)
+	defer client.Close()
+
+	counts := make(map[string]int)
+	for i := range 3000 {
+		counts[client.ring.Load().lookup(fmt.Sprintf("key%d", i), 1, false)[0].addr]++
+	}
+	for _, addr := range addrs {
+		assert.InDelta(t, 1000, counts[addr], 250, addr)
+	}
+}
+
+func TestRingMovesFewKeys(t *testing.T) {
+	nodes := []*node{newNode("a:1", 1, time.Second), newNode("b:1", 1, time.Second), newNode("c:1", 1, time.Second)}
+	before := newRing(nodes, 160)
+	after := newRing(append(nodes, newNode("d:1", 1, time.Second)), 160)
+
+	moved := 0
+	for i := range 4000 {
+		key := fmt.Sprintf("key%d", i)
+		if before.lookup(key, 1, false)[0].addr != after.lookup(key, 1, false)[0].addr {
+			moved++
+		}
+	}
+	assert.InDelta(t, 1000, moved, 300) // About a quarter of the keys move to the new node
+}
+
+func TestClientReadsFromReplica(t *testing.T) {
+	servers, addrs := startServers(t, 3)
+	client := New(addrs, WithReplicas(2), WithHealthCheck(time.Hour))
+	defer client.Close()
+	ctx := context.Background()
+
+	assert.NoError(t, client.Set(ctx, "key1", []byte("value1"), 0))
+	primary := client.ring.Load().lookup("key1", 1, true)[0]
+	for i, addr := range addrs {
+		if addr == primary.addr {
+			servers[i].Close()
+		}
+	}
+
+	value, found, err := client.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value1"), value)
+	assert.False(t, primary.healthy.Load()) // Skipped until a health check succeeds
+}
+
+func TestClientNoHealthyNodes(t *testing.T) {
+	client := New([]string{"127.0.0.1:1"}, WithDialTimeout(100*time.Millisecond))
+	defer client.Close()
+	ctx := context.Background()
+
+	assert.Error(t, client.Set(ctx, "key1", []byte("value1"), 0))
+	_, _, err := client.Get(ctx, "key1")
+	assert.ErrorIs(t, err, ErrNoNodes)
+}
+
+func TestClientHealthCheckRevivesNodes(t *testing.T) {
+	_, addrs := startServers(t, 1)
+	client := New(addrs, WithHealthCheck(time.Millisecond))
+	defer client.Close()
+
+	client.ring.Load().nodes[0].healthy.Store(false)
+	assert.Eventually(t, client.ring.Load().nodes[0].healthy.Load, time.Second, time.Millisecond)
+}
+
+func TestClientSetNodes(t *testing.T) {
+	_, addrs := startServers(t, 3)
+	client := New(addrs[:2])
+	defer client.Close()
+	removed, kept := client.ring.Load().nodes[0], client.ring.Load().nodes[1]
+
+	client.SetNodes(addrs[1:])
+	assert.Equal(t, addrs[1:], client.Nodes())
+	assert.Same(t, kept, client.ring.Load().nodes[0]) // Keeps its connections and health
+	assert.True(t, removed.removed.Load())
+
+	assert.NoError(t, client.Set(context.Background(), "key1", []byte("value1"), 0))
+	_, found, err := client.Get(context.Background(), "key1")
+	assert.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestClientNearCache(t *testing.T) {
+	_, addrs := startServers(t, 1)
+	client := New(addrs, WithNearCache(100, time.Minute))
+	defer client.Close()
+	other := New(addrs)
+	defer other.Close()
+	ctx := context.Background()
+	near := client.ring.Load().nodes[0].near
+
+	assert.Eventually(t, func() bool { return near.id.Load() != 0 }, time.Second, time.Millisecond)
+	assert.NoError(t, client.Set(ctx, "key1", []byte("value1"), 0))
+	value, found, err := client.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value1"), value)
+	_, found = near.get("key1")
+	assert.True(t, found, "the value read is kept in the client")
+
+	// A write of another client invalidates the copy
+	assert.NoError(t, other.Set(ctx, "key1", []byte("value2"), 0))
+	assert.Eventually(t, func() bool {
+		_, found := near.get("key1")
+		return !found
+	}, time.Second, time.Millisecond)
+	value, _, err = client.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value2"), value)
+
+	// The own writes are read back immediately
+	assert.NoError(t, client.Set(ctx, "key1", []byte("value3"), 0))
+	value, _, err = client.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value3"), value)
+}
+
+func TestClientGetOrLoad(t *testing.T) {
+	_, addrs := startServers(t, 2)
+	client := New(addrs)
+	defer client.Close()
+	ctx := context.Background()
+
+	var loads atomic.Int32
+	load := func(ctx context.Context) ([]byte, error) {
+		loads.Add(1)
+		time.Sleep(50 * time.Millisecond) // An expensive computation
+		return []byte("value1"), nil
+	}
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := client.GetOrLoad(ctx, "key1", time.Minute, load)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("value1"), value)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), loads.Load(), "only the lease holder loads the value")
+
+	value, found, err := client.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value1"), value)
+}
+
+func TestClientGetAtLeast(t *testing.T) {
+	servers, addrs := startServers(t, 2)
+	client := New(addrs, WithReplicas(2), WithHealthCheck(0))
+	defer client.Close()
+	ctx := context.Background()
+
+	assert.NoError(t, client.Set(ctx, "key1", []byte("value1"), 0))
+	primary := client.ring.Load().lookup("key1", 1, false)[0]
+	index := slices.Index(addrs, primary.addr)
+
+	// The primary misses the next write while it is down, and comes back with the former value
+	servers[index].Close()
+	token, err := client.SetWithToken(ctx, "key1", []byte("value2"), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "key1", token.Key)
+	assert.Equal(t, []string{addrs[1-index]}, token.Nodes)
+	cache := lru.NewSafeLRUCache(1000)
+	cache.Set("key1", "value1")
+	restarted := server.New(cache)
+	defer restarted.Close()
+	listener, err := net.Listen("tcp", primary.addr)
+	assert.NoError(t, err)
+	go restarted.Serve(listener)
+
+	value, _, err := client.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value, "the primary answers with its older value")
+	value, found, err := client.GetAtLeast(ctx, "key1", token)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value2"), value)
+
+	// Without a reachable replica that acknowledged the write, nothing is read
+	servers[1-index].Close()
+	_, _, err = client.GetAtLeast(ctx, "key1", token)
+	assert.Error(t, err)
+	_, _, err = client.GetAtLeast(ctx, "key1", Token{Key: "key1", Nodes: []string{"127.0.0.1:1"}})
+	assert.ErrorIs(t, err, ErrStale)
+}
+
+func TestClientCache(t *testing.T) {
+	_, addrs := startServers(t, 2)
+	client := New(addrs)
+	defer client.Close()
+	remote := client.Cache(time.Second)
+	local := lru.NewSafeLRUCache(10, lru.WithoutMetrics())
+	cache := lru.NewDualWriteCache(local, remote, lru.WithoutMetrics())
+
+	assert.Equal(t, lru.SetAdded, cache.SetWithTTL("key1", "value1", time.Minute).Status)
+	value, found := remote.Get("key1")
+	assert.True(t, found, "Expected the write to be mirrored to the servers")
+	assert.Equal(t, []byte("value1"), value)
+	assert.Equal(t, lru.SetRejected, remote.Set("key2", 42).Status)
+
+	cache.Remove("key1")
+	_, found = remote.Get("key1")
+	assert.False(t, found)
+}
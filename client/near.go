@@ -0,0 +1,148 @@
+package client
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"caching/internal/resp"
+	"caching/lru"
+)
+
+// nearCache keeps local copies of the values read from a node, the client side caching of Redis 6.
+// The node pushes an invalidation over a dedicated connection when a key read by the client changes,
+// and the data connections redirect their tracking to it. While that connection is down,
+// invalidations may be missed, so the copies are dropped and no new ones are kept.
+type nearCache struct {
+	node     *node
+	capacity int
+	ttl      time.Duration // Bounds how long a copy is served, zero to keep it until invalidated
+
+	cache         atomic.Pointer[lru.SafeLRUCache] // The copies, replaced by an empty cache when invalidations may have been missed
+	id            atomic.Int64                     // Id of the invalidation connection on the node, zero while disconnected
+	invalidations atomic.Uint64                    // Number of invalidations received, to detect the ones racing with a read
+
+	mutex sync.Mutex
+	conn  net.Conn      // The invalidation connection, nil while disconnected
+	stop  chan struct{} // Closed to stop the near cache
+	done  chan struct{} // Closed when the invalidation goroutine returned
+	once  sync.Once     // Closes stop
+}
+
+func newNearCache(node *node, capacity int, ttl time.Duration) *nearCache {
+	near := &nearCache{
+		node:     node,
+		capacity: capacity,
+		ttl:      ttl,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	near.reset()
+	go near.run()
+	return near
+}
+
+// get returns a copy of the value cached for the key.
+func (near *nearCache) get(key string) ([]byte, bool) {
+	value, found := near.cache.Load().Get(key)
+	if !found {
+		return nil, false
+	}
+	return append([]byte(nil), value.([]byte)...), true
+}
+
+// set caches a copy of the value read for the key, unless the read may have raced with an invalidation:
+// generation is the number of invalidations received before the read was sent.
+func (near *nearCache) set(key string, value []byte, generation uint64) {
+	if near.invalidations.Load() != generation || near.id.Load() == 0 {
+		return
+	}
+	cache := near.cache.Load()
+	if near.ttl > 0 {
+		cache.SetWithTTL(key, append([]byte(nil), value...), near.ttl)
+	} else {
+		cache.Set(key, append([]byte(nil), value...))
+	}
+}
+
+// invalidate drops the copy of the key, and prevents the reads in flight from caching a copy.
+func (near *nearCache) invalidate(key string) {
+	near.invalidations.Add(1)
+	near.cache.Load().Remove(key)
+}
+
+// reset drops every copy.
+func (near *nearCache) reset() {
+	near.cache.Store(lru.NewSafeLRUCache(near.capacity, lru.WithoutMetrics()))
+}
+
+// run keeps the invalidation connection open until the near cache is closed.
+func (near *nearCache) run() {
+	defer close(near.done)
+	for {
+		if err := near.listen(); err != nil {
+			near.id.Store(0)
+			near.invalidations.Add(1) // Fail the reads in flight, their copies may be stale
+			near.reset()
+		}
+		select {
+		case <-near.stop:
+			return
+		case <-time.After(near.node.dialTimeout): // Wait before reconnecting
+		}
+	}
+}
+
+// listen opens the invalidation connection and applies the invalidations until it fails.
+func (near *nearCache) listen() error {
+	netConn, err := net.DialTimeout("tcp", near.node.addr, near.node.dialTimeout)
+	if err != nil {
+		return err
+	}
+	near.mutex.Lock()
+	select {
+	case <-near.stop:
+		near.mutex.Unlock()
+		return netConn.Close()
+	default:
+		near.conn = netConn
+	}
+	near.mutex.Unlock()
+	defer netConn.Close()
+
+	conn := &conn{Conn: netConn, reader: resp.NewReader(netConn), writer: resp.NewWriter(netConn)}
+	conn.SetDeadline(time.Now().Add(near.node.dialTimeout))
+	reply, err := conn.roundTrip("CLIENT", "ID")
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+	near.id.Store(reply.Int)
+
+	for {
+		message, err := conn.reader.ReadValue()
+		if err != nil {
+			return err
+		}
+		if message.Kind != resp.Push || len(message.Array) != 2 || message.Array[0].Str != "invalidate" {
+			continue
+		}
+		for _, key := range message.Array[1].Array {
+			near.invalidate(key.Str)
+		}
+	}
+}
+
+// close stops the near cache and closes its invalidation connection.
+func (near *nearCache) close() {
+	near.once.Do(func() {
+		near.mutex.Lock()
+		close(near.stop)
+		if near.conn != nil {
+			near.conn.Close()
+		}
+		near.mutex.Unlock()
+	})
+	<-near.done
+}
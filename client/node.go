@@ -0,0 +1,155 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"caching/internal/resp"
+)
+
+// conn is an open connection to a node.
+type conn struct {
+	net.Conn
+	reader   *resp.Reader
+	writer   *resp.Writer
+	redirect int64 // Id of the connection receiving the invalidations of the keys read, zero without tracking
+}
+
+// roundTrip sends a command and reads its reply.
+func (conn *conn) roundTrip(args ...string) (resp.Value, error) {
+	conn.writer.WriteCommand(args...)
+	if err := conn.writer.Flush(); err != nil {
+		return resp.Value{}, err
+	}
+	return conn.reader.ReadValue()
+}
+
+// node is a cache server, reached through a small pool of connections.
+type node struct {
+	addr        string
+	healthy     atomic.Bool   // Whether the node answered its last request or health check
+	removed     atomic.Bool   // Whether the node was removed from the ring, its connections are then closed
+	idle        chan *conn    // Connections ready to be reused
+	dialTimeout time.Duration // Timeout of the connection attempts
+	near        *nearCache    // Local copies of the values read from the node, nil without client-side caching
+}
+
+func newNode(addr string, poolSize int, dialTimeout time.Duration) *node {
+	node := &node{
+		addr:        addr,
+		idle:        make(chan *conn, poolSize),
+		dialTimeout: dialTimeout,
+	}
+	node.healthy.Store(true) // Optimistic until a request or a health check fails
+	return node
+}
+
+// do sends a command to the node and returns its reply.
+// Replies of the error kind are returned as a ServerError, they don't make the node unhealthy,
+// unlike network errors which also drop the connection.
+func (node *node) do(ctx context.Context, args ...string) (resp.Value, error) {
+	conn, err := node.get(ctx)
+	if err != nil {
+		node.healthy.Store(false)
+		return resp.Value{}, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{} // No deadline, clear the one of a previous request
+	}
+	conn.SetDeadline(deadline)
+	err = node.redirectTracking(conn)
+	var reply resp.Value
+	if err == nil {
+		reply, err = conn.roundTrip(args...)
+	}
+	if err != nil {
+		conn.Close()
+		node.healthy.Store(false)
+		return resp.Value{}, err
+	}
+
+	node.put(conn)
+	node.healthy.Store(true)
+	if reply.Kind == resp.Error {
+		return resp.Value{}, &ServerError{Addr: node.addr, Message: reply.Str}
+	}
+	return reply, nil
+}
+
+// redirectTracking makes the server notify the invalidation connection of the near cache
+// when the keys read on conn change, if it is not already the case.
+func (node *node) redirectTracking(conn *conn) error {
+	if node.near == nil {
+		return nil
+	}
+	id := node.near.id.Load()
+	if id == 0 || id == conn.redirect {
+		return nil
+	}
+	reply, err := conn.roundTrip("CLIENT", "TRACKING", "ON", "REDIRECT", strconv.FormatInt(id, 10))
+	if err != nil {
+		return err
+	}
+	if reply.Kind != resp.Error { // The invalidation connection may have been closed meanwhile
+		conn.redirect = id
+	}
+	return nil
+}
+
+// get returns an idle connection, or dials a new one if there is none.
+func (node *node) get(ctx context.Context) (*conn, error) {
+	select {
+	case conn := <-node.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: node.dialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", node.addr)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: netConn, reader: resp.NewReader(netConn), writer: resp.NewWriter(netConn)}, nil
+}
+
+// put returns a connection to the pool, closing it if the pool is full or the node was removed.
+func (node *node) put(conn *conn) {
+	if node.removed.Load() {
+		conn.Close()
+		return
+	}
+	select {
+	case node.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// check pings the node, updating its health.
+func (node *node) check(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	node.do(ctx, "PING")
+}
+
+// close closes the idle connections, and stops the near cache.
+func (node *node) close() error {
+	if node.near != nil {
+		node.near.close()
+	}
+	var errs []error
+	for {
+		select {
+		case conn := <-node.idle:
+			errs = append(errs, conn.Close())
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// ring is a consistent hash ring: every node is placed at several points, its virtual nodes,
+// and a key belongs to the first nodes found clockwise from the hash of the key.
+// Adding or removing a node only moves the keys of its own points, about 1/n of the keys.
+type ring struct {
+	nodes  []*node          // Nodes placed on the ring
+	points []uint32         // Hashes of the virtual nodes, sorted
+	owners map[uint32]*node // Node owning every point
+}
+
+func newRing(nodes []*node, virtualNodes int) *ring {
+	ring := &ring{nodes: nodes, owners: make(map[uint32]*node, len(nodes)*virtualNodes)}
+	for _, node := range nodes {
+		for i := range virtualNodes {
+			point := hash(node.addr + "#" + strconv.Itoa(i))
+			if _, taken := ring.owners[point]; taken {
+				continue // Keep the first owner of a colliding point, so the ring does not depend on the order of insertion
+			}
+			ring.owners[point] = node
+			ring.points = append(ring.points, point)
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool { return ring.points[i] < ring.points[j] })
+	return ring
+}
+
+// lookup returns up to n distinct nodes responsible for the key, in ring order.
+// With skipUnhealthy, unhealthy nodes are skipped, so their keys are served by the next nodes
+// of the ring until they recover.
+func (ring *ring) lookup(key string, n int, skipUnhealthy bool) []*node {
+	if len(ring.points) == 0 {
+		return nil
+	}
+	target := hash(key)
+	start := sort.Search(len(ring.points), func(i int) bool { return ring.points[i] >= target })
+
+	nodes := make([]*node, 0, n)
+	for i := 0; i < len(ring.points) && len(nodes) < n; i++ {
+		node := ring.owners[ring.points[(start+i)%len(ring.points)]]
+		if (!skipUnhealthy || node.healthy.Load()) && !slices.Contains(nodes, node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// hash returns the FNV-1a hash of the text, passed through the finalizer of MurmurHash3.
+// FNV-1a alone spreads the similar names of the virtual nodes poorly over the ring.
+func hash(text string) uint32 {
+	hasher := fnv.New32a()
+	hasher.Write([]byte(text))
+	h := hasher.Sum32()
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
@@ -0,0 +1,258 @@
+// Command cacheserver serves a sharded cache over RESP, so it can be used with redis-cli
+// and with the client package.
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"caching/gossip"
+	"caching/lru"
+	"caching/migrate"
+	"caching/persist"
+	"caching/server"
+)
+
+func main() {
+	addr := flag.String("addr", ":6380", "TCP address to listen on")
+	capacity := flag.Int("capacity", 100000, "maximum number of items in the cache, 0 to disable caching, -1 for no limit")
+	shards := flag.Int("shards", 16, "number of shards of the cache")
+	replicas := flag.String("replicas", "", "comma separated addresses of the servers receiving the writes")
+	quorum := flag.Bool("quorum", false, "acknowledge writes once a majority of the replicas applied them")
+	secretFile := flag.String("replication-secret-file", "", "file holding the secret shared by the servers replicating to each other, required to send or receive replicated writes")
+	gossipAddr := flag.String("gossip-addr", "", "UDP address to gossip on, empty to not join a cluster")
+	advertise := flag.String("advertise", "", "address of this server announced to the cluster, defaults to -addr")
+	join := flag.String("join", "", "comma separated gossip addresses of members of the cluster to join")
+	aofPath := flag.String("aof", "", "append-only log restored at startup and recording every write, empty to not persist")
+	aofFsync := flag.String("aof-fsync", "everysec", "when the append-only log is flushed to the disk: always, everysec or no")
+	backupDir := flag.String("backup-dir", "", "directory receiving periodic snapshots of the append-only log, restored when the log is empty")
+	commandTimeout := flag.Duration("command-timeout", 0, "maximum duration of a command, e.g. waiting for the replicas, 0 for no limit")
+	namespaceSeparator := flag.String("namespace-separator", "", "separator ending the namespace of the keys counted by INFO keyspace, e.g. ':', empty to not count them")
+	slowlogThreshold := flag.Duration("slowlog-threshold", 0, "duration above which a command is recorded in the slow log served by SLOWLOG, 0 to disable it")
+	slowlogMaxLen := flag.Int("slowlog-max-len", 128, "number of entries kept by the slow log")
+	backupInterval := flag.Duration("backup-interval", time.Hour, "interval between the snapshots written to -backup-dir")
+	ttlBuckets := flag.String("ttl-buckets", "", "comma separated upper bounds in seconds of the TTL histogram and distribution, empty for the defaults")
+	metricsNamespace := flag.String("metrics-namespace", lru.DefaultMetricsNamespace, "prefix of the names of the Prometheus metrics")
+	legacyMetrics := flag.Bool("legacy-metrics", false, "also record the metrics under their former lru_cache_ names, while the dashboards migrate")
+	metricsBatch := flag.Int("metrics-batch", 0, "operations whose counters are added to the metrics at once, to lighten the hot path, zero to add them on every operation")
+	keyFile := flag.String("encryption-key-file", "", "file holding a hex encoded AES key encrypting the values persisted by -aof and -backup-dir")
+	importFrom := flag.String("import", "", "strings loaded when the cache is empty at startup, from a Redis RDB file, redis://host:port or memcached://host:port")
+	invalidate := flag.String("invalidate", "", "semicolon separated invalidations of a namespace on a schedule, as a cron expression in local time followed by the prefix of the keys, e.g. '0 0 * * * report:'")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if *ttlBuckets != "" {
+		var buckets []float64
+		for _, field := range strings.Split(*ttlBuckets, ",") {
+			bucket, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				logger.Error("invalid -ttl-buckets", "value", *ttlBuckets)
+				os.Exit(2)
+			}
+			buckets = append(buckets, bucket)
+		}
+		lru.SetTTLBuckets(buckets)
+	}
+	if err := lru.SetMetricsNamespace(*metricsNamespace); err != nil {
+		logger.Error("invalid -metrics-namespace", "value", *metricsNamespace, "error", err)
+		os.Exit(2)
+	}
+	var codec lru.Codec = lru.StringCodec{}
+	if *keyFile != "" {
+		data, err := os.ReadFile(*keyFile)
+		if err != nil {
+			logger.Error("reading the encryption key failed", "error", err)
+			os.Exit(1)
+		}
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			logger.Error("invalid encryption key", "error", err)
+			os.Exit(2)
+		}
+		codec = lru.NewEncryptedCodec(codec, lru.StaticKeys{Keys: map[uint32][]byte{0: key}})
+	}
+	cacheOpts := []lru.Option{lru.WithKeyIndex()} // SCAN reads its pages from the sorted keys
+	if *legacyMetrics {
+		cacheOpts = append(cacheOpts, lru.WithLegacyMetrics())
+	}
+	if *metricsBatch > 1 {
+		cacheOpts = append(cacheOpts, lru.WithMetricsBatching(*metricsBatch))
+	}
+	var aof *persist.AOF
+	if *aofPath != "" {
+		policies := map[string]persist.FsyncPolicy{"always": persist.FsyncAlways, "everysec": persist.FsyncEverySecond, "no": persist.FsyncNever}
+		policy, ok := policies[*aofFsync]
+		if !ok {
+			logger.Error("invalid -aof-fsync", "value", *aofFsync)
+			os.Exit(2)
+		}
+		var err error
+		if aof, err = persist.OpenAOF(*aofPath, codec, persist.WithFsync(policy), persist.WithAutoRewrite(64<<20)); err != nil {
+			logger.Error("opening the append-only log failed", "error", err)
+			os.Exit(1)
+		}
+		cacheOpts = append(cacheOpts, lru.WithEventListener(aof.Record))
+	}
+	var srv *server.Server // Publishes the keyspace notifications once created, after the restores
+	cacheOpts = append(cacheOpts, lru.WithEventListener(func(event lru.Event) {
+		if srv != nil {
+			srv.Notify(event)
+		}
+	}))
+	cache := lru.NewShardedCache(*shards, *capacity, cacheOpts...)
+	if aof != nil {
+		if err := aof.Restore(cache); err != nil {
+			logger.Error("restoring the append-only log failed", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("restored", "items", cache.Len())
+	}
+	var backup *persist.Backup
+	if *backupDir != "" {
+		if aof == nil {
+			logger.Error("-backup-dir requires -aof")
+			os.Exit(2)
+		}
+		store := persist.NewDirStore(*backupDir)
+		if cache.Len() == 0 { // A new node, start from the latest snapshot
+			err := persist.Restore(context.Background(), store, cache, codec)
+			if err != nil && !errors.Is(err, persist.ErrBlobNotFound) {
+				logger.Error("restoring the latest snapshot failed", "error", err)
+				os.Exit(1)
+			}
+			logger.Info("restored from snapshot", "items", cache.Len())
+		}
+		backup = persist.NewBackup(aof, store, persist.WithBackupInterval(*backupInterval), persist.OnBackupError(func(err error) {
+			logger.Error("backup failed", "error", err)
+		}))
+	}
+	if *importFrom != "" && cache.Len() == 0 { // A new node replacing another cache, start warm
+		report, err := importEntries(*importFrom, cache)
+		if err != nil {
+			logger.Error("importing failed", "from", *importFrom, "error", err)
+			os.Exit(1)
+		}
+		logger.Info("imported", "from", *importFrom, "items", report.Imported, "expired", report.Expired, "skipped", report.Skipped)
+	}
+	opts := []server.Option{server.WithCommandTimeout(*commandTimeout)}
+	if *slowlogThreshold > 0 {
+		opts = append(opts, server.WithSlowLog(lru.NewSlowLog(*slowlogMaxLen, *slowlogThreshold, 0)))
+	}
+	if *namespaceSeparator != "" {
+		opts = append(opts, server.WithNamespaces(func(key string) string {
+			namespace, _, _ := strings.Cut(key, *namespaceSeparator)
+			return namespace
+		}))
+	}
+	if *replicas != "" {
+		consistency := server.FireAndForget
+		if *quorum {
+			consistency = server.Quorum
+		}
+		opts = append(opts, server.WithReplicas(strings.Split(*replicas, ","), consistency))
+	}
+	if *secretFile != "" {
+		data, err := os.ReadFile(*secretFile)
+		if err != nil {
+			logger.Error("reading the replication secret failed", "error", err)
+			os.Exit(1)
+		}
+		opts = append(opts, server.WithReplicationSecret(strings.TrimSpace(string(data))))
+	}
+	srv = server.New(cache, opts...)
+
+	var scheduler *lru.InvalidationScheduler
+	if *invalidate != "" {
+		// Through the server, so the clients tracking the keys and the replicas learn about the removals
+		scheduler = lru.NewInvalidationScheduler(srv, func(invalidation lru.Invalidation) {
+			logger.Info("invalidated", "prefix", invalidation.Prefix, "items", invalidation.Removed)
+		})
+		for _, entry := range strings.Split(*invalidate, ";") {
+			fields := strings.Fields(entry)
+			if len(fields) != 6 {
+				logger.Error("invalid -invalidate, expected a cron expression and a prefix", "value", entry)
+				os.Exit(2)
+			}
+			schedule, err := lru.Cron(strings.Join(fields[:5], " "), time.Local)
+			if err != nil {
+				logger.Error("invalid -invalidate", "value", entry, "error", err)
+				os.Exit(2)
+			}
+			if err := scheduler.InvalidatePrefix(schedule, fields[5]); err != nil {
+				logger.Error("invalid -invalidate", "value", entry, "error", err)
+				os.Exit(2)
+			}
+		}
+	}
+
+	var member *gossip.Member
+	if *gossipAddr != "" {
+		conn, err := net.ListenPacket("udp", *gossipAddr)
+		if err != nil {
+			logger.Error("gossip failed", "error", err)
+			os.Exit(1)
+		}
+		var seeds []string
+		if *join != "" {
+			seeds = strings.Split(*join, ",")
+		}
+		member = gossip.Join(conn, cmp.Or(*advertise, *addr), seeds, gossip.OnChange(func(addrs []string) {
+			logger.Info("cluster changed", "members", addrs)
+		}))
+	}
+
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		<-signals
+		logger.Info("shutting down")
+		if member != nil {
+			member.Leave()
+		}
+		srv.Close()
+		if scheduler != nil {
+			scheduler.Close()
+		}
+		cache.Close()
+		if backup != nil {
+			backup.Close()
+		}
+		if aof != nil {
+			aof.Close()
+		}
+	}()
+
+	logger.Info("listening", "addr", *addr, "capacity", *capacity, "shards", *shards)
+	if err := srv.ListenAndServe(*addr); err != nil && !errors.Is(err, server.ErrServerClosed) {
+		logger.Error("server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// importEntries loads the strings of the given source into the cache: a live server for the redis:// and memcached://
+// addresses, or an RDB file otherwise.
+func importEntries(source string, cache lru.Cache) (migrate.Report, error) {
+	if addr, found := strings.CutPrefix(source, "redis://"); found {
+		return migrate.ImportRedis(context.Background(), addr, cache)
+	}
+	if addr, found := strings.CutPrefix(source, "memcached://"); found {
+		return migrate.ImportMemcached(context.Background(), addr, cache)
+	}
+	file, err := os.Open(source)
+	if err != nil {
+		return migrate.Report{}, err
+	}
+	defer file.Close()
+	return migrate.ImportRDB(file, cache)
+}
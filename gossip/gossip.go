@@ -0,0 +1,303 @@
+// Package gossip discovers the servers of a cluster and detects their failures with a gossip protocol,
+// so the servers don't need static peer lists. Every member periodically increments its heartbeat and
+// sends its view of the cluster over UDP to a few random members, which merge it into their own.
+// A member whose heartbeat stops increasing is considered failed, and a member leaving gracefully
+// announces it, so the change reaches every member in a few rounds.
+//
+// The members advertise the address of their cache server, which clients can follow with
+// client.SetNodes in the OnChange callback.
+package gossip
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxMessageSize is the largest message sent or received, enough for about a thousand members.
+const maxMessageSize = 64 << 10
+
+// memberState is what a member knows about another, as sent in the messages.
+type memberState struct {
+	GossipAddr string `json:"gossip_addr"`    // UDP address of the member, its identity
+	Addr       string `json:"addr"`           // Advertised address of the cache server of the member
+	Heartbeat  uint64 `json:"heartbeat"`      // Incremented by the member on every round
+	Left       bool   `json:"left,omitempty"` // Whether the member left gracefully
+}
+
+// peer is a member known locally.
+type peer struct {
+	state     memberState
+	updatedAt time.Time // When the heartbeat last increased
+}
+
+// message is the view of the cluster gossiped by a member.
+type message struct {
+	Members []memberState `json:"members"`
+}
+
+// options holds the optional configuration of a Member.
+type options struct {
+	interval       time.Duration
+	fanout         int
+	failureTimeout time.Duration
+	onChange       func(addrs []string)
+}
+
+// Option configures a Member at construction time.
+type Option func(*options)
+
+// WithInterval sets how often the member gossips. Defaults to 200 milliseconds.
+func WithInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.interval = interval
+	}
+}
+
+// WithFanout sets to how many random members every round is sent. Defaults to 3.
+func WithFanout(fanout int) Option {
+	return func(o *options) {
+		o.fanout = fanout
+	}
+}
+
+// WithFailureTimeout sets after how long without a heartbeat a member is considered failed.
+// Failed members are forgotten after twice this timeout. Defaults to 10 gossip intervals.
+func WithFailureTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.failureTimeout = timeout
+	}
+}
+
+// OnChange registers a callback receiving the sorted addresses of the live members, the local
+// one included, every time a member joins, leaves or fails. It is called from the gossip goroutine.
+func OnChange(callback func(addrs []string)) Option {
+	return func(o *options) {
+		o.onChange = callback
+	}
+}
+
+// Member takes part in the gossip of a cluster.
+type Member struct {
+	conn  net.PacketConn
+	self  memberState
+	seeds []string // Gossip addresses contacted until their members are known
+	opts  options
+
+	mutex         sync.Mutex
+	peers         map[string]*peer // Known members by gossip address, the local one excluded
+	live          []string         // Last addresses reported to OnChange
+	callbackMutex sync.Mutex       // Keeps the OnChange calls in the order of the changes
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// Join starts gossiping on the UDP connection, advertising addr as the address of the local cache server.
+// The seeds are the gossip addresses of a few members of the cluster, it can be empty for the first member.
+func Join(conn net.PacketConn, addr string, seeds []string, opts ...Option) *Member {
+	o := options{interval: 200 * time.Millisecond, fanout: 3}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.failureTimeout <= 0 {
+		o.failureTimeout = 10 * o.interval
+	}
+
+	member := &Member{
+		conn:  conn,
+		self:  memberState{GossipAddr: conn.LocalAddr().String(), Addr: addr},
+		seeds: seeds,
+		opts:  o,
+		peers: make(map[string]*peer),
+		live:  []string{addr},
+		stop:  make(chan struct{}),
+	}
+	member.done.Add(2)
+	go member.receive()
+	go member.gossip()
+	return member
+}
+
+// Members returns the sorted addresses of the live members, the local one included.
+func (member *Member) Members() []string {
+	member.mutex.Lock()
+	defer member.mutex.Unlock()
+
+	return member.liveAddrs()
+}
+
+// Leave announces to the cluster that the member leaves, then stops gossiping and closes the connection.
+func (member *Member) Leave() error {
+	member.mutex.Lock()
+	member.self.Heartbeat++
+	member.self.Left = true
+	member.mutex.Unlock()
+
+	member.send(member.targets(member.opts.fanout * 2)) // Reach more members, there is no next round
+	return member.Close()
+}
+
+// Close stops gossiping and closes the connection, without announcing it.
+// The other members consider the member failed after the failure timeout.
+func (member *Member) Close() error {
+	select {
+	case <-member.stop:
+		return nil // Already closed
+	default:
+		close(member.stop)
+	}
+	err := member.conn.Close()
+	member.done.Wait()
+	return err
+}
+
+// gossip runs the rounds until the member is closed.
+func (member *Member) gossip() {
+	defer member.done.Done()
+
+	ticker := time.NewTicker(member.opts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			member.mutex.Lock()
+			member.self.Heartbeat++
+			member.mutex.Unlock()
+
+			member.send(member.targets(member.opts.fanout))
+			member.expire()
+		case <-member.stop:
+			return
+		}
+	}
+}
+
+// targets picks up to n random live members to gossip with, falling back to the seeds
+// while no member is known.
+func (member *Member) targets(n int) []string {
+	member.mutex.Lock()
+	defer member.mutex.Unlock()
+
+	var candidates []string
+	for gossipAddr, peer := range member.peers {
+		if member.alive(peer) {
+			candidates = append(candidates, gossipAddr)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = slices.Clone(member.seeds)
+	}
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	return candidates[:min(n, len(candidates))]
+}
+
+// send gossips the local view of the cluster to the given members.
+// Failed members are not gossiped, so they are not spread again once the cluster forgets them.
+func (member *Member) send(targets []string) {
+	member.mutex.Lock()
+	msg := message{Members: []memberState{member.self}}
+	for _, peer := range member.peers {
+		if time.Since(peer.updatedAt) <= member.opts.failureTimeout {
+			msg.Members = append(msg.Members, peer.state)
+		}
+	}
+	member.mutex.Unlock()
+
+	data, err := json.Marshal(msg)
+	if err != nil || len(data) > maxMessageSize {
+		return
+	}
+	for _, target := range targets {
+		if addr, err := net.ResolveUDPAddr("udp", target); err == nil {
+			member.conn.WriteTo(data, addr) // Lost messages are made up for by the next rounds
+		}
+	}
+}
+
+// receive merges the messages of the other members until the connection is closed.
+func (member *Member) receive() {
+	defer member.done.Done()
+
+	buffer := make([]byte, maxMessageSize)
+	for {
+		n, _, err := member.conn.ReadFrom(buffer)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		var msg message
+		if json.Unmarshal(buffer[:n], &msg) == nil {
+			member.merge(msg.Members)
+		}
+	}
+}
+
+// merge updates the known members with the states of a message, keeping the highest heartbeats.
+func (member *Member) merge(states []memberState) {
+	now := time.Now()
+	member.mutex.Lock()
+	for _, state := range states {
+		if state.GossipAddr == member.self.GossipAddr {
+			continue
+		}
+		known, found := member.peers[state.GossipAddr]
+		if !found {
+			member.peers[state.GossipAddr] = &peer{state: state, updatedAt: now}
+		} else if state.Heartbeat > known.state.Heartbeat {
+			known.state, known.updatedAt = state, now
+		}
+	}
+	member.notify()
+}
+
+// expire forgets the members failed for twice the failure timeout, then reports the changes.
+// Until then, a failed member is only revived by a higher heartbeat, so stale messages cannot revive it.
+// A forgotten member reappears if it is still alive, as its heartbeat keeps increasing.
+func (member *Member) expire() {
+	member.mutex.Lock()
+	for gossipAddr, peer := range member.peers {
+		if time.Since(peer.updatedAt) > 2*member.opts.failureTimeout {
+			delete(member.peers, gossipAddr)
+		}
+	}
+	member.notify()
+}
+
+// notify calls OnChange if the live members changed. It must be called with the mutex held,
+// and unlocks it before calling the callback.
+func (member *Member) notify() {
+	live := member.liveAddrs()
+	changed := !slices.Equal(live, member.live)
+	member.live = live
+	member.callbackMutex.Lock()
+	member.mutex.Unlock()
+	defer member.callbackMutex.Unlock()
+
+	if changed && member.opts.onChange != nil {
+		member.opts.onChange(live)
+	}
+}
+
+// alive reports whether a member is live: it did not leave and its heartbeat increased recently.
+func (member *Member) alive(peer *peer) bool {
+	return !peer.state.Left && time.Since(peer.updatedAt) <= member.opts.failureTimeout
+}
+
+// liveAddrs returns the sorted addresses of the live members. It must be called with the mutex held.
+func (member *Member) liveAddrs() []string {
+	addrs := []string{member.self.Addr}
+	for _, peer := range member.peers {
+		if member.alive(peer) {
+			addrs = append(addrs, peer.state.Addr)
+		}
+	}
+	slices.Sort(addrs)
+	return addrs
+}
@@ -0,0 +1,54 @@
+package gossip
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// join starts a member on a random local UDP port, advertising addr.
+func join(t *testing.T, addr string, seeds []string, opts ...Option) *Member {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	opts = append([]Option{WithInterval(10 * time.Millisecond)}, opts...)
+	member := Join(conn, addr, seeds, opts...)
+	t.Cleanup(func() { member.Close() })
+	return member
+}
+
+func TestMembersDiscoverEachOther(t *testing.T) {
+	first := join(t, "a:6380", nil)
+	second := join(t, "b:6380", []string{first.self.GossipAddr})
+	third := join(t, "c:6380", []string{second.self.GossipAddr}) // Only knows the second member
+
+	for _, member := range []*Member{first, second, third} {
+		assert.Eventually(t, func() bool { return len(member.Members()) == 3 }, time.Second, 5*time.Millisecond)
+	}
+	assert.Equal(t, []string{"a:6380", "b:6380", "c:6380"}, first.Members())
+}
+
+func TestFailedMemberIsRemoved(t *testing.T) {
+	first := join(t, "a:6380", nil, WithFailureTimeout(100*time.Millisecond))
+	second := join(t, "b:6380", []string{first.self.GossipAddr}, WithFailureTimeout(100*time.Millisecond))
+	assert.Eventually(t, func() bool { return len(first.Members()) == 2 }, time.Second, 5*time.Millisecond)
+
+	second.Close() // Crashes without announcing it
+	assert.Eventually(t, func() bool { return len(first.Members()) == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestLeavingMemberIsRemovedQuickly(t *testing.T) {
+	changes := make(chan []string, 10)
+	first := join(t, "a:6380", nil, WithFailureTimeout(time.Hour), OnChange(func(addrs []string) { changes <- addrs }))
+	second := join(t, "b:6380", []string{first.self.GossipAddr})
+	assert.Equal(t, []string{"a:6380", "b:6380"}, <-changes)
+
+	assert.NoError(t, second.Leave())
+	select {
+	case addrs := <-changes:
+		assert.Equal(t, []string{"a:6380"}, addrs)
+	case <-time.After(time.Second):
+		t.Fatal("the departure was not reported")
+	}
+}
@@ -0,0 +1,216 @@
+// Package resp implements the subset of the Redis serialization protocol (RESP2) spoken by the
+// cache server and its client: commands are arrays of bulk strings, replies can be any RESP2 type.
+// The push type of RESP3 is also supported, for the messages sent by the server unprompted.
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxBulkLength  = 512 << 20 // Longest bulk string accepted, the same limit as Redis
+	maxArrayLength = 1 << 20   // Most elements accepted in an array
+)
+
+// ErrProtocol is returned when the peer sends data that is not valid RESP2.
+var ErrProtocol = errors.New("resp: protocol error")
+
+// Kind is the type of a RESP2 value, identified by its first byte on the wire.
+type Kind byte
+
+const (
+	SimpleString Kind = '+'
+	Error        Kind = '-'
+	Integer      Kind = ':'
+	BulkString   Kind = '$'
+	Array        Kind = '*'
+	Push         Kind = '>' // RESP3 out of band message, shaped like an array
+)
+
+// Value is a RESP2 value.
+type Value struct {
+	Kind  Kind
+	Str   string  // Content of simple strings, errors and bulk strings
+	Int   int64   // Content of integers
+	Array []Value // Elements of arrays and push messages
+	Null  bool    // Whether the bulk string or array is null
+}
+
+// Reader reads RESP2 values from a connection.
+type Reader struct {
+	reader *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{reader: bufio.NewReader(r)}
+}
+
+// Buffered returns the number of bytes received and not read yet, more than zero when
+// the peer pipelined several commands.
+func (reader *Reader) Buffered() int {
+	return reader.reader.Buffered()
+}
+
+// ReadCommand reads a command, either as an array of bulk strings or as an inline command,
+// the space separated form typed in a telnet session.
+func (reader *Reader) ReadCommand() ([]string, error) {
+	first, err := reader.reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if Kind(first[0]) != Array {
+		line, err := reader.readLine()
+		if err != nil {
+			return nil, err
+		}
+		return strings.Fields(line), nil
+	}
+
+	value, err := reader.ReadValue()
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, len(value.Array))
+	for i, arg := range value.Array {
+		if arg.Kind != BulkString || arg.Null {
+			return nil, fmt.Errorf("%w: command arguments must be bulk strings", ErrProtocol)
+		}
+		args[i] = arg.Str
+	}
+	return args, nil
+}
+
+// ReadValue reads a single value, including every element of an array.
+func (reader *Reader) ReadValue() (Value, error) {
+	line, err := reader.readLine()
+	if err != nil {
+		return Value{}, err
+	}
+	if line == "" {
+		return Value{}, fmt.Errorf("%w: empty line", ErrProtocol)
+	}
+
+	value := Value{Kind: Kind(line[0])}
+	switch value.Kind {
+	case SimpleString, Error:
+		value.Str = line[1:]
+	case Integer:
+		if value.Int, err = strconv.ParseInt(line[1:], 10, 64); err != nil {
+			return Value{}, fmt.Errorf("%w: invalid integer", ErrProtocol)
+		}
+	case BulkString:
+		length, err := parseLength(line[1:], maxBulkLength)
+		if err != nil || length < 0 {
+			value.Null = length < 0
+			return value, err
+		}
+		data := make([]byte, length+2) // Followed by CRLF
+		if _, err := io.ReadFull(reader.reader, data); err != nil {
+			return Value{}, err
+		}
+		value.Str = string(data[:length])
+	case Array, Push:
+		length, err := parseLength(line[1:], maxArrayLength)
+		if err != nil || length < 0 {
+			value.Null = length < 0
+			return value, err
+		}
+		value.Array = make([]Value, length)
+		for i := range value.Array {
+			if value.Array[i], err = reader.ReadValue(); err != nil {
+				return Value{}, err
+			}
+		}
+	default:
+		return Value{}, fmt.Errorf("%w: unknown type %q", ErrProtocol, line[0])
+	}
+	return value, nil
+}
+
+// readLine reads a line, without its CRLF terminator.
+func (reader *Reader) readLine() (string, error) {
+	line, err := reader.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// parseLength parses the length of a bulk string or an array, -1 meaning null.
+func parseLength(text string, limit int) (int, error) {
+	length, err := strconv.Atoi(text)
+	if err != nil || length < -1 || length > limit {
+		return 0, fmt.Errorf("%w: invalid length", ErrProtocol)
+	}
+	return length, nil
+}
+
+// Writer writes RESP2 values to a connection. The values are buffered until Flush is called.
+type Writer struct {
+	writer *bufio.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{writer: bufio.NewWriter(w)}
+}
+
+// WriteSimpleString writes a simple string, e.g. "OK". It must not contain CR nor LF.
+func (writer *Writer) WriteSimpleString(text string) {
+	writer.writeLine(SimpleString, text)
+}
+
+// WriteError writes an error. By convention, its first word is an error code such as "ERR".
+func (writer *Writer) WriteError(message string) {
+	writer.writeLine(Error, message)
+}
+
+// WriteInteger writes an integer.
+func (writer *Writer) WriteInteger(n int64) {
+	writer.writeLine(Integer, strconv.FormatInt(n, 10))
+}
+
+// WriteBulkString writes a binary safe string.
+func (writer *Writer) WriteBulkString(text string) {
+	writer.writeLine(BulkString, strconv.Itoa(len(text)))
+	writer.writer.WriteString(text)
+	writer.writer.WriteString("\r\n")
+}
+
+// WriteNull writes a null bulk string, the reply for a missing key.
+func (writer *Writer) WriteNull() {
+	writer.writeLine(BulkString, "-1")
+}
+
+// WriteArrayHeader starts an array of n elements, which must be written next.
+func (writer *Writer) WriteArrayHeader(n int) {
+	writer.writeLine(Array, strconv.Itoa(n))
+}
+
+// WritePushHeader starts a push message of n elements, which must be written next.
+func (writer *Writer) WritePushHeader(n int) {
+	writer.writeLine(Push, strconv.Itoa(n))
+}
+
+// WriteCommand writes a command as an array of bulk strings.
+func (writer *Writer) WriteCommand(args ...string) {
+	writer.WriteArrayHeader(len(args))
+	for _, arg := range args {
+		writer.WriteBulkString(arg)
+	}
+}
+
+// Flush sends the buffered values.
+func (writer *Writer) Flush() error {
+	return writer.writer.Flush()
+}
+
+func (writer *Writer) writeLine(kind Kind, text string) {
+	writer.writer.WriteByte(byte(kind))
+	writer.writer.WriteString(text)
+	writer.writer.WriteString("\r\n")
+}
@@ -0,0 +1,53 @@
+package resp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAndReadCommand(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := NewWriter(&buffer)
+	writer.WriteCommand("SET", "key", "value with spaces\r\n")
+	assert.NoError(t, writer.Flush())
+
+	args, err := NewReader(&buffer).ReadCommand()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"SET", "key", "value with spaces\r\n"}, args)
+}
+
+func TestReadInlineCommand(t *testing.T) {
+	args, err := NewReader(strings.NewReader("GET  key\r\n")).ReadCommand()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"GET", "key"}, args)
+}
+
+func TestReadValues(t *testing.T) {
+	reader := NewReader(strings.NewReader("+OK\r\n-ERR oops\r\n:42\r\n$-1\r\n*2\r\n$1\r\na\r\n:1\r\n"))
+
+	value, err := reader.ReadValue()
+	assert.NoError(t, err)
+	assert.Equal(t, Value{Kind: SimpleString, Str: "OK"}, value)
+
+	value, _ = reader.ReadValue()
+	assert.Equal(t, Value{Kind: Error, Str: "ERR oops"}, value)
+
+	value, _ = reader.ReadValue()
+	assert.Equal(t, Value{Kind: Integer, Int: 42}, value)
+
+	value, _ = reader.ReadValue()
+	assert.True(t, value.Null)
+
+	value, _ = reader.ReadValue()
+	assert.Equal(t, Value{Kind: Array, Array: []Value{{Kind: BulkString, Str: "a"}, {Kind: Integer, Int: 1}}}, value)
+}
+
+func TestReadInvalidValues(t *testing.T) {
+	for _, input := range []string{"?\r\n", ":abc\r\n", "$-2\r\n", "*99999999\r\n"} {
+		_, err := NewReader(strings.NewReader(input)).ReadValue()
+		assert.ErrorIs(t, err, ErrProtocol, input)
+	}
+}
@@ -0,0 +1,181 @@
+// Package lookupcache caches host name lookups on top of lru.LoadingCache.
+// Successful lookups are cached for their TTL and "no such host" answers, like the lookups returning
+// no address, are cached for a shorter negative TTL, so a burst of dials to the same host costs a single lookup.
+package lookupcache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"time"
+
+	"caching/lru"
+)
+
+// LookupFunc resolves a host name, returning its addresses and how long they may be cached.
+// A ttl of zero or less means the default TTL of the cache is used.
+type LookupFunc func(ctx context.Context, host string) (addrs []netip.Addr, ttl time.Duration, err error)
+
+// negativeResult is cached in place of the addresses of a host that does not exist.
+type negativeResult struct {
+	err error // The error returned by the lookup
+}
+
+// options holds the optional configuration of the cache.
+type options struct {
+	lookup      LookupFunc
+	ttl         time.Duration // TTL of successful lookups that don't provide their own
+	negativeTTL time.Duration // TTL of "no such host" answers, zero disables negative caching
+	cacheOpts   []lru.Option  // Options of the underlying cache
+}
+
+// Option configures a Cache at construction time.
+type Option func(*options)
+
+// WithResolver resolves host names with the given resolver instead of net.DefaultResolver.
+// The resolver does not report record TTLs, so the default TTL of the cache is used.
+func WithResolver(resolver *net.Resolver) Option {
+	return func(o *options) {
+		o.lookup = resolverLookup(resolver)
+	}
+}
+
+// WithLookupFunc resolves host names with a custom function, e.g. a DNS client reporting per-record TTLs.
+func WithLookupFunc(lookup LookupFunc) Option {
+	return func(o *options) {
+		o.lookup = lookup
+	}
+}
+
+// WithTTL sets how long successful lookups are cached when the lookup does not report a TTL.
+// Defaults to 30 seconds.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithNegativeTTL sets how long "no such host" answers are cached. Defaults to 5 seconds, zero disables it.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithCacheOptions passes options to the underlying cache, e.g. lru.WithClock.
+func WithCacheOptions(opts ...lru.Option) Option {
+	return func(o *options) {
+		o.cacheOpts = append(o.cacheOpts, opts...)
+	}
+}
+
+// Cache caches the results of host name lookups.
+type Cache struct {
+	loading *lru.LoadingCache
+	options options
+}
+
+// New creates a Cache holding the lookups of up to capacity host names.
+func New(capacity int, opts ...Option) *Cache {
+	o := options{
+		lookup:      resolverLookup(net.DefaultResolver),
+		ttl:         30 * time.Second,
+		negativeTTL: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	c := &Cache{options: o}
+	c.loading = lru.NewLoadingCache(lru.NewSafeLRUCache(capacity, o.cacheOpts...), c.load)
+	return c
+}
+
+// resolverLookup adapts a net.Resolver to a LookupFunc.
+func resolverLookup(resolver *net.Resolver) LookupFunc {
+	return func(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+		addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+		return addrs, 0, err
+	}
+}
+
+// load is the loader of the underlying LoadingCache.
+func (c *Cache) load(ctx context.Context, host string) (any, time.Duration, error) {
+	addrs, ttl, err := c.options.lookup(ctx, host)
+	if err == nil && len(addrs) == 0 { // Nothing to dial, answered like a host that does not exist
+		err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	if err != nil {
+		var dnsErr *net.DNSError
+		if c.options.negativeTTL > 0 && errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return negativeResult{err: err}, c.options.negativeTTL, nil
+		}
+		return nil, 0, err
+	}
+
+	if ttl <= 0 {
+		ttl = c.options.ttl
+	}
+	return addrs, ttl, nil
+}
+
+// LookupNetIP returns the addresses of the host, from the cache when possible.
+func (c *Cache) LookupNetIP(ctx context.Context, host string) ([]netip.Addr, error) {
+	value, err := c.loading.Get(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if negative, ok := value.(negativeResult); ok {
+		return nil, negative.err
+	}
+	return value.([]netip.Addr), nil
+}
+
+// LookupHost returns the addresses of the host as strings, like net.Resolver.LookupHost.
+func (c *Cache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, err := c.LookupNetIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		hosts = append(hosts, addr.String())
+	}
+	return hosts, nil
+}
+
+// Invalidate forgets the cached lookup of the host, e.g. after a connection failure.
+func (c *Cache) Invalidate(host string) {
+	c.loading.Cache().Remove(host)
+}
+
+// DialContext returns a function usable as http.Transport.DialContext, resolving host names
+// through the cache and trying each address in turn until a connection succeeds.
+func (c *Cache) DialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := netip.ParseAddr(host); err == nil {
+			return dialer.DialContext(ctx, network, address) // Already an IP address
+		}
+
+		addrs, err := c.LookupNetIP(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var errs []error
+		for _, addr := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+		}
+		return nil, errors.Join(errs...)
+	}
+}
@@ -0,0 +1,101 @@
+package lookupcache
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"caching/lru"
+)
+
+func TestLookupIsCachedForItsTTL(t *testing.T) {
+	clock := lru.NewManualClock(time.Now())
+	lookups := 0
+	cache := New(5, WithCacheOptions(lru.WithClock(clock)), WithLookupFunc(func(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+		lookups++
+		return []netip.Addr{netip.MustParseAddr("192.0.2.1")}, time.Minute, nil
+	}))
+
+	hosts, err := cache.LookupHost(context.Background(), "example.test")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.1"}, hosts)
+
+	cache.LookupHost(context.Background(), "example.test")
+	assert.Equal(t, 1, lookups)
+
+	clock.Advance(2 * time.Minute)
+	cache.LookupHost(context.Background(), "example.test")
+	assert.Equal(t, 2, lookups, "The record expired, so it should be looked up again")
+}
+
+func TestNegativeCaching(t *testing.T) {
+	clock := lru.NewManualClock(time.Now())
+	lookups := 0
+	cache := New(5, WithNegativeTTL(time.Second), WithCacheOptions(lru.WithClock(clock)), WithLookupFunc(func(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+		lookups++
+		return nil, 0, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}))
+
+	_, err := cache.LookupNetIP(context.Background(), "missing.test")
+	assert.Error(t, err)
+	_, err = cache.LookupNetIP(context.Background(), "missing.test")
+	assert.Error(t, err)
+	assert.Equal(t, 1, lookups)
+
+	clock.Advance(2 * time.Second)
+	cache.LookupNetIP(context.Background(), "missing.test")
+	assert.Equal(t, 2, lookups)
+}
+
+func TestTemporaryErrorsAreNotCached(t *testing.T) {
+	lookups := 0
+	cache := New(5, WithLookupFunc(func(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+		lookups++
+		return nil, 0, &net.DNSError{Err: "timeout", Name: host, IsTimeout: true}
+	}))
+
+	cache.LookupNetIP(context.Background(), "slow.test")
+	cache.LookupNetIP(context.Background(), "slow.test")
+	assert.Equal(t, 2, lookups)
+}
+
+func TestDialContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+
+	cache := New(5, WithLookupFunc(func(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+		return []netip.Addr{netip.MustParseAddr("127.0.0.1")}, 0, nil
+	}))
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	conn, err := cache.DialContext(&net.Dialer{})(context.Background(), "tcp", net.JoinHostPort("example.test", port))
+	assert.NoError(t, err)
+	conn.Close()
+}
+
+func TestDialContextWithoutAddresses(t *testing.T) {
+	lookups := 0
+	cache := New(5, WithLookupFunc(func(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+		lookups++
+		return nil, 0, nil
+	}))
+
+	conn, err := cache.DialContext(&net.Dialer{})(context.Background(), "tcp", "empty.test:80")
+	assert.Nil(t, conn)
+	var dnsErr *net.DNSError
+	assert.ErrorAs(t, err, &dnsErr)
+	assert.True(t, dnsErr.IsNotFound)
+
+	cache.LookupNetIP(context.Background(), "empty.test")
+	assert.Equal(t, 1, lookups, "Expected the empty answer to be cached like a missing host")
+}
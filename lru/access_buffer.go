@@ -0,0 +1,55 @@
+package lru
+
+// batchedReader is implemented by the caches able to serve reads without updating their usage order,
+// letting SafeLRUCache serve Get under a read lock and apply the promotions later in batches.
+type batchedReader interface {
+	// peek returns the value of a live item without modifying the cache. Expired items are not found.
+	peek(key string) (value any, found bool)
+	// promote marks the given keys as accessed, in order. Keys no longer in the cache are ignored.
+	promote(keys []string)
+}
+
+// accessBuffer records the keys read by a SafeLRUCache until their promotions are applied.
+type accessBuffer struct {
+	reader batchedReader // The underlying cache
+	keys   chan string   // Keys read and not yet promoted, shared by concurrent readers
+	batch  []string      // Reused between batches, only accessed under the write lock
+}
+
+func newAccessBuffer(reader batchedReader, size int) *accessBuffer {
+	return &accessBuffer{
+		reader: reader,
+		keys:   make(chan string, size),
+		batch:  make([]string, 0, size),
+	}
+}
+
+// record adds a read key to the buffer without blocking.
+// It returns false if the buffer is full and the access was dropped.
+func (buffer *accessBuffer) record(key string) bool {
+	select {
+	case buffer.keys <- key:
+		return true
+	default:
+		return false
+	}
+}
+
+// apply promotes every buffered key. The cache must be locked for writing.
+func (buffer *accessBuffer) apply() {
+	batch := buffer.batch[:0]
+	for drained := false; !drained; {
+		select {
+		case key := <-buffer.keys:
+			batch = append(batch, key)
+		default:
+			drained = true
+		}
+	}
+
+	if len(batch) > 0 {
+		buffer.reader.promote(batch)
+	}
+	clear(batch) // Don't retain the keys until the next batch
+	buffer.batch = batch[:0]
+}
@@ -0,0 +1,38 @@
+package lru
+
+import (
+	"errors"
+)
+
+// ErrDenied is returned by Increment when the admission hook refuses the counter.
+var ErrDenied = errors.New("lru: item refused by the admission hook")
+
+// AdmitFunc decides whether an item may be written to the cache, given its weight from WithWeigher,
+// e.g. to never cache the values above a size or the keys with a given prefix.
+type AdmitFunc func(key string, value any, weight int64) bool
+
+// admission applies the AdmitFunc of WithAdmission. A nil *admission admits every item.
+type admission struct {
+	admit AdmitFunc  // Decides whether the items are admitted
+	weigh WeightFunc // Weight of the items, nil to weigh every item 1
+}
+
+// newAdmission creates the admission of the given options, nil without WithAdmission.
+func newAdmission(o options) *admission {
+	if o.admit == nil {
+		return nil
+	}
+	return &admission{admit: o.admit, weigh: o.weigh}
+}
+
+// admits returns whether the item may be written.
+func (admission *admission) admits(key string, value any) bool {
+	if admission == nil {
+		return true
+	}
+	weight := int64(1)
+	if admission.weigh != nil {
+		weight = admission.weigh(key, value)
+	}
+	return admission.admit(key, value, weight)
+}
@@ -0,0 +1,40 @@
+package lru
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmission(t *testing.T) {
+	admit := func(key string, value any, weight int64) bool {
+		return !strings.HasPrefix(key, "tmp:") && weight <= 5
+	}
+	size := func(key string, value any) int64 {
+		if s, ok := value.(string); ok {
+			return int64(len(s))
+		}
+		return 1
+	}
+	caches := map[string]Cache{
+		"lru":     NewLRUCache(2, WithAdmission(admit), WithWeigher(size)),
+		"lfu":     NewLFUCache(2, WithAdmission(admit), WithWeigher(size)),
+		"sharded": NewShardedCache(2, 4, WithAdmission(admit), WithWeigher(size)),
+	}
+	for name, cache := range caches {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, SetAdded, cache.Set("key1", "small").Status)
+			assert.Equal(t, SetResult{Status: SetDenied}, cache.Set("tmp:key", "small"))
+			assert.Equal(t, SetDenied, cache.Set("key2", "too large").Status)
+			assert.Equal(t, 1, cache.Len())
+
+			assert.Equal(t, SetDenied, cache.Set("key1", "too large").Status)
+			_, found := cache.Get("key1")
+			assert.False(t, found, "Expected a refused update to remove the outdated item")
+
+			_, err := cache.(Incrementer).Increment("tmp:counter", 1, 0)
+			assert.ErrorIs(t, err, ErrDenied)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package lru
+
+// Batcher is implemented by the caches reading or writing several keys with a single lock acquisition,
+// one per shard for a ShardedCache, instead of one per key.
+type Batcher interface {
+	// GetMany returns the values of the keys, in order, and whether each one was found, like Get does.
+	GetMany(keys []string) (values []any, found []bool)
+	// SetMany adds or updates the items with no expiration, values[i] being the value of keys[i].
+	// The writes are applied in order, so the last value of a repeated key wins.
+	SetMany(keys []string, values []any)
+}
+
+var _ Batcher = (*SafeLRUCache)(nil) // Ensure SafeLRUCache supports batches
+var _ Batcher = (*ShardedCache)(nil) // Ensure ShardedCache supports batches
+
+// GetMany retrieves the items of the keys under a single lock acquisition, promoting them like Get does.
+// With an access buffer, the items are read under the read lock and promoted later.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) GetMany(keys []string) (values []any, found []bool) {
+	values, found = make([]any, len(keys)), make([]bool, len(keys))
+	if safeCache.accesses != nil {
+		full := false
+		safeCache.readLock()
+		for i, key := range keys {
+			key = safeCache.transform.apply(key)
+			values[i], found[i] = safeCache.accesses.reader.peek(key)
+			full = (found[i] && !safeCache.accesses.record(key)) || full
+		}
+		safeCache.mutex.RUnlock()
+
+		if full {
+			safeCache.writeLock()
+			safeCache.accesses.apply()
+			safeCache.mutex.Unlock()
+		}
+		return values, found
+	}
+
+	safeCache.writeLock()
+	defer safeCache.mutex.Unlock()
+
+	for i, key := range keys {
+		values[i], found[i] = safeCache.cache.Get(safeCache.transform.apply(key))
+	}
+	return values, found
+}
+
+// SetMany adds or updates the items with no expiration under a single lock acquisition.
+// With a write buffer, the writes are queued like Set does.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) SetMany(keys []string, values []any) {
+	if safeCache.writes != nil {
+		for i, key := range keys {
+			safeCache.Set(key, values[i])
+		}
+		return
+	}
+
+	safeCache.lock()
+	defer safeCache.mutex.Unlock()
+
+	for i, key := range keys {
+		safeCache.cache.Set(safeCache.transform.apply(key), values[i])
+	}
+}
+
+// GetMany retrieves the items of the keys, locking every shard holding some of them once.
+// It is thread-safe.
+func (sharded *ShardedCache) GetMany(keys []string) (values []any, found []bool) {
+	values, found = make([]any, len(keys)), make([]bool, len(keys))
+	for shard, indexes := range sharded.group(keys) {
+		shardKeys := make([]string, len(indexes))
+		for i, index := range indexes {
+			shardKeys[i] = sharded.transform.apply(keys[index])
+		}
+		shardValues, shardFound := shard.GetMany(shardKeys)
+		for i, index := range indexes {
+			values[index], found[index] = shardValues[i], shardFound[i]
+		}
+	}
+	return values, found
+}
+
+// SetMany adds or updates the items with no expiration, locking every shard holding some of them once.
+// It is thread-safe.
+func (sharded *ShardedCache) SetMany(keys []string, values []any) {
+	for shard, indexes := range sharded.group(keys) {
+		shardKeys, shardValues := make([]string, len(indexes)), make([]any, len(indexes))
+		for i, index := range indexes {
+			shardKeys[i], shardValues[i] = sharded.transform.apply(keys[index]), values[index]
+		}
+		shard.SetMany(shardKeys, shardValues)
+	}
+}
+
+// group returns the indexes of the keys by the shard holding them, in the order of the keys.
+func (sharded *ShardedCache) group(keys []string) map[*SafeLRUCache][]int {
+	groups := make(map[*SafeLRUCache][]int)
+	for i, key := range keys {
+		shard := sharded.shard(sharded.transform.apply(key))
+		groups[shard] = append(groups[shard], i)
+	}
+	return groups
+}
@@ -0,0 +1,44 @@
+package lru
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatches(t *testing.T) {
+	caches := map[string]Batcher{
+		"safe":          NewSafeLRUCache(100),
+		"access buffer": NewSafeLRUCache(100, WithAccessBuffer(2)),
+		"sharded":       NewShardedCache(4, 100),
+	}
+	for name, cache := range caches {
+		t.Run(name, func(t *testing.T) {
+			var keys []string
+			var values []any
+			for i := range 10 {
+				keys = append(keys, fmt.Sprintf("key%d", i))
+				values = append(values, i)
+			}
+			cache.SetMany(append(keys, "key0"), append(values, 42)) // The last value of a repeated key wins
+
+			values, found := cache.GetMany([]string{"key0", "missing", "key9", "key5"})
+			assert.Equal(t, []any{42, nil, 9, 5}, values)
+			assert.Equal(t, []bool{true, false, true, true}, found)
+			assert.Equal(t, 10, cache.(Cache).Len())
+		})
+	}
+}
+
+func TestGetManyPromotes(t *testing.T) {
+	cache := NewSafeLRUCache(2)
+	cache.SetMany([]string{"key1", "key2"}, []any{1, 2})
+	cache.GetMany([]string{"key1"})
+	cache.Set("key3", 3) // Evicts key2, read less recently than key1
+
+	_, found := cache.Get("key2")
+	assert.False(t, found)
+	_, found = cache.Get("key1")
+	assert.True(t, found)
+}
@@ -0,0 +1,235 @@
+package lru
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrCircuitOpen is returned by LoadingCache.Get when the circuit breaker short-circuits the load of a key
+// and no stale value is available.
+var ErrCircuitOpen = errors.New("lru: circuit breaker open")
+
+// maxBreakerKeys bounds the keys whose failures are counted by WithKeyCircuitBreaker,
+// the failures of the keys beyond it only count towards the global circuit.
+const maxBreakerKeys = 10000
+
+// BreakerState is the state of a circuit of the breaker of a LoadingCache.
+type BreakerState int
+
+const (
+	// BreakerClosed lets the loads through, counting their consecutive failures.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen short-circuits the loads until the cooldown elapsed.
+	BreakerOpen
+	// BreakerHalfOpen lets a single load probe the backend once the cooldown elapsed:
+	// its success closes the circuit, its failure opens it again.
+	BreakerHalfOpen
+)
+
+// String returns the name of the state, e.g. open.
+func (state BreakerState) String() string {
+	switch state {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// breakerOptions configures a circuit of the breaker, zero failures disabling it.
+type breakerOptions struct {
+	failures int           // Consecutive failures opening the circuit
+	cooldown time.Duration // How long the circuit stays open before a load probes the backend
+}
+
+// WithCircuitBreaker makes a LoadingCache stop calling its loader after the given number of consecutive
+// failures, whatever their keys, for the cooldown. Meanwhile, Get serves the expired value still held
+// by the underlying cache, if it is a Peeker, or fails with ErrCircuitOpen. Once the cooldown elapsed,
+// a single load probes the loader, closing the circuit if it succeeds. Ignored by the caches.
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(o *options) {
+		o.breaker = breakerOptions{failures: failures, cooldown: cooldown}
+	}
+}
+
+// WithKeyCircuitBreaker is like WithCircuitBreaker, counting the consecutive failures of every key apart,
+// so a key whose loads keep failing does not reach the loader for the cooldown, without affecting the other keys.
+// It can be combined with WithCircuitBreaker. Ignored by the caches.
+func WithKeyCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(o *options) {
+		o.keyBreaker = breakerOptions{failures: failures, cooldown: cooldown}
+	}
+}
+
+// circuit counts the consecutive failures of the loads it covers.
+type circuit struct {
+	failures int       // Consecutive failures
+	openedAt time.Time // When the circuit opened, zero while closed
+	probing  bool      // Whether a load probes the backend after the cooldown
+}
+
+// state returns the state of the circuit at the given time.
+func (circuit *circuit) state(now time.Time, cooldown time.Duration) BreakerState {
+	switch {
+	case circuit == nil || circuit.openedAt.IsZero():
+		return BreakerClosed
+	case now.Sub(circuit.openedAt) < cooldown:
+		return BreakerOpen
+	default:
+		return BreakerHalfOpen
+	}
+}
+
+// rejects returns whether the circuit short-circuits a load at the given time:
+// while open, or while another load probes the backend.
+func (circuit *circuit) rejects(now time.Time, cooldown time.Duration) bool {
+	state := circuit.state(now, cooldown)
+	return state == BreakerOpen || state == BreakerHalfOpen && circuit.probing
+}
+
+// circuitBreaker holds the global circuit and the circuits of the keys of a LoadingCache.
+type circuitBreaker struct {
+	global    breakerOptions
+	perKey    breakerOptions
+	clock     Clock
+	metrics   *loadingMetrics
+	openCount atomic.Int64 // Open circuits, so Get only locks the breaker while one is
+
+	mutex   sync.Mutex
+	circuit *circuit            // The global circuit, nil without WithCircuitBreaker
+	keys    map[string]*circuit // Circuits of the keys that failed since their last success, nil without WithKeyCircuitBreaker
+}
+
+// newCircuitBreaker returns the breaker configured by the options, or nil if none is enabled.
+func newCircuitBreaker(o options, metrics *loadingMetrics) *circuitBreaker {
+	if o.breaker.failures <= 0 && o.keyBreaker.failures <= 0 {
+		return nil
+	}
+	breaker := &circuitBreaker{global: o.breaker, perKey: o.keyBreaker, clock: o.clock, metrics: metrics}
+	if o.breaker.failures > 0 {
+		breaker.circuit = &circuit{}
+	}
+	if o.keyBreaker.failures > 0 {
+		breaker.keys = make(map[string]*circuit)
+	}
+	return breaker
+}
+
+// state returns the state of the circuit of the key, or of the global one if it is not closed.
+func (breaker *circuitBreaker) state(key string) BreakerState {
+	if breaker == nil {
+		return BreakerClosed
+	}
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	now := breaker.clock.Now()
+	if state := breaker.circuit.state(now, breaker.global.cooldown); state != BreakerClosed {
+		return state
+	}
+	return breaker.keys[key].state(now, breaker.perKey.cooldown)
+}
+
+// rejects returns whether the load of the key would be short-circuited, without taking the probe.
+func (breaker *circuitBreaker) rejects(key string) bool {
+	if breaker == nil || breaker.openCount.Load() == 0 {
+		return false
+	}
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	now := breaker.clock.Now()
+	return breaker.circuit.rejects(now, breaker.global.cooldown) || breaker.keys[key].rejects(now, breaker.perKey.cooldown)
+}
+
+// allow returns whether the key may be loaded, taking the probe of the circuits whose cooldown elapsed.
+func (breaker *circuitBreaker) allow(key string) bool {
+	if breaker == nil {
+		return true
+	}
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	now := breaker.clock.Now()
+	keyCircuit := breaker.keys[key]
+	if breaker.circuit.rejects(now, breaker.global.cooldown) || keyCircuit.rejects(now, breaker.perKey.cooldown) {
+		breaker.metrics.shortCircuited()
+		return false
+	}
+	for _, target := range []*circuit{breaker.circuit, keyCircuit} {
+		if target != nil && !target.openedAt.IsZero() {
+			target.probing = true
+		}
+	}
+	return true
+}
+
+// record counts the outcome of a load of the key. The loads cancelled by their caller are not counted,
+// releasing the probe they took, if any.
+func (breaker *circuitBreaker) record(key string, err error) {
+	if breaker == nil {
+		return
+	}
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	now := breaker.clock.Now()
+	if errors.Is(err, context.Canceled) {
+		for _, target := range []*circuit{breaker.circuit, breaker.keys[key]} {
+			if target != nil {
+				target.probing = false
+			}
+		}
+		return
+	}
+	if err == nil {
+		breaker.close(breaker.circuit)
+		if keyCircuit, found := breaker.keys[key]; found {
+			breaker.close(keyCircuit)
+			delete(breaker.keys, key)
+		}
+		return
+	}
+
+	breaker.fail(breaker.circuit, breaker.global.failures, now)
+	if breaker.keys != nil {
+		keyCircuit, found := breaker.keys[key]
+		if !found && len(breaker.keys) < maxBreakerKeys {
+			keyCircuit = &circuit{}
+			breaker.keys[key] = keyCircuit
+		}
+		breaker.fail(keyCircuit, breaker.perKey.failures, now)
+	}
+}
+
+// fail counts a failure of the circuit, opening it at the threshold, or again if the failure was a probe.
+func (breaker *circuitBreaker) fail(target *circuit, threshold int, now time.Time) {
+	if target == nil {
+		return
+	}
+	target.failures++
+	if target.probing || target.openedAt.IsZero() && target.failures >= threshold {
+		if target.openedAt.IsZero() {
+			breaker.openCount.Add(1)
+			breaker.metrics.circuitOpened(1)
+		}
+		target.openedAt, target.probing = now, false
+	}
+}
+
+// close resets the failures of the circuit.
+func (breaker *circuitBreaker) close(target *circuit) {
+	if target == nil {
+		return
+	}
+	if !target.openedAt.IsZero() {
+		breaker.openCount.Add(-1)
+		breaker.metrics.circuitOpened(-1)
+	}
+	*target = circuit{}
+}
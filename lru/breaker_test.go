@@ -0,0 +1,87 @@
+package lru
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyCircuitBreaker(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	failure := errors.New("backend down")
+	loads := map[string]int{}
+	failing := true
+	loading := NewLoadingCache(NewSafeLRUCache(5), func(ctx context.Context, key string) (any, time.Duration, error) {
+		loads[key]++
+		if key == "bad" && failing {
+			return nil, 0, failure
+		}
+		return "value-" + key, 0, nil
+	}, WithKeyCircuitBreaker(2, time.Minute), WithClock(clock), WithMetricsName("breaker_keys"))
+	ctx := context.Background()
+
+	for range 2 {
+		_, err := loading.Get(ctx, "bad")
+		assert.ErrorIs(t, err, failure)
+	}
+	assert.Equal(t, BreakerOpen, loading.BreakerState("bad"))
+	_, err := loading.Get(ctx, "bad")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 2, loads["bad"], "the open circuit does not reach the loader")
+	assert.Equal(t, 1.0, gaugeValue(currentMetrics.openCircuits.WithLabelValues("breaker_keys")))
+	assert.Equal(t, 1.0, counterValue(currentMetrics.shortCircuits.WithLabelValues("breaker_keys")))
+
+	// The other keys are not affected
+	value, err := loading.Get(ctx, "good")
+	assert.NoError(t, err)
+	assert.Equal(t, "value-good", value)
+	assert.Equal(t, BreakerClosed, loading.BreakerState("good"))
+
+	// A failed probe opens the circuit again
+	clock.Advance(time.Minute)
+	assert.Equal(t, BreakerHalfOpen, loading.BreakerState("bad"))
+	_, err = loading.Get(ctx, "bad")
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, BreakerOpen, loading.BreakerState("bad"))
+
+	// A successful one closes it
+	clock.Advance(time.Minute)
+	failing = false
+	value, err = loading.Get(ctx, "bad")
+	assert.NoError(t, err)
+	assert.Equal(t, "value-bad", value)
+	assert.Equal(t, BreakerClosed, loading.BreakerState("bad"))
+	assert.Equal(t, "closed", loading.BreakerState("bad").String())
+	assert.Zero(t, gaugeValue(currentMetrics.openCircuits.WithLabelValues("breaker_keys")))
+}
+
+func TestCircuitBreakerServesStale(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	failing := false
+	loading := NewLoadingCache(NewSafeLRUCache(5, WithClock(clock)), func(ctx context.Context, key string) (any, time.Duration, error) {
+		if failing {
+			return nil, 0, errors.New("backend down")
+		}
+		return "value-" + key, time.Second, nil
+	}, WithCircuitBreaker(2, time.Minute), WithClock(clock), WithoutMetrics())
+	ctx := context.Background()
+
+	_, err := loading.Get(ctx, "key1")
+	assert.NoError(t, err)
+	clock.Advance(2 * time.Second)
+	failing = true
+	for _, key := range []string{"key2", "key3"} {
+		_, err := loading.Get(ctx, key)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, BreakerOpen, loading.BreakerState("key1"), "the failures of every key open the global circuit")
+
+	value, err := loading.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, "value-key1", value, "the expired value is served while the circuit is open")
+	_, err = loading.Get(ctx, "key4")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+}
@@ -1,14 +1,46 @@
 package lru
 
 import (
+	"errors"
 	"time"
 )
 
+// ErrNotInteger is returned by Increment when the key holds a value that is not an int64.
+var ErrNotInteger = errors.New("lru: value is not an int64")
+
 type Cache interface {
 	Get(key string) (any, bool)
-	Set(key string, value any) (status string)
-	SetWithTTL(key string, value any, ttl time.Duration) (status string)
+	Set(key string, value any) SetResult
+	SetWithTTL(key string, value any, ttl time.Duration) SetResult
 	Remove(key string)
 	Len() int
 	Capacity() int
 }
+
+// Incrementer is implemented by the caches supporting atomic counters.
+type Incrementer interface {
+	Increment(key string, delta int64, ttl time.Duration) (int64, error)
+}
+
+// counterExpiration returns the expiration of a new counter, zero when the ttl is not positive.
+func counterExpiration(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// counterResult returns the new value of a counter, or the error matching the result of its write.
+func counterResult(result SetResult, value int64) (int64, error) {
+	switch result.Status {
+	case SetRejected:
+		return 0, ErrUnsupportedValue
+	case SetThrottled:
+		return 0, ErrThrottled
+	case SetDenied:
+		return 0, ErrDenied
+	case SetDisabled:
+		return 0, ErrDisabled
+	}
+	return value, nil
+}
@@ -0,0 +1,199 @@
+// Package cachetest provides test doubles for the code depending on an lru.Cache:
+// MockCache, whose hits, misses and latencies are scripted by the test,
+// Recorder, capturing the operations made on any cache for assertions,
+// and Chaos, injecting random latency, misses and errors into any cache.
+package cachetest
+
+import (
+	"sync"
+	"time"
+
+	"caching/lru"
+)
+
+// Op is the name of a Cache operation.
+type Op string
+
+const (
+	OpGet        Op = "Get"
+	OpSet        Op = "Set"
+	OpSetWithTTL Op = "SetWithTTL"
+	OpRemove     Op = "Remove"
+	OpLen        Op = "Len"
+	OpCapacity   Op = "Capacity"
+)
+
+// MockCache is a Cache storing its items in a map without ever evicting them.
+// The results of the next reads of a key can be scripted with Hit and Miss, and every operation
+// can be delayed by a latency, on a ManualClock to keep the tests deterministic.
+// It is thread-safe.
+type MockCache struct {
+	mutex    sync.Mutex              // Protects the fields below
+	items    map[string]mockItem     // The items set on the cache
+	scripted map[string][]mockResult // The scripted results of the next reads, by key
+	capacity int                     // The capacity reported by Capacity
+	latency  time.Duration           // The delay of every operation
+	clock    *lru.ManualClock        // Advanced by the latency instead of sleeping, and used for expiration, nil for the system clock
+}
+
+var _ lru.Cache = (*MockCache)(nil) // Ensure MockCache implements the Cache interface
+
+// mockItem is an item of a MockCache.
+type mockItem struct {
+	value     any
+	expiresAt time.Time // Zero if the item does not expire
+}
+
+// mockResult is a scripted result of Get.
+type mockResult struct {
+	value any
+	found bool
+}
+
+// MockOption configures a MockCache.
+type MockOption func(*MockCache)
+
+// WithCapacity sets the capacity reported by the mock, zero by default. The mock never evicts its items.
+func WithCapacity(capacity int) MockOption {
+	return func(mock *MockCache) {
+		mock.capacity = capacity
+	}
+}
+
+// WithLatency delays every operation of the mock by the given duration,
+// e.g. to test the timeouts of the callers.
+func WithLatency(latency time.Duration) MockOption {
+	return func(mock *MockCache) {
+		mock.latency = latency
+	}
+}
+
+// WithManualClock makes the latency advance the clock instead of sleeping, and expires the items on it.
+func WithManualClock(clock *lru.ManualClock) MockOption {
+	return func(mock *MockCache) {
+		mock.clock = clock
+	}
+}
+
+// NewMockCache creates an empty MockCache.
+func NewMockCache(opts ...MockOption) *MockCache {
+	mock := &MockCache{
+		items:    make(map[string]mockItem),
+		scripted: make(map[string][]mockResult),
+	}
+	for _, opt := range opts {
+		opt(mock)
+	}
+	return mock
+}
+
+// Hit scripts the next Get of the key to return the value, whatever the mock holds.
+// Successive calls script the successive reads.
+func (mock *MockCache) Hit(key string, value any) *MockCache {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	mock.scripted[key] = append(mock.scripted[key], mockResult{value: value, found: true})
+	return mock
+}
+
+// Miss scripts the next Get of the key to miss, whatever the mock holds.
+// Successive calls script the successive reads.
+func (mock *MockCache) Miss(key string) *MockCache {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	mock.scripted[key] = append(mock.scripted[key], mockResult{})
+	return mock
+}
+
+// wait applies the latency of an operation.
+func (mock *MockCache) wait() {
+	if mock.latency <= 0 {
+		return
+	}
+	if mock.clock != nil {
+		mock.clock.Advance(mock.latency)
+		return
+	}
+	time.Sleep(mock.latency)
+}
+
+// now returns the current time of the clock of the mock.
+func (mock *MockCache) now() time.Time {
+	if mock.clock != nil {
+		return mock.clock.Now()
+	}
+	return time.Now()
+}
+
+// Get returns the next scripted result of the key if any, or the value set for the key.
+func (mock *MockCache) Get(key string) (any, bool) {
+	mock.wait()
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if results := mock.scripted[key]; len(results) > 0 {
+		mock.scripted[key] = results[1:]
+		return results[0].value, results[0].found
+	}
+	item, found := mock.items[key]
+	if !found || (!item.expiresAt.IsZero() && !mock.now().Before(item.expiresAt)) {
+		return nil, false
+	}
+	return item.value, true
+}
+
+// Set stores the value without expiration.
+func (mock *MockCache) Set(key string, value any) lru.SetResult {
+	mock.wait()
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	return mock.set(key, mockItem{value: value})
+}
+
+// SetWithTTL stores the value expiring after ttl. A ttl of zero or less is already expired and not stored.
+func (mock *MockCache) SetWithTTL(key string, value any, ttl time.Duration) lru.SetResult {
+	mock.wait()
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if ttl <= 0 {
+		delete(mock.items, key)
+		return lru.SetResult{Status: lru.SetExpired}
+	}
+	return mock.set(key, mockItem{value: value, expiresAt: mock.now().Add(ttl)})
+}
+
+// set stores the item, returning whether it was added or updated.
+func (mock *MockCache) set(key string, item mockItem) lru.SetResult {
+	_, found := mock.items[key]
+	mock.items[key] = item
+	if found {
+		return lru.SetResult{Status: lru.SetUpdated}
+	}
+	return lru.SetResult{Status: lru.SetAdded}
+}
+
+// Remove deletes the item of the key. The scripted results are kept.
+func (mock *MockCache) Remove(key string) {
+	mock.wait()
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	delete(mock.items, key)
+}
+
+// Len returns the number of items set on the mock, including the expired ones.
+func (mock *MockCache) Len() int {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	return len(mock.items)
+}
+
+// Capacity returns the capacity set by WithCapacity.
+func (mock *MockCache) Capacity() int {
+	return mock.capacity
+}
@@ -0,0 +1,104 @@
+package cachetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"caching/lru"
+)
+
+func TestMockCacheScriptedReads(t *testing.T) {
+	mock := NewMockCache(WithCapacity(10))
+	mock.Set("key", "stored")
+	mock.Miss("key").Hit("key", "scripted")
+
+	_, found := mock.Get("key")
+	assert.False(t, found, "Expected the scripted miss")
+	value, _ := mock.Get("key")
+	assert.Equal(t, "scripted", value)
+	value, _ = mock.Get("key")
+	assert.Equal(t, "stored", value, "Expected the stored value once the script is exhausted")
+	assert.Equal(t, 1, mock.Len())
+	assert.Equal(t, 10, mock.Capacity())
+}
+
+func TestMockCacheLatencyAndExpiration(t *testing.T) {
+	clock := lru.NewManualClock(time.Now())
+	start := clock.Now()
+	mock := NewMockCache(WithLatency(time.Second), WithManualClock(clock))
+
+	assert.Equal(t, lru.SetAdded, mock.SetWithTTL("key", "value", 2*time.Second).Status)
+	assert.Equal(t, lru.SetAdded, mock.Set("other", 1).Status)
+	assert.Equal(t, lru.SetExpired, mock.SetWithTTL("key2", "value", 0).Status)
+	_, found := mock.Get("key")
+	assert.False(t, found, "Expected the item to expire after the latency of three operations")
+	assert.Equal(t, 4*time.Second, clock.Now().Sub(start))
+
+	mock.Remove("other")
+	assert.Equal(t, 1, mock.Len())
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder(lru.NewLRUCache(1))
+	recorder.Set("key1", "value1")
+	recorder.SetWithTTL("key2", "value2", time.Minute)
+	recorder.Get("key1")
+	recorder.Remove("key2")
+	recorder.Len()
+
+	assert.Equal(t, []Call{
+		{Op: OpSet, Key: "key1", Value: "value1", Result: lru.SetResult{Status: lru.SetAdded}},
+		{Op: OpSetWithTTL, Key: "key2", Value: "value2", TTL: time.Minute, Result: lru.SetResult{Status: lru.SetAdded, Evicted: true, EvictedKey: "key1"}},
+		{Op: OpGet, Key: "key1"},
+		{Op: OpRemove, Key: "key2"},
+		{Op: OpLen},
+	}, recorder.Calls())
+	assert.Equal(t, []string{"key1"}, recorder.Keys(OpGet))
+
+	recorder.Reset()
+	assert.Empty(t, recorder.Calls())
+}
+
+func TestChaosIsReproducible(t *testing.T) {
+	run := func(seed uint64) []bool {
+		chaos := NewChaos(lru.NewLRUCache(10), WithChaosSeed(seed), WithChaosMisses(0.5))
+		chaos.Set("key", "value")
+		var hits []bool
+		for range 20 {
+			_, found := chaos.Get("key")
+			hits = append(hits, found)
+		}
+		return hits
+	}
+
+	assert.Equal(t, run(42), run(42))
+	assert.NotEqual(t, run(42), run(7))
+	assert.Contains(t, run(42), true)
+	assert.Contains(t, run(42), false)
+}
+
+func TestChaosFaults(t *testing.T) {
+	clock := lru.NewManualClock(time.Now())
+	start := clock.Now()
+	cache := lru.NewLRUCache(10)
+	chaos := NewChaos(cache, WithChaosErrors(1), WithChaosLatency(time.Second, 1), WithChaosClock(clock))
+
+	assert.Equal(t, lru.SetRejected, chaos.Set("key", "value").Status)
+	assert.Equal(t, lru.SetRejected, chaos.SetWithTTL("key", "value", time.Minute).Status)
+	_, err := chaos.Increment("counter", 1, 0)
+	assert.ErrorIs(t, err, ErrInjected)
+	assert.Equal(t, 0, cache.Len(), "Expected the failed writes to not reach the cache")
+	assert.Equal(t, 3*time.Second, clock.Now().Sub(start))
+	assert.Equal(t, int64(3), chaos.Injected(FaultError))
+	assert.Equal(t, int64(3), chaos.Injected(FaultLatency))
+	assert.Equal(t, int64(0), chaos.Injected(FaultMiss))
+
+	chaos.SetEnabled(false)
+	assert.Equal(t, lru.SetAdded, chaos.Set("key", "value").Status)
+	value, found := chaos.Get("key")
+	assert.True(t, found)
+	assert.Equal(t, "value", value)
+	assert.Equal(t, 3*time.Second, clock.Now().Sub(start), "Expected no latency once disabled")
+}
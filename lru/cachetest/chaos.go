@@ -0,0 +1,194 @@
+package cachetest
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"caching/lru"
+)
+
+// ErrInjected is returned by the operations of a Chaos cache failed on purpose, for the ones returning an error.
+var ErrInjected = errors.New("cachetest: injected fault")
+
+// Fault is a kind of fault injected by a Chaos cache.
+type Fault string
+
+const (
+	FaultLatency Fault = "latency" // An operation was delayed
+	FaultMiss    Fault = "miss"    // A Get missed, whether the key was present or not
+	FaultError   Fault = "error"   // A write failed without reaching the decorated cache
+)
+
+// Chaos decorates a cache, injecting faults into its operations so the applications can test how they degrade
+// when the cache misbehaves: added latency, reads missing keys that are present, and failed writes, Set and
+// SetWithTTL returning lru.SetRejected, Remove being dropped and Increment returning ErrInjected.
+// The faults are drawn from a random source seeded by WithChaosSeed, so a single-goroutine run can be reproduced.
+// It is thread-safe if the decorated cache is.
+type Chaos struct {
+	cache       lru.Cache        // The decorated cache
+	mutex       sync.Mutex       // Protects random
+	random      *rand.Rand       // Draws the faults
+	latency     time.Duration    // Delay of the operations picked by latencyRate
+	latencyRate float64          // Share of the operations delayed
+	missRate    float64          // Share of the reads missing
+	errorRate   float64          // Share of the writes failing
+	clock       *lru.ManualClock // Advanced by the latency instead of sleeping, nil to sleep
+	disabled    atomic.Bool      // Whether the faults are suspended, see SetEnabled
+	injected    sync.Map         // Number of faults injected by Fault, as *atomic.Int64
+}
+
+var _ lru.Cache = (*Chaos)(nil)       // Ensure Chaos implements the Cache interface
+var _ lru.Incrementer = (*Chaos)(nil) // Ensure Chaos supports counters
+
+// ChaosOption configures a Chaos cache.
+type ChaosOption func(*Chaos)
+
+// WithChaosSeed seeds the random source of the faults, zero by default.
+func WithChaosSeed(seed uint64) ChaosOption {
+	return func(chaos *Chaos) {
+		chaos.random = rand.New(rand.NewPCG(seed, seed))
+	}
+}
+
+// WithChaosLatency delays the given share of the operations, from 0 to 1, by latency.
+func WithChaosLatency(latency time.Duration, rate float64) ChaosOption {
+	return func(chaos *Chaos) {
+		chaos.latency = latency
+		chaos.latencyRate = rate
+	}
+}
+
+// WithChaosMisses makes the given share of the reads, from 0 to 1, miss.
+func WithChaosMisses(rate float64) ChaosOption {
+	return func(chaos *Chaos) {
+		chaos.missRate = rate
+	}
+}
+
+// WithChaosErrors makes the given share of the writes, from 0 to 1, fail without reaching the decorated cache.
+func WithChaosErrors(rate float64) ChaosOption {
+	return func(chaos *Chaos) {
+		chaos.errorRate = rate
+	}
+}
+
+// WithChaosClock makes the latency advance the clock instead of sleeping, to keep the tests fast and deterministic.
+func WithChaosClock(clock *lru.ManualClock) ChaosOption {
+	return func(chaos *Chaos) {
+		chaos.clock = clock
+	}
+}
+
+// NewChaos creates a Chaos cache forwarding the operations it does not fail to the cache.
+func NewChaos(cache lru.Cache, opts ...ChaosOption) *Chaos {
+	chaos := &Chaos{cache: cache, random: rand.New(rand.NewPCG(0, 0))}
+	for _, opt := range opts {
+		opt(chaos)
+	}
+	return chaos
+}
+
+// SetEnabled suspends or resumes the faults, e.g. to test that the application recovers once the cache does.
+// The faults are enabled at construction.
+func (chaos *Chaos) SetEnabled(enabled bool) {
+	chaos.disabled.Store(!enabled)
+}
+
+// Injected returns the number of faults of the given kind injected so far.
+func (chaos *Chaos) Injected(fault Fault) int64 {
+	if count, found := chaos.injected.Load(fault); found {
+		return count.(*atomic.Int64).Load()
+	}
+	return 0
+}
+
+// inject draws whether to inject a fault happening at the given rate, and counts it if so.
+func (chaos *Chaos) inject(fault Fault, rate float64) bool {
+	if rate <= 0 || chaos.disabled.Load() {
+		return false
+	}
+	chaos.mutex.Lock()
+	draw := chaos.random.Float64()
+	chaos.mutex.Unlock()
+	if draw >= rate {
+		return false
+	}
+	count, _ := chaos.injected.LoadOrStore(fault, new(atomic.Int64))
+	count.(*atomic.Int64).Add(1)
+	return true
+}
+
+// delay applies the latency to the operations picked by the latency rate.
+func (chaos *Chaos) delay() {
+	if !chaos.inject(FaultLatency, chaos.latencyRate) {
+		return
+	}
+	if chaos.clock != nil {
+		chaos.clock.Advance(chaos.latency)
+		return
+	}
+	time.Sleep(chaos.latency)
+}
+
+// Get reads the key from the decorated cache, unless the read is picked to miss.
+func (chaos *Chaos) Get(key string) (any, bool) {
+	chaos.delay()
+	if chaos.inject(FaultMiss, chaos.missRate) {
+		return nil, false
+	}
+	return chaos.cache.Get(key)
+}
+
+// Set writes the item to the decorated cache, unless the write is picked to fail with lru.SetRejected.
+func (chaos *Chaos) Set(key string, value any) lru.SetResult {
+	chaos.delay()
+	if chaos.inject(FaultError, chaos.errorRate) {
+		return lru.SetResult{Status: lru.SetRejected}
+	}
+	return chaos.cache.Set(key, value)
+}
+
+// SetWithTTL writes the item to the decorated cache, unless the write is picked to fail with lru.SetRejected.
+func (chaos *Chaos) SetWithTTL(key string, value any, ttl time.Duration) lru.SetResult {
+	chaos.delay()
+	if chaos.inject(FaultError, chaos.errorRate) {
+		return lru.SetResult{Status: lru.SetRejected}
+	}
+	return chaos.cache.SetWithTTL(key, value, ttl)
+}
+
+// Remove deletes the key from the decorated cache, unless the removal is picked to be dropped.
+func (chaos *Chaos) Remove(key string) {
+	chaos.delay()
+	if chaos.inject(FaultError, chaos.errorRate) {
+		return
+	}
+	chaos.cache.Remove(key)
+}
+
+// Increment adds delta to the counter of the decorated cache, unless the write is picked to fail with ErrInjected.
+// It returns errors.ErrUnsupported if the decorated cache does not implement lru.Incrementer.
+func (chaos *Chaos) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	chaos.delay()
+	incrementer, ok := chaos.cache.(lru.Incrementer)
+	if !ok {
+		return 0, errors.ErrUnsupported
+	}
+	if chaos.inject(FaultError, chaos.errorRate) {
+		return 0, ErrInjected
+	}
+	return incrementer.Increment(key, delta, ttl)
+}
+
+// Len returns the number of items of the decorated cache.
+func (chaos *Chaos) Len() int {
+	return chaos.cache.Len()
+}
+
+// Capacity returns the capacity of the decorated cache.
+func (chaos *Chaos) Capacity() int {
+	return chaos.cache.Capacity()
+}
@@ -0,0 +1,111 @@
+package cachetest
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"caching/lru"
+)
+
+// Call is an operation recorded by a Recorder, with its arguments and result.
+type Call struct {
+	Op     Op
+	Key    string        // Empty for Len and Capacity
+	Value  any           // The value set, or the value returned by Get
+	TTL    time.Duration // The ttl of SetWithTTL
+	Found  bool          // Whether Get found the key
+	Result lru.SetResult // The result of Set and SetWithTTL
+}
+
+// Recorder decorates a cache, recording every operation made on it in order.
+// It is thread-safe if the decorated cache is.
+type Recorder struct {
+	cache lru.Cache  // The decorated cache
+	mutex sync.Mutex // Protects calls
+	calls []Call     // The operations made, oldest first
+}
+
+var _ lru.Cache = (*Recorder)(nil) // Ensure Recorder implements the Cache interface
+
+// NewRecorder creates a Recorder forwarding the operations to the cache.
+func NewRecorder(cache lru.Cache) *Recorder {
+	return &Recorder{cache: cache}
+}
+
+// record appends a call.
+func (recorder *Recorder) record(call Call) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.calls = append(recorder.calls, call)
+}
+
+// Calls returns a copy of the recorded operations, oldest first.
+func (recorder *Recorder) Calls() []Call {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	return slices.Clone(recorder.calls)
+}
+
+// Keys returns the keys of the recorded operations of the given kind, in order, e.g. the keys read.
+func (recorder *Recorder) Keys(op Op) []string {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	var keys []string
+	for _, call := range recorder.calls {
+		if call.Op == op {
+			keys = append(keys, call.Key)
+		}
+	}
+	return keys
+}
+
+// Reset forgets the recorded operations.
+func (recorder *Recorder) Reset() {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.calls = nil
+}
+
+// Get reads the key from the decorated cache.
+func (recorder *Recorder) Get(key string) (any, bool) {
+	value, found := recorder.cache.Get(key)
+	recorder.record(Call{Op: OpGet, Key: key, Value: value, Found: found})
+	return value, found
+}
+
+// Set writes the key to the decorated cache.
+func (recorder *Recorder) Set(key string, value any) lru.SetResult {
+	result := recorder.cache.Set(key, value)
+	recorder.record(Call{Op: OpSet, Key: key, Value: value, Result: result})
+	return result
+}
+
+// SetWithTTL writes the key expiring after ttl to the decorated cache.
+func (recorder *Recorder) SetWithTTL(key string, value any, ttl time.Duration) lru.SetResult {
+	result := recorder.cache.SetWithTTL(key, value, ttl)
+	recorder.record(Call{Op: OpSetWithTTL, Key: key, Value: value, TTL: ttl, Result: result})
+	return result
+}
+
+// Remove removes the key from the decorated cache.
+func (recorder *Recorder) Remove(key string) {
+	recorder.cache.Remove(key)
+	recorder.record(Call{Op: OpRemove, Key: key})
+}
+
+// Len returns the length of the decorated cache.
+func (recorder *Recorder) Len() int {
+	recorder.record(Call{Op: OpLen})
+	return recorder.cache.Len()
+}
+
+// Capacity returns the capacity of the decorated cache.
+func (recorder *Recorder) Capacity() int {
+	recorder.record(Call{Op: OpCapacity})
+	return recorder.cache.Capacity()
+}
@@ -0,0 +1,195 @@
+package lru
+
+import (
+	"cmp"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// canaryBuckets is the number of buckets the keys are hashed into by a CanaryCache, so the percentage
+// of keys routed to the canary has a precision of 0.01%.
+const canaryBuckets = 10000
+
+const (
+	metricVariantStable = "stable" // Variant label of the keys routed to the stable cache of a CanaryCache
+	metricVariantCanary = "canary" // Variant label of the keys routed to the canary cache of a CanaryCache
+)
+
+// CanaryCache routes a percentage of the keys, picked by their hash, to a canary cache with a new policy or
+// configuration, and the other keys to the stable cache, so a change can be rolled out gradually while comparing
+// the hit ratios of both, see VariantStats. A key always goes to the same cache for a given percentage,
+// on every instance. The writes also remove the key from the other cache, so a key moving back after
+// a change of the percentage does not read a stale value. It is thread-safe if both caches are.
+type CanaryCache struct {
+	stable    Cache
+	canary    Cache
+	threshold atomic.Int64 // Keys whose hash bucket is below go to the canary
+	variants  [2]canaryVariant
+	percent   prometheus.Gauge // Percentage of the keys routed to the canary, nil without metrics
+}
+
+// canaryVariant counts the gets routed to one of the caches of a CanaryCache.
+type canaryVariant struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	gets   map[bool]prometheus.Counter // Gets by whether they found their key, nil without metrics
+}
+
+var _ Cache = (*CanaryCache)(nil) // Ensure CanaryCache implements the Cache interface
+
+// CanaryStats are the stats of the two caches of a CanaryCache.
+type CanaryStats struct {
+	Percent float64 `json:"percent"` // Percentage of the keys routed to the canary
+	Stable  Stats   `json:"stable"`
+	Canary  Stats   `json:"canary"`
+}
+
+// NewCanaryCache creates a CanaryCache routing the given percentage of the keys, between 0 and 100, to canary
+// and the others to stable. Only WithMetricsName and WithoutMetrics are used among the options: the gets are
+// counted by variant in cache_canary_gets_total, labeled "canary" by default.
+func NewCanaryCache(stable, canary Cache, percent float64, opts ...Option) *CanaryCache {
+	o := newOptions(opts...)
+	cache := &CanaryCache{stable: stable, canary: canary}
+	if o.metrics {
+		cache.resolveMetrics(cmp.Or(o.name, metricCacheTypeCanary))
+	}
+	cache.SetPercent(percent)
+	return cache
+}
+
+// resolveMetrics resolves the metric children of the CanaryCache with the given name.
+func (cache *CanaryCache) resolveMetrics(name string) {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	cache.percent = currentMetrics.canaryPercent.WithLabelValues(name)
+	for i, variant := range []string{metricVariantStable, metricVariantCanary} {
+		cache.variants[i].gets = map[bool]prometheus.Counter{
+			true:  currentMetrics.canaryGets.WithLabelValues(name, variant, metricResultHit),
+			false: currentMetrics.canaryGets.WithLabelValues(name, variant, metricResultMiss),
+		}
+	}
+}
+
+// SetPercent changes the percentage of the keys routed to the canary, e.g. to ramp it up, or to 0 to roll it back.
+// The keys routed to the canary at a percentage stay there at any higher one.
+func (cache *CanaryCache) SetPercent(percent float64) {
+	percent = min(max(percent, 0), 100)
+	cache.threshold.Store(int64(math.Round(percent * canaryBuckets / 100)))
+	if cache.percent != nil {
+		cache.percent.Set(percent)
+	}
+}
+
+// Percent returns the percentage of the keys routed to the canary.
+func (cache *CanaryCache) Percent() float64 {
+	return float64(cache.threshold.Load()) * 100 / canaryBuckets
+}
+
+// route returns the index of the variant of the key, 1 for the canary, and its cache and the other one.
+func (cache *CanaryCache) route(key string) (variant int, routed Cache, other Cache) {
+	if canaryBucket(key) < cache.threshold.Load() {
+		return 1, cache.canary, cache.stable
+	}
+	return 0, cache.stable, cache.canary
+}
+
+// canaryBucket returns the bucket of the key, from its 64-bit FNV-1a hash: a hash other than the one of the shards,
+// so the keys of the canary spread evenly over the shards of a ShardedCache.
+func canaryBucket(key string) int64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	hash := uint64(offset64)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= prime64
+	}
+	return int64(hash % canaryBuckets)
+}
+
+// Get retrieves an item by its key from the cache it is routed to, counting the hit or miss for that cache.
+func (cache *CanaryCache) Get(key string) (any, bool) {
+	variant, routed, _ := cache.route(key)
+	value, found := routed.Get(key)
+	counts := &cache.variants[variant]
+	if found {
+		counts.hits.Add(1)
+	} else {
+		counts.misses.Add(1)
+	}
+	if counts.gets != nil {
+		counts.gets[found].Inc()
+	}
+	return value, found
+}
+
+// Set adds or updates an item with no expiration in the cache the key is routed to, removing it from the other one.
+func (cache *CanaryCache) Set(key string, value any) SetResult {
+	_, routed, other := cache.route(key)
+	other.Remove(key)
+	return routed.Set(key, value)
+}
+
+// SetWithTTL adds or updates an item with a specified expiration time in the cache the key is routed to,
+// removing it from the other one.
+func (cache *CanaryCache) SetWithTTL(key string, value any, ttl time.Duration) SetResult {
+	_, routed, other := cache.route(key)
+	other.Remove(key)
+	return routed.SetWithTTL(key, value, ttl)
+}
+
+// Remove deletes an item from both caches by key.
+func (cache *CanaryCache) Remove(key string) {
+	cache.stable.Remove(key)
+	cache.canary.Remove(key)
+}
+
+// Len returns the number of items currently in both caches.
+func (cache *CanaryCache) Len() int {
+	return cache.stable.Len() + cache.canary.Len()
+}
+
+// Capacity returns the sum of the capacities of both caches, or Unbounded if either of them is.
+func (cache *CanaryCache) Capacity() int {
+	stable, canary := cache.stable.Capacity(), cache.canary.Capacity()
+	if stable < 0 || canary < 0 {
+		return Unbounded
+	}
+	return stable + canary
+}
+
+// VariantStats returns the stats of both caches, with the hits and misses of the gets routed to each of them
+// through the CanaryCache, to compare their hit ratios.
+func (cache *CanaryCache) VariantStats() CanaryStats {
+	stats := func(variant int, target Cache) Stats {
+		var stats Stats
+		if reporter, ok := target.(StatsReporter); ok {
+			stats = reporter.Stats()
+		} else {
+			stats = Stats{Len: target.Len(), Capacity: target.Capacity()}
+		}
+		stats.Hits = cache.variants[variant].hits.Load()
+		stats.Misses = cache.variants[variant].misses.Load()
+		return stats
+	}
+	return CanaryStats{
+		Percent: cache.Percent(),
+		Stable:  stats(0, cache.stable),
+		Canary:  stats(1, cache.canary),
+	}
+}
+
+// Stable returns the cache receiving the keys not routed to the canary.
+func (cache *CanaryCache) Stable() Cache {
+	return cache.stable
+}
+
+// Canary returns the cache receiving the percentage of the keys rolled out.
+func (cache *CanaryCache) Canary() Cache {
+	return cache.canary
+}
@@ -0,0 +1,75 @@
+package lru
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanaryCache(t *testing.T) {
+	stable := NewLRUCache(1000, WithoutMetrics())
+	canary := NewLFUCache(1000, WithoutMetrics())
+	cache := NewCanaryCache(stable, canary, 30, WithMetricsName("canary_test"))
+
+	for i := range 1000 {
+		cache.Set(strconv.Itoa(i), i)
+	}
+	assert.Equal(t, 1000, cache.Len())
+	assert.InDelta(t, 300, canary.Len(), 50, "Expected about 30% of the keys in the canary")
+	for i := range 1000 {
+		value, found := cache.Get(strconv.Itoa(i))
+		assert.True(t, found)
+		assert.Equal(t, i, value)
+	}
+	cache.Get("missing")
+
+	stats := cache.VariantStats()
+	assert.Equal(t, 30.0, stats.Percent)
+	assert.Equal(t, uint64(canary.Len()), stats.Canary.Hits)
+	assert.Equal(t, uint64(stable.Len()), stats.Stable.Hits)
+	assert.Equal(t, uint64(1), stats.Stable.Misses+stats.Canary.Misses)
+	assert.Greater(t, stats.Stable.HitRatio(), 0.99)
+	assert.Equal(t, float64(stats.Canary.Hits), counterValue(currentMetrics.canaryGets.WithLabelValues("canary_test", metricVariantCanary, metricResultHit)))
+	assert.Equal(t, 30.0, gaugeValue(currentMetrics.canaryPercent.WithLabelValues("canary_test")))
+}
+
+func TestCanaryCacheRamp(t *testing.T) {
+	stable := NewLRUCache(1000, WithoutMetrics())
+	canary := NewLRUCache(1000, WithoutMetrics())
+	cache := NewCanaryCache(stable, canary, 10, WithoutMetrics())
+	for i := range 1000 {
+		cache.Set(strconv.Itoa(i), "old")
+	}
+	routed := canary.Len()
+
+	cache.SetPercent(50)
+	for i := range 1000 {
+		cache.Set(strconv.Itoa(i), "new")
+	}
+	assert.Greater(t, canary.Len(), routed)
+	assert.Equal(t, 1000, cache.Len(), "Expected the keys moved to the canary to be removed from the stable cache")
+
+	cache.SetPercent(0) // Rollback
+	for i := range 1000 {
+		value, found := cache.Get(strconv.Itoa(i))
+		if found {
+			assert.Equal(t, "new", value, "Expected no stale value after moving back")
+		}
+	}
+	cache.SetPercent(150)
+	assert.Equal(t, 100.0, cache.Percent())
+	cache.SetPercent(0.57) // 0.57 * 100 is 56.99999999999999
+	assert.Equal(t, 0.57, cache.Percent(), "Expected the percentage to be rounded to the nearest bucket")
+}
+
+func TestCanaryCacheCapacity(t *testing.T) {
+	bounded := NewCanaryCache(NewLRUCache(10, WithoutMetrics()), NewLRUCache(5, WithoutMetrics()), 10, WithoutMetrics())
+	assert.Equal(t, 15, bounded.Capacity())
+	disabled := NewCanaryCache(NewLRUCache(10, WithoutMetrics()), NewLRUCache(0, WithoutMetrics()), 10, WithoutMetrics())
+	assert.Equal(t, 10, disabled.Capacity())
+	unbounded := NewCanaryCache(NewLRUCache(10, WithoutMetrics()), NewLRUCache(Unbounded, WithoutMetrics()), 10, WithoutMetrics())
+	assert.Equal(t, Unbounded, unbounded.Capacity())
+	unbounded = NewCanaryCache(NewLRUCache(Unbounded, WithoutMetrics()), NewLRUCache(Unbounded, WithoutMetrics()), 10, WithoutMetrics())
+	assert.Equal(t, Unbounded, unbounded.Capacity())
+}
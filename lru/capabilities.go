@@ -0,0 +1,162 @@
+package lru
+
+// Peeker is implemented by the caches able to read an item without side effects: the item is not promoted,
+// not reclaimed if expired, and the read is not counted in the stats. An expired item is found until it is reclaimed,
+// use EntryInfo to only find the live ones.
+type Peeker interface {
+	Peek(key string) (value any, found bool)
+}
+
+// Iterator is implemented by the caches able to visit their items in eviction order.
+type Iterator interface {
+	// Range calls fn with every live item, from the most to the least valuable one, until fn returns false.
+	// The items are not promoted.
+	Range(fn func(key string, value any) bool)
+}
+
+// Resizer is implemented by the caches whose capacity can change after their construction.
+type Resizer interface {
+	// Resize changes the capacity of the cache, evicting items if it shrinks below the current length.
+	Resize(capacity int)
+}
+
+var _ Peeker = (*LRUCache)(nil)     // Ensure LRUCache supports peeks
+var _ Peeker = (*LFUCache)(nil)     // Ensure LFUCache supports peeks
+var _ Peeker = (*LRUKCache)(nil)    // Ensure LRUKCache supports peeks
+var _ Peeker = (*S3FIFOCache)(nil)  // Ensure S3FIFOCache supports peeks
+var _ Peeker = (*SafeLRUCache)(nil) // Ensure SafeLRUCache supports peeks
+var _ Peeker = (*ShardedCache)(nil) // Ensure ShardedCache supports peeks
+
+var _ Iterator = (*LRUCache)(nil)     // Ensure LRUCache can be iterated
+var _ Iterator = (*LFUCache)(nil)     // Ensure LFUCache can be iterated
+var _ Iterator = (*LRUKCache)(nil)    // Ensure LRUKCache can be iterated
+var _ Iterator = (*S3FIFOCache)(nil)  // Ensure S3FIFOCache can be iterated
+var _ Iterator = (*SafeLRUCache)(nil) // Ensure SafeLRUCache can be iterated
+var _ Iterator = (*ShardedCache)(nil) // Ensure ShardedCache can be iterated
+
+var _ Resizer = (*LRUCache)(nil)     // Ensure LRUCache can be resized
+var _ Resizer = (*LFUCache)(nil)     // Ensure LFUCache can be resized
+var _ Resizer = (*LRUKCache)(nil)    // Ensure LRUKCache can be resized
+var _ Resizer = (*S3FIFOCache)(nil)  // Ensure S3FIFOCache can be resized
+var _ Resizer = (*SafeLRUCache)(nil) // Ensure SafeLRUCache can be resized
+
+// Peek returns the value of the key without promoting it nor reclaiming it if expired.
+func (cache *LRUCache) Peek(key string) (value any, found bool) {
+	key = cache.transform.apply(key)
+	if elem, found := cache.items[key]; found {
+		return cache.load(elem.Value.(*entry)), true
+	}
+	return nil, false
+}
+
+// Range calls fn with every live item, from the most to the least recently used one, until fn returns false.
+// fn must not modify the cache.
+func (cache *LRUCache) Range(fn func(key string, value any) bool) {
+	now := cache.clock.Now()
+	for e := cache.usageOrder.Front(); e != nil; e = e.Next() {
+		if ent := e.Value.(*entry); !ent.hasExpired(now) && !fn(ent.key, cache.load(ent)) {
+			return
+		}
+	}
+}
+
+// Peek returns the value of the key without counting the access nor reclaiming it if expired.
+func (cache *LFUCache) Peek(key string) (value any, found bool) {
+	key = cache.transform.apply(key)
+	if elem, found := cache.items[key]; found {
+		return elem.Value.(*lfuEntry).value, true
+	}
+	return nil, false
+}
+
+// Range calls fn with every live item, from the most to the least frequently used one, until fn returns false.
+// fn must not modify the cache.
+func (cache *LFUCache) Range(fn func(key string, value any) bool) {
+	now := cache.clock.Now()
+	var live []*lfuEntry // Collected first, as eachByFrequency cannot be stopped
+	cache.eachByFrequency(func(ent *lfuEntry) {
+		if !ent.hasExpired(now) {
+			live = append(live, ent)
+		}
+	})
+	for _, ent := range live {
+		if !fn(ent.key, ent.value) {
+			return
+		}
+	}
+}
+
+// Resize changes the capacity of the cache. When the cache holds more items than the new capacity,
+// the expired items are removed first, then the least frequently used ones.
+func (cache *LFUCache) Resize(capacity int) {
+	cache.capacity = capacity
+	cache.counters.capacity.Store(int64(capacity))
+	if isOver(len(cache.items), capacity) {
+		cache.removeExpired()
+	}
+	for isOver(len(cache.items), capacity) {
+		cache.remove(cache.lowestFrequencyList().Back().Value.(*lfuEntry).key, metricReasonEvicted)
+	}
+}
+
+// Peek returns the value of the key without promoting it nor reclaiming it if expired,
+// or false if the underlying cache is not a Peeker. It is thread-safe.
+func (safeCache *SafeLRUCache) Peek(key string) (value any, found bool) {
+	key = safeCache.transform.apply(key)
+	safeCache.readLock()
+	defer safeCache.mutex.RUnlock()
+
+	if peeker, ok := safeCache.cache.(Peeker); ok {
+		return peeker.Peek(key)
+	}
+	return nil, false
+}
+
+// Range calls fn with every live item in eviction order, until fn returns false.
+// The items are collected under the lock and fn is called after releasing it, so fn may use the cache.
+// It does nothing if the underlying cache is not an Iterator.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) Range(fn func(key string, value any) bool) {
+	for _, item := range safeCache.items() {
+		if !fn(item.key, item.value) {
+			return
+		}
+	}
+}
+
+// items returns the live items in eviction order, collected under the lock.
+func (safeCache *SafeLRUCache) items() []keyValue {
+	safeCache.lock() // Apply the pending promotions, if any, so the order is up to date
+	defer safeCache.mutex.Unlock()
+
+	iterator, ok := safeCache.cache.(Iterator)
+	if !ok {
+		return nil
+	}
+	items := make([]keyValue, 0, safeCache.cache.Len())
+	iterator.Range(func(key string, value any) bool {
+		items = append(items, keyValue{key, value})
+		return true
+	})
+	return items
+}
+
+// Peek returns the value of the key from its shard without promoting it nor reclaiming it if expired.
+// It is thread-safe.
+func (sharded *ShardedCache) Peek(key string) (value any, found bool) {
+	key = sharded.transform.apply(key)
+	return sharded.shard(key).Peek(key)
+}
+
+// Range calls fn with the live items of every shard, shard after shard, each in its own eviction order,
+// until fn returns false. The shards are locked one after the other, and fn is called after releasing their lock.
+// It is thread-safe.
+func (sharded *ShardedCache) Range(fn func(key string, value any) bool) {
+	for _, shard := range sharded.shards {
+		for _, item := range shard.items() {
+			if !fn(item.key, item.value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rangeKeys returns the keys visited by Range, in order.
+func rangeKeys(iterator Iterator) []string {
+	var keys []string
+	iterator.Range(func(key string, value any) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func TestPeekDoesNotPromote(t *testing.T) {
+	for name, cache := range map[string]Cache{
+		"lru":  NewSafeLRUCache(2, WithoutMetrics()),
+		"lfu":  NewSafe(NewLFUCache(2, WithoutMetrics())),
+		"safe": NewSafe(NewLRUCache(2, WithoutMetrics())),
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache.Set("a", 1)
+			cache.Set("b", 2)
+
+			value, found := cache.(Peeker).Peek("a")
+			assert.True(t, found)
+			assert.Equal(t, 1, value)
+			assert.Equal(t, []string{"b", "a"}, rangeKeys(cache.(Iterator)), "Expected the peek to not promote a")
+		})
+	}
+}
+
+func TestPeekFindsExpiredItems(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLRUCache(2, WithClock(clock), WithoutMetrics())
+	cache.SetWithTTL("a", 1, time.Second)
+	clock.Advance(2 * time.Second)
+
+	_, found := cache.Peek("a")
+	assert.True(t, found, "Expected the expired item to be found until it is reclaimed")
+	assert.Empty(t, rangeKeys(cache), "Expected Range to skip the expired items")
+	assert.Equal(t, 1, cache.Len(), "Expected Peek to not reclaim the expired item")
+}
+
+func TestLFURange(t *testing.T) {
+	cache := NewLFUCache(3, WithoutMetrics())
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Set("c", 3)
+	cache.Get("b")
+	cache.Get("b")
+	cache.Get("a")
+
+	assert.Equal(t, []string{"b", "a", "c"}, rangeKeys(cache))
+
+	var first []string
+	cache.Range(func(key string, value any) bool {
+		first = append(first, key)
+		return false
+	})
+	assert.Equal(t, []string{"b"}, first, "Expected Range to stop when fn returns false")
+}
+
+func TestLFUResize(t *testing.T) {
+	cache := NewLFUCache(4, WithoutMetrics())
+	for _, key := range []string{"a", "b", "c", "d"} {
+		cache.Set(key, 1)
+	}
+	cache.Get("a")
+	cache.Get("a")
+	cache.Get("c")
+	cache.Remove("b")
+	cache.Remove("d") // Leaves no item with the minimum frequency
+
+	cache.Resize(1)
+	assert.Equal(t, 1, cache.Capacity())
+	assert.Equal(t, []string{"a"}, rangeKeys(cache), "Expected the least frequently used item to be evicted")
+}
+
+func TestLFUResizeThenSet(t *testing.T) {
+	cache := NewLFUCache(3, WithoutMetrics())
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Set(key, 1)
+	}
+	for range 5 {
+		cache.Get("b")
+		cache.Get("c")
+	}
+
+	cache.Resize(2) // Evicts a, emptying the list of the minimum frequency
+	cache.Set("d", 1)
+	cache.Set("e", 1)
+	assert.Equal(t, 2, cache.Len(), "Expected the sets after Resize to evict")
+	assert.Equal(t, 2, cache.Capacity())
+}
+
+func TestSafeCacheFeatureDetection(t *testing.T) {
+	safeCache := NewSafe(&fakeLRUCache{})
+	_, found := safeCache.Peek("a")
+	assert.False(t, found)
+	assert.Empty(t, rangeKeys(safeCache))
+	assert.NotPanics(t, func() { safeCache.Resize(1) })
+}
+
+func TestShardedRangeAndPeek(t *testing.T) {
+	sharded := NewShardedCache(4, 40, WithoutMetrics())
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		sharded.Set(key, key)
+	}
+
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d", "e"}, rangeKeys(sharded))
+	value, found := sharded.Peek("c")
+	assert.True(t, found)
+	assert.Equal(t, "c", value)
+}
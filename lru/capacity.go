@@ -0,0 +1,26 @@
+package lru
+
+import (
+	"errors"
+)
+
+// ErrDisabled is returned by Increment when the cache is disabled by a capacity of zero, so nothing was stored.
+var ErrDisabled = errors.New("lru: cache disabled")
+
+// Unbounded is the capacity of a cache that never evicts to make room: its items only leave it when they
+// expire or are removed, or to enforce the quotas and the watermarks. Any negative capacity is unbounded.
+// A capacity of zero disables the cache instead: nothing is stored, the writes returning SetDisabled,
+// so caching can be turned off from the configuration without changing the callers.
+const Unbounded = -1
+
+// isFull returns whether a cache of the given capacity holding length items must make room before adding one.
+// An unbounded cache is never full.
+func isFull(length, capacity int) bool {
+	return capacity >= 0 && length >= capacity
+}
+
+// isOver returns whether a cache of the given capacity holding length items exceeds it.
+// An unbounded cache never does.
+func isOver(length, capacity int) bool {
+	return capacity >= 0 && length > capacity
+}
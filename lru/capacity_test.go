@@ -0,0 +1,76 @@
+package lru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// capacityPolicies returns a constructor of every eviction policy, by name.
+func capacityPolicies() map[string]func(capacity int) Cache {
+	return map[string]func(capacity int) Cache{
+		"lru":     func(capacity int) Cache { return NewLRUCache(capacity, WithoutMetrics()) },
+		"lfu":     func(capacity int) Cache { return NewLFUCache(capacity, WithoutMetrics()) },
+		"lruk":    func(capacity int) Cache { return NewLRUKCache(capacity, WithoutMetrics()) },
+		"s3fifo":  func(capacity int) Cache { return NewS3FIFOCache(capacity, WithoutMetrics()) },
+		"sampled": func(capacity int) Cache { return NewSampledLRUCache(capacity, WithoutMetrics()) },
+		"sharded": func(capacity int) Cache { return NewShardedCache(4, capacity, WithoutMetrics()) },
+	}
+}
+
+func TestZeroCapacityDisablesTheCache(t *testing.T) {
+	for name, create := range capacityPolicies() {
+		cache := create(0)
+		assert.Equal(t, SetDisabled, cache.Set("key1", "value1").Status, name)
+		assert.Equal(t, SetDisabled, cache.SetWithTTL("key2", "value2", time.Minute).Status, name)
+		_, found := cache.Get("key1")
+		assert.False(t, found, name)
+		assert.Zero(t, cache.Len(), name)
+		if incrementer, ok := cache.(Incrementer); ok {
+			_, err := incrementer.Increment("counter", 1, 0)
+			assert.ErrorIs(t, err, ErrDisabled, name)
+		}
+	}
+}
+
+func TestUnboundedCapacity(t *testing.T) {
+	for name, create := range capacityPolicies() {
+		cache := create(Unbounded)
+		for i := range 1000 {
+			result := cache.Set(fmt.Sprint("key", i), i)
+			assert.False(t, result.Evicted, name)
+		}
+		assert.Equal(t, 1000, cache.Len(), name)
+		assert.Equal(t, Unbounded, cache.Capacity(), name)
+		value, found := cache.Get("key0")
+		assert.True(t, found, name)
+		assert.Equal(t, 0, value, name)
+	}
+}
+
+func TestUnboundedCapacityExpires(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLRUCache(Unbounded, WithClock(clock), WithoutMetrics())
+	cache.SetWithTTL("key1", "value1", time.Second)
+	cache.Set("key2", "value2")
+	clock.Advance(2 * time.Second)
+
+	assert.Equal(t, 1, cache.RemoveExpired())
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestResizeToUnboundedAndDisabled(t *testing.T) {
+	cache := NewLRUCache(2, WithoutMetrics())
+	cache.Set("key1", "value1")
+	cache.Set("key2", "value2")
+
+	cache.Resize(Unbounded)
+	cache.Set("key3", "value3")
+	assert.Equal(t, 3, cache.Len(), "nothing is evicted once unbounded")
+
+	cache.Resize(0)
+	assert.Zero(t, cache.Len(), "disabling the cache drops its items")
+	assert.Equal(t, SetDisabled, cache.Set("key4", "value4").Status)
+}
@@ -0,0 +1,90 @@
+package lru
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time to the caches.
+// It allows expiration to be controlled in tests and demos instead of waiting in real time.
+// The expirations are compared on the monotonic clock when the times carry a monotonic reading, like those of time.Now,
+// and on the wall clock otherwise.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the default Clock, backed by time.Now.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a Clock that only moves forward when it is told to.
+// It is thread-safe, so it can be shared by several caches and advanced from another goroutine.
+type ManualClock struct {
+	mutex   sync.Mutex     // Protects now and waiters
+	now     time.Time      // The current time reported by the clock
+	waiters []manualWaiter // Channels returned by After, not fired yet
+}
+
+// manualWaiter is a channel returned by ManualClock.After, fired once the clock reaches its deadline.
+type manualWaiter struct {
+	deadline time.Time
+	fired    chan time.Time
+}
+
+// afterClock is implemented by the clocks able to wake a goroutine once they moved by a duration, like ManualClock.
+// The goroutines waiting for the other clocks, e.g. of an InvalidationScheduler, wait in real time.
+type afterClock interface {
+	After(duration time.Duration) <-chan time.Time
+}
+
+var _ afterClock = (*ManualClock)(nil) // Ensure ManualClock can wake the goroutines waiting on it
+
+var _ Clock = (*ManualClock)(nil) // Ensure ManualClock implements the Clock interface
+
+// NewManualClock creates a ManualClock starting at the given time.
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now returns the current time of the clock.
+func (clock *ManualClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+// Advance moves the clock forward by the given duration and returns the new time.
+func (clock *ManualClock) Advance(duration time.Duration) time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	clock.now = clock.now.Add(duration)
+	clock.waiters = slices.DeleteFunc(clock.waiters, func(waiter manualWaiter) bool {
+		if waiter.deadline.After(clock.now) {
+			return false
+		}
+		waiter.fired <- clock.now
+		return true
+	})
+	return clock.now
+}
+
+// After returns a channel receiving the time of the clock once it is advanced by at least the duration, like time.After,
+// so the goroutines waiting on the clock, such as the one of an InvalidationScheduler, follow it instead of the real time.
+func (clock *ManualClock) After(duration time.Duration) <-chan time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	fired := make(chan time.Time, 1) // Buffered so Advance never blocks
+	if duration <= 0 {
+		fired <- clock.now
+		return fired
+	}
+	clock.waiters = append(clock.waiters, manualWaiter{deadline: clock.now.Add(duration), fired: fired})
+	return fired
+}
@@ -0,0 +1,83 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManualClockAdvance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	assert.Equal(t, start, clock.Now())
+
+	now := clock.Advance(time.Minute)
+	assert.Equal(t, start.Add(time.Minute), now)
+	assert.Equal(t, now, clock.Now())
+}
+
+func TestManualClockAfter(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	fired := clock.After(time.Minute)
+	assert.Equal(t, clock.Now(), <-clock.After(0), "Expected a zero duration to fire at once")
+
+	clock.Advance(30 * time.Second)
+	assert.Empty(t, fired)
+	now := clock.Advance(time.Minute)
+	assert.Equal(t, now, <-fired, "Expected the channel to receive the time the clock reached")
+}
+
+func TestExpirationWithManualClock(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLRUCache(5, WithClock(clock))
+	cache.SetWithTTL("key1", "value1", time.Minute)
+
+	clock.Advance(59 * time.Second)
+	value, found := cache.Get("key1")
+	assert.True(t, found)
+	assert.Equal(t, "value1", value)
+
+	clock.Advance(2 * time.Second)
+	value, found = cache.Get("key1")
+	assert.False(t, found)
+	assert.Nil(t, value)
+}
+
+func TestLFUExpirationWithManualClock(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLFUCache(5, WithClock(clock))
+	cache.SetWithTTL("key1", "value1", time.Minute)
+
+	clock.Advance(2 * time.Minute)
+	_, found := cache.Get("key1")
+	assert.False(t, found)
+}
+
+func TestMonotonicDeadlines(t *testing.T) {
+	now := time.Now()
+	deadline := now.Add(time.Minute)
+
+	wall := wallDeadline(deadline, now)
+	assert.True(t, wall.Equal(deadline))
+	assert.Equal(t, wall.Round(0), wall, "Expected the wall-clock deadline to drop the monotonic reading")
+
+	restored := monotonicDeadline(wall, now)
+	assert.True(t, restored.Equal(deadline))
+	assert.NotEqual(t, restored.Round(0), restored, "Expected the restored deadline to be compared on the monotonic clock")
+	assert.True(t, wallDeadline(time.Time{}, now).IsZero())
+	assert.True(t, monotonicDeadline(time.Time{}, now).IsZero())
+}
+
+func TestRestoredExpirationsAreMonotonic(t *testing.T) {
+	cache := NewLRUCache(2, WithoutMetrics())
+	cache.SetWithTTL("key1", "value1", time.Minute)
+	data, err := cache.MarshalJSON()
+	assert.NoError(t, err)
+
+	restored := NewLRUCache(2, WithoutMetrics())
+	assert.NoError(t, restored.UnmarshalJSON(data))
+	expiresAt := restored.items["key1"].Value.(*entry).expiresAt
+	assert.NotEqual(t, expiresAt.Round(0), expiresAt, "Expected the persisted wall-clock expiration to become a monotonic deadline")
+	assert.WithinDuration(t, time.Now().Add(time.Minute), expiresAt, time.Second)
+}
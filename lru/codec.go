@@ -0,0 +1,54 @@
+package lru
+
+import (
+	"errors"
+)
+
+// ErrUnsupportedValue is returned by a Codec when it cannot encode a value of the given type.
+var ErrUnsupportedValue = errors.New("value not supported by the codec")
+
+// Codec converts the cached values to bytes and back, for the storage modes keeping them serialized.
+// Decode must accept every output of Encode, and copy the data if it keeps a reference to it,
+// as the slice belongs to the storage and is reused once the item is removed.
+type Codec interface {
+	Encode(value any) ([]byte, error)
+	Decode(data []byte) (any, error)
+}
+
+// BytesCodec stores []byte values as they are.
+type BytesCodec struct{}
+
+var _ Codec = BytesCodec{} // Ensure BytesCodec implements the Codec interface
+
+// Encode returns the value itself, which must be a []byte.
+func (BytesCodec) Encode(value any) ([]byte, error) {
+	data, ok := value.([]byte)
+	if !ok {
+		return nil, ErrUnsupportedValue
+	}
+	return data, nil
+}
+
+// Decode returns a copy of the data.
+func (BytesCodec) Decode(data []byte) (any, error) {
+	return append([]byte(nil), data...), nil
+}
+
+// StringCodec stores string values as their bytes.
+type StringCodec struct{}
+
+var _ Codec = StringCodec{} // Ensure StringCodec implements the Codec interface
+
+// Encode returns the bytes of the value, which must be a string.
+func (StringCodec) Encode(value any) ([]byte, error) {
+	text, ok := value.(string)
+	if !ok {
+		return nil, ErrUnsupportedValue
+	}
+	return []byte(text), nil
+}
+
+// Decode returns the data as a string.
+func (StringCodec) Decode(data []byte) (any, error) {
+	return string(data), nil
+}
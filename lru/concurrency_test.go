@@ -0,0 +1,124 @@
+package lru
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stress runs every operation of the cache from several goroutines at once,
+// so the race detector can catch unsynchronized accesses with go test -race.
+func stress(t *testing.T, cache *SafeLRUCache) {
+	t.Helper()
+
+	const (
+		goroutines = 8
+		operations = 500
+	)
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range operations {
+				key := fmt.Sprintf("key%d", (g*operations+i)%64)
+				switch i % 10 {
+				case 0:
+					cache.Set(key, i)
+				case 1:
+					cache.SetWithTTL(key, i, time.Millisecond)
+				case 2:
+					cache.Remove(key)
+				case 3:
+					cache.Resize(16 + i%32)
+				case 4:
+					cache.ScanPrefix("key1", func(string, any) bool { return true })
+				case 5:
+					cache.RemoveExpired()
+				case 6:
+					_, _ = cache.Increment("counter", 1, 0)
+				case 7:
+					cache.Len()
+				default:
+					cache.Get(key)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	cache.Flush()
+
+	assert.LessOrEqual(t, cache.Len(), cache.Capacity(), "Expected the cache to stay within its capacity")
+}
+
+func TestSafeLRUCacheStress(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "synchronous"},
+		{name: "write buffer", opts: []Option{WithWriteBuffer(16)}},
+		{name: "access buffer", opts: []Option{WithAccessBuffer(16)}},
+		{name: "janitor", opts: []Option{WithJanitor(time.Millisecond)}},
+		{name: "key index", opts: []Option{WithKeyIndex(), WithExpiryBuckets(time.Millisecond)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cache := NewSafeLRUCache(32, append(test.opts, WithoutMetrics())...)
+			defer cache.Close()
+
+			stress(t, cache)
+		})
+	}
+}
+
+func TestSafeLRUCacheIncrementAfterBufferedSet(t *testing.T) {
+	cache := NewSafeLRUCache(10, WithWriteBuffer(100), WithoutMetrics())
+	defer cache.Close()
+
+	for i := range 50 {
+		key := fmt.Sprintf("counter%d", i)
+		cache.Set(key, int64(10))
+		value, err := cache.Increment(key, 1, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(11), value, "Expected Increment to apply after the buffered Set of the same goroutine")
+	}
+}
+
+func TestSafeLRUCacheResizeAfterBufferedSet(t *testing.T) {
+	cache := NewSafeLRUCache(10, WithWriteBuffer(100), WithoutMetrics())
+	defer cache.Close()
+
+	for i := range 10 {
+		cache.Set(fmt.Sprintf("key%d", i), i)
+	}
+	cache.Resize(5)
+
+	assert.Equal(t, 5, cache.Len(), "Expected Resize to evict the items buffered before it")
+}
+
+func TestShardedCacheConcurrentRebalance(t *testing.T) {
+	cache := NewShardedCache(4, 100, WithRebalancing(time.Hour), WithoutMetrics())
+	defer cache.Close()
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				cache.Set(fmt.Sprintf("key%d", (g*200+i)%40), i)
+				if i%20 == 0 {
+					cache.Rebalance()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 100, cache.Capacity(), "Expected concurrent rebalancing to keep the total capacity")
+	assert.LessOrEqual(t, cache.Len(), cache.Capacity())
+}
@@ -0,0 +1,137 @@
+package lru
+
+// DebugInfo reports the sizes of the internal structures of a cache, to see where the memory goes when profiling.
+// The structures and their names depend on the policy and the options of the cache.
+type DebugInfo struct {
+	Len        int            `json:"len"`
+	Capacity   int            `json:"capacity"`
+	Structures map[string]int `json:"structures"` // Number of elements of every internal structure, by name
+}
+
+// Debugger is implemented by the caches reporting their DebugInfo.
+type Debugger interface {
+	DebugInfo() DebugInfo
+}
+
+var _ Debugger = (*LRUCache)(nil)     // Ensure LRUCache reports its debug info
+var _ Debugger = (*LFUCache)(nil)     // Ensure LFUCache reports its debug info
+var _ Debugger = (*SafeLRUCache)(nil) // Ensure SafeLRUCache reports its debug info
+var _ Debugger = (*ShardedCache)(nil) // Ensure ShardedCache reports its debug info
+
+// DebugInfo returns the sizes of the usage list, the expiration index, and of the structures of the enabled options.
+// Some sizes are counted by visiting the structures, so it costs O(n).
+func (cache *LRUCache) DebugInfo() DebugInfo {
+	structures := map[string]int{
+		"items":       len(cache.items),
+		"usage_order": cache.usageOrder.Len(),
+		"expiries":    len(cache.expiries),
+	}
+	if cache.buckets != nil {
+		structures["expiry_buckets"] = len(cache.buckets.buckets)
+	}
+	if cache.keys != nil {
+		structures["key_index"] = cache.keys.size()
+	}
+	for name, index := range cache.indexes {
+		structures["index_"+name+"_terms"] = len(index.keys)
+	}
+	if cache.slabs != nil {
+		structures["slabs"], structures["slab_bytes"] = cache.slabs.size()
+	}
+	if cache.ghosts != nil {
+		structures["ghosts"] = cache.ghosts.size()
+	}
+	return DebugInfo{Len: cache.usageOrder.Len(), Capacity: cache.capacity, Structures: structures}
+}
+
+// DebugInfo returns the sizes of the frequency lists, the expiration index, and the ghost list if enabled.
+func (cache *LFUCache) DebugInfo() DebugInfo {
+	structures := map[string]int{
+		"items":       len(cache.items),
+		"frequencies": len(cache.frequencies),
+		"expiries":    len(cache.expiries),
+	}
+	if cache.ghosts != nil {
+		structures["ghosts"] = cache.ghosts.size()
+	}
+	return DebugInfo{Len: len(cache.items), Capacity: cache.capacity, Structures: structures}
+}
+
+// DebugInfo returns the debug info of the underlying cache, or only its length and capacity if it does not
+// implement Debugger, along with the pending accesses and writes of the buffers.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) DebugInfo() DebugInfo {
+	safeCache.mutex.RLock() // Without applying the buffers, to report them
+	defer safeCache.mutex.RUnlock()
+
+	info := DebugInfo{Len: safeCache.cache.Len(), Capacity: safeCache.cache.Capacity(), Structures: make(map[string]int)}
+	if debugger, ok := safeCache.cache.(Debugger); ok {
+		info = debugger.DebugInfo()
+	}
+	if safeCache.accesses != nil {
+		info.Structures["pending_accesses"] = len(safeCache.accesses.keys)
+	}
+	if safeCache.writes != nil {
+		info.Structures["pending_writes"] = len(safeCache.writes.ops)
+	}
+	return info
+}
+
+// DebugInfo returns the sum of the debug info of the shards.
+// Shards are locked one after the other, so the result is not a consistent snapshot under concurrent writes.
+func (sharded *ShardedCache) DebugInfo() DebugInfo {
+	total := DebugInfo{Structures: map[string]int{"shards": len(sharded.shards)}}
+	for _, shard := range sharded.shards {
+		info := shard.DebugInfo()
+		total.Len += info.Len
+		total.Capacity += info.Capacity
+		for name, size := range info.Structures {
+			total.Structures[name] += size
+		}
+	}
+	return total
+}
+
+// DebugInfo returns the debug info of the cache, along with the sizes of the state journal and of the subscribers.
+func (observable *ObservableCache) DebugInfo() DebugInfo {
+	info := observable.Cache.DebugInfo()
+
+	observable.journal.mutex.Lock()
+	info.Structures["state_journal"] = len(observable.journal.changes)
+	observable.journal.mutex.Unlock()
+
+	if observable.shadow != nil {
+		info.Structures["shadow_pending"] = observable.shadow.pendingChanges()
+	}
+
+	observable.subscribersMutex.Lock()
+	info.Structures["subscribers"] = len(observable.subscribers)
+	observable.subscribersMutex.Unlock()
+	return info
+}
+
+// size returns the number of keys remembered.
+func (ghost *ghostList) size() int {
+	ghost.mutex.Lock()
+	defer ghost.mutex.Unlock()
+
+	return len(ghost.evicted)
+}
+
+// size returns the number of keys of the index, visiting them.
+func (index *keyIndex) size() int {
+	size := 0
+	for node := index.head.next[0]; node != nil; node = node.next[0] {
+		size++
+	}
+	return size
+}
+
+// size returns the number of slabs allocated, and their total size in bytes.
+func (store *slabStore) size() (slabs, bytes int) {
+	for _, class := range store.classes {
+		slabs += len(class.slabs)
+		bytes += len(class.slabs) * store.slabSize
+	}
+	return slabs, bytes
+}
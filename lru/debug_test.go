@@ -0,0 +1,45 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUDebugInfo(t *testing.T) {
+	cache := NewLRUCache(3, WithKeyIndex(), WithGhostList(10))
+	cache.Set("key1", "value1")
+	cache.SetWithTTL("key2", "value2", time.Minute)
+	cache.Set("key3", "value3")
+	cache.Set("key4", "value4") // Evicts key1
+
+	info := cache.DebugInfo()
+	assert.Equal(t, 3, info.Len)
+	assert.Equal(t, 3, info.Capacity)
+	assert.Equal(t, map[string]int{"items": 3, "usage_order": 3, "expiries": 1, "key_index": 3, "ghosts": 1}, info.Structures)
+}
+
+func TestShardedDebugInfo(t *testing.T) {
+	cache := NewShardedCache(2, 4, WithAccessBuffer(8))
+	cache.Set("key1", "value1")
+	cache.Set("key2", "value2")
+
+	info := cache.DebugInfo()
+	assert.Equal(t, 2, info.Len)
+	assert.Equal(t, 4, info.Capacity)
+	assert.Equal(t, 2, info.Structures["shards"])
+	assert.Equal(t, 2, info.Structures["items"])
+	assert.Contains(t, info.Structures, "pending_accesses")
+}
+
+func TestObservableDebugInfo(t *testing.T) {
+	observable := NewObservableCache(2)
+	_, unsubscribe := observable.Subscribe(1)
+	defer unsubscribe()
+	observable.Cache.Set("key1", "value1")
+
+	info := observable.DebugInfo()
+	assert.Equal(t, 1, info.Structures["state_journal"])
+	assert.Equal(t, 1, info.Structures["subscribers"])
+}
@@ -0,0 +1,176 @@
+package lru
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"reflect"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ValueEqualFunc returns whether the values of a key read from two caches are the same.
+type ValueEqualFunc func(primary, secondary any) bool
+
+const (
+	metricCompareMatch         = "match"          // Both caches returned the same value, or both missed
+	metricCompareMismatch      = "mismatch"       // Both caches found the key with different values
+	metricComparePrimaryOnly   = "primary_only"   // Only the primary cache found the key
+	metricCompareSecondaryOnly = "secondary_only" // Only the secondary cache found the key
+
+	metricWriteMatch    = "match"    // Both caches stored the write, or both did not
+	metricWriteDiverged = "diverged" // Only one of the caches stored the write
+)
+
+// WithReadComparison makes a DualWriteCache also read the given share of the keys read, between 0 and 1,
+// from its secondary cache, comparing the values with equal, or reflect.DeepEqual if nil. The outcomes
+// are counted in cache_dual_write_reads_total. Ignored by the caches.
+func WithReadComparison(rate float64, equal ValueEqualFunc) Option {
+	return func(o *options) {
+		o.compareRate = rate
+		o.compareEqual = equal
+	}
+}
+
+// DualWriteCache mirrors the writes made to a primary cache to a secondary one, for gradual migrations and
+// shadow testing: the secondary cache, e.g. with another policy or a remote cache adapted to the Cache interface,
+// receives the same traffic while the primary one keeps serving the reads. It is thread-safe if both caches are.
+type DualWriteCache struct {
+	primary     Cache
+	secondary   Cache
+	compareRate float64        // Share of the reads compared with the secondary cache
+	equal       ValueEqualFunc // Compares the values read from both caches
+	metrics     *dualWriteMetrics
+}
+
+var _ Cache = (*DualWriteCache)(nil) // Ensure DualWriteCache implements the Cache interface
+
+// NewDualWriteCache creates a DualWriteCache serving the reads from primary and writing to both caches.
+// Only WithReadComparison, WithMetricsName and WithoutMetrics are used among the options:
+// the metrics are labeled "dual_write" by default.
+func NewDualWriteCache(primary, secondary Cache, opts ...Option) *DualWriteCache {
+	o := newOptions(opts...)
+	dual := &DualWriteCache{
+		primary:     primary,
+		secondary:   secondary,
+		compareRate: o.compareRate,
+		equal:       o.compareEqual,
+	}
+	if dual.equal == nil {
+		dual.equal = func(primary, secondary any) bool { return reflect.DeepEqual(primary, secondary) }
+	}
+	if o.metrics {
+		dual.metrics = newDualWriteMetrics(cmp.Or(o.name, metricCacheTypeDualWrite))
+	}
+	return dual
+}
+
+// Get retrieves an item from the primary cache by its key. With WithReadComparison, a share of the keys
+// is also read from the secondary cache, and the values compared.
+func (dual *DualWriteCache) Get(key string) (any, bool) {
+	value, found := dual.primary.Get(key)
+	if dual.compareRate > 0 && rand.Float64() < dual.compareRate {
+		secondaryValue, secondaryFound := dual.secondary.Get(key)
+		switch {
+		case found && secondaryFound && !dual.equal(value, secondaryValue):
+			dual.metrics.compared(metricCompareMismatch)
+		case found && !secondaryFound:
+			dual.metrics.compared(metricComparePrimaryOnly)
+		case !found && secondaryFound:
+			dual.metrics.compared(metricCompareSecondaryOnly)
+		default:
+			dual.metrics.compared(metricCompareMatch)
+		}
+	}
+	return value, found
+}
+
+// Set adds or updates an item in both caches with no expiration, returning the result of the primary cache.
+func (dual *DualWriteCache) Set(key string, value any) SetResult {
+	result := dual.primary.Set(key, value)
+	dual.metrics.wrote(result, dual.secondary.Set(key, value))
+	return result
+}
+
+// SetWithTTL adds or updates an item in both caches with a specified expiration time,
+// returning the result of the primary cache.
+func (dual *DualWriteCache) SetWithTTL(key string, value any, ttl time.Duration) SetResult {
+	result := dual.primary.SetWithTTL(key, value, ttl)
+	dual.metrics.wrote(result, dual.secondary.SetWithTTL(key, value, ttl))
+	return result
+}
+
+// Remove deletes an item from both caches by key.
+func (dual *DualWriteCache) Remove(key string) {
+	dual.primary.Remove(key)
+	dual.secondary.Remove(key)
+}
+
+// Len returns the number of items currently in the primary cache.
+func (dual *DualWriteCache) Len() int {
+	return dual.primary.Len()
+}
+
+// Capacity returns the maximum number of items that can be stored in the primary cache.
+func (dual *DualWriteCache) Capacity() int {
+	return dual.primary.Capacity()
+}
+
+// Primary returns the cache serving the reads.
+func (dual *DualWriteCache) Primary() Cache {
+	return dual.primary
+}
+
+// Secondary returns the cache receiving the mirrored writes.
+func (dual *DualWriteCache) Secondary() Cache {
+	return dual.secondary
+}
+
+// writeStored returns whether the write was stored, or will be once buffered.
+func writeStored(result SetResult) bool {
+	switch result.Status {
+	case SetAdded, SetUpdated, SetBuffered:
+		return true
+	}
+	return false
+}
+
+// dualWriteMetrics holds the counters of a DualWriteCache. A nil *dualWriteMetrics records nothing.
+type dualWriteMetrics struct {
+	reads  map[string]prometheus.Counter // Compared reads by result
+	writes map[bool]prometheus.Counter   // Mirrored writes by whether they diverged
+}
+
+// newDualWriteMetrics resolves the counters of the DualWriteCache with the given name.
+func newDualWriteMetrics(name string) *dualWriteMetrics {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	vecs := currentMetrics
+	return &dualWriteMetrics{
+		reads: map[string]prometheus.Counter{
+			metricCompareMatch:         vecs.dualWriteReads.WithLabelValues(name, metricCompareMatch),
+			metricCompareMismatch:      vecs.dualWriteReads.WithLabelValues(name, metricCompareMismatch),
+			metricComparePrimaryOnly:   vecs.dualWriteReads.WithLabelValues(name, metricComparePrimaryOnly),
+			metricCompareSecondaryOnly: vecs.dualWriteReads.WithLabelValues(name, metricCompareSecondaryOnly),
+		},
+		writes: map[bool]prometheus.Counter{
+			false: vecs.dualWriteWrites.WithLabelValues(name, metricWriteMatch),
+			true:  vecs.dualWriteWrites.WithLabelValues(name, metricWriteDiverged),
+		},
+	}
+}
+
+// compared counts a read compared with the secondary cache.
+func (metrics *dualWriteMetrics) compared(result string) {
+	if metrics != nil {
+		metrics.reads[result].Inc()
+	}
+}
+
+// wrote counts a write mirrored to the secondary cache, diverging when only one of the caches stored it.
+func (metrics *dualWriteMetrics) wrote(primary, secondary SetResult) {
+	if metrics != nil {
+		metrics.writes[writeStored(primary) != writeStored(secondary)].Inc()
+	}
+}
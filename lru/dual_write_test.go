@@ -0,0 +1,58 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDualWriteCache(t *testing.T) {
+	primary := NewLRUCache(10, WithoutMetrics())
+	secondary := NewLFUCache(1, WithoutMetrics())
+	cache := NewDualWriteCache(primary, secondary, WithMetricsName("dual_write_test"), WithReadComparison(1, nil))
+
+	assert.Equal(t, SetAdded, cache.Set("key1", "value1").Status)
+	assert.Equal(t, SetAdded, cache.SetWithTTL("key2", "value2", time.Minute).Status)
+	_, found := secondary.Get("key2")
+	assert.True(t, found, "Expected the writes to be mirrored")
+
+	value, found := cache.Get("key2")
+	assert.True(t, found)
+	assert.Equal(t, "value2", value)
+	value, found = cache.Get("key1") // Evicted from the smaller secondary cache
+	assert.True(t, found)
+	assert.Equal(t, "value1", value)
+	secondary.Set("key3", "value3")
+	_, found = cache.Get("key3")
+	assert.False(t, found, "Expected the reads to be served by the primary cache")
+	secondary.Set("key2", "other")
+	primary.Set("key2", "value2")
+	cache.Get("key2")
+
+	reads := func(result string) float64 {
+		return counterValue(currentMetrics.dualWriteReads.WithLabelValues("dual_write_test", result))
+	}
+	assert.Equal(t, 1.0, reads(metricCompareMatch))
+	assert.Equal(t, 1.0, reads(metricComparePrimaryOnly))
+	assert.Equal(t, 1.0, reads(metricCompareSecondaryOnly))
+	assert.Equal(t, 1.0, reads(metricCompareMismatch))
+	assert.Equal(t, 2.0, counterValue(currentMetrics.dualWriteWrites.WithLabelValues("dual_write_test", metricWriteMatch)))
+
+	cache.Remove("key2")
+	_, found = secondary.Get("key2")
+	assert.False(t, found)
+	assert.Equal(t, primary.Len(), cache.Len())
+}
+
+func TestDualWriteCacheDivergence(t *testing.T) {
+	primary := NewLRUCache(10, WithoutMetrics())
+	secondary := NewLRUCache(0, WithoutMetrics()) // Stores nothing
+	cache := NewDualWriteCache(primary, secondary, WithMetricsName("dual_write_divergence"))
+
+	cache.Set("key1", "value1")
+	assert.Equal(t, 1.0, counterValue(currentMetrics.dualWriteWrites.WithLabelValues("dual_write_divergence", metricWriteDiverged)))
+	cache.Get("key1")
+	assert.Zero(t, counterValue(currentMetrics.dualWriteReads.WithLabelValues("dual_write_divergence", metricComparePrimaryOnly)),
+		"Expected the reads not to be compared without WithReadComparison")
+}
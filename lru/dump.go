@@ -0,0 +1,78 @@
+package lru
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DumpFormat is the format of the items written by Snapshot.Dump.
+type DumpFormat string
+
+const (
+	DumpJSONLines DumpFormat = "jsonl" // One JSON object per item, with its key, value and expires_at
+	DumpCSV       DumpFormat = "csv"   // A key,value,expires_at header, then one row per item
+)
+
+// ErrUnknownDumpFormat is returned by Snapshot.Dump for a format other than DumpJSONLines and DumpCSV.
+var ErrUnknownDumpFormat = errors.New("lru: unknown dump format")
+
+// dumpLine is an item written by Snapshot.Dump in the DumpJSONLines format.
+type dumpLine struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	ExpiresAt string `json:"expires_at,omitempty"` // RFC 3339, empty if the item does not expire
+}
+
+// Dump writes the items of the snapshot to w in the given format, in eviction order, the next candidate last,
+// e.g. to capture the contents of a cache during an incident. The values are rendered by renderer,
+// or formatted with %v if nil; Redact hides the values of the sensitive keys.
+func (snapshot Snapshot) Dump(w io.Writer, format DumpFormat, renderer ValueRenderer) error {
+	if renderer == nil {
+		renderer = func(_ string, value any) string { return fmt.Sprintf("%v", value) }
+	}
+	line := func(entry EntryInfo) dumpLine {
+		dumped := dumpLine{Key: entry.Key, Value: renderer(entry.Key, entry.Value)}
+		if !entry.ExpiresAt.IsZero() {
+			dumped.ExpiresAt = entry.ExpiresAt.Format(time.RFC3339Nano)
+		}
+		return dumped
+	}
+
+	switch format {
+	case DumpJSONLines:
+		buffered := bufio.NewWriter(w)
+		encoder := json.NewEncoder(buffered)
+		var err error
+		snapshot.Range(func(entry EntryInfo) bool {
+			err = encoder.Encode(line(entry))
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+		return buffered.Flush()
+	case DumpCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"key", "value", "expires_at"}); err != nil {
+			return err
+		}
+		var err error
+		snapshot.Range(func(entry EntryInfo) bool {
+			dumped := line(entry)
+			err = writer.Write([]string{dumped.Key, dumped.Value, dumped.ExpiresAt})
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownDumpFormat, format)
+	}
+}
@@ -0,0 +1,43 @@
+package lru
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpJSONLines(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := NewLRUCache(5, WithClock(clock), WithoutMetrics())
+	cache.SetWithTTL("session:1", "token", time.Minute)
+	cache.Set("user:1", 42)
+
+	var out bytes.Buffer
+	redacted := Redact(nil, func(key string) bool { return strings.HasPrefix(key, "session:") })
+	assert.NoError(t, cache.Snapshot().Dump(&out, DumpJSONLines, redacted))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2)
+	var first, second dumpLine
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, dumpLine{Key: "user:1", Value: "42"}, first, "Expected the most recently used item first")
+	assert.Equal(t, "session:1", second.Key)
+	assert.Equal(t, "[redacted]", second.Value)
+	assert.NotEmpty(t, second.ExpiresAt)
+}
+
+func TestDumpCSV(t *testing.T) {
+	cache := NewLRUCache(5, WithoutMetrics())
+	cache.Set("key1", "a,b")
+
+	var out bytes.Buffer
+	assert.NoError(t, cache.Snapshot().Dump(&out, DumpCSV, nil))
+	assert.Equal(t, "key,value,expires_at\nkey1,\"a,b\",\n", out.String())
+
+	assert.ErrorIs(t, cache.Snapshot().Dump(&out, "xml", nil), ErrUnknownDumpFormat)
+}
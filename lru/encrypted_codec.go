@@ -0,0 +1,136 @@
+package lru
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrDecryption is returned by an EncryptedCodec when a value cannot be authenticated,
+// e.g. because it was tampered with or encrypted with an unknown key.
+var ErrDecryption = errors.New("lru: value decryption failed")
+
+// KeyProvider supplies the AES keys of an EncryptedCodec, e.g. from a KMS or a secret manager.
+// Keys are identified so they can be rotated: new values are encrypted with the current key,
+// and the values encrypted before a rotation are decrypted with the key of their id.
+// The key of an id must never change. Keys must be 16, 24 or 32 bytes long, for AES-128, AES-192 or AES-256.
+type KeyProvider interface {
+	CurrentKey() (id uint32, key []byte, err error)
+	Key(id uint32) ([]byte, error)
+}
+
+// StaticKeys is a KeyProvider holding its keys in memory, by id.
+type StaticKeys struct {
+	Current uint32            // Id of the key encrypting the new values
+	Keys    map[uint32][]byte // Every key that may still be needed to decrypt a value
+}
+
+var _ KeyProvider = StaticKeys{} // Ensure StaticKeys implements the KeyProvider interface
+
+// CurrentKey returns the key with the Current id.
+func (keys StaticKeys) CurrentKey() (uint32, []byte, error) {
+	key, err := keys.Key(keys.Current)
+	return keys.Current, key, err
+}
+
+// Key returns the key with the given id.
+func (keys StaticKeys) Key(id uint32) ([]byte, error) {
+	key, found := keys.Keys[id]
+	if !found {
+		return nil, fmt.Errorf("lru: unknown encryption key %d", id)
+	}
+	return key, nil
+}
+
+// EncryptedCodec encrypts the output of another codec with AES-GCM, so the values kept by the slab storage
+// and the ones persisted with that codec, e.g. by the persist package, are never held in plain form.
+// Every value is stored as the id of its key, a random nonce and the authenticated ciphertext,
+// 32 bytes longer than the output of the wrapped codec. It is safe for concurrent use.
+type EncryptedCodec struct {
+	codec Codec
+	keys  KeyProvider
+
+	mutex   sync.Mutex
+	ciphers map[uint32]cipher.AEAD // Ciphers by key id, created once per key
+}
+
+var _ Codec = (*EncryptedCodec)(nil) // Ensure EncryptedCodec implements the Codec interface
+
+// encryptionHeader is the length of the key id and nonce preceding the ciphertext.
+const encryptionHeader = 4 + 12
+
+// NewEncryptedCodec encrypts the values encoded by codec with the keys of the provider.
+func NewEncryptedCodec(codec Codec, keys KeyProvider) *EncryptedCodec {
+	return &EncryptedCodec{codec: codec, keys: keys, ciphers: make(map[uint32]cipher.AEAD)}
+}
+
+// Encode encodes the value with the wrapped codec and encrypts it with the current key.
+func (codec *EncryptedCodec) Encode(value any) ([]byte, error) {
+	plaintext, err := codec.codec.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+	id, key, err := codec.keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := codec.cipher(id, key)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, encryptionHeader, encryptionHeader+len(plaintext)+aead.Overhead())
+	binary.BigEndian.PutUint32(data, id)
+	if _, err := rand.Read(data[4:encryptionHeader]); err != nil {
+		return nil, err
+	}
+	return aead.Seal(data, data[4:encryptionHeader], plaintext, data[:4]), nil // The key id is authenticated too
+}
+
+// Decode decrypts the data with the key it was encrypted with, and decodes it with the wrapped codec.
+// The errors of the provider fetching that key are wrapped in ErrDecryption.
+func (codec *EncryptedCodec) Decode(data []byte) (any, error) {
+	if len(data) < encryptionHeader {
+		return nil, ErrDecryption
+	}
+	id := binary.BigEndian.Uint32(data)
+	aead, err := codec.cipher(id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecryption, err)
+	}
+	plaintext, err := aead.Open(nil, data[4:encryptionHeader], data[encryptionHeader:], data[:4])
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return codec.codec.Decode(plaintext) // The plaintext is a copy, it can be kept by the codec
+}
+
+// cipher returns the cipher of the key with the given id, fetching the key from the provider if it is nil.
+func (codec *EncryptedCodec) cipher(id uint32, key []byte) (cipher.AEAD, error) {
+	codec.mutex.Lock()
+	defer codec.mutex.Unlock()
+
+	if aead, found := codec.ciphers[id]; found {
+		return aead, nil
+	}
+	if key == nil {
+		var err error
+		if key, err = codec.keys.Key(id); err != nil {
+			return nil, err
+		}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	codec.ciphers[id] = aead
+	return aead, nil
+}
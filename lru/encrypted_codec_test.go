@@ -0,0 +1,57 @@
+package lru
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptedCodec(t *testing.T) {
+	keys := StaticKeys{Current: 1, Keys: map[uint32][]byte{1: bytes.Repeat([]byte{1}, 32)}}
+	codec := NewEncryptedCodec(StringCodec{}, keys)
+
+	data, err := codec.Encode("secret")
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+	value, err := codec.Decode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", value)
+
+	other, _ := codec.Encode("secret")
+	assert.NotEqual(t, data, other) // Random nonces
+
+	data[len(data)-1] ^= 1
+	_, err = codec.Decode(data)
+	assert.ErrorIs(t, err, ErrDecryption)
+	_, err = codec.Encode(42)
+	assert.ErrorIs(t, err, ErrUnsupportedValue)
+}
+
+func TestEncryptedCodecKeyRotation(t *testing.T) {
+	keys := StaticKeys{Current: 1, Keys: map[uint32][]byte{1: bytes.Repeat([]byte{1}, 16)}}
+	old, _ := NewEncryptedCodec(StringCodec{}, keys).Encode("before")
+
+	keys.Keys[2] = bytes.Repeat([]byte{2}, 16)
+	keys.Current = 2
+	codec := NewEncryptedCodec(StringCodec{}, keys)
+	value, err := codec.Decode(old)
+	assert.NoError(t, err)
+	assert.Equal(t, "before", value)
+
+	delete(keys.Keys, 1) // Retired key
+	_, err = NewEncryptedCodec(StringCodec{}, keys).Decode(old)
+	assert.ErrorIs(t, err, ErrDecryption, "Expected a value encrypted with an unknown key not to be decrypted")
+	assert.ErrorContains(t, err, "unknown encryption key 1")
+}
+
+func TestEncryptedSlabStorage(t *testing.T) {
+	keys := StaticKeys{Keys: map[uint32][]byte{0: bytes.Repeat([]byte{7}, 32)}}
+	cache := NewLRUCache(2, WithoutMetrics(), WithSlabStorage(NewEncryptedCodec(StringCodec{}, keys), 128))
+	cache.Set("key1", "value1")
+
+	value, found := cache.Get("key1")
+	assert.True(t, found)
+	assert.Equal(t, "value1", value)
+	assert.NotContains(t, string(cache.slabs.bytes(cache.items["key1"].Value.(*entry).value.(slabRef))), "value1")
+}
@@ -0,0 +1,63 @@
+package lru
+
+import (
+	"time"
+)
+
+type EventType string
+
+const (
+	EventHit     EventType = "hit"     // A Get found the key
+	EventMiss    EventType = "miss"    // A Get did not find the key
+	EventAdded   EventType = "added"   // A new item was inserted
+	EventUpdated EventType = "updated" // An existing item was overridden
+	EventRemoved EventType = "removed" // An item left the cache, see Event.Reason
+)
+
+// Event describes an operation performed on a cache.
+// Events are emitted synchronously while the cache is being modified, so listeners must be fast
+// and must not call back into the cache.
+type Event struct {
+	Type      EventType `json:"type"`
+	Key       string    `json:"key"`
+	Value     any       `json:"-"`                   // The value involved in the operation, if any
+	ExpiresAt time.Time `json:"expires_at,omitzero"` // Wall-clock expiration of the item, for additions and updates
+	Reason    string    `json:"reason,omitempty"`    // Why the item was removed: "manual", "expired" or "evicted"
+	Metadata  any       `json:"-"`                   // The metadata attached to the item by SetWithMetadata, if any
+	Time      time.Time `json:"time"`                // When the event happened, according to the cache clock
+}
+
+// EventListener receives the events emitted by a cache.
+type EventListener func(Event)
+
+// eventSource is implemented by the caches able to emit events.
+// It allows wrappers such as ObservableCache to subscribe to an already constructed cache.
+type eventSource interface {
+	subscribe(listener EventListener)
+}
+
+// listeners is a list of event listeners, embedded by the caches to emit events.
+type listeners []EventListener
+
+// subscribe adds a listener to the list.
+func (l *listeners) subscribe(listener EventListener) {
+	*l = append(*l, listener)
+}
+
+// notify sends the event to every listener.
+func (l listeners) notify(event Event) {
+	for _, listener := range l {
+		listener(event)
+	}
+}
+
+// newEvent creates an event about the given entry, which may be nil for misses.
+func newEvent(eventType EventType, key string, ent *entry, reason string, now time.Time) Event {
+	event := Event{Type: eventType, Key: key, Reason: reason, Time: now}
+	if ent != nil {
+		event.Value = ent.value
+		event.ExpiresAt = wallDeadline(ent.expiresAt, now)
+		event.Metadata = ent.metadata
+	}
+	return event
+}
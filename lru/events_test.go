@@ -0,0 +1,121 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventListener(t *testing.T) {
+	var events []Event
+	cache := NewLRUCache(1, WithEventListener(func(event Event) {
+		events = append(events, event)
+	}))
+
+	cache.Set("key1", "value1")
+	cache.Set("key1", "value1_updated")
+	cache.Get("key1")
+	cache.Get("missing")
+	cache.Set("key2", "value2") // Evicts key1
+	cache.Remove("key2")
+
+	types := make([]EventType, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []EventType{EventAdded, EventUpdated, EventHit, EventMiss, EventRemoved, EventAdded, EventRemoved}, types)
+	assert.Equal(t, "value1_updated", events[1].Value)
+	assert.Equal(t, metricReasonEvicted, events[4].Reason)
+	assert.Equal(t, "key1", events[4].Key)
+	assert.Equal(t, metricReasonManual, events[6].Reason)
+}
+
+func TestEventListenerExpired(t *testing.T) {
+	var events []Event
+	clock := NewManualClock(time.Now())
+	cache := NewLFUCache(1, WithClock(clock), WithEventListener(func(event Event) {
+		events = append(events, event)
+	}))
+
+	cache.SetWithTTL("key1", "value1", time.Second)
+	clock.Advance(time.Minute)
+	cache.Get("key1")
+
+	assert.Len(t, events, 3)
+	assert.Equal(t, EventRemoved, events[1].Type)
+	assert.Equal(t, metricReasonExpired, events[1].Reason)
+	assert.Equal(t, EventMiss, events[2].Type)
+	assert.Equal(t, clock.Now(), events[2].Time)
+}
+
+func TestObservableCacheSubscribe(t *testing.T) {
+	observable := NewObservableCache(5)
+	events, unsubscribe := observable.Subscribe(2)
+
+	observable.Cache.Set("key1", "value1")
+	observable.Cache.Get("key1")
+	observable.Cache.Get("key2") // Dropped, the buffer is full
+
+	assert.Equal(t, EventAdded, (<-events).Type)
+	assert.Equal(t, EventHit, (<-events).Type)
+
+	unsubscribe()
+	unsubscribe() // Unsubscribing twice is harmless
+	_, open := <-events
+	assert.False(t, open)
+}
+
+func TestObservableCacheClose(t *testing.T) {
+	observable := NewObservableCache(5)
+	events, unsubscribe := observable.Subscribe(1)
+
+	assert.NoError(t, observable.Close())
+	_, open := <-events
+	assert.False(t, open, "Close should close the subscriber channels")
+	unsubscribe() // Unsubscribing after Close is harmless
+
+	events, _ = observable.Subscribe(1)
+	_, open = <-events
+	assert.False(t, open, "Subscribing after Close returns a closed channel")
+
+	observable.Cache.Set("key1", "value1") // The cache is still usable
+	assert.Equal(t, 1, observable.Cache.Len())
+}
+
+func TestObservableCacheChanges(t *testing.T) {
+	observable := NewObservableCache(2)
+	observable.Cache.Set("key1", "value1")
+	state := observable.State()
+	assert.Equal(t, uint64(1), state.Version)
+
+	observable.Cache.Get("key1")
+	observable.Cache.Get("missing") // Leaves the state untouched
+	observable.Cache.Set("key2", "value2")
+	changes, ok := observable.Changes(state.Version)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), changes.Version)
+	assert.Len(t, changes.Changes, 2)
+	assert.Equal(t, StateChange{Version: 2, Event: changes.Changes[0].Event}, changes.Changes[0])
+	assert.Equal(t, EventHit, changes.Changes[0].Type)
+	assert.Equal(t, "key2", changes.Changes[1].Key)
+
+	changes, ok = observable.Changes(changes.Version)
+	assert.True(t, ok)
+	assert.Empty(t, changes.Changes)
+	_, ok = observable.Changes(changes.Version + 1)
+	assert.False(t, ok, "Expected an unknown version to require the full state")
+}
+
+func TestObservableCacheChangesDiscarded(t *testing.T) {
+	observable := NewObservableCache(1)
+	for range journalSize + 1 {
+		observable.Cache.Set("key", "value")
+	}
+
+	_, ok := observable.Changes(0)
+	assert.False(t, ok, "Expected the first change to be discarded")
+	changes, ok := observable.Changes(1)
+	assert.True(t, ok)
+	assert.Len(t, changes.Changes, journalSize)
+}
@@ -0,0 +1,72 @@
+package lru
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrThrottled is returned by Increment when creating a counter needs an eviction beyond the eviction rate limit.
+var ErrThrottled = errors.New("lru: eviction rate limit reached")
+
+// evictionLimiter is a token bucket capping the evictions per second, refilled on the cache clock.
+// It is not thread-safe, the caches call it under their lock. A nil *evictionLimiter allows every eviction.
+type evictionLimiter struct {
+	rate   float64   // Tokens added per second
+	tokens float64   // Evictions currently allowed, up to rate
+	last   time.Time // Time of the last refill
+}
+
+// newEvictionLimiter creates a limiter allowing perSecond evictions per second, starting full.
+func newEvictionLimiter(perSecond int, now time.Time) *evictionLimiter {
+	return &evictionLimiter{rate: float64(perSecond), tokens: float64(perSecond), last: now}
+}
+
+// allow consumes a token and returns true if an eviction is allowed at the given time.
+func (limiter *evictionLimiter) allow(now time.Time) bool {
+	if limiter == nil {
+		return true
+	}
+	if elapsed := now.Sub(limiter.last); elapsed > 0 {
+		limiter.tokens = min(limiter.rate, limiter.tokens+elapsed.Seconds()*limiter.rate)
+		limiter.last = now
+	}
+	if limiter.tokens < 1 {
+		return false
+	}
+	limiter.tokens--
+	return true
+}
+
+// throttled returns true if adding the key needs an eviction the rate limit does not allow.
+// The expired items are reclaimed first, as their removal is not limited.
+func (cache *LRUCache) throttled(key string) bool {
+	if cache.evictions == nil || !isFull(cache.usageOrder.Len(), cache.capacity) {
+		return false
+	}
+	if _, found := cache.items[key]; found {
+		return false
+	}
+	cache.removeExpired()
+	if !isFull(cache.usageOrder.Len(), cache.capacity) || cache.evictions.allow(cache.clock.Now()) {
+		return false
+	}
+	cache.metrics.throttledSet()
+	return true
+}
+
+// throttled returns true if adding the key needs an eviction the rate limit does not allow.
+// The expired items are reclaimed first, as their removal is not limited.
+func (cache *LFUCache) throttled(key string) bool {
+	if cache.evictions == nil || !isFull(len(cache.items), cache.capacity) {
+		return false
+	}
+	if _, found := cache.items[key]; found {
+		return false
+	}
+	cache.removeExpired()
+	if !isFull(len(cache.items), cache.capacity) || cache.evictions.allow(cache.clock.Now()) {
+		return false
+	}
+	cache.metrics.throttledSet()
+	return true
+}
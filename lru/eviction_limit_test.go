@@ -0,0 +1,50 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvictionRateLimit(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLRUCache(2, WithClock(clock), WithEvictionRateLimit(2))
+	throttledBefore := counterValue(cache.metrics.throttled)
+	cache.Set("key1", 1)
+	cache.Set("key2", 2)
+
+	assert.Equal(t, SetAdded, cache.Set("key3", 3).Status) // Evicts key1
+	assert.Equal(t, SetAdded, cache.Set("key4", 4).Status) // Evicts key2
+	assert.Equal(t, SetResult{Status: SetThrottled}, cache.Set("key5", 5))
+	assert.Equal(t, []string{"key4", "key3"}, snapshotKeys(cache.Snapshot()), "Expected a throttled write to leave the cache untouched")
+	assert.Equal(t, SetUpdated, cache.Set("key3", 30).Status, "Expected the updates not to be limited")
+	_, err := cache.Increment("counter", 1, 0)
+	assert.ErrorIs(t, err, ErrThrottled)
+	assert.Equal(t, 2.0, counterValue(cache.metrics.throttled)-throttledBefore)
+
+	clock.Advance(500 * time.Millisecond) // Refills one eviction
+	assert.Equal(t, SetAdded, cache.Set("key5", 5).Status)
+	assert.Equal(t, SetThrottled, cache.Set("key6", 6).Status)
+
+	cache.SetWithTTL("key5", 5, time.Second)
+	clock.Advance(time.Second) // key5 expires, refilling two evictions
+	assert.Equal(t, SetAdded, cache.Set("key6", 6).Status, "Expected the expired items to make room without eviction")
+	assert.Equal(t, SetAdded, cache.Set("key7", 7).Status)
+	assert.Equal(t, SetAdded, cache.Set("key8", 8).Status)
+	assert.Equal(t, SetThrottled, cache.Set("key9", 9).Status, "Expected the burst to be capped to a second of evictions")
+}
+
+func TestLFUEvictionRateLimit(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLFUCache(1, WithClock(clock), WithEvictionRateLimit(1))
+	cache.Set("key1", 1)
+
+	assert.Equal(t, SetAdded, cache.Set("key2", 2).Status)
+	assert.Equal(t, SetThrottled, cache.Set("key3", 3).Status)
+	_, found := cache.Get("key2")
+	assert.True(t, found)
+
+	clock.Advance(time.Second)
+	assert.Equal(t, SetAdded, cache.Set("key3", 3).Status)
+}
@@ -0,0 +1,148 @@
+package lru
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// expirationRateWindow is the number of seconds over which Stats.ExpirationRate is averaged.
+const expirationRateWindow = 60
+
+// expirationHorizon is how far ahead Stats.UpcomingExpirations looks, see SetExpirationHorizon.
+var expirationHorizon atomic.Int64
+
+func init() {
+	expirationHorizon.Store(int64(time.Minute))
+}
+
+// SetExpirationHorizon sets how far ahead the live items expiring are counted by Stats.UpcomingExpirations
+// and the upcoming_expirations gauge, a minute by default, e.g. to match the time a refill of the backing
+// store takes. It applies to every cache.
+func SetExpirationHorizon(horizon time.Duration) {
+	expirationHorizon.Store(int64(horizon))
+}
+
+// expirationRate counts the expirations of the last seconds, to report how many items expire per second.
+// It is written under the lock of the cache and read without it.
+type expirationRate struct {
+	seconds [expirationRateWindow]atomic.Int64  // Unix second counted by every slot
+	counts  [expirationRateWindow]atomic.Uint64 // Expirations during the second of every slot
+}
+
+// add counts an expiration at the given time.
+func (rate *expirationRate) add(now time.Time) {
+	second := now.Unix()
+	slot := second % expirationRateWindow
+	if rate.seconds[slot].Load() != second {
+		rate.seconds[slot].Store(second)
+		rate.counts[slot].Store(0)
+	}
+	rate.counts[slot].Add(1)
+}
+
+// perSecond returns the average number of expirations per second over the window ending at the given time.
+func (rate *expirationRate) perSecond(now time.Time) float64 {
+	second := now.Unix()
+	var total uint64
+	for slot := range rate.seconds {
+		if age := second - rate.seconds[slot].Load(); age >= 0 && age < expirationRateWindow {
+			total += rate.counts[slot].Load()
+		}
+	}
+	return float64(total) / expirationRateWindow
+}
+
+// upcoming returns the number of live items expiring by the deadline, visiting only them
+// and the entries of the heap whose parent they are.
+func (index expiryIndex) upcoming(now, deadline time.Time) int {
+	count := 0
+	var visit func(position int)
+	visit = func(position int) {
+		if position >= len(index) || !index[position].hasExpired(deadline) {
+			return // The children expire after their parent
+		}
+		if !index[position].hasExpired(now) {
+			count++
+		}
+		visit(2*position + 1)
+		visit(2*position + 2)
+	}
+	visit(0)
+	return count
+}
+
+// upcoming returns the number of live items expiring by the deadline, visiting the buckets starting before it.
+func (index *expiryBuckets) upcoming(now, deadline time.Time) int {
+	count := 0
+	last := index.bucket(deadline)
+	for id, bucket := range index.buckets {
+		if id > last {
+			continue
+		}
+		for _, ent := range bucket {
+			if !ent.hasExpired(now) && ent.hasExpired(deadline) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// expirationForecaster is implemented by the caches able to count their upcoming expirations,
+// so the janitor of a SafeLRUCache updates the expiration gauges.
+type expirationForecaster interface {
+	// forecastExpirations returns the expirations per second over the last minute, and the number
+	// of live items expiring within the horizon, recording them in the gauges of the cache.
+	forecastExpirations() (rate float64, upcoming int)
+}
+
+var _ expirationForecaster = (*LRUCache)(nil)    // Ensure LRUCache forecasts its expirations
+var _ expirationForecaster = (*LFUCache)(nil)    // Ensure LFUCache forecasts its expirations
+var _ expirationForecaster = (*LRUKCache)(nil)   // Ensure LRUKCache forecasts its expirations
+var _ expirationForecaster = (*S3FIFOCache)(nil) // Ensure S3FIFOCache forecasts its expirations
+
+func (cache *LRUCache) forecastExpirations() (rate float64, upcoming int) {
+	now := cache.clock.Now()
+	deadline := now.Add(time.Duration(expirationHorizon.Load()))
+	if cache.buckets != nil {
+		upcoming = cache.buckets.upcoming(now, deadline)
+	} else {
+		upcoming = cache.expiries.upcoming(now, deadline)
+	}
+	rate = cache.counters.expired.perSecond(now)
+	cache.metrics.expirationForecast(rate, upcoming)
+	return rate, upcoming
+}
+
+func (cache *LFUCache) forecastExpirations() (rate float64, upcoming int) {
+	now := cache.clock.Now()
+	upcoming = cache.expiries.upcoming(now, now.Add(time.Duration(expirationHorizon.Load())))
+	rate = cache.counters.expired.perSecond(now)
+	cache.metrics.expirationForecast(rate, upcoming)
+	return rate, upcoming
+}
+
+func (cache *LRUKCache) forecastExpirations() (rate float64, upcoming int) {
+	now := cache.clock.Now()
+	upcoming = cache.expiries.upcoming(now, now.Add(time.Duration(expirationHorizon.Load())))
+	rate = cache.counters.expired.perSecond(now)
+	cache.metrics.expirationForecast(rate, upcoming)
+	return rate, upcoming
+}
+
+func (cache *S3FIFOCache) forecastExpirations() (rate float64, upcoming int) {
+	now := cache.clock.Now()
+	upcoming = cache.expiries.upcoming(now, now.Add(time.Duration(expirationHorizon.Load())))
+	rate = cache.counters.expired.perSecond(now)
+	cache.metrics.expirationForecast(rate, upcoming)
+	return rate, upcoming
+}
+
+// forecastExpirations updates the expiration gauges of the underlying cache, under the read lock.
+func (safeCache *SafeLRUCache) forecastExpirations() {
+	if forecaster, ok := safeCache.cache.(expirationForecaster); ok {
+		safeCache.readLock()
+		defer safeCache.mutex.RUnlock()
+		forecaster.forecastExpirations()
+	}
+}
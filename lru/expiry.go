@@ -0,0 +1,69 @@
+package lru
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryIndex is a min-heap of the entries that have an expiration, the soonest to expire first.
+// It lets the caches find expired entries without scanning every item.
+type expiryIndex []*entry
+
+func (index expiryIndex) Len() int {
+	return len(index)
+}
+
+func (index expiryIndex) Less(i, j int) bool {
+	return index[i].expiresAt.Before(index[j].expiresAt)
+}
+
+func (index expiryIndex) Swap(i, j int) {
+	index[i], index[j] = index[j], index[i]
+	index[i].expiryPosition = i
+	index[j].expiryPosition = j
+}
+
+// Push is used by container/heap, use track instead.
+func (index *expiryIndex) Push(x any) {
+	ent := x.(*entry)
+	ent.expiryPosition = len(*index)
+	*index = append(*index, ent)
+}
+
+// Pop is used by container/heap, use untrack instead.
+func (index *expiryIndex) Pop() any {
+	old := *index
+	ent := old[len(old)-1]
+	old[len(old)-1] = nil // Avoid holding a reference to the removed entry
+	ent.expiryPosition = notIndexed
+	*index = old[:len(old)-1]
+	return ent
+}
+
+// track adds, moves or removes the entry from the index according to its current expiration.
+// It must be called whenever the expiration of an entry changes.
+func (index *expiryIndex) track(ent *entry) {
+	switch {
+	case ent.expiresAt.IsZero():
+		index.untrack(ent)
+	case ent.expiryPosition == notIndexed:
+		heap.Push(index, ent)
+	default:
+		heap.Fix(index, ent.expiryPosition)
+	}
+}
+
+// untrack removes the entry from the index, if present.
+func (index *expiryIndex) untrack(ent *entry) {
+	if ent.expiryPosition != notIndexed {
+		heap.Remove(index, ent.expiryPosition)
+	}
+}
+
+// nextExpired returns the entry expiring the soonest if it has expired at the given time, nil otherwise.
+func (index expiryIndex) nextExpired(now time.Time) *entry {
+	if len(index) > 0 && index[0].hasExpired(now) {
+		return index[0]
+	}
+	return nil
+}
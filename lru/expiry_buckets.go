@@ -0,0 +1,98 @@
+package lru
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryBuckets groups the entries expiring within the same interval of the given width, so expiring
+// millions of entries sharing similar TTLs removes whole buckets at once, and the index only orders
+// the buckets instead of every entry. An entry is only removed once the end of its bucket has passed,
+// until then Get still reports it missing as soon as it expires.
+type expiryBuckets struct {
+	width   int64              // Width of the buckets in nanoseconds
+	origin  time.Time          // Start of the bucket 0, the buckets are measured from it on the monotonic clock when the times have one
+	buckets map[int64][]*entry // Entries by bucket, an entry's expiryPosition is its position in its bucket
+	order   bucketOrder        // Ids of the buckets, the soonest to end first, possibly with ids of emptied buckets
+}
+
+func newExpiryBuckets(width time.Duration, origin time.Time) *expiryBuckets {
+	return &expiryBuckets{width: max(int64(width), 1), origin: origin, buckets: make(map[int64][]*entry)}
+}
+
+// bucket returns the id of the bucket of an expiration.
+// Using the offset from the origin rather than the Unix time keeps the buckets right if the wall clock is stepped.
+func (index *expiryBuckets) bucket(expiresAt time.Time) int64 {
+	offset := int64(expiresAt.Sub(index.origin))
+	if offset < 0 && offset%index.width != 0 {
+		return offset/index.width - 1 // Round down, so the bucket 0 does not span twice the width
+	}
+	return offset / index.width
+}
+
+// add puts the entry in the bucket of its expiration, unless it does not expire.
+// The entry must not be in the index, untrack it before changing its expiration.
+func (index *expiryBuckets) add(ent *entry) {
+	if ent.expiresAt.IsZero() {
+		return
+	}
+	id := index.bucket(ent.expiresAt)
+	bucket, found := index.buckets[id]
+	if !found {
+		heap.Push(&index.order, id)
+	}
+	ent.expiryPosition = len(bucket)
+	index.buckets[id] = append(bucket, ent)
+}
+
+// remove takes the entry out of its bucket, if it is in the index.
+func (index *expiryBuckets) remove(ent *entry) {
+	if ent.expiryPosition == notIndexed {
+		return
+	}
+	id := index.bucket(ent.expiresAt)
+	bucket := index.buckets[id]
+	last := len(bucket) - 1
+	bucket[ent.expiryPosition] = bucket[last]
+	bucket[ent.expiryPosition].expiryPosition = ent.expiryPosition
+	bucket[last] = nil // Avoid holding a reference to the removed entry
+	ent.expiryPosition = notIndexed
+	if last == 0 {
+		delete(index.buckets, id) // Its id is dropped from the order once it ends
+	} else {
+		index.buckets[id] = bucket[:last]
+	}
+}
+
+// expired takes the entries of the buckets that ended at the given time out of the index.
+func (index *expiryBuckets) expired(now time.Time) []*entry {
+	var expired []*entry
+	end := int64(now.Sub(index.origin))
+	for len(index.order) > 0 && (index.order[0]+1)*index.width <= end {
+		id := heap.Pop(&index.order).(int64)
+		for _, ent := range index.buckets[id] {
+			ent.expiryPosition = notIndexed
+			expired = append(expired, ent)
+		}
+		delete(index.buckets, id)
+	}
+	return expired
+}
+
+// bucketOrder is a min-heap of bucket ids.
+type bucketOrder []int64
+
+func (order bucketOrder) Len() int           { return len(order) }
+func (order bucketOrder) Less(i, j int) bool { return order[i] < order[j] }
+func (order bucketOrder) Swap(i, j int)      { order[i], order[j] = order[j], order[i] }
+
+// Push is used by container/heap.
+func (order *bucketOrder) Push(x any) { *order = append(*order, x.(int64)) }
+
+// Pop is used by container/heap.
+func (order *bucketOrder) Pop() any {
+	old := *order
+	id := old[len(old)-1]
+	*order = old[:len(old)-1]
+	return id
+}
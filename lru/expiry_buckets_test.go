@@ -0,0 +1,41 @@
+package lru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiryBuckets(t *testing.T) {
+	clock := NewManualClock(time.Unix(960, 0)) // At the start of a bucket
+	cache := NewLRUCache(100, WithoutMetrics(), WithClock(clock), WithExpiryBuckets(time.Minute))
+	for i := range 50 {
+		cache.SetWithTTL(fmt.Sprintf("key%d", i), i, time.Duration(i)*time.Second+time.Second)
+	}
+	cache.Set("forever", 0)
+	cache.SetWithTTL("key0", 0, time.Hour) // Moves to a later bucket
+	assert.LessOrEqual(t, len(cache.buckets.order), 3, "the index holds buckets, not entries")
+
+	clock.Advance(30 * time.Second)
+	_, found := cache.Get("key10")
+	assert.False(t, found, "an expired item is never returned")
+	assert.Equal(t, 0, cache.RemoveExpired(), "the bucket has not ended")
+
+	clock.Advance(2 * time.Minute)
+	assert.Equal(t, 48, cache.RemoveExpired(), "whole buckets are removed at once")
+	assert.Equal(t, 2, cache.Len())
+	_, found = cache.Get("key0")
+	assert.True(t, found)
+	assert.Equal(t, 0, cache.RemoveExpired())
+}
+
+func TestJanitor(t *testing.T) {
+	cache := NewShardedCache(2, 10, WithoutMetrics(), WithJanitor(5*time.Millisecond))
+	defer cache.Close()
+	cache.SetWithTTL("key1", "value1", time.Millisecond)
+	cache.Set("key2", "value2")
+
+	assert.Eventually(t, func() bool { return cache.Len() == 1 }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,119 @@
+package lru
+
+// Generation identifies the items written between two calls to NewGeneration, the first one being 0.
+type Generation uint64
+
+// GenerationInvalidator is implemented by the caches able to invalidate every item written before a point in time
+// at once, e.g. after a deployment changing the format of the values, without visiting the items.
+type GenerationInvalidator interface {
+	// NewGeneration starts a new generation, to which the items written from now on belong, and returns it.
+	NewGeneration() Generation
+	// InvalidateGeneration invalidates the items written during the given generation and every earlier one.
+	InvalidateGeneration(gen Generation)
+}
+
+var _ GenerationInvalidator = (*LRUCache)(nil)     // Ensure LRUCache supports generations
+var _ GenerationInvalidator = (*SafeLRUCache)(nil) // Ensure SafeLRUCache supports generations
+var _ GenerationInvalidator = (*ShardedCache)(nil) // Ensure ShardedCache supports generations
+
+// generationTracker holds the current generation of a cache, and the generations invalidated.
+type generationTracker struct {
+	current     *generation
+	invalidated Generation // Generations below are invalidated
+}
+
+// generation is a generation of a cache, shared by the entries written during it.
+type generation struct {
+	id      Generation
+	tracker *generationTracker
+}
+
+func newGenerationTracker() *generationTracker {
+	tracker := &generationTracker{}
+	tracker.current = &generation{tracker: tracker}
+	return tracker
+}
+
+// isInvalidated returns whether the generation was invalidated, false for a nil generation.
+func (gen *generation) isInvalidated() bool {
+	return gen != nil && gen.id < gen.tracker.invalidated
+}
+
+// next starts a new generation and returns it.
+func (tracker *generationTracker) next() Generation {
+	tracker.current = &generation{id: tracker.current.id + 1, tracker: tracker}
+	return tracker.current.id
+}
+
+// invalidate invalidates the given generation and every earlier one, starting a new generation
+// if the current one is invalidated, so the items written afterwards are not.
+func (tracker *generationTracker) invalidate(gen Generation) {
+	if gen+1 > tracker.invalidated {
+		tracker.invalidated = gen + 1
+	}
+	if tracker.current.isInvalidated() {
+		tracker.current = &generation{id: tracker.invalidated, tracker: tracker}
+	}
+}
+
+// NewGeneration starts a new generation, to which the items written from now on belong, and returns it.
+func (cache *LRUCache) NewGeneration() Generation {
+	return cache.generation.next()
+}
+
+// InvalidateGeneration invalidates the items written during the given generation and every earlier one in O(1):
+// they are treated as expired, and removed when they are next read or evicted. If the current generation
+// is invalidated, a new one starts, to which the items written from now on belong.
+func (cache *LRUCache) InvalidateGeneration(gen Generation) {
+	cache.generation.invalidate(gen)
+}
+
+// NewGeneration starts a new generation of the wrapped cache, and returns it. It returns 0 if the wrapped cache
+// does not support generations.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) NewGeneration() Generation {
+	safeCache.lockOrdered() // The writes buffered before belong to the previous generation
+	defer safeCache.mutex.Unlock()
+
+	if invalidator, ok := safeCache.cache.(GenerationInvalidator); ok {
+		return invalidator.NewGeneration()
+	}
+	return 0
+}
+
+// InvalidateGeneration invalidates the items of the wrapped cache written during the given generation
+// and every earlier one, if it supports generations.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) InvalidateGeneration(gen Generation) {
+	safeCache.lockOrdered()
+	defer safeCache.mutex.Unlock()
+
+	if invalidator, ok := safeCache.cache.(GenerationInvalidator); ok {
+		invalidator.InvalidateGeneration(gen)
+	}
+}
+
+// NewGeneration starts a new generation in every shard, and returns it.
+// The shards start their generations together, so they share their numbers.
+// It is thread-safe.
+func (sharded *ShardedCache) NewGeneration() Generation {
+	sharded.generations.Lock()
+	defer sharded.generations.Unlock()
+
+	var gen Generation
+	for _, shard := range sharded.shards {
+		gen = shard.NewGeneration()
+	}
+	return gen
+}
+
+// InvalidateGeneration invalidates the items of every shard written during the given generation and every earlier one.
+// It is thread-safe.
+func (sharded *ShardedCache) InvalidateGeneration(gen Generation) {
+	sharded.generations.Lock()
+	defer sharded.generations.Unlock()
+
+	for _, shard := range sharded.shards {
+		shard.InvalidateGeneration(gen)
+	}
+}
@@ -0,0 +1,63 @@
+package lru
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidateGeneration(t *testing.T) {
+	cache := NewLRUCache(10, WithoutMetrics())
+	cache.Set("old", 1)
+	first := cache.NewGeneration()
+	cache.Set("middle", 2)
+	second := cache.NewGeneration()
+	cache.Set("new", 3)
+	assert.Equal(t, Generation(1), first)
+	assert.Equal(t, Generation(2), second)
+
+	cache.InvalidateGeneration(first)
+	_, found := cache.Get("old")
+	assert.False(t, found, "Expected the earlier generations to be invalidated too")
+	_, found = cache.Get("middle")
+	assert.False(t, found)
+	_, found = cache.Get("new")
+	assert.True(t, found)
+	assert.Equal(t, 1, cache.Len(), "Expected the invalidated items read to be removed")
+
+	cache.Set("middle", 4) // Written again in the current generation
+	value, found := cache.Get("middle")
+	assert.True(t, found)
+	assert.Equal(t, 4, value)
+}
+
+func TestInvalidateCurrentGeneration(t *testing.T) {
+	cache := NewSafeLRUCache(10, WithoutMetrics())
+	cache.Set("key1", 1)
+	cache.InvalidateGeneration(cache.NewGeneration())
+	cache.Set("key2", 2)
+
+	_, found := cache.Get("key1")
+	assert.False(t, found)
+	_, found = cache.Get("key2")
+	assert.True(t, found, "Expected the items written after the invalidation to start a new generation")
+	assert.Equal(t, Generation(3), cache.NewGeneration())
+}
+
+func TestShardedGenerations(t *testing.T) {
+	cache := NewShardedCache(4, 100, WithoutMetrics())
+	keys := []string{"a", "b", "c", "d", "e", "f"}
+	for _, key := range keys {
+		cache.Set(key, key)
+	}
+	gen := cache.NewGeneration()
+	cache.Set("new", 0)
+
+	cache.InvalidateGeneration(gen - 1)
+	for _, key := range keys {
+		_, found := cache.Get(key)
+		assert.False(t, found, key)
+	}
+	_, found := cache.Get("new")
+	assert.True(t, found)
+}
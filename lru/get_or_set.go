@@ -0,0 +1,84 @@
+package lru
+
+// GetOrSetter is implemented by the caches able to insert an item only if its key is absent,
+// like sync.Map.LoadOrStore, e.g. so concurrent callers agree on a single value for a key.
+type GetOrSetter interface {
+	// GetOrSet returns the live value of the key and true if it is present. Otherwise, it stores the value
+	// like Set and returns it with false.
+	GetOrSet(key string, value any) (actual any, loaded bool)
+}
+
+var _ GetOrSetter = (*LRUCache)(nil)     // Ensure LRUCache supports GetOrSet
+var _ GetOrSetter = (*LFUCache)(nil)     // Ensure LFUCache supports GetOrSet
+var _ GetOrSetter = (*LRUKCache)(nil)    // Ensure LRUKCache supports GetOrSet
+var _ GetOrSetter = (*S3FIFOCache)(nil)  // Ensure S3FIFOCache supports GetOrSet
+var _ GetOrSetter = (*SafeLRUCache)(nil) // Ensure SafeLRUCache supports GetOrSet
+var _ GetOrSetter = (*ShardedCache)(nil) // Ensure ShardedCache supports GetOrSet
+var _ GetOrSetter = (*HashedKeys)(nil)   // Ensure HashedKeys supports GetOrSet
+
+// getOrSet gets the key from a cache that is not thread-safe, setting the value if it is missing.
+// The read is counted as a Get, a hit if the key is present and a miss otherwise.
+func getOrSet(cache Cache, key string, value any) (actual any, loaded bool) {
+	if current, found := cache.Get(key); found {
+		return current, true
+	}
+	cache.Set(key, value)
+	return value, false
+}
+
+// GetOrSet returns the live value of the key and true if it is present, promoting it like Get.
+// Otherwise, it stores the value like Set and returns it with false, even if the cache refused to store it.
+func (cache *LRUCache) GetOrSet(key string, value any) (actual any, loaded bool) {
+	return getOrSet(cache, key, value)
+}
+
+// GetOrSet returns the live value of the key and true if it is present, counting the access like Get.
+// Otherwise, it stores the value like Set and returns it with false, even if the cache refused to store it.
+func (cache *LFUCache) GetOrSet(key string, value any) (actual any, loaded bool) {
+	return getOrSet(cache, key, value)
+}
+
+// GetOrSet returns the live value of the key and true if it is present, recording a reference like Get.
+// Otherwise, it stores the value like Set and returns it with false, even if the cache refused to store it.
+func (cache *LRUKCache) GetOrSet(key string, value any) (actual any, loaded bool) {
+	return getOrSet(cache, key, value)
+}
+
+// GetOrSet returns the live value of the key and true if it is present, counting the access like Get.
+// Otherwise, it stores the value like Set and returns it with false, even if the cache refused to store it.
+func (cache *S3FIFOCache) GetOrSet(key string, value any) (actual any, loaded bool) {
+	return getOrSet(cache, key, value)
+}
+
+// GetOrSet returns the live value of the key and true if it is present. Otherwise, it stores the value
+// like Set and returns it with false, even if the cache refused to store it.
+// The read and the write are done under a single acquisition of the lock, bypassing the write buffer,
+// so concurrent callers for a missing key all get the value of the first one.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) GetOrSet(key string, value any) (actual any, loaded bool) {
+	key = safeCache.transform.apply(key)
+	safeCache.lockOrdered()
+	defer safeCache.mutex.Unlock()
+
+	if setter, ok := safeCache.cache.(GetOrSetter); ok {
+		return setter.GetOrSet(key, value)
+	}
+	return getOrSet(safeCache.cache, key, value)
+}
+
+// GetOrSet returns the live value of the key in its shard and true if it is present. Otherwise, it stores the value
+// like Set and returns it with false.
+// It is thread-safe.
+func (sharded *ShardedCache) GetOrSet(key string, value any) (actual any, loaded bool) {
+	key = sharded.transform.apply(key)
+	return sharded.shard(key).GetOrSet(key, value)
+}
+
+// GetOrSet returns the live value of the key and true if it is present. Otherwise, it stores the value
+// like Set and returns it with false. It is atomic if the underlying cache implements GetOrSetter.
+func (hashed *HashedKeys) GetOrSet(key string, value any) (actual any, loaded bool) {
+	if setter, ok := hashed.cache.(GetOrSetter); ok {
+		return setter.GetOrSet(hashed.hash(key), value)
+	}
+	return getOrSet(hashed.cache, hashed.hash(key), value)
+}
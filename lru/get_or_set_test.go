@@ -0,0 +1,66 @@
+package lru
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrSet(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	caches := map[string]GetOrSetter{
+		"lru":     NewLRUCache(10, WithoutMetrics(), WithClock(clock)),
+		"lfu":     NewLFUCache(10, WithoutMetrics(), WithClock(clock)),
+		"lruk":    NewLRUKCache(10, WithoutMetrics(), WithClock(clock)),
+		"s3fifo":  NewS3FIFOCache(10, WithoutMetrics(), WithClock(clock)),
+		"safe":    NewSafeLRUCache(10, WithoutMetrics(), WithClock(clock)),
+		"sharded": NewShardedCache(4, 10, WithoutMetrics(), WithClock(clock)),
+		"hashed":  NewHashedKeys(NewLRUCache(10, WithoutMetrics(), WithClock(clock)), SHA256Keys([]byte("secret"), 16)),
+	}
+	for name, cache := range caches {
+		actual, loaded := cache.GetOrSet("key", "first")
+		assert.False(t, loaded, name)
+		assert.Equal(t, "first", actual, name)
+		actual, loaded = cache.GetOrSet("key", "second")
+		assert.True(t, loaded, name)
+		assert.Equal(t, "first", actual, name)
+
+		cache.(Cache).SetWithTTL("expiring", "old", time.Second)
+	}
+	clock.Advance(time.Minute)
+	for name, cache := range caches {
+		actual, loaded := cache.GetOrSet("expiring", "new")
+		assert.False(t, loaded, name+": expected an expired item to be replaced")
+		assert.Equal(t, "new", actual, name)
+		value, _ := cache.(Cache).Get("expiring")
+		assert.Equal(t, "new", value, name)
+	}
+}
+
+func TestGetOrSetConcurrent(t *testing.T) {
+	cache := NewSafeLRUCache(10, WithoutMetrics(), WithAccessBuffer(16), WithWriteBuffer(16))
+	defer cache.Close()
+
+	var wg sync.WaitGroup
+	results := make([]any, 50)
+	loads := make([]bool, 50)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], loads[i] = cache.GetOrSet("key", i)
+		}()
+	}
+	wg.Wait()
+
+	stored := 0
+	for i := range results {
+		assert.Equal(t, results[0], results[i], "Expected every caller to get the same value")
+		if !loads[i] {
+			stored++
+		}
+	}
+	assert.Equal(t, 1, stored)
+}
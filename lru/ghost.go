@@ -0,0 +1,140 @@
+package lru
+
+import (
+	"sync"
+)
+
+// ghostList remembers the keys evicted during the last operations, to count the misses a larger cache
+// would have served. An operation is a read or a write. It has its own mutex, as the misses
+// of a SafeLRUCache with an access buffer are recorded concurrently under a read lock.
+// A nil *ghostList records nothing.
+type ghostList struct {
+	mutex   sync.Mutex
+	window  uint64            // Operations during which an evicted key is remembered
+	ops     uint64            // Operations seen so far
+	evicted map[string]uint64 // Operation at which the remembered keys were evicted
+	queue   []ghostKey        // Remembered keys in eviction order, from head
+	head    int               // Index of the oldest remembered key in queue
+	hits    uint64            // Misses for remembered keys
+}
+
+// ghostKey is a key evicted at the given operation.
+type ghostKey struct {
+	key string
+	op  uint64
+}
+
+func newGhostList(window int) *ghostList {
+	return &ghostList{window: uint64(window), evicted: make(map[string]uint64)}
+}
+
+// access counts an operation, forgetting the keys evicted more than window operations ago.
+func (ghost *ghostList) access() {
+	if ghost == nil {
+		return
+	}
+	ghost.mutex.Lock()
+	defer ghost.mutex.Unlock()
+
+	ghost.ops++
+	for ghost.head < len(ghost.queue) && ghost.ops-ghost.queue[ghost.head].op > ghost.window {
+		oldest := ghost.queue[ghost.head]
+		if op, found := ghost.evicted[oldest.key]; found && op == oldest.op {
+			delete(ghost.evicted, oldest.key)
+		}
+		ghost.queue[ghost.head] = ghostKey{}
+		ghost.head++
+	}
+	if ghost.head > len(ghost.queue)/2 { // Reclaim the space of the forgotten keys
+		ghost.queue = append(ghost.queue[:0], ghost.queue[ghost.head:]...)
+		ghost.head = 0
+	}
+}
+
+// add remembers an evicted key.
+func (ghost *ghostList) add(key string) {
+	if ghost == nil {
+		return
+	}
+	ghost.mutex.Lock()
+	defer ghost.mutex.Unlock()
+
+	ghost.evicted[key] = ghost.ops
+	ghost.queue = append(ghost.queue, ghostKey{key: key, op: ghost.ops})
+}
+
+// forget drops a key added back to the cache.
+func (ghost *ghostList) forget(key string) {
+	if ghost == nil {
+		return
+	}
+	ghost.mutex.Lock()
+	defer ghost.mutex.Unlock()
+
+	delete(ghost.evicted, key)
+}
+
+// take forgets the key, returning true if it was evicted within the window, without counting a hit.
+func (ghost *ghostList) take(key string) bool {
+	ghost.mutex.Lock()
+	defer ghost.mutex.Unlock()
+
+	_, found := ghost.evicted[key]
+	delete(ghost.evicted, key)
+	return found
+}
+
+// miss records a miss, returning true if the key was evicted within the window.
+func (ghost *ghostList) miss(key string) bool {
+	if ghost == nil {
+		return false
+	}
+	ghost.mutex.Lock()
+	defer ghost.mutex.Unlock()
+
+	if _, found := ghost.evicted[key]; !found {
+		return false
+	}
+	delete(ghost.evicted, key)
+	ghost.hits++
+	return true
+}
+
+// hitCount returns the number of misses for keys evicted within the window.
+func (ghost *ghostList) hitCount() uint64 {
+	if ghost == nil {
+		return 0
+	}
+	ghost.mutex.Lock()
+	defer ghost.mutex.Unlock()
+
+	return ghost.hits
+}
+
+// ghostCounter is implemented by the caches counting the misses for keys evicted recently.
+type ghostCounter interface {
+	GhostHits() uint64
+}
+
+// GhostHits returns the number of misses for keys evicted recently, which a larger cache would have served.
+// It is always zero without WithGhostList.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) GhostHits() uint64 {
+	safeCache.readLock()
+	defer safeCache.mutex.RUnlock()
+
+	if counter, ok := safeCache.cache.(ghostCounter); ok {
+		return counter.GhostHits()
+	}
+	return 0
+}
+
+// GhostHits returns the number of misses for keys evicted recently in every shard.
+// It is thread-safe.
+func (sharded *ShardedCache) GhostHits() uint64 {
+	var hits uint64
+	for _, shard := range sharded.shards {
+		hits += shard.GhostHits()
+	}
+	return hits
+}
@@ -0,0 +1,68 @@
+package lru
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGhostList(t *testing.T) {
+	for name, cache := range map[string]interface {
+		Cache
+		ghostCounter
+	}{"lru": NewLRUCache(2, WithGhostList(4)), "lfu": NewLFUCache(2, WithGhostList(4))} {
+		t.Run(name, func(t *testing.T) {
+			cache.Set("key1", "value1")
+			cache.Set("key2", "value2")
+			cache.Set("key3", "value3") // Evicts key1
+
+			cache.Get("missing")
+			assert.Zero(t, cache.GhostHits())
+			cache.Get("key1") // Evicted 2 operations ago
+			assert.Equal(t, uint64(1), cache.GhostHits())
+			cache.Get("key1") // Only counted once
+			assert.Equal(t, uint64(1), cache.GhostHits())
+
+			cache.Set("key1", "value1") // Evicts the next victim
+			for range 5 {
+				cache.Get("key1")
+			}
+			cache.Set("key4", "value4")
+			cache.Set("key5", "value5")
+			assert.Equal(t, uint64(1), cache.GhostHits())
+		})
+	}
+}
+
+func TestGhostListWindow(t *testing.T) {
+	cache := NewLRUCache(1, WithGhostList(2), WithoutMetrics())
+	cache.Set("key1", "value1")
+	cache.Set("key2", "value2") // Evicts key1
+	cache.Get("key2")
+	cache.Get("key2")
+	cache.Get("key1") // Evicted 3 operations ago, out of the window
+	assert.Zero(t, cache.GhostHits())
+	assert.Empty(t, cache.ghosts.evicted)
+
+	cache.Set("key3", "value3") // Evicts key2
+	cache.Get("key2")
+	assert.Equal(t, uint64(1), cache.GhostHits())
+	assert.Zero(t, NewLRUCache(1).GhostHits()) // Disabled
+}
+
+func TestGhostListSafeAndSharded(t *testing.T) {
+	safe := NewSafeLRUCache(1, WithGhostList(10), WithAccessBuffer(8))
+	safe.Set("key1", "value1")
+	safe.Set("key2", "value2")
+	safe.Get("key1")
+	assert.Equal(t, uint64(1), safe.GhostHits())
+
+	sharded := NewShardedCache(2, 2, WithGhostList(10))
+	for _, key := range []string{"key1", "key2", "key3", "key4", "key5"} {
+		sharded.Set(key, key)
+	}
+	for _, key := range []string{"key1", "key2", "key3", "key4", "key5"} {
+		sharded.Get(key)
+	}
+	assert.Equal(t, uint64(3), sharded.GhostHits()) // 5 keys in 2 shards of 1 item
+}
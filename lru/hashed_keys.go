@@ -0,0 +1,96 @@
+package lru
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// KeyHasher turns a key into the form stored by the cache.
+type KeyHasher func(key string) string
+
+// SHA256Keys returns a KeyHasher replacing every key with the hex encoded HMAC-SHA256 of the key
+// with the secret, truncated to size bytes, or 32 bytes if size is not between 1 and 32.
+// The secret keeps the identifiers from being recovered by hashing guessed values, it may be empty
+// when they can't be guessed. 16 bytes make collisions negligible for billions of keys.
+func SHA256Keys(secret []byte, size int) KeyHasher {
+	if size <= 0 || size > sha256.Size {
+		size = sha256.Size
+	}
+	return func(key string) string {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(key))
+		return hex.EncodeToString(mac.Sum(nil)[:size])
+	}
+}
+
+// HashedKeys wraps a cache so it only ever sees hashed keys: sensitive identifiers never appear in plain form
+// in its events, its metrics, the logs and snapshots of the persist package, or the visualizer state.
+// Give the wrapped cache to these components, e.g. restore the log into it, and the HashedKeys to the callers.
+// The keys can't be recovered, so the scans and secondary indexes of the wrapped cache return hashed keys.
+type HashedKeys struct {
+	cache Cache // The underlying cache, holding the hashed keys
+	hash  KeyHasher
+}
+
+var _ Cache = (*HashedKeys)(nil)       // Ensure HashedKeys implements the Cache interface
+var _ Incrementer = (*HashedKeys)(nil) // Ensure HashedKeys supports atomic counters
+
+// NewHashedKeys wraps the cache, hashing every key with hash, e.g. SHA256Keys(secret, 16).
+func NewHashedKeys(cache Cache, hash KeyHasher) *HashedKeys {
+	return &HashedKeys{cache: cache, hash: hash}
+}
+
+// Key returns the form of the key stored by the underlying cache, e.g. to find it in the visualizer state.
+func (hashed *HashedKeys) Key(key string) string {
+	return hashed.hash(key)
+}
+
+// Get retrieves an item from the cache by its key.
+// It returns the value and a boolean indicating whether the item was found.
+func (hashed *HashedKeys) Get(key string) (any, bool) {
+	return hashed.cache.Get(hashed.hash(key))
+}
+
+// Set adds or updates an item in the cache with no expiration.
+// The key reported as evicted in the result is hashed.
+func (hashed *HashedKeys) Set(key string, value any) SetResult {
+	return hashed.cache.Set(hashed.hash(key), value)
+}
+
+// SetWithTTL adds or updates an item in the cache with a specified expiration time.
+// The key reported as evicted in the result is hashed.
+func (hashed *HashedKeys) SetWithTTL(key string, value any, ttl time.Duration) SetResult {
+	return hashed.cache.SetWithTTL(hashed.hash(key), value, ttl)
+}
+
+// Remove deletes an item from the cache by key.
+func (hashed *HashedKeys) Remove(key string) {
+	hashed.cache.Remove(hashed.hash(key))
+}
+
+// Increment atomically adds delta to the int64 counter stored under key and returns the new value.
+// It returns errors.ErrUnsupported if the underlying cache does not implement Incrementer.
+func (hashed *HashedKeys) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	if incrementer, ok := hashed.cache.(Incrementer); ok {
+		return incrementer.Increment(hashed.hash(key), delta, ttl)
+	}
+	return 0, errors.ErrUnsupported
+}
+
+// Len returns the number of items currently in the cache.
+func (hashed *HashedKeys) Len() int {
+	return hashed.cache.Len()
+}
+
+// Capacity returns the maximum number of items that can be stored in the cache.
+func (hashed *HashedKeys) Capacity() int {
+	return hashed.cache.Capacity()
+}
+
+// Cache returns the underlying cache.
+func (hashed *HashedKeys) Cache() Cache {
+	return hashed.cache
+}
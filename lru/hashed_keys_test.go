@@ -0,0 +1,43 @@
+package lru
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashedKeys(t *testing.T) {
+	var events []Event
+	inner := NewLRUCache(10, WithoutMetrics(), WithEventListener(func(event Event) {
+		events = append(events, event)
+	}))
+	cache := NewHashedKeys(inner, SHA256Keys([]byte("secret"), 16))
+
+	cache.Set("user@example.com", "value")
+	value, found := cache.Get("user@example.com")
+	assert.True(t, found)
+	assert.Equal(t, "value", value)
+
+	for _, event := range events {
+		assert.NotContains(t, event.Key, "example")
+	}
+
+	stored := cache.Key("user@example.com")
+	assert.Len(t, stored, 32) // 16 bytes in hex
+	_, found = inner.Get(stored)
+	assert.True(t, found)
+	_, found = inner.Get("user@example.com")
+	assert.False(t, found)
+
+	counter, err := cache.Increment("counter", 2, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), counter)
+	cache.Remove("user@example.com")
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestSHA256Keys(t *testing.T) {
+	assert.Len(t, SHA256Keys(nil, 0)("key"), 64)
+	assert.Equal(t, SHA256Keys([]byte("a"), 8)("key"), SHA256Keys([]byte("a"), 8)("key"))
+	assert.NotEqual(t, SHA256Keys([]byte("a"), 8)("key"), SHA256Keys([]byte("b"), 8)("key"))
+}
@@ -0,0 +1,147 @@
+package lru
+
+import (
+	"container/list"
+	"hash/maphash"
+	"math"
+	"sync"
+)
+
+const (
+	curvePoints     = 20   // Capacities at which the hit ratio is estimated
+	curveSampleKeys = 8192 // Most sampled keys tracked, the sampling rate is lowered beyond
+)
+
+// CurvePoint is the estimated hit ratio of an LRU cache of the given capacity.
+type CurvePoint struct {
+	Capacity int     `json:"capacity"`
+	HitRatio float64 `json:"hitRatio"`
+}
+
+// curveEstimator estimates the hit ratio curve of an LRU cache from its accesses, following SHARDS
+// (Waldspurger et al., FAST 2015): the keys whose hash falls below a threshold are sampled,
+// and the stack distance of every access to a sampled key, scaled by the sampling rate, tells from
+// which capacity that access would have been a hit. When more keys than curveSampleKeys are sampled,
+// the threshold is lowered to drop the highest hashes, so the memory used stays bounded.
+// It has its own mutex, as the reads of a SafeLRUCache with an access buffer are recorded concurrently
+// under a read lock. A nil *curveEstimator records nothing.
+type curveEstimator struct {
+	mutex      sync.Mutex
+	seed       maphash.Seed             // Seed of the hash, independent of the shard selection of the ShardedCache
+	threshold  uint64                   // Keys whose hash is lower are sampled
+	stack      *list.List               // Sampled keys, most recently accessed first
+	items      map[string]*list.Element // Sampled keys by key
+	width      int                      // Capacities covered by every bucket of the histogram
+	histogram  [curvePoints]uint64      // Sampled accesses by scaled stack distance, the farther ones are misses at every capacity
+	references uint64                   // Sampled accesses
+}
+
+// sampledKey is a sampled key with its hash.
+type sampledKey struct {
+	key  string
+	hash uint64
+}
+
+// newCurveEstimator creates an estimator sampling the given share of the keys, estimating the hit ratio
+// of the capacities up to maxCapacity.
+func newCurveEstimator(rate float64, maxCapacity int) *curveEstimator {
+	threshold := uint64(math.MaxUint64)
+	if rate < 1 {
+		threshold = uint64(max(rate, 0) * math.MaxUint64)
+	}
+	return &curveEstimator{
+		seed:      maphash.MakeSeed(),
+		threshold: threshold,
+		stack:     list.New(),
+		items:     make(map[string]*list.Element),
+		width:     max((maxCapacity+curvePoints-1)/curvePoints, 1),
+	}
+}
+
+// access records an access to the key, if it is sampled.
+func (curve *curveEstimator) access(key string) {
+	if curve == nil {
+		return
+	}
+	hash := maphash.String(curve.seed, key)
+	curve.mutex.Lock()
+	defer curve.mutex.Unlock()
+
+	if hash >= curve.threshold && curve.threshold != math.MaxUint64 {
+		return
+	}
+	curve.references++
+	if elem, found := curve.items[key]; found {
+		distance := 0
+		for e := curve.stack.Front(); e != elem; e = e.Next() {
+			distance++
+		}
+		// The sampled keys stand for 1/rate keys each
+		scaled := float64(distance) / (float64(curve.threshold) / math.MaxUint64)
+		if bucket := int(scaled) / curve.width; bucket < curvePoints {
+			curve.histogram[bucket]++
+		}
+		curve.stack.MoveToFront(elem)
+		return
+	}
+
+	curve.items[key] = curve.stack.PushFront(sampledKey{key: key, hash: hash})
+	if len(curve.items) > curveSampleKeys {
+		curve.lowerThreshold()
+	}
+}
+
+// lowerThreshold stops sampling the key with the highest hash.
+func (curve *curveEstimator) lowerThreshold() {
+	var highest *list.Element
+	for _, elem := range curve.items {
+		if highest == nil || elem.Value.(sampledKey).hash > highest.Value.(sampledKey).hash {
+			highest = elem
+		}
+	}
+	curve.threshold = highest.Value.(sampledKey).hash
+	delete(curve.items, highest.Value.(sampledKey).key)
+	curve.stack.Remove(highest)
+}
+
+// points returns the estimated hit ratio at evenly spaced capacities, nil without estimator.
+func (curve *curveEstimator) points() []CurvePoint {
+	if curve == nil {
+		return nil
+	}
+	curve.mutex.Lock()
+	defer curve.mutex.Unlock()
+
+	points := make([]CurvePoint, curvePoints)
+	var hits uint64
+	for i, count := range curve.histogram {
+		hits += count
+		points[i].Capacity = (i + 1) * curve.width
+		if curve.references > 0 {
+			points[i].HitRatio = float64(hits) / float64(curve.references)
+		}
+	}
+	return points
+}
+
+// mergeCurves merges the curves of several caches sharing the accesses, such as the shards of a ShardedCache,
+// by adding up their capacities and weighting their hit ratios.
+func mergeCurves(curves [][]CurvePoint, weights []float64) []CurvePoint {
+	if len(curves) == 0 {
+		return nil
+	}
+	var total float64
+	for _, weight := range weights {
+		total += weight
+	}
+	merged := make([]CurvePoint, len(curves[0]))
+	for i := range merged {
+		for j, curve := range curves {
+			merged[i].Capacity += curve[i].Capacity
+			if total > 0 {
+				merged[i].HitRatio += curve[i].HitRatio * weights[j] / total
+			}
+		}
+	}
+	return merged
+}
@@ -0,0 +1,60 @@
+package lru
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHitRatioCurve(t *testing.T) {
+	cache := NewLRUCache(5, WithHitRatioCurve(1, 20), WithoutMetrics())
+	for range 10 {
+		for i := range 10 { // Every key is read again after the 9 others
+			cache.Get(fmt.Sprint("key", i))
+		}
+	}
+
+	curve := cache.Stats().HitRatioCurve
+	assert.Len(t, curve, curvePoints)
+	assert.Equal(t, CurvePoint{Capacity: 9, HitRatio: 0}, curve[8])
+	assert.Equal(t, CurvePoint{Capacity: 10, HitRatio: 0.9}, curve[9])
+	assert.Equal(t, CurvePoint{Capacity: 20, HitRatio: 0.9}, curve[19])
+	assert.Nil(t, NewLRUCache(5).Stats().HitRatioCurve) // Disabled
+}
+
+func TestHitRatioCurveSampled(t *testing.T) {
+	cache := NewLRUCache(10, WithHitRatioCurve(0.5, 2000), WithoutMetrics())
+	for range 5 {
+		for i := range 1000 {
+			cache.Get(fmt.Sprint("key", i))
+		}
+	}
+
+	curve := cache.Stats().HitRatioCurve
+	assert.Zero(t, curve[7].HitRatio) // Capacity 800
+	assert.InDelta(t, 0.8, curve[11].HitRatio, 0.01)
+}
+
+func TestHitRatioCurveBoundedSample(t *testing.T) {
+	cache := NewLRUCache(10, WithHitRatioCurve(1, 100), WithoutMetrics())
+	for i := range curveSampleKeys + 100 {
+		cache.Set(fmt.Sprint("key", i), i)
+	}
+	assert.Len(t, cache.curve.items, curveSampleKeys)
+	assert.Equal(t, curveSampleKeys, cache.curve.stack.Len())
+}
+
+func TestHitRatioCurveSharded(t *testing.T) {
+	cache := NewShardedCache(2, 10, WithHitRatioCurve(1, 20))
+	for range 10 {
+		for i := range 4 {
+			cache.Get(fmt.Sprint("key", i))
+		}
+	}
+
+	curve := cache.Stats().HitRatioCurve
+	assert.Len(t, curve, curvePoints)
+	assert.Equal(t, 2, curve[0].Capacity) // One per shard
+	assert.InDelta(t, 0.9, curve[19].HitRatio, 1e-9)
+}
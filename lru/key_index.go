@@ -0,0 +1,86 @@
+package lru
+
+import (
+	"math/rand/v2"
+	"strings"
+)
+
+// keyIndexMaxLevel bounds the height of the skip list, enough for billions of keys.
+const keyIndexMaxLevel = 24
+
+// keyIndex keeps the keys of a cache sorted in a skip list, so the keys with a given prefix
+// are found in O(log n) plus the number of matches instead of scanning the whole cache.
+type keyIndex struct {
+	head  indexNode // Sentinel before the first key
+	level int       // Number of levels in use
+}
+
+// indexNode is a key of the skip list, with its successor on every level it belongs to.
+type indexNode struct {
+	key  string
+	next []*indexNode
+}
+
+func newKeyIndex() *keyIndex {
+	return &keyIndex{head: indexNode{next: make([]*indexNode, keyIndexMaxLevel)}, level: 1}
+}
+
+// predecessors fills path with the last node before key on every level.
+func (index *keyIndex) predecessors(key string, path *[keyIndexMaxLevel]*indexNode) {
+	node := &index.head
+	for level := index.level - 1; level >= 0; level-- {
+		for node.next[level] != nil && node.next[level].key < key {
+			node = node.next[level]
+		}
+		path[level] = node
+	}
+}
+
+// insert adds the key, if it is not already in the index.
+func (index *keyIndex) insert(key string) {
+	var path [keyIndexMaxLevel]*indexNode
+	index.predecessors(key, &path)
+	if next := path[0].next[0]; next != nil && next.key == key {
+		return
+	}
+
+	level := 1
+	for level < keyIndexMaxLevel && rand.Uint32()%4 == 0 { // Each level holds a quarter of the keys of the one below
+		level++
+	}
+	for ; index.level < level; index.level++ {
+		path[index.level] = &index.head
+	}
+	node := &indexNode{key: key, next: make([]*indexNode, level)}
+	for i := range level {
+		node.next[i] = path[i].next[i]
+		path[i].next[i] = node
+	}
+}
+
+// delete removes the key, if it is in the index.
+func (index *keyIndex) delete(key string) {
+	var path [keyIndexMaxLevel]*indexNode
+	index.predecessors(key, &path)
+	node := path[0].next[0]
+	if node == nil || node.key != key {
+		return
+	}
+	for i := range node.next {
+		path[i].next[i] = node.next[i]
+	}
+	for index.level > 1 && index.head.next[index.level-1] == nil {
+		index.level--
+	}
+}
+
+// withPrefix returns the keys starting with prefix, in lexicographic order.
+func (index *keyIndex) withPrefix(prefix string) []string {
+	var path [keyIndexMaxLevel]*indexNode
+	index.predecessors(prefix, &path)
+	var keys []string
+	for node := path[0].next[0]; node != nil && strings.HasPrefix(node.key, prefix); node = node.next[0] {
+		keys = append(keys, node.key)
+	}
+	return keys
+}
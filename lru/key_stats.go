@@ -0,0 +1,163 @@
+package lru
+
+import (
+	"cmp"
+	"container/list"
+	"slices"
+	"sync"
+	"time"
+)
+
+// keyStatsSlots is the number of slots the window of a KeyStats is divided into: the hits of a key
+// leave the window one slot at a time.
+const keyStatsSlots = 12
+
+// KeyStat is the activity of a key retained by a KeyStats.
+type KeyStat struct {
+	Key          string    `json:"key"`
+	Hits         uint64    `json:"hits"`       // Hits within the window
+	TotalHits    uint64    `json:"total_hits"` // Hits since the key is tracked
+	LastHit      time.Time `json:"last_hit,omitzero"`
+	Evictions    uint64    `json:"evictions"` // Times the key was evicted since it is tracked
+	LastEviction time.Time `json:"last_eviction,omitzero"`
+}
+
+// KeyStats retains the hits and evictions of the keys beyond their eviction, to report the hottest keys of
+// the last window and the most frequently evicted ones, e.g. to tune the capacity or to spot the keys worth
+// pinning. It receives the events of the caches through Record, so the same KeyStats can be shared by the shards
+// of a ShardedCache. At most maxKeys keys are tracked: the least recently active one is forgotten for a new one.
+// It is thread-safe.
+type KeyStats struct {
+	mutex   sync.Mutex
+	clock   Clock
+	maxKeys int
+	slot    time.Duration            // Duration covered by every slot of the window
+	keys    map[string]*list.Element // Tracked keys by key
+	recent  *list.List               // Tracked keys, most recently active first
+}
+
+// keyRecord is the activity of a tracked key.
+type keyRecord struct {
+	KeyStat
+	slots    [keyStatsSlots]uint64 // Hits by slot, slots[last%keyStatsSlots] being the latest
+	lastSlot int64                 // Index of the latest slot hit, counted from the zero time
+}
+
+// NewKeyStats creates a KeyStats tracking up to maxKeys keys, counting their hits over the last window
+// as told by the clock, the system clock if nil. It must be the clock of the caches.
+func NewKeyStats(maxKeys int, window time.Duration, clock Clock) *KeyStats {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &KeyStats{
+		clock:   clock,
+		maxKeys: max(maxKeys, 1),
+		slot:    max(window/keyStatsSlots, 1),
+		keys:    make(map[string]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// Record counts the hits and the evictions among the events. It is an EventListener, given to the caches
+// with WithEventListener.
+func (stats *KeyStats) Record(event Event) {
+	switch {
+	case event.Type == EventHit:
+		stats.mutex.Lock()
+		defer stats.mutex.Unlock()
+
+		record := stats.track(event.Key)
+		record.advance(stats.slotOf(event.Time))
+		record.slots[record.lastSlot%keyStatsSlots]++
+		record.TotalHits++
+		record.LastHit = event.Time
+	case event.Type == EventRemoved && event.Reason == metricReasonEvicted:
+		stats.mutex.Lock()
+		defer stats.mutex.Unlock()
+
+		record := stats.track(event.Key)
+		record.Evictions++
+		record.LastEviction = event.Time
+	}
+}
+
+// track returns the record of the key, tracking it if needed, and marks it as the most recently active.
+func (stats *KeyStats) track(key string) *keyRecord {
+	if elem, found := stats.keys[key]; found {
+		stats.recent.MoveToFront(elem)
+		return elem.Value.(*keyRecord)
+	}
+	if stats.recent.Len() >= stats.maxKeys {
+		oldest := stats.recent.Back()
+		stats.recent.Remove(oldest)
+		delete(stats.keys, oldest.Value.(*keyRecord).Key)
+	}
+	record := &keyRecord{KeyStat: KeyStat{Key: key}}
+	stats.keys[key] = stats.recent.PushFront(record)
+	return record
+}
+
+// slotOf returns the index of the slot of the given time.
+func (stats *KeyStats) slotOf(t time.Time) int64 {
+	return t.UnixNano() / int64(stats.slot)
+}
+
+// advance moves the latest slot of the record forward to the given one, clearing the slots left behind.
+func (record *keyRecord) advance(slot int64) {
+	if slot <= record.lastSlot {
+		return
+	}
+	for i := record.lastSlot + 1; i <= slot && i <= record.lastSlot+keyStatsSlots; i++ {
+		record.slots[i%keyStatsSlots] = 0
+	}
+	record.lastSlot = slot
+}
+
+// windowHits returns the hits of the record within the window ending at the given slot.
+func (record *keyRecord) windowHits(slot int64) uint64 {
+	var hits uint64
+	for i := max(record.lastSlot-keyStatsSlots+1, slot-keyStatsSlots+1); i <= record.lastSlot; i++ {
+		hits += record.slots[i%keyStatsSlots]
+	}
+	return hits
+}
+
+// TopHits returns up to n of the keys hit the most within the window, the hottest first.
+func (stats *KeyStats) TopHits(n int) []KeyStat {
+	return stats.top(n, func(stat KeyStat) uint64 { return stat.Hits })
+}
+
+// TopEvictions returns up to n of the keys evicted the most, the most frequently evicted first.
+// The keys evicted again and again soon after being added tell that the capacity is too small for them.
+func (stats *KeyStats) TopEvictions(n int) []KeyStat {
+	return stats.top(n, func(stat KeyStat) uint64 { return stat.Evictions })
+}
+
+// top returns up to n of the tracked keys with the highest non-zero count, ties broken by key.
+func (stats *KeyStats) top(n int, count func(KeyStat) uint64) []KeyStat {
+	now := stats.slotOf(stats.clock.Now())
+	stats.mutex.Lock()
+	keys := make([]KeyStat, 0, stats.recent.Len())
+	for elem := stats.recent.Front(); elem != nil; elem = elem.Next() {
+		record := elem.Value.(*keyRecord)
+		stat := record.KeyStat
+		stat.Hits = record.windowHits(now)
+		if count(stat) > 0 {
+			keys = append(keys, stat)
+		}
+	}
+	stats.mutex.Unlock()
+
+	slices.SortFunc(keys, func(a, b KeyStat) int {
+		return cmp.Or(cmp.Compare(count(b), count(a)), cmp.Compare(a.Key, b.Key))
+	})
+	return keys[:min(max(n, 0), len(keys))]
+}
+
+// Len returns the number of keys tracked.
+func (stats *KeyStats) Len() int {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	return stats.recent.Len()
+}
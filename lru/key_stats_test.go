@@ -0,0 +1,59 @@
+package lru
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyStats(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	stats := NewKeyStats(100, time.Hour, clock)
+	cache := NewLRUCache(2, WithoutMetrics(), WithClock(clock), WithEventListener(stats.Record))
+
+	cache.Set("old", 1)
+	for range 5 {
+		cache.Get("old")
+	}
+	clock.Advance(2 * time.Hour)
+	cache.Set("hot", 2)
+	for range 3 {
+		cache.Get("hot")
+	}
+	cache.Set("warm", 3)
+	cache.Get("warm")
+
+	top := stats.TopHits(10)
+	assert.Len(t, top, 2, "Expected the hits older than the window to be left out")
+	assert.Equal(t, "hot", top[0].Key)
+	assert.Equal(t, uint64(3), top[0].Hits)
+	assert.Equal(t, uint64(1), top[1].Hits)
+	assert.Equal(t, uint64(5), stats.TopEvictions(1)[0].TotalHits, "Expected the hits of the evicted old to be retained")
+
+	for i := range 3 {
+		cache.Set("hot", i)
+		cache.Set("filler1", i)
+		cache.Set("filler2", i) // Evicts hot
+	}
+	evicted := stats.TopEvictions(1)
+	assert.Len(t, evicted, 1)
+	assert.Equal(t, "hot", evicted[0].Key)
+	assert.Equal(t, uint64(3), evicted[0].Evictions)
+	assert.Equal(t, uint64(3), evicted[0].TotalHits)
+	assert.Equal(t, clock.Now(), evicted[0].LastEviction)
+	assert.Len(t, stats.TopEvictions(-1), 0)
+}
+
+func TestKeyStatsBounded(t *testing.T) {
+	stats := NewKeyStats(3, time.Minute, nil)
+	now := time.Now()
+	for i := range 10 {
+		stats.Record(Event{Type: EventHit, Key: strconv.Itoa(i), Time: now})
+	}
+	stats.Record(Event{Type: EventMiss, Key: "missing", Time: now})
+	assert.Equal(t, 3, stats.Len())
+	top := stats.TopHits(5)
+	assert.Equal(t, []string{"7", "8", "9"}, []string{top[0].Key, top[1].Key, top[2].Key}, "Expected the least recently active keys to be forgotten")
+}
@@ -0,0 +1,41 @@
+package lru
+
+import (
+	"strings"
+)
+
+// KeyTransform rewrites the keys given to a cache before any operation, e.g. strings.ToLower or strings.TrimSpace.
+// The keys restored from a log or a snapshot go through it again, so it should be idempotent.
+type KeyTransform func(key string) string
+
+// KeyHash hashes the keys to spread them over the shards of a ShardedCache.
+type KeyHash func(key string) uint64
+
+// apply returns the transformed key, or the key itself without transform.
+func (transform KeyTransform) apply(key string) string {
+	if transform == nil {
+		return key
+	}
+	return transform(key)
+}
+
+// ChainKeyTransforms returns a transform applying the given ones in order.
+func ChainKeyTransforms(transforms ...KeyTransform) KeyTransform {
+	return func(key string) string {
+		for _, transform := range transforms {
+			key = transform(key)
+		}
+		return key
+	}
+}
+
+// PrefixKeys returns a transform prefixing the keys, e.g. with a tenant or a namespace.
+// The keys already starting with the prefix are kept as is, so the transform is idempotent.
+func PrefixKeys(prefix string) KeyTransform {
+	return func(key string) string {
+		if strings.HasPrefix(key, prefix) {
+			return key
+		}
+		return prefix + key
+	}
+}
@@ -0,0 +1,75 @@
+package lru
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyTransform(t *testing.T) {
+	normalize := ChainKeyTransforms(strings.TrimSpace, strings.ToLower, PrefixKeys("tenant:"))
+	caches := map[string]Cache{
+		"lru":     NewLRUCache(5, WithKeyTransform(normalize), WithKeyIndex()),
+		"lfu":     NewLFUCache(5, WithKeyTransform(normalize)),
+		"safe":    NewSafeLRUCache(5, WithKeyTransform(normalize), WithKeyIndex()),
+		"sharded": NewShardedCache(3, 9, WithKeyTransform(normalize), WithKeyIndex()),
+	}
+	for name, cache := range caches {
+		t.Run(name, func(t *testing.T) {
+			cache.Set(" User:1 ", "Ada")
+			value, found := cache.Get("user:1")
+			assert.True(t, found)
+			assert.Equal(t, "Ada", value)
+			_, found = cache.Get("tenant:USER:1")
+			assert.True(t, found, "Expected the transform to be applied once")
+
+			cache.SetWithTTL("USER:2", "Alan", time.Minute)
+			counter, err := cache.(Incrementer).Increment("Hits", 1, 0)
+			assert.NoError(t, err)
+			counter, _ = cache.(Incrementer).Increment("hits ", 1, 0)
+			assert.Equal(t, int64(2), counter)
+			assert.Equal(t, 3, cache.Len())
+
+			if scanner, ok := cache.(KeyspaceScanner); ok {
+				var keys []string
+				scanner.ScanPrefix("USER", func(key string, value any) bool {
+					keys = append(keys, key)
+					return true
+				})
+				assert.ElementsMatch(t, []string{"tenant:user:1", "tenant:user:2"}, keys)
+			}
+
+			cache.Remove("User:2")
+			_, found = cache.Get("user:2")
+			assert.False(t, found)
+		})
+	}
+}
+
+func TestKeyTransformWithUnsafePeek(t *testing.T) {
+	cache := NewSafeLRUCache(5, WithKeyTransform(strings.ToLower), WithoutMetrics())
+	cache.Set("Key", 1)
+
+	value, found := cache.Peek("Key")
+	assert.True(t, found)
+	assert.Equal(t, 1, value)
+	value, found = cache.UnsafePeek("Key")
+	assert.True(t, found, "Expected UnsafePeek to transform the key like Peek")
+	assert.Equal(t, 1, value)
+}
+
+func TestKeyHash(t *testing.T) {
+	cache := NewShardedCache(4, 8, WithKeyHash(func(key string) uint64 { return uint64(len(key)) }))
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+
+	assert.Equal(t, 2, cache.shards[1].Len(), "Expected the keys of the same length on the same shard")
+}
+
+func TestPrefixKeysIsIdempotent(t *testing.T) {
+	prefix := PrefixKeys("ns:")
+	assert.Equal(t, "ns:key", prefix("key"))
+	assert.Equal(t, "ns:key", prefix(prefix("key")))
+}
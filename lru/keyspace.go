@@ -0,0 +1,316 @@
+package lru
+
+import (
+	"sort"
+	"strings"
+)
+
+// KeyspaceScanner is implemented by the caches able to find their keys by prefix or glob pattern,
+// e.g. to invalidate every key of a user at once without tracking them.
+type KeyspaceScanner interface {
+	// ScanPrefix calls fn with every live item whose key starts with prefix, until fn returns false.
+	ScanPrefix(prefix string, fn func(key string, value any) bool)
+	// RemoveByPrefix removes every item whose key starts with prefix, and returns how many live items were removed.
+	RemoveByPrefix(prefix string) int
+	// ScanPattern calls fn with every live item whose key matches the glob pattern, until fn returns false.
+	ScanPattern(pattern string, fn func(key string, value any) bool)
+	// RemoveByPattern removes every item whose key matches the glob pattern, and returns how many live items were removed.
+	RemoveByPattern(pattern string) int
+}
+
+var _ KeyspaceScanner = (*LRUCache)(nil)     // Ensure LRUCache supports keyspace scans
+var _ KeyspaceScanner = (*SafeLRUCache)(nil) // Ensure SafeLRUCache supports keyspace scans
+var _ KeyspaceScanner = (*ShardedCache)(nil) // Ensure ShardedCache supports keyspace scans
+
+// matchingKeys returns the keys starting with prefix and matching the pattern, if any.
+// With a key index, only the keys with the prefix are visited and they are returned sorted,
+// otherwise every key is visited and they are returned in no particular order.
+func (cache *LRUCache) matchingKeys(prefix string, pattern string) []string {
+	var keys []string
+	if cache.keys != nil {
+		keys = cache.keys.withPrefix(prefix)
+	} else {
+		for key := range cache.items {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	if pattern == "" {
+		return keys
+	}
+	matching := keys[:0]
+	for _, key := range keys {
+		if matchGlob(pattern, key) {
+			matching = append(matching, key)
+		}
+	}
+	return matching
+}
+
+// scan calls fn with the live items of the keys, without promoting them.
+func (cache *LRUCache) scan(keys []string, fn func(key string, value any) bool) {
+	now := cache.clock.Now()
+	for _, key := range keys {
+		ent := cache.items[key].Value.(*entry)
+		if !ent.hasExpired(now) && !fn(key, cache.load(ent)) {
+			return
+		}
+	}
+}
+
+// removeKeys removes the items of the keys, and returns how many were alive.
+func (cache *LRUCache) removeKeys(keys []string) int {
+	now := cache.clock.Now()
+	removed := 0
+	for _, key := range keys {
+		if cache.items[key].Value.(*entry).hasExpired(now) {
+			cache.remove(key, metricReasonExpired)
+		} else {
+			cache.remove(key, metricReasonManual)
+			removed++
+		}
+	}
+	return removed
+}
+
+// ScanPrefix calls fn with every live item whose key starts with prefix, until fn returns false.
+// The items are not promoted. With WithKeyIndex, only the matching keys are visited, in lexicographic order.
+// fn must not modify the cache.
+func (cache *LRUCache) ScanPrefix(prefix string, fn func(key string, value any) bool) {
+	prefix = cache.transform.apply(prefix)
+	cache.scan(cache.matchingKeys(prefix, ""), fn)
+}
+
+// RemoveByPrefix removes every item whose key starts with prefix, and returns how many live items were removed.
+func (cache *LRUCache) RemoveByPrefix(prefix string) int {
+	prefix = cache.transform.apply(prefix)
+	return cache.removeKeys(cache.matchingKeys(prefix, ""))
+}
+
+// ScanPattern calls fn with every live item whose key matches the glob pattern, until fn returns false.
+// The pattern supports the wildcards of Redis: * for any sequence, ? for any character, [abc], [a-z]
+// and [^a] for character classes, and \ to escape them. With WithKeyIndex, only the keys starting
+// with the literal prefix of the pattern are visited. fn must not modify the cache.
+func (cache *LRUCache) ScanPattern(pattern string, fn func(key string, value any) bool) {
+	cache.scan(cache.matchingKeys(globPrefix(pattern), pattern), fn)
+}
+
+// RemoveByPattern removes every item whose key matches the glob pattern, and returns how many live items were removed.
+func (cache *LRUCache) RemoveByPattern(pattern string) int {
+	return cache.removeKeys(cache.matchingKeys(globPrefix(pattern), pattern))
+}
+
+// keyValue is a key and its value, collected under the lock of a SafeLRUCache.
+type keyValue struct {
+	key   string
+	value any
+}
+
+// collect returns the live items of the keys starting with prefix and matching the pattern, if any.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) collect(prefix string, pattern string) []keyValue {
+	safeCache.lock()
+	defer safeCache.mutex.Unlock()
+
+	lru, ok := safeCache.cache.(*LRUCache)
+	if !ok {
+		return nil
+	}
+	var items []keyValue
+	lru.scan(lru.matchingKeys(prefix, pattern), func(key string, value any) bool {
+		items = append(items, keyValue{key, value})
+		return true
+	})
+	return items
+}
+
+// removeMatching removes the items of the keys starting with prefix and matching the pattern, if any.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) removeMatching(prefix string, pattern string) int {
+	safeCache.lockOrdered()
+	defer safeCache.mutex.Unlock()
+
+	if lru, ok := safeCache.cache.(*LRUCache); ok {
+		return lru.removeKeys(lru.matchingKeys(prefix, pattern))
+	}
+	return 0
+}
+
+// ScanPrefix calls fn with every live item whose key starts with prefix, until fn returns false.
+// The items are collected under the lock and fn is called after releasing it, so fn may use the cache.
+// It does nothing if the underlying cache is not an LRUCache.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) ScanPrefix(prefix string, fn func(key string, value any) bool) {
+	prefix = safeCache.transform.apply(prefix)
+	for _, item := range safeCache.collect(prefix, "") {
+		if !fn(item.key, item.value) {
+			return
+		}
+	}
+}
+
+// RemoveByPrefix removes every item whose key starts with prefix, and returns how many live items were removed.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) RemoveByPrefix(prefix string) int {
+	prefix = safeCache.transform.apply(prefix)
+	return safeCache.removeMatching(prefix, "")
+}
+
+// ScanPattern calls fn with every live item whose key matches the glob pattern, until fn returns false.
+// Like ScanPrefix, fn is called after releasing the lock.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) ScanPattern(pattern string, fn func(key string, value any) bool) {
+	for _, item := range safeCache.collect(globPrefix(pattern), pattern) {
+		if !fn(item.key, item.value) {
+			return
+		}
+	}
+}
+
+// RemoveByPattern removes every item whose key matches the glob pattern, and returns how many live items were removed.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) RemoveByPattern(pattern string) int {
+	return safeCache.removeMatching(globPrefix(pattern), pattern)
+}
+
+// scanShards calls fn with the matching items of every shard. With WithKeyIndex, the items are sorted by key.
+func (sharded *ShardedCache) scanShards(prefix string, pattern string, fn func(key string, value any) bool) {
+	var items []keyValue
+	for _, shard := range sharded.shards {
+		items = append(items, shard.collect(prefix, pattern)...)
+	}
+	if sharded.shards[0].cache.(*LRUCache).keys != nil {
+		sort.Slice(items, func(i, j int) bool { return items[i].key < items[j].key })
+	}
+	for _, item := range items {
+		if !fn(item.key, item.value) {
+			return
+		}
+	}
+}
+
+// ScanPrefix calls fn with the live items of every shard whose key starts with prefix, until fn returns false.
+// The shards are locked one after the other, fn is called once every shard was scanned.
+// It is thread-safe.
+func (sharded *ShardedCache) ScanPrefix(prefix string, fn func(key string, value any) bool) {
+	prefix = sharded.transform.apply(prefix)
+	sharded.scanShards(prefix, "", fn)
+}
+
+// RemoveByPrefix removes the items of every shard whose key starts with prefix, and returns how many live items were removed.
+// It is thread-safe.
+func (sharded *ShardedCache) RemoveByPrefix(prefix string) int {
+	prefix = sharded.transform.apply(prefix)
+	removed := 0
+	for _, shard := range sharded.shards {
+		removed += shard.RemoveByPrefix(prefix)
+	}
+	return removed
+}
+
+// ScanPattern calls fn with the live items of every shard whose key matches the glob pattern, until fn returns false.
+// It is thread-safe.
+func (sharded *ShardedCache) ScanPattern(pattern string, fn func(key string, value any) bool) {
+	sharded.scanShards(globPrefix(pattern), pattern, fn)
+}
+
+// RemoveByPattern removes the items of every shard whose key matches the glob pattern, and returns how many live items were removed.
+// It is thread-safe.
+func (sharded *ShardedCache) RemoveByPattern(pattern string) int {
+	removed := 0
+	for _, shard := range sharded.shards {
+		removed += shard.RemoveByPattern(pattern)
+	}
+	return removed
+}
+
+// globPrefix returns the literal prefix of the glob pattern, which every matching key starts with.
+func globPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+// MatchPattern reports whether the key matches the glob pattern, with the wildcards of ScanPattern
+// and of the Redis KEYS command, e.g. to filter keys the same way outside the cache.
+func MatchPattern(pattern string, key string) bool {
+	return matchGlob(pattern, key)
+}
+
+// matchGlob reports whether the key matches the glob pattern, with the wildcards of the Redis KEYS command.
+func matchGlob(pattern string, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if matchGlob(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			key = key[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			end, matched := matchClass(pattern, key[0])
+			if !matched {
+				return false
+			}
+			pattern = pattern[end:]
+			key = key[1:]
+			continue
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || key[0] != pattern[0] {
+				return false
+			}
+			key = key[1:]
+		}
+		pattern = pattern[1:]
+	}
+	return len(key) == 0
+}
+
+// matchClass matches the character against the class starting the pattern, such as [a-z] or [^abc].
+// It returns the length of the class in the pattern, and whether the character is in it.
+func matchClass(pattern string, c byte) (int, bool) {
+	i := 1
+	negated := i < len(pattern) && pattern[i] == '^'
+	if negated {
+		i++
+	}
+	matched := false
+	for ; i < len(pattern) && pattern[i] != ']'; i++ {
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			i++
+			matched = matched || pattern[i] == c
+		} else if i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']' {
+			low, high := min(pattern[i], pattern[i+2]), max(pattern[i], pattern[i+2])
+			matched = matched || low <= c && c <= high
+			i += 2
+		} else {
+			matched = matched || pattern[i] == c
+		}
+	}
+	if i < len(pattern) {
+		i++ // The closing bracket
+	}
+	return i, matched != negated
+}
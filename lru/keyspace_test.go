@@ -0,0 +1,109 @@
+package lru
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// scannedKeys returns the keys visited by a scan.
+func scannedKeys(scan func(fn func(key string, value any) bool)) []string {
+	var keys []string
+	scan(func(key string, value any) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func TestScanAndRemoveByPrefix(t *testing.T) {
+	for name, opts := range map[string][]Option{"map": nil, "index": {WithKeyIndex()}} {
+		t.Run(name, func(t *testing.T) {
+			clock := NewManualClock(time.Now())
+			cache := NewLRUCache(10, append(opts, WithoutMetrics(), WithClock(clock))...)
+			cache.Set("user:2:name", "Alan")
+			cache.Set("user:1:name", "Ada")
+			cache.SetWithTTL("user:1:session", "expired", time.Second)
+			cache.Set("user:10:name", "Grace")
+			cache.Set("post:1", "Hello")
+			clock.Advance(2 * time.Second)
+
+			keys := scannedKeys(func(fn func(string, any) bool) { cache.ScanPrefix("user:1", fn) })
+			sort.Strings(keys)
+			assert.Equal(t, []string{"user:10:name", "user:1:name"}, keys, "expired items are skipped")
+
+			assert.Equal(t, 1, cache.RemoveByPrefix("user:1:"))
+			assert.Equal(t, 3, cache.Len())
+			assert.Equal(t, 2, cache.RemoveByPattern("user:*:name"))
+			assert.Equal(t, []string{"post:1"}, scannedKeys(func(fn func(string, any) bool) { cache.ScanPrefix("", fn) }))
+		})
+	}
+}
+
+func TestScanPrefixIsSortedWithKeyIndex(t *testing.T) {
+	cache := NewShardedCache(4, 100, WithKeyIndex(), WithoutMetrics())
+	var expected []string
+	for i := range 20 {
+		key := fmt.Sprintf("key%02d", 19-i)
+		cache.Set(key, i)
+		expected = append(expected, key)
+	}
+	slices.Sort(expected)
+
+	assert.Equal(t, expected, scannedKeys(func(fn func(string, any) bool) { cache.ScanPrefix("key", fn) }))
+	assert.Equal(t, []string{"key01", "key11"}, scannedKeys(func(fn func(string, any) bool) { cache.ScanPattern("key?1", fn) }))
+	assert.Equal(t, 10, cache.RemoveByPrefix("key1"))
+	assert.Equal(t, 10, cache.Len())
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		match   bool
+	}{
+		{"user:*", "user:1", true},
+		{"user:*", "post:1", false},
+		{"*:name", "user:1:name", true},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"**", "", true},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.match, matchGlob(test.pattern, test.key), "%s %s", test.pattern, test.key)
+	}
+	assert.Equal(t, "user:", globPrefix("user:*"))
+}
+
+func TestKeyIndex(t *testing.T) {
+	index := newKeyIndex()
+	present := make(map[string]bool)
+	for range 2000 {
+		key := fmt.Sprintf("k%03d", rand.IntN(500))
+		if rand.IntN(3) == 0 {
+			index.delete(key)
+			delete(present, key)
+		} else {
+			index.insert(key)
+			present[key] = true
+		}
+	}
+	var expected []string
+	for key := range present {
+		expected = append(expected, key)
+	}
+	slices.Sort(expected)
+	assert.Equal(t, expected, index.withPrefix("k"))
+}
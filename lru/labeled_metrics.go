@@ -0,0 +1,199 @@
+package lru
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultMaxNamespaces is the number of namespaces labeled in the metrics when WithNamespaceMetrics is given none.
+const defaultMaxNamespaces = 50
+
+// metricNamespaceOther is the namespace label of the namespaces beyond the limit of WithNamespaceMetrics.
+const metricNamespaceOther = "other"
+
+const (
+	metricResultHit  = "hit" // Result label of the gets of a shard or namespace
+	metricResultMiss = "miss"
+)
+
+// WithShardMetrics records the gets, items and evictions of every shard of a ShardedCache in the
+// cache_shard_* metrics, labeled by the index of the shard, so an imbalanced shard shows on the dashboards.
+// There are as many label values as shards. Ignored by the other caches.
+func WithShardMetrics() Option {
+	return func(o *options) {
+		o.shardMetrics = true
+	}
+}
+
+// withShardLabel sets the shard label of the metrics of a shard of a ShardedCache, see WithShardMetrics.
+func withShardLabel(shard string) Option {
+	return func(o *options) {
+		o.shardLabel = shard
+	}
+}
+
+// WithNamespaceMetrics records the gets, items and evictions of every namespace in the cache_namespace_* metrics,
+// namespaceOf returning the namespace of a key, e.g. the part before its first ':', so a noisy tenant shows
+// on the dashboards. To bound the cardinality, only the first maxNamespaces namespaces seen get their own
+// label, 50 if not positive, and the others are recorded under "other". The limit is shared by the caches
+// given the same option, e.g. the shards of a ShardedCache. Ignored by the caches other than LRUCache.
+func WithNamespaceMetrics(namespaceOf TenantFunc, maxNamespaces int) Option {
+	labels := &namespaceLabels{
+		namespaceOf: namespaceOf,
+		max:         maxNamespaces,
+		seen:        make(map[string]struct{}),
+	}
+	if labels.max <= 0 {
+		labels.max = defaultMaxNamespaces
+	}
+	return func(o *options) {
+		o.namespaces = labels
+	}
+}
+
+// shardMetrics holds the metric children of a shard of a ShardedCache. A nil *shardMetrics records nothing.
+type shardMetrics struct {
+	hits      prometheus.Counter
+	misses    prometheus.Counter
+	items     prometheus.Gauge
+	evictions prometheus.Counter
+}
+
+// newShardMetrics resolves the metric children of the shard of the cache with the given name.
+func newShardMetrics(name string, shard string) *shardMetrics {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	return &shardMetrics{
+		hits:      currentMetrics.shardGets.WithLabelValues(name, shard, metricResultHit),
+		misses:    currentMetrics.shardGets.WithLabelValues(name, shard, metricResultMiss),
+		items:     currentMetrics.shardItems.WithLabelValues(name, shard),
+		evictions: currentMetrics.shardEvictions.WithLabelValues(name, shard),
+	}
+}
+
+// get records a Get, that found its key or not.
+func (metrics *shardMetrics) get(found bool) {
+	if metrics == nil {
+		return
+	}
+	if found {
+		metrics.hits.Inc()
+	} else {
+		metrics.misses.Inc()
+	}
+}
+
+// resized records the number of items of the shard after a write, and whether it evicted an item.
+func (metrics *shardMetrics) resized(items int, evicted bool) {
+	if metrics == nil {
+		return
+	}
+	metrics.items.Set(float64(items))
+	if evicted {
+		metrics.evictions.Inc()
+	}
+}
+
+// namespaceLabels bounds the namespaces labeled in the metrics, see WithNamespaceMetrics.
+type namespaceLabels struct {
+	namespaceOf TenantFunc
+	max         int
+	mutex       sync.Mutex
+	seen        map[string]struct{} // Namespaces with their own label
+}
+
+// label returns the namespace label of the key, metricNamespaceOther once the limit is reached.
+func (labels *namespaceLabels) label(key string) string {
+	namespace := labels.namespaceOf(key)
+
+	labels.mutex.Lock()
+	defer labels.mutex.Unlock()
+
+	if _, found := labels.seen[namespace]; found {
+		return namespace
+	}
+	if len(labels.seen) >= labels.max {
+		return metricNamespaceOther
+	}
+	labels.seen[namespace] = struct{}{}
+	return namespace
+}
+
+// namespaceMetrics records the metrics of the namespaces of a cache. A nil *namespaceMetrics records nothing.
+// It is thread-safe, as the peeks of a SafeLRUCache run concurrently.
+type namespaceMetrics struct {
+	name     string
+	vecs     *metricVecs // Vectors current when the cache was created
+	labels   *namespaceLabels
+	mutex    sync.Mutex
+	children map[string]*namespaceChildren // Metric children by namespace label
+}
+
+// namespaceChildren holds the metric children of a namespace.
+type namespaceChildren struct {
+	hits      prometheus.Counter
+	misses    prometheus.Counter
+	items     prometheus.Gauge
+	evictions prometheus.Counter
+}
+
+// newNamespaceMetrics creates the metrics of the namespaces of the cache with the given name.
+func newNamespaceMetrics(name string, labels *namespaceLabels) *namespaceMetrics {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	return &namespaceMetrics{name: name, vecs: currentMetrics, labels: labels, children: make(map[string]*namespaceChildren)}
+}
+
+// of returns the metric children of the namespace of the key, resolving them on first use.
+func (metrics *namespaceMetrics) of(key string) *namespaceChildren {
+	namespace := metrics.labels.label(key)
+
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	children, found := metrics.children[namespace]
+	if !found {
+		children = &namespaceChildren{
+			hits:      metrics.vecs.namespaceGets.WithLabelValues(metrics.name, namespace, metricResultHit),
+			misses:    metrics.vecs.namespaceGets.WithLabelValues(metrics.name, namespace, metricResultMiss),
+			items:     metrics.vecs.namespaceItems.WithLabelValues(metrics.name, namespace),
+			evictions: metrics.vecs.namespaceEvictions.WithLabelValues(metrics.name, namespace),
+		}
+		metrics.children[namespace] = children
+	}
+	return children
+}
+
+// get records a Get of the key, that found it or not.
+func (metrics *namespaceMetrics) get(key string, found bool) {
+	if metrics == nil {
+		return
+	}
+	if found {
+		metrics.of(key).hits.Inc()
+	} else {
+		metrics.of(key).misses.Inc()
+	}
+}
+
+// added records the addition of the item of the key.
+func (metrics *namespaceMetrics) added(key string) {
+	if metrics != nil {
+		metrics.of(key).items.Inc()
+	}
+}
+
+// removed records the removal of the item of the key for the given reason.
+func (metrics *namespaceMetrics) removed(key string, reason string) {
+	if metrics == nil {
+		return
+	}
+	children := metrics.of(key)
+	children.items.Dec()
+	if reason == metricReasonEvicted {
+		children.evictions.Inc()
+	}
+}
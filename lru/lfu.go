@@ -0,0 +1,364 @@
+package lru
+
+import (
+	"cmp"
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+)
+
+type lfuEntry struct {
+	entry
+	frequency int // Number of times the item has been accessed
+}
+
+// lfuEntryPool recycles the entries removed from the LFU caches, like entryPool does for the LRU ones.
+var lfuEntryPool = sync.Pool{New: func() any { return new(lfuEntry) }}
+
+// acquireLFUEntry returns an entry from the pool, with a frequency of one.
+func acquireLFUEntry(key string, value any, expiresAt time.Time) *lfuEntry {
+	ent := lfuEntryPool.Get().(*lfuEntry)
+	*ent = lfuEntry{entry: makeEntry(key, value, expiresAt), frequency: 1}
+	return ent
+}
+
+// releaseLFUEntry clears the entry, so the pool does not keep its value alive, and returns it to the pool.
+func releaseLFUEntry(ent *lfuEntry) {
+	*ent = lfuEntry{}
+	lfuEntryPool.Put(ent)
+}
+
+type LFUCache struct {
+	capacity     int                      // The capacity of this cache, when full, the least frequently used item will be removed
+	items        map[string]*list.Element // Provides easy access to the cached elements
+	frequencies  map[int]*list.List       // Holds the cached elements grouped by access frequency, most recent first
+	minFrequency int                      // The lowest frequency present in the cache, or below it after a removal, see lowestFrequencyList
+	expiries     expiryIndex              // Holds the entries with an expiration, the soonest to expire first
+	metrics      *cacheMetrics            // Metrics of the cache, nil when disabled
+	clock        Clock                    // Source of the current time, used for expiration
+	ghosts       *ghostList               // Keys evicted recently, nil without WithGhostList
+	curve        *curveEstimator          // Estimates the hit ratio curve, nil without WithHitRatioCurve
+	counters     statsCounters            // Counters reported by Stats
+	previous     bool                     // Whether the writes return the value they replace, see WithPreviousValues
+	evictions    *evictionLimiter         // Caps the evictions per second, nil without WithEvictionRateLimit
+	transform    KeyTransform             // Rewrites the keys before every operation, nil without WithKeyTransform
+	ttlPolicy    TTLPolicy                // Decides the ttl of the items written by Set, nil without WithTTLPolicy
+	admission    *admission               // Decides whether the items are written, nil without WithAdmission
+	listeners                             // Receive the events emitted by the cache
+}
+
+var _ Cache = (*LFUCache)(nil) // Ensure LFUCache implements the Cache interface
+
+func NewLFUCache(capacity int, opts ...Option) *LFUCache {
+	o := newOptions(opts...)
+	cache := &LFUCache{
+		capacity:    capacity,
+		items:       make(map[string]*list.Element),
+		frequencies: make(map[int]*list.List),
+		clock:       o.clock,
+		previous:    o.previousValues,
+		listeners:   o.listeners,
+		transform:   o.keyTransform,
+		ttlPolicy:   o.ttlPolicy,
+		admission:   newAdmission(o),
+	}
+	cache.counters.capacity.Store(int64(capacity))
+	cache.counters.clock = o.clock
+	if o.metrics {
+		cache.metrics = newCacheMetrics(cmp.Or(o.name, metricCacheTypeLFU), o.legacyMetrics, o.metricsBatch)
+	}
+	if o.ghostWindow > 0 {
+		cache.ghosts = newGhostList(o.ghostWindow)
+	}
+	if o.curveRate > 0 {
+		cache.curve = newCurveEstimator(o.curveRate, cmp.Or(o.curveMaxCapacity, 2*capacity))
+	}
+	if o.evictionRate > 0 {
+		cache.evictions = newEvictionLimiter(o.evictionRate, o.clock.Now())
+	}
+	return cache
+}
+
+// Get retrieves an item from the cache by its key.
+// It returns the value and a boolean indicating whether the item was found.
+// If the ttl has expired, the item will be removed and not found.
+func (cache *LFUCache) Get(key string) (value any, found bool) {
+	key = cache.transform.apply(key)
+	cache.ghosts.access()
+	cache.curve.access(key)
+	if elem, found := cache.items[key]; found {
+		ent := elem.Value.(*lfuEntry)
+		if ent.hasExpired(cache.clock.Now()) {
+			cache.remove(key, metricReasonExpired) // Remove the item if it has expired
+			cache.counters.misses.Add(1)
+			cache.emit(EventMiss, key, nil, "")
+			return nil, false // Item expired and removed
+		}
+
+		cache.increment(elem) // Count the access, moving the item to the next frequency
+
+		cache.metrics.getHit() // Increment cache hit metric
+		cache.counters.hits.Add(1)
+		cache.emit(EventHit, key, &ent.entry, "")
+		return ent.value, true
+	}
+	cache.metrics.getMiss() // Increment cache miss metric
+	cache.counters.misses.Add(1)
+	if cache.ghosts.miss(key) {
+		cache.metrics.ghostHit()
+	}
+	cache.emit(EventMiss, key, nil, "")
+	return nil, false // Item not found
+}
+
+// GhostHits returns the number of misses for keys evicted recently, which a larger cache would have served.
+// It is always zero without WithGhostList.
+func (cache *LFUCache) GhostHits() uint64 {
+	return cache.ghosts.hitCount()
+}
+
+// increment moves an element to the list of the next frequency.
+// The element is placed at the front of that list so ties are broken by recency.
+func (cache *LFUCache) increment(element *list.Element) {
+	ent := element.Value.(*lfuEntry)
+	cache.unlink(element)
+
+	ent.frequency++
+	cache.items[ent.key] = cache.frequencyList(ent.frequency).PushFront(ent)
+}
+
+// unlink removes an element from its frequency list, dropping the list when it becomes empty.
+func (cache *LFUCache) unlink(element *list.Element) {
+	frequency := element.Value.(*lfuEntry).frequency
+	bucket := cache.frequencies[frequency]
+	bucket.Remove(element)
+	if bucket.Len() == 0 {
+		delete(cache.frequencies, frequency)
+		if cache.minFrequency == frequency {
+			cache.minFrequency++
+		}
+	}
+}
+
+// frequencyList returns the list holding the elements with the given frequency, creating it if needed.
+func (cache *LFUCache) frequencyList(frequency int) *list.List {
+	bucket, found := cache.frequencies[frequency]
+	if !found {
+		bucket = list.New()
+		cache.frequencies[frequency] = bucket
+	}
+	return bucket
+}
+
+// lowestFrequencyList returns the list of the least frequently used elements, nil if the cache is empty.
+// The minimum frequency only moves up one at a time when its list empties, so it is searched for
+// when no list has that frequency, e.g. after a removal emptied the list of the lowest one.
+func (cache *LFUCache) lowestFrequencyList() *list.List {
+	if bucket, found := cache.frequencies[cache.minFrequency]; found {
+		return bucket
+	}
+	var lowest *list.List
+	for frequency, bucket := range cache.frequencies {
+		if lowest == nil || frequency < cache.minFrequency {
+			lowest, cache.minFrequency = bucket, frequency
+		}
+	}
+	return lowest
+}
+
+// checkCapacity checks if the cache has reached its capacity.
+// If it has, it first reclaims the expired items, and only if none expired it removes
+// the least frequently used item, breaking ties by the least recently used.
+// It returns the key and the value of the item evicted to make room, if any.
+func (cache *LFUCache) checkCapacity() (evicted string, value any, found bool) {
+	if isFull(len(cache.items), cache.capacity) {
+		cache.removeExpired()
+	}
+	if isFull(len(cache.items), cache.capacity) {
+		if bucket := cache.lowestFrequencyList(); bucket != nil && bucket.Back() != nil {
+			evicted, value = bucket.Back().Value.(*lfuEntry).key, bucket.Back().Value.(*lfuEntry).value
+			cache.remove(evicted, metricReasonEvicted)
+			return evicted, value, true
+		}
+	}
+	return "", nil, false
+}
+
+// set adds or updates an item in the cache.
+// Updating an existing item counts as an access and increments its frequency.
+// With values, the result holds the value replaced or evicted.
+func (cache *LFUCache) set(key string, value any, expiration time.Time, values bool) (result SetResult) {
+	if cache.capacity == 0 { // Disabled
+		return SetResult{Status: SetDisabled}
+	}
+	cache.ghosts.access()
+	cache.curve.access(key)
+	if !cache.admission.admits(key, value) {
+		cache.remove(key, metricReasonDenied)
+		return SetResult{Status: SetDenied}
+	}
+	if elem, found := cache.items[key]; found {
+		result = SetResult{Status: SetUpdated}
+		if values && !elem.Value.(*lfuEntry).hasExpired(cache.clock.Now()) {
+			result.Previous, result.HasPrevious = elem.Value.(*lfuEntry).value, true
+		}
+		elem.Value.(*lfuEntry).value = value
+		cache.counters.expirationChanged(elem.Value.(*lfuEntry).expiresAt, expiration)
+		elem.Value.(*lfuEntry).expiresAt = expiration
+		cache.expiries.track(&elem.Value.(*lfuEntry).entry)
+		cache.increment(elem)
+
+		cache.metrics.updated() // Increment cache hit metric
+		cache.emit(EventUpdated, key, &elem.Value.(*lfuEntry).entry, "")
+		return result
+	}
+
+	if cache.throttled(key) {
+		return SetResult{Status: SetThrottled}
+	}
+	var evictedValue any
+	result.EvictedKey, evictedValue, result.Evicted = cache.checkCapacity() // Check capacity before adding a new item
+	if values {
+		result.EvictedValue = evictedValue
+	}
+	newEntry := &lfuEntry{entry: makeEntry(key, value, expiration), frequency: 1}
+	cache.items[key] = cache.frequencyList(1).PushFront(newEntry)
+	cache.expiries.track(&newEntry.entry)
+	cache.ghosts.forget(key)
+	cache.minFrequency = 1
+
+	cache.metrics.added(cache.counters.added(expiration)) // Increment cache miss metric and update total items metric
+	cache.emit(EventAdded, key, &newEntry.entry, "")
+	result.Status = SetAdded
+	return result
+}
+
+// Set adds or updates an item in the cache with no expiration, unless WithTTLPolicy gives it a ttl.
+// Without ttl, the item will not expire unless explicitly removed.
+// If the key already exists, both its value and expiration will be overridden.
+func (cache *LFUCache) Set(key string, value any) SetResult {
+	key = cache.transform.apply(key)
+	if ttl := cache.ttlPolicy.apply(key, value); ttl > 0 {
+		return cache.setWithTTL(key, value, ttl)
+	}
+	return cache.set(key, value, time.Time{}, cache.previous) // No expiration
+}
+
+// SetWithTTL adds or updates an item in the cache with a specified expiration time.
+// It calls the internal set method with the expiration time.
+func (cache *LFUCache) SetWithTTL(key string, value any, ttl time.Duration) SetResult {
+	return cache.setWithTTL(cache.transform.apply(key), value, ttl)
+}
+
+// setWithTTL adds or updates an item expiring after ttl, the key being already transformed.
+func (cache *LFUCache) setWithTTL(key string, value any, ttl time.Duration) (result SetResult) {
+	now := cache.clock.Now()
+	expiration := now.Add(ttl)
+
+	if !hasExpired(expiration, now) {
+		result = cache.set(key, value, expiration, cache.previous)
+	} else {
+		cache.remove(key, metricReasonExpired) // Remove the item if it has expired
+		result = SetResult{Status: SetExpired}
+	}
+
+	cache.metrics.expiration(ttl) // Record the expiration duration in the histogram
+	return result
+}
+
+// remove deletes an item from the cache by key.
+// If the item does not exist, it does nothing.
+// The reason parameter is used to specify why the item is being removed (e.g., "manual", "expired", "evicted").
+func (cache *LFUCache) remove(key string, reason string) {
+	if elem, found := cache.items[key]; found {
+		cache.unlink(elem)
+		cache.expiries.untrack(&elem.Value.(*lfuEntry).entry)
+		delete(cache.items, key)
+		if reason == metricReasonEvicted {
+			cache.ghosts.add(key)
+		}
+
+		length := cache.counters.removed(reason, elem.Value.(*lfuEntry).expiresAt)
+		cache.metrics.removed(reason, length) // Increment eviction metric and update total items metric
+		cache.emit(EventRemoved, key, &elem.Value.(*lfuEntry).entry, reason)
+		releaseLFUEntry(elem.Value.(*lfuEntry)) // The listeners received a copy, the entry can be reused
+		elem.Value = nil
+	}
+}
+
+// removeExpired removes every expired item, using the expiry index to avoid scanning the cache.
+func (cache *LFUCache) removeExpired() {
+	now := cache.clock.Now()
+	for ent := cache.expiries.nextExpired(now); ent != nil; ent = cache.expiries.nextExpired(now) {
+		cache.remove(ent.key, metricReasonExpired)
+	}
+}
+
+// Remove deletes an item from the cache by key.
+func (cache *LFUCache) Remove(key string) {
+	key = cache.transform.apply(key)
+	cache.remove(key, metricReasonManual) // Default reason is "manual"
+}
+
+// Capacity returns the maximum number of items that can be stored in the cache.
+func (cache *LFUCache) Capacity() int {
+	return cache.capacity
+}
+
+// metricsName returns the value of the cache_type label of the metrics, empty when they are disabled.
+func (cache *LFUCache) metricsName() string {
+	return cache.metrics.cacheName()
+}
+
+// Len returns the number of items currently in the cache.
+func (cache *LFUCache) Len() int {
+	return len(cache.items)
+}
+
+// emit sends an event about the given entry to the listeners, if any.
+func (cache *LFUCache) emit(eventType EventType, key string, ent *entry, reason string) {
+	if len(cache.listeners) > 0 {
+		cache.listeners.notify(newEvent(eventType, key, ent, reason, cache.clock.Now()))
+	}
+}
+
+// eachByFrequency walks the cached entries from the most to the least frequently used.
+// Entries sharing a frequency are visited from the most to the least recently used,
+// so the last entry visited is the next eviction candidate.
+func (cache *LFUCache) eachByFrequency(fn func(ent *lfuEntry)) {
+	frequencies := make([]int, 0, len(cache.frequencies))
+	for frequency := range cache.frequencies {
+		frequencies = append(frequencies, frequency)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(frequencies)))
+
+	for _, frequency := range frequencies {
+		for e := cache.frequencies[frequency].Front(); e != nil; e = e.Next() {
+			fn(e.Value.(*lfuEntry))
+		}
+	}
+}
+
+// Increment atomically adds delta to the int64 counter stored under key and returns the new value.
+// If the key is missing or expired, the counter is created with delta as its value, expiring after ttl.
+// A ttl of zero or less creates a counter with the ttl given by WithTTLPolicy, if any, or that does not expire.
+// It returns ErrNotInteger if the key holds a value that is not an int64,
+// ErrThrottled if creating the counter needs an eviction beyond the eviction rate limit,
+// ErrDenied if the admission hook refuses the counter, which is then removed,
+// and ErrDisabled if the capacity of the cache is zero.
+func (cache *LFUCache) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	key = cache.transform.apply(key)
+	now := cache.clock.Now()
+	if elem, found := cache.items[key]; found && !elem.Value.(*lfuEntry).hasExpired(now) {
+		current, ok := elem.Value.(*lfuEntry).value.(int64)
+		if !ok {
+			return 0, ErrNotInteger
+		}
+		return counterResult(cache.set(key, current+delta, elem.Value.(*lfuEntry).expiresAt, false), current+delta)
+	}
+	if ttl <= 0 {
+		ttl = cache.ttlPolicy.apply(key, delta)
+	}
+	return counterResult(cache.set(key, delta, counterExpiration(now, ttl), false), delta)
+}
@@ -0,0 +1,133 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstructLFUCache(t *testing.T) {
+	cache := NewLFUCache(5)
+	assert.NotNil(t, cache)
+	assert.Equal(t, 5, cache.Capacity())
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestLFUSetAndGet(t *testing.T) {
+	cache := NewLFUCache(5)
+	status := cache.Set("key1", "value1")
+	assert.Equal(t, SetAdded, status.Status)
+
+	status = cache.Set("key1", "value1_updated")
+	assert.Equal(t, SetUpdated, status.Status)
+	assert.Equal(t, 1, cache.Len())
+
+	value, found := cache.Get("key1")
+	assert.True(t, found)
+	assert.Equal(t, "value1_updated", value)
+}
+
+func TestLFUEvictsLeastFrequentlyUsed(t *testing.T) {
+	cache := NewLFUCache(2)
+	cache.Set("key1", "value1")
+	cache.Set("key2", "value2")
+	cache.Get("key1")
+	cache.Get("key1")
+	cache.Get("key2")
+	cache.Set("key3", "value3") // key2 was used less often than key1, so it should be evicted
+
+	_, found := cache.Get("key2")
+	assert.False(t, found)
+
+	value, found := cache.Get("key1")
+	assert.True(t, found)
+	assert.Equal(t, "value1", value)
+}
+
+func TestLFUBreaksTiesByRecency(t *testing.T) {
+	cache := NewLFUCache(2)
+	cache.Set("key1", "value1")
+	cache.Set("key2", "value2")
+	cache.Set("key3", "value3") // Both have a frequency of 1, key1 is the least recently used
+
+	_, found := cache.Get("key1")
+	assert.False(t, found)
+
+	_, found = cache.Get("key2")
+	assert.True(t, found)
+}
+
+func TestLFUGetAfterExpiration(t *testing.T) {
+	cache := NewLFUCache(5)
+	cache.SetWithTTL("key1", "value1", 10*time.Millisecond)
+	time.Sleep(11 * time.Millisecond) // Wait for the item to expire
+
+	value, found := cache.Get("key1")
+	assert.False(t, found)
+	assert.Nil(t, value)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestLFUSetWithTTLIfExpired(t *testing.T) {
+	cache := NewLFUCache(5)
+	cache.SetWithTTL("key1", "value1", time.Minute)
+	status := cache.SetWithTTL("key1", "value2", 0)
+	assert.Equal(t, SetExpired, status.Status)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestLFURemove(t *testing.T) {
+	cache := NewLFUCache(5)
+	cache.Set("key1", "value1")
+	cache.Remove("key1")
+	value, found := cache.Get("key1")
+	assert.False(t, found)
+	assert.Nil(t, value)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestObservableLFUState(t *testing.T) {
+	observable := NewObservableCacheFrom(NewSafeLRUCacheFrom(NewLFUCache(3)))
+	observable.Cache.Set("key1", "value1")
+	observable.Cache.Set("key2", "value2")
+	observable.Cache.Get("key2")
+
+	state := observable.State()
+	assert.Equal(t, 3, state.Capacity)
+	assert.Len(t, state.Items, 2)
+	assert.Equal(t, "key2", state.Items[0].Key)
+	assert.Equal(t, 2, state.Items[0].Frequency)
+	assert.Equal(t, "key1", state.Items[0].Next)
+	assert.Equal(t, "key1", state.Items[1].Key)
+	assert.Equal(t, "key2", state.Items[1].Prev)
+	assert.Equal(t, "lfu", state.Policy)
+	assert.Equal(t, 1, state.Items[0].DistanceFromEviction)
+	assert.Equal(t, 0, state.Items[1].DistanceFromEviction, "Expected the least frequently used item to be evicted next")
+}
+
+func TestLFUSetReclaimsExpiredBeforeEvicting(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLFUCache(2, WithClock(clock))
+	cache.SetWithTTL("dead", "value", time.Second)
+	cache.Get("dead") // More frequently used than "live", but expired
+	cache.Set("live", "value")
+
+	clock.Advance(2 * time.Second)
+	cache.Set("new", "value")
+
+	_, found := cache.Get("live")
+	assert.True(t, found)
+	assert.Empty(t, cache.expiries)
+}
+
+func TestLFURemoveClearsRecycledEntry(t *testing.T) {
+	cache := NewLFUCache(5)
+	cache.Set("key1", "value1")
+	ent := cache.items["key1"].Value.(*lfuEntry)
+
+	cache.Remove("key1")
+
+	assert.Nil(t, ent.value)
+	assert.Zero(t, ent.frequency)
+}
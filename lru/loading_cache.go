@@ -0,0 +1,274 @@
+package lru
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Loader loads the value of a key missing from the cache, along with how long it stays valid.
+// A ttl of zero or less stores the value without expiration.
+type Loader func(ctx context.Context, key string) (value any, ttl time.Duration, err error)
+
+// EntryState is the lifecycle state of a key in a LoadingCache.
+type EntryState int
+
+const (
+	// EntryAbsent is a key neither cached nor being loaded.
+	EntryAbsent EntryState = iota
+	// EntryLoading is a key being loaded: the callers reading it wait for the load instead of starting another one.
+	EntryLoading
+	// EntryPresent is a cached key.
+	EntryPresent
+)
+
+// String returns the name of the state, e.g. loading.
+func (state EntryState) String() string {
+	switch state {
+	case EntryLoading:
+		return "loading"
+	case EntryPresent:
+		return "present"
+	default:
+		return "absent"
+	}
+}
+
+// LoadState describes a load in progress, see LoadingCache.Loads.
+type LoadState struct {
+	Key     string    `json:"key"`
+	Started time.Time `json:"started"`
+	Waiters int       `json:"waiters"` // Callers waiting for the load, besides the one running it
+}
+
+// loadCall is a load in progress, shared by every caller waiting for the same key.
+type loadCall struct {
+	done    chan struct{} // Closed once the load has completed
+	value   any           // The loaded value, valid after done is closed
+	err     error         // The loader error, valid after done is closed
+	started time.Time     // When the loader was called
+	waiters int           // Callers waiting for the load, protected by the mutex of the LoadingCache
+}
+
+// LoadingCache wraps a cache, loading the missing keys with a Loader.
+// Concurrent loads of the same key are deduplicated: one caller runs the loader
+// while the others wait for its result. Loader errors are returned and not cached.
+// While a key loads, it is in the EntryLoading state reported by State, Loads and Stats,
+// telling it apart from an absent key.
+type LoadingCache struct {
+	cache    Cache                // The underlying cache, must be thread-safe
+	loader   Loader               // Loads the missing keys
+	mutex    sync.Mutex           // Protects inflight and the waiters of the calls
+	inflight map[string]*loadCall // Loads in progress by key
+	waiters  int                  // Callers waiting for a load in progress, protected by mutex
+	slowLog  *SlowLog             // Records the slow loads, nil to not record them
+	metrics  *loadingMetrics      // Gauges of the loads in progress, nil without metrics
+	breaker  *circuitBreaker      // Short-circuits the loads while the loader fails, nil without a breaker
+
+	stale        *SafeLRUCache // The values loaded with a ttl, kept maxStaleness longer, nil without WithServeStaleOnError
+	maxStaleness time.Duration // How long after their expiration the values are served if their load fails
+}
+
+var _ StatsReporter = (*LoadingCache)(nil) // Ensure LoadingCache reports its stats
+
+// WithServeStaleOnError makes a LoadingCache keep the values loaded with a ttl for maxStaleness after they
+// expire, and return them from Get instead of the error when their load fails, or is short-circuited by the
+// circuit breaker, favoring availability over freshness. GetWithStale tells these values apart.
+// Ignored by the caches.
+func WithServeStaleOnError(maxStaleness time.Duration) Option {
+	return func(o *options) {
+		o.serveStale = true
+		o.maxStaleness = maxStaleness
+	}
+}
+
+// NewLoadingCache creates a LoadingCache storing the loaded values in the given cache,
+// which must be thread-safe, e.g. a SafeLRUCache. Only WithSlowLog, WithCircuitBreaker, WithKeyCircuitBreaker,
+// WithServeStaleOnError, WithClock, WithMetricsName and WithoutMetrics are used among the options:
+// the metrics are labeled "loading" by default.
+func NewLoadingCache(cache Cache, loader Loader, opts ...Option) *LoadingCache {
+	o := newOptions(opts...)
+	loading := &LoadingCache{
+		cache:    cache,
+		loader:   loader,
+		inflight: make(map[string]*loadCall),
+		slowLog:  o.slowLog,
+	}
+	if o.metrics {
+		loading.metrics = newLoadingMetrics(cmp.Or(o.name, metricCacheTypeLoading))
+	}
+	loading.breaker = newCircuitBreaker(o, loading.metrics)
+	if o.serveStale {
+		// The stale copies share the values of the cache, so they only cost their entries
+		loading.stale = NewSafeLRUCache(cache.Capacity(), WithClock(o.clock), WithoutMetrics())
+		loading.maxStaleness = o.maxStaleness
+	}
+	return loading
+}
+
+// Get returns the value of the key, loading it if it is not in the cache.
+// If the context is done while waiting for another caller's load, the context error is returned.
+// While the circuit breaker short-circuits the loads of the key, the value still held by the underlying
+// cache is returned even if expired, or ErrCircuitOpen. With WithServeStaleOnError, a failed load
+// returns the previous value instead of the error, see GetWithStale.
+func (loading *LoadingCache) Get(ctx context.Context, key string) (value any, err error) {
+	value, _, err = loading.GetWithStale(ctx, key)
+	return value, err
+}
+
+// GetWithStale is like Get, also returning whether the value is a stale one returned in place of
+// a failed or short-circuited load: an expired value still held by the underlying cache, or one kept
+// by WithServeStaleOnError.
+func (loading *LoadingCache) GetWithStale(ctx context.Context, key string) (value any, stale bool, err error) {
+	if loading.breaker.rejects(key) {
+		if peeker, ok := loading.cache.(Peeker); ok {
+			if value, found := peeker.Peek(key); found { // Peeked before Get reclaims it if expired
+				_, live := loading.cache.Get(key)
+				return value, !live, nil
+			}
+		}
+	}
+	if value, found := loading.cache.Get(key); found {
+		return value, false, nil
+	}
+
+	value, err = loading.load(ctx, key)
+	if err != nil && ctx.Err() == nil && loading.stale != nil {
+		if value, found := loading.stale.Get(key); found {
+			return value, true, nil
+		}
+	}
+	return value, false, err
+}
+
+// load loads the key, or waits for the load in progress started by another caller.
+func (loading *LoadingCache) load(ctx context.Context, key string) (value any, err error) {
+	loading.mutex.Lock()
+	call, found := loading.inflight[key]
+	if !found {
+		call = &loadCall{done: make(chan struct{}), started: time.Now()}
+		loading.inflight[key] = call
+		loading.mutex.Unlock()
+		loading.metrics.loading(1)
+
+		loading.run(ctx, key, call)
+		return call.value, call.err
+	}
+	call.waiters++
+	loading.waiters++
+	loading.mutex.Unlock()
+	loading.metrics.waiting(1)
+	defer func() {
+		loading.mutex.Lock()
+		call.waiters--
+		loading.waiters--
+		loading.mutex.Unlock()
+		loading.metrics.waiting(-1)
+	}()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run runs the loader for the key, storing the value and releasing the waiting callers.
+func (loading *LoadingCache) run(ctx context.Context, key string, call *loadCall) {
+	defer func() {
+		loading.mutex.Lock()
+		delete(loading.inflight, key)
+		loading.mutex.Unlock()
+		loading.metrics.loading(-1)
+		close(call.done)
+	}()
+
+	if !loading.breaker.allow(key) {
+		call.err = ErrCircuitOpen
+		return
+	}
+	value, ttl, err := loading.loader(ctx, key)
+	loading.slowLog.Observe(SlowLoad, key, call.started, 0, err)
+	loading.breaker.record(key, err)
+	if err != nil {
+		call.err = err
+		return
+	}
+
+	if ttl > 0 {
+		loading.cache.SetWithTTL(key, value, ttl)
+		if loading.stale != nil {
+			loading.stale.SetWithTTL(key, value, ttl+loading.maxStaleness)
+		}
+	} else {
+		loading.cache.Set(key, value)
+	}
+	call.value = value
+}
+
+// State returns whether the key is cached, being loaded or absent, without loading it.
+// The key is peeked if the underlying cache is a Peeker, so it is not promoted nor counted in the stats.
+func (loading *LoadingCache) State(key string) EntryState {
+	loading.mutex.Lock()
+	_, found := loading.inflight[key]
+	loading.mutex.Unlock()
+	if found {
+		return EntryLoading
+	}
+
+	if peeker, ok := loading.cache.(Peeker); ok {
+		_, found = peeker.Peek(key)
+	} else {
+		_, found = loading.cache.Get(key)
+	}
+	if found {
+		return EntryPresent
+	}
+	return EntryAbsent
+}
+
+// BreakerState returns the state of the circuit breaker for the key: the state of the global circuit
+// of WithCircuitBreaker unless closed, otherwise the one of the key of WithKeyCircuitBreaker.
+// It is always BreakerClosed without a breaker.
+func (loading *LoadingCache) BreakerState(key string) BreakerState {
+	return loading.breaker.state(key)
+}
+
+// Loads returns the loads in progress ordered by key, with the number of callers waiting for each of them.
+func (loading *LoadingCache) Loads() []LoadState {
+	loading.mutex.Lock()
+	loads := make([]LoadState, 0, len(loading.inflight))
+	for key, call := range loading.inflight {
+		loads = append(loads, LoadState{Key: key, Started: call.started, Waiters: call.waiters})
+	}
+	loading.mutex.Unlock()
+
+	slices.SortFunc(loads, func(a, b LoadState) int { return strings.Compare(a.Key, b.Key) })
+	return loads
+}
+
+// Stats returns the Stats of the underlying cache, or only its length and capacity if it does not
+// implement StatsReporter, along with the number of keys being loaded and of callers waiting for them.
+func (loading *LoadingCache) Stats() Stats {
+	var stats Stats
+	if reporter, ok := loading.cache.(StatsReporter); ok {
+		stats = reporter.Stats()
+	} else {
+		stats = Stats{Len: loading.cache.Len(), Capacity: loading.cache.Capacity()}
+	}
+
+	loading.mutex.Lock()
+	defer loading.mutex.Unlock()
+	stats.Loading = len(loading.inflight)
+	stats.LoadWaiters = loading.waiters
+	return stats
+}
+
+// Cache returns the underlying cache, e.g. to invalidate keys.
+func (loading *LoadingCache) Cache() Cache {
+	return loading.cache
+}
@@ -0,0 +1,156 @@
+package lru
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadingCacheLoadsMissingKeys(t *testing.T) {
+	loads := 0
+	loading := NewLoadingCache(NewSafeLRUCache(5), func(ctx context.Context, key string) (any, time.Duration, error) {
+		loads++
+		return "value-" + key, time.Minute, nil
+	})
+
+	value, err := loading.Get(context.Background(), "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, "value-key1", value)
+
+	value, err = loading.Get(context.Background(), "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, "value-key1", value)
+	assert.Equal(t, 1, loads, "The second Get should be served from the cache")
+}
+
+func TestLoadingCacheDoesNotCacheErrors(t *testing.T) {
+	failure := errors.New("failure")
+	loading := NewLoadingCache(NewSafeLRUCache(5), func(ctx context.Context, key string) (any, time.Duration, error) {
+		return nil, 0, failure
+	})
+
+	_, err := loading.Get(context.Background(), "key1")
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, 0, loading.Cache().Len())
+}
+
+func TestLoadingCacheDeduplicatesLoads(t *testing.T) {
+	var loads atomic.Int32
+	release := make(chan struct{})
+	loading := NewLoadingCache(NewSafeLRUCache(5), func(ctx context.Context, key string) (any, time.Duration, error) {
+		loads.Add(1)
+		<-release
+		return "value", 0, nil
+	})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := loading.Get(context.Background(), "key1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value", value)
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond) // Let the goroutines pile up behind the first load
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), loads.Load())
+}
+
+func TestLoadingCacheWaitRespectsContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	loading := NewLoadingCache(NewSafeLRUCache(5), func(ctx context.Context, key string) (any, time.Duration, error) {
+		<-release
+		return "value", 0, nil
+	})
+
+	go loading.Get(context.Background(), "key1")
+	time.Sleep(10 * time.Millisecond) // Let the first load start
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := loading.Get(ctx, "key1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLoadingCacheStates(t *testing.T) {
+	release := make(chan struct{})
+	loading := NewLoadingCache(NewSafeLRUCache(5), func(ctx context.Context, key string) (any, time.Duration, error) {
+		<-release
+		return "value", 0, nil
+	}, WithoutMetrics())
+	assert.Equal(t, EntryAbsent, loading.State("key1"))
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loading.Get(context.Background(), "key1")
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		loads := loading.Loads()
+		return len(loads) == 1 && loads[0].Waiters == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, EntryLoading, loading.State("key1"))
+	assert.Equal(t, "loading", loading.State("key1").String())
+	assert.Equal(t, "key1", loading.Loads()[0].Key)
+	stats := loading.Stats()
+	assert.Equal(t, 1, stats.Loading)
+	assert.Equal(t, 2, stats.LoadWaiters)
+	assert.Equal(t, 0, stats.Len)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, EntryPresent, loading.State("key1"))
+	assert.Empty(t, loading.Loads())
+	stats = loading.Stats()
+	assert.Equal(t, 0, stats.Loading)
+	assert.Equal(t, 0, stats.LoadWaiters)
+	assert.Equal(t, 1, stats.Len)
+	assert.Equal(t, uint64(0), stats.Hits, "State does not count as a read")
+}
+
+func TestLoadingCacheServeStaleOnError(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	failure := errors.New("backend down")
+	failing := false
+	loading := NewLoadingCache(NewSafeLRUCache(5, WithClock(clock)), func(ctx context.Context, key string) (any, time.Duration, error) {
+		if failing {
+			return nil, 0, failure
+		}
+		return "value-" + key, time.Second, nil
+	}, WithServeStaleOnError(time.Minute), WithClock(clock), WithoutMetrics())
+	ctx := context.Background()
+
+	value, stale, err := loading.GetWithStale(ctx, "key1")
+	assert.NoError(t, err)
+	assert.False(t, stale)
+	assert.Equal(t, "value-key1", value)
+
+	clock.Advance(30 * time.Second)
+	failing = true
+	value, stale, err = loading.GetWithStale(ctx, "key1")
+	assert.NoError(t, err)
+	assert.True(t, stale)
+	assert.Equal(t, "value-key1", value)
+	value, err = loading.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, "value-key1", value)
+	_, err = loading.Get(ctx, "key2")
+	assert.ErrorIs(t, err, failure, "nothing to serve for a key never loaded")
+
+	clock.Advance(time.Minute)
+	_, err = loading.Get(ctx, "key1")
+	assert.ErrorIs(t, err, failure, "the value is too stale")
+}
@@ -1,94 +1,307 @@
 package lru
 
 import (
+	"cmp"
 	"container/list"
+	"sync"
 	"time"
 )
 
-const (
-	setStatusAdded   = "added"
-	setStatusUpdated = "updated"
-	setStatusExpired = "expired"
-)
+// notIndexed is the expiry position of the entries that are not in the expiry index.
+const notIndexed = -1
 
 type entry struct {
-	key       string    // The key for the cached item
-	value     any       // The value for the cached item
-	expiresAt time.Time // Optional expiration time for the cached item
+	key            string      // The key for the cached item
+	value          any         // The value for the cached item
+	expiresAt      time.Time   // Optional expiration time for the cached item
+	expiryPosition int         // Position of the entry in the expiry index, notIndexed if it does not expire
+	metadata       any         // Opaque data attached by SetWithMetadata, nil otherwise
+	protected      bool        // Whether the entry is in the protected segment, see WithGhostReadmission
+	generation     *generation // Generation the entry was written in, nil outside an LRUCache
+}
+
+// makeEntry creates an entry that is not yet tracked by the expiry index.
+func makeEntry(key string, value any, expiresAt time.Time) entry {
+	return entry{key: key, value: value, expiresAt: expiresAt, expiryPosition: notIndexed}
 }
 
-// hasExpired checks if the entry has expired based on its expiration time.
-func (e *entry) hasExpired() bool {
-	return hasExpired(e.expiresAt)
+// entryPool recycles the entries removed from the caches, so a cache churning at capacity
+// does not leave one entry per eviction for the garbage collector.
+// The list elements holding the entries are still allocated by container/list.
+var entryPool = sync.Pool{New: func() any { return new(entry) }}
+
+// acquireEntry returns an entry from the pool, initialized like makeEntry does.
+func acquireEntry(key string, value any, expiresAt time.Time) *entry {
+	ent := entryPool.Get().(*entry)
+	*ent = makeEntry(key, value, expiresAt)
+	return ent
 }
 
-// hasExpired checks if an expiration date has expired.
+// releaseEntry clears the entry, so the pool does not keep its value alive, and returns it to the pool.
+// The entry must not be referenced anymore by the cache.
+func releaseEntry(ent *entry) {
+	*ent = entry{}
+	entryPool.Put(ent)
+}
+
+// hasExpired checks if the entry has expired at the given time, or belongs to an invalidated generation.
+func (e *entry) hasExpired(now time.Time) bool {
+	return hasExpired(e.expiresAt, now) || e.generation.isInvalidated()
+}
+
+// hasExpired checks if an expiration date has expired at the given time.
 // If the expiration date is zero, it means the item does not expire.
-// If the expiration date is in the past, it means the item has expired.
-func hasExpired(expiration time.Time) bool {
-	return !expiration.IsZero() && expiration.Before(time.Now())
+// If the expiration date is not after the given time, it means the item has expired.
+func hasExpired(expiration time.Time, now time.Time) bool {
+	return !expiration.IsZero() && !expiration.After(now)
+}
+
+// wallDeadline returns the wall-clock time at which the deadline passes, for the expirations leaving the cache,
+// e.g. to be persisted. The expirations are deadlines of the cache clock, whose times carry a monotonic reading
+// with the system clock, used by the comparisons so stepping the wall clock, e.g. by NTP, neither expires
+// the items early nor keeps them forever. The remaining time is measured on that monotonic clock too,
+// and added to the current wall-clock time, so the result stays right after such a step.
+func wallDeadline(deadline time.Time, now time.Time) time.Time {
+	if deadline.IsZero() {
+		return deadline
+	}
+	return now.Round(0).Add(deadline.Sub(now))
+}
+
+// monotonicDeadline returns the deadline of the cache clock matching a wall-clock time, e.g. a persisted expiration,
+// so the cache compares it on the monotonic clock from then on.
+func monotonicDeadline(wall time.Time, now time.Time) time.Time {
+	if wall.IsZero() {
+		return wall
+	}
+	return now.Add(wall.Sub(now.Round(0)))
 }
 
 type LRUCache struct {
 	capacity   int                      // The capacity of this cache, when full, the least recently used item will be removed
 	items      map[string]*list.Element // Provides easy access to the cached elements
 	usageOrder *list.List               // Holds the cached elements in order
-	name       string                   // Name of the cache, used for metrics
+	expiries   expiryIndex              // Holds the elements with an expiration, the soonest to expire first
+	buckets    *expiryBuckets           // Replaces expiries with coarse buckets, nil without WithExpiryBuckets
+	metrics    *cacheMetrics            // Metrics of the cache, nil when disabled
+	namespaces *namespaceMetrics        // Metrics of the namespaces, nil without WithNamespaceMetrics
+	slabs      *slabStore               // Holds the encoded values when the slab storage is enabled, nil otherwise
+	slowLog    *SlowLog                 // Records the slow encodings, nil to not record them
+	keys       *keyIndex                // Sorted keys for the prefix scans, nil without WithKeyIndex
+	indexes    map[string]*valueIndex   // Secondary indexes on the values by name, registered with WithIndex
+	clock      Clock                    // Source of the current time, used for expiration
+	ghosts     *ghostList               // Keys evicted recently, nil without WithGhostList
+	curve      *curveEstimator          // Estimates the hit ratio curve, nil without WithHitRatioCurve
+	counters   statsCounters            // Counters reported by Stats
+	quotas     *tenantQuotas            // Usage of the tenants with a quota, nil without WithTenantQuotas
+	evictions  *evictionLimiter         // Caps the evictions per second, nil without WithEvictionRateLimit
+	watermarks *watermarks              // Soft capacity trimmed in the background, nil without WithWatermarks
+	timeAware  int                      // Least recently used items among which the soonest to expire is evicted, see WithTimeAwareEviction
+	segments   *segments                // Protected and probationary segments of the usage order, nil without WithGhostReadmission
+	generation *generationTracker       // Generation of the items written, see NewGeneration
+	transform  KeyTransform             // Rewrites the keys before every operation, nil without WithKeyTransform
+	ttlPolicy  TTLPolicy                // Decides the ttl of the items written by Set, nil without WithTTLPolicy
+	admission  *admission               // Decides whether the items are written, nil without WithAdmission
+	previous   bool                     // Whether the writes return the value they replace, see WithPreviousValues
+	listeners                           // Receive the events emitted by the cache
 }
 
-var _ Cache = (*LRUCache)(nil) // Ensure LRUCache implements the Cache interface
+var _ Cache = (*LRUCache)(nil)         // Ensure LRUCache implements the Cache interface
+var _ batchedReader = (*LRUCache)(nil) // Ensure LRUCache supports batched promotions
 
-func NewLRUCache(capacity int) *LRUCache {
-	return &LRUCache{
+func NewLRUCache(capacity int, opts ...Option) *LRUCache {
+	o := newOptions(opts...)
+	cache := &LRUCache{
 		capacity:   capacity,
 		items:      make(map[string]*list.Element),
 		usageOrder: list.New(),
-		name:       metricCacheTypeLRU, // Default name for the cache
+		clock:      o.clock,
+		previous:   o.previousValues,
+		listeners:  o.listeners,
+		transform:  o.keyTransform,
+		ttlPolicy:  o.ttlPolicy,
+		slowLog:    o.slowLog,
+		admission:  newAdmission(o),
+		timeAware:  o.timeAwareWindow,
+		generation: newGenerationTracker(),
+	}
+	cache.counters.capacity.Store(int64(capacity))
+	cache.counters.clock = o.clock
+	if o.metrics {
+		cache.metrics = newCacheMetrics(cmp.Or(o.name, metricCacheTypeLRU), o.legacyMetrics, o.metricsBatch)
+		if o.shardLabel != "" {
+			cache.metrics.shard = newShardMetrics(cache.metrics.name, o.shardLabel)
+		}
+		if o.namespaces != nil {
+			cache.namespaces = newNamespaceMetrics(cache.metrics.name, o.namespaces)
+		}
+	}
+	if o.codec != nil {
+		cache.slabs = newSlabStore(o.codec, o.slabSize, o.maxChunkedSize)
+	}
+	if o.keyIndex {
+		cache.keys = newKeyIndex()
+	}
+	if o.ghostWindow > 0 {
+		cache.ghosts = newGhostList(o.ghostWindow)
+	}
+	if o.curveRate > 0 {
+		cache.curve = newCurveEstimator(o.curveRate, cmp.Or(o.curveMaxCapacity, 2*capacity))
+	}
+	if o.tenantOf != nil && len(o.quotas) > 0 {
+		cache.quotas = newTenantQuotas(o.tenantOf, o.weigh, o.quotas)
+		cache.quotas.setName(cmp.Or(o.name, metricCacheTypeLRU), o.metrics, o.legacyMetrics)
+	}
+	if o.expiryBucketWidth > 0 {
+		cache.buckets = newExpiryBuckets(o.expiryBucketWidth, o.clock.Now())
+	}
+	if o.evictionRate > 0 {
+		cache.evictions = newEvictionLimiter(o.evictionRate, o.clock.Now())
+	}
+	if o.readmissionWindow > 0 {
+		cache.segments = newSegments(o.readmissionWindow)
 	}
+	if o.highWatermark > 0 {
+		cache.watermarks = newWatermarks(o.lowWatermark, o.highWatermark)
+	}
+	for name, extract := range o.indexes {
+		if cache.indexes == nil {
+			cache.indexes = make(map[string]*valueIndex)
+		}
+		cache.indexes[name] = newValueIndex(extract)
+	}
+	return cache
+}
+
+// metricsName returns the value of the cache_type label of the metrics, empty when they are disabled.
+func (cache *LRUCache) metricsName() string {
+	return cache.metrics.cacheName()
 }
 
 // Get retrieves an item from the cache by its key.
 // It returns the value and a boolean indicating whether the item was found.
 // If the ttl has expired, the item will be removed and not found.
 func (cache *LRUCache) Get(key string) (value any, found bool) {
+	key = cache.transform.apply(key)
+	cache.ghosts.access()
+	cache.segments.access()
+	cache.curve.access(key)
 	if elem, found := cache.items[key]; found {
-		if elem.Value.(*entry).hasExpired() {
+		if elem.Value.(*entry).hasExpired(cache.clock.Now()) {
 			cache.remove(key, metricReasonExpired) // Remove the item if it has expired
-			return nil, false                      // Item expired and removed
+			cache.counters.misses.Add(1)
+			cache.emit(EventMiss, key, nil, "")
+			return nil, false // Item expired and removed
 		}
 
 		// Move the accessed item to the front of the usage order list
-		cache.usageOrder.MoveToFront(elem)
+		cache.touch(elem)
+		if cache.quotas != nil {
+			cache.quotas.touch(key)
+		}
 
-		cacheHits.WithLabelValues(cache.name, metricOpGet).Inc() // Increment cache hit metric
-		return elem.Value.(*entry).value, true
+		cache.metrics.getHit() // Increment cache hit metric
+		cache.namespaces.get(key, true)
+		cache.counters.hits.Add(1)
+		cache.emit(EventHit, key, elem.Value.(*entry), "")
+		return cache.load(elem.Value.(*entry)), true
+	}
+	cache.metrics.getMiss() // Increment cache miss metric
+	cache.namespaces.get(key, false)
+	cache.counters.misses.Add(1)
+	cache.recordGhostHit(key)
+	cache.emit(EventMiss, key, nil, "")
+	return nil, false // Item not found
+}
+
+// peek returns the value of a live item without moving it nor removing it if it has expired.
+// It only reads the cache, so it can be called concurrently under a read lock.
+// Hits and misses are recorded in the metrics, but events are only emitted by promote.
+func (cache *LRUCache) peek(key string) (value any, found bool) {
+	cache.ghosts.access()
+	cache.segments.access()
+	cache.curve.access(key)
+	if elem, found := cache.items[key]; found && !elem.Value.(*entry).hasExpired(cache.clock.Now()) {
+		cache.metrics.getHit() // Increment cache hit metric
+		cache.namespaces.get(key, true)
+		cache.counters.hits.Add(1)
+		return cache.load(elem.Value.(*entry)), true
+	}
+	cache.metrics.getMiss() // Increment cache miss metric
+	cache.namespaces.get(key, false)
+	cache.counters.misses.Add(1)
+	cache.recordGhostHit(key)
+	return nil, false
+}
+
+// recordGhostHit records a miss in the ghost list, counting it in the metrics if the key was evicted recently.
+func (cache *LRUCache) recordGhostHit(key string) {
+	if cache.ghosts.miss(key) {
+		cache.metrics.ghostHit()
+	}
+}
+
+// GhostHits returns the number of misses for keys evicted recently, which a larger cache would have served.
+// It is always zero without WithGhostList.
+func (cache *LRUCache) GhostHits() uint64 {
+	return cache.ghosts.hitCount()
+}
+
+// promote moves the given keys to the front of the usage order list, as Get would have done.
+func (cache *LRUCache) promote(keys []string) {
+	for _, key := range keys {
+		if elem, found := cache.items[key]; found {
+			cache.touch(elem)
+			if cache.quotas != nil {
+				cache.quotas.touch(key)
+			}
+			cache.emit(EventHit, key, elem.Value.(*entry), "")
+		}
 	}
-	cacheMisses.WithLabelValues(cache.name, metricOpGet).Inc() // Increment cache miss metric
-	return nil, false                                          // Item not found
 }
 
 // update updates the value and expiration time of an existing item in the cache.
 // It moves the item to the front of the usage order list to mark it as recently used.
-func (cache *LRUCache) update(element *list.Element, value any, expiration time.Time) {
+// The value must have been prepared by store.
+func (cache *LRUCache) update(element *list.Element, value any, expiration time.Time, metadata any) {
 	// Update the value and move it to the front of the usage order list
+	cache.release(element.Value.(*entry))
+	if cache.buckets != nil {
+		cache.buckets.remove(element.Value.(*entry)) // Its bucket follows from its current expiration
+	}
 	element.Value.(*entry).value = value
+	cache.counters.expirationChanged(element.Value.(*entry).expiresAt, expiration)
 	element.Value.(*entry).expiresAt = expiration
-	cache.usageOrder.MoveToFront(element)
+	element.Value.(*entry).metadata = metadata
+	element.Value.(*entry).generation = cache.generation.current
+	cache.trackExpiry(element.Value.(*entry))
+	cache.touch(element)
 
-	cacheHits.WithLabelValues(cache.name, metricOpSet).Inc() // Increment cache hit metric
+	cache.metrics.updated() // Increment cache hit metric
+	cache.emit(EventUpdated, element.Value.(*entry).key, element.Value.(*entry), "")
 }
 
 // checkCapacity checks if the cache has reached its capacity.
-// If it has, it removes the least recently used item.
+// If it has, it first reclaims the expired items, and only if none expired it removes the least recently used item.
 // This method is called before adding a new item to ensure the cache does not exceed its capacity.
-func (cache *LRUCache) checkCapacity() {
-	if cache.usageOrder.Len() >= cache.capacity {
-		// Remove the least recently used item
-		leastRecentlyUsed := cache.usageOrder.Back()
+// It returns the key of the item evicted to make room, if any, and its value when values is true.
+func (cache *LRUCache) checkCapacity(values bool) (evicted string, value any, found bool) {
+	if isFull(cache.usageOrder.Len(), cache.capacity) {
+		cache.removeExpired()
+	}
+	if isFull(cache.usageOrder.Len(), cache.capacity) {
+		// Remove the least recently used item, or the soonest to expire among the least recently used ones
+		leastRecentlyUsed := cache.victim()
 		if leastRecentlyUsed != nil {
-			cache.remove(leastRecentlyUsed.Value.(*entry).key, metricReasonEvicted)
+			evicted = leastRecentlyUsed.Value.(*entry).key
+			if values {
+				value = cache.load(leastRecentlyUsed.Value.(*entry)) // Before the slab space is freed
+			}
+			cache.remove(evicted, metricReasonEvicted)
+			return evicted, value, true
 		}
 	}
+	return "", nil, false
 }
 
 // Set adds or updates an item in the cache.
@@ -96,44 +309,98 @@ func (cache *LRUCache) checkCapacity() {
 // If the item already exists, it updates the value and expiration time.
 // If the expiration time is in the past, the item will be removed immediately.
 // If the expiration time is zero, the item will not expire.
-func (cache *LRUCache) set(key string, value any, expiration time.Time) (status string) {
+// If the value cannot be written to the slab storage, or the eviction rate limit is reached, the cache is left untouched.
+// If the admission hook refuses the item, the current item of the key is removed.
+// If the capacity is zero, nothing is stored.
+// The metadata replaces the one of the existing item, if any.
+// With values, the result holds the value replaced or evicted.
+func (cache *LRUCache) set(key string, value any, expiration time.Time, metadata any, values bool) (result SetResult) {
+	if cache.capacity == 0 { // Disabled
+		return SetResult{Status: SetDisabled}
+	}
+	cache.ghosts.access()
+	cache.segments.access()
+	cache.curve.access(key)
+	if !cache.admission.admits(key, value) {
+		cache.remove(key, metricReasonDenied)
+		return SetResult{Status: SetDenied}
+	}
+	if cache.throttled(key) {
+		return SetResult{Status: SetThrottled}
+	}
+	stored, err := cache.store(key, value)
+	if err != nil {
+		return SetResult{Status: SetRejected}
+	}
+	defer cache.indexValue(key, value) // Indexed on the value before encoding
+
 	if elem, found := cache.items[key]; found {
-		cache.update(elem, value, expiration) // Update existing item
-		return setStatusUpdated
+		result = SetResult{Status: SetUpdated}
+		if values && !elem.Value.(*entry).hasExpired(cache.clock.Now()) {
+			result.Previous, result.HasPrevious = cache.load(elem.Value.(*entry)), true
+		}
+		cache.update(elem, stored, expiration, metadata) // Update existing item
+		if cache.quotas != nil {
+			cache.enforceQuota(key, value)
+		}
+		return result
 	} else {
-		cache.checkCapacity() // Check capacity before adding a new item
+		result.EvictedKey, result.EvictedValue, result.Evicted = cache.checkCapacity(values) // Check capacity before adding a new item
 		// Create a new entry and add it to the cache
-		newEntry := &entry{key: key, value: value, expiresAt: expiration}
-		newElem := cache.usageOrder.PushFront(newEntry)
+		newEntry := acquireEntry(key, stored, expiration)
+		newEntry.metadata = metadata
+		newEntry.generation = cache.generation.current
+		cache.ghosts.forget(key)
+		newElem := cache.insert(newEntry)
 		cache.items[key] = newElem
+		cache.trackExpiry(newEntry)
+		if cache.keys != nil {
+			cache.keys.insert(key)
+		}
 
-		cacheMisses.WithLabelValues(cache.name, metricOpSet).Inc()                               // Increment cache miss metric
-		totalItems.WithLabelValues(cache.name, metricOpSet).Set(float64(cache.usageOrder.Len())) // Update total items metric
-		return setStatusAdded
+		cache.metrics.added(cache.counters.added(expiration)) // Increment cache miss metric and update total items metric
+		cache.namespaces.added(key)
+		cache.emit(EventAdded, key, newEntry, "")
+		cache.watermarks.check(cache.usageOrder.Len(), cache.capacity)
+		if cache.quotas != nil {
+			cache.enforceQuota(key, value)
+		}
+		result.Status = SetAdded
+		return result
 	}
 }
 
-// Set adds or updates an item in the cache with no expiration.
-// The item will not expire unless explicitly removed.
+// Set adds or updates an item in the cache with no expiration, unless WithTTLPolicy gives it a ttl.
+// Without ttl, the item will not expire unless explicitly removed.
 // If the key already exists, both its value and expiration will be overridden.
-func (cache *LRUCache) Set(key string, value any) (status string) {
-	return cache.set(key, value, time.Time{}) // No expiration
+func (cache *LRUCache) Set(key string, value any) SetResult {
+	key = cache.transform.apply(key)
+	if ttl := cache.ttlPolicy.apply(key, value); ttl > 0 {
+		return cache.setWithTTL(key, value, ttl)
+	}
+	return cache.set(key, value, time.Time{}, nil, cache.previous) // No expiration
 }
 
 // SetWithTTL adds or updates an item in the cache with a specified expiration time.
 // It calls the internal set method with the expiration time.
-func (cache *LRUCache) SetWithTTL(key string, value any, ttl time.Duration) (status string) {
-	expiration := time.Now().Add(ttl)
+func (cache *LRUCache) SetWithTTL(key string, value any, ttl time.Duration) SetResult {
+	return cache.setWithTTL(cache.transform.apply(key), value, ttl)
+}
+
+// setWithTTL adds or updates an item expiring after ttl, the key being already transformed.
+func (cache *LRUCache) setWithTTL(key string, value any, ttl time.Duration) (result SetResult) {
+	now := cache.clock.Now()
+	expiration := now.Add(ttl)
 
-	if !hasExpired(expiration) {
-		status = cache.set(key, value, expiration)
+	if !hasExpired(expiration, now) {
+		result = cache.set(key, value, expiration, nil, cache.previous)
 	} else {
 		cache.remove(key, metricReasonExpired) // Remove the item if it has expired
-		status = setStatusExpired
+		result = SetResult{Status: SetExpired}
 	}
 
-	expirationHistogram.WithLabelValues(cache.name).Observe(ttl.Seconds()) // Record the expiration duration in the histogram
-	return status
+	cache.metrics.expiration(ttl) // Record the expiration duration in the histogram
+	return result
 }
 
 // Remove deletes an item from the cache by key.
@@ -143,19 +410,99 @@ func (cache *LRUCache) SetWithTTL(key string, value any, ttl time.Duration) (sta
 func (cache *LRUCache) remove(key string, reason string) {
 	if elem, found := cache.items[key]; found {
 		// Remove the item from the cache
+		cache.unlink(elem, reason)
 		cache.usageOrder.Remove(elem)
+		cache.untrackExpiry(elem.Value.(*entry))
 		delete(cache.items, key)
+		if cache.keys != nil {
+			cache.keys.delete(key)
+		}
+		cache.unindex(key)
+		if cache.quotas != nil {
+			cache.quotas.remove(key)
+		}
+		if reason == metricReasonEvicted {
+			cache.ghosts.add(key)
+		}
 
-		evictionCount.WithLabelValues(cache.name, metricOpRemove, reason).Inc()                     // Increment eviction metric
-		totalItems.WithLabelValues(cache.name, metricOpRemove).Set(float64(cache.usageOrder.Len())) // Update total items metric
+		length := cache.counters.removed(reason, elem.Value.(*entry).expiresAt)
+		cache.metrics.removed(reason, length) // Increment eviction metric and update total items metric
+		cache.namespaces.removed(key, reason)
+		cache.emit(EventRemoved, key, elem.Value.(*entry), reason)
+		cache.release(elem.Value.(*entry))
+		releaseEntry(elem.Value.(*entry)) // The listeners received a copy, the entry can be reused
+		elem.Value = nil
 	}
 }
 
+// trackExpiry adds the entry to the expiry index, or moves it after its expiration changed.
+func (cache *LRUCache) trackExpiry(ent *entry) {
+	if cache.buckets != nil {
+		cache.buckets.add(ent)
+	} else {
+		cache.expiries.track(ent)
+	}
+}
+
+// untrackExpiry removes the entry from the expiry index.
+func (cache *LRUCache) untrackExpiry(ent *entry) {
+	if cache.buckets != nil {
+		cache.buckets.remove(ent)
+	} else {
+		cache.expiries.untrack(ent)
+	}
+}
+
+// removeExpired removes every expired item, using the expiry index to avoid scanning the cache.
+// With expiry buckets, only the items of the buckets that ended are removed.
+// It returns the number of items removed.
+func (cache *LRUCache) removeExpired() int {
+	now := cache.clock.Now()
+	removed := 0
+	if cache.buckets != nil {
+		for _, ent := range cache.buckets.expired(now) {
+			cache.remove(ent.key, metricReasonExpired)
+			removed++
+		}
+		return removed
+	}
+	for ent := cache.expiries.nextExpired(now); ent != nil; ent = cache.expiries.nextExpired(now) {
+		cache.remove(ent.key, metricReasonExpired)
+		removed++
+	}
+	return removed
+}
+
+// RemoveExpired removes the expired items, which are otherwise only removed when they are read
+// or when room is needed, and returns how many were removed. With WithExpiryBuckets,
+// the items expired in a bucket that has not ended yet are left for a later call.
+func (cache *LRUCache) RemoveExpired() int {
+	return cache.removeExpired()
+}
+
 // Remove deletes an item from the cache by key.
 func (cache *LRUCache) Remove(key string) {
+	key = cache.transform.apply(key)
 	cache.remove(key, metricReasonManual) // Default reason is "manual"
 }
 
+// Resize changes the capacity of the cache. When the cache holds more items than the new capacity,
+// the expired items are removed first, then the least recently used ones.
+// With WithGhostReadmission, the protected segment is resized too.
+func (cache *LRUCache) Resize(capacity int) {
+	cache.capacity = capacity
+	cache.counters.capacity.Store(int64(capacity))
+	if isOver(cache.usageOrder.Len(), capacity) {
+		cache.removeExpired()
+	}
+	for isOver(cache.usageOrder.Len(), capacity) {
+		cache.remove(cache.victim().Value.(*entry).key, metricReasonEvicted)
+	}
+	if cache.segments != nil {
+		cache.demote() // The protected segment shrinks with the capacity
+	}
+}
+
 // Capacity returns the maximum number of items that can be stored in the cache.
 func (cache *LRUCache) Capacity() int {
 	return cache.capacity
@@ -165,3 +512,71 @@ func (cache *LRUCache) Capacity() int {
 func (cache *LRUCache) Len() int {
 	return cache.usageOrder.Len()
 }
+
+// emit sends an event about the given entry to the listeners, if any.
+func (cache *LRUCache) emit(eventType EventType, key string, ent *entry, reason string) {
+	if len(cache.listeners) > 0 {
+		event := newEvent(eventType, key, ent, reason, cache.clock.Now())
+		if ent != nil {
+			event.Value = cache.load(ent)
+		}
+		cache.listeners.notify(event)
+	}
+}
+
+// store prepares the value of a key to be held by an entry, writing it to the slab storage if the cache uses one.
+func (cache *LRUCache) store(key string, value any) (any, error) {
+	if cache.slabs == nil {
+		return value, nil
+	}
+	start := time.Now()
+	ref, length, err := cache.slabs.put(value)
+	cache.slowLog.Observe(SlowEncode, key, start, length, err)
+	if err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+// load returns the value held by the entry, decoding it from the slab storage if the cache uses one.
+func (cache *LRUCache) load(ent *entry) any {
+	if cache.slabs == nil {
+		return ent.value
+	}
+	return cache.slabs.get(ent.value)
+}
+
+// release frees the slab chunks holding the value of the entry, if the cache uses the slab storage.
+func (cache *LRUCache) release(ent *entry) {
+	if cache.slabs != nil {
+		cache.slabs.release(ent.value)
+	}
+}
+
+// Increment atomically adds delta to the int64 counter stored under key and returns the new value.
+// If the key is missing or expired, the counter is created with delta as its value, expiring after ttl.
+// A ttl of zero or less creates a counter with the ttl given by WithTTLPolicy, if any, or that does not expire.
+// The expiration of an existing counter is left untouched, making it suitable for fixed windows.
+// It returns ErrNotInteger if the key holds a value that is not an int64,
+// ErrUnsupportedValue if the codec of the slab storage cannot encode the counter,
+// ErrThrottled if creating the counter needs an eviction beyond the eviction rate limit,
+// ErrDenied if the admission hook refuses the counter, which is then removed,
+// and ErrDisabled if the capacity of the cache is zero.
+func (cache *LRUCache) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	key = cache.transform.apply(key)
+	now := cache.clock.Now()
+	if ttl <= 0 {
+		ttl = cache.ttlPolicy.apply(key, delta)
+	}
+	value, expiration := delta, counterExpiration(now, ttl)
+	var metadata any
+	if elem, found := cache.items[key]; found && !elem.Value.(*entry).hasExpired(now) {
+		current, ok := cache.load(elem.Value.(*entry)).(int64)
+		if !ok {
+			return 0, ErrNotInteger
+		}
+		value, expiration, metadata = current+delta, elem.Value.(*entry).expiresAt, elem.Value.(*entry).metadata
+	}
+
+	return counterResult(cache.set(key, value, expiration, metadata, false), value)
+}
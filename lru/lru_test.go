@@ -1,6 +1,7 @@
 package lru
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -17,7 +18,7 @@ func TestConstructLRUCache(t *testing.T) {
 func TestSet(t *testing.T) {
 	cache := NewLRUCache(5)
 	status := cache.Set("key1", "value1")
-	assert.Equal(t, "added", status)
+	assert.Equal(t, SetAdded, status.Status)
 	assert.Equal(t, 1, cache.Len())
 }
 
@@ -25,7 +26,7 @@ func TestSetOverride(t *testing.T) {
 	cache := NewLRUCache(5)
 	cache.Set("key1", "value1")
 	status := cache.Set("key1", "value1_updated")
-	assert.Equal(t, "updated", status)
+	assert.Equal(t, SetUpdated, status.Status)
 	assert.Equal(t, 1, cache.Len())
 
 	// Check if the value was updated
@@ -38,7 +39,7 @@ func TestSetWithTTL(t *testing.T) {
 	cache := NewLRUCache(5)
 
 	status := cache.SetWithTTL("key2", "value1", 100*time.Millisecond) // With expiration
-	assert.Equal(t, "added", status)
+	assert.Equal(t, SetAdded, status.Status)
 	assert.Equal(t, 1, cache.Len())
 
 	// Check if the item with expiration is retrievable
@@ -51,11 +52,11 @@ func TestSetWithTTLIfExpired(t *testing.T) {
 	cache := NewLRUCache(5)
 
 	status := cache.Set("key1", "value1")
-	assert.Equal(t, "added", status)
+	assert.Equal(t, SetAdded, status.Status)
 	cache.SetWithTTL("key3", "value1", 100*time.Millisecond) // With expiration
 	assert.Equal(t, 2, cache.Len())
 	status = cache.SetWithTTL("key3", "value2", 0) // Update with current time, should make it expire
-	assert.Equal(t, "expired", status)
+	assert.Equal(t, SetExpired, status.Status)
 	assert.Equal(t, 1, cache.Len())
 }
 
@@ -208,3 +209,130 @@ func TestRemove(t *testing.T) {
 	assert.False(t, found)
 	assert.Nil(t, value)
 }
+
+func TestIncrement(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLRUCache(5, WithClock(clock))
+
+	value, err := cache.Increment("counter", 2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), value)
+
+	value, err = cache.Increment("counter", 3, time.Hour) // The ttl only applies when the counter is created
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), value)
+
+	clock.Advance(2 * time.Minute)
+	value, err = cache.Increment("counter", 1, time.Minute) // Expired, so the counter starts over
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), value)
+}
+
+func TestIncrementNotInteger(t *testing.T) {
+	cache := NewLRUCache(5)
+	cache.Set("key1", "value1")
+
+	_, err := cache.Increment("key1", 1, 0)
+	assert.ErrorIs(t, err, ErrNotInteger)
+}
+
+func TestSetReclaimsExpiredBeforeEvicting(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLRUCache(3, WithClock(clock))
+	cache.Set("live", "value")
+	cache.SetWithTTL("dead1", "value", time.Second)
+	cache.SetWithTTL("dead2", "value", time.Minute)
+
+	clock.Advance(2 * time.Second)
+	cache.Set("new", "value") // dead1 expired, so it should be reclaimed instead of evicting "live"
+
+	_, found := cache.Get("live")
+	assert.True(t, found)
+	_, found = cache.Get("dead2")
+	assert.True(t, found)
+	assert.Equal(t, 3, cache.Len())
+}
+
+func TestExpiryIndexFollowsUpdates(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLRUCache(2, WithClock(clock))
+	cache.SetWithTTL("key1", "value1", time.Second)
+	cache.Set("key1", "value1") // No longer expires
+	cache.SetWithTTL("key2", "value2", time.Hour)
+	assert.Len(t, cache.expiries, 1)
+
+	clock.Advance(2 * time.Second)
+	cache.Set("key3", "value3") // Nothing expired, so the least recently used item is evicted
+
+	_, found := cache.Get("key1")
+	assert.False(t, found)
+	cache.Remove("key2")
+	assert.Empty(t, cache.expiries)
+}
+
+func TestRemoveClearsRecycledEntry(t *testing.T) {
+	cache := NewLRUCache(1)
+	value := &struct{ payload [64]byte }{}
+	cache.Set("key1", value)
+	ent := cache.items["key1"].Value.(*entry)
+
+	cache.Remove("key1") // Recycles the entry of key1
+
+	assert.Nil(t, ent.value) // The pool must not keep the evicted value alive
+	assert.Empty(t, ent.key)
+}
+
+func TestSetAtCapacityReusesEntries(t *testing.T) {
+	cache := NewLRUCache(10, WithoutMetrics())
+	keys := make([]string, 200)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	for _, key := range keys[:10] {
+		cache.Set(key, nil)
+	}
+
+	i := 10
+	allocs := testing.AllocsPerRun(100, func() {
+		cache.Set(keys[i], nil) // Evicts the least recently used key
+		i++
+	})
+	assert.LessOrEqual(t, allocs, 1.0) // Only the list element is allocated
+}
+
+func TestObservableLRUStateRanksEvictions(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	observable := NewObservableCacheFrom(NewSafeLRUCacheFrom(NewLRUCache(4, WithClock(clock))))
+	observable.Cache.Set("key1", "value1")
+	observable.Cache.SetWithTTL("key2", "value2", time.Second)
+	observable.Cache.Set("key3", "value3")
+	clock.Advance(time.Second)
+
+	state := observable.State()
+	assert.Equal(t, "lru", state.Policy)
+	assert.Len(t, state.Items, 3)
+	assert.Equal(t, "key3", state.Items[0].Key)
+	assert.Equal(t, 1, state.Items[0].DistanceFromEviction)
+	assert.True(t, state.Items[1].Expired, "Expected key2 to be flagged as expired")
+	assert.Equal(t, 0, state.Items[1].DistanceFromEviction)
+	assert.Equal(t, 0, state.Items[2].DistanceFromEviction, "Expected the least recently used item to be evicted next")
+	assert.Empty(t, state.Items[2].Segment)
+}
+
+func TestObservableStatePage(t *testing.T) {
+	observable := NewObservableCache(10)
+	for _, key := range []string{"user:1", "order:1", "user:2", "user:3", "order:2"} {
+		observable.Cache.Set(key, "value")
+	}
+
+	state := observable.StatePage(StateQuery{Prefix: "user:", Offset: 1, Limit: 1, OmitValues: true})
+	assert.Equal(t, 3, state.Total)
+	assert.Len(t, state.Items, 1)
+	assert.Equal(t, "user:2", state.Items[0].Key)
+	assert.Empty(t, state.Items[0].Value)
+	assert.Equal(t, "user:3", state.Items[0].Prev, "Expected the neighbors of the full state")
+	assert.Equal(t, 2, state.Items[0].DistanceFromEviction)
+
+	assert.Empty(t, observable.StatePage(StateQuery{Offset: 10}).Items)
+	assert.Equal(t, 5, observable.State().Total)
+}
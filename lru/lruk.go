@@ -0,0 +1,414 @@
+package lru
+
+import (
+	"cmp"
+	"container/heap"
+	"container/list"
+	"slices"
+	"time"
+)
+
+// defaultLRUKDepth is the K of the LRUKCache unless WithLRUKDepth changes it, i.e. LRU-2.
+const defaultLRUKDepth = 2
+
+// WithLRUKDepth sets the K of an LRUKCache: the number of references remembered per item,
+// the items being evicted by the time of their K-th most recent reference. Defaults to 2.
+// A K of 1 is the LRU policy, a larger K resists scans better but takes longer to keep a new hot item.
+// Ignored by the other caches.
+func WithLRUKDepth(k int) Option {
+	return func(o *options) {
+		o.lrukDepth = k
+	}
+}
+
+type lrukEntry struct {
+	entry
+	references   []uint64      // Times of the last K references, the oldest first
+	element      *list.Element // Element of the entry in the list of the items with fewer than K references, nil otherwise
+	heapPosition int           // Position of the entry in the heap of the items with K references, notIndexed otherwise
+}
+
+// kthReference returns the time of the K-th most recent reference of an entry with K references.
+func (ent *lrukEntry) kthReference() uint64 {
+	return ent.references[0]
+}
+
+// lrukHeap is a min-heap of the entries with K references, the one with the oldest K-th most recent reference first.
+type lrukHeap []*lrukEntry
+
+func (h lrukHeap) Len() int {
+	return len(h)
+}
+
+func (h lrukHeap) Less(i, j int) bool {
+	return h[i].kthReference() < h[j].kthReference()
+}
+
+func (h lrukHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapPosition = i
+	h[j].heapPosition = j
+}
+
+// Push is used by container/heap.
+func (h *lrukHeap) Push(x any) {
+	ent := x.(*lrukEntry)
+	ent.heapPosition = len(*h)
+	*h = append(*h, ent)
+}
+
+// Pop is used by container/heap.
+func (h *lrukHeap) Pop() any {
+	old := *h
+	ent := old[len(old)-1]
+	old[len(old)-1] = nil // Avoid holding a reference to the removed entry
+	ent.heapPosition = notIndexed
+	*h = old[:len(old)-1]
+	return ent
+}
+
+// LRUKCache evicts the item whose K-th most recent reference is the oldest (LRU-K), the items referenced
+// fewer than K times first, from the least recently used. An item read once, e.g. by a sequential scan,
+// is so evicted before the items read repeatedly, which a plain LRU cache would evict to make room for the scan.
+// The references are counted on a logical clock, every Get and Set of the cache being one tick.
+type LRUKCache struct {
+	capacity  int                   // The capacity of this cache, when full, the item with the oldest K-th reference is removed
+	k         int                   // Number of references remembered per item
+	items     map[string]*lrukEntry // Provides easy access to the cached entries
+	young     *list.List            // Holds the entries with fewer than K references, the most recently used first
+	mature    lrukHeap              // Holds the entries with K references, the oldest K-th reference first
+	ticks     uint64                // Logical clock of the references
+	expiries  expiryIndex           // Holds the entries with an expiration, the soonest to expire first
+	metrics   *cacheMetrics         // Metrics of the cache, nil when disabled
+	clock     Clock                 // Source of the current time, used for expiration
+	ghosts    *ghostList            // Keys evicted recently, nil without WithGhostList
+	curve     *curveEstimator       // Estimates the hit ratio curve, nil without WithHitRatioCurve
+	counters  statsCounters         // Counters reported by Stats
+	previous  bool                  // Whether the writes return the value they replace, see WithPreviousValues
+	evictions *evictionLimiter      // Caps the evictions per second, nil without WithEvictionRateLimit
+	transform KeyTransform          // Rewrites the keys before every operation, nil without WithKeyTransform
+	ttlPolicy TTLPolicy             // Decides the ttl of the items written by Set, nil without WithTTLPolicy
+	admission *admission            // Decides whether the items are written, nil without WithAdmission
+	listeners                       // Receive the events emitted by the cache
+}
+
+var _ Cache = (*LRUKCache)(nil) // Ensure LRUKCache implements the Cache interface
+
+// NewLRUKCache creates an LRU-K cache, LRU-2 unless WithLRUKDepth sets another K.
+func NewLRUKCache(capacity int, opts ...Option) *LRUKCache {
+	o := newOptions(opts...)
+	cache := &LRUKCache{
+		capacity:  capacity,
+		k:         max(cmp.Or(o.lrukDepth, defaultLRUKDepth), 1),
+		items:     make(map[string]*lrukEntry),
+		young:     list.New(),
+		clock:     o.clock,
+		previous:  o.previousValues,
+		listeners: o.listeners,
+		transform: o.keyTransform,
+		ttlPolicy: o.ttlPolicy,
+		admission: newAdmission(o),
+	}
+	cache.counters.capacity.Store(int64(capacity))
+	cache.counters.clock = o.clock
+	if o.metrics {
+		cache.metrics = newCacheMetrics(cmp.Or(o.name, metricCacheTypeLRUK), o.legacyMetrics, o.metricsBatch)
+	}
+	if o.ghostWindow > 0 {
+		cache.ghosts = newGhostList(o.ghostWindow)
+	}
+	if o.curveRate > 0 {
+		cache.curve = newCurveEstimator(o.curveRate, cmp.Or(o.curveMaxCapacity, 2*capacity))
+	}
+	if o.evictionRate > 0 {
+		cache.evictions = newEvictionLimiter(o.evictionRate, o.clock.Now())
+	}
+	return cache
+}
+
+// Get retrieves an item from the cache by its key, counting a reference.
+// It returns the value and a boolean indicating whether the item was found.
+// If the ttl has expired, the item will be removed and not found.
+func (cache *LRUKCache) Get(key string) (value any, found bool) {
+	key = cache.transform.apply(key)
+	cache.ghosts.access()
+	cache.curve.access(key)
+	if ent, found := cache.items[key]; found {
+		if ent.hasExpired(cache.clock.Now()) {
+			cache.remove(key, metricReasonExpired) // Remove the item if it has expired
+			cache.counters.misses.Add(1)
+			cache.emit(EventMiss, key, nil, "")
+			return nil, false // Item expired and removed
+		}
+
+		cache.reference(ent)
+
+		cache.metrics.getHit() // Increment cache hit metric
+		cache.counters.hits.Add(1)
+		cache.emit(EventHit, key, &ent.entry, "")
+		return ent.value, true
+	}
+	cache.metrics.getMiss() // Increment cache miss metric
+	cache.counters.misses.Add(1)
+	if cache.ghosts.miss(key) {
+		cache.metrics.ghostHit()
+	}
+	cache.emit(EventMiss, key, nil, "")
+	return nil, false // Item not found
+}
+
+// GhostHits returns the number of misses for keys evicted recently, which a larger cache would have served.
+// It is always zero without WithGhostList.
+func (cache *LRUKCache) GhostHits() uint64 {
+	return cache.ghosts.hitCount()
+}
+
+// reference records a reference to the entry at the next tick of the logical clock,
+// moving it to the heap once it has K references.
+func (cache *LRUKCache) reference(ent *lrukEntry) {
+	cache.ticks++
+	if len(ent.references) < cache.k {
+		ent.references = append(ent.references, cache.ticks)
+		if len(ent.references) < cache.k {
+			cache.young.MoveToFront(ent.element)
+			return
+		}
+		cache.young.Remove(ent.element)
+		ent.element = nil
+		heap.Push(&cache.mature, ent)
+		return
+	}
+	copy(ent.references, ent.references[1:])
+	ent.references[cache.k-1] = cache.ticks
+	heap.Fix(&cache.mature, ent.heapPosition)
+}
+
+// victim returns the entry to evict to make room, nil if the cache is empty: the least recently used entry
+// with fewer than K references, or else the one with the oldest K-th most recent reference.
+func (cache *LRUKCache) victim() *lrukEntry {
+	if back := cache.young.Back(); back != nil {
+		return back.Value.(*lrukEntry)
+	}
+	if len(cache.mature) > 0 {
+		return cache.mature[0]
+	}
+	return nil
+}
+
+// checkCapacity checks if the cache has reached its capacity.
+// If it has, it first reclaims the expired items, and only if none expired it evicts the victim.
+// It returns the key and the value of the item evicted to make room, if any.
+func (cache *LRUKCache) checkCapacity() (evicted string, value any, found bool) {
+	if isFull(len(cache.items), cache.capacity) {
+		cache.removeExpired()
+	}
+	if isFull(len(cache.items), cache.capacity) {
+		if victim := cache.victim(); victim != nil {
+			evicted, value = victim.key, victim.value
+			cache.remove(evicted, metricReasonEvicted)
+			return evicted, value, true
+		}
+	}
+	return "", nil, false
+}
+
+// set adds or updates an item in the cache. Updating an existing item counts as a reference.
+// With values, the result holds the value replaced or evicted.
+func (cache *LRUKCache) set(key string, value any, expiration time.Time, values bool) (result SetResult) {
+	if cache.capacity == 0 { // Disabled
+		return SetResult{Status: SetDisabled}
+	}
+	cache.ghosts.access()
+	cache.curve.access(key)
+	if !cache.admission.admits(key, value) {
+		cache.remove(key, metricReasonDenied)
+		return SetResult{Status: SetDenied}
+	}
+	if ent, found := cache.items[key]; found {
+		result = SetResult{Status: SetUpdated}
+		if values && !ent.hasExpired(cache.clock.Now()) {
+			result.Previous, result.HasPrevious = ent.value, true
+		}
+		ent.value = value
+		cache.counters.expirationChanged(ent.expiresAt, expiration)
+		ent.expiresAt = expiration
+		cache.expiries.track(&ent.entry)
+		cache.reference(ent)
+
+		cache.metrics.updated() // Increment cache hit metric
+		cache.emit(EventUpdated, key, &ent.entry, "")
+		return result
+	}
+
+	if cache.throttled(key) {
+		return SetResult{Status: SetThrottled}
+	}
+	var evictedValue any
+	result.EvictedKey, evictedValue, result.Evicted = cache.checkCapacity() // Check capacity before adding a new item
+	if values {
+		result.EvictedValue = evictedValue
+	}
+	newEntry := &lrukEntry{entry: makeEntry(key, value, expiration), references: make([]uint64, 0, cache.k), heapPosition: notIndexed}
+	cache.items[key] = newEntry
+	newEntry.element = cache.young.PushFront(newEntry)
+	cache.reference(newEntry) // The insertion is the first reference, which is enough with K = 1
+	cache.expiries.track(&newEntry.entry)
+	cache.ghosts.forget(key)
+
+	cache.metrics.added(cache.counters.added(expiration)) // Increment cache miss metric and update total items metric
+	cache.emit(EventAdded, key, &newEntry.entry, "")
+	result.Status = SetAdded
+	return result
+}
+
+// Set adds or updates an item in the cache with no expiration, unless WithTTLPolicy gives it a ttl.
+// If the key already exists, both its value and expiration will be overridden.
+func (cache *LRUKCache) Set(key string, value any) SetResult {
+	key = cache.transform.apply(key)
+	if ttl := cache.ttlPolicy.apply(key, value); ttl > 0 {
+		return cache.setWithTTL(key, value, ttl)
+	}
+	return cache.set(key, value, time.Time{}, cache.previous) // No expiration
+}
+
+// SetWithTTL adds or updates an item in the cache with a specified expiration time.
+func (cache *LRUKCache) SetWithTTL(key string, value any, ttl time.Duration) SetResult {
+	return cache.setWithTTL(cache.transform.apply(key), value, ttl)
+}
+
+// setWithTTL adds or updates an item expiring after ttl, the key being already transformed.
+func (cache *LRUKCache) setWithTTL(key string, value any, ttl time.Duration) (result SetResult) {
+	now := cache.clock.Now()
+	expiration := now.Add(ttl)
+
+	if !hasExpired(expiration, now) {
+		result = cache.set(key, value, expiration, cache.previous)
+	} else {
+		cache.remove(key, metricReasonExpired) // Remove the item if it has expired
+		result = SetResult{Status: SetExpired}
+	}
+
+	cache.metrics.expiration(ttl) // Record the expiration duration in the histogram
+	return result
+}
+
+// throttled returns true if adding the key needs an eviction the rate limit does not allow.
+// The expired items are reclaimed first, as their removal is not limited.
+func (cache *LRUKCache) throttled(key string) bool {
+	if cache.evictions == nil || !isFull(len(cache.items), cache.capacity) {
+		return false
+	}
+	if _, found := cache.items[key]; found {
+		return false
+	}
+	cache.removeExpired()
+	if !isFull(len(cache.items), cache.capacity) || cache.evictions.allow(cache.clock.Now()) {
+		return false
+	}
+	cache.metrics.throttledSet()
+	return true
+}
+
+// remove deletes an item from the cache by key.
+// If the item does not exist, it does nothing.
+// The reason parameter is used to specify why the item is being removed (e.g., "manual", "expired", "evicted").
+func (cache *LRUKCache) remove(key string, reason string) {
+	if ent, found := cache.items[key]; found {
+		if ent.element != nil {
+			cache.young.Remove(ent.element)
+		} else {
+			heap.Remove(&cache.mature, ent.heapPosition)
+		}
+		cache.expiries.untrack(&ent.entry)
+		delete(cache.items, key)
+		if reason == metricReasonEvicted {
+			cache.ghosts.add(key)
+		}
+
+		length := cache.counters.removed(reason, ent.expiresAt)
+		cache.metrics.removed(reason, length) // Increment eviction metric and update total items metric
+		cache.emit(EventRemoved, key, &ent.entry, reason)
+	}
+}
+
+// removeExpired removes every expired item, using the expiry index to avoid scanning the cache.
+func (cache *LRUKCache) removeExpired() {
+	now := cache.clock.Now()
+	for ent := cache.expiries.nextExpired(now); ent != nil; ent = cache.expiries.nextExpired(now) {
+		cache.remove(ent.key, metricReasonExpired)
+	}
+}
+
+// Remove deletes an item from the cache by key.
+func (cache *LRUKCache) Remove(key string) {
+	key = cache.transform.apply(key)
+	cache.remove(key, metricReasonManual) // Default reason is "manual"
+}
+
+// Capacity returns the maximum number of items that can be stored in the cache.
+func (cache *LRUKCache) Capacity() int {
+	return cache.capacity
+}
+
+// Len returns the number of items currently in the cache.
+func (cache *LRUKCache) Len() int {
+	return len(cache.items)
+}
+
+// metricsName returns the value of the cache label of the metrics, empty when they are disabled.
+func (cache *LRUKCache) metricsName() string {
+	return cache.metrics.cacheName()
+}
+
+// emit sends an event about the given entry to the listeners, if any.
+func (cache *LRUKCache) emit(eventType EventType, key string, ent *entry, reason string) {
+	if len(cache.listeners) > 0 {
+		cache.listeners.notify(newEvent(eventType, key, ent, reason, cache.clock.Now()))
+	}
+}
+
+// byValue returns the entries from the most to the least valuable: the ones with K references from the most recent
+// K-th reference, then the other ones from the most recently used. The last one is the next eviction candidate.
+func (cache *LRUKCache) byValue() []*lrukEntry {
+	entries := slices.Clone(cache.mature)
+	slices.SortFunc(entries, func(a, b *lrukEntry) int {
+		return cmp.Compare(b.kthReference(), a.kthReference())
+	})
+	for e := cache.young.Front(); e != nil; e = e.Next() {
+		entries = append(entries, e.Value.(*lrukEntry))
+	}
+	return entries
+}
+
+// Peek returns the value of the key without counting a reference nor reclaiming it if expired.
+func (cache *LRUKCache) Peek(key string) (value any, found bool) {
+	key = cache.transform.apply(key)
+	if ent, found := cache.items[key]; found {
+		return ent.value, true
+	}
+	return nil, false
+}
+
+// Range calls fn with every live item, from the most to the least valuable one, until fn returns false.
+// fn must not modify the cache.
+func (cache *LRUKCache) Range(fn func(key string, value any) bool) {
+	now := cache.clock.Now()
+	for _, ent := range cache.byValue() {
+		if !ent.hasExpired(now) && !fn(ent.key, ent.value) {
+			return
+		}
+	}
+}
+
+// Resize changes the capacity of the cache. When the cache holds more items than the new capacity,
+// the expired items are removed first, then the victims of the policy.
+func (cache *LRUKCache) Resize(capacity int) {
+	cache.capacity = capacity
+	cache.counters.capacity.Store(int64(capacity))
+	if isOver(len(cache.items), capacity) {
+		cache.removeExpired()
+	}
+	for isOver(len(cache.items), capacity) {
+		cache.remove(cache.victim().key, metricReasonEvicted)
+	}
+}
@@ -0,0 +1,80 @@
+package lru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUKResistsScans(t *testing.T) {
+	cache := NewLRUKCache(4, WithoutMetrics())
+	cache.Set("hot1", 1)
+	cache.Set("hot2", 2)
+	cache.Get("hot1")
+	cache.Get("hot2")
+
+	for i := range 10 { // A scan reads every key once
+		cache.Set(fmt.Sprint("scan", i), i)
+	}
+	for _, key := range []string{"hot1", "hot2"} {
+		_, found := cache.Get(key)
+		assert.True(t, found, "Expected the items referenced twice to survive the scan")
+	}
+
+	lru := NewLRUCache(4, WithoutMetrics())
+	lru.Set("hot1", 1)
+	lru.Get("hot1")
+	for i := range 10 {
+		lru.Set(fmt.Sprint("scan", i), i)
+	}
+	_, found := lru.Get("hot1")
+	assert.False(t, found, "Expected a plain LRU cache to lose its hot item to the scan")
+}
+
+func TestLRUKEvictsTheOldestKthReference(t *testing.T) {
+	cache := NewLRUKCache(2, WithoutMetrics())
+	cache.Set("a", 1) // Tick 1
+	cache.Set("b", 2) // Tick 2
+	cache.Get("a")    // Tick 3, the second reference of a is at tick 1
+	cache.Get("b")    // Tick 4, the second reference of b is at tick 2
+	cache.Get("a")    // Tick 5, moves the second reference of a to tick 3
+
+	result := cache.Set("c", 3)
+	assert.Equal(t, "b", result.EvictedKey)
+	assert.Equal(t, []string{"a", "c"}, rangeKeys(cache))
+
+	result = cache.Set("d", 4)
+	assert.Equal(t, "c", result.EvictedKey, "Expected the items with fewer than K references to be evicted first")
+}
+
+func TestLRUKDepth(t *testing.T) {
+	cache := NewLRUKCache(2, WithLRUKDepth(1), WithoutMetrics()) // LRU-1 is LRU
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Get("a")
+	assert.Equal(t, "b", cache.Set("c", 3).EvictedKey)
+
+	cache = NewLRUKCache(2, WithLRUKDepth(3), WithoutMetrics())
+	cache.Set("a", 1)
+	cache.Get("a")
+	cache.Set("b", 2)
+	assert.Equal(t, "a", cache.Set("c", 3).EvictedKey, "Expected a to need three references to be kept over b")
+}
+
+func TestLRUKExpirationAndStats(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewSafe(NewLRUKCache(2, WithClock(clock), WithoutMetrics()))
+	cache.SetWithTTL("a", 1, time.Second)
+	cache.Set("b", 2)
+	cache.Get("b")
+	clock.Advance(2 * time.Second)
+
+	_, found := cache.Get("a")
+	assert.False(t, found)
+	cache.Remove("b")
+	cache.Resize(1)
+	assert.Equal(t, Stats{Capacity: 1, Hits: 1, Misses: 1, Expirations: 1, ExpirationRate: 1.0 / expirationRateWindow}, cache.Stats())
+	assert.Equal(t, metricCacheTypeLRUK, NewLRUKCache(1).metricsName())
+}
@@ -0,0 +1,102 @@
+package lru
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/gob"
+	"encoding/json"
+	"time"
+)
+
+// cacheDump is the serialized form of an LRUCache, shared by the JSON and gob encodings.
+type cacheDump struct {
+	Capacity int          `json:"capacity"`
+	Items    []dumpedItem `json:"items"` // From the most to the least recently used item
+}
+
+// dumpedItem is the serialized form of an item, with its wall-clock expiration so the remaining ttl is kept.
+type dumpedItem struct {
+	Key       string    `json:"key"`
+	Value     any       `json:"value"`
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+	Metadata  any       `json:"metadata,omitempty"`
+}
+
+var _ json.Marshaler = (*LRUCache)(nil)   // Ensure LRUCache can be marshaled to JSON
+var _ json.Unmarshaler = (*LRUCache)(nil) // Ensure LRUCache can be unmarshaled from JSON
+var _ gob.GobEncoder = (*LRUCache)(nil)   // Ensure LRUCache can be gob encoded
+var _ gob.GobDecoder = (*LRUCache)(nil)   // Ensure LRUCache can be gob decoded
+
+// dump returns the capacity and the live items of the cache, in usage order.
+func (cache *LRUCache) dump() cacheDump {
+	snapshot := cache.Snapshot()
+	items := make([]dumpedItem, 0, snapshot.Len())
+	snapshot.Range(func(entry EntryInfo) bool {
+		items = append(items, dumpedItem{Key: entry.Key, Value: entry.Value, ExpiresAt: entry.ExpiresAt, Metadata: entry.Metadata})
+		return true
+	})
+	return cacheDump{Capacity: cache.capacity, Items: items}
+}
+
+// restore replaces the items of the cache with the dumped ones, keeping their usage order and expiration.
+// The items that expired since the dump are skipped. A zero LRUCache is initialized without options.
+func (cache *LRUCache) restore(dump cacheDump) {
+	if cache.items == nil {
+		cache.items = make(map[string]*list.Element)
+		cache.usageOrder = list.New()
+		cache.clock = systemClock{}
+		cache.generation = newGenerationTracker()
+	}
+	for cache.usageOrder.Len() > 0 {
+		cache.remove(cache.usageOrder.Back().Value.(*entry).key, metricReasonManual)
+	}
+	cache.Resize(dump.Capacity)
+
+	now := cache.clock.Now()
+	for i := len(dump.Items) - 1; i >= 0; i-- { // Least recently used first, so the last one ends up in front
+		item := dump.Items[i]
+		expiresAt := monotonicDeadline(item.ExpiresAt, now)
+		if hasExpired(expiresAt, now) {
+			continue
+		}
+		cache.set(item.Key, item.Value, expiresAt, item.Metadata, false)
+	}
+}
+
+// MarshalJSON encodes the capacity and the live items of the cache, from the most to the least recently used,
+// with their expiration time and metadata.
+func (cache *LRUCache) MarshalJSON() ([]byte, error) {
+	return json.Marshal(cache.dump())
+}
+
+// UnmarshalJSON replaces the items of the cache with the encoded ones, restoring their order and expiration.
+// The values are decoded like encoding/json does into an any, e.g. numbers become float64.
+// The options of the cache are kept, its capacity is the encoded one.
+func (cache *LRUCache) UnmarshalJSON(data []byte) error {
+	var dump cacheDump
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return err
+	}
+	cache.restore(dump)
+	return nil
+}
+
+// GobEncode encodes the cache like MarshalJSON, keeping the types of the values.
+// The types of the values other than the basic ones must be registered with gob.Register.
+func (cache *LRUCache) GobEncode() ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(cache.dump()); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// GobDecode replaces the items of the cache with the encoded ones, like UnmarshalJSON.
+func (cache *LRUCache) GobDecode(data []byte) error {
+	var dump cacheDump
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&dump); err != nil {
+		return err
+	}
+	cache.restore(dump)
+	return nil
+}
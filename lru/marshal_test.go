@@ -0,0 +1,60 @@
+package lru
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCacheJSON(t *testing.T) {
+	clock := NewManualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := NewLRUCache(3, WithClock(clock))
+	cache.Set("key1", "value1")
+	cache.SetWithTTL("key2", 2, time.Minute)
+	cache.SetWithMetadata("key3", "value3", 0, "origin:db")
+	cache.Get("key1")
+
+	data, err := json.Marshal(cache)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"capacity": 3, "items": [
+		{"key": "key1", "value": "value1"},
+		{"key": "key3", "value": "value3", "metadata": "origin:db"},
+		{"key": "key2", "value": 2, "expiresAt": "2025-01-01T00:01:00Z"}
+	]}`, string(data))
+
+	restored := NewLRUCache(1, WithClock(clock))
+	restored.Set("stale", "value")
+	assert.NoError(t, json.Unmarshal(data, restored))
+	assert.Equal(t, 3, restored.Capacity())
+	assert.Equal(t, []string{"key1", "key3", "key2"}, snapshotKeys(restored.Snapshot()))
+	info, _ := restored.EntryInfo("key2")
+	assert.Equal(t, EntryInfo{Key: "key2", Value: 2.0, ExpiresAt: clock.Now().Add(time.Minute)}, info)
+
+	clock.Advance(time.Hour)
+	assert.NoError(t, json.Unmarshal(data, restored))
+	assert.Equal(t, 2, restored.Len(), "Expected the items expired since the dump to be skipped")
+
+	assert.Error(t, json.Unmarshal([]byte(`{"items": 1}`), restored))
+}
+
+func TestLRUCacheGob(t *testing.T) {
+	cache := NewLRUCache(2)
+	cache.Set("key1", int64(1))
+	cache.SetWithTTL("key2", []byte("value2"), time.Hour)
+
+	var buffer bytes.Buffer
+	assert.NoError(t, gob.NewEncoder(&buffer).Encode(cache))
+
+	var restored LRUCache // A zero cache is initialized by the decoding
+	assert.NoError(t, gob.NewDecoder(&buffer).Decode(&restored))
+	assert.Equal(t, []string{"key2", "key1"}, snapshotKeys(restored.Snapshot()))
+	value, _ := restored.Get("key1")
+	assert.Equal(t, int64(1), value, "Expected gob to keep the types of the values")
+	info, _ := restored.EntryInfo("key2")
+	assert.Equal(t, []byte("value2"), info.Value)
+	assert.False(t, info.ExpiresAt.IsZero())
+}
@@ -0,0 +1,101 @@
+package lru
+
+import (
+	"time"
+)
+
+// EntryInfo describes an item of a cache, without promoting it.
+type EntryInfo struct {
+	Key       string
+	Value     any
+	ExpiresAt time.Time // Wall-clock expiration, zero if the item does not expire
+	Metadata  any       // The metadata attached by SetWithMetadata, nil if none
+}
+
+// MetadataCache is implemented by the caches able to attach metadata to their items,
+// such as their origin, version or cost, without the callers wrapping every value in their own struct.
+type MetadataCache interface {
+	// SetWithMetadata adds or updates an item with the given metadata, expiring after ttl, or never if ttl is zero or less.
+	SetWithMetadata(key string, value any, ttl time.Duration, metadata any) SetResult
+	// EntryInfo returns the live item of the key with its metadata, without promoting it.
+	EntryInfo(key string) (EntryInfo, bool)
+}
+
+var _ MetadataCache = (*LRUCache)(nil)     // Ensure LRUCache supports metadata
+var _ MetadataCache = (*SafeLRUCache)(nil) // Ensure SafeLRUCache supports metadata
+var _ MetadataCache = (*ShardedCache)(nil) // Ensure ShardedCache supports metadata
+
+// SetWithMetadata adds or updates an item with the given metadata, expiring after ttl, or never if ttl is zero or less.
+// Without ttl, the one of WithTTLPolicy is used, if any.
+// The metadata is returned by EntryInfo and carried by the events of the item, e.g. the evictions.
+// Set and SetWithTTL clear it.
+func (cache *LRUCache) SetWithMetadata(key string, value any, ttl time.Duration, metadata any) SetResult {
+	key = cache.transform.apply(key)
+	if ttl <= 0 {
+		ttl = cache.ttlPolicy.apply(key, value)
+	}
+	var expiration time.Time
+	if ttl > 0 {
+		expiration = cache.clock.Now().Add(ttl)
+		cache.metrics.expiration(ttl)
+	}
+	return cache.set(key, value, expiration, metadata, cache.previous)
+}
+
+// EntryInfo returns the live item of the key with its expiration and metadata, without promoting it.
+func (cache *LRUCache) EntryInfo(key string) (EntryInfo, bool) {
+	key = cache.transform.apply(key)
+	now := cache.clock.Now()
+	elem, found := cache.items[key]
+	if !found || elem.Value.(*entry).hasExpired(now) {
+		return EntryInfo{}, false
+	}
+	ent := elem.Value.(*entry)
+	return EntryInfo{Key: key, Value: cache.load(ent), ExpiresAt: wallDeadline(ent.expiresAt, now), Metadata: ent.metadata}, true
+}
+
+// SetWithMetadata adds or updates an item with the given metadata, expiring after ttl, or never if ttl is zero or less.
+// With a write buffer, the write is queued and SetBuffered is returned.
+// It returns SetRejected if the underlying cache does not support metadata.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) SetWithMetadata(key string, value any, ttl time.Duration, metadata any) SetResult {
+	key = safeCache.transform.apply(key)
+	if safeCache.writes != nil && safeCache.writes.enqueue(writeOp{kind: writeOpSetWithMetadata, key: key, value: value, ttl: ttl, metadata: metadata}) {
+		return SetResult{Status: SetBuffered}
+	}
+
+	safeCache.lock()
+	defer safeCache.mutex.Unlock()
+
+	if metadataCache, ok := safeCache.cache.(MetadataCache); ok {
+		return metadataCache.SetWithMetadata(key, value, ttl, metadata)
+	}
+	return SetResult{Status: SetRejected}
+}
+
+// EntryInfo returns the live item of the key with its expiration and metadata, without promoting it.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) EntryInfo(key string) (EntryInfo, bool) {
+	key = safeCache.transform.apply(key)
+	safeCache.readLock()
+	defer safeCache.mutex.RUnlock()
+
+	if metadataCache, ok := safeCache.cache.(MetadataCache); ok {
+		return metadataCache.EntryInfo(key)
+	}
+	return EntryInfo{}, false
+}
+
+// SetWithMetadata adds or updates an item with the given metadata in the shard holding the key.
+// It is thread-safe.
+func (sharded *ShardedCache) SetWithMetadata(key string, value any, ttl time.Duration, metadata any) SetResult {
+	key = sharded.transform.apply(key)
+	return sharded.shard(key).SetWithMetadata(key, value, ttl, metadata)
+}
+
+// EntryInfo returns the live item of the key with its expiration and metadata, without promoting it.
+// It is thread-safe.
+func (sharded *ShardedCache) EntryInfo(key string) (EntryInfo, bool) {
+	key = sharded.transform.apply(key)
+	return sharded.shard(key).EntryInfo(key)
+}
@@ -0,0 +1,55 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryInfoMetadata(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLRUCache(2, WithClock(clock))
+
+	cache.SetWithMetadata("key1", "value1", time.Minute, "origin:db")
+	info, found := cache.EntryInfo("key1")
+	assert.True(t, found)
+	assert.Equal(t, EntryInfo{Key: "key1", Value: "value1", ExpiresAt: clock.Now().Add(time.Minute).Round(0), Metadata: "origin:db"}, info)
+
+	cache.Set("key1", "value2") // Clears the metadata
+	info, _ = cache.EntryInfo("key1")
+	assert.Nil(t, info.Metadata)
+	assert.True(t, info.ExpiresAt.IsZero())
+
+	cache.SetWithMetadata("counter", int64(1), 0, 7)
+	cache.Increment("counter", 1, 0)
+	info, _ = cache.EntryInfo("counter")
+	assert.Equal(t, 7, info.Metadata) // Kept by Increment
+
+	clock.Advance(time.Hour)
+	_, found = cache.EntryInfo("missing")
+	assert.False(t, found)
+}
+
+func TestMetadataInEvictionEvent(t *testing.T) {
+	var evicted []Event
+	cache := NewLRUCache(1, WithEventListener(func(event Event) {
+		if event.Reason == metricReasonEvicted {
+			evicted = append(evicted, event)
+		}
+	}))
+
+	cache.SetWithMetadata("key1", "value1", 0, "v3")
+	cache.Set("key2", "value2")
+	assert.Len(t, evicted, 1)
+	assert.Equal(t, "v3", evicted[0].Metadata)
+}
+
+func TestSafeAndShardedMetadata(t *testing.T) {
+	for _, cache := range []MetadataCache{NewSafeLRUCache(4), NewShardedCache(2, 4)} {
+		cache.SetWithMetadata("key", "value", 0, "cost:3")
+		info, found := cache.EntryInfo("key")
+		assert.True(t, found)
+		assert.Equal(t, "cost:3", info.Metadata)
+	}
+}
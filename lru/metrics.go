@@ -1,65 +1,589 @@
 package lru
 
 import (
+	"slices"
+	"sync"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-var (
-	cacheHits = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "lru_cache_hits_total",
-			Help: "Total number of cache hits",
-		},
-		[]string{"cache_type", "operation"},
-	)
-	cacheMisses = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "lru_cache_misses_total",
-			Help: "Total number of cache misses",
-		},
-		[]string{"cache_type", "operation"},
-	)
-	totalItems = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "lru_cache_total_items",
-			Help: "Total number of items in the cache",
-		},
-		[]string{"cache_type", "operation"},
-	)
-	evictionCount = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "lru_cache_evictions_total",
-			Help: "Total number of items evicted from the cache",
+// DefaultMetricsNamespace prefixes the names of the metrics, e.g. cache_hits_total, unless SetMetricsNamespace changes it.
+const DefaultMetricsNamespace = "cache"
+
+// legacyMetricsNamespace prefixed the names of the metrics before they followed the Prometheus conventions,
+// they are still recorded under it with WithLegacyMetrics.
+const legacyMetricsNamespace = "lru_cache"
+
+// metricVecs holds the metric vectors of the caches under one naming scheme.
+type metricVecs struct {
+	legacy          bool                   // Whether the vectors have the legacy names and labels, see WithLegacyMetrics
+	hits            *prometheus.CounterVec // Gets that found their key; with the legacy names, also the sets that updated one
+	misses          *prometheus.CounterVec // Gets that did not find their key; with the legacy names, also the sets that added one
+	sets            *prometheus.CounterVec // Sets by result, nil with the legacy names
+	items           *prometheus.GaugeVec
+	removals        *prometheus.CounterVec
+	ttls            *prometheus.HistogramVec
+	ttlOpts         prometheus.HistogramOpts // Options of ttls, to create it again with other buckets
+	cacheLabel      string                   // Name of the label holding the name of the cache
+	ghostHits       *prometheus.CounterVec
+	throttledSets   *prometheus.CounterVec
+	tenantItems     *prometheus.GaugeVec
+	tenantWeight    *prometheus.GaugeVec
+	tenantEvictions *prometheus.CounterVec
+	lockContentions *prometheus.CounterVec
+	lockWaits       *prometheus.HistogramVec
+	loadingKeys     *prometheus.GaugeVec   // Keys being loaded by a LoadingCache, nil with the legacy names
+	loadWaiters     *prometheus.GaugeVec   // Callers waiting for the load of another caller, nil with the legacy names
+	openCircuits    *prometheus.GaugeVec   // Open circuits of the breaker of a LoadingCache, nil with the legacy names
+	shortCircuits   *prometheus.CounterVec // Loads short-circuited by the breaker of a LoadingCache, nil with the legacy names
+	expirationRate  *prometheus.GaugeVec   // Expirations per second, nil with the legacy names
+	upcoming        *prometheus.GaugeVec   // Items expiring within the horizon, nil with the legacy names
+
+	shardGets          *prometheus.CounterVec // Gets of every shard by result, nil with the legacy names
+	shardItems         *prometheus.GaugeVec   // Items of every shard, nil with the legacy names
+	shardEvictions     *prometheus.CounterVec // Evictions of every shard, nil with the legacy names
+	namespaceGets      *prometheus.CounterVec // Gets of every namespace by result, nil with the legacy names
+	namespaceItems     *prometheus.GaugeVec   // Items of every namespace, nil with the legacy names
+	namespaceEvictions *prometheus.CounterVec // Evictions of every namespace, nil with the legacy names
+
+	stageDurations *prometheus.HistogramVec // Time spent in the stages of the value pipelines, nil with the legacy names
+	stageErrors    *prometheus.CounterVec   // Errors of the stages of the value pipelines, nil with the legacy names
+	stageBytes     *prometheus.CounterVec   // Bytes of the stages of the value pipelines, nil with the legacy names
+
+	dualWriteReads  *prometheus.CounterVec // Reads of the dual-write caches compared by result, nil with the legacy names
+	dualWriteWrites *prometheus.CounterVec // Writes mirrored by the dual-write caches by result, nil with the legacy names
+	canaryGets      *prometheus.CounterVec // Gets of the canary caches by variant and result, nil with the legacy names
+	canaryPercent   *prometheus.GaugeVec   // Percentage of the keys routed to the canary, nil with the legacy names
+}
+
+// newMetricVecs creates the metric vectors named after the given namespace, following the Prometheus conventions:
+// every metric has a cache label with the name of the cache, and the hits and misses only count the gets.
+func newMetricVecs(namespace string, buckets []float64) *metricVecs {
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help}, append([]string{"cache"}, labels...))
+	}
+	gauge := func(name, help string, labels ...string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: namespace, Name: name, Help: help}, append([]string{"cache"}, labels...))
+	}
+	vecs := &metricVecs{
+		hits:     counter("hits_total", "Total number of gets that found their key"),
+		misses:   counter("misses_total", "Total number of gets that did not find their key"),
+		sets:     counter("sets_total", "Total number of sets that added or updated an item, by result", "result"),
+		items:    gauge("items", "Number of items in the cache"),
+		removals: counter("removals_total", "Total number of items removed from the cache, by reason", "reason"),
+		ttlOpts: prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "ttl_seconds",
+			Help:      "Histogram of the TTLs given to SetWithTTL in seconds",
 		},
-		[]string{"cache_type", "operation", "reason"},
-	)
-	expirationHistogram = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "lru_cache_item_expiration_duration_seconds",
-			Help:    "Histogram of item expiration durations in seconds",
-			Buckets: []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60},
+		cacheLabel:      "cache",
+		ghostHits:       counter("ghost_hits_total", "Total number of misses for keys evicted recently, which a larger cache would have served"),
+		throttledSets:   counter("throttled_sets_total", "Total number of writes rejected because they needed an eviction beyond the eviction rate limit"),
+		tenantItems:     gauge("tenant_items", "Number of items of a tenant with a quota", "tenant"),
+		tenantWeight:    gauge("tenant_weight", "Total weight of the items of a tenant with a quota", "tenant"),
+		tenantEvictions: counter("tenant_evictions_total", "Total number of items of a tenant evicted to enforce its quota", "tenant"),
+		lockContentions: counter("lock_contentions_total", "Total number of lock acquisitions that had to wait for another goroutine", "lock"),
+		loadingKeys:     gauge("loading_keys", "Number of keys being loaded by a LoadingCache"),
+		loadWaiters:     gauge("load_waiters", "Number of callers waiting for the load of a key started by another caller"),
+		openCircuits:    gauge("loader_open_circuits", "Number of open circuits of the breaker of a LoadingCache, the global one and those of the keys"),
+		shortCircuits:   counter("loader_short_circuits_total", "Total number of loads short-circuited by the breaker of a LoadingCache"),
+		expirationRate:  gauge("expirations_per_second", "Items expired per second over the last minute, updated by the janitor and Stats"),
+		upcoming:        gauge("upcoming_expirations", "Number of live items expiring within the horizon, a minute by default, updated by the janitor and Stats"),
+
+		shardGets:          counter("shard_gets_total", "Total number of gets of a shard of a ShardedCache, by result", "shard", "result"),
+		shardItems:         gauge("shard_items", "Number of items in a shard of a ShardedCache", "shard"),
+		shardEvictions:     counter("shard_evictions_total", "Total number of items evicted from a shard of a ShardedCache", "shard"),
+		namespaceGets:      counter("namespace_gets_total", "Total number of gets of the keys of a namespace, by result", "namespace", "result"),
+		namespaceItems:     gauge("namespace_items", "Number of items of a namespace in the cache", "namespace"),
+		namespaceEvictions: counter("namespace_evictions_total", "Total number of items of a namespace evicted from the cache", "namespace"),
+
+		stageErrors: counter("pipeline_stage_errors_total", "Total number of errors of a stage of a value pipeline, by direction", "stage", "direction"),
+		stageBytes:  counter("pipeline_stage_bytes_total", "Total number of bytes output by a stage of a value pipeline when applied, and read when reverted", "stage", "direction"),
+		stageDurations: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "pipeline_stage_seconds",
+				Help:      "Histogram of the time spent in a stage of a value pipeline in seconds, by direction",
+				Buckets:   prometheus.ExponentialBuckets(1e-6, 4, 10), // From 1µs to about 0.26s
+			},
+			[]string{"cache", "stage", "direction"},
+		),
+
+		dualWriteReads:  counter("dual_write_reads_total", "Total number of reads of a dual-write cache compared with its secondary cache, by result", "result"),
+		dualWriteWrites: counter("dual_write_writes_total", "Total number of writes mirrored to the secondary cache of a dual-write cache, by whether only one cache stored them", "result"),
+		canaryGets:      counter("canary_gets_total", "Total number of gets routed to the stable or the canary cache of a canary rollout, by result", "variant", "result"),
+		canaryPercent:   gauge("canary_percent", "Percentage of the keys routed to the canary cache of a canary rollout"),
+
+		lockWaits: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "lock_wait_seconds",
+				Help:      "Histogram of the time spent waiting for a contended lock in seconds",
+				Buckets:   prometheus.ExponentialBuckets(1e-6, 4, 10), // From 1µs to about 0.26s
+			},
+			[]string{"cache", "lock"},
+		),
+	}
+	vecs.setTTLBuckets(buckets)
+	return vecs
+}
+
+// newLegacyMetricVecs creates the metric vectors with their former names and labels, see WithLegacyMetrics.
+func newLegacyMetricVecs(buckets []float64) *metricVecs {
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: legacyMetricsNamespace, Name: name, Help: help}, append([]string{"cache_type"}, labels...))
+	}
+	gauge := func(name, help string, labels ...string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: legacyMetricsNamespace, Name: name, Help: help}, append([]string{"cache_type"}, labels...))
+	}
+	vecs := &metricVecs{
+		legacy:   true,
+		hits:     counter("hits_total", "Total number of cache hits", "operation"),
+		misses:   counter("misses_total", "Total number of cache misses", "operation"),
+		items:    gauge("total_items", "Total number of items in the cache", "operation"),
+		removals: counter("evictions_total", "Total number of items evicted from the cache", "operation", "reason"),
+		ttlOpts: prometheus.HistogramOpts{
+			Namespace: legacyMetricsNamespace,
+			Name:      "item_expiration_duration_seconds",
+			Help:      "Histogram of item expiration durations in seconds",
 		},
-		[]string{"cache_type"},
-	)
+		cacheLabel:      "cache_type",
+		ghostHits:       counter("ghost_hits_total", "Total number of misses for keys evicted recently, which a larger cache would have served"),
+		throttledSets:   counter("throttled_sets_total", "Total number of writes rejected because they needed an eviction beyond the eviction rate limit"),
+		tenantItems:     gauge("tenant_items", "Number of items of a tenant with a quota", "tenant"),
+		tenantWeight:    gauge("tenant_weight", "Total weight of the items of a tenant with a quota", "tenant"),
+		tenantEvictions: counter("tenant_evictions_total", "Total number of items of a tenant evicted to enforce its quota", "tenant"),
+		lockContentions: counter("lock_contentions_total", "Total number of lock acquisitions that had to wait for another goroutine", "lock"),
+		lockWaits: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: legacyMetricsNamespace,
+				Name:      "lock_wait_duration_seconds",
+				Help:      "Histogram of the time spent waiting for a contended lock in seconds",
+				Buckets:   prometheus.ExponentialBuckets(1e-6, 4, 10), // From 1µs to about 0.26s
+			},
+			[]string{"cache_type", "lock"},
+		),
+	}
+	vecs.setTTLBuckets(buckets)
+	return vecs
+}
+
+// setTTLBuckets creates the histogram of the TTLs again with the given buckets. It must be registered again.
+func (vecs *metricVecs) setTTLBuckets(buckets []float64) {
+	opts := vecs.ttlOpts
+	opts.Buckets = buckets
+	vecs.ttls = prometheus.NewHistogramVec(opts, []string{vecs.cacheLabel})
+}
+
+// collectors returns the metric vectors to register.
+func (vecs *metricVecs) collectors() []prometheus.Collector {
+	collectors := []prometheus.Collector{
+		vecs.hits, vecs.misses, vecs.items, vecs.removals, vecs.ttls, vecs.ghostHits, vecs.throttledSets,
+		vecs.tenantItems, vecs.tenantWeight, vecs.tenantEvictions, vecs.lockContentions, vecs.lockWaits,
+	}
+	if vecs.sets != nil {
+		collectors = append(collectors, vecs.sets, vecs.loadingKeys, vecs.loadWaiters, vecs.openCircuits, vecs.shortCircuits, vecs.expirationRate, vecs.upcoming)
+		collectors = append(collectors, vecs.shardGets, vecs.shardItems, vecs.shardEvictions, vecs.namespaceGets, vecs.namespaceItems, vecs.namespaceEvictions)
+		collectors = append(collectors, vecs.stageDurations, vecs.stageErrors, vecs.stageBytes)
+		collectors = append(collectors, vecs.dualWriteReads, vecs.dualWriteWrites, vecs.canaryGets, vecs.canaryPercent)
+	}
+	return collectors
+}
+
+// register registers the metric vectors, or none of them if one fails.
+func (vecs *metricVecs) register() error {
+	var registered []prometheus.Collector
+	for _, collector := range vecs.collectors() {
+		if err := prometheus.Register(collector); err != nil {
+			for _, collector := range registered {
+				prometheus.Unregister(collector)
+			}
+			return err
+		}
+		registered = append(registered, collector)
+	}
+	return nil
+}
+
+// unregister unregisters the metric vectors.
+func (vecs *metricVecs) unregister() {
+	for _, collector := range vecs.collectors() {
+		prometheus.Unregister(collector)
+	}
+}
+
+var (
+	metricsMutex      sync.Mutex                                                  // Protects the metric vectors and expirationBuckets
+	expirationBuckets = []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60}                  // Upper bounds of the TTL buckets in seconds, see SetTTLBuckets
+	currentMetrics    = newMetricVecs(DefaultMetricsNamespace, expirationBuckets) // Metric vectors of the caches created from now on
+	legacyMetrics     = newLegacyMetricVecs(expirationBuckets)                    // Metric vectors with the legacy names, see WithLegacyMetrics
 )
 
+// SetMetricsNamespace replaces the prefix of the names of the metrics, DefaultMetricsNamespace by default,
+// e.g. to tell the caches of an application apart from the ones of its libraries. Empty for no prefix.
+// The metrics are registered again, so it must be called before creating the caches, e.g. in main:
+// the caches created before keep recording in the previous metrics, which are no longer exported.
+// It returns an error, and keeps the current namespace, if the new names collide with registered metrics.
+func SetMetricsNamespace(namespace string) error {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	renamed := newMetricVecs(namespace, expirationBuckets)
+	currentMetrics.unregister()
+	if err := renamed.register(); err != nil {
+		currentMetrics.register() // Registered before, so it cannot fail
+		return err
+	}
+	currentMetrics = renamed
+	return nil
+}
+
+// SetTTLBuckets replaces the buckets, upper bounds in seconds, of the histogram of the TTLs given to
+// SetWithTTL, and of the TTL distribution reported by Stats. The default buckets go from 0.1s to a minute.
+// The histogram is registered again, so it must be called before creating the caches, e.g. in main:
+// the caches created before keep recording in the previous histogram, which is no longer exported.
+func SetTTLBuckets(buckets []float64) {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	for _, vecs := range []*metricVecs{currentMetrics, legacyMetrics} {
+		prometheus.Unregister(vecs.ttls)
+		vecs.setTTLBuckets(buckets)
+		prometheus.MustRegister(vecs.ttls)
+	}
+	expirationBuckets = buckets
+}
+
+// ttlBuckets returns the upper bounds of the TTL buckets in seconds.
+func ttlBuckets() []float64 {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	return expirationBuckets
+}
+
 const (
 	metricCacheTypeLRU     = "lru"
 	metricCacheTypeSafeLRU = "safe_lru"
+	metricCacheTypeLFU     = "lfu"
+	metricCacheTypeTLRU    = "tlru"
+	metricCacheTypeLRUK    = "lruk"
+	metricCacheTypeS3FIFO  = "s3fifo"
+	metricCacheTypeSampled = "sampled_lru"
+
+	metricCacheTypeShardedLRU = "sharded_lru"
+	metricCacheTypeLoading    = "loading"
+	metricCacheTypeDualWrite  = "dual_write"
+	metricCacheTypeCanary     = "canary"
 
-	metricOpGet    = "get"
+	metricOpGet    = "get" // Operation label of the legacy metrics
 	metricOpSet    = "set"
 	metricOpRemove = "remove"
 
+	metricResultAdded   = "added"
+	metricResultUpdated = "updated"
+
 	metricReasonManual  = "manual"
 	metricReasonExpired = "expired"
 	metricReasonEvicted = "evicted"
+	metricReasonQuota   = "quota"  // Evicted to keep its tenant within its quota
+	metricReasonDenied  = "denied" // Removed because the admission hook refused its new value
+
+	metricLockRead  = "read"
+	metricLockWrite = "write"
 )
 
+// cacheMetrics holds the metric children of a cache, resolved once when the cache is named
+// so the hot paths don't pay for the label lookups. A nil *cacheMetrics records nothing.
+type cacheMetrics struct {
+	getHits        prometheus.Counter
+	getMisses      prometheus.Counter
+	setAdded       prometheus.Counter
+	setUpdated     prometheus.Counter
+	itemsOnSet     prometheus.Gauge // Number of items after an addition
+	itemsOnRemove  prometheus.Gauge // Number of items after a removal, the same gauge unless legacy
+	removedManual  prometheus.Counter
+	removedExpired prometheus.Counter
+	removedEvicted prometheus.Counter
+	removedOther   func(reason string) prometheus.Counter // Resolves the removals for the other reasons
+	expirations    prometheus.Observer
+	ghostHits      prometheus.Counter
+	throttled      prometheus.Counter
+	expirationRate prometheus.Gauge // Expirations per second, nil with the legacy names
+	upcoming       prometheus.Gauge // Items expiring within the horizon, nil with the legacy names
+	legacy         *cacheMetrics    // Records the metrics under their legacy names too, nil without WithLegacyMetrics
+	batch          *metricsBatch    // Accumulates the counters of the gets, sets and removals, nil without WithMetricsBatching
+	shard          *shardMetrics    // Records the metrics of the shard too, nil outside a ShardedCache with WithShardMetrics
+	name           string           // Name of the cache, the value of the cache label
+}
+
+// newCacheMetrics resolves the metric children of the cache with the given name, and of its legacy metrics if enabled.
+// With a batch larger than one, the counters are batched, see WithMetricsBatching.
+func newCacheMetrics(name string, legacy bool, batch int) *cacheMetrics {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	metrics := currentMetrics.cacheMetrics(name)
+	if legacy {
+		metrics.legacy = legacyMetrics.cacheMetrics(name)
+	}
+	if batch > 1 {
+		metrics.batch = newMetricsBatch(batch)
+		if metrics.legacy != nil {
+			metrics.legacy.batch = newMetricsBatch(batch)
+		}
+	}
+	return metrics
+}
+
+// cacheMetrics resolves the metric children of the cache with the given name.
+func (vecs *metricVecs) cacheMetrics(name string) *cacheMetrics {
+	metrics := &cacheMetrics{
+		expirations: vecs.ttls.WithLabelValues(name),
+		ghostHits:   vecs.ghostHits.WithLabelValues(name),
+		throttled:   vecs.throttledSets.WithLabelValues(name),
+		name:        name,
+	}
+	if vecs.legacy { // The sets were counted as hits and misses, and the gauge had an operation label
+		metrics.getHits = vecs.hits.WithLabelValues(name, metricOpGet)
+		metrics.getMisses = vecs.misses.WithLabelValues(name, metricOpGet)
+		metrics.setAdded = vecs.misses.WithLabelValues(name, metricOpSet)
+		metrics.setUpdated = vecs.hits.WithLabelValues(name, metricOpSet)
+		metrics.itemsOnSet = vecs.items.WithLabelValues(name, metricOpSet)
+		metrics.itemsOnRemove = vecs.items.WithLabelValues(name, metricOpRemove)
+		metrics.removedOther = func(reason string) prometheus.Counter {
+			return vecs.removals.WithLabelValues(name, metricOpRemove, reason)
+		}
+	} else {
+		metrics.getHits = vecs.hits.WithLabelValues(name)
+		metrics.getMisses = vecs.misses.WithLabelValues(name)
+		metrics.expirationRate = vecs.expirationRate.WithLabelValues(name)
+		metrics.upcoming = vecs.upcoming.WithLabelValues(name)
+		metrics.setAdded = vecs.sets.WithLabelValues(name, metricResultAdded)
+		metrics.setUpdated = vecs.sets.WithLabelValues(name, metricResultUpdated)
+		metrics.itemsOnSet = vecs.items.WithLabelValues(name)
+		metrics.itemsOnRemove = metrics.itemsOnSet
+		metrics.removedOther = func(reason string) prometheus.Counter {
+			return vecs.removals.WithLabelValues(name, reason)
+		}
+	}
+	metrics.removedManual = metrics.removedOther(metricReasonManual)
+	metrics.removedExpired = metrics.removedOther(metricReasonExpired)
+	metrics.removedEvicted = metrics.removedOther(metricReasonEvicted)
+	return metrics
+}
+
+// loadingMetrics holds the gauges of a LoadingCache. A nil *loadingMetrics records nothing.
+type loadingMetrics struct {
+	keys          prometheus.Gauge
+	waiters       prometheus.Gauge
+	openCircuits  prometheus.Gauge
+	shortCircuits prometheus.Counter
+}
+
+// newLoadingMetrics resolves the gauges of the LoadingCache with the given name.
+func newLoadingMetrics(name string) *loadingMetrics {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	return &loadingMetrics{
+		keys:    currentMetrics.loadingKeys.WithLabelValues(name),
+		waiters: currentMetrics.loadWaiters.WithLabelValues(name),
+
+		openCircuits:  currentMetrics.openCircuits.WithLabelValues(name),
+		shortCircuits: currentMetrics.shortCircuits.WithLabelValues(name),
+	}
+}
+
+// loading adds delta to the number of keys being loaded.
+func (metrics *loadingMetrics) loading(delta int) {
+	if metrics != nil {
+		metrics.keys.Add(float64(delta))
+	}
+}
+
+// waiting adds delta to the number of callers waiting for a load.
+func (metrics *loadingMetrics) waiting(delta int) {
+	if metrics != nil {
+		metrics.waiters.Add(float64(delta))
+	}
+}
+
+// circuitOpened adds delta to the number of open circuits.
+func (metrics *loadingMetrics) circuitOpened(delta int) {
+	if metrics != nil {
+		metrics.openCircuits.Add(float64(delta))
+	}
+}
+
+// shortCircuited records a load short-circuited by the breaker.
+func (metrics *loadingMetrics) shortCircuited() {
+	if metrics != nil {
+		metrics.shortCircuits.Inc()
+	}
+}
+
+// cacheName returns the name of the cache, empty when the metrics are disabled.
+func (metrics *cacheMetrics) cacheName() string {
+	if metrics == nil {
+		return ""
+	}
+	return metrics.name
+}
+
+// getHit records a Get that found the key.
+func (metrics *cacheMetrics) getHit() {
+	if metrics != nil {
+		if !metrics.batch.count(batchedGetHits, metrics) {
+			metrics.getHits.Inc()
+		}
+		metrics.shard.get(true)
+		metrics.legacy.getHit()
+	}
+}
+
+// getMiss records a Get that did not find the key.
+func (metrics *cacheMetrics) getMiss() {
+	if metrics != nil {
+		if !metrics.batch.count(batchedGetMisses, metrics) {
+			metrics.getMisses.Inc()
+		}
+		metrics.shard.get(false)
+		metrics.legacy.getMiss()
+	}
+}
+
+// ghostHit records a miss for a key evicted recently.
+func (metrics *cacheMetrics) ghostHit() {
+	if metrics != nil {
+		metrics.ghostHits.Inc()
+		metrics.legacy.ghostHit()
+	}
+}
+
+// expirationForecast records the expirations per second and the number of items expiring within the horizon.
+func (metrics *cacheMetrics) expirationForecast(rate float64, upcoming int) {
+	if metrics != nil && metrics.expirationRate != nil {
+		metrics.expirationRate.Set(rate)
+		metrics.upcoming.Set(float64(upcoming))
+	}
+}
+
+// throttledSet records a write rejected by the eviction rate limit.
+func (metrics *cacheMetrics) throttledSet() {
+	if metrics != nil {
+		metrics.throttled.Inc()
+		metrics.legacy.throttledSet()
+	}
+}
+
+// added records a Set that inserted a new item, and the resulting number of items.
+func (metrics *cacheMetrics) added(items int) {
+	if metrics != nil {
+		if !metrics.batch.count(batchedSetAdded, metrics) {
+			metrics.setAdded.Inc()
+		}
+		metrics.itemsOnSet.Set(float64(items))
+		metrics.shard.resized(items, false)
+		metrics.legacy.added(items)
+	}
+}
+
+// updated records a Set that overrode an existing item.
+func (metrics *cacheMetrics) updated() {
+	if metrics != nil {
+		if !metrics.batch.count(batchedSetUpdated, metrics) {
+			metrics.setUpdated.Inc()
+		}
+		metrics.legacy.updated()
+	}
+}
+
+// removed records the removal of an item for the given reason, and the resulting number of items.
+func (metrics *cacheMetrics) removed(reason string, items int) {
+	if metrics == nil {
+		return
+	}
+	switch reason {
+	case metricReasonManual:
+		if !metrics.batch.count(batchedRemovedManual, metrics) {
+			metrics.removedManual.Inc()
+		}
+	case metricReasonExpired:
+		if !metrics.batch.count(batchedRemovedExpired, metrics) {
+			metrics.removedExpired.Inc()
+		}
+	case metricReasonEvicted:
+		if !metrics.batch.count(batchedRemovedEvicted, metrics) {
+			metrics.removedEvicted.Inc()
+		}
+	default:
+		metrics.removedOther(reason).Inc()
+	}
+	metrics.itemsOnRemove.Set(float64(items))
+	metrics.shard.resized(items, reason == metricReasonEvicted)
+	metrics.legacy.removed(reason, items)
+}
+
+// expiration records the TTL given to SetWithTTL.
+func (metrics *cacheMetrics) expiration(ttl time.Duration) {
+	if metrics != nil {
+		metrics.expirations.Observe(ttl.Seconds())
+		metrics.legacy.expiration(ttl)
+	}
+}
+
+// lockMetrics holds the contention metric children of a SafeLRUCache. A nil *lockMetrics records nothing.
+type lockMetrics struct {
+	readContentions  prometheus.Counter
+	writeContentions prometheus.Counter
+	readWaits        prometheus.Observer
+	writeWaits       prometheus.Observer
+	legacy           *lockMetrics // Records the metrics under their legacy names too, nil without WithLegacyMetrics
+}
+
+// newLockMetrics resolves the contention metric children of the cache with the given name, and of its legacy metrics if enabled.
+func newLockMetrics(name string, legacy bool) *lockMetrics {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	metrics := currentMetrics.lockMetrics(name)
+	if legacy {
+		metrics.legacy = legacyMetrics.lockMetrics(name)
+	}
+	return metrics
+}
+
+// lockMetrics resolves the contention metric children of the cache with the given name.
+func (vecs *metricVecs) lockMetrics(name string) *lockMetrics {
+	return &lockMetrics{
+		readContentions:  vecs.lockContentions.WithLabelValues(name, metricLockRead),
+		writeContentions: vecs.lockContentions.WithLabelValues(name, metricLockWrite),
+		readWaits:        vecs.lockWaits.WithLabelValues(name, metricLockRead),
+		writeWaits:       vecs.lockWaits.WithLabelValues(name, metricLockWrite),
+	}
+}
+
+// contended records an acquisition of the given lock that waited for another goroutine.
+func (metrics *lockMetrics) contended(lock string, wait time.Duration) {
+	if metrics == nil {
+		return
+	}
+	if lock == metricLockRead {
+		metrics.readContentions.Inc()
+		metrics.readWaits.Observe(wait.Seconds())
+	} else {
+		metrics.writeContentions.Inc()
+		metrics.writeWaits.Observe(wait.Seconds())
+	}
+	metrics.legacy.contended(lock, wait)
+}
+
 func init() {
-	prometheus.MustRegister(cacheHits)
-	prometheus.MustRegister(cacheMisses)
-	prometheus.MustRegister(totalItems)
-	prometheus.MustRegister(evictionCount)
-	prometheus.MustRegister(expirationHistogram)
+	prometheus.MustRegister(currentMetrics.collectors()...)
+	prometheus.MustRegister(legacyMetrics.collectors()...)
 }
@@ -0,0 +1,106 @@
+package lru
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsBatch accumulates the counters updated on every Get and Set of a cache, so they are added to Prometheus
+// once every size operations instead of on each one. The counts are atomic, as the reads of a SafeLRUCache
+// with an access buffer are recorded concurrently under a read lock.
+type metricsBatch struct {
+	size    int64                          // Operations between two flushes
+	pending atomic.Int64                   // Operations since the last flush
+	mutex   sync.Mutex                     // Serializes the flushes
+	counts  [batchedCounters]atomic.Uint64 // Counts since the last flush, by batchedCounter
+}
+
+// batchedCounter identifies a counter of cacheMetrics accumulated by a metricsBatch.
+type batchedCounter int
+
+const (
+	batchedGetHits batchedCounter = iota
+	batchedGetMisses
+	batchedSetAdded
+	batchedSetUpdated
+	batchedRemovedManual
+	batchedRemovedExpired
+	batchedRemovedEvicted
+	batchedCounters // Number of batched counters
+)
+
+func newMetricsBatch(size int) *metricsBatch {
+	return &metricsBatch{size: int64(size)}
+}
+
+// count increments the counter, and flushes the batch into the metrics once it holds size operations.
+// It returns false without a batch, the caller then records the operation itself.
+func (batch *metricsBatch) count(counter batchedCounter, metrics *cacheMetrics) bool {
+	if batch == nil {
+		return false
+	}
+	batch.counts[counter].Add(1)
+	if batch.pending.Add(1) >= batch.size {
+		batch.flush(metrics)
+	}
+	return true
+}
+
+// flush adds the counts accumulated since the last flush to the metrics.
+func (batch *metricsBatch) flush(metrics *cacheMetrics) {
+	batch.mutex.Lock()
+	defer batch.mutex.Unlock()
+
+	batch.pending.Store(0)
+	for counter, prometheusCounter := range []prometheus.Counter{
+		batchedGetHits: metrics.getHits, batchedGetMisses: metrics.getMisses,
+		batchedSetAdded: metrics.setAdded, batchedSetUpdated: metrics.setUpdated,
+		batchedRemovedManual: metrics.removedManual, batchedRemovedExpired: metrics.removedExpired, batchedRemovedEvicted: metrics.removedEvicted,
+	} {
+		if count := batch.counts[counter].Swap(0); count > 0 {
+			prometheusCounter.Add(float64(count))
+		}
+	}
+}
+
+// flush adds the batched counts to the metrics right away, and to their legacy names if any.
+// It does nothing without batching.
+func (metrics *cacheMetrics) flush() {
+	if metrics != nil && metrics.batch != nil {
+		metrics.batch.flush(metrics)
+		metrics.legacy.flush()
+	}
+}
+
+// metricsFlusher is implemented by the caches able to batch their metrics, so a SafeLRUCache flushes them
+// with its janitor and when closed.
+type metricsFlusher interface {
+	flushMetrics()
+}
+
+var _ metricsFlusher = (*LRUCache)(nil)    // Ensure LRUCache metrics can be flushed
+var _ metricsFlusher = (*LFUCache)(nil)    // Ensure LFUCache metrics can be flushed
+var _ metricsFlusher = (*LRUKCache)(nil)   // Ensure LRUKCache metrics can be flushed
+var _ metricsFlusher = (*S3FIFOCache)(nil) // Ensure S3FIFOCache metrics can be flushed
+
+// flushMetrics adds the batched counts to the metrics.
+func (cache *LRUCache) flushMetrics() {
+	cache.metrics.flush()
+}
+
+// flushMetrics adds the batched counts to the metrics.
+func (cache *LFUCache) flushMetrics() {
+	cache.metrics.flush()
+}
+
+// flushMetrics adds the batched counts to the metrics.
+func (cache *LRUKCache) flushMetrics() {
+	cache.metrics.flush()
+}
+
+// flushMetrics adds the batched counts to the metrics.
+func (cache *S3FIFOCache) flushMetrics() {
+	cache.metrics.flush()
+}
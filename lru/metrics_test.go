@@ -0,0 +1,130 @@
+package lru
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+// gaugeValue reads the current value of a Prometheus gauge.
+func gaugeValue(gauge prometheus.Gauge) float64 {
+	var metric dto.Metric
+	gauge.Write(&metric)
+	return metric.GetGauge().GetValue()
+}
+
+func TestMetricsCountOnlyGets(t *testing.T) {
+	cache := NewLRUCache(2, WithMetricsName("metrics_gets"))
+	cache.Set("key1", "value1")
+	cache.Set("key1", "value2")
+	cache.Get("key1")
+	cache.Get("missing")
+	cache.Remove("key1")
+
+	assert.Equal(t, 1.0, counterValue(currentMetrics.hits.WithLabelValues("metrics_gets")))
+	assert.Equal(t, 1.0, counterValue(currentMetrics.misses.WithLabelValues("metrics_gets")), "Expected the sets to not count as misses")
+	assert.Equal(t, 1.0, counterValue(currentMetrics.sets.WithLabelValues("metrics_gets", metricResultAdded)))
+	assert.Equal(t, 1.0, counterValue(currentMetrics.sets.WithLabelValues("metrics_gets", metricResultUpdated)))
+	assert.Equal(t, 1.0, counterValue(currentMetrics.removals.WithLabelValues("metrics_gets", metricReasonManual)))
+	assert.Zero(t, gaugeValue(currentMetrics.items.WithLabelValues("metrics_gets")), "Expected a single gauge for the additions and removals")
+}
+
+func TestLegacyMetrics(t *testing.T) {
+	cache := NewLRUCache(2, WithMetricsName("metrics_legacy"), WithLegacyMetrics())
+	cache.Set("key1", "value1")
+	cache.Get("key1")
+	cache.SetWithTTL("key2", "value2", time.Minute)
+
+	assert.Equal(t, 1.0, counterValue(legacyMetrics.hits.WithLabelValues("metrics_legacy", metricOpGet)))
+	assert.Equal(t, 2.0, counterValue(legacyMetrics.misses.WithLabelValues("metrics_legacy", metricOpSet)))
+	assert.Equal(t, 2.0, gaugeValue(legacyMetrics.items.WithLabelValues("metrics_legacy", metricOpSet)))
+	assert.Equal(t, 1.0, counterValue(currentMetrics.hits.WithLabelValues("metrics_legacy")), "Expected the current names to be recorded too")
+
+	NewLRUCache(2, WithMetricsName("metrics_current")).Get("missing")
+	assert.Zero(t, counterValue(legacyMetrics.misses.WithLabelValues("metrics_current", metricOpGet)), "Expected the legacy names to be opt-in")
+}
+
+func TestMetricsBatching(t *testing.T) {
+	cache := NewSafe(NewLRUCache(2, WithMetricsName("metrics_batched"), WithMetricsBatching(4), WithLegacyMetrics()))
+	hits := currentMetrics.hits.WithLabelValues("metrics_batched")
+	cache.Set("key1", "value1")
+	cache.Get("key1")
+	cache.Get("key1")
+	assert.Zero(t, counterValue(hits), "Expected the counters to wait for the batch to fill")
+	assert.Equal(t, 1.0, gaugeValue(currentMetrics.items.WithLabelValues("metrics_batched")), "Expected the gauge to be updated right away")
+
+	cache.Get("missing") // Fourth operation
+	assert.Equal(t, 2.0, counterValue(hits))
+	assert.Equal(t, 1.0, counterValue(currentMetrics.misses.WithLabelValues("metrics_batched")))
+	assert.Equal(t, 1.0, counterValue(currentMetrics.sets.WithLabelValues("metrics_batched", metricResultAdded)))
+
+	cache.Remove("key1")
+	assert.Zero(t, counterValue(currentMetrics.removals.WithLabelValues("metrics_batched", metricReasonManual)))
+	assert.NoError(t, cache.Close())
+	assert.Equal(t, 1.0, counterValue(currentMetrics.removals.WithLabelValues("metrics_batched", metricReasonManual)), "Expected Close to flush the batch")
+	assert.Equal(t, 2.0, counterValue(legacyMetrics.hits.WithLabelValues("metrics_batched", metricOpGet)), "Expected the legacy names to be batched too")
+	assert.Equal(t, 1.0, counterValue(legacyMetrics.removals.WithLabelValues("metrics_batched", metricOpRemove, metricReasonManual)))
+}
+
+func TestSetMetricsNamespace(t *testing.T) {
+	defer SetMetricsNamespace(DefaultMetricsNamespace)
+
+	assert.NoError(t, SetMetricsNamespace("app"))
+	cache := NewLRUCache(2, WithMetricsName("metrics_namespace"))
+	cache.Get("missing")
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.NoError(t, err)
+	var names []string
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	assert.Contains(t, names, "app_misses_total")
+	assert.NotContains(t, names, "cache_misses_total")
+
+	assert.Error(t, SetMetricsNamespace(legacyMetricsNamespace), "Expected the names colliding with the legacy ones to be rejected")
+	assert.Equal(t, "app", currentMetrics.ttlOpts.Namespace, "Expected the namespace to be kept after an error")
+}
+
+func TestShardMetrics(t *testing.T) {
+	cache := NewShardedCache(2, 2, WithMetricsName("metrics_shards"), WithShardMetrics(), WithKeyHash(func(key string) uint64 {
+		return uint64(len(key)) // Shard 1 for the odd lengths
+	}))
+	cache.Set("a", 1)
+	cache.Set("b", 2) // Evicts a from shard 1
+	cache.Get("b")
+	cache.Get("aa")
+
+	assert.Equal(t, 1.0, counterValue(currentMetrics.shardGets.WithLabelValues("metrics_shards", "1", metricResultHit)))
+	assert.Equal(t, 1.0, counterValue(currentMetrics.shardGets.WithLabelValues("metrics_shards", "0", metricResultMiss)))
+	assert.Equal(t, 1.0, gaugeValue(currentMetrics.shardItems.WithLabelValues("metrics_shards", "1")))
+	assert.Equal(t, 1.0, counterValue(currentMetrics.shardEvictions.WithLabelValues("metrics_shards", "1")))
+	assert.Zero(t, counterValue(currentMetrics.shardEvictions.WithLabelValues("metrics_shards", "0")))
+}
+
+func TestNamespaceMetrics(t *testing.T) {
+	namespaceOf := func(key string) string {
+		namespace, _, _ := strings.Cut(key, ":")
+		return namespace
+	}
+	cache := NewLRUCache(3, WithMetricsName("metrics_namespaces"), WithNamespaceMetrics(namespaceOf, 2))
+	cache.Set("user:1", 1)
+	cache.Set("order:1", 1)
+	cache.Set("session:1", 1) // Beyond the limit of namespaces
+	cache.Get("user:1")
+	cache.Get("user:2")
+	cache.Set("user:3", 3) // Evicts order:1
+
+	gets := currentMetrics.namespaceGets
+	assert.Equal(t, 1.0, counterValue(gets.WithLabelValues("metrics_namespaces", "user", metricResultHit)))
+	assert.Equal(t, 1.0, counterValue(gets.WithLabelValues("metrics_namespaces", "user", metricResultMiss)))
+	assert.Equal(t, 2.0, gaugeValue(currentMetrics.namespaceItems.WithLabelValues("metrics_namespaces", "user")))
+	assert.Zero(t, gaugeValue(currentMetrics.namespaceItems.WithLabelValues("metrics_namespaces", "order")))
+	assert.Equal(t, 1.0, counterValue(currentMetrics.namespaceEvictions.WithLabelValues("metrics_namespaces", "order")))
+	assert.Equal(t, 1.0, gaugeValue(currentMetrics.namespaceItems.WithLabelValues("metrics_namespaces", metricNamespaceOther)),
+		"Expected the namespaces beyond the limit to share a label")
+}
@@ -1,7 +1,10 @@
 package lru
 
 import (
-	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -9,37 +12,194 @@ import (
 // This is intended to be used for demo purposes, where we want to return the state of the cache as a JSON object.
 type ObservableCacheItem struct {
 	Key       string    `json:"key"`
-	Value     string    `json:"value"` // Value is stored as a string for JSON serialization
+	Value     string    `json:"value,omitempty"` // Value is stored as a string for JSON serialization, empty if omitted by StateQuery
 	ExpiresAt time.Time `json:"expires_at"`
 	Prev      string    `json:"prev"`
 	Next      string    `json:"next"`
+	Frequency int       `json:"frequency,omitempty"` // Access frequency, only reported by LFU caches
+	Segment   string    `json:"segment,omitempty"`   // Segment of the policy holding the item, empty for the policies with a single segment
+
+	// DistanceFromEviction is the number of live items evicted before this one, zero for the next eviction candidate.
+	// Expired items are reclaimed before any eviction, so their distance is zero too.
+	DistanceFromEviction int  `json:"distance_from_eviction"`
+	Expired              bool `json:"expired,omitempty"` // Whether the item expired and waits to be reclaimed
 }
 
 type ObservableCache struct {
 	Cache *SafeLRUCache // The underlying SafeLRUCache
+
+	subscribersMutex sync.Mutex              // Protects subscribers, independently of the cache mutex
+	subscribers      map[chan Event]struct{} // Channels receiving the events of the cache
+	closed           bool                    // Whether Close was called, rejecting new subscribers
+	journal          stateJournal            // The last changes of the state, by version
+	shadow           *shadowState            // Copy of the items serving State, nil without WithShadowState
+	preview          valuePreview            // Renders the values of the state
 }
 
+var _ io.Closer = (*ObservableCache)(nil) // Ensure ObservableCache can be closed
+
 type ObservableCacheState struct {
+	Policy   string                `json:"policy"`  // The eviction policy of the cache, e.g. lru, tlru or lfu
+	Version  uint64                `json:"version"` // The version of the state, to poll the following changes with Changes
 	Capacity int                   `json:"capacity"`
+	Total    int                   `json:"total"` // The number of items matching the StateQuery, Items holding a page of them
 	Items    []ObservableCacheItem `json:"items"`
 }
 
-func NewObservableCache(capacity int) *ObservableCache {
-	cache := NewSafeLRUCache(capacity)
-	return &ObservableCache{
-		Cache: cache,
+// StateQuery selects the items returned by StatePage, so large caches can be inspected page by page.
+type StateQuery struct {
+	Prefix     string // Only return the keys starting with the prefix
+	Offset     int    // Number of matching items to skip
+	Limit      int    // Maximum number of items to return, zero for no limit
+	OmitValues bool   // Leave the values empty, e.g. when only the order matters
+}
+
+func NewObservableCache(capacity int, opts ...Option) *ObservableCache {
+	return NewObservableCacheFrom(NewSafeLRUCache(capacity, opts...), opts...)
+}
+
+// NewObservableCacheFrom creates an ObservableCache around an existing SafeLRUCache.
+// This allows observing caches using other policies, e.g. NewSafe(NewLFUCache(5)).
+// If the underlying cache emits events, they are made available through Subscribe.
+// Only the options specific to ObservableCache, such as WithShadowState and WithValueRenderer, are used.
+func NewObservableCacheFrom(cache *SafeLRUCache, opts ...Option) *ObservableCache {
+	o := newOptions(opts...)
+	observable := &ObservableCache{
+		Cache:       cache,
+		subscribers: make(map[chan Event]struct{}),
+		preview:     valuePreview{renderer: o.valueRenderer, maxLength: o.maxValueLength},
 	}
+	if o.shadowState {
+		observable.shadow = newShadowState(o.shadowStaleness)
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if source, ok := cache.cache.(eventSource); ok {
+		source.subscribe(observable.publish)
+	}
+	return observable
 }
 
+// Subscribe returns a channel receiving the events of the cache, e.g. to stream them to the visualizer.
+// Events are dropped for subscribers that don't keep up once the buffer is full, so a slow
+// subscriber never blocks the cache.
+// The returned function unsubscribes and closes the channel. The channel is also closed by Close.
+func (observable *ObservableCache) Subscribe(buffer int) (events <-chan Event, unsubscribe func()) {
+	channel := make(chan Event, buffer)
+
+	observable.subscribersMutex.Lock()
+	defer observable.subscribersMutex.Unlock()
+
+	if observable.closed {
+		close(channel)
+		return channel, func() {}
+	}
+	observable.subscribers[channel] = struct{}{}
+
+	return channel, func() {
+		observable.subscribersMutex.Lock()
+		defer observable.subscribersMutex.Unlock()
+
+		if _, found := observable.subscribers[channel]; found {
+			delete(observable.subscribers, channel)
+			close(channel)
+		}
+	}
+}
+
+// Close closes the channels of every subscriber, so the goroutines consuming them can return.
+// Subscribing after Close returns an already closed channel. The cache itself remains usable.
+func (observable *ObservableCache) Close() error {
+	observable.subscribersMutex.Lock()
+	defer observable.subscribersMutex.Unlock()
+
+	observable.closed = true
+	for channel := range observable.subscribers {
+		delete(observable.subscribers, channel)
+		close(channel)
+	}
+	return nil
+}
+
+// publish records the event in the journal and the shadow state, and sends it to every subscriber without blocking.
+func (observable *ObservableCache) publish(event Event) {
+	if version := observable.journal.record(event); version > 0 && observable.shadow != nil {
+		observable.shadow.record(StateChange{Version: version, Event: event})
+	}
+
+	observable.subscribersMutex.Lock()
+	defer observable.subscribersMutex.Unlock()
+
+	for channel := range observable.subscribers {
+		select {
+		case channel <- event:
+		default: // The subscriber is not keeping up, drop the event
+		}
+	}
+}
+
+// State returns the items of the cache ordered from the most to the least valuable entry,
+// meaning the last item is the next eviction candidate.
+// Only LRUCache and LFUCache are supported, other caches return an empty state.
 func (observable *ObservableCache) State() ObservableCacheState {
-	observable.Cache.mutex.Lock()
+	return observable.StatePage(StateQuery{})
+}
+
+// StatePage returns the items of the cache selected by the query, in the order of State.
+// The neighbors and the distances from eviction of the items are those of the full state.
+// With WithShadowState, the state is rendered from the shadow state without holding the cache lock,
+// and may lag the cache by up to the configured staleness.
+func (observable *ObservableCache) StatePage(query StateQuery) ObservableCacheState {
+	if observable.shadow != nil {
+		state := observable.shadow.current(observable)
+		state.Items = query.apply(slices.Clone(state.Items)) // The rendered state is shared by the callers
+		state.Total = len(state.Items)
+		state.Items = query.page(state.Items)
+		return state
+	}
+
+	observable.Cache.lock() // Apply the pending promotions, if any, so the order is up to date
 	defer observable.Cache.mutex.Unlock()
 
-	lru, ok := observable.Cache.cache.(*LRUCache)
-	if !ok {
-		return ObservableCacheState{}
+	// The events are published under the cache mutex, so the version matches the items
+	var state ObservableCacheState
+	switch cache := observable.Cache.cache.(type) {
+	case *LRUCache:
+		state = lruState(cache, observable.preview)
+	case *LFUCache:
+		state = lfuState(cache, observable.preview)
+	}
+	state.Version = observable.journal.current()
+	state.Items = query.apply(state.Items)
+	state.Total = len(state.Items)
+	state.Items = query.page(state.Items)
+	return state
+}
+
+// apply returns the items matching the prefix, without their values if omitted.
+func (query StateQuery) apply(items []ObservableCacheItem) []ObservableCacheItem {
+	if query.Prefix != "" {
+		items = slices.DeleteFunc(items, func(item ObservableCacheItem) bool { return !strings.HasPrefix(item.Key, query.Prefix) })
+	}
+	if query.OmitValues {
+		for i := range items {
+			items[i].Value = ""
+		}
+	}
+	return items
+}
+
+// page returns the items between the offset and the limit.
+func (query StateQuery) page(items []ObservableCacheItem) []ObservableCacheItem {
+	items = items[min(max(query.Offset, 0), len(items)):]
+	if query.Limit > 0 && query.Limit < len(items) {
+		items = items[:query.Limit]
 	}
+	return items
+}
 
+func lruState(lru *LRUCache, preview valuePreview) ObservableCacheState {
 	// This is not performant, but it is a simple way to get the state of the cache.
 	// In a real application, observability in cache is often done with metrics,
 	// but here we want to return the state as a JSON object.
@@ -54,16 +214,84 @@ func (observable *ObservableCache) State() ObservableCacheState {
 		}
 		items = append(items, ObservableCacheItem{
 			Key:       ent.key,
-			Value:     fmt.Sprintf("%v", ent.value), // Convert value to string for JSON serialization
+			Value:     preview.render(ent.key, lru.load(ent)), // Convert value to string for JSON serialization
 			ExpiresAt: ent.expiresAt,
 			Prev:      prev,
 			Next:      next,
+			Segment:   lru.segmentOf(ent),
 		})
 		prev = ent.key
 	}
 
+	rankEvictions(items, lru.clock.Now(), lru.timeAware)
+	policy := metricCacheTypeLRU
+	if lru.timeAware > 1 {
+		policy = metricCacheTypeTLRU
+	} else if lru.segments != nil {
+		policy = policySLRU
+	}
 	return ObservableCacheState{
+		Policy:   policy,
 		Capacity: lru.capacity,
 		Items:    items,
 	}
 }
+
+func lfuState(lfu *LFUCache, preview valuePreview) ObservableCacheState {
+	items := make([]ObservableCacheItem, 0, len(lfu.items))
+	lfu.eachByFrequency(func(ent *lfuEntry) {
+		prev := ""
+		if len(items) > 0 {
+			prev = items[len(items)-1].Key
+			items[len(items)-1].Next = ent.key
+		}
+		items = append(items, ObservableCacheItem{
+			Key:       ent.key,
+			Value:     preview.render(ent.key, ent.value), // Convert value to string for JSON serialization
+			ExpiresAt: ent.expiresAt,
+			Prev:      prev,
+			Frequency: ent.frequency,
+		})
+	})
+
+	rankEvictions(items, lfu.clock.Now(), 0)
+	return ObservableCacheState{
+		Policy:   metricCacheTypeLFU,
+		Capacity: lfu.capacity,
+		Items:    items,
+	}
+}
+
+// rankEvictions sets the distance from eviction of the items, ordered from the most to the least valuable,
+// and flags the expired ones, so the visualizer can color the items by their risk of eviction.
+// With a time-aware window above 1, the items are ranked in the order the victims of WithTimeAwareEviction
+// would be chosen: the one expiring the soonest among the window least valuable ones first.
+func rankEvictions(items []ObservableCacheItem, now time.Time, timeAware int) {
+	var live []int // Indexes of the items not expired, the least valuable first
+	for i := len(items) - 1; i >= 0; i-- {
+		if hasExpired(items[i].ExpiresAt, now) {
+			items[i].Expired = true
+			continue
+		}
+		live = append(live, i)
+	}
+
+	window := make([]int, 0, max(timeAware, 1)) // The candidates of the next eviction, the least valuable first
+	for distance := 0; ; distance++ {
+		for len(window) < cap(window) && len(live) > 0 {
+			window, live = append(window, live[0]), live[1:]
+		}
+		if len(window) == 0 {
+			return
+		}
+		victim := 0
+		for considered := 1; considered < len(window); considered++ {
+			expiresAt, soonest := items[window[considered]].ExpiresAt, items[window[victim]].ExpiresAt
+			if !expiresAt.IsZero() && (soonest.IsZero() || expiresAt.Before(soonest)) {
+				victim = considered
+			}
+		}
+		items[window[victim]].DistanceFromEviction = distance
+		window = slices.Delete(window, victim, victim+1)
+	}
+}
@@ -0,0 +1,350 @@
+package lru
+
+import (
+	"time"
+)
+
+// options holds the optional configuration shared by the cache implementations.
+type options struct {
+	clock     Clock                // Source of the current time, used for expiration
+	metrics   bool                 // Whether Prometheus metrics are recorded
+	name      string               // Value of the cache label of the metrics, empty for the type of the cache
+	codec     Codec                // Encodes the values of the slab storage, nil to keep the values on the heap
+	slabSize  int                  // Size of the slabs of the slab storage
+	slowLog   *SlowLog             // Records the slow loads and encodings, nil to not record them
+	listeners []EventListener      // Receive the events emitted by the cache
+	keyIndex  bool                 // Whether the keys are kept sorted for the prefix scans
+	indexes   map[string]IndexFunc // Extractors of the secondary indexes by name
+
+	maxChunkedSize int // Largest encoded value split in chunks by the slab storage, zero to reject the values larger than a slab
+
+	breaker      breakerOptions // Global circuit breaker of a LoadingCache, see WithCircuitBreaker
+	keyBreaker   breakerOptions // Circuit breaker of every key of a LoadingCache, see WithKeyCircuitBreaker
+	serveStale   bool           // Whether a LoadingCache serves the expired values when their load fails
+	maxStaleness time.Duration  // How long after their expiration the values are served by WithServeStaleOnError
+
+	legacyMetrics bool // Whether the metrics are recorded under their legacy names too
+	metricsBatch  int  // Operations whose counters are added to the metrics at once, zero or one to add them on every operation
+
+	tenantOf TenantFunc       // Tenant owning every key, nil without quotas
+	weigh    WeightFunc       // Weight of the items counted against the quotas, nil to weigh every item 1
+	quotas   map[string]Quota // Quotas by tenant
+
+	curveRate        float64 // Share of the keys sampled to estimate the hit ratio curve, zero to disable it
+	curveMaxCapacity int     // Largest capacity of the hit ratio curve, zero for twice the capacity
+
+	ghostWindow       int  // Operations during which the evicted keys are remembered, zero to disable the ghost list
+	readmissionWindow int  // Operations during which the evicted keys are readmitted to the protected segment, zero for a single segment
+	previousValues    bool // Whether the writes return the values they replace or evict in SetResult
+
+	admit AdmitFunc // Decides whether the items are written, nil to admit them all
+
+	keyTransform KeyTransform // Rewrites the keys before every operation, nil to use them as is
+	ttlPolicy    TTLPolicy    // Decides the ttl of the items written by Set, nil to keep them until removed
+	keyHash      KeyHash      // Spreads the keys over the shards, nil for FNV-1a
+
+	evictionRate int // Maximum evictions per second to make room for new items, zero for no limit
+
+	timeAwareWindow int // Least recently used items among which the soonest to expire is evicted, zero for the plain LRU policy
+	lrukDepth       int // References remembered per item by an LRUKCache, zero for the default
+	evictionSamples int // Items compared by a SampledLRUCache to pick a victim, zero for the default
+
+	lowWatermark  float64 // Fraction of the capacity the background trims go down to
+	highWatermark float64 // Fraction of the capacity above which a background trim starts, zero without watermarks
+
+	expiryBucketWidth time.Duration // Width of the expiry buckets, zero to order the expirations exactly
+	janitorInterval   time.Duration // Interval between the removals of the expired items, zero to remove them lazily
+
+	writeBuffer  int // Size of the write buffer of a SafeLRUCache, zero for synchronous writes
+	accessBuffer int // Size of the access buffer of a SafeLRUCache, zero to promote on every Get
+
+	rebalanceInterval time.Duration // Interval between the rebalancings of a ShardedCache, zero to keep an even split
+
+	shardMetrics bool             // Whether a ShardedCache records the metrics of every shard
+	shardLabel   string           // Shard label of the metrics of a shard, empty outside a ShardedCache with WithShardMetrics
+	namespaces   *namespaceLabels // Namespaces labeled in the metrics, nil without WithNamespaceMetrics
+
+	compareRate  float64        // Share of the reads of a DualWriteCache compared with its secondary cache, zero to compare none
+	compareEqual ValueEqualFunc // Compares the values read by a DualWriteCache, nil for reflect.DeepEqual
+
+	shadowState     bool          // Whether an ObservableCache maintains a shadow state for State
+	shadowStaleness time.Duration // Maximum staleness of the shadow state
+	valueRenderer   ValueRenderer // Renders the values of the state of an ObservableCache, nil for %v
+	maxValueLength  int           // Maximum length of the rendered values of an ObservableCache, zero for no limit
+}
+
+// Option configures a cache at construction time.
+type Option func(*options)
+
+// newOptions returns the default options with the given options applied.
+func newOptions(opts ...Option) options {
+	o := options{
+		clock:   systemClock{},
+		metrics: true,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithClock sets the clock used by the cache to decide when items expire.
+// Defaults to the system clock.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithMetricsName sets the value of the cache label of the metrics, e.g. to tell apart the caches of an application.
+// Defaults to the type of the cache: lru, lfu, safe_lru or sharded_lru.
+func WithMetricsName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithLegacyMetrics records the metrics of the cache under their legacy names too, with the lru_cache prefix
+// and the cache_type and operation labels, so the dashboards and alerts keep working while they migrate.
+// The legacy lru_cache_hits_total and lru_cache_misses_total count the sets too, the sets that updated an item as hits
+// and the ones that added one as misses, where cache_hits_total and cache_misses_total only count the gets.
+//
+// Deprecated: the legacy names will be removed, migrate to the names of SetMetricsNamespace.
+func WithLegacyMetrics() Option {
+	return func(o *options) {
+		o.legacyMetrics = true
+	}
+}
+
+// WithMetricsBatching accumulates the counters of the gets, sets and removals in atomics, and adds them to the
+// Prometheus metrics once every size operations, saving the cost of a Prometheus increment on the hot path.
+// The counters then lag by up to size operations, until the batch fills or FlushMetrics is called on the
+// SafeLRUCache, which its janitor and Close do too. The gauge of the items is still updated on every write.
+func WithMetricsBatching(size int) Option {
+	return func(o *options) {
+		o.metricsBatch = size
+	}
+}
+
+// WithSlowLog records the slow operations of the cache in the log: the encodings of the values written to
+// the slab storage, and the loads of a LoadingCache given the option. The same log can be shared by several caches.
+func WithSlowLog(log *SlowLog) Option {
+	return func(o *options) {
+		o.slowLog = log
+	}
+}
+
+// WithoutMetrics disables the Prometheus metrics of the cache, removing their cost from every operation.
+func WithoutMetrics() Option {
+	return func(o *options) {
+		o.metrics = false
+	}
+}
+
+// WithSlabStorage keeps the values of an LRUCache, and of the caches built on it, encoded in byte slabs
+// of the given size instead of on the heap, so millions of entries do not inflate the garbage collector
+// scan time. Every Get decodes the value, and values whose encoding is larger than a slab are rejected,
+// unless WithValueChunking splits them.
+// A nil codec leaves the values on the heap. Ignored by the LFUCache.
+func WithSlabStorage(codec Codec, slabSize int) Option {
+	return func(o *options) {
+		o.codec = codec
+		o.slabSize = slabSize
+	}
+}
+
+// WithEventListener registers a listener receiving every event emitted by the cache.
+// It can be used multiple times to register several listeners.
+func WithEventListener(listener EventListener) Option {
+	return func(o *options) {
+		o.listeners = append(o.listeners, listener)
+	}
+}
+
+// WithGhostList remembers the keys evicted during the last window operations, reads and writes,
+// and counts the misses for these keys in the cache_ghost_hits_total metric and GhostHits.
+// These are the misses a larger cache would have served, telling whether increasing the capacity would help.
+// It costs a mutex acquisition per operation and up to window remembered keys.
+func WithGhostList(window int) Option {
+	return func(o *options) {
+		o.ghostWindow = window
+	}
+}
+
+// WithTenantQuotas limits the items, or their total weight, of the tenants sharing a cache,
+// so one noisy tenant can't evict everyone else's items: writing an item of a tenant over its quota
+// evicts the least recently used items of that tenant, not of the others. The tenant of every key is
+// returned by tenantOf, and only the tenants in quotas are limited; their usage is reported by TenantUsage
+// and the cache_tenant_* metrics. The capacity of the cache still applies to every item.
+// In a ShardedCache, every shard enforces the quotas on its own keys. Ignored by the LFUCache.
+func WithTenantQuotas(tenantOf TenantFunc, quotas map[string]Quota) Option {
+	return func(o *options) {
+		o.tenantOf = tenantOf
+		o.quotas = quotas
+	}
+}
+
+// WithWeigher sets the weight of the items counted against the MaxWeight of the tenant quotas.
+// Defaults to 1 per item.
+func WithWeigher(weigh WeightFunc) Option {
+	return func(o *options) {
+		o.weigh = weigh
+	}
+}
+
+// WithHitRatioCurve estimates online the hit ratio an LRU cache would have at up to 20 capacities
+// up to maxCapacity, zero for twice the capacity, from the keys read and written, and reports it
+// in Stats().HitRatioCurve to guide capacity planning. Only the given share of the keys, between 0 and 1,
+// is tracked: 0.01 is usually accurate enough for millions of keys, tiny caches need a larger share.
+// For a ShardedCache, maxCapacity applies to every shard.
+func WithHitRatioCurve(rate float64, maxCapacity int) Option {
+	return func(o *options) {
+		o.curveRate = rate
+		o.curveMaxCapacity = maxCapacity
+	}
+}
+
+// WithPreviousValues makes the writes return the value they replace in SetResult.Previous,
+// and the value they evict in SetResult.EvictedValue.
+// It is off by default, as it decodes these values with the slab storage.
+func WithPreviousValues() Option {
+	return func(o *options) {
+		o.previousValues = true
+	}
+}
+
+// WithAdmission calls admit before every write of an LRUCache or LFUCache, and of the caches built on them,
+// with the weight of the item from WithWeigher, or 1. A refused write returns SetDenied without evicting
+// anything, and removes the current item of the key, if any, so the cache never serves an outdated value.
+// It plugs custom admission rules into the write path, e.g. never caching the values above a size.
+func WithAdmission(admit AdmitFunc) Option {
+	return func(o *options) {
+		o.admit = admit
+	}
+}
+
+// WithTTLPolicy sets the policy deciding the ttl of the items written by Set and by SetWithMetadata without ttl.
+// SetWithTTL takes precedence, its ttl is kept as is.
+func WithTTLPolicy(policy TTLPolicy) Option {
+	return func(o *options) {
+		o.ttlPolicy = policy
+	}
+}
+
+// WithKeyTransform rewrites the keys given to the cache before every operation taking a key
+// or a prefix, e.g. to normalize them with strings.ToLower or to namespace them with PrefixKeys,
+// without wrapping the cache. Only the outermost cache applies it: a SafeLRUCache or ShardedCache
+// created with it transforms the keys once, before passing them to the underlying caches.
+// The glob patterns and the keys reported by the events, snapshots and scans are not transformed.
+func WithKeyTransform(transform KeyTransform) Option {
+	return func(o *options) {
+		o.keyTransform = transform
+	}
+}
+
+// WithKeyHash sets the hash picking the shard of every key of a ShardedCache, instead of the
+// default FNV-1a, e.g. to keep related keys on the same shard. Ignored by the other caches.
+func WithKeyHash(hash KeyHash) Option {
+	return func(o *options) {
+		o.keyHash = hash
+	}
+}
+
+// WithEvictionRateLimit caps the evictions an LRUCache or LFUCache makes to make room for new items
+// to perSecond, with bursts of up to a second worth of evictions. Past the limit, the writes of new keys
+// into a full cache are rejected with SetThrottled instead, leaving the cache untouched, which protects
+// the downstream systems reacting to the evictions, e.g. write-behind flushes. Updates of existing keys,
+// expirations, Resize and the quota evictions are not limited. The rate is measured on the cache clock.
+// With a write buffer, the throttled writes are dropped silently.
+func WithEvictionRateLimit(perSecond int) Option {
+	return func(o *options) {
+		o.evictionRate = perSecond
+	}
+}
+
+// WithWatermarks gives an LRUCache, and the caches built on it, a soft capacity: once the items
+// cross high times the capacity, a SafeLRUCache evicts the least recently used items in the background,
+// in batches, down to low times the capacity, instead of evicting one item on every Set once full.
+// This amortizes the cost of the evictions and keeps it off the write path. The capacity remains a
+// hard limit, reached when the writes outpace the trimming. An LRUCache used on its own must call Trim.
+// Use Close to stop the goroutine. It is ignored unless 0 <= low < high <= 1.
+func WithWatermarks(low, high float64) Option {
+	return func(o *options) {
+		if 0 <= low && low < high && high <= 1 {
+			o.lowWatermark, o.highWatermark = low, high
+		}
+	}
+}
+
+// WithKeyIndex keeps the keys of an LRUCache, and of the caches built on it, sorted in a skip list,
+// so ScanPrefix, RemoveByPrefix and the patterns starting with a literal prefix only visit the matching
+// keys instead of every key, and return them in lexicographic order. Insertions and removals cost
+// an extra O(log n). Ignored by the LFUCache.
+func WithKeyIndex() Option {
+	return func(o *options) {
+		o.keyIndex = true
+	}
+}
+
+// WithIndex registers a secondary index on the values of an LRUCache, and of the caches built on it:
+// every value is indexed under the terms returned by extract, maintained on every Set and removal,
+// so the items can be found with Lookup or invalidated with RemoveByIndex by an attribute of the value,
+// e.g. every session of a user. It can be used several times with different names. Ignored by the LFUCache.
+func WithIndex(name string, extract IndexFunc) Option {
+	return func(o *options) {
+		if o.indexes == nil {
+			o.indexes = make(map[string]IndexFunc)
+		}
+		o.indexes[name] = extract
+	}
+}
+
+// WithExpiryBuckets groups the expirations of an LRUCache, and of the caches built on it, in buckets
+// of the given width instead of ordering every one of them, so the expiry index stays small and
+// millions of keys sharing similar TTLs are removed a bucket at a time. Expired items are still
+// never returned, but are only reclaimed once their whole bucket has ended. Ignored by the LFUCache.
+func WithExpiryBuckets(width time.Duration) Option {
+	return func(o *options) {
+		o.expiryBucketWidth = width
+	}
+}
+
+// WithJanitor makes a SafeLRUCache, or every shard of a ShardedCache, remove its expired items
+// at the given interval, instead of only when they are read or when room is needed, so they don't
+// hold memory until then. Use Close to stop the goroutine. Ignored by the non thread-safe caches.
+func WithJanitor(interval time.Duration) Option {
+	return func(o *options) {
+		o.janitorInterval = interval
+	}
+}
+
+// WithWriteBuffer makes the writes of a SafeLRUCache asynchronous: Set, SetWithTTL and Remove
+// enqueue into a buffer of the given size, applied in batches by a background goroutine.
+// This decouples writers from the cache bookkeeping, reducing tail latency under write-heavy load.
+// Reads may not observe a write until it is applied, use Flush to wait for the pending writes
+// and Close to stop the goroutine. Ignored by the non thread-safe caches.
+func WithWriteBuffer(size int) Option {
+	return func(o *options) {
+		o.writeBuffer = size
+	}
+}
+
+// WithAccessBuffer lets a SafeLRUCache serve Get under a shared read lock: accessed keys are recorded
+// in a buffer of the given size and promoted in batches before the next write, or when the buffer fills up.
+// Accesses happening while the buffer is full are dropped, so the usage order is approximate,
+// trading exact LRU for far better read throughput. Hit events are emitted when promotions are applied,
+// and misses emit no events. Only supported when the underlying cache is an LRUCache, ignored otherwise.
+func WithAccessBuffer(size int) Option {
+	return func(o *options) {
+		o.accessBuffer = size
+	}
+}
+
+// WithRebalancing makes a ShardedCache count the operations of each shard and redistribute
+// the total capacity between the shards at the given interval, so hot shards get more room
+// instead of evicting prematurely. Use Close to stop the goroutine. Ignored by the other caches.
+func WithRebalancing(interval time.Duration) Option {
+	return func(o *options) {
+		o.rebalanceInterval = interval
+	}
+}
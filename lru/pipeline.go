@@ -0,0 +1,211 @@
+package lru
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ValueStage transforms the encoded values of a ValuePipeline, e.g. to compress or encrypt them.
+// Revert must accept every output of Apply, and must not keep a reference to its input,
+// which may belong to the storage.
+type ValueStage interface {
+	Name() string                       // Value of the stage label of the metrics
+	Apply(data []byte) ([]byte, error)  // Transforms the data written to the cache
+	Revert(data []byte) ([]byte, error) // Restores the data read from the cache
+}
+
+// metricStageEncode is the stage label of the codec of a ValuePipeline.
+const metricStageEncode = "encode"
+
+const (
+	metricDirectionApply  = "apply" // Direction label of the stages of a ValuePipeline
+	metricDirectionRevert = "revert"
+)
+
+// ValuePipeline is a Codec encoding the values with a codec, then passing them through stages in order,
+// e.g. to compress then encrypt them, and reverting the stages in the opposite order on the way back.
+// Unless its name is empty, it records the time spent, the errors and the bytes of every stage, the codec
+// included as the encode stage, in the cache_pipeline_* metrics with the name as cache label, so comparing
+// the bytes of the compress and encode stages gives the compression ratio.
+// It is safe for concurrent use if its codec and stages are.
+type ValuePipeline struct {
+	codec   Codec
+	stages  []ValueStage
+	metrics []*stageMetrics // Metrics of the codec then of every stage, nil when disabled
+}
+
+var _ Codec = (*ValuePipeline)(nil) // Ensure ValuePipeline implements the Codec interface
+
+// NewValuePipeline creates a pipeline encoding the values with codec and passing them through the stages in order,
+// e.g. NewValuePipeline("sessions", StringCodec{}, NewCompressionStage(flate.BestSpeed), NewEncryptionStage(keys)).
+func NewValuePipeline(name string, codec Codec, stages ...ValueStage) *ValuePipeline {
+	pipeline := &ValuePipeline{codec: codec, stages: stages}
+	if name != "" {
+		pipeline.metrics = append(pipeline.metrics, newStageMetrics(name, metricStageEncode))
+		for _, stage := range stages {
+			pipeline.metrics = append(pipeline.metrics, newStageMetrics(name, stage.Name()))
+		}
+	}
+	return pipeline
+}
+
+// Encode encodes the value with the codec, then applies every stage in order.
+func (pipeline *ValuePipeline) Encode(value any) ([]byte, error) {
+	start := time.Now()
+	data, err := pipeline.codec.Encode(value)
+	pipeline.stageMetrics(0).record(metricDirectionApply, start, len(data), err)
+	if err != nil {
+		return nil, err
+	}
+	for i, stage := range pipeline.stages {
+		start = time.Now()
+		data, err = stage.Apply(data)
+		pipeline.stageMetrics(i+1).record(metricDirectionApply, start, len(data), err)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// Decode reverts every stage in the opposite order, then decodes the value with the codec.
+func (pipeline *ValuePipeline) Decode(data []byte) (any, error) {
+	for i := len(pipeline.stages) - 1; i >= 0; i-- {
+		start := time.Now()
+		reverted, err := pipeline.stages[i].Revert(data)
+		pipeline.stageMetrics(i+1).record(metricDirectionRevert, start, len(data), err)
+		if err != nil {
+			return nil, err
+		}
+		data = reverted
+	}
+	start := time.Now()
+	value, err := pipeline.codec.Decode(data)
+	pipeline.stageMetrics(0).record(metricDirectionRevert, start, len(data), err)
+	return value, err
+}
+
+// stageMetrics returns the metrics of the stage at the given index, the codec being the first, nil when disabled.
+func (pipeline *ValuePipeline) stageMetrics(index int) *stageMetrics {
+	if pipeline.metrics == nil {
+		return nil
+	}
+	return pipeline.metrics[index]
+}
+
+// CompressionStage compresses the values with DEFLATE.
+type CompressionStage struct {
+	level int
+}
+
+var _ ValueStage = (*CompressionStage)(nil) // Ensure CompressionStage implements the ValueStage interface
+
+// NewCompressionStage compresses the values at the given level of compress/flate, e.g. flate.BestSpeed.
+func NewCompressionStage(level int) *CompressionStage {
+	return &CompressionStage{level: level}
+}
+
+// Name returns compress.
+func (stage *CompressionStage) Name() string {
+	return "compress"
+}
+
+// Apply compresses the data.
+func (stage *CompressionStage) Apply(data []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	writer, err := flate.NewWriter(&compressed, stage.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// Revert decompresses the data.
+func (stage *CompressionStage) Revert(data []byte) ([]byte, error) {
+	return io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+}
+
+// EncryptionStage encrypts the values with AES-GCM like an EncryptedCodec, with keys supporting rotation.
+type EncryptionStage struct {
+	codec *EncryptedCodec
+}
+
+var _ ValueStage = (*EncryptionStage)(nil) // Ensure EncryptionStage implements the ValueStage interface
+
+// NewEncryptionStage encrypts the values with the keys of the provider.
+func NewEncryptionStage(keys KeyProvider) *EncryptionStage {
+	return &EncryptionStage{codec: NewEncryptedCodec(BytesCodec{}, keys)}
+}
+
+// Name returns encrypt.
+func (stage *EncryptionStage) Name() string {
+	return "encrypt"
+}
+
+// Apply encrypts the data with the current key.
+func (stage *EncryptionStage) Apply(data []byte) ([]byte, error) {
+	return stage.codec.Encode(data)
+}
+
+// Revert decrypts the data with the key it was encrypted with.
+func (stage *EncryptionStage) Revert(data []byte) ([]byte, error) {
+	value, err := stage.codec.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return value.([]byte), nil
+}
+
+// stageMetrics holds the metric children of a stage of a ValuePipeline. A nil *stageMetrics records nothing.
+type stageMetrics struct {
+	applyDurations  prometheus.Observer
+	revertDurations prometheus.Observer
+	applyErrors     prometheus.Counter
+	revertErrors    prometheus.Counter
+	applyBytes      prometheus.Counter
+	revertBytes     prometheus.Counter
+}
+
+// newStageMetrics resolves the metric children of the stage of the pipeline with the given name.
+func newStageMetrics(name string, stage string) *stageMetrics {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	vecs := currentMetrics
+	return &stageMetrics{
+		applyDurations:  vecs.stageDurations.WithLabelValues(name, stage, metricDirectionApply),
+		revertDurations: vecs.stageDurations.WithLabelValues(name, stage, metricDirectionRevert),
+		applyErrors:     vecs.stageErrors.WithLabelValues(name, stage, metricDirectionApply),
+		revertErrors:    vecs.stageErrors.WithLabelValues(name, stage, metricDirectionRevert),
+		applyBytes:      vecs.stageBytes.WithLabelValues(name, stage, metricDirectionApply),
+		revertBytes:     vecs.stageBytes.WithLabelValues(name, stage, metricDirectionRevert),
+	}
+}
+
+// record records a run of the stage in the given direction started at start, its error, and the size of
+// the data in the format of the stage: the bytes it output when applied, and the bytes it read when reverted.
+func (metrics *stageMetrics) record(direction string, start time.Time, size int, err error) {
+	if metrics == nil {
+		return
+	}
+	durations, errs, written := metrics.applyDurations, metrics.applyErrors, metrics.applyBytes
+	if direction == metricDirectionRevert {
+		durations, errs, written = metrics.revertDurations, metrics.revertErrors, metrics.revertBytes
+	}
+	durations.Observe(time.Since(start).Seconds())
+	if err != nil {
+		errs.Inc()
+		return
+	}
+	written.Add(float64(size))
+}
@@ -0,0 +1,95 @@
+package lru
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValuePipeline(t *testing.T) {
+	keys := StaticKeys{Current: 1, Keys: map[uint32][]byte{1: bytes.Repeat([]byte{1}, 32)}}
+	pipeline := NewValuePipeline("pipeline_roundtrip", StringCodec{}, NewCompressionStage(flate.BestSpeed), NewEncryptionStage(keys))
+	value := strings.Repeat("secret", 100)
+
+	data, err := pipeline.Encode(value)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+	assert.Less(t, len(data), len(value), "Expected the value to be compressed before being encrypted")
+	decoded, err := pipeline.Decode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, value, decoded)
+
+	bytesOf := func(stage, direction string) float64 {
+		return counterValue(currentMetrics.stageBytes.WithLabelValues("pipeline_roundtrip", stage, direction))
+	}
+	assert.Equal(t, float64(len(value)), bytesOf(metricStageEncode, metricDirectionApply))
+	assert.Equal(t, float64(len(data)), bytesOf("encrypt", metricDirectionApply))
+	assert.Equal(t, float64(len(data)), bytesOf("encrypt", metricDirectionRevert))
+	assert.Equal(t, bytesOf("compress", metricDirectionApply), bytesOf("compress", metricDirectionRevert))
+
+	data[len(data)-1] ^= 1
+	_, err = pipeline.Decode(data)
+	assert.ErrorIs(t, err, ErrDecryption)
+	assert.Equal(t, 1.0, counterValue(currentMetrics.stageErrors.WithLabelValues("pipeline_roundtrip", "encrypt", metricDirectionRevert)))
+	assert.Zero(t, counterValue(currentMetrics.stageErrors.WithLabelValues("pipeline_roundtrip", "compress", metricDirectionRevert)),
+		"Expected the stages after a failure to be skipped")
+}
+
+// reverseStage reverses the bytes of the values, to check the order of the stages.
+type reverseStage struct{}
+
+func (reverseStage) Name() string { return "reverse" }
+
+func (reverseStage) Apply(data []byte) ([]byte, error) {
+	reversed := make([]byte, len(data))
+	for i, b := range data {
+		reversed[len(data)-1-i] = b
+	}
+	return reversed, nil
+}
+
+func (stage reverseStage) Revert(data []byte) ([]byte, error) { return stage.Apply(data) }
+
+// suffixStage appends a suffix to the values, to check the order of the stages.
+type suffixStage struct{ suffix string }
+
+func (suffixStage) Name() string { return "suffix" }
+
+func (stage suffixStage) Apply(data []byte) ([]byte, error) {
+	return append(append([]byte(nil), data...), stage.suffix...), nil
+}
+
+func (stage suffixStage) Revert(data []byte) ([]byte, error) {
+	if !bytes.HasSuffix(data, []byte(stage.suffix)) {
+		return nil, errors.New("missing suffix")
+	}
+	return bytes.Clone(data[:len(data)-len(stage.suffix)]), nil
+}
+
+func TestValuePipelineStageOrder(t *testing.T) {
+	pipeline := NewValuePipeline("", StringCodec{}, suffixStage{"!"}, reverseStage{})
+	data, err := pipeline.Encode("abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "!cba", string(data))
+	value, err := pipeline.Decode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", value)
+
+	_, err = pipeline.Encode(42)
+	assert.ErrorIs(t, err, ErrUnsupportedValue)
+}
+
+func TestValuePipelineSlabStorage(t *testing.T) {
+	pipeline := NewValuePipeline("", StringCodec{}, NewCompressionStage(flate.DefaultCompression))
+	cache := NewLRUCache(2, WithoutMetrics(), WithSlabStorage(pipeline, 128))
+	value := strings.Repeat("a", 1000) // Larger than a slab until compressed
+	assert.Equal(t, SetAdded, cache.Set("key1", value).Status)
+
+	stored, found := cache.Get("key1")
+	assert.True(t, found)
+	assert.Equal(t, value, stored)
+}
@@ -0,0 +1,255 @@
+package lru
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// opKind is the kind of a cache operation generated by the property tests.
+type opKind byte
+
+const (
+	opGet opKind = iota
+	opSet
+	opSetWithTTL
+	opRemove
+	opAdvance
+	opKinds // Number of kinds
+)
+
+// op is a cache operation generated by the property tests.
+type op struct {
+	kind  opKind
+	key   string
+	value int
+	ttl   time.Duration // The ttl of opSetWithTTL, or the duration of opAdvance
+}
+
+func (o op) String() string {
+	return fmt.Sprintf("%d(%s, %d, %v)", o.kind, o.key, o.value, o.ttl)
+}
+
+// opFromBytes decodes an operation from three bytes, so the fuzzer can explore the sequences.
+// The ttls and clock advances are whole seconds from zero to three, so items expire often.
+func opFromBytes(kind, key, arg byte) op {
+	return op{
+		kind:  opKind(kind % byte(opKinds)),
+		key:   fmt.Sprintf("key%d", key%8),
+		value: int(arg),
+		ttl:   time.Duration(arg%4) * time.Second,
+	}
+}
+
+// randomOps returns n random operations.
+func randomOps(random *rand.Rand, n int) []op {
+	ops := make([]op, n)
+	for i := range ops {
+		ops[i] = opFromBytes(byte(random.IntN(256)), byte(random.IntN(256)), byte(random.IntN(256)))
+	}
+	return ops
+}
+
+// modelItem is an item of lruModel.
+type modelItem struct {
+	key       string
+	value     int
+	expiresAt time.Time // Zero if the item does not expire
+}
+
+func (item modelItem) expired(now time.Time) bool {
+	return !item.expiresAt.IsZero() && !now.Before(item.expiresAt)
+}
+
+// lruModel is a naive reference implementation of LRUCache, most recently used item first.
+type lruModel struct {
+	capacity int
+	items    []modelItem
+}
+
+func (model *lruModel) find(key string) int {
+	return slices.IndexFunc(model.items, func(item modelItem) bool { return item.key == key })
+}
+
+func (model *lruModel) get(key string, now time.Time) (int, bool) {
+	i := model.find(key)
+	if i < 0 {
+		return 0, false
+	}
+	item := model.items[i]
+	model.items = slices.Delete(model.items, i, i+1)
+	if item.expired(now) {
+		return 0, false
+	}
+	model.items = slices.Insert(model.items, 0, item)
+	return item.value, true
+}
+
+func (model *lruModel) set(item modelItem, now time.Time) {
+	if i := model.find(item.key); i >= 0 {
+		model.items = slices.Delete(model.items, i, i+1)
+	} else if len(model.items) >= model.capacity {
+		model.items = slices.DeleteFunc(model.items, func(item modelItem) bool { return item.expired(now) })
+		if len(model.items) >= model.capacity {
+			model.items = model.items[:len(model.items)-1]
+		}
+	}
+	model.items = slices.Insert(model.items, 0, item)
+}
+
+func (model *lruModel) remove(key string) {
+	if i := model.find(key); i >= 0 {
+		model.items = slices.Delete(model.items, i, i+1)
+	}
+}
+
+// liveKeys returns the keys of the unexpired items, most recently used first.
+func (model *lruModel) liveKeys(now time.Time) []string {
+	var keys []string
+	for _, item := range model.items {
+		if !item.expired(now) {
+			keys = append(keys, item.key)
+		}
+	}
+	return keys
+}
+
+// checkPolicy applies the operations to the cache, checking the invariants every policy must hold:
+// the length never exceeds the capacity, and a read never returns an expired nor an outdated value.
+// With model, the cache must also behave exactly like the reference LRU implementation.
+func checkPolicy(t *testing.T, cache Cache, clock *ManualClock, ops []op, model *lruModel) {
+	t.Helper()
+
+	latest := make(map[string]modelItem) // The last item written per key
+	for i, o := range ops {
+		now := clock.Now()
+		switch o.kind {
+		case opGet:
+			value, found := cache.Get(o.key)
+			if found {
+				item, written := latest[o.key]
+				if !assert.True(t, written, "op %d %v: found a key never written", i, o) ||
+					!assert.False(t, item.expired(now), "op %d %v: returned an expired item", i, o) ||
+					!assert.Equal(t, item.value, value, "op %d %v: returned an outdated value", i, o) {
+					return
+				}
+			}
+			if model != nil {
+				expected, expectedFound := model.get(o.key, now)
+				if !assert.Equal(t, expectedFound, found, "op %d %v", i, o) || (found && !assert.Equal(t, expected, value, "op %d %v", i, o)) {
+					return
+				}
+			}
+		case opSet:
+			cache.Set(o.key, o.value)
+			latest[o.key] = modelItem{key: o.key, value: o.value}
+			if model != nil {
+				model.set(latest[o.key], now)
+			}
+		case opSetWithTTL:
+			cache.SetWithTTL(o.key, o.value, o.ttl)
+			if o.ttl <= 0 {
+				delete(latest, o.key)
+				if model != nil {
+					model.remove(o.key)
+				}
+				break
+			}
+			latest[o.key] = modelItem{key: o.key, value: o.value, expiresAt: now.Add(o.ttl)}
+			if model != nil {
+				model.set(latest[o.key], now)
+			}
+		case opRemove:
+			cache.Remove(o.key)
+			delete(latest, o.key)
+			if model != nil {
+				model.remove(o.key)
+			}
+		case opAdvance:
+			clock.Advance(o.ttl)
+		}
+
+		if !assert.LessOrEqual(t, cache.Len(), cache.Capacity(), "op %d %v: length above the capacity", i, o) {
+			return
+		}
+		if snapshotter, ok := cache.(Snapshotter); ok {
+			for _, key := range snapshotKeys(snapshotter.Snapshot()) {
+				if !assert.False(t, latest[key].expired(clock.Now()), "op %d %v: snapshot holds the expired key %s", i, o, key) {
+					return
+				}
+			}
+		}
+		if model != nil {
+			if !assert.Equal(t, len(model.items), cache.Len(), "op %d %v", i, o) ||
+				!assert.Equal(t, model.liveKeys(clock.Now()), snapshotKeys(cache.(Snapshotter).Snapshot()), "op %d %v: usage order", i, o) {
+				return
+			}
+		}
+	}
+}
+
+// policies returns every cache implementation to check, on the given clock, and whether it must follow the LRU model.
+func policies(capacity int, clock *ManualClock) map[string]struct {
+	cache Cache
+	lru   bool
+} {
+	opts := []Option{WithClock(clock), WithoutMetrics()}
+	return map[string]struct {
+		cache Cache
+		lru   bool
+	}{
+		"lru":            {cache: NewLRUCache(capacity, opts...), lru: true},
+		"lru key index":  {cache: NewLRUCache(capacity, append(opts, WithKeyIndex(), WithExpiryBuckets(time.Second))...)},
+		"lfu":            {cache: NewLFUCache(capacity, opts...)},
+		"safe":           {cache: NewSafeLRUCache(capacity, opts...), lru: true},
+		"safe buffered":  {cache: NewSafeLRUCache(capacity, append(opts, WithAccessBuffer(4))...)},
+		"sharded":        {cache: NewShardedCache(2, 2*capacity, opts...)},
+		"lru ghost list": {cache: NewLRUCache(capacity, append(opts, WithGhostList(8))...), lru: true},
+		"slru":           {cache: NewSafeLRUCache(capacity, append(opts, WithGhostReadmission(8), WithAccessBuffer(4))...)},
+	}
+}
+
+func TestPolicyProperties(t *testing.T) {
+	for seed := range uint64(20) {
+		random := rand.New(rand.NewPCG(seed, seed))
+		capacity := 1 + random.IntN(5)
+		ops := randomOps(random, 300)
+
+		clock := NewManualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		for name, policy := range policies(capacity, clock) {
+			t.Run(fmt.Sprintf("%s/seed %d", name, seed), func(t *testing.T) {
+				var model *lruModel
+				if policy.lru {
+					model = &lruModel{capacity: capacity}
+				}
+				checkPolicy(t, policy.cache, clock, ops, model)
+			})
+		}
+	}
+}
+
+func FuzzPolicies(f *testing.F) {
+	f.Add(byte(2), []byte{1, 0, 1, 1, 1, 2, 1, 2, 3, 0, 0, 0})
+	f.Add(byte(1), []byte{2, 0, 2, 4, 0, 1, 0, 0, 0, 2, 1, 3, 0, 1, 0})
+	f.Add(byte(3), []byte{1, 0, 0, 1, 1, 0, 1, 2, 0, 0, 0, 0, 1, 3, 0, 3, 1, 0})
+
+	f.Fuzz(func(t *testing.T, capacity byte, data []byte) {
+		ops := make([]op, 0, len(data)/3)
+		for i := 0; i+2 < len(data); i += 3 {
+			ops = append(ops, opFromBytes(data[i], data[i+1], data[i+2]))
+		}
+
+		clock := NewManualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		for _, policy := range policies(1+int(capacity%8), clock) {
+			var model *lruModel
+			if policy.lru {
+				model = &lruModel{capacity: 1 + int(capacity%8)}
+			}
+			checkPolicy(t, policy.cache, clock, ops, model)
+		}
+	})
+}
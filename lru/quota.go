@@ -0,0 +1,232 @@
+package lru
+
+import (
+	"container/list"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TenantFunc returns the tenant, or namespace, owning a key, e.g. the part of the key before its first ':'.
+type TenantFunc func(key string) string
+
+// WeightFunc returns the weight of an item counted against the MaxWeight of its tenant, e.g. its size in bytes.
+type WeightFunc func(key string, value any) int64
+
+// Quota limits the share of a cache used by a tenant.
+type Quota struct {
+	MaxItems  int   // Most items of the tenant, zero for no limit
+	MaxWeight int64 // Most total weight of the items of the tenant, zero for no limit
+}
+
+// tenantQuotas tracks the items of the tenants with a quota, in their own least recently used order,
+// so a tenant over its quota evicts its own items instead of the ones of the other tenants.
+type tenantQuotas struct {
+	tenantOf TenantFunc
+	weigh    WeightFunc              // Nil to weigh every item 1
+	tenants  map[string]*tenantUsage // Usage of the tenants with a quota
+	items    map[string]tenantItem   // Items of the tenants with a quota by key
+}
+
+// tenantUsage is the usage of a tenant with a quota.
+type tenantUsage struct {
+	name    string
+	quota   Quota
+	order   *list.List // Keys of the items of the tenant, most recently used first
+	weight  int64      // Total weight of the items of the tenant
+	metrics *tenantMetrics
+}
+
+// tenantItem is an item of a tenant with a quota.
+type tenantItem struct {
+	tenant *tenantUsage
+	elem   *list.Element // Element of the key in the order of the tenant
+	weight int64
+}
+
+func newTenantQuotas(tenantOf TenantFunc, weigh WeightFunc, quotas map[string]Quota) *tenantQuotas {
+	tenants := make(map[string]*tenantUsage, len(quotas))
+	for name, quota := range quotas {
+		tenants[name] = &tenantUsage{name: name, quota: quota, order: list.New()}
+	}
+	return &tenantQuotas{tenantOf: tenantOf, weigh: weigh, tenants: tenants, items: make(map[string]tenantItem)}
+}
+
+// setName resolves the metric children of the tenants for the cache with the given name, unless disabled,
+// and of their legacy metrics if enabled.
+func (quotas *tenantQuotas) setName(name string, enabled, legacy bool) {
+	for _, tenant := range quotas.tenants {
+		tenant.metrics = nil
+		if enabled {
+			tenant.metrics = newTenantMetrics(name, tenant.name, legacy)
+		}
+	}
+}
+
+// weight returns the weight of an item.
+func (quotas *tenantQuotas) weight(key string, value any) int64 {
+	if quotas.weigh == nil {
+		return 1
+	}
+	return quotas.weigh(key, value)
+}
+
+// set records that the item was added or updated, and returns its tenant if it has a quota.
+func (quotas *tenantQuotas) set(key string, value any) *tenantUsage {
+	weight := quotas.weight(key, value)
+	if item, found := quotas.items[key]; found {
+		item.tenant.weight += weight - item.weight
+		item.weight = weight
+		quotas.items[key] = item
+		item.tenant.order.MoveToFront(item.elem)
+		item.tenant.metrics.usage(item.tenant)
+		return item.tenant
+	}
+
+	tenant, found := quotas.tenants[quotas.tenantOf(key)]
+	if !found {
+		return nil
+	}
+	tenant.weight += weight
+	quotas.items[key] = tenantItem{tenant: tenant, elem: tenant.order.PushFront(key), weight: weight}
+	tenant.metrics.usage(tenant)
+	return tenant
+}
+
+// touch marks the item as recently used in the order of its tenant.
+func (quotas *tenantQuotas) touch(key string) {
+	if item, found := quotas.items[key]; found {
+		item.tenant.order.MoveToFront(item.elem)
+	}
+}
+
+// remove forgets a removed item.
+func (quotas *tenantQuotas) remove(key string) {
+	if item, found := quotas.items[key]; found {
+		item.tenant.weight -= item.weight
+		item.tenant.order.Remove(item.elem)
+		delete(quotas.items, key)
+		item.tenant.metrics.usage(item.tenant)
+	}
+}
+
+// victim returns the least recently used key of the tenant if it exceeds its quota,
+// leaving at least the given key, the one just written.
+func (quotas *tenantQuotas) victim(tenant *tenantUsage, keep string) (string, bool) {
+	over := (tenant.quota.MaxItems > 0 && tenant.order.Len() > tenant.quota.MaxItems) ||
+		(tenant.quota.MaxWeight > 0 && tenant.weight > tenant.quota.MaxWeight)
+	if !over || tenant.order.Len() <= 1 {
+		return "", false
+	}
+	oldest := tenant.order.Back()
+	if oldest.Value.(string) == keep {
+		oldest = oldest.Prev()
+	}
+	return oldest.Value.(string), true
+}
+
+// usage returns the items and total weight of the tenant, zero if it has no quota.
+func (quotas *tenantQuotas) usage(name string) (items int, weight int64) {
+	if tenant, found := quotas.tenants[name]; found {
+		return tenant.order.Len(), tenant.weight
+	}
+	return 0, 0
+}
+
+// enforceQuota evicts the least recently used items of the tenant of the key written,
+// until it is within its quota.
+func (cache *LRUCache) enforceQuota(key string, value any) {
+	tenant := cache.quotas.set(key, value)
+	if tenant == nil {
+		return
+	}
+	for {
+		victim, found := cache.quotas.victim(tenant, key)
+		if !found {
+			return
+		}
+		cache.remove(victim, metricReasonQuota)
+		tenant.metrics.evicted()
+	}
+}
+
+// TenantUsage returns the items and total weight of the tenant, zero if it has no quota.
+func (cache *LRUCache) TenantUsage(tenant string) (items int, weight int64) {
+	if cache.quotas == nil {
+		return 0, 0
+	}
+	return cache.quotas.usage(tenant)
+}
+
+// tenantUsageReporter is implemented by the caches enforcing tenant quotas.
+type tenantUsageReporter interface {
+	TenantUsage(tenant string) (items int, weight int64)
+}
+
+// TenantUsage returns the items and total weight of the tenant, zero if it has no quota.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) TenantUsage(tenant string) (items int, weight int64) {
+	safeCache.readLock()
+	defer safeCache.mutex.RUnlock()
+
+	if reporter, ok := safeCache.cache.(tenantUsageReporter); ok {
+		return reporter.TenantUsage(tenant)
+	}
+	return 0, 0
+}
+
+// TenantUsage returns the items and total weight of the tenant in every shard.
+// Every shard enforces the quotas on its own keys, see WithTenantQuotas.
+// It is thread-safe.
+func (sharded *ShardedCache) TenantUsage(tenant string) (items int, weight int64) {
+	for _, shard := range sharded.shards {
+		shardItems, shardWeight := shard.TenantUsage(tenant)
+		items += shardItems
+		weight += shardWeight
+	}
+	return items, weight
+}
+
+// tenantMetrics holds the metric children of a tenant with a quota. A nil *tenantMetrics records nothing.
+type tenantMetrics struct {
+	items     prometheus.Gauge
+	weight    prometheus.Gauge
+	evictions prometheus.Counter
+	legacy    *tenantMetrics // Records the metrics under their legacy names too, nil without WithLegacyMetrics
+}
+
+func newTenantMetrics(name string, tenant string, legacy bool) *tenantMetrics {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	metrics := currentMetrics.tenantMetrics(name, tenant)
+	if legacy {
+		metrics.legacy = legacyMetrics.tenantMetrics(name, tenant)
+	}
+	return metrics
+}
+
+// tenantMetrics resolves the metric children of the tenant of the cache with the given name.
+func (vecs *metricVecs) tenantMetrics(name string, tenant string) *tenantMetrics {
+	return &tenantMetrics{
+		items:     vecs.tenantItems.WithLabelValues(name, tenant),
+		weight:    vecs.tenantWeight.WithLabelValues(name, tenant),
+		evictions: vecs.tenantEvictions.WithLabelValues(name, tenant),
+	}
+}
+
+// usage records the items and total weight of the tenant.
+func (metrics *tenantMetrics) usage(tenant *tenantUsage) {
+	if metrics != nil {
+		metrics.items.Set(float64(tenant.order.Len()))
+		metrics.weight.Set(float64(tenant.weight))
+		metrics.legacy.usage(tenant)
+	}
+}
+
+// evicted records an item of the tenant evicted to enforce its quota.
+func (metrics *tenantMetrics) evicted() {
+	if metrics != nil {
+		metrics.evictions.Inc()
+		metrics.legacy.evicted()
+	}
+}
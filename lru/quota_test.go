@@ -0,0 +1,78 @@
+package lru
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tenantPrefix returns the part of the key before its first ':'.
+func tenantPrefix(key string) string {
+	tenant, _, _ := strings.Cut(key, ":")
+	return tenant
+}
+
+func TestTenantQuotaItems(t *testing.T) {
+	cache := NewLRUCache(10, WithTenantQuotas(tenantPrefix, map[string]Quota{"noisy": {MaxItems: 3}}))
+	cache.Set("quiet:1", "value")
+	cache.Set("quiet:2", "value")
+	for i := range 20 {
+		cache.Set(fmt.Sprint("noisy:", i), "value")
+	}
+	cache.Get("noisy:17") // Most recently used of the tenant
+
+	cache.Set("noisy:20", "value")
+	assert.Equal(t, 5, cache.Len())
+	for _, key := range []string{"quiet:1", "quiet:2", "noisy:17", "noisy:19", "noisy:20"} {
+		_, found := cache.Get(key)
+		assert.True(t, found, key)
+	}
+	items, _ := cache.TenantUsage("noisy")
+	assert.Equal(t, 3, items)
+	items, _ = cache.TenantUsage("quiet") // No quota
+	assert.Zero(t, items)
+}
+
+func TestTenantQuotaWeight(t *testing.T) {
+	weigh := func(key string, value any) int64 { return int64(len(value.(string))) }
+	cache := NewLRUCache(10, WithTenantQuotas(tenantPrefix, map[string]Quota{"a": {MaxWeight: 10}}), WithWeigher(weigh))
+
+	cache.Set("a:1", "xxxx")
+	cache.Set("a:2", "xxxx")
+	_, weight := cache.TenantUsage("a")
+	assert.Equal(t, int64(8), weight)
+
+	cache.Set("a:2", "xxxxxxxx") // Growing an item evicts the others
+	items, weight := cache.TenantUsage("a")
+	assert.Equal(t, 1, items)
+	assert.Equal(t, int64(8), weight)
+
+	cache.Set("a:3", strings.Repeat("x", 20)) // Kept alone even if larger than the quota
+	_, found := cache.Get("a:3")
+	assert.True(t, found)
+	items, weight = cache.TenantUsage("a")
+	assert.Equal(t, 1, items)
+	assert.Equal(t, int64(20), weight)
+
+	cache.Remove("a:3")
+	items, weight = cache.TenantUsage("a")
+	assert.Zero(t, items)
+	assert.Zero(t, weight)
+}
+
+func TestTenantQuotaShardedAndSafe(t *testing.T) {
+	quotas := WithTenantQuotas(tenantPrefix, map[string]Quota{"noisy": {MaxItems: 2}})
+	safe := NewSafeLRUCache(10, quotas)
+	sharded := NewShardedCache(2, 20, quotas)
+	for i := range 10 {
+		safe.Set(fmt.Sprint("noisy:", i), i)
+		sharded.Set(fmt.Sprint("noisy:", i), i)
+	}
+	items, _ := safe.TenantUsage("noisy")
+	assert.Equal(t, 2, items)
+	items, _ = sharded.TenantUsage("noisy")
+	assert.LessOrEqual(t, items, 4) // Up to 2 per shard
+	assert.Equal(t, items, sharded.Len())
+}
@@ -0,0 +1,121 @@
+package lru
+
+import (
+	"container/list"
+)
+
+// protectedShare is the share of the capacity in percent held by the protected segment with WithGhostReadmission.
+const protectedShare = 80
+
+const (
+	policySLRU          = "slru"      // Policy reported by the observable state of a segmented LRUCache
+	segmentProtected    = "protected" // Segment of the items read again or readmitted
+	segmentProbationary = "probation" // Segment of the new items, evicted first
+)
+
+// WithGhostReadmission turns an LRUCache, and the caches built on it, into a segmented LRU remembering the keys,
+// without their values, evicted during the last window operations, reads and writes. The new items enter
+// a probationary segment, evicted first, and move to the protected segment, holding up to 80% of the capacity,
+// when read again. A key written again while remembered goes straight to the protected segment,
+// which stops the cycles of the same keys being evicted and written again. Ignored by the other policies.
+func WithGhostReadmission(window int) Option {
+	return func(o *options) {
+		o.readmissionWindow = window
+	}
+}
+
+// segments splits the usage order of an LRUCache in a protected segment, at the front, and a probationary one.
+type segments struct {
+	readmit   *ghostList    // Keys evicted recently, written again into the protected segment
+	probation *list.Element // Most recently used element of the probationary segment, nil if it is empty
+	protected int           // Number of elements in the protected segment
+}
+
+func newSegments(window int) *segments {
+	return &segments{readmit: newGhostList(window)}
+}
+
+// access counts an operation for the remembered keys. It does nothing without segments.
+func (segments *segments) access() {
+	if segments != nil {
+		segments.readmit.access()
+	}
+}
+
+// insert adds a new entry to the usage order: in front of the probationary segment, or in front of the protected one
+// if its key was evicted recently.
+func (cache *LRUCache) insert(ent *entry) *list.Element {
+	if cache.segments == nil {
+		return cache.usageOrder.PushFront(ent)
+	}
+	if cache.segments.readmit.take(ent.key) {
+		ent.protected = true
+		cache.segments.protected++
+		elem := cache.usageOrder.PushFront(ent)
+		cache.demote()
+		return elem
+	}
+	var elem *list.Element
+	if cache.segments.probation != nil {
+		elem = cache.usageOrder.InsertBefore(ent, cache.segments.probation)
+	} else {
+		elem = cache.usageOrder.PushBack(ent)
+	}
+	cache.segments.probation = elem
+	return elem
+}
+
+// touch moves an element read or written to the front of the usage order, which is the protected segment if any.
+func (cache *LRUCache) touch(elem *list.Element) {
+	if cache.segments != nil && !elem.Value.(*entry).protected {
+		if cache.segments.probation == elem {
+			cache.segments.probation = elem.Next()
+		}
+		elem.Value.(*entry).protected = true
+		cache.segments.protected++
+		defer cache.demote()
+	}
+	cache.usageOrder.MoveToFront(elem)
+}
+
+// unlink updates the segments before the element leaves the usage order, remembering its key if it was evicted.
+func (cache *LRUCache) unlink(elem *list.Element, reason string) {
+	if cache.segments == nil {
+		return
+	}
+	if cache.segments.probation == elem {
+		cache.segments.probation = elem.Next()
+	}
+	if elem.Value.(*entry).protected {
+		cache.segments.protected--
+	}
+	if reason == metricReasonEvicted {
+		cache.segments.readmit.add(elem.Value.(*entry).key)
+	}
+}
+
+// demote moves the least recently used elements of the protected segment to the probationary one,
+// until the protected segment fits in its share of the capacity. The elements keep their place in the usage order.
+func (cache *LRUCache) demote() {
+	for cache.segments.protected > max(cache.capacity*protectedShare/100, 1) {
+		last := cache.usageOrder.Back()
+		if cache.segments.probation != nil {
+			last = cache.segments.probation.Prev()
+		}
+		last.Value.(*entry).protected = false
+		cache.segments.protected--
+		cache.segments.probation = last
+	}
+}
+
+// segmentOf returns the segment of the entry for the observable state, empty without segments.
+func (cache *LRUCache) segmentOf(ent *entry) string {
+	switch {
+	case cache.segments == nil:
+		return ""
+	case ent.protected:
+		return segmentProtected
+	default:
+		return segmentProbationary
+	}
+}
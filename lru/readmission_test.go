@@ -0,0 +1,64 @@
+package lru
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGhostReadmissionStopsChurn(t *testing.T) {
+	for _, readmission := range []bool{false, true} {
+		opts := []Option{WithoutMetrics()}
+		if readmission {
+			opts = append(opts, WithGhostReadmission(100))
+		}
+		cache := NewLRUCache(4, opts...)
+		for i := range 5 {
+			cache.Set(fmt.Sprint("key", i), i) // Evicts key0
+		}
+		cache.Set("key0", 0) // Written again shortly after its eviction
+		for i := range 4 {
+			cache.Set(fmt.Sprint("new", i), i)
+		}
+
+		_, found := cache.Peek("key0")
+		assert.Equal(t, readmission, found, "Expected key0 to be kept only when readmitted to the protected segment")
+	}
+}
+
+func TestGhostReadmissionSegments(t *testing.T) {
+	observable := NewObservableCacheFrom(NewSafe(NewLRUCache(5, WithGhostReadmission(100), WithoutMetrics())))
+	cache := observable.Cache
+	for i := range 5 {
+		cache.Set(fmt.Sprint("key", i), i)
+	}
+	for i := range 5 {
+		cache.Get(fmt.Sprint("key", i)) // The last promotion demotes key0, as 4 items fit in the protected segment
+	}
+
+	state := observable.State()
+	assert.Equal(t, policySLRU, state.Policy)
+	assert.Equal(t, "key0", state.Items[4].Key)
+	assert.Equal(t, segmentProbationary, state.Items[4].Segment)
+	assert.Equal(t, segmentProtected, state.Items[0].Segment)
+
+	assert.Equal(t, "key0", cache.Set("new", 0).EvictedKey)
+	assert.Equal(t, "new", cache.Set("other", 0).EvictedKey, "Expected the new items to be evicted before the protected ones")
+
+	cache.Resize(2)
+	assert.Equal(t, []string{"key4", "key3"}, rangeKeys(cache))
+}
+
+func TestGhostReadmissionWindow(t *testing.T) {
+	cache := NewLRUCache(2, WithGhostReadmission(2), WithoutMetrics())
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Set("c", 3) // Evicts a
+	cache.Get("b")
+	cache.Get("c")
+	cache.Get("b") // a is forgotten after 2 operations
+
+	cache.Set("a", 1)
+	assert.Equal(t, segmentProbationary, cache.segmentOf(cache.items["a"].Value.(*entry)))
+}
@@ -0,0 +1,394 @@
+package lru
+
+import (
+	"cmp"
+	"container/list"
+	"time"
+)
+
+const (
+	s3fifoSmallShare   = 10 // Share of the capacity in percent given to the small queue
+	s3fifoMaxFrequency = 3  // Saturation of the access counters, two bits in the original design
+)
+
+type s3fifoEntry struct {
+	entry
+	frequency int           // Accesses since the insertion, or since the last reinsertion in the main queue, up to 3
+	element   *list.Element // Element of the entry in its queue
+	main      bool          // Whether the entry is in the main queue, or else in the small one
+}
+
+// S3FIFOCache evicts its items with the S3-FIFO policy: the new items enter a small FIFO queue, holding 10% of the
+// capacity, and only move to the main FIFO queue if they are read again before reaching its end, while the other ones
+// are evicted and remembered in a ghost FIFO queue. A key found in the ghost queue goes straight to the main queue.
+// The main queue reinserts the items read since their insertion instead of evicting them, decrementing their counter.
+// Most items read once, e.g. by a scan, so leave quickly, and the hits only increment a counter instead of moving
+// the item like LRU does, which keeps the reads cheap.
+type S3FIFOCache struct {
+	capacity  int                      // The capacity of this cache
+	items     map[string]*s3fifoEntry  // Provides easy access to the cached entries
+	small     *list.List               // Holds the entries on probation, the newest first
+	main      *list.List               // Holds the entries read again while on probation, or found in the ghost queue, the newest first
+	ghost     *list.List               // Holds the keys evicted from the small queue, the newest first
+	ghostKeys map[string]*list.Element // Provides easy access to the ghost keys
+	expiries  expiryIndex              // Holds the entries with an expiration, the soonest to expire first
+	metrics   *cacheMetrics            // Metrics of the cache, nil when disabled
+	clock     Clock                    // Source of the current time, used for expiration
+	ghosts    *ghostList               // Keys evicted recently, nil without WithGhostList
+	curve     *curveEstimator          // Estimates the hit ratio curve, nil without WithHitRatioCurve
+	counters  statsCounters            // Counters reported by Stats
+	previous  bool                     // Whether the writes return the value they replace, see WithPreviousValues
+	evictions *evictionLimiter         // Caps the evictions per second, nil without WithEvictionRateLimit
+	transform KeyTransform             // Rewrites the keys before every operation, nil without WithKeyTransform
+	ttlPolicy TTLPolicy                // Decides the ttl of the items written by Set, nil without WithTTLPolicy
+	admission *admission               // Decides whether the items are written, nil without WithAdmission
+	listeners                          // Receive the events emitted by the cache
+}
+
+var _ Cache = (*S3FIFOCache)(nil) // Ensure S3FIFOCache implements the Cache interface
+
+// NewS3FIFOCache creates an S3-FIFO cache.
+func NewS3FIFOCache(capacity int, opts ...Option) *S3FIFOCache {
+	o := newOptions(opts...)
+	cache := &S3FIFOCache{
+		capacity:  capacity,
+		items:     make(map[string]*s3fifoEntry),
+		small:     list.New(),
+		main:      list.New(),
+		ghost:     list.New(),
+		ghostKeys: make(map[string]*list.Element),
+		clock:     o.clock,
+		previous:  o.previousValues,
+		listeners: o.listeners,
+		transform: o.keyTransform,
+		ttlPolicy: o.ttlPolicy,
+		admission: newAdmission(o),
+	}
+	cache.counters.capacity.Store(int64(capacity))
+	cache.counters.clock = o.clock
+	if o.metrics {
+		cache.metrics = newCacheMetrics(cmp.Or(o.name, metricCacheTypeS3FIFO), o.legacyMetrics, o.metricsBatch)
+	}
+	if o.ghostWindow > 0 {
+		cache.ghosts = newGhostList(o.ghostWindow)
+	}
+	if o.curveRate > 0 {
+		cache.curve = newCurveEstimator(o.curveRate, cmp.Or(o.curveMaxCapacity, 2*capacity))
+	}
+	if o.evictionRate > 0 {
+		cache.evictions = newEvictionLimiter(o.evictionRate, o.clock.Now())
+	}
+	return cache
+}
+
+// Get retrieves an item from the cache by its key, counting the access without moving the item.
+// It returns the value and a boolean indicating whether the item was found.
+// If the ttl has expired, the item will be removed and not found.
+func (cache *S3FIFOCache) Get(key string) (value any, found bool) {
+	key = cache.transform.apply(key)
+	cache.ghosts.access()
+	cache.curve.access(key)
+	if ent, found := cache.items[key]; found {
+		if ent.hasExpired(cache.clock.Now()) {
+			cache.remove(key, metricReasonExpired) // Remove the item if it has expired
+			cache.counters.misses.Add(1)
+			cache.emit(EventMiss, key, nil, "")
+			return nil, false // Item expired and removed
+		}
+
+		ent.frequency = min(ent.frequency+1, s3fifoMaxFrequency)
+
+		cache.metrics.getHit() // Increment cache hit metric
+		cache.counters.hits.Add(1)
+		cache.emit(EventHit, key, &ent.entry, "")
+		return ent.value, true
+	}
+	cache.metrics.getMiss() // Increment cache miss metric
+	cache.counters.misses.Add(1)
+	if cache.ghosts.miss(key) {
+		cache.metrics.ghostHit()
+	}
+	cache.emit(EventMiss, key, nil, "")
+	return nil, false // Item not found
+}
+
+// GhostHits returns the number of misses for keys evicted recently, which a larger cache would have served.
+// It is always zero without WithGhostList.
+func (cache *S3FIFOCache) GhostHits() uint64 {
+	return cache.ghosts.hitCount()
+}
+
+// smallCapacity returns the number of items the small queue holds before it evicts.
+func (cache *S3FIFOCache) smallCapacity() int {
+	return max(cache.capacity*s3fifoSmallShare/100, 1)
+}
+
+// victim returns the entry to evict to make room, nil if the cache is empty. On the way, the entries of the small
+// queue read again move to the main queue, and the entries of the main queue read again are reinserted.
+func (cache *S3FIFOCache) victim() *s3fifoEntry {
+	for cache.small.Len() > 0 && (cache.small.Len() >= cache.smallCapacity() || cache.main.Len() == 0) {
+		ent := cache.small.Back().Value.(*s3fifoEntry)
+		if ent.frequency == 0 {
+			return ent
+		}
+		cache.small.Remove(ent.element)
+		ent.frequency, ent.main, ent.element = 0, true, cache.main.PushFront(ent)
+	}
+	for cache.main.Len() > 0 {
+		ent := cache.main.Back().Value.(*s3fifoEntry)
+		if ent.frequency == 0 {
+			return ent
+		}
+		ent.frequency--
+		cache.main.MoveToFront(ent.element)
+	}
+	return nil
+}
+
+// evict removes the victim, remembering its key in the ghost queue if it was on probation.
+// It returns the key and the value of the evicted item, if any.
+func (cache *S3FIFOCache) evict() (evicted string, value any, found bool) {
+	victim := cache.victim()
+	if victim == nil {
+		return "", nil, false
+	}
+	evicted, value = victim.key, victim.value
+	if !victim.main {
+		cache.rememberGhost(evicted)
+	}
+	cache.remove(evicted, metricReasonEvicted)
+	return evicted, value, true
+}
+
+// rememberGhost adds the key to the ghost queue, which remembers as many keys as the main queue holds items.
+func (cache *S3FIFOCache) rememberGhost(key string) {
+	if elem, found := cache.ghostKeys[key]; found {
+		cache.ghost.MoveToFront(elem)
+		return
+	}
+	cache.ghostKeys[key] = cache.ghost.PushFront(key)
+	for cache.ghost.Len() > max(cache.capacity-cache.smallCapacity(), 1) {
+		delete(cache.ghostKeys, cache.ghost.Remove(cache.ghost.Back()).(string))
+	}
+}
+
+// checkCapacity checks if the cache has reached its capacity.
+// If it has, it first reclaims the expired items, and only if none expired it evicts an item.
+// It returns the key and the value of the item evicted to make room, if any.
+func (cache *S3FIFOCache) checkCapacity() (evicted string, value any, found bool) {
+	if isFull(len(cache.items), cache.capacity) {
+		cache.removeExpired()
+	}
+	if isFull(len(cache.items), cache.capacity) {
+		return cache.evict()
+	}
+	return "", nil, false
+}
+
+// set adds or updates an item in the cache. Updating an existing item counts as an access.
+// A new item enters the main queue if its key is in the ghost queue, the small queue otherwise.
+// With values, the result holds the value replaced or evicted.
+func (cache *S3FIFOCache) set(key string, value any, expiration time.Time, values bool) (result SetResult) {
+	if cache.capacity == 0 { // Disabled
+		return SetResult{Status: SetDisabled}
+	}
+	cache.ghosts.access()
+	cache.curve.access(key)
+	if !cache.admission.admits(key, value) {
+		cache.remove(key, metricReasonDenied)
+		return SetResult{Status: SetDenied}
+	}
+	if ent, found := cache.items[key]; found {
+		result = SetResult{Status: SetUpdated}
+		if values && !ent.hasExpired(cache.clock.Now()) {
+			result.Previous, result.HasPrevious = ent.value, true
+		}
+		ent.value = value
+		cache.counters.expirationChanged(ent.expiresAt, expiration)
+		ent.expiresAt = expiration
+		cache.expiries.track(&ent.entry)
+		ent.frequency = min(ent.frequency+1, s3fifoMaxFrequency)
+
+		cache.metrics.updated() // Increment cache hit metric
+		cache.emit(EventUpdated, key, &ent.entry, "")
+		return result
+	}
+
+	if cache.throttled(key) {
+		return SetResult{Status: SetThrottled}
+	}
+	// Take the key out of the ghost queue first, so the eviction does not push it out
+	elem, returning := cache.ghostKeys[key]
+	if returning {
+		cache.ghost.Remove(elem)
+		delete(cache.ghostKeys, key)
+	}
+	var evictedValue any
+	result.EvictedKey, evictedValue, result.Evicted = cache.checkCapacity() // Check capacity before adding a new item
+	if values {
+		result.EvictedValue = evictedValue
+	}
+	newEntry := &s3fifoEntry{entry: makeEntry(key, value, expiration)}
+	if returning {
+		newEntry.main, newEntry.element = true, cache.main.PushFront(newEntry)
+	} else {
+		newEntry.element = cache.small.PushFront(newEntry)
+	}
+	cache.items[key] = newEntry
+	cache.expiries.track(&newEntry.entry)
+	cache.ghosts.forget(key)
+
+	cache.metrics.added(cache.counters.added(expiration)) // Increment cache miss metric and update total items metric
+	cache.emit(EventAdded, key, &newEntry.entry, "")
+	result.Status = SetAdded
+	return result
+}
+
+// Set adds or updates an item in the cache with no expiration, unless WithTTLPolicy gives it a ttl.
+// If the key already exists, both its value and expiration will be overridden.
+func (cache *S3FIFOCache) Set(key string, value any) SetResult {
+	key = cache.transform.apply(key)
+	if ttl := cache.ttlPolicy.apply(key, value); ttl > 0 {
+		return cache.setWithTTL(key, value, ttl)
+	}
+	return cache.set(key, value, time.Time{}, cache.previous) // No expiration
+}
+
+// SetWithTTL adds or updates an item in the cache with a specified expiration time.
+func (cache *S3FIFOCache) SetWithTTL(key string, value any, ttl time.Duration) SetResult {
+	return cache.setWithTTL(cache.transform.apply(key), value, ttl)
+}
+
+// setWithTTL adds or updates an item expiring after ttl, the key being already transformed.
+func (cache *S3FIFOCache) setWithTTL(key string, value any, ttl time.Duration) (result SetResult) {
+	now := cache.clock.Now()
+	expiration := now.Add(ttl)
+
+	if !hasExpired(expiration, now) {
+		result = cache.set(key, value, expiration, cache.previous)
+	} else {
+		cache.remove(key, metricReasonExpired) // Remove the item if it has expired
+		result = SetResult{Status: SetExpired}
+	}
+
+	cache.metrics.expiration(ttl) // Record the expiration duration in the histogram
+	return result
+}
+
+// throttled returns true if adding the key needs an eviction the rate limit does not allow.
+// The expired items are reclaimed first, as their removal is not limited.
+func (cache *S3FIFOCache) throttled(key string) bool {
+	if cache.evictions == nil || !isFull(len(cache.items), cache.capacity) {
+		return false
+	}
+	if _, found := cache.items[key]; found {
+		return false
+	}
+	cache.removeExpired()
+	if !isFull(len(cache.items), cache.capacity) || cache.evictions.allow(cache.clock.Now()) {
+		return false
+	}
+	cache.metrics.throttledSet()
+	return true
+}
+
+// remove deletes an item from the cache by key.
+// If the item does not exist, it does nothing.
+// The reason parameter is used to specify why the item is being removed (e.g., "manual", "expired", "evicted").
+func (cache *S3FIFOCache) remove(key string, reason string) {
+	if ent, found := cache.items[key]; found {
+		if ent.main {
+			cache.main.Remove(ent.element)
+		} else {
+			cache.small.Remove(ent.element)
+		}
+		cache.expiries.untrack(&ent.entry)
+		delete(cache.items, key)
+		if reason == metricReasonEvicted {
+			cache.ghosts.add(key)
+		}
+
+		length := cache.counters.removed(reason, ent.expiresAt)
+		cache.metrics.removed(reason, length) // Increment eviction metric and update total items metric
+		cache.emit(EventRemoved, key, &ent.entry, reason)
+	}
+}
+
+// removeExpired removes every expired item, using the expiry index to avoid scanning the cache.
+func (cache *S3FIFOCache) removeExpired() {
+	now := cache.clock.Now()
+	for ent := cache.expiries.nextExpired(now); ent != nil; ent = cache.expiries.nextExpired(now) {
+		cache.remove(ent.key, metricReasonExpired)
+	}
+}
+
+// Remove deletes an item from the cache by key.
+func (cache *S3FIFOCache) Remove(key string) {
+	key = cache.transform.apply(key)
+	cache.remove(key, metricReasonManual) // Default reason is "manual"
+}
+
+// Capacity returns the maximum number of items that can be stored in the cache.
+func (cache *S3FIFOCache) Capacity() int {
+	return cache.capacity
+}
+
+// Len returns the number of items currently in the cache.
+func (cache *S3FIFOCache) Len() int {
+	return len(cache.items)
+}
+
+// metricsName returns the value of the cache label of the metrics, empty when they are disabled.
+func (cache *S3FIFOCache) metricsName() string {
+	return cache.metrics.cacheName()
+}
+
+// emit sends an event about the given entry to the listeners, if any.
+func (cache *S3FIFOCache) emit(eventType EventType, key string, ent *entry, reason string) {
+	if len(cache.listeners) > 0 {
+		cache.listeners.notify(newEvent(eventType, key, ent, reason, cache.clock.Now()))
+	}
+}
+
+// each walks the entries from the most to the least protected: the main queue, then the small queue,
+// each from the newest. The order does not account for the reinsertions of the accessed items.
+func (cache *S3FIFOCache) each(fn func(ent *s3fifoEntry) bool) {
+	for _, queue := range []*list.List{cache.main, cache.small} {
+		for e := queue.Front(); e != nil; e = e.Next() {
+			if !fn(e.Value.(*s3fifoEntry)) {
+				return
+			}
+		}
+	}
+}
+
+// Peek returns the value of the key without counting the access nor reclaiming it if expired.
+func (cache *S3FIFOCache) Peek(key string) (value any, found bool) {
+	key = cache.transform.apply(key)
+	if ent, found := cache.items[key]; found {
+		return ent.value, true
+	}
+	return nil, false
+}
+
+// Range calls fn with every live item, the main queue first then the small one, each from the newest item,
+// until fn returns false. fn must not modify the cache.
+func (cache *S3FIFOCache) Range(fn func(key string, value any) bool) {
+	now := cache.clock.Now()
+	cache.each(func(ent *s3fifoEntry) bool {
+		return ent.hasExpired(now) || fn(ent.key, ent.value)
+	})
+}
+
+// Resize changes the capacity of the cache, and so the one of its small queue. When the cache holds more items
+// than the new capacity, the expired items are removed first, then the victims of the policy.
+func (cache *S3FIFOCache) Resize(capacity int) {
+	cache.capacity = capacity
+	cache.counters.capacity.Store(int64(capacity))
+	if isOver(len(cache.items), capacity) {
+		cache.removeExpired()
+	}
+	for isOver(len(cache.items), capacity) {
+		cache.evict()
+	}
+}
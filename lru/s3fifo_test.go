@@ -0,0 +1,76 @@
+package lru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestS3FIFOResistsScans(t *testing.T) {
+	cache := NewS3FIFOCache(10, WithoutMetrics())
+	cache.Set("hot1", 1)
+	cache.Set("hot2", 2)
+	cache.Get("hot1")
+	cache.Get("hot2")
+
+	for i := range 20 { // A scan reads every key once
+		cache.Set(fmt.Sprint("scan", i), i)
+	}
+	for _, key := range []string{"hot1", "hot2"} {
+		_, found := cache.Get(key)
+		assert.True(t, found, "Expected the items read again to move to the main queue and survive the scan")
+	}
+
+	lru := NewLRUCache(10, WithoutMetrics())
+	lru.Set("hot1", 1)
+	lru.Get("hot1")
+	for i := range 20 {
+		lru.Set(fmt.Sprint("scan", i), i)
+	}
+	_, found := lru.Get("hot1")
+	assert.False(t, found, "Expected a plain LRU cache to lose its hot item to the scan")
+}
+
+func TestS3FIFOReadmitsGhostsToMain(t *testing.T) {
+	cache := NewS3FIFOCache(2, WithoutMetrics())
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	assert.Equal(t, "a", cache.Set("c", 3).EvictedKey, "Expected the oldest item read once to be evicted")
+
+	assert.Equal(t, "b", cache.Set("a", 1).EvictedKey)
+	assert.Equal(t, []string{"a", "c"}, rangeKeys(cache), "Expected a to return to the main queue")
+
+	assert.Equal(t, "c", cache.Set("d", 4).EvictedKey, "Expected the small queue to evict before the main one")
+	assert.Equal(t, []string{"a", "d"}, rangeKeys(cache))
+}
+
+func TestS3FIFOReinsertsReadItemsInMain(t *testing.T) {
+	cache := NewS3FIFOCache(20, WithoutMetrics())
+	for i := range 20 {
+		cache.Set(fmt.Sprint("k", i), i)
+		cache.Get(fmt.Sprint("k", i))
+	}
+	assert.Equal(t, "k0", cache.Set("x", 0).EvictedKey, "Expected every item to move to the main queue first")
+
+	cache.Get("k1")
+	assert.Equal(t, "k2", cache.Set("y", 0).EvictedKey, "Expected k1 to be reinserted as it was read")
+	_, found := cache.Peek("k1")
+	assert.True(t, found)
+}
+
+func TestS3FIFOExpirationAndStats(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewSafe(NewS3FIFOCache(2, WithClock(clock), WithoutMetrics()))
+	cache.SetWithTTL("a", 1, time.Second)
+	cache.Set("b", 2)
+	cache.Get("b")
+	clock.Advance(2 * time.Second)
+
+	assert.Equal(t, "", cache.Set("c", 3).EvictedKey, "Expected the expired item to be reclaimed instead of an eviction")
+	cache.Remove("b")
+	cache.Resize(1)
+	assert.Equal(t, Stats{Len: 1, Capacity: 1, Hits: 1, Expirations: 1, ExpirationRate: 1.0 / expirationRateWindow}, cache.Stats())
+	assert.Equal(t, metricCacheTypeS3FIFO, NewS3FIFOCache(1).metricsName())
+}
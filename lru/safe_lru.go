@@ -1,53 +1,185 @@
 package lru
 
 import (
+	"cmp"
+	"errors"
+	"io"
+	"slices"
 	"sync"
 	"time"
 )
 
+// SafeLRUCache makes a cache safe for concurrent use, serializing its operations with a mutex.
+// Every operation, including the composite ones such as Get removing an expired item or Increment,
+// runs entirely under the lock, so it is atomic with respect to the others.
+// With a write buffer, Set, SetWithTTL and Remove are applied later in the order they were enqueued,
+// and the writes bypassing the buffer (Increment, Resize, the bulk removals) first wait for them,
+// so the writes of a goroutine are applied in the order it made them. Reads do not wait for the buffer.
 type SafeLRUCache struct {
-	cache Cache      // The underlying LRU cache
-	mutex sync.Mutex // Mutex to ensure thread safety
+	cache     Cache         // The underlying LRU cache
+	mutex     sync.RWMutex  // Mutex to ensure thread safety, only read-locked by Get with an access buffer
+	writes    *writeBuffer  // Optional buffer for Set, SetWithTTL and Remove, nil when writes are synchronous
+	accesses  *accessBuffer // Optional buffer of promotions, nil when Get promotes immediately
+	locks     *lockMetrics  // Contention metrics of the mutex, nil when disabled
+	transform KeyTransform  // Rewrites the keys before every operation, nil without WithKeyTransform
+
+	janitor     chan struct{} // Closed to stop the janitor goroutine, nil without janitor
+	janitorDone chan struct{} // Closed when the janitor goroutine returned
+	janitorOnce sync.Once     // Ensures the janitor goroutine is stopped once
+
+	trimmer     chan struct{} // Closed to stop the goroutine trimming to the low watermark, nil without watermarks
+	trimmerDone chan struct{} // Closed when the trimming goroutine returned
+	trimmerOnce sync.Once     // Ensures the trimming goroutine is stopped once
 }
 
-var _ Cache = (*SafeLRUCache)(nil) // Ensure SafeLRUCache implements the Cache interface
+var _ Cache = (*SafeLRUCache)(nil)     // Ensure SafeLRUCache implements the Cache interface
+var _ io.Closer = (*SafeLRUCache)(nil) // Ensure SafeLRUCache can be closed
 
-func NewSafeLRUCache(capacity int) *SafeLRUCache {
-	cache := NewLRUCache(capacity)
-	cache.name = metricCacheTypeSafeLRU // Set a different name for the safe cache
-	return &SafeLRUCache{
-		cache: cache,
-	}
+// namedCache is implemented by the caches recording metrics, so their wrappers record theirs under the same name.
+type namedCache interface {
+	metricsName() string
+}
+
+var _ namedCache = (*LRUCache)(nil)    // Ensure LRUCache shares its metrics name
+var _ namedCache = (*LFUCache)(nil)    // Ensure LFUCache shares its metrics name
+var _ namedCache = (*LRUKCache)(nil)   // Ensure LRUKCache shares its metrics name
+var _ namedCache = (*S3FIFOCache)(nil) // Ensure S3FIFOCache shares its metrics name
+
+// NewSafeLRUCache creates a thread-safe LRU cache, named safe_lru in the metrics unless WithMetricsName is used.
+func NewSafeLRUCache(capacity int, opts ...Option) *SafeLRUCache {
+	name := cmp.Or(newOptions(opts...).name, metricCacheTypeSafeLRU)
+	// The keys are transformed once, by the safe cache
+	return NewSafe(NewLRUCache(capacity, append(slices.Clip(opts), WithKeyTransform(nil), WithMetricsName(name))...), opts...)
 }
 
-// NewSafeLRUCacheFrom creates a SafeLRUCache from an existing LRUCache.
-// This is useful for wrapping an existing cache without losing its state.
-// The new SafeLRUCache will be thread-safe.
-// It does not copy the items from the original cache, so it should be used with caution.
-// Used in tests
-func NewSafeLRUCacheFrom(cache Cache) *SafeLRUCache {
-	return &SafeLRUCache{
-		cache: cache,
+// NewSafe makes any cache safe for concurrent use, e.g. NewSafe(NewLFUCache(100)), serializing its operations with a mutex.
+// The lock metrics share the name of the wrapped cache, or are named by WithMetricsName if it records no metrics.
+// The cache must not be used directly afterwards, and its items are kept as is.
+// Only the options specific to SafeLRUCache, such as WithWriteBuffer, and WithKeyTransform are used.
+func NewSafe(cache Cache, opts ...Option) *SafeLRUCache {
+	o := newOptions(opts...)
+	safeCache := &SafeLRUCache{
+		cache:     cache,
+		transform: o.keyTransform,
+	}
+	if o.metrics {
+		name := cmp.Or(o.name, metricCacheTypeSafeLRU)
+		if named, ok := cache.(namedCache); ok && named.metricsName() != "" {
+			name = named.metricsName() // Share the name of the wrapped cache, e.g. for the shards
+		}
+		safeCache.locks = newLockMetrics(name, o.legacyMetrics)
+	}
+	if reader, ok := cache.(batchedReader); ok && o.accessBuffer > 0 {
+		safeCache.accesses = newAccessBuffer(reader, o.accessBuffer)
+	}
+	if o.writeBuffer > 0 {
+		safeCache.writes = newWriteBuffer(o.writeBuffer)
+		go safeCache.writes.run(safeCache)
+	}
+	if o.janitorInterval > 0 {
+		safeCache.janitor = make(chan struct{})
+		safeCache.janitorDone = make(chan struct{})
+		go safeCache.cleanEvery(o.janitorInterval)
 	}
+	if lru, ok := cache.(*LRUCache); ok && lru.watermarks != nil {
+		safeCache.trimmer = make(chan struct{})
+		safeCache.trimmerDone = make(chan struct{})
+		go safeCache.trimOnSignal(lru.watermarks)
+	}
+	return safeCache
+}
+
+// NewSafeLRUCacheFrom creates a SafeLRUCache from an existing cache.
+//
+// Deprecated: use NewSafe, which supports every cache, LRUCache or not, in the same way.
+func NewSafeLRUCacheFrom(cache Cache, opts ...Option) *SafeLRUCache {
+	return NewSafe(cache, opts...)
 }
 
 // Get retrieves an item from the cache by its key.
 // It returns the value and a boolean indicating whether the item was found.
 // If the ttl has expired, the item will be removed and not found.
+// With an access buffer, the item is promoted later and expired items are left for the next write to remove.
 // It is thread-safe.
 func (safeCache *SafeLRUCache) Get(key string) (value any, found bool) {
-	safeCache.mutex.Lock()
+	key = safeCache.transform.apply(key)
+	if safeCache.accesses != nil {
+		return safeCache.getBatched(key)
+	}
+
+	safeCache.writeLock()
 	defer safeCache.mutex.Unlock()
 
 	return safeCache.cache.Get(key)
 }
 
+// getBatched serves a Get under the read lock, recording the access instead of promoting the item.
+// When the access buffer is full, the pending promotions are applied under the write lock.
+func (safeCache *SafeLRUCache) getBatched(key string) (value any, found bool) {
+	safeCache.readLock()
+	value, found = safeCache.accesses.reader.peek(key)
+	full := found && !safeCache.accesses.record(key)
+	safeCache.mutex.RUnlock()
+
+	if full {
+		safeCache.writeLock()
+		safeCache.accesses.apply()
+		safeCache.mutex.Unlock()
+	}
+	return value, found
+}
+
+// lock acquires the write lock, applying the pending promotions so writes see an up to date usage order.
+func (safeCache *SafeLRUCache) lock() {
+	safeCache.writeLock()
+	if safeCache.accesses != nil {
+		safeCache.accesses.apply()
+	}
+}
+
+// lockOrdered acquires the write lock for a write bypassing the write buffer, such as Increment,
+// once the buffered writes enqueued before it are applied, so the writes of a goroutine are applied in order.
+func (safeCache *SafeLRUCache) lockOrdered() {
+	if safeCache.writes != nil {
+		safeCache.writes.flush()
+	}
+	safeCache.lock()
+}
+
+// writeLock acquires the write lock. When another goroutine holds the lock,
+// the contention and the time spent waiting are recorded in the metrics.
+// Uncontended acquisitions only pay for a TryLock, so the wait time is sampled on the contended ones.
+func (safeCache *SafeLRUCache) writeLock() {
+	if safeCache.mutex.TryLock() {
+		return
+	}
+	start := time.Now()
+	safeCache.mutex.Lock()
+	safeCache.locks.contended(metricLockWrite, time.Since(start))
+}
+
+// readLock acquires the read lock, recording the contention like writeLock does.
+func (safeCache *SafeLRUCache) readLock() {
+	if safeCache.mutex.TryRLock() {
+		return
+	}
+	start := time.Now()
+	safeCache.mutex.RLock()
+	safeCache.locks.contended(metricLockRead, time.Since(start))
+}
+
 // Set adds or updates an item in the cache with no expiration.
 // The item will not expire unless explicitly removed.
 // If the key already exists, both its value and expiration will be overridden.
+// With a write buffer, the write is queued and SetBuffered is returned.
 // It is thread-safe.
-func (safeCache *SafeLRUCache) Set(key string, value any) (status string) {
-	safeCache.mutex.Lock()
+func (safeCache *SafeLRUCache) Set(key string, value any) SetResult {
+	key = safeCache.transform.apply(key)
+	if safeCache.writes != nil && safeCache.writes.enqueue(writeOp{kind: writeOpSet, key: key, value: value}) {
+		return SetResult{Status: SetBuffered}
+	}
+
+	safeCache.lock()
 	defer safeCache.mutex.Unlock()
 
 	return safeCache.cache.Set(key, value)
@@ -55,9 +187,15 @@ func (safeCache *SafeLRUCache) Set(key string, value any) (status string) {
 
 // SetWithTTL adds or updates an item in the cache with a specified expiration time. (TTL: time to live).
 // It calls the internal set method with the expiration time.
+// With a write buffer, the write is queued and SetBuffered is returned.
 // It is thread-safe.
-func (safeCache *SafeLRUCache) SetWithTTL(key string, value any, ttl time.Duration) (status string) {
-	safeCache.mutex.Lock()
+func (safeCache *SafeLRUCache) SetWithTTL(key string, value any, ttl time.Duration) SetResult {
+	key = safeCache.transform.apply(key)
+	if safeCache.writes != nil && safeCache.writes.enqueue(writeOp{kind: writeOpSetWithTTL, key: key, value: value, ttl: ttl}) {
+		return SetResult{Status: SetBuffered}
+	}
+
+	safeCache.lock()
 	defer safeCache.mutex.Unlock()
 
 	return safeCache.cache.SetWithTTL(key, value, ttl)
@@ -65,24 +203,131 @@ func (safeCache *SafeLRUCache) SetWithTTL(key string, value any, ttl time.Durati
 
 // Remove deletes an item from the cache by key.
 // If the item does not exist, it does nothing.
+// With a write buffer, the removal is queued behind the pending writes to preserve their order.
 // It is thread-safe.
 func (safeCache *SafeLRUCache) Remove(key string) {
-	safeCache.mutex.Lock()
+	key = safeCache.transform.apply(key)
+	if safeCache.writes != nil && safeCache.writes.enqueue(writeOp{kind: writeOpRemove, key: key}) {
+		return
+	}
+
+	safeCache.lock()
 	defer safeCache.mutex.Unlock()
 
 	safeCache.cache.Remove(key)
 }
 
+// Increment atomically adds delta to the int64 counter stored under key and returns the new value.
+// If the key is missing or expired, the counter is created with delta as its value, expiring after ttl.
+// It returns errors.ErrUnsupported if the underlying cache does not implement Incrementer.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	key = safeCache.transform.apply(key)
+	safeCache.lockOrdered()
+	defer safeCache.mutex.Unlock()
+
+	if incrementer, ok := safeCache.cache.(Incrementer); ok {
+		return incrementer.Increment(key, delta, ttl)
+	}
+	return 0, errors.ErrUnsupported
+}
+
+// Flush blocks until every write buffered before the call has been applied.
+// Without a write buffer, it returns immediately. It is mostly useful in tests.
+func (safeCache *SafeLRUCache) Flush() {
+	if safeCache.writes != nil {
+		safeCache.writes.flush()
+	}
+}
+
+// Close applies the pending buffered writes and stops the goroutine processing them, the janitor
+// and the trimming to the low watermark, then flushes the batched metrics. Writes after Close are applied synchronously.
+// Without a write buffer, janitor, watermarks nor metrics batching, it does nothing.
+func (safeCache *SafeLRUCache) Close() error {
+	if safeCache.writes != nil {
+		safeCache.writes.close()
+	}
+	if safeCache.janitor != nil {
+		safeCache.janitorOnce.Do(func() { close(safeCache.janitor) })
+		<-safeCache.janitorDone
+	}
+	if safeCache.trimmer != nil {
+		safeCache.trimmerOnce.Do(func() { close(safeCache.trimmer) })
+		<-safeCache.trimmerDone
+	}
+	safeCache.FlushMetrics()
+	return nil
+}
+
+// RemoveExpired removes the expired items and returns how many were removed.
+// It does nothing if the underlying cache cannot remove its expired items.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) RemoveExpired() int {
+	safeCache.lock()
+	defer safeCache.mutex.Unlock()
+
+	if expirer, ok := safeCache.cache.(interface{ RemoveExpired() int }); ok {
+		return expirer.RemoveExpired()
+	}
+	return 0
+}
+
+// FlushMetrics adds the counters batched by WithMetricsBatching to the Prometheus metrics right away,
+// e.g. before shutting down. It does nothing without batching.
+// It is thread-safe, and does not lock the cache.
+func (safeCache *SafeLRUCache) FlushMetrics() {
+	if flusher, ok := safeCache.cache.(metricsFlusher); ok {
+		flusher.flushMetrics()
+	}
+}
+
+// cleanEvery calls RemoveExpired, updates the expiration gauges and flushes the batched metrics, at the given interval until the cache is closed.
+func (safeCache *SafeLRUCache) cleanEvery(interval time.Duration) {
+	defer close(safeCache.janitorDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			safeCache.RemoveExpired()
+			safeCache.forecastExpirations()
+			safeCache.FlushMetrics()
+		case <-safeCache.janitor:
+			return
+		}
+	}
+}
+
 // Capacity returns the maximum number of items that can be stored in the cache.
-// This value is fixed at initialization and does not require locking.
+// It is thread-safe.
 func (safeCache *SafeLRUCache) Capacity() int {
+	safeCache.readLock()
+	defer safeCache.mutex.RUnlock()
+
 	return safeCache.cache.Capacity()
 }
 
-// Len returns the number of items currently in the cache.
+// Resize changes the capacity of the cache, evicting items if it shrinks below the current length.
+// It does nothing if the underlying cache cannot be resized.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) Resize(capacity int) {
+	safeCache.lockOrdered()
+	defer safeCache.mutex.Unlock()
+
+	if resizer, ok := safeCache.cache.(Resizer); ok {
+		resizer.Resize(capacity)
+	}
+}
+
+// Len returns the number of items currently in the cache. The length of an LRUCache or an LFUCache is read
+// without locking, from an atomic counter, so it may miss the writes in progress.
 // It is thread-safe.
 func (safeCache *SafeLRUCache) Len() int {
-	safeCache.mutex.Lock()
+	if reporter, ok := safeCache.cache.(weakStatsReporter); ok {
+		return reporter.weakLen()
+	}
+	safeCache.writeLock()
 	defer safeCache.mutex.Unlock()
 
 	return safeCache.cache.Len()
@@ -90,17 +335,16 @@ func (safeCache *SafeLRUCache) Len() int {
 
 // UnsafePeek retrieves the value for a key without updates to its usage order nor expiration.
 // This method is not thread-safe and may return expired items.
-// It assumes the underlying cache is an LRUCache, if not, it will panic.
-// It returns the value and a boolean indicating whether the item was found.
+// It returns the value and a boolean indicating whether the item was found,
+// which is always false if the underlying cache is not a Peeker.
+//
+// Deprecated: use Peek, which is thread-safe.
 func (safeCache *SafeLRUCache) UnsafePeek(key string) (value any, found bool) {
-	if lru, ok := safeCache.cache.(*LRUCache); ok {
-		if elem, found := lru.items[key]; found {
-			return elem.Value.(*entry).value, true
-		}
-	} else {
-		panic("UnsafePeek can only be used with LRUCache")
+	key = safeCache.transform.apply(key)
+	if peeker, ok := safeCache.cache.(Peeker); ok {
+		return peeker.Peek(key)
 	}
-	return nil, false // Item not found, or not an LRUCache
+	return nil, false
 }
 
 // UnsafeLen returns the number of items in the cache without locking.
@@ -1,9 +1,14 @@
 package lru
 
 import (
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,14 +26,14 @@ func (f *fakeLRUCache) Get(key string) (any, bool) {
 	return nil, false
 }
 
-func (f *fakeLRUCache) Set(key string, value any) (status string) {
+func (f *fakeLRUCache) Set(key string, value any) SetResult {
 	f.setCalled = true
-	return "added"
+	return SetResult{Status: SetAdded}
 }
 
-func (f *fakeLRUCache) SetWithTTL(key string, value any, ttl time.Duration) (status string) {
+func (f *fakeLRUCache) SetWithTTL(key string, value any, ttl time.Duration) SetResult {
 	f.setWithTTLCalled = true
-	return "added"
+	return SetResult{Status: SetAdded}
 }
 
 func (f *fakeLRUCache) Remove(key string) {
@@ -164,13 +169,15 @@ func TestCacheUnsafePeekNotMoveItems(t *testing.T) {
 	assert.Equal(t, "testValue2", value)
 }
 
-func TestUnsafePeekPanicOnNonLRUCache(t *testing.T) {
+func TestUnsafePeekWithoutPeeker(t *testing.T) {
 	fake := &fakeLRUCache{}
-	safeCache := NewSafeLRUCacheFrom(fake)
+	safeCache := NewSafe(fake)
 
-	assert.Panics(t, func() {
-		safeCache.UnsafePeek("testKey")
-	}, "UnsafePeek should panic if the underlying cache is not an LRUCache")
+	assert.NotPanics(t, func() {
+		value, found := safeCache.UnsafePeek("testKey")
+		assert.False(t, found, "UnsafePeek should not find any item if the underlying cache is not a Peeker")
+		assert.Nil(t, value)
+	})
 }
 
 func TestCacheUnsafeLen(t *testing.T) {
@@ -181,3 +188,154 @@ func TestCacheUnsafeLen(t *testing.T) {
 	assert.Equal(t, 0, length)
 	assert.True(t, fake.lenCalled, "UnsafeLen should call the underlying cache's Len method")
 }
+
+func TestCacheIncrement(t *testing.T) {
+	safeCache := NewSafeLRUCache(5)
+	value, err := safeCache.Increment("counter", 1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), value)
+}
+
+func TestCacheIncrementUnsupported(t *testing.T) {
+	safeCache := NewSafeLRUCacheFrom(&fakeLRUCache{})
+	_, err := safeCache.Increment("counter", 1, 0)
+	assert.ErrorIs(t, err, errors.ErrUnsupported)
+}
+
+func TestCacheWriteBuffer(t *testing.T) {
+	safeCache := NewSafeLRUCache(5, WithWriteBuffer(16))
+	defer safeCache.Close()
+
+	status := safeCache.Set("key1", "value1")
+	assert.Equal(t, SetBuffered, status.Status)
+	safeCache.SetWithTTL("key2", "value2", time.Minute)
+	safeCache.Remove("key1")
+	safeCache.Flush()
+
+	_, found := safeCache.Get("key1")
+	assert.False(t, found, "The removal should be applied after the buffered Set")
+	value, found := safeCache.Get("key2")
+	assert.True(t, found)
+	assert.Equal(t, "value2", value)
+}
+
+func TestCacheWriteBufferConcurrentWriters(t *testing.T) {
+	safeCache := NewSafeLRUCache(1000, WithWriteBuffer(8))
+
+	var wg sync.WaitGroup
+	for writer := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				safeCache.Set(fmt.Sprintf("key-%d-%d", writer, i), i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.NoError(t, safeCache.Close()) // Close applies the pending writes
+	assert.Equal(t, 800, safeCache.Len())
+
+	status := safeCache.Set("key-0-0", "value") // Writes after Close are synchronous
+	assert.Equal(t, SetUpdated, status.Status)
+}
+
+func TestCacheAccessBuffer(t *testing.T) {
+	safeCache := NewSafeLRUCache(2, WithAccessBuffer(4))
+	safeCache.Set("key1", "value1")
+	safeCache.Set("key2", "value2")
+
+	value, found := safeCache.Get("key1") // Promotion is recorded, and applied before the next write
+	assert.True(t, found)
+	assert.Equal(t, "value1", value)
+	safeCache.Set("key3", "value3")
+
+	_, found = safeCache.Get("key2")
+	assert.False(t, found, "key2 should be evicted since key1 was promoted")
+	_, found = safeCache.Get("key1")
+	assert.True(t, found)
+}
+
+func TestCacheAccessBufferFull(t *testing.T) {
+	safeCache := NewSafeLRUCache(5, WithAccessBuffer(1))
+	safeCache.Set("key1", "value1")
+
+	for range 10 {
+		_, found := safeCache.Get("key1") // Filling the buffer applies it instead of blocking
+		assert.True(t, found)
+	}
+	assert.Empty(t, safeCache.accesses.keys)
+}
+
+func TestCacheAccessBufferConcurrent(t *testing.T) {
+	safeCache := NewSafeLRUCache(50, WithAccessBuffer(16))
+
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				key := fmt.Sprintf("key-%d", (worker+i)%100)
+				if _, found := safeCache.Get(key); !found {
+					safeCache.Set(key, i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 50, safeCache.Len())
+}
+
+func TestLockContentionMetrics(t *testing.T) {
+	cache := NewSafeLRUCache(5)
+	before := counterValue(cache.locks.writeContentions)
+
+	cache.mutex.Lock() // Hold the lock so the Set has to wait
+	done := make(chan struct{})
+	go func() {
+		cache.Set("key1", "value1")
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cache.mutex.Unlock()
+	<-done
+
+	assert.Equal(t, before+1, counterValue(cache.locks.writeContentions))
+	cache.Get("key1") // Uncontended, not recorded
+	assert.Equal(t, before+1, counterValue(cache.locks.writeContentions))
+}
+
+func TestLockMetricsDisabled(t *testing.T) {
+	cache := NewSafeLRUCache(5, WithoutMetrics())
+	assert.Nil(t, cache.locks)
+	cache.Set("key1", "value1")
+}
+
+// counterValue reads the current value of a Prometheus counter.
+func counterValue(counter prometheus.Counter) float64 {
+	var metric dto.Metric
+	counter.Write(&metric)
+	return metric.GetCounter().GetValue()
+}
+
+func TestNewSafeWrapsAnyPolicy(t *testing.T) {
+	safeCache := NewSafe(NewLFUCache(2, WithMetricsName("sessions")))
+	safeCache.Set("a", 1)
+	safeCache.Get("a")
+	safeCache.Set("b", 2)
+	safeCache.Set("c", 3) // Evicts b, the least frequently used item
+
+	_, found := safeCache.Get("b")
+	assert.False(t, found)
+	assert.Equal(t, "sessions", safeCache.cache.(*LFUCache).metrics.name)
+	assert.NotNil(t, safeCache.locks, "Expected the lock metrics to share the name of the wrapped cache")
+}
+
+func TestMetricsName(t *testing.T) {
+	assert.Equal(t, metricCacheTypeSafeLRU, NewSafeLRUCache(2).cache.(*LRUCache).metricsName())
+	assert.Equal(t, "users", NewSafeLRUCache(2, WithMetricsName("users")).cache.(*LRUCache).metricsName())
+	assert.Equal(t, metricCacheTypeShardedLRU, NewShardedCache(2, 4).shards[0].cache.(*LRUCache).metricsName())
+	assert.Empty(t, NewSafeLRUCache(2, WithoutMetrics()).cache.(*LRUCache).metricsName())
+}
@@ -0,0 +1,225 @@
+package lru
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultEvictionSamples is the number of items compared to pick a victim, the default of Redis.
+const defaultEvictionSamples = 5
+
+// WithEvictionSamples sets how many items a SampledLRUCache compares to pick the one to evict, 5 by default.
+// More samples approximate LRU better, at the cost of a slower eviction. Ignored by the other caches.
+func WithEvictionSamples(samples int) Option {
+	return func(o *options) {
+		o.evictionSamples = samples
+	}
+}
+
+// sampledEntry is an item of a SampledLRUCache. Only lastAccess changes once it is stored,
+// so the reads don't need the lock.
+type sampledEntry struct {
+	key        string
+	value      any
+	expiresAt  time.Time
+	lastAccess atomic.Int64 // Time of the last read or write in nanoseconds, on the cache clock
+	position   int          // Position of the key in the keys of the cache, protected by its mutex
+}
+
+// SampledLRUCache approximates LRU like Redis: instead of keeping the items in a list ordered by recency,
+// every item records the time of its last access, and an eviction samples a few random items and evicts
+// the least recently used among them, or an expired one. Reads neither lock nor reorder anything, they only
+// store the time of the access, so they scale with the readers; the writes are serialized by a mutex.
+// It is thread-safe, and does not need a SafeLRUCache.
+type SampledLRUCache struct {
+	items    sync.Map   // *sampledEntry by key
+	mutex    sync.Mutex // Serializes the writes
+	keys     []string   // Keys of the items, to sample them, protected by mutex
+	capacity int        // Maximum number of items, protected by mutex
+	samples  int        // Items compared to pick a victim
+	clock    Clock
+	counters statsCounters
+	metrics  *cacheMetrics
+}
+
+var _ Cache = (*SampledLRUCache)(nil)         // Ensure SampledLRUCache implements the Cache interface
+var _ StatsReporter = (*SampledLRUCache)(nil) // Ensure SampledLRUCache reports its stats
+var _ Resizer = (*SampledLRUCache)(nil)       // Ensure SampledLRUCache can be resized
+
+// NewSampledLRUCache creates a SampledLRUCache of the given capacity, see Unbounded for the capacities below one.
+// Among the options, only WithEvictionSamples, WithClock and the metrics options are used.
+func NewSampledLRUCache(capacity int, opts ...Option) *SampledLRUCache {
+	o := newOptions(opts...)
+	cache := &SampledLRUCache{
+		capacity: capacity,
+		samples:  max(cmp.Or(o.evictionSamples, defaultEvictionSamples), 1),
+		clock:    o.clock,
+	}
+	cache.counters.capacity.Store(int64(capacity))
+	cache.counters.clock = o.clock
+	if o.metrics {
+		cache.metrics = newCacheMetrics(cmp.Or(o.name, metricCacheTypeSampled), o.legacyMetrics, o.metricsBatch)
+	}
+	return cache
+}
+
+// Get returns the value of the key and records the access, without locking.
+// An expired item is removed and reported missing.
+func (cache *SampledLRUCache) Get(key string) (value any, found bool) {
+	stored, found := cache.items.Load(key)
+	if !found {
+		cache.counters.misses.Add(1)
+		cache.metrics.getMiss()
+		return nil, false
+	}
+	ent := stored.(*sampledEntry)
+	now := cache.clock.Now()
+	if hasExpired(ent.expiresAt, now) {
+		cache.mutex.Lock()
+		if current, found := cache.items.Load(key); found && current == stored { // Not replaced meanwhile
+			cache.remove(ent, metricReasonExpired)
+		}
+		cache.mutex.Unlock()
+		cache.counters.misses.Add(1)
+		cache.metrics.getMiss()
+		return nil, false
+	}
+	ent.lastAccess.Store(now.UnixNano())
+	cache.counters.hits.Add(1)
+	cache.metrics.getHit()
+	return ent.value, true
+}
+
+// Set adds or updates an item with no expiration, evicting a sampled item if the cache is full.
+func (cache *SampledLRUCache) Set(key string, value any) SetResult {
+	return cache.set(key, value, time.Time{})
+}
+
+// SetWithTTL adds or updates an item expiring after ttl, or removes it if ttl is not positive.
+func (cache *SampledLRUCache) SetWithTTL(key string, value any, ttl time.Duration) SetResult {
+	if ttl <= 0 {
+		cache.Remove(key)
+		return SetResult{Status: SetExpired}
+	}
+	cache.metrics.expiration(ttl)
+	return cache.set(key, value, cache.clock.Now().Add(ttl))
+}
+
+func (cache *SampledLRUCache) set(key string, value any, expiration time.Time) (result SetResult) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.capacity == 0 { // Disabled
+		return SetResult{Status: SetDisabled}
+	}
+	now := cache.clock.Now()
+	ent := &sampledEntry{key: key, value: value, expiresAt: expiration}
+	ent.lastAccess.Store(now.UnixNano())
+	if stored, found := cache.items.Load(key); found {
+		previous := stored.(*sampledEntry)
+		ent.position = previous.position
+		cache.items.Store(key, ent) // The entries are replaced rather than modified, for the reads in progress
+		cache.counters.expirationChanged(previous.expiresAt, expiration)
+		cache.metrics.updated()
+		return SetResult{Status: SetUpdated}
+	}
+
+	if isFull(len(cache.keys), cache.capacity) {
+		if victim := cache.victim(now); victim != nil {
+			reason := metricReasonEvicted
+			if hasExpired(victim.expiresAt, now) {
+				reason = metricReasonExpired
+			} else {
+				result.Evicted, result.EvictedKey = true, victim.key
+			}
+			cache.remove(victim, reason)
+		}
+	}
+	ent.position = len(cache.keys)
+	cache.keys = append(cache.keys, key)
+	cache.items.Store(key, ent)
+	cache.metrics.added(cache.counters.added(expiration))
+	result.Status = SetAdded
+	return result
+}
+
+// victim samples items at random and returns the first expired one, or the least recently used one.
+// When the samples cover the cache, every item is compared instead. It must be called with the mutex held.
+func (cache *SampledLRUCache) victim(now time.Time) *sampledEntry {
+	var victim *sampledEntry
+	exhaustive := cache.samples >= len(cache.keys)
+	for i := range min(cache.samples, len(cache.keys)) {
+		if !exhaustive {
+			i = rand.IntN(len(cache.keys))
+		}
+		stored, _ := cache.items.Load(cache.keys[i])
+		ent := stored.(*sampledEntry)
+		if hasExpired(ent.expiresAt, now) {
+			return ent
+		}
+		if victim == nil || ent.lastAccess.Load() < victim.lastAccess.Load() {
+			victim = ent
+		}
+	}
+	return victim
+}
+
+// remove deletes the entry, moving the last key to its position. It must be called with the mutex held.
+func (cache *SampledLRUCache) remove(ent *sampledEntry, reason string) {
+	last := len(cache.keys) - 1
+	if ent.position != last {
+		moved := cache.keys[last]
+		stored, _ := cache.items.Load(moved)
+		stored.(*sampledEntry).position = ent.position
+		cache.keys[ent.position] = moved
+	}
+	cache.keys = cache.keys[:last]
+	cache.items.Delete(ent.key)
+	cache.metrics.removed(reason, cache.counters.removed(reason, ent.expiresAt))
+}
+
+// Remove deletes the item of the key, if any.
+func (cache *SampledLRUCache) Remove(key string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if stored, found := cache.items.Load(key); found {
+		cache.remove(stored.(*sampledEntry), metricReasonManual)
+	}
+}
+
+// Len returns the number of items, including the expired ones not removed yet, without locking.
+func (cache *SampledLRUCache) Len() int {
+	return int(cache.counters.length.Load())
+}
+
+// Capacity returns the maximum number of items.
+func (cache *SampledLRUCache) Capacity() int {
+	return int(cache.counters.capacity.Load())
+}
+
+// Resize changes the capacity, evicting sampled items until the cache fits.
+func (cache *SampledLRUCache) Resize(capacity int) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.capacity = capacity
+	cache.counters.capacity.Store(int64(capacity))
+	now := cache.clock.Now()
+	for isOver(len(cache.keys), capacity) {
+		victim := cache.victim(now)
+		reason := metricReasonEvicted
+		if hasExpired(victim.expiresAt, now) {
+			reason = metricReasonExpired
+		}
+		cache.remove(victim, reason)
+	}
+}
+
+// Stats returns the counters of the cache, without locking.
+func (cache *SampledLRUCache) Stats() Stats {
+	return cache.counters.stats()
+}
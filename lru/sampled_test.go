@@ -0,0 +1,89 @@
+package lru
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampledLRUEvictsLeastRecentlyUsedWhenSamplingAll(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewSampledLRUCache(3, WithEvictionSamples(100), WithClock(clock), WithoutMetrics())
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Set(key, key)
+		clock.Advance(time.Second)
+	}
+	cache.Get("a")
+	clock.Advance(time.Second)
+
+	result := cache.Set("d", "d")
+	assert.True(t, result.Evicted)
+	assert.Equal(t, "b", result.EvictedKey, "Expected the least recently used item to be evicted when every item is sampled")
+	assert.Equal(t, 3, cache.Len())
+	_, found := cache.Get("a")
+	assert.True(t, found)
+}
+
+func TestSampledLRUEvictsExpiredItemsFirst(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewSampledLRUCache(2, WithEvictionSamples(100), WithClock(clock), WithoutMetrics())
+	cache.Set("old", 1)
+	clock.Advance(time.Second)
+	cache.SetWithTTL("short", 2, time.Second)
+	clock.Advance(2 * time.Second)
+
+	result := cache.Set("new", 3)
+	assert.False(t, result.Evicted, "Expected the expired item to make room without counting as an eviction")
+	_, found := cache.Get("old")
+	assert.True(t, found)
+	assert.Equal(t, int64(1), int64(cache.Stats().Expirations))
+}
+
+func TestSampledLRUUpdateKeepsLength(t *testing.T) {
+	cache := NewSampledLRUCache(2, WithoutMetrics())
+	cache.Set("a", 1)
+	assert.Equal(t, SetUpdated, cache.Set("a", 2).Status)
+	value, _ := cache.Get("a")
+	assert.Equal(t, 2, value)
+	assert.Equal(t, 1, cache.Len())
+
+	cache.Remove("a")
+	_, found := cache.Get("a")
+	assert.False(t, found)
+	assert.Zero(t, cache.Len())
+}
+
+func TestSampledLRUResize(t *testing.T) {
+	cache := NewSampledLRUCache(10, WithoutMetrics())
+	for i := range 10 {
+		cache.Set(fmt.Sprint("key", i), i)
+	}
+	cache.Resize(4)
+	assert.Equal(t, 4, cache.Len())
+	assert.Equal(t, 4, cache.Capacity())
+	assert.Equal(t, 6, int(cache.Stats().Evictions))
+}
+
+func TestSampledLRUConcurrentAccess(t *testing.T) {
+	cache := NewSampledLRUCache(100, WithoutMetrics())
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				key := fmt.Sprint("key", (worker*1000+i)%300)
+				if i%4 == 0 {
+					cache.Set(key, i)
+				} else {
+					cache.Get(key)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, cache.Len(), 100)
+}
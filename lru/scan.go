@@ -0,0 +1,138 @@
+package lru
+
+import (
+	"container/heap"
+)
+
+// ScanCursor is the position of a key scan, returned by ScanKeys to resume the scan. The zero value starts a new scan.
+type ScanCursor struct {
+	shard   int    // Index of the shard being scanned, for a ShardedCache
+	after   string // Last key returned, the scan resuming after it
+	resumed bool   // Whether after is set, as the empty string is a valid key
+	done    bool   // Whether every key was returned
+}
+
+// Done reports whether the scan returned every key.
+func (cursor ScanCursor) Done() bool {
+	return cursor.done
+}
+
+// KeyScanner is implemented by the caches listing their keys page by page, like the Redis SCAN command,
+// holding their lock for one page at a time instead of the whole scan. The keys are returned in lexicographic
+// order, so a key present from the start to the end of the scan is returned exactly once, whatever the writes
+// in between, while the keys added or removed during the scan may or may not be.
+type KeyScanner interface {
+	// ScanKeys returns up to count live keys following the cursor, and the cursor of the next page.
+	ScanKeys(cursor ScanCursor, count int) (keys []string, next ScanCursor)
+}
+
+var _ KeyScanner = (*SafeLRUCache)(nil) // Ensure SafeLRUCache supports key scans
+var _ KeyScanner = (*ShardedCache)(nil) // Ensure ShardedCache supports key scans
+
+// ScanKeys returns up to count live keys following the cursor under the read lock. With WithKeyIndex, the page
+// is read from the sorted keys, so it costs O(log n + count); otherwise every item of the underlying cache is
+// visited, so it costs O(n log count). It returns no key and a done cursor if the underlying cache is not
+// an Iterator.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) ScanKeys(cursor ScanCursor, count int) (keys []string, next ScanCursor) {
+	if cursor.done {
+		return nil, cursor
+	}
+	safeCache.readLock()
+	defer safeCache.mutex.RUnlock()
+
+	var more bool
+	if cache, ok := safeCache.cache.(*LRUCache); ok && cache.keys != nil {
+		keys, more = cache.scanIndexed(cursor, max(count, 1))
+	} else if iterator, ok := safeCache.cache.(Iterator); ok {
+		keys, more = scanPage(iterator, cursor, max(count, 1))
+	} else {
+		return nil, ScanCursor{done: true}
+	}
+	if !more {
+		return keys, ScanCursor{shard: cursor.shard, done: true}
+	}
+	return keys, ScanCursor{shard: cursor.shard, after: keys[len(keys)-1], resumed: true}
+}
+
+// ScanKeys returns up to count live keys following the cursor, scanning the shards one after the other,
+// so a page only locks the shards it visits.
+// It is thread-safe.
+func (sharded *ShardedCache) ScanKeys(cursor ScanCursor, count int) (keys []string, next ScanCursor) {
+	count = max(count, 1)
+	for !cursor.done && len(keys) < count {
+		if cursor.shard >= len(sharded.shards) {
+			return keys, ScanCursor{shard: cursor.shard, done: true}
+		}
+		page, next := sharded.shards[cursor.shard].ScanKeys(cursor, count-len(keys))
+		keys = append(keys, page...)
+		cursor = next
+		if cursor.done { // Move on to the next shard
+			cursor = ScanCursor{shard: cursor.shard + 1}
+		}
+	}
+	if cursor.shard >= len(sharded.shards) {
+		cursor.done = true
+	}
+	return keys, cursor
+}
+
+// scanIndexed returns the count smallest live keys following the cursor, in lexicographic order, and whether more
+// keys follow them, seeking the cursor in the key index instead of visiting every item.
+func (cache *LRUCache) scanIndexed(cursor ScanCursor, count int) (keys []string, more bool) {
+	var path [keyIndexMaxLevel]*indexNode
+	cache.keys.predecessors(cursor.after, &path)
+	now := cache.clock.Now()
+	for node := path[0].next[0]; node != nil; node = node.next[0] {
+		if cursor.resumed && node.key <= cursor.after {
+			continue
+		}
+		if len(keys) == count {
+			return keys, true
+		}
+		if elem, found := cache.items[node.key]; found && !elem.Value.(*entry).hasExpired(now) {
+			keys = append(keys, node.key)
+		}
+	}
+	return keys, false
+}
+
+// scanPage returns the count smallest live keys following the cursor, in lexicographic order,
+// and whether more keys follow them.
+func scanPage(iterator Iterator, cursor ScanCursor, count int) (keys []string, more bool) {
+	var page maxKeys
+	iterator.Range(func(key string, _ any) bool {
+		switch {
+		case cursor.resumed && key <= cursor.after:
+		case len(page) < count:
+			heap.Push(&page, key)
+		case key < page[0]:
+			page[0] = key
+			heap.Fix(&page, 0)
+			more = true
+		default:
+			more = true
+		}
+		return true
+	})
+
+	keys = make([]string, len(page))
+	for i := len(page) - 1; i >= 0; i-- {
+		keys[i] = heap.Pop(&page).(string)
+	}
+	return keys, more
+}
+
+// maxKeys is a max-heap of keys, keeping the smallest ones of a page.
+type maxKeys []string
+
+func (keys maxKeys) Len() int           { return len(keys) }
+func (keys maxKeys) Less(i, j int) bool { return keys[i] > keys[j] }
+func (keys maxKeys) Swap(i, j int)      { keys[i], keys[j] = keys[j], keys[i] }
+func (keys *maxKeys) Push(key any)      { *keys = append(*keys, key.(string)) }
+func (keys *maxKeys) Pop() any {
+	old := *keys
+	key := old[len(old)-1]
+	*keys = old[:len(old)-1]
+	return key
+}
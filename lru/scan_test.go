@@ -0,0 +1,85 @@
+package lru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// scanAll scans every key of the cache in pages of count keys, calling between after every page.
+func scanAll(t *testing.T, scanner KeyScanner, count int, between func()) (keys []string, pages int) {
+	var cursor ScanCursor
+	for !cursor.Done() {
+		var page []string
+		page, cursor = scanner.ScanKeys(cursor, count)
+		assert.LessOrEqual(t, len(page), count)
+		keys = append(keys, page...)
+		pages++
+		between()
+	}
+	return keys, pages
+}
+
+func TestScanKeys(t *testing.T) {
+	caches := map[string]interface {
+		Cache
+		KeyScanner
+	}{
+		"safe":    NewSafeLRUCache(100),
+		"lfu":     NewSafe(NewLFUCache(100)),
+		"sharded": NewShardedCache(4, 100),
+		"indexed": NewShardedCache(4, 100, WithKeyIndex()),
+	}
+	for name, cache := range caches {
+		t.Run(name, func(t *testing.T) {
+			var expected []string
+			for i := range 50 {
+				key := fmt.Sprintf("key%02d", i)
+				cache.Set(key, i)
+				expected = append(expected, key)
+			}
+
+			keys, pages := scanAll(t, cache, 7, func() {})
+			assert.ElementsMatch(t, expected, keys)
+			assert.GreaterOrEqual(t, pages, 8)
+		})
+	}
+}
+
+func TestScanKeysDuringWrites(t *testing.T) {
+	cache := NewShardedCache(4, 1000)
+	var stable []string
+	for i := range 40 {
+		key := fmt.Sprintf("stable%02d", i)
+		cache.Set(key, i)
+		stable = append(stable, key)
+	}
+
+	written := 0
+	keys, _ := scanAll(t, cache, 5, func() {
+		cache.Set(fmt.Sprintf("added%02d", written), written) // May or may not be returned
+		cache.Remove(fmt.Sprintf("added%02d", written-1))
+		written++
+	})
+	var scanned []string
+	for _, key := range keys {
+		if key[0] == 's' {
+			scanned = append(scanned, key)
+		}
+	}
+	assert.ElementsMatch(t, stable, scanned, "Expected every key present during the scan to be returned once")
+}
+
+func TestScanKeysSkipsExpired(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewSafeLRUCache(10, WithClock(clock))
+	cache.Set("key1", 1)
+	cache.SetWithTTL("key2", 2, time.Second)
+	clock.Advance(2 * time.Second)
+
+	keys, next := cache.ScanKeys(ScanCursor{}, 10)
+	assert.Equal(t, []string{"key1"}, keys)
+	assert.True(t, next.Done())
+}
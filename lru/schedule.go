@@ -0,0 +1,156 @@
+package lru
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a scheduled invalidation runs, see InvalidationScheduler.
+type Schedule interface {
+	// Next returns the first time the schedule runs strictly after the given one, or the zero time if it never runs again.
+	Next(after time.Time) time.Time
+}
+
+// At returns a Schedule running once, at the given time.
+func At(t time.Time) Schedule {
+	return onceSchedule{at: t}
+}
+
+// onceSchedule runs once, see At.
+type onceSchedule struct {
+	at time.Time
+}
+
+// Next returns the time of the schedule if it is after the given one, the zero time otherwise.
+func (schedule onceSchedule) Next(after time.Time) time.Time {
+	if schedule.at.After(after) {
+		return schedule.at
+	}
+	return time.Time{}
+}
+
+// cronFields are the fields of a cron expression, in order, with their range.
+var cronFields = [5]struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// cronSchedule runs at the minutes matching a cron expression, see Cron.
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays uint64 // Bit i is set if the value i matches
+	anyDay, anyWeekday                     bool   // Whether the field is *, which matters when both are restricted
+	location                               *time.Location
+}
+
+// Cron parses a cron expression of five fields, "minute hour day-of-month month day-of-week", into a Schedule
+// running in the given location, UTC if nil. Every field is *, a value, a range such as 1-5, a step such as */15
+// or 0-30/10, or a comma separated list of them; Sunday is 0 or 7. As with cron, a day matches if either
+// the day of month or the day of week does when both are restricted. For instance "0 0 * * *" runs every midnight,
+// and "30 6 * * 1-5" at 6:30 on weekdays.
+func Cron(expression string, location *time.Location) (Schedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("lru: cron expression %q must have 5 fields", expression)
+	}
+	var sets [len(cronFields)]uint64
+	for i, field := range fields {
+		highest := cronFields[i].max
+		if i == 4 {
+			highest = 7 // Sunday can also be 7
+		}
+		set, err := parseCronField(field, cronFields[i].min, highest)
+		if err != nil {
+			return nil, fmt.Errorf("lru: invalid %s in cron expression %q: %w", cronFields[i].name, expression, err)
+		}
+		sets[i] = set
+	}
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1 // Sunday
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	return &cronSchedule{
+		minutes: sets[0], hours: sets[1], days: sets[2], months: sets[3], weekdays: sets[4],
+		anyDay: fields[2] == "*", anyWeekday: fields[4] == "*",
+		location: location,
+	}, nil
+}
+
+// parseCronField returns the set of the values matched by a field of a cron expression, between lowest and highest.
+func parseCronField(field string, lowest, highest int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		span, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+		}
+		low, high := lowest, highest
+		if span != "*" {
+			first, last, isRange := strings.Cut(span, "-")
+			var err error
+			if low, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("invalid value %q", first)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("invalid value %q", last)
+				}
+			} else if hasStep {
+				high = highest // 5/15 means from 5 on, every 15
+			}
+			if low < lowest || high > highest || low > high {
+				return 0, fmt.Errorf("%q out of range %d-%d", span, lowest, highest)
+			}
+		}
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first minute matching the expression strictly after the given time, or the zero time
+// if none does within five years, e.g. for February 30.
+func (schedule *cronSchedule) Next(after time.Time) time.Time {
+	t := after.In(schedule.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case schedule.months&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, schedule.location)
+		case !schedule.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, schedule.location)
+		case schedule.hours&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, schedule.location)
+		case schedule.minutes&(1<<t.Minute()) == 0:
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay returns whether the day of the time matches the day of month and day of week fields.
+func (schedule *cronSchedule) matchesDay(t time.Time) bool {
+	day := schedule.days&(1<<t.Day()) != 0
+	weekday := schedule.weekdays&(1<<int(t.Weekday())) != 0
+	if schedule.anyDay || schedule.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
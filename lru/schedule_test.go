@@ -0,0 +1,47 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCron(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	start := time.Date(2026, 3, 28, 23, 59, 30, 0, paris) // A Saturday, before the switch to summer time
+	tests := []struct {
+		expression string
+		want       time.Time
+	}{
+		{"0 0 * * *", time.Date(2026, 3, 29, 0, 0, 0, 0, paris)},
+		{"30 6 * * 1-5", time.Date(2026, 3, 30, 6, 30, 0, 0, paris)},
+		{"*/15 * * * *", time.Date(2026, 3, 29, 0, 0, 0, 0, paris)},
+		{"0 3 * * *", time.Date(2026, 3, 29, 3, 0, 0, 0, paris)}, // 2:00 to 3:00 is skipped
+		{"0 12 1 * *", time.Date(2026, 4, 1, 12, 0, 0, 0, paris)},
+		{"0 12 15 * 0", time.Date(2026, 3, 29, 12, 0, 0, 0, paris)}, // Either the day of month or the day of week
+		{"0 0 1 * 7", time.Date(2026, 3, 29, 0, 0, 0, 0, paris)},
+		{"5,10 8-9/1 * 6 *", time.Date(2026, 6, 1, 8, 5, 0, 0, paris)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, test := range tests {
+		schedule, err := Cron(test.expression, paris)
+		if assert.NoError(t, err, test.expression) {
+			assert.True(t, test.want.Equal(schedule.Next(start)), "%s: got %v", test.expression, schedule.Next(start))
+		}
+	}
+
+	for _, expression := range []string{"0 0 * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		_, err := Cron(expression, nil)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestAt(t *testing.T) {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, at, At(at).Next(at.Add(-time.Second)))
+	assert.True(t, At(at).Next(at).IsZero())
+}
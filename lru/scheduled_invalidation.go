@@ -0,0 +1,205 @@
+package lru
+
+import (
+	"container/heap"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrNoKeyspace is returned when invalidating a prefix of a cache unable to find its keys by prefix.
+var ErrNoKeyspace = errors.New("lru: cache does not support keyspace scans")
+
+// Invalidation is a scheduled invalidation that ran, reported to the function given to NewInvalidationScheduler.
+type Invalidation struct {
+	Time     time.Time // When the invalidation ran
+	Keys     []string  // The keys invalidated, if any
+	Prefix   string    // The prefix of the keys invalidated, for InvalidatePrefix
+	Removed  int       // Number of live items removed, only counted for a prefix
+	Schedule Schedule  // The schedule of the invalidation
+}
+
+// Invalidator is what an InvalidationScheduler removes the keys from: a Cache, or the front end of one that must
+// learn about the removals, e.g. a server telling its clients and replicas. Invalidating a prefix also needs
+// a RemoveByPrefix method, like the one of KeyspaceScanner.
+type Invalidator interface {
+	Remove(key string)
+}
+
+// prefixRemover is implemented by the invalidators able to remove the keys starting with a prefix.
+type prefixRemover interface {
+	RemoveByPrefix(prefix string) int
+}
+
+// InvalidationScheduler removes keys or whole namespaces from a cache at scheduled times, e.g. every midnight
+// for the data of a daily report that must roll over at a boundary rather than after a TTL. Its goroutine
+// sleeps until the next invalidation, and is stopped by Close. It is thread-safe if the cache is.
+type InvalidationScheduler struct {
+	cache    Invalidator
+	clock    Clock              // Clock the schedules follow, see WithClock
+	report   func(Invalidation) // Called after every invalidation, nil to not report them
+	mutex    sync.Mutex
+	jobs     invalidationHeap // Pending invalidations, the next one at the root
+	wake     chan struct{}    // Receives a value when an earlier invalidation is scheduled
+	stop     chan struct{}    // Closed to stop the goroutine
+	done     chan struct{}    // Closed when the goroutine returned
+	stopOnce sync.Once
+}
+
+var _ io.Closer = (*InvalidationScheduler)(nil) // Ensure InvalidationScheduler can be closed
+
+// invalidationJob is an invalidation scheduled by an InvalidationScheduler.
+type invalidationJob struct {
+	Invalidation
+	byPrefix bool      // Whether the keys starting with Prefix are invalidated, rather than Keys
+	next     time.Time // When the invalidation runs next
+}
+
+// NewInvalidationScheduler creates an InvalidationScheduler for the cache and starts its goroutine.
+// The function report, if not nil, is called after every invalidation, e.g. to log it, from the goroutine.
+// Only WithClock is used among the options, e.g. to follow the clock of the cache: with a ManualClock,
+// the invalidations run when the clock is advanced past them.
+func NewInvalidationScheduler(cache Invalidator, report func(Invalidation), opts ...Option) *InvalidationScheduler {
+	scheduler := &InvalidationScheduler{
+		cache:  cache,
+		clock:  newOptions(opts...).clock,
+		report: report,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go scheduler.run()
+	return scheduler
+}
+
+// InvalidateKeys removes the keys from the cache every time the schedule runs.
+func (scheduler *InvalidationScheduler) InvalidateKeys(schedule Schedule, keys ...string) {
+	scheduler.add(&invalidationJob{Invalidation: Invalidation{Keys: keys, Schedule: schedule}})
+}
+
+// InvalidatePrefix removes every key starting with prefix from the cache every time the schedule runs,
+// e.g. a namespace such as "report:". It returns ErrNoKeyspace if the cache has no RemoveByPrefix method.
+func (scheduler *InvalidationScheduler) InvalidatePrefix(schedule Schedule, prefix string) error {
+	if _, ok := scheduler.cache.(prefixRemover); !ok {
+		return ErrNoKeyspace
+	}
+	scheduler.add(&invalidationJob{Invalidation: Invalidation{Prefix: prefix, Schedule: schedule}, byPrefix: true})
+	return nil
+}
+
+// add schedules the invalidation, unless its schedule never runs.
+func (scheduler *InvalidationScheduler) add(job *invalidationJob) {
+	if job.next = job.Schedule.Next(scheduler.clock.Now()); job.next.IsZero() {
+		return
+	}
+	scheduler.mutex.Lock()
+	heap.Push(&scheduler.jobs, job)
+	earliest := scheduler.jobs[0] == job
+	scheduler.mutex.Unlock()
+
+	if earliest {
+		select {
+		case scheduler.wake <- struct{}{}:
+		default: // Already woken up
+		}
+	}
+}
+
+// Pending returns the number of scheduled invalidations still due to run.
+func (scheduler *InvalidationScheduler) Pending() int {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	return scheduler.jobs.Len()
+}
+
+// run sleeps until the next invalidation, runs the due ones, and repeats until the scheduler is closed.
+func (scheduler *InvalidationScheduler) run() {
+	defer close(scheduler.done)
+
+	for {
+		scheduler.mutex.Lock()
+		var due <-chan time.Time // Nil, so never ready, while nothing is scheduled
+		if scheduler.jobs.Len() > 0 {
+			due = scheduler.after(scheduler.jobs[0].next.Sub(scheduler.clock.Now()))
+		}
+		scheduler.mutex.Unlock()
+
+		select {
+		case <-due:
+			scheduler.runDue()
+		case <-scheduler.wake:
+		case <-scheduler.stop:
+			return
+		}
+	}
+}
+
+// after returns a channel receiving a value once the clock moved by the duration: from the clock itself
+// if it can wake the goroutine, like ManualClock, and in real time otherwise.
+func (scheduler *InvalidationScheduler) after(duration time.Duration) <-chan time.Time {
+	if clock, ok := scheduler.clock.(afterClock); ok {
+		return clock.After(duration)
+	}
+	return time.After(duration) // Collected once unreferenced, even if it did not fire
+}
+
+// runDue runs the invalidations due by now, and schedules their next run.
+func (scheduler *InvalidationScheduler) runDue() {
+	now := scheduler.clock.Now()
+	var due []*invalidationJob
+	scheduler.mutex.Lock()
+	for scheduler.jobs.Len() > 0 && !scheduler.jobs[0].next.After(now) {
+		due = append(due, heap.Pop(&scheduler.jobs).(*invalidationJob))
+	}
+	scheduler.mutex.Unlock()
+
+	for _, job := range due {
+		invalidation := job.Invalidation
+		invalidation.Time = now
+		if job.byPrefix {
+			invalidation.Removed = scheduler.cache.(prefixRemover).RemoveByPrefix(invalidation.Prefix)
+		} else {
+			for _, key := range invalidation.Keys {
+				scheduler.cache.Remove(key)
+			}
+		}
+		if scheduler.report != nil {
+			scheduler.report(invalidation)
+		}
+
+		if job.next = job.Schedule.Next(now); !job.next.IsZero() {
+			scheduler.mutex.Lock()
+			heap.Push(&scheduler.jobs, job)
+			scheduler.mutex.Unlock()
+		}
+	}
+}
+
+// Close stops the goroutine of the scheduler, dropping the pending invalidations.
+// It waits for the invalidations running, if any.
+func (scheduler *InvalidationScheduler) Close() error {
+	scheduler.stopOnce.Do(func() { close(scheduler.stop) })
+	<-scheduler.done
+	return nil
+}
+
+// invalidationHeap is a heap of invalidations, the next one to run at the root.
+type invalidationHeap []*invalidationJob
+
+func (h invalidationHeap) Len() int { return len(h) }
+
+func (h invalidationHeap) Less(i, j int) bool { return h[i].next.Before(h[j].next) }
+
+func (h invalidationHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *invalidationHeap) Push(x any) { *h = append(*h, x.(*invalidationJob)) }
+
+func (h *invalidationHeap) Pop() any {
+	old := *h
+	job := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return job
+}
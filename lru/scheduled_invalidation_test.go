@@ -0,0 +1,49 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidationScheduler(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewSafeLRUCache(10, WithClock(clock), WithoutMetrics())
+	cache.Set("report:daily", 1)
+	cache.Set("report:weekly", 2)
+	cache.Set("user:1", 3)
+	cache.Set("user:2", 4)
+
+	invalidations := make(chan Invalidation, 2)
+	scheduler := NewInvalidationScheduler(cache, func(invalidation Invalidation) { invalidations <- invalidation }, WithClock(clock))
+	defer scheduler.Close()
+	scheduler.InvalidateKeys(At(clock.Now().Add(time.Hour)), "user:2")
+	assert.NoError(t, scheduler.InvalidatePrefix(At(clock.Now().Add(2*time.Minute)), "report:"))
+	scheduler.InvalidateKeys(At(clock.Now().Add(time.Minute)), "user:1")
+	scheduler.InvalidateKeys(At(clock.Now().Add(-time.Second)), "user:2") // Never runs
+	assert.Equal(t, 3, scheduler.Pending())
+
+	assert.Empty(t, invalidations, "Expected nothing to run before the clock is advanced")
+
+	now := clock.Advance(time.Minute) // Wakes the goroutine, or is seen by it when it computes its wait
+	first := <-invalidations
+	assert.Equal(t, []string{"user:1"}, first.Keys)
+	assert.Equal(t, now, first.Time)
+	_, found := cache.Get("user:1")
+	assert.False(t, found)
+	assert.Equal(t, 3, cache.Len(), "Expected the prefix not to be invalidated yet")
+
+	clock.Advance(time.Minute)
+	second := <-invalidations
+	assert.Equal(t, "report:", second.Prefix)
+	assert.Equal(t, 2, second.Removed)
+	assert.Equal(t, 1, cache.Len())
+	_, found = cache.Get("user:2")
+	assert.True(t, found)
+	assert.Equal(t, 1, scheduler.Pending(), "Expected the invalidations scheduled once to be dropped")
+
+	other := NewInvalidationScheduler(NewLFUCache(10, WithoutMetrics()), nil)
+	defer other.Close()
+	assert.ErrorIs(t, other.InvalidatePrefix(At(time.Now().Add(time.Hour)), "report:"), ErrNoKeyspace)
+}
@@ -0,0 +1,85 @@
+package lru
+
+// SetStatus is the outcome of a write.
+type SetStatus string
+
+const (
+	SetAdded    SetStatus = "added"    // A new item was inserted
+	SetUpdated  SetStatus = "updated"  // An existing item was overridden
+	SetExpired  SetStatus = "expired"  // The ttl was not positive, the item was removed instead
+	SetBuffered SetStatus = "buffered" // The write was queued in a write buffer and will be applied later
+	SetRejected SetStatus = "rejected" // The value could not be written, e.g. to the slab storage
+	SetDisabled SetStatus = "disabled" // The capacity of the cache is zero, nothing is stored
+
+	SetThrottled SetStatus = "throttled" // The cache was full and the eviction rate limit was reached, see WithEvictionRateLimit
+	SetDenied    SetStatus = "denied"    // The admission hook refused the item, see WithAdmission
+)
+
+// SetResult describes the outcome of a write, including the item pushed out to make room for it.
+type SetResult struct {
+	Status       SetStatus `json:"status"`
+	Evicted      bool      `json:"evicted,omitempty"`    // Whether an item was evicted to make room for the new one
+	EvictedKey   string    `json:"evictedKey,omitempty"` // Key of the evicted item
+	EvictedValue any       `json:"-"`                    // Value of the evicted item, only with WithPreviousValues or SetAndReturnEvicted
+	Previous     any       `json:"-"`                    // Value replaced by an update, only with WithPreviousValues
+	HasPrevious  bool      `json:"-"`                    // Whether Previous was set, as nil is a valid value
+}
+
+// EvictionReporter is implemented by the caches returning the item a write evicted, e.g. so a write-back
+// cache can persist the victim to a slower storage instead of losing it.
+type EvictionReporter interface {
+	SetAndReturnEvicted(key string, value any) (evictedKey string, evictedValue any, evicted bool)
+}
+
+var _ EvictionReporter = (*LRUCache)(nil)     // Ensure LRUCache reports the evictions
+var _ EvictionReporter = (*LFUCache)(nil)     // Ensure LFUCache reports the evictions
+var _ EvictionReporter = (*SafeLRUCache)(nil) // Ensure SafeLRUCache reports the evictions
+var _ EvictionReporter = (*ShardedCache)(nil) // Ensure ShardedCache reports the evictions
+
+// SetAndReturnEvicted adds or updates an item like Set, so with the ttl given by WithTTLPolicy if any,
+// and returns the item evicted to make room for it, if any.
+func (cache *LRUCache) SetAndReturnEvicted(key string, value any) (evictedKey string, evictedValue any, evicted bool) {
+	key = cache.transform.apply(key)
+	expiration := counterExpiration(cache.clock.Now(), cache.ttlPolicy.apply(key, value))
+	result := cache.set(key, value, expiration, nil, true)
+	return result.EvictedKey, result.EvictedValue, result.Evicted
+}
+
+// SetAndReturnEvicted adds or updates an item like Set, so with the ttl given by WithTTLPolicy if any,
+// and returns the item evicted to make room for it, if any.
+func (cache *LFUCache) SetAndReturnEvicted(key string, value any) (evictedKey string, evictedValue any, evicted bool) {
+	key = cache.transform.apply(key)
+	expiration := counterExpiration(cache.clock.Now(), cache.ttlPolicy.apply(key, value))
+	result := cache.set(key, value, expiration, true)
+	return result.EvictedKey, result.EvictedValue, result.Evicted
+}
+
+// SetAndReturnEvicted adds or updates an item like Set,
+// and returns the item evicted to make room for it, if any.
+// The write is applied synchronously, even with a write buffer, and nothing is reported
+// if the underlying cache does not implement EvictionReporter.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) SetAndReturnEvicted(key string, value any) (evictedKey string, evictedValue any, evicted bool) {
+	key = safeCache.transform.apply(key)
+	safeCache.lockOrdered()
+	defer safeCache.mutex.Unlock()
+
+	if reporter, ok := safeCache.cache.(EvictionReporter); ok {
+		return reporter.SetAndReturnEvicted(key, value)
+	}
+	safeCache.cache.Set(key, value)
+	return "", nil, false
+}
+
+// SetAndReturnEvicted adds or updates an item like Set in the shard holding the key,
+// and returns the item evicted from that shard to make room for it, if any.
+// It is thread-safe.
+func (sharded *ShardedCache) SetAndReturnEvicted(key string, value any) (evictedKey string, evictedValue any, evicted bool) {
+	key = sharded.transform.apply(key)
+	return sharded.shard(key).SetAndReturnEvicted(key, value)
+}
+
+// String returns the status, e.g. "added".
+func (result SetResult) String() string {
+	return string(result.Status)
+}
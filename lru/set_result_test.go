@@ -0,0 +1,60 @@
+package lru
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetResultEviction(t *testing.T) {
+	for name, cache := range map[string]Cache{"lru": NewLRUCache(2), "lfu": NewLFUCache(2)} {
+		t.Run(name, func(t *testing.T) {
+			assert.False(t, cache.Set("key1", "value1").Evicted)
+			cache.Set("key2", "value2")
+
+			result := cache.Set("key3", "value3")
+			assert.Equal(t, SetResult{Status: SetAdded, Evicted: true, EvictedKey: "key1"}, result)
+			assert.Equal(t, "added", result.String())
+		})
+	}
+}
+
+func TestSetResultPreviousValue(t *testing.T) {
+	for name, cache := range map[string]Cache{"lru": NewLRUCache(2, WithPreviousValues()), "lfu": NewLFUCache(2, WithPreviousValues())} {
+		t.Run(name, func(t *testing.T) {
+			assert.False(t, cache.Set("key1", "value1").HasPrevious)
+
+			result := cache.Set("key1", "value2")
+			assert.Equal(t, SetUpdated, result.Status)
+			assert.True(t, result.HasPrevious)
+			assert.Equal(t, "value1", result.Previous)
+		})
+	}
+
+	result := NewLRUCache(2).Set("key1", "value1")
+	assert.False(t, result.HasPrevious) // Not requested
+}
+
+func TestSetAndReturnEvicted(t *testing.T) {
+	caches := map[string]EvictionReporter{
+		"lru":     NewLRUCache(2),
+		"lfu":     NewLFUCache(2),
+		"safe":    NewSafeLRUCache(2),
+		"sharded": NewShardedCache(1, 2),
+		"slabs":   NewLRUCache(2, WithSlabStorage(StringCodec{}, 64)),
+	}
+	for name, cache := range caches {
+		t.Run(name, func(t *testing.T) {
+			_, _, evicted := cache.SetAndReturnEvicted("key1", "value1")
+			assert.False(t, evicted)
+			cache.SetAndReturnEvicted("key2", "value2")
+			_, _, evicted = cache.SetAndReturnEvicted("key2", "value2_updated") // Updates don't evict
+			assert.False(t, evicted)
+
+			key, value, evicted := cache.SetAndReturnEvicted("key3", "value3")
+			assert.True(t, evicted)
+			assert.Equal(t, "key1", key)
+			assert.Equal(t, "value1", value)
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package lru
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shadowMaxPending is the number of changes a shadow state buffers between two refreshes,
+// beyond which it drops them and reloads the items from the cache on the next refresh instead.
+const shadowMaxPending = 4096
+
+// WithShadowState makes an ObservableCache maintain a copy of its items from their events,
+// so State and StatePage no longer hold the cache lock while walking and formatting every item.
+// The events are only buffered while the cache is locked, and applied when the state is requested,
+// at most once per maxStaleness: the returned state may lag the cache by up to maxStaleness.
+// A zero maxStaleness applies the buffered events on every call. Ignored by the other caches.
+func WithShadowState(maxStaleness time.Duration) Option {
+	return func(o *options) {
+		o.shadowState = true
+		o.shadowStaleness = maxStaleness
+	}
+}
+
+// shadowItem is the copy of an item of the cache in a shadow state.
+type shadowItem struct {
+	value     any
+	expiresAt time.Time
+	frequency int    // Accesses counted like the LFUCache does, only reported for LFU caches
+	sequence  uint64 // Order of the last promotion, the most recently promoted item coming first in its frequency
+}
+
+// shadowSnapshot is a state rendered by a shadow state.
+type shadowSnapshot struct {
+	state      ObservableCacheState
+	renderedAt time.Time // Wall time of the rendering, the cache clock possibly being a manual one
+}
+
+// shadowState is a copy of the items of an ObservableCache, updated from its events.
+// Both policies order their items by frequency then by recency, the LRU items all having a zero frequency,
+// so a promotion only bumps the sequence of the item and the order is restored by sorting when rendering.
+type shadowState struct {
+	staleness time.Duration // Maximum age of the rendered state
+
+	pendingMutex sync.Mutex    // Protects pending and resync, only held briefly by the publishers
+	pending      []StateChange // The changes since the last refresh, oldest first
+	resync       bool          // Whether changes were dropped, so the items must be reloaded from the cache
+
+	mutex    sync.Mutex             // Serializes the refreshes, protecting the fields below
+	items    map[string]*shadowItem // The items of the cache by key
+	sequence uint64                 // The sequence of the last promotion
+	version  uint64                 // The version of the last applied change
+	policy   string                 // The policy of the cache, empty if not supported
+	window   int                    // The window of WithTimeAwareEviction of the cache, ranking the evictions
+	clock    Clock                  // The clock of the cache, deciding which items expired
+
+	snapshot atomic.Pointer[shadowSnapshot] // The state rendered by the last refresh, nil before the first one
+}
+
+// newShadowState creates a shadow state loading the items of the cache on its first refresh.
+func newShadowState(staleness time.Duration) *shadowState {
+	return &shadowState{staleness: staleness, resync: true}
+}
+
+// record buffers the change, to be applied by the next refresh.
+func (shadow *shadowState) record(change StateChange) {
+	shadow.pendingMutex.Lock()
+	defer shadow.pendingMutex.Unlock()
+
+	if shadow.resync {
+		return // The items are reloaded anyway
+	}
+	if len(shadow.pending) == shadowMaxPending {
+		shadow.pending, shadow.resync = nil, true
+		return
+	}
+	shadow.pending = append(shadow.pending, change)
+}
+
+// current returns the rendered state if younger than the staleness, otherwise refreshes it.
+func (shadow *shadowState) current(observable *ObservableCache) ObservableCacheState {
+	if snapshot := shadow.snapshot.Load(); snapshot != nil && time.Since(snapshot.renderedAt) < shadow.staleness {
+		return snapshot.state
+	}
+
+	shadow.mutex.Lock()
+	defer shadow.mutex.Unlock()
+
+	if snapshot := shadow.snapshot.Load(); snapshot != nil && time.Since(snapshot.renderedAt) < shadow.staleness {
+		return snapshot.state // Refreshed by a concurrent call meanwhile
+	}
+
+	shadow.pendingMutex.Lock()
+	pending, resync := shadow.pending, shadow.resync
+	shadow.pending = nil
+	shadow.pendingMutex.Unlock()
+
+	if resync {
+		shadow.reload(observable)
+	} else {
+		for _, change := range pending {
+			shadow.apply(change)
+		}
+	}
+	state := shadow.render(observable.Cache.Capacity(), observable.preview)
+	shadow.snapshot.Store(&shadowSnapshot{state: state, renderedAt: time.Now()})
+	return state
+}
+
+// apply updates the items with the change, as the cache did.
+func (shadow *shadowState) apply(change StateChange) {
+	shadow.version = change.Version
+	item := shadow.items[change.Key]
+	switch change.Type {
+	case EventAdded:
+		item = &shadowItem{}
+		shadow.items[change.Key] = item
+	case EventRemoved:
+		delete(shadow.items, change.Key)
+		return
+	}
+	if item == nil {
+		return // Every hit or update follows the addition of its key, the reload included
+	}
+	if change.Type != EventHit {
+		item.value, item.expiresAt = change.Value, change.ExpiresAt
+	}
+	if shadow.policy == metricCacheTypeLFU {
+		item.frequency++ // Additions, updates and hits are all counted
+	}
+	shadow.sequence++
+	item.sequence = shadow.sequence
+}
+
+// reload copies the items of the cache, under its lock, dropping the pending changes they already include.
+func (shadow *shadowState) reload(observable *ObservableCache) {
+	observable.Cache.lock() // Apply the pending promotions, if any, so the order is up to date
+	defer observable.Cache.mutex.Unlock()
+
+	shadow.items, shadow.sequence, shadow.policy, shadow.window = make(map[string]*shadowItem), 0, "", 0
+	switch cache := observable.Cache.cache.(type) {
+	case *LRUCache:
+		shadow.policy, shadow.clock, shadow.window = metricCacheTypeLRU, cache.clock, cache.timeAware
+		if cache.timeAware > 1 {
+			shadow.policy = metricCacheTypeTLRU
+		}
+		for e := cache.usageOrder.Back(); e != nil; e = e.Prev() { // The least recently used item gets the lowest sequence
+			ent := e.Value.(*entry)
+			shadow.sequence++
+			shadow.items[ent.key] = &shadowItem{value: cache.load(ent), expiresAt: ent.expiresAt, sequence: shadow.sequence}
+		}
+	case *LFUCache:
+		shadow.policy, shadow.clock = metricCacheTypeLFU, cache.clock
+		var ordered []*lfuEntry
+		cache.eachByFrequency(func(ent *lfuEntry) { ordered = append(ordered, ent) })
+		for i := len(ordered) - 1; i >= 0; i-- {
+			ent := ordered[i]
+			shadow.sequence++
+			shadow.items[ent.key] = &shadowItem{value: ent.value, expiresAt: ent.expiresAt, frequency: ent.frequency, sequence: shadow.sequence}
+		}
+	}
+
+	// The changes are published under the cache lock, so none is missed or applied twice
+	shadow.version = observable.journal.current()
+	shadow.pendingMutex.Lock()
+	shadow.pending, shadow.resync = nil, false
+	shadow.pendingMutex.Unlock()
+}
+
+// render returns the state of the items, ordered like lruState and lfuState do.
+func (shadow *shadowState) render(capacity int, preview valuePreview) ObservableCacheState {
+	state := ObservableCacheState{Policy: shadow.policy, Version: shadow.version, Capacity: capacity}
+	if shadow.policy == "" {
+		return state // Not supported, like in StatePage
+	}
+
+	keys := make([]string, 0, len(shadow.items))
+	for key := range shadow.items {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		first, second := shadow.items[a], shadow.items[b]
+		return cmp.Or(cmp.Compare(second.frequency, first.frequency), cmp.Compare(second.sequence, first.sequence))
+	})
+
+	state.Items = make([]ObservableCacheItem, len(keys))
+	for i, key := range keys {
+		item := shadow.items[key]
+		state.Items[i] = ObservableCacheItem{
+			Key:       key,
+			Value:     preview.render(key, item.value), // Convert value to string for JSON serialization
+			ExpiresAt: item.expiresAt,
+		}
+		if shadow.policy == metricCacheTypeLFU {
+			state.Items[i].Frequency = item.frequency
+		}
+		if i > 0 {
+			state.Items[i].Prev = keys[i-1]
+			state.Items[i-1].Next = key
+		}
+	}
+	rankEvictions(state.Items, shadow.clock.Now(), shadow.window)
+	return state
+}
+
+// pendingChanges returns the number of buffered changes.
+func (shadow *shadowState) pendingChanges() int {
+	shadow.pendingMutex.Lock()
+	defer shadow.pendingMutex.Unlock()
+
+	return len(shadow.pending)
+}
@@ -0,0 +1,104 @@
+package lru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exerciseObservable runs the same operations on every cache: additions, updates, hits, misses, removals, evictions and expirations.
+func exerciseObservable(clock *ManualClock, observables ...*ObservableCache) {
+	for _, observable := range observables {
+		cache := observable.Cache
+		cache.Set("a", 1)
+		cache.SetWithTTL("b", 2, time.Second)
+		cache.Set("c", 3)
+		cache.Get("a")
+		cache.Get("missing")
+		cache.Set("c", 30)
+		cache.Set("d", 4)
+		cache.Get("a")
+		cache.Set("e", 5) // Evicts an item
+		cache.Remove("d")
+	}
+	clock.Advance(2 * time.Second) // b expires, waiting to be reclaimed
+}
+
+func TestShadowStateMatchesLRUState(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	locked := NewObservableCache(4, WithClock(clock), WithoutMetrics())
+	shadowed := NewObservableCache(4, WithClock(clock), WithoutMetrics(), WithShadowState(0))
+	shadowed.State() // Loads the empty cache, the following changes being applied from the events
+
+	exerciseObservable(clock, locked, shadowed)
+	assert.Equal(t, locked.State(), shadowed.State())
+	assert.Equal(t, locked.StatePage(StateQuery{Prefix: "c", OmitValues: true}), shadowed.StatePage(StateQuery{Prefix: "c", OmitValues: true}))
+}
+
+func TestShadowStateMatchesLFUState(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	locked := NewObservableCacheFrom(NewSafeLRUCacheFrom(NewLFUCache(4, WithClock(clock), WithoutMetrics())))
+	shadowed := NewObservableCacheFrom(NewSafeLRUCacheFrom(NewLFUCache(4, WithClock(clock), WithoutMetrics())), WithShadowState(0))
+	shadowed.State()
+
+	exerciseObservable(clock, locked, shadowed)
+	assert.Equal(t, locked.State(), shadowed.State())
+}
+
+func TestShadowStateMatchesTLRUState(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	locked := NewObservableCacheFrom(NewSafeLRUCacheFrom(NewTLRUCache(4, WithClock(clock), WithoutMetrics())))
+	shadowed := NewObservableCacheFrom(NewSafeLRUCacheFrom(NewTLRUCache(4, WithClock(clock), WithoutMetrics())), WithShadowState(0))
+	shadowed.State()
+
+	exerciseObservable(clock, locked, shadowed)
+	assert.Equal(t, locked.State(), shadowed.State())
+	assert.Equal(t, metricCacheTypeTLRU, shadowed.State().Policy)
+}
+
+func TestTLRUStateRanksTheSoonestExpiryFirst(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	observable := NewObservableCacheFrom(NewSafeLRUCacheFrom(NewTLRUCache(4, WithClock(clock), WithoutMetrics())), WithShadowState(0))
+	observable.Cache.Set("a", 1)
+	observable.Cache.SetWithTTL("b", 2, time.Hour)
+	observable.Cache.SetWithTTL("c", 3, time.Minute)
+
+	state := observable.State()
+	distances := make(map[string]int)
+	for _, item := range state.Items {
+		distances[item.Key] = item.DistanceFromEviction
+	}
+	assert.Equal(t, map[string]int{"c": 0, "b": 1, "a": 2}, distances, "Expected the evictions ranked like the victims of the time-aware window")
+
+	observable.Cache.Set("d", 4)
+	observable.Cache.Set("e", 5)
+	_, found := observable.Cache.Get("c")
+	assert.False(t, found, "Expected the item ranked first to be evicted first")
+}
+
+func TestShadowStateStaleness(t *testing.T) {
+	observable := NewObservableCache(4, WithoutMetrics(), WithShadowState(time.Hour))
+	observable.Cache.Set("a", 1)
+	assert.Len(t, observable.State().Items, 1)
+
+	observable.Cache.Set("b", 2)
+	state := observable.State()
+	assert.Len(t, state.Items, 1, "Expected the state rendered less than the staleness ago")
+	assert.Equal(t, uint64(1), state.Version, "Expected the version of the rendered state")
+}
+
+func TestShadowStateReloadsAfterOverflow(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	locked := NewObservableCache(10, WithClock(clock), WithoutMetrics())
+	shadowed := NewObservableCache(10, WithClock(clock), WithoutMetrics(), WithShadowState(0))
+	shadowed.State()
+
+	for i := range shadowMaxPending {
+		locked.Cache.Set(fmt.Sprint(i), i)
+		shadowed.Cache.Set(fmt.Sprint(i), i)
+	}
+	assert.Equal(t, 0, shadowed.DebugInfo().Structures["shadow_pending"], "Expected the changes to be dropped once overflowing")
+	assert.Equal(t, locked.State(), shadowed.State())
+}
@@ -0,0 +1,272 @@
+package lru
+
+import (
+	"cmp"
+	"errors"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShardedCache spreads the keys over several SafeLRUCache shards, each with its own lock,
+// reducing lock contention under concurrent load.
+// Each shard evicts independently, so the eviction order is only LRU within a shard.
+// With WithRebalancing, the total capacity is periodically redistributed following the load of each shard.
+type ShardedCache struct {
+	shards   []*SafeLRUCache // The shards, selected by the hash of the key
+	capacity int             // Total capacity of the shards
+	loads    []atomic.Int64  // Operations per shard since the last rebalancing, nil without rebalancing
+	stop     chan struct{}   // Closed to stop the rebalancing goroutine, nil without rebalancing
+	stopOnce sync.Once       // Ensures the rebalancing goroutine is stopped once
+
+	transform KeyTransform // Rewrites the keys before every operation, nil without WithKeyTransform
+	hash      KeyHash      // Picks the shard of the keys, nil for FNV-1a
+
+	rebalancing sync.Mutex // Serializes Rebalance, so the capacities computed from the current ones still add up
+	generations sync.Mutex // Serializes the generation changes, so the shards share their generation numbers
+}
+
+var _ Cache = (*ShardedCache)(nil)     // Ensure ShardedCache implements the Cache interface
+var _ io.Closer = (*ShardedCache)(nil) // Ensure ShardedCache can be closed
+
+// NewShardedCache creates a cache of the given total capacity, split evenly between the shards.
+// Every shard holds at least one item, so a positive capacity below the number of shards is rounded up to it.
+// With an Unbounded capacity, every shard is unbounded. The options apply to every shard, e.g. WithAccessBuffer enables batched promotions in each of them.
+func NewShardedCache(shards int, capacity int, opts ...Option) *ShardedCache {
+	shards = max(shards, 1)
+	o := newOptions(opts...)
+	sharded := &ShardedCache{
+		shards:    make([]*SafeLRUCache, shards),
+		capacity:  capacity,
+		transform: o.keyTransform,
+		hash:      o.keyHash,
+	}
+	// The keys are transformed once, before picking their shard
+	opts = append(slices.Clip(opts), WithKeyTransform(nil), WithMetricsName(cmp.Or(o.name, metricCacheTypeShardedLRU)))
+	for i := range shards {
+		// Spread the remainder over the first shards, so the capacities add up to the total
+		shardCapacity := capacity / shards
+		if i < capacity%shards {
+			shardCapacity++
+		}
+		if capacity < 0 {
+			shardCapacity = Unbounded
+		} else if capacity > 0 {
+			shardCapacity = max(shardCapacity, 1) // A capacity of zero would disable the shard
+		}
+
+		shardOpts := opts
+		if o.shardMetrics {
+			shardOpts = append(slices.Clip(opts), withShardLabel(strconv.Itoa(i)))
+		}
+		sharded.shards[i] = NewSafe(NewLRUCache(shardCapacity, shardOpts...), shardOpts...)
+	}
+
+	if o.rebalanceInterval > 0 {
+		sharded.loads = make([]atomic.Int64, shards)
+		sharded.stop = make(chan struct{})
+		go sharded.rebalanceEvery(o.rebalanceInterval)
+	}
+	return sharded
+}
+
+// shard returns the shard holding the key, using the FNV-1a hash of the key unless WithKeyHash is used.
+// The hash is computed inline to keep the lookup free of allocations.
+func (sharded *ShardedCache) shard(key string) *SafeLRUCache {
+	var index uint32
+	if sharded.hash != nil {
+		index = uint32(sharded.hash(key) % uint64(len(sharded.shards)))
+	} else {
+		const (
+			offset32 = 2166136261
+			prime32  = 16777619
+		)
+		hash := uint32(offset32)
+		for i := 0; i < len(key); i++ {
+			hash ^= uint32(key[i])
+			hash *= prime32
+		}
+		index = hash % uint32(len(sharded.shards))
+	}
+	if sharded.loads != nil {
+		sharded.loads[index].Add(1)
+	}
+	return sharded.shards[index]
+}
+
+// Get retrieves an item from the shard holding the key.
+// It returns the value and a boolean indicating whether the item was found.
+// It is thread-safe.
+func (sharded *ShardedCache) Get(key string) (value any, found bool) {
+	key = sharded.transform.apply(key)
+	return sharded.shard(key).Get(key)
+}
+
+// Set adds or updates an item with no expiration in the shard holding the key.
+// It is thread-safe.
+func (sharded *ShardedCache) Set(key string, value any) SetResult {
+	key = sharded.transform.apply(key)
+	return sharded.shard(key).Set(key, value)
+}
+
+// SetWithTTL adds or updates an item expiring after ttl in the shard holding the key.
+// It is thread-safe.
+func (sharded *ShardedCache) SetWithTTL(key string, value any, ttl time.Duration) SetResult {
+	key = sharded.transform.apply(key)
+	return sharded.shard(key).SetWithTTL(key, value, ttl)
+}
+
+// Remove deletes an item from the shard holding the key.
+// It is thread-safe.
+func (sharded *ShardedCache) Remove(key string) {
+	key = sharded.transform.apply(key)
+	sharded.shard(key).Remove(key)
+}
+
+// Increment atomically adds delta to the int64 counter stored under key and returns the new value.
+// It is thread-safe.
+func (sharded *ShardedCache) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	key = sharded.transform.apply(key)
+	return sharded.shard(key).Increment(key, delta, ttl)
+}
+
+// Len returns the number of items in every shard.
+// Shards are locked one after the other, so the result is not a consistent snapshot under concurrent writes.
+func (sharded *ShardedCache) Len() int {
+	length := 0
+	for _, shard := range sharded.shards {
+		length += shard.Len()
+	}
+	return length
+}
+
+// Capacity returns the total capacity of the shards, Unbounded if they are.
+func (sharded *ShardedCache) Capacity() int {
+	if sharded.capacity < 0 {
+		return Unbounded
+	}
+	capacity := 0
+	for _, shard := range sharded.shards {
+		capacity += shard.Capacity()
+	}
+	return capacity
+}
+
+// RemoveExpired removes the expired items of every shard and returns how many were removed.
+// It is thread-safe.
+func (sharded *ShardedCache) RemoveExpired() int {
+	removed := 0
+	for _, shard := range sharded.shards {
+		removed += shard.RemoveExpired()
+	}
+	return removed
+}
+
+// Flush blocks until the writes buffered by every shard are applied.
+func (sharded *ShardedCache) Flush() {
+	for _, shard := range sharded.shards {
+		shard.Flush()
+	}
+}
+
+// rebalanceEvery calls Rebalance at the given interval until the cache is closed.
+func (sharded *ShardedCache) rebalanceEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			sharded.Rebalance()
+		case <-sharded.stop:
+			return
+		}
+	}
+}
+
+// Rebalance redistributes the total capacity between the shards in proportion to their load
+// since the previous rebalancing, blended evenly with their current capacity to damp oscillations.
+// Every shard keeps at least a quarter of an even split, so a cold shard can warm up again.
+// Shrunk shards evict their least recently used items. It does nothing without WithRebalancing
+// or when the shards saw no operation since the previous call.
+func (sharded *ShardedCache) Rebalance() {
+	if sharded.loads == nil || sharded.capacity < len(sharded.shards) { // Below, every shard holds a single item
+		return
+	}
+	sharded.rebalancing.Lock()
+	defer sharded.rebalancing.Unlock()
+
+	loads := make([]float64, len(sharded.shards))
+	totalLoad := 0.0
+	for i := range sharded.loads {
+		loads[i] = float64(sharded.loads[i].Swap(0))
+		totalLoad += loads[i]
+	}
+	if totalLoad == 0 {
+		return
+	}
+
+	// Split the capacity above the floor by weight, rounding with the largest remainder method
+	// so the capacities still add up to the total
+	floor := max(sharded.capacity/(4*len(sharded.shards)), 1) // A capacity of zero would disable the shard
+	spare := sharded.capacity - floor*len(sharded.shards)
+	capacities := make([]int, len(sharded.shards))
+	remainders := make([]float64, len(sharded.shards))
+	assigned := 0
+	for i, shard := range sharded.shards {
+		weight := (float64(shard.Capacity())/float64(sharded.capacity) + loads[i]/totalLoad) / 2
+		share := weight * float64(spare)
+		capacities[i] = floor + int(math.Floor(share))
+		remainders[i] = share - math.Floor(share)
+		assigned += capacities[i]
+	}
+	order := make([]int, len(sharded.shards))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for i := 0; assigned < sharded.capacity; i++ {
+		capacities[order[i%len(order)]]++
+		assigned++
+	}
+
+	for i, shard := range sharded.shards {
+		if capacities[i] != shard.Capacity() {
+			shard.Resize(capacities[i])
+		}
+	}
+}
+
+// ShardCapacities returns the current capacity of every shard.
+func (sharded *ShardedCache) ShardCapacities() []int {
+	capacities := make([]int, len(sharded.shards))
+	for i, shard := range sharded.shards {
+		capacities[i] = shard.Capacity()
+	}
+	return capacities
+}
+
+// FlushMetrics adds the counters batched by WithMetricsBatching in every shard to the Prometheus metrics right away.
+// It is thread-safe.
+func (sharded *ShardedCache) FlushMetrics() {
+	for _, shard := range sharded.shards {
+		shard.FlushMetrics()
+	}
+}
+
+// Close stops the rebalancing and closes every shard, stopping their background goroutines.
+func (sharded *ShardedCache) Close() error {
+	if sharded.stop != nil {
+		sharded.stopOnce.Do(func() { close(sharded.stop) })
+	}
+
+	var errs []error
+	for _, shard := range sharded.shards {
+		errs = append(errs, shard.Close())
+	}
+	return errors.Join(errs...)
+}
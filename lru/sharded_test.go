@@ -0,0 +1,137 @@
+package lru
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstructShardedCache(t *testing.T) {
+	cache := NewShardedCache(4, 10)
+	assert.Len(t, cache.shards, 4)
+	assert.Equal(t, 10, cache.Capacity())
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestShardedCacheOperations(t *testing.T) {
+	cache := NewShardedCache(4, 100)
+	for i := range 20 {
+		cache.Set(fmt.Sprintf("key%d", i), i)
+	}
+	assert.Equal(t, 20, cache.Len())
+
+	value, found := cache.Get("key7")
+	assert.True(t, found)
+	assert.Equal(t, 7, value)
+
+	cache.Remove("key7")
+	_, found = cache.Get("key7")
+	assert.False(t, found)
+
+	status := cache.SetWithTTL("key8", "value", 0)
+	assert.Equal(t, SetExpired, status.Status)
+	assert.Equal(t, 18, cache.Len())
+
+	counter, err := cache.Increment("counter", 2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), counter)
+}
+
+func TestShardedCacheSameKeySameShard(t *testing.T) {
+	cache := NewShardedCache(8, 80)
+	assert.Same(t, cache.shard("key1"), cache.shard("key1"))
+}
+
+func TestShardedCacheConcurrent(t *testing.T) {
+	cache := NewShardedCache(4, 1000, WithAccessBuffer(8), WithWriteBuffer(8))
+	defer cache.Close()
+
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				key := fmt.Sprintf("key-%d-%d", worker, i)
+				cache.Set(key, i)
+				cache.Get(key)
+			}
+		}()
+	}
+	wg.Wait()
+	cache.Flush()
+	assert.Equal(t, 800, cache.Len())
+}
+
+func TestShardedCacheRebalance(t *testing.T) {
+	cache := NewShardedCache(2, 100, WithRebalancing(time.Hour))
+	defer cache.Close()
+
+	hot := cache.shards[0]
+	for i := range 200 {
+		if key := fmt.Sprintf("key%d", i); cache.shard(key) == hot {
+			cache.Set(key, i)
+		}
+	}
+	cache.loads[1].Store(0) // Forget the lookups above, so only the hot shard saw operations
+	cache.Rebalance()
+
+	assert.Equal(t, []int{69, 31}, cache.ShardCapacities()) // A quarter of an even split stays with the cold shard
+	assert.Equal(t, 100, cache.Capacity())
+	assert.LessOrEqual(t, hot.Len(), 69)
+}
+
+func TestShardedCacheSmallCapacity(t *testing.T) {
+	cache := NewShardedCache(16, 5, WithoutMetrics(), WithRebalancing(time.Hour))
+	defer cache.Close()
+
+	for i := range 100 {
+		assert.NotEqual(t, SetDisabled, cache.Set(fmt.Sprintf("key%d", i), i).Status, "Expected no shard to be disabled")
+	}
+	cache.Rebalance()
+	assert.NotContains(t, cache.ShardCapacities(), 0)
+
+	skewed := NewShardedCache(4, 20, WithoutMetrics(), WithRebalancing(time.Hour)) // The floor rounds to zero
+	defer skewed.Close()
+	skewed.loads[0].Store(1000)
+	skewed.Rebalance()
+	assert.NotContains(t, skewed.ShardCapacities(), 0)
+	assert.Equal(t, 20, skewed.Capacity())
+}
+
+func TestShardedCacheRebalanceWithoutLoad(t *testing.T) {
+	cache := NewShardedCache(2, 100, WithRebalancing(time.Hour))
+	defer cache.Close()
+
+	cache.Rebalance()
+	assert.Equal(t, []int{50, 50}, cache.ShardCapacities())
+}
+
+func TestShardedCacheRebalancesPeriodically(t *testing.T) {
+	cache := NewShardedCache(2, 100, WithRebalancing(time.Millisecond))
+	defer cache.Close()
+
+	hot := cache.shards[0]
+	for i := range 100 {
+		if key := fmt.Sprintf("key%d", i); cache.shard(key) == hot {
+			cache.Get(key)
+		}
+	}
+	assert.Eventually(t, func() bool { return cache.ShardCapacities()[0] > 50 }, time.Second, time.Millisecond)
+}
+
+func TestSafeLRUCacheResize(t *testing.T) {
+	cache := NewSafeLRUCache(3)
+	cache.Set("key1", "value1")
+	cache.Set("key2", "value2")
+	cache.Set("key3", "value3")
+
+	cache.Resize(2)
+	assert.Equal(t, 2, cache.Capacity())
+	_, found := cache.Get("key1") // The least recently used item was evicted
+	assert.False(t, found)
+	assert.Equal(t, 2, cache.Len())
+}
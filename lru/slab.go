@@ -0,0 +1,164 @@
+package lru
+
+import (
+	"errors"
+	"math"
+)
+
+// minChunkSize is the chunk size of the smallest size class of a slabStore.
+const minChunkSize = 64
+
+// ErrValueTooLarge is returned when an encoded value does not fit in a slab, nor in the chunks of WithValueChunking.
+var ErrValueTooLarge = errors.New("value larger than the slab size, or than the maximum size of WithValueChunking")
+
+// WithValueChunking lets the slab storage hold the values whose encoding is larger than a slab, as memcached
+// clients do: they are split in chunks of the slab size, written to their own slabs, and reassembled by every Get.
+// The values whose encoding is larger than maxSize bytes are still rejected, none if it is not positive.
+// Ignored without WithSlabStorage.
+func WithValueChunking(maxSize int) Option {
+	if maxSize <= 0 {
+		maxSize = math.MaxInt
+	}
+	return func(o *options) {
+		o.maxChunkedSize = maxSize
+	}
+}
+
+// slabRef locates an encoded value in a slabStore.
+// It holds no pointers, so the garbage collector does not scan the entries referencing the values.
+type slabRef struct {
+	class  uint32 // Index of the size class holding the value
+	chunk  uint32 // Index of the chunk holding the value, counted over every slab of the class
+	length uint32 // Length of the encoded value
+}
+
+// slabChunks locates an encoded value larger than a slab, split in chunks of the slab size, see WithValueChunking.
+type slabChunks []slabRef
+
+// slabClass holds the slabs split in chunks of a single size.
+type slabClass struct {
+	chunkSize int      // Size of the chunks of the class
+	slabs     [][]byte // Slabs of the class, each one split in chunks
+	free      []uint32 // Chunks released by removed values, reused first
+	used      uint32   // Number of chunks handed out at least once
+}
+
+// slabStore keeps encoded values in byte slabs, the way memcached does: every slab is split in chunks
+// of one size class, and a value is written to a chunk of the smallest class fitting it.
+// The slabs hold no pointers, so millions of values do not add to the garbage collector scan time.
+type slabStore struct {
+	codec      Codec       // Encodes the values written to the slabs
+	slabSize   int         // Size of every slab, and so the maximum size of an encoded value written at once
+	maxChunked int         // Maximum size of an encoded value split in chunks, zero to reject the values larger than a slab
+	classes    []slabClass // Size classes, doubling from minChunkSize up to slabSize
+}
+
+func newSlabStore(codec Codec, slabSize int, maxChunked int) *slabStore {
+	store := &slabStore{codec: codec, slabSize: max(slabSize, minChunkSize), maxChunked: maxChunked}
+	for chunkSize := minChunkSize; ; chunkSize *= 2 {
+		store.classes = append(store.classes, slabClass{chunkSize: min(chunkSize, store.slabSize)})
+		if chunkSize >= store.slabSize {
+			break
+		}
+	}
+	return store
+}
+
+// put encodes the value and writes it to a free chunk, or to several with WithValueChunking if it is larger than a slab.
+// It returns the slabRef or slabChunks locating the value, and the length of its encoding.
+func (store *slabStore) put(value any) (ref any, length int, err error) {
+	data, err := store.codec.Encode(value)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(data) <= store.slabSize {
+		return store.write(data), len(data), nil
+	}
+	if len(data) > store.maxChunked {
+		return nil, len(data), ErrValueTooLarge
+	}
+
+	chunks := make(slabChunks, 0, (len(data)+store.slabSize-1)/store.slabSize)
+	for start := 0; start < len(data); start += store.slabSize {
+		chunks = append(chunks, store.write(data[start:min(start+store.slabSize, len(data))]))
+	}
+	return chunks, len(data), nil
+}
+
+// write writes the data, no larger than a slab, to a free chunk of the smallest class fitting it.
+func (store *slabStore) write(data []byte) slabRef {
+	class := 0
+	for store.classes[class].chunkSize < len(data) {
+		class++
+	}
+	ref := slabRef{class: uint32(class), chunk: store.classes[class].allocate(store.slabSize), length: uint32(len(data))}
+	copy(store.bytes(ref), data)
+	return ref
+}
+
+// get decodes the value held by the chunk, or reassembled from the chunks of a value larger than a slab.
+// It returns nil if the codec fails, which only happens if it cannot decode its own output.
+func (store *slabStore) get(ref any) any {
+	var data []byte
+	switch ref := ref.(type) {
+	case slabRef:
+		data = store.bytes(ref)
+	case slabChunks:
+		length := 0
+		for _, chunk := range ref {
+			length += int(chunk.length)
+		}
+		data = make([]byte, 0, length)
+		for _, chunk := range ref {
+			data = append(data, store.bytes(chunk)...)
+		}
+	}
+	value, err := store.codec.Decode(data)
+	if err != nil {
+		return nil
+	}
+	return value
+}
+
+// release makes the chunks of the value available to the next values of their class.
+func (store *slabStore) release(ref any) {
+	switch ref := ref.(type) {
+	case slabRef:
+		store.releaseChunk(ref)
+	case slabChunks:
+		for _, chunk := range ref {
+			store.releaseChunk(chunk)
+		}
+	}
+}
+
+// releaseChunk makes the chunk available to the next values of its class.
+func (store *slabStore) releaseChunk(ref slabRef) {
+	class := &store.classes[ref.class]
+	class.free = append(class.free, ref.chunk)
+}
+
+// bytes returns the encoded value held by the chunk, capped so it cannot be appended into the next chunk.
+func (store *slabStore) bytes(ref slabRef) []byte {
+	class := &store.classes[ref.class]
+	perSlab := uint32(store.slabSize / class.chunkSize)
+	start := int(ref.chunk%perSlab) * class.chunkSize
+	end := start + int(ref.length)
+	return class.slabs[ref.chunk/perSlab][start:end:end]
+}
+
+// allocate returns a free chunk, adding a slab to the class when every chunk is in use.
+func (class *slabClass) allocate(slabSize int) uint32 {
+	if n := len(class.free); n > 0 {
+		chunk := class.free[n-1]
+		class.free = class.free[:n-1]
+		return chunk
+	}
+
+	perSlab := uint32(slabSize / class.chunkSize)
+	if class.used == uint32(len(class.slabs))*perSlab {
+		class.slabs = append(class.slabs, make([]byte, slabSize))
+	}
+	class.used++
+	return class.used - 1
+}
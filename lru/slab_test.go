@@ -0,0 +1,84 @@
+package lru
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlabStorageSetAndGet(t *testing.T) {
+	cache := NewLRUCache(5, WithSlabStorage(StringCodec{}, 1024))
+	assert.Equal(t, SetAdded, cache.Set("key1", "value1").Status)
+	assert.Equal(t, SetUpdated, cache.Set("key1", strings.Repeat("x", 100)).Status) // Moves to a larger size class
+
+	value, found := cache.Get("key1")
+	assert.True(t, found)
+	assert.Equal(t, strings.Repeat("x", 100), value)
+	assert.Len(t, cache.slabs.classes[0].free, 1) // The chunk of the previous value was released
+}
+
+func TestSlabStorageRejectsValues(t *testing.T) {
+	cache := NewLRUCache(5, WithSlabStorage(StringCodec{}, 64))
+	assert.Equal(t, SetRejected, cache.Set("key1", 42).Status)
+	assert.Equal(t, SetRejected, cache.Set("key2", strings.Repeat("x", 65)).Status)
+	assert.Equal(t, 0, cache.Len())
+
+	_, err := cache.Increment("counter", 1, time.Minute)
+	assert.ErrorIs(t, err, ErrUnsupportedValue)
+}
+
+func TestSlabStorageReusesChunks(t *testing.T) {
+	cache := NewLRUCache(10, WithSlabStorage(BytesCodec{}, 640))
+	for i := range 1000 {
+		cache.Set(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("value%d", i)))
+	}
+
+	// The evicted values freed their chunks, only one more is needed as values are written before evicting
+	assert.Equal(t, 2, len(cache.slabs.classes[0].slabs))
+	value, found := cache.Get("key999")
+	assert.True(t, found)
+	assert.Equal(t, []byte("value999"), value)
+}
+
+func TestSlabStorageEventsCarryValues(t *testing.T) {
+	var events []Event
+	cache := NewLRUCache(1, WithSlabStorage(StringCodec{}, 64), WithEventListener(func(event Event) {
+		events = append(events, event)
+	}))
+	cache.Set("key1", "value1")
+	cache.Set("key2", "value2")
+
+	assert.Len(t, events, 3)
+	assert.Equal(t, "value1", events[0].Value)
+	assert.Equal(t, EventRemoved, events[1].Type)
+	assert.Equal(t, "value1", events[1].Value)
+}
+
+func TestBytesCodecCopiesOnDecode(t *testing.T) {
+	data := []byte("value")
+	decoded, err := BytesCodec{}.Decode(data)
+	assert.NoError(t, err)
+
+	data[0] = 'V'
+	assert.Equal(t, []byte("value"), decoded)
+}
+
+func TestSlabStorageChunksLargeValues(t *testing.T) {
+	cache := NewLRUCache(5, WithSlabStorage(StringCodec{}, 64), WithValueChunking(200), WithoutMetrics())
+	large := strings.Repeat("abcdefghij", 15) // Three chunks
+	assert.Equal(t, SetAdded, cache.Set("key1", large).Status)
+	assert.Equal(t, SetRejected, cache.Set("key2", strings.Repeat("x", 201)).Status, "Expected the values beyond the maximum size to be rejected")
+
+	value, found := cache.Get("key1")
+	assert.True(t, found)
+	assert.Equal(t, large, value)
+	assert.Len(t, cache.items["key1"].Value.(*entry).value.(slabChunks), 3)
+
+	cache.Set("key1", "small")
+	assert.Len(t, cache.slabs.classes[0].free, 3, "Expected every chunk of the previous value to be released")
+	value, _ = cache.Get("key1")
+	assert.Equal(t, "small", value)
+}
@@ -0,0 +1,102 @@
+package lru
+
+import (
+	"sync"
+	"time"
+)
+
+// Operations recorded in a SlowLog by the caches. Other operations, such as the fetches from a second level cache,
+// can be recorded by the application with Observe.
+const (
+	SlowLoad   = "load"   // A Loader execution of a LoadingCache
+	SlowEncode = "encode" // The encoding of a value written to the slab storage, see WithSlabStorage
+)
+
+// SlowEntry is an operation recorded by a SlowLog.
+type SlowEntry struct {
+	ID        int64         `json:"id"`             // Increasing number of the entry, to spot the entries already seen
+	Time      time.Time     `json:"time"`           // When the operation started
+	Duration  time.Duration `json:"duration"`       // How long the operation took
+	Operation string        `json:"operation"`      // What was done, e.g. SlowLoad
+	Key       string        `json:"key"`            // The key of the operation
+	Size      int           `json:"size,omitempty"` // Size of the encoded value in bytes, for the encodings
+	Error     string        `json:"error,omitempty"`
+}
+
+// SlowLog records the operations slower than a threshold, or encoding values larger than a size threshold,
+// keeping the latest ones, like the SLOWLOG of Redis. It is shared by the caches and loaders given
+// WithSlowLog, and is thread-safe.
+type SlowLog struct {
+	mutex         sync.Mutex
+	threshold     time.Duration // Duration above which an operation is recorded
+	sizeThreshold int           // Size above which an encoding is recorded, zero to only record the slow ones
+	entries       []SlowEntry   // Ring of the latest entries, entries[next] being the oldest once full
+	next          int           // Index of the next entry written in the ring
+	lastID        int64         // Id of the last entry
+}
+
+// NewSlowLog creates a SlowLog keeping the latest capacity operations that took longer than threshold,
+// or encoded a value larger than sizeThreshold bytes, zero to only record the slow encodings.
+func NewSlowLog(capacity int, threshold time.Duration, sizeThreshold int) *SlowLog {
+	return &SlowLog{threshold: threshold, sizeThreshold: sizeThreshold, entries: make([]SlowEntry, 0, max(capacity, 1))}
+}
+
+// Observe records an operation started at the given time and finishing now, if it took longer than the threshold,
+// or if it produced more than the size threshold bytes. It does nothing on a nil SlowLog.
+func (log *SlowLog) Observe(operation string, key string, start time.Time, size int, err error) {
+	if log == nil {
+		return
+	}
+	duration := time.Since(start)
+	if duration <= log.threshold && (log.sizeThreshold <= 0 || size <= log.sizeThreshold) {
+		return
+	}
+
+	entry := SlowEntry{Time: start, Duration: duration, Operation: operation, Key: key, Size: size}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	log.lastID++
+	entry.ID = log.lastID
+	if len(log.entries) < cap(log.entries) {
+		log.entries = append(log.entries, entry)
+	} else {
+		log.entries[log.next] = entry
+	}
+	log.next = (log.next + 1) % cap(log.entries)
+}
+
+// Entries returns up to n of the latest entries, the newest first, or all of them if n is negative.
+func (log *SlowLog) Entries(n int) []SlowEntry {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	if n < 0 || n > len(log.entries) {
+		n = len(log.entries)
+	}
+	entries := make([]SlowEntry, n)
+	for i := range entries {
+		entries[i] = log.entries[(log.next-1-i+len(log.entries))%len(log.entries)]
+	}
+	return entries
+}
+
+// Len returns the number of entries kept.
+func (log *SlowLog) Len() int {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	return len(log.entries)
+}
+
+// Reset removes every entry. The ids keep increasing.
+func (log *SlowLog) Reset() {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	log.entries = log.entries[:0]
+	log.next = 0
+}
@@ -0,0 +1,67 @@
+package lru
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowLogKeepsTheLatestEntries(t *testing.T) {
+	log := NewSlowLog(3, 0, 0)
+	for i := range 5 {
+		log.Observe("op", fmt.Sprintf("key%d", i), time.Now().Add(-time.Millisecond), 0, nil)
+	}
+
+	entries := log.Entries(-1)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, []string{"key4", "key3", "key2"}, []string{entries[0].Key, entries[1].Key, entries[2].Key})
+	assert.Equal(t, int64(5), entries[0].ID)
+	assert.Len(t, log.Entries(2), 2)
+
+	log.Reset()
+	assert.Equal(t, 0, log.Len())
+	log.Observe("op", "key5", time.Now().Add(-time.Millisecond), 0, nil)
+	assert.Equal(t, int64(6), log.Entries(1)[0].ID, "Expected the ids to keep increasing")
+}
+
+func TestSlowLogThresholds(t *testing.T) {
+	log := NewSlowLog(10, time.Hour, 100)
+	log.Observe("op", "fast", time.Now(), 10, nil)
+	log.Observe("op", "large", time.Now(), 101, nil)
+	log.Observe("op", "slow", time.Now().Add(-2*time.Hour), 0, errors.New("failed"))
+
+	entries := log.Entries(-1)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "failed", entries[0].Error)
+	assert.Equal(t, 101, entries[1].Size)
+
+	var disabled *SlowLog
+	disabled.Observe("op", "key", time.Now(), 0, nil) // A nil log records nothing
+}
+
+func TestSlowLogRecordsLoadsAndEncodings(t *testing.T) {
+	log := NewSlowLog(10, 20*time.Millisecond, 32)
+	cache := NewSafeLRUCache(10, WithSlabStorage(StringCodec{}, 1024), WithSlowLog(log))
+	loading := NewLoadingCache(cache, func(ctx context.Context, key string) (any, time.Duration, error) {
+		if key == "slow" {
+			time.Sleep(30 * time.Millisecond)
+		}
+		return strings.Repeat("x", len(key)*10), 0, nil
+	}, WithSlowLog(log))
+
+	loading.Get(context.Background(), "slow") // Slow load, and a 40 bytes value
+	loading.Get(context.Background(), "key")  // Fast load of a 30 bytes value
+
+	entries := log.Entries(-1)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, SlowEncode, entries[0].Operation) // Stored once loaded
+	assert.Equal(t, 40, entries[0].Size)
+	assert.Equal(t, SlowLoad, entries[1].Operation)
+	assert.Equal(t, "slow", entries[1].Key)
+	assert.GreaterOrEqual(t, entries[1].Duration, 30*time.Millisecond)
+}
@@ -0,0 +1,93 @@
+package lru
+
+import (
+	"time"
+)
+
+// Snapshot is an immutable point-in-time view of the live items of a cache, in eviction order.
+// Taking it copies the items under the cache lock, then it can be iterated for as long as needed
+// without blocking the writers, e.g. by a dashboard scanning the whole cache.
+// The values themselves are not copied: a pointer value still refers to the same data as the cache.
+type Snapshot struct {
+	TakenAt time.Time   // Time of the cache clock when the snapshot was taken
+	entries []EntryInfo // The live items, from the most to the least valuable one
+}
+
+// Snapshotter is implemented by the caches able to take a Snapshot of their items.
+type Snapshotter interface {
+	Snapshot() Snapshot
+}
+
+var _ Snapshotter = (*LRUCache)(nil)     // Ensure LRUCache can take snapshots
+var _ Snapshotter = (*LFUCache)(nil)     // Ensure LFUCache can take snapshots
+var _ Snapshotter = (*SafeLRUCache)(nil) // Ensure SafeLRUCache can take snapshots
+var _ Snapshotter = (*ShardedCache)(nil) // Ensure ShardedCache can take snapshots
+
+// Len returns the number of items in the snapshot.
+func (snapshot Snapshot) Len() int {
+	return len(snapshot.entries)
+}
+
+// Range calls fn with every item of the snapshot in eviction order, the next candidate last, until fn returns false.
+func (snapshot Snapshot) Range(fn func(entry EntryInfo) bool) {
+	for _, entry := range snapshot.entries {
+		if !fn(entry) {
+			return
+		}
+	}
+}
+
+// Snapshot returns the live items of the cache, from the most to the least recently used one.
+func (cache *LRUCache) Snapshot() Snapshot {
+	now := cache.clock.Now()
+	entries := make([]EntryInfo, 0, len(cache.items))
+	for e := cache.usageOrder.Front(); e != nil; e = e.Next() {
+		ent := e.Value.(*entry)
+		if ent.hasExpired(now) {
+			continue
+		}
+		entries = append(entries, EntryInfo{Key: ent.key, Value: cache.load(ent), ExpiresAt: wallDeadline(ent.expiresAt, now), Metadata: ent.metadata})
+	}
+	return Snapshot{TakenAt: now, entries: entries}
+}
+
+// Snapshot returns the live items of the cache, from the most to the least frequently used one.
+func (cache *LFUCache) Snapshot() Snapshot {
+	now := cache.clock.Now()
+	entries := make([]EntryInfo, 0, len(cache.items))
+	cache.eachByFrequency(func(ent *lfuEntry) {
+		if ent.hasExpired(now) {
+			return
+		}
+		entries = append(entries, EntryInfo{Key: ent.key, Value: ent.value, ExpiresAt: wallDeadline(ent.expiresAt, now), Metadata: ent.metadata})
+	})
+	return Snapshot{TakenAt: now, entries: entries}
+}
+
+// Snapshot returns the live items of the underlying cache, or an empty snapshot if it does not implement Snapshotter.
+// The pending promotions are applied first, so the order is up to date. Buffered writes are not waited for.
+// It is thread-safe, and only holds the lock while copying the items.
+func (safeCache *SafeLRUCache) Snapshot() Snapshot {
+	safeCache.lock()
+	defer safeCache.mutex.Unlock()
+
+	if snapshotter, ok := safeCache.cache.(Snapshotter); ok {
+		return snapshotter.Snapshot()
+	}
+	return Snapshot{}
+}
+
+// Snapshot returns the live items of every shard, shard after shard, each in its own eviction order.
+// Shards are locked one after the other, so the snapshot is only point-in-time within a shard.
+// TakenAt is the time the first shard was copied.
+func (sharded *ShardedCache) Snapshot() Snapshot {
+	var total Snapshot
+	for i, shard := range sharded.shards {
+		snapshot := shard.Snapshot()
+		if i == 0 {
+			total.TakenAt = snapshot.TakenAt
+		}
+		total.entries = append(total.entries, snapshot.entries...)
+	}
+	return total
+}
@@ -0,0 +1,76 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// snapshotKeys returns the keys of the snapshot in iteration order.
+func snapshotKeys(snapshot Snapshot) []string {
+	var keys []string
+	snapshot.Range(func(entry EntryInfo) bool {
+		keys = append(keys, entry.Key)
+		return true
+	})
+	return keys
+}
+
+func TestLRUSnapshot(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLRUCache(3, WithClock(clock))
+	cache.Set("key1", "value1")
+	cache.SetWithTTL("key2", "value2", time.Minute)
+	cache.SetWithMetadata("key3", "value3", 0, "origin:db")
+	cache.Get("key1")
+
+	snapshot := cache.Snapshot()
+	assert.Equal(t, clock.Now(), snapshot.TakenAt)
+	assert.Equal(t, []string{"key1", "key3", "key2"}, snapshotKeys(snapshot))
+
+	cache.Remove("key1") // The snapshot is not affected by later writes
+	clock.Advance(time.Hour)
+	assert.Equal(t, 3, snapshot.Len())
+	assert.Equal(t, []string{"key3"}, snapshotKeys(cache.Snapshot()), "Expected the expired items to be skipped")
+
+	var entries []EntryInfo
+	snapshot.Range(func(entry EntryInfo) bool {
+		entries = append(entries, entry)
+		return false
+	})
+	assert.Equal(t, []EntryInfo{{Key: "key1", Value: "value1"}}, entries, "Expected Range to stop when fn returns false")
+}
+
+func TestLFUSnapshot(t *testing.T) {
+	cache := NewLFUCache(3)
+	cache.Set("key1", "value1")
+	cache.Set("key2", "value2")
+	cache.Get("key2")
+
+	assert.Equal(t, []string{"key2", "key1"}, snapshotKeys(cache.Snapshot()))
+}
+
+func TestSafeSnapshotDoesNotHoldTheLock(t *testing.T) {
+	cache := NewSafeLRUCache(3, WithAccessBuffer(10))
+	cache.Set("key1", "value1")
+	cache.Set("key2", "value2")
+	cache.Get("key1") // Promotion applied by Snapshot
+
+	snapshot := cache.Snapshot()
+	snapshot.Range(func(entry EntryInfo) bool {
+		cache.Set("key3", "value3") // Would deadlock if the lock was held
+		return true
+	})
+	assert.Equal(t, []string{"key1", "key2"}, snapshotKeys(snapshot))
+	assert.Equal(t, 0, NewSafeLRUCacheFrom(&fakeLRUCache{}).Snapshot().Len())
+}
+
+func TestShardedSnapshot(t *testing.T) {
+	cache := NewShardedCache(4, 8)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		cache.Set(key, key)
+	}
+
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d"}, snapshotKeys(cache.Snapshot()))
+}
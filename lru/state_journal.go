@@ -0,0 +1,73 @@
+package lru
+
+import "sync"
+
+// journalSize is the number of changes an ObservableCache keeps to answer Changes.
+const journalSize = 1024
+
+// StateChange is an event changing the state of an ObservableCache, along with the state version it produced.
+type StateChange struct {
+	Version uint64 `json:"version"`
+	Event
+}
+
+// StateChanges are the changes made to the state of an ObservableCache since a version, oldest first.
+type StateChanges struct {
+	Version uint64        `json:"version"` // The current version of the state
+	Changes []StateChange `json:"changes"`
+}
+
+// stateJournal numbers the changes of the state with a monotonically increasing version,
+// keeping the last journalSize ones so the clients can catch up without reloading the full state.
+type stateJournal struct {
+	mutex   sync.Mutex    // Protects the fields below
+	version uint64        // The version of the last change, zero before any change
+	changes []StateChange // The last changes, oldest first
+}
+
+// record appends the event to the journal if it changed the state, and returns the version of the change.
+// Misses leave the state untouched, so they are not recorded and their version is zero.
+func (journal *stateJournal) record(event Event) uint64 {
+	if event.Type == EventMiss {
+		return 0
+	}
+
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	journal.version++
+	if len(journal.changes) == journalSize {
+		copy(journal.changes, journal.changes[1:])
+		journal.changes = journal.changes[:len(journal.changes)-1]
+	}
+	journal.changes = append(journal.changes, StateChange{Version: journal.version, Event: event})
+	return journal.version
+}
+
+// current returns the version of the last change.
+func (journal *stateJournal) current() uint64 {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	return journal.version
+}
+
+// since returns the changes made after the version, and false if some were already discarded
+// or the version is unknown, e.g. from before a restart.
+func (journal *stateJournal) since(version uint64) (StateChanges, bool) {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	if version > journal.version || journal.version-version > uint64(len(journal.changes)) {
+		return StateChanges{Version: journal.version}, false
+	}
+	missed := journal.changes[len(journal.changes)-int(journal.version-version):]
+	return StateChanges{Version: journal.version, Changes: append([]StateChange{}, missed...)}, true
+}
+
+// Changes returns the changes made to the state since the given version, e.g. so the visualizer can
+// animate the transitions while polling small payloads. It returns false if the journal no longer holds
+// every change since that version, in which case the caller must reload the full State.
+func (observable *ObservableCache) Changes(since uint64) (StateChanges, bool) {
+	return observable.journal.since(since)
+}
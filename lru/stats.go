@@ -0,0 +1,260 @@
+package lru
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats summarizes the activity of a cache since its creation.
+type Stats struct {
+	Len           int          `json:"len"`
+	Capacity      int          `json:"capacity"`
+	Hits          uint64       `json:"hits"`
+	Misses        uint64       `json:"misses"`
+	Evictions     uint64       `json:"evictions"`               // Items removed to make room for others
+	Expirations   uint64       `json:"expirations"`             // Items removed because they expired
+	GhostHits     uint64       `json:"ghostHits"`               // Misses a larger cache would have served, see WithGhostList
+	HitRatioCurve []CurvePoint `json:"hitRatioCurve,omitempty"` // Estimated hit ratio by capacity, see WithHitRatioCurve
+	TTLs          []TTLBucket  `json:"ttls,omitempty"`          // Live items by remaining ttl, in the buckets of SetTTLBuckets
+	Loading       int          `json:"loading,omitempty"`       // Keys being loaded, only reported by LoadingCache
+	LoadWaiters   int          `json:"loadWaiters,omitempty"`   // Callers waiting for a load started by another one, only reported by LoadingCache
+
+	ExpirationRate      float64 `json:"expirationRate"`      // Items expired per second over the last minute, by the time they were removed
+	UpcomingExpirations int     `json:"upcomingExpirations"` // Live items expiring within the horizon of SetExpirationHorizon
+}
+
+// HitRatio returns the share of the reads that found their key, zero before the first read.
+func (stats Stats) HitRatio() float64 {
+	if stats.Hits+stats.Misses == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+}
+
+// StatsReporter is implemented by the caches reporting their Stats.
+type StatsReporter interface {
+	Stats() Stats
+}
+
+var _ StatsReporter = (*LRUCache)(nil)     // Ensure LRUCache reports its stats
+var _ StatsReporter = (*LFUCache)(nil)     // Ensure LFUCache reports its stats
+var _ StatsReporter = (*LRUKCache)(nil)    // Ensure LRUKCache reports its stats
+var _ StatsReporter = (*S3FIFOCache)(nil)  // Ensure S3FIFOCache reports its stats
+var _ StatsReporter = (*SafeLRUCache)(nil) // Ensure SafeLRUCache reports its stats
+var _ StatsReporter = (*ShardedCache)(nil) // Ensure ShardedCache reports its stats
+
+// weakStatsReporter is implemented by the caches keeping their length and counters in statsCounters,
+// so a SafeLRUCache reads them without its lock. The result may miss the writes in progress.
+type weakStatsReporter interface {
+	// weakLen returns the number of items, without locking.
+	weakLen() int
+	// weakStats returns the Stats without locking, and false if they lack the hit ratio curve
+	// or the distribution of the TTLs, which need the lock.
+	weakStats() (stats Stats, complete bool)
+}
+
+var _ weakStatsReporter = (*LRUCache)(nil)    // Ensure LRUCache stats can be read without locking
+var _ weakStatsReporter = (*LFUCache)(nil)    // Ensure LFUCache stats can be read without locking
+var _ weakStatsReporter = (*LRUKCache)(nil)   // Ensure LRUKCache stats can be read without locking
+var _ weakStatsReporter = (*S3FIFOCache)(nil) // Ensure S3FIFOCache stats can be read without locking
+
+// statsCounters holds the counters of Stats. They are atomic, as the reads of a SafeLRUCache
+// with an access buffer are recorded concurrently under a read lock, and as its Len and Stats read them without locking.
+type statsCounters struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+	length      atomic.Int64 // Number of items
+	capacity    atomic.Int64 // Maximum number of items
+	expiring    atomic.Int64 // Number of items with an expiration, listed by the distribution of the TTLs
+	expired     expirationRate
+	clock       Clock // Source of the time of the expirations, for their rate
+}
+
+// added counts a new item expiring at the given time, zero if never, and returns the length of the cache.
+func (counters *statsCounters) added(expiresAt time.Time) int {
+	counters.expirationChanged(time.Time{}, expiresAt)
+	return int(counters.length.Add(1))
+}
+
+// removed counts the removal of an item expiring at the given time for the given reason,
+// and returns the length of the cache.
+func (counters *statsCounters) removed(reason string, expiresAt time.Time) int {
+	switch reason {
+	case metricReasonEvicted:
+		counters.evictions.Add(1)
+	case metricReasonExpired:
+		counters.expirations.Add(1)
+		counters.expired.add(counters.clock.Now())
+	}
+	counters.expirationChanged(expiresAt, time.Time{})
+	return int(counters.length.Add(-1))
+}
+
+// expirationChanged counts the items with an expiration after one changed from before to after, zero if never.
+func (counters *statsCounters) expirationChanged(before, after time.Time) {
+	switch {
+	case before.IsZero() && !after.IsZero():
+		counters.expiring.Add(1)
+	case !before.IsZero() && after.IsZero():
+		counters.expiring.Add(-1)
+	}
+}
+
+// stats returns the counters as Stats, without the ghost hits, the curve, the TTLs and the upcoming expirations.
+func (counters *statsCounters) stats() Stats {
+	return Stats{
+		Len:            int(counters.length.Load()),
+		Capacity:       int(counters.capacity.Load()),
+		Hits:           counters.hits.Load(),
+		Misses:         counters.misses.Load(),
+		Evictions:      counters.evictions.Load(),
+		Expirations:    counters.expirations.Load(),
+		ExpirationRate: counters.expired.perSecond(counters.clock.Now()),
+	}
+}
+
+// Stats returns the counters of the cache, with the hit ratio curve with WithHitRatioCurve.
+// The distribution of the remaining TTLs visits every item, so it costs O(n).
+func (cache *LRUCache) Stats() Stats {
+	stats := cache.counters.stats()
+	stats.GhostHits = cache.ghosts.hitCount()
+	stats.HitRatioCurve = cache.curve.points()
+	stats.TTLs = cache.ttls()
+	stats.ExpirationRate, stats.UpcomingExpirations = cache.forecastExpirations()
+	return stats
+}
+
+// Stats returns the counters of the cache, with the hit ratio curve with WithHitRatioCurve.
+// The curve estimates the hit ratio of an LRU cache, which is usually close to the one of an LFU cache.
+// The distribution of the remaining TTLs visits every item, so it costs O(n).
+func (cache *LFUCache) Stats() Stats {
+	stats := cache.counters.stats()
+	stats.GhostHits = cache.ghosts.hitCount()
+	stats.HitRatioCurve = cache.curve.points()
+	stats.TTLs = cache.ttls()
+	stats.ExpirationRate, stats.UpcomingExpirations = cache.forecastExpirations()
+	return stats
+}
+
+// Stats returns the counters of the cache, with the hit ratio curve with WithHitRatioCurve.
+// The curve estimates the hit ratio of an LRU cache, which is usually close to the one of an LRU-K cache.
+// The distribution of the remaining TTLs visits every item, so it costs O(n).
+func (cache *LRUKCache) Stats() Stats {
+	stats := cache.counters.stats()
+	stats.GhostHits = cache.ghosts.hitCount()
+	stats.HitRatioCurve = cache.curve.points()
+	stats.TTLs = cache.ttls()
+	stats.ExpirationRate, stats.UpcomingExpirations = cache.forecastExpirations()
+	return stats
+}
+
+// Stats returns the counters of the cache, with the hit ratio curve with WithHitRatioCurve.
+// The curve estimates the hit ratio of an LRU cache, which S3-FIFO usually exceeds.
+// The distribution of the remaining TTLs visits every item, so it costs O(n).
+func (cache *S3FIFOCache) Stats() Stats {
+	stats := cache.counters.stats()
+	stats.GhostHits = cache.ghosts.hitCount()
+	stats.HitRatioCurve = cache.curve.points()
+	stats.TTLs = cache.ttls()
+	stats.ExpirationRate, stats.UpcomingExpirations = cache.forecastExpirations()
+	return stats
+}
+
+// weakLen returns the number of items from the atomic counters.
+func (cache *LRUCache) weakLen() int {
+	return int(cache.counters.length.Load())
+}
+
+// weakStats returns the counters of the cache, complete unless it estimates the hit ratio curve or holds expiring items.
+func (cache *LRUCache) weakStats() (stats Stats, complete bool) {
+	stats = cache.counters.stats()
+	stats.GhostHits = cache.ghosts.hitCount() // The ghost list has its own lock
+	return stats, cache.curve == nil && cache.counters.expiring.Load() == 0
+}
+
+// weakLen returns the number of items from the atomic counters.
+func (cache *LFUCache) weakLen() int {
+	return int(cache.counters.length.Load())
+}
+
+// weakStats returns the counters of the cache, complete unless it estimates the hit ratio curve or holds expiring items.
+func (cache *LFUCache) weakStats() (stats Stats, complete bool) {
+	stats = cache.counters.stats()
+	stats.GhostHits = cache.ghosts.hitCount() // The ghost list has its own lock
+	return stats, cache.curve == nil && cache.counters.expiring.Load() == 0
+}
+
+// weakLen returns the number of items from the atomic counters.
+func (cache *LRUKCache) weakLen() int {
+	return int(cache.counters.length.Load())
+}
+
+// weakStats returns the counters of the cache, complete unless it estimates the hit ratio curve or holds expiring items.
+func (cache *LRUKCache) weakStats() (stats Stats, complete bool) {
+	stats = cache.counters.stats()
+	stats.GhostHits = cache.ghosts.hitCount() // The ghost list has its own lock
+	return stats, cache.curve == nil && cache.counters.expiring.Load() == 0
+}
+
+// weakLen returns the number of items from the atomic counters.
+func (cache *S3FIFOCache) weakLen() int {
+	return int(cache.counters.length.Load())
+}
+
+// weakStats returns the counters of the cache, complete unless it estimates the hit ratio curve or holds expiring items.
+func (cache *S3FIFOCache) weakStats() (stats Stats, complete bool) {
+	stats = cache.counters.stats()
+	stats.GhostHits = cache.ghosts.hitCount() // The ghost list has its own lock
+	return stats, cache.curve == nil && cache.counters.expiring.Load() == 0
+}
+
+// Stats returns the counters of the underlying cache, or only its length and capacity
+// if it does not implement StatsReporter.
+// The counters of an LRUCache or an LFUCache are read without locking, unless the cache estimates the hit ratio curve
+// or holds expiring items, so monitoring does not contend with the reads and writes. They may then miss the writes
+// in progress, e.g. the length may not match the evictions yet.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) Stats() Stats {
+	if reporter, ok := safeCache.cache.(weakStatsReporter); ok {
+		if stats, complete := reporter.weakStats(); complete {
+			return stats
+		}
+	}
+	safeCache.readLock()
+	defer safeCache.mutex.RUnlock()
+
+	if reporter, ok := safeCache.cache.(StatsReporter); ok {
+		return reporter.Stats()
+	}
+	return Stats{Len: safeCache.cache.Len(), Capacity: safeCache.cache.Capacity()}
+}
+
+// Stats returns the sum of the counters and TTL distributions of the shards. The hit ratio curves of the shards are merged
+// by adding up their capacities, weighting their hit ratios by the reads of every shard.
+// Shards are read one after the other, so the result is not a consistent snapshot under concurrent writes.
+func (sharded *ShardedCache) Stats() Stats {
+	var total Stats
+	var curves [][]CurvePoint
+	var weights []float64
+	for _, shard := range sharded.shards {
+		stats := shard.Stats()
+		total.Len += stats.Len
+		total.Capacity += stats.Capacity
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Evictions += stats.Evictions
+		total.Expirations += stats.Expirations
+		total.ExpirationRate += stats.ExpirationRate
+		total.UpcomingExpirations += stats.UpcomingExpirations
+		total.GhostHits += stats.GhostHits
+		total.TTLs = mergeTTLs(total.TTLs, stats.TTLs)
+		if stats.HitRatioCurve != nil {
+			curves = append(curves, stats.HitRatioCurve)
+			weights = append(weights, float64(stats.Hits+stats.Misses))
+		}
+	}
+	total.HitRatioCurve = mergeCurves(curves, weights)
+	return total
+}
@@ -0,0 +1,110 @@
+package lru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLRUCache(2, WithClock(clock), WithGhostList(10))
+	cache.Set("key1", "value1")
+	cache.SetWithTTL("key2", "value2", time.Second)
+	cache.Get("key1")
+	cache.Get("missing")
+	cache.Set("key3", "value3") // Evicts key2
+	cache.Get("key2")           // Ghost hit
+	clock.Advance(time.Minute)
+	cache.SetWithTTL("key4", "value4", time.Second)
+	clock.Advance(time.Minute)
+	cache.Get("key4") // Expired
+
+	stats := cache.Stats()
+	assert.Equal(t, Stats{Len: 1, Capacity: 2, Hits: 1, Misses: 3, Evictions: 2, Expirations: 1, GhostHits: 1, ExpirationRate: 1.0 / expirationRateWindow}, stats)
+	assert.Equal(t, 0.25, stats.HitRatio())
+	assert.Zero(t, Stats{}.HitRatio())
+}
+
+func TestStatsSafeAndSharded(t *testing.T) {
+	safe := NewSafeLRUCache(2, WithAccessBuffer(4))
+	safe.Set("key1", "value1")
+	safe.Get("key1")
+	safe.Get("missing")
+	assert.Equal(t, Stats{Len: 1, Capacity: 2, Hits: 1, Misses: 1}, safe.Stats())
+
+	sharded := NewShardedCache(4, 8)
+	for i := range 10 {
+		sharded.Set(fmt.Sprint("key", i), i)
+		sharded.Get(fmt.Sprint("key", i))
+	}
+	stats := sharded.Stats()
+	assert.Equal(t, 8, stats.Capacity)
+	assert.Equal(t, uint64(10), stats.Hits)
+	assert.Equal(t, stats.Len, 10-int(stats.Evictions))
+}
+
+func TestStatsWithoutLocking(t *testing.T) {
+	for name, cache := range map[string]*SafeLRUCache{
+		"lru": NewSafeLRUCache(2, WithoutMetrics()),
+		"lfu": NewSafe(NewLFUCache(2, WithoutMetrics())),
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache.Set("key1", "value1")
+			cache.Set("key2", "value2")
+			cache.Set("key3", "value3") // Evicts an item
+
+			cache.mutex.Lock() // Len and Stats must not wait for the lock
+			assert.Equal(t, 2, cache.Len())
+			assert.Equal(t, Stats{Len: 2, Capacity: 2, Evictions: 1}, cache.Stats())
+			cache.mutex.Unlock()
+		})
+	}
+}
+
+func TestStatsWithExpiringItemsLock(t *testing.T) {
+	cache := NewSafeLRUCache(5, WithoutMetrics())
+	cache.SetWithTTL("key1", "value1", time.Minute)
+	_, complete := cache.cache.(*LRUCache).weakStats()
+	assert.False(t, complete, "Expected the distribution of the TTLs to need the lock")
+	assert.Len(t, cache.Stats().TTLs, len(ttlBuckets()))
+
+	cache.Set("key1", "value1") // No longer expires
+	_, complete = cache.cache.(*LRUCache).weakStats()
+	assert.True(t, complete)
+	cache.SetWithTTL("key2", "value2", time.Minute)
+	cache.Remove("key2")
+	_, complete = cache.cache.(*LRUCache).weakStats()
+	assert.True(t, complete)
+}
+
+func TestStatsExpirationForecast(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithExpiryBuckets(time.Second)}} {
+		clock := NewManualClock(time.Now())
+		cache := NewLRUCache(10, append(opts, WithClock(clock), WithMetricsName("expiration_forecast"))...)
+		for i := range 3 {
+			cache.SetWithTTL(fmt.Sprint("short", i), i, 10*time.Second)
+		}
+		cache.SetWithTTL("long", "value", 2*time.Minute)
+		cache.Set("forever", "value")
+
+		stats := cache.Stats()
+		assert.Equal(t, 3, stats.UpcomingExpirations)
+		assert.Zero(t, stats.ExpirationRate)
+
+		clock.Advance(11 * time.Second)
+		assert.Equal(t, 3, cache.RemoveExpired())
+		stats = cache.Stats()
+		assert.Zero(t, stats.UpcomingExpirations)
+		assert.Equal(t, 3.0/expirationRateWindow, stats.ExpirationRate)
+		assert.Equal(t, 3.0/expirationRateWindow, gaugeValue(currentMetrics.expirationRate.WithLabelValues("expiration_forecast")))
+
+		clock.Advance(time.Minute) // The expirations leave the window, the long ttl enters the horizon
+		stats = cache.Stats()
+		assert.Equal(t, 1, stats.UpcomingExpirations)
+		assert.Zero(t, stats.ExpirationRate)
+		assert.Equal(t, 1.0, gaugeValue(currentMetrics.upcoming.WithLabelValues("expiration_forecast")))
+	}
+}
@@ -0,0 +1,159 @@
+package lru
+
+// Swapper is implemented by the caches able to replace the value of a key and return the previous one at once,
+// like sync.Map.Swap, e.g. to hand over a resource without losing the one being replaced.
+type Swapper interface {
+	// Swap stores the value like Set, and returns the previous live value of the key and whether it was present.
+	Swap(key string, value any) (previous any, loaded bool)
+}
+
+// GetAndRemover is implemented by the caches able to remove an item and return its value at once,
+// like sync.Map.LoadAndDelete, e.g. so a single consumer takes a one-time token.
+type GetAndRemover interface {
+	// GetAndRemove removes the item of the key, and returns its live value and whether it was present.
+	GetAndRemove(key string) (value any, loaded bool)
+}
+
+var _ Swapper = (*LRUCache)(nil)     // Ensure LRUCache supports Swap
+var _ Swapper = (*LFUCache)(nil)     // Ensure LFUCache supports Swap
+var _ Swapper = (*LRUKCache)(nil)    // Ensure LRUKCache supports Swap
+var _ Swapper = (*S3FIFOCache)(nil)  // Ensure S3FIFOCache supports Swap
+var _ Swapper = (*SafeLRUCache)(nil) // Ensure SafeLRUCache supports Swap
+var _ Swapper = (*ShardedCache)(nil) // Ensure ShardedCache supports Swap
+var _ Swapper = (*HashedKeys)(nil)   // Ensure HashedKeys supports Swap
+
+var _ GetAndRemover = (*LRUCache)(nil)     // Ensure LRUCache supports GetAndRemove
+var _ GetAndRemover = (*LFUCache)(nil)     // Ensure LFUCache supports GetAndRemove
+var _ GetAndRemover = (*LRUKCache)(nil)    // Ensure LRUKCache supports GetAndRemove
+var _ GetAndRemover = (*S3FIFOCache)(nil)  // Ensure S3FIFOCache supports GetAndRemove
+var _ GetAndRemover = (*SafeLRUCache)(nil) // Ensure SafeLRUCache supports GetAndRemove
+var _ GetAndRemover = (*ShardedCache)(nil) // Ensure ShardedCache supports GetAndRemove
+var _ GetAndRemover = (*HashedKeys)(nil)   // Ensure HashedKeys supports GetAndRemove
+
+// swap replaces the value of the key in a cache that is not thread-safe, returning the previous one.
+// The read is counted as a Get, a hit if the key is present and a miss otherwise.
+func swap(cache Cache, key string, value any) (previous any, loaded bool) {
+	previous, loaded = cache.Get(key)
+	cache.Set(key, value)
+	return previous, loaded
+}
+
+// getAndRemove removes the key from a cache that is not thread-safe, returning its value.
+// The read is counted as a Get, a hit if the key is present and a miss otherwise.
+func getAndRemove(cache Cache, key string) (value any, loaded bool) {
+	if value, loaded = cache.Get(key); loaded {
+		cache.Remove(key)
+	}
+	return value, loaded
+}
+
+// Swap stores the value like Set, and returns the previous live value of the key and whether it was present.
+// The read is counted like a Get.
+func (cache *LRUCache) Swap(key string, value any) (previous any, loaded bool) {
+	return swap(cache, key, value)
+}
+
+// GetAndRemove removes the item of the key, and returns its live value and whether it was present.
+// The read is counted like a Get.
+func (cache *LRUCache) GetAndRemove(key string) (value any, loaded bool) {
+	return getAndRemove(cache, key)
+}
+
+// Swap stores the value like Set, and returns the previous live value of the key and whether it was present.
+// The access is counted like a Get, so the frequency of the key carries over to the new value.
+func (cache *LFUCache) Swap(key string, value any) (previous any, loaded bool) {
+	return swap(cache, key, value)
+}
+
+// GetAndRemove removes the item of the key, and returns its live value and whether it was present.
+// The read is counted like a Get.
+func (cache *LFUCache) GetAndRemove(key string) (value any, loaded bool) {
+	return getAndRemove(cache, key)
+}
+
+// Swap stores the value like Set, and returns the previous live value of the key and whether it was present.
+// The read records a reference like Get.
+func (cache *LRUKCache) Swap(key string, value any) (previous any, loaded bool) {
+	return swap(cache, key, value)
+}
+
+// GetAndRemove removes the item of the key, and returns its live value and whether it was present.
+// The read is counted like a Get.
+func (cache *LRUKCache) GetAndRemove(key string) (value any, loaded bool) {
+	return getAndRemove(cache, key)
+}
+
+// Swap stores the value like Set, and returns the previous live value of the key and whether it was present.
+// The read is counted like a Get.
+func (cache *S3FIFOCache) Swap(key string, value any) (previous any, loaded bool) {
+	return swap(cache, key, value)
+}
+
+// GetAndRemove removes the item of the key, and returns its live value and whether it was present.
+// The read is counted like a Get.
+func (cache *S3FIFOCache) GetAndRemove(key string) (value any, loaded bool) {
+	return getAndRemove(cache, key)
+}
+
+// Swap stores the value like Set, and returns the previous live value of the key and whether it was present.
+// The read and the write are done under a single acquisition of the lock, bypassing the write buffer,
+// so no concurrent write is lost between them.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) Swap(key string, value any) (previous any, loaded bool) {
+	key = safeCache.transform.apply(key)
+	safeCache.lockOrdered()
+	defer safeCache.mutex.Unlock()
+
+	if swapper, ok := safeCache.cache.(Swapper); ok {
+		return swapper.Swap(key, value)
+	}
+	return swap(safeCache.cache, key, value)
+}
+
+// GetAndRemove removes the item of the key, and returns its live value and whether it was present.
+// The read and the removal are done under a single acquisition of the lock, bypassing the write buffer,
+// so only one of concurrent callers gets the value.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) GetAndRemove(key string) (value any, loaded bool) {
+	key = safeCache.transform.apply(key)
+	safeCache.lockOrdered()
+	defer safeCache.mutex.Unlock()
+
+	if remover, ok := safeCache.cache.(GetAndRemover); ok {
+		return remover.GetAndRemove(key)
+	}
+	return getAndRemove(safeCache.cache, key)
+}
+
+// Swap stores the value like Set in the shard of the key, and returns the previous live value
+// and whether it was present.
+// It is thread-safe.
+func (sharded *ShardedCache) Swap(key string, value any) (previous any, loaded bool) {
+	key = sharded.transform.apply(key)
+	return sharded.shard(key).Swap(key, value)
+}
+
+// GetAndRemove removes the item of the key from its shard, and returns its live value and whether it was present.
+// It is thread-safe.
+func (sharded *ShardedCache) GetAndRemove(key string) (value any, loaded bool) {
+	key = sharded.transform.apply(key)
+	return sharded.shard(key).GetAndRemove(key)
+}
+
+// Swap stores the value like Set, and returns the previous live value of the key and whether it was present.
+// It is atomic if the underlying cache implements Swapper.
+func (hashed *HashedKeys) Swap(key string, value any) (previous any, loaded bool) {
+	if swapper, ok := hashed.cache.(Swapper); ok {
+		return swapper.Swap(hashed.hash(key), value)
+	}
+	return swap(hashed.cache, hashed.hash(key), value)
+}
+
+// GetAndRemove removes the item of the key, and returns its live value and whether it was present.
+// It is atomic if the underlying cache implements GetAndRemover.
+func (hashed *HashedKeys) GetAndRemove(key string) (value any, loaded bool) {
+	if remover, ok := hashed.cache.(GetAndRemover); ok {
+		return remover.GetAndRemove(hashed.hash(key))
+	}
+	return getAndRemove(hashed.cache, hashed.hash(key))
+}
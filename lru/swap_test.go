@@ -0,0 +1,70 @@
+package lru
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSwapAndGetAndRemove(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	caches := map[string]Cache{
+		"lru":     NewLRUCache(10, WithoutMetrics(), WithClock(clock)),
+		"lfu":     NewLFUCache(10, WithoutMetrics(), WithClock(clock)),
+		"lruk":    NewLRUKCache(10, WithoutMetrics(), WithClock(clock)),
+		"s3fifo":  NewS3FIFOCache(10, WithoutMetrics(), WithClock(clock)),
+		"safe":    NewSafeLRUCache(10, WithoutMetrics(), WithClock(clock)),
+		"sharded": NewShardedCache(4, 10, WithoutMetrics(), WithClock(clock)),
+		"hashed":  NewHashedKeys(NewLRUCache(10, WithoutMetrics(), WithClock(clock)), SHA256Keys([]byte("secret"), 16)),
+	}
+	for name, cache := range caches {
+		previous, loaded := cache.(Swapper).Swap("key", "first")
+		assert.False(t, loaded, name)
+		assert.Nil(t, previous, name)
+		previous, loaded = cache.(Swapper).Swap("key", "second")
+		assert.True(t, loaded, name)
+		assert.Equal(t, "first", previous, name)
+
+		value, loaded := cache.(GetAndRemover).GetAndRemove("key")
+		assert.True(t, loaded, name)
+		assert.Equal(t, "second", value, name)
+		_, loaded = cache.(GetAndRemover).GetAndRemove("key")
+		assert.False(t, loaded, name)
+		assert.Zero(t, cache.Len(), name)
+
+		cache.SetWithTTL("expiring", "old", time.Second)
+	}
+	clock.Advance(time.Minute)
+	for name, cache := range caches {
+		_, loaded := cache.(GetAndRemover).GetAndRemove("expiring")
+		assert.False(t, loaded, name+": expected an expired item to be missing")
+	}
+}
+
+func TestGetAndRemoveConcurrent(t *testing.T) {
+	cache := NewSafeLRUCache(100, WithoutMetrics(), WithWriteBuffer(16))
+	defer cache.Close()
+	for i := range 10 {
+		cache.Set("token"+strconv.Itoa(i), i)
+	}
+
+	var wg sync.WaitGroup
+	var taken atomic.Int64
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 10 {
+				if _, loaded := cache.GetAndRemove("token" + strconv.Itoa(i)); loaded {
+					taken.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(10), taken.Load(), "Expected every token to be taken once")
+}
@@ -0,0 +1,46 @@
+package lru
+
+import (
+	"container/list"
+)
+
+// defaultTimeAwareWindow is the number of least recently used items considered by NewTLRUCache.
+const defaultTimeAwareWindow = 8
+
+// WithTimeAwareEviction turns an LRUCache, and the caches built on it, into a time-aware LRU (TLRU):
+// to make room, it evicts the item expiring the soonest among the window least recently used items,
+// instead of the least recently used one, as the items about to expire are the least worth keeping.
+// The items without expiration are evicted last, and the ties go to the least recently used item.
+// The window bounds the cost of an eviction, and how recent an item evicted for its TTL can be.
+// A window of 1 or less keeps the LRU policy. Ignored by the LFUCache.
+func WithTimeAwareEviction(window int) Option {
+	return func(o *options) {
+		o.timeAwareWindow = window
+	}
+}
+
+// NewTLRUCache creates a time-aware LRU cache, an LRUCache evicting the item expiring the soonest
+// among its 8 least recently used items, see WithTimeAwareEviction. Its metrics are named tlru by default.
+func NewTLRUCache(capacity int, opts ...Option) *LRUCache {
+	opts = append([]Option{WithMetricsName(metricCacheTypeTLRU), WithTimeAwareEviction(defaultTimeAwareWindow)}, opts...)
+	return NewLRUCache(capacity, opts...)
+}
+
+// victim returns the element to evict to make room, nil if the cache is empty: the least recently used one,
+// or with WithTimeAwareEviction the one expiring the soonest among the least recently used ones.
+func (cache *LRUCache) victim() *list.Element {
+	victim := cache.usageOrder.Back()
+	if cache.timeAware <= 1 || victim == nil {
+		return victim
+	}
+	soonest := victim.Value.(*entry).expiresAt
+	e := victim.Prev()
+	for considered := 1; considered < cache.timeAware && e != nil; considered++ {
+		expiresAt := e.Value.(*entry).expiresAt
+		if !expiresAt.IsZero() && (soonest.IsZero() || expiresAt.Before(soonest)) {
+			victim, soonest = e, expiresAt
+		}
+		e = e.Prev()
+	}
+	return victim
+}
@@ -0,0 +1,35 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLRUEvictsTheSoonestToExpire(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewTLRUCache(3, WithClock(clock), WithoutMetrics())
+	cache.Set("forever", 1)
+	cache.SetWithTTL("hour", 2, time.Hour)
+	cache.SetWithTTL("minute", 3, time.Minute)
+
+	result := cache.Set("new", 4)
+	assert.Equal(t, "minute", result.EvictedKey, "Expected the item expiring the soonest to be evicted")
+	result = cache.Set("newer", 5)
+	assert.Equal(t, "hour", result.EvictedKey)
+	result = cache.Set("newest", 6)
+	assert.Equal(t, "forever", result.EvictedKey, "Expected the least recently used item without expiration to be evicted last")
+	assert.Equal(t, metricCacheTypeTLRU, NewObservableCacheFrom(NewSafe(cache)).State().Policy)
+}
+
+func TestTLRUWindow(t *testing.T) {
+	cache := NewLRUCache(3, WithTimeAwareEviction(2), WithoutMetrics())
+	cache.SetWithTTL("a", 1, time.Hour)
+	cache.Set("b", 2)
+	cache.SetWithTTL("c", 3, time.Minute) // Expires the soonest, but is out of the window
+
+	assert.Equal(t, "a", cache.Set("d", 4).EvictedKey)
+	cache.Resize(1) // Evicts c, now in the window, then b
+	assert.Equal(t, []string{"d"}, rangeKeys(cache))
+}
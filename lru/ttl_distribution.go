@@ -0,0 +1,97 @@
+package lru
+
+import (
+	"slices"
+	"time"
+)
+
+// TTLBucket counts the live items expiring within an upper bound, like a bucket of a Prometheus histogram.
+type TTLBucket struct {
+	UpperBound float64 `json:"upperBound"` // Remaining time to live in seconds
+	Count      int     `json:"count"`      // Items expiring within UpperBound, including those of the smaller buckets
+}
+
+// ttlDistribution counts the remaining TTLs of the live items in the buckets of SetTTLBuckets.
+type ttlDistribution struct {
+	now      time.Time
+	buckets  []TTLBucket
+	expiring int // Number of live items with an expiration
+}
+
+// newTTLDistribution creates an empty distribution of the TTLs remaining at now.
+func newTTLDistribution(now time.Time) *ttlDistribution {
+	bounds := ttlBuckets()
+	buckets := make([]TTLBucket, len(bounds))
+	for i, bound := range bounds {
+		buckets[i].UpperBound = bound
+	}
+	return &ttlDistribution{now: now, buckets: buckets}
+}
+
+// add counts an item expiring at the given time. Items without expiration, or already expired, are skipped.
+func (distribution *ttlDistribution) add(expiresAt time.Time) {
+	if expiresAt.IsZero() || hasExpired(expiresAt, distribution.now) {
+		return
+	}
+	distribution.expiring++
+	remaining := expiresAt.Sub(distribution.now).Seconds()
+	for i := len(distribution.buckets) - 1; i >= 0 && remaining <= distribution.buckets[i].UpperBound; i-- {
+		distribution.buckets[i].Count++
+	}
+}
+
+// result returns the buckets, or nil when no live item expires.
+func (distribution *ttlDistribution) result() []TTLBucket {
+	if distribution.expiring == 0 {
+		return nil
+	}
+	return distribution.buckets
+}
+
+// mergeTTLs adds up the distributions of several caches, e.g. the shards of a ShardedCache.
+// The distributions are expected to share the same buckets, or be nil.
+func mergeTTLs(total, distribution []TTLBucket) []TTLBucket {
+	if total == nil {
+		return slices.Clone(distribution)
+	}
+	for i := range min(len(total), len(distribution)) {
+		total[i].Count += distribution[i].Count
+	}
+	return total
+}
+
+// ttls returns the distribution of the remaining TTLs of the live items, nil when none expires.
+func (cache *LRUCache) ttls() []TTLBucket {
+	distribution := newTTLDistribution(cache.clock.Now())
+	for _, elem := range cache.items {
+		distribution.add(elem.Value.(*entry).expiresAt)
+	}
+	return distribution.result()
+}
+
+// ttls returns the distribution of the remaining TTLs of the live items, nil when none expires.
+func (cache *LFUCache) ttls() []TTLBucket {
+	distribution := newTTLDistribution(cache.clock.Now())
+	for _, elem := range cache.items {
+		distribution.add(elem.Value.(*lfuEntry).expiresAt)
+	}
+	return distribution.result()
+}
+
+// ttls returns the distribution of the remaining TTLs of the live items, nil when none expires.
+func (cache *LRUKCache) ttls() []TTLBucket {
+	distribution := newTTLDistribution(cache.clock.Now())
+	for _, ent := range cache.items {
+		distribution.add(ent.expiresAt)
+	}
+	return distribution.result()
+}
+
+// ttls returns the distribution of the remaining TTLs of the live items, nil when none expires.
+func (cache *S3FIFOCache) ttls() []TTLBucket {
+	distribution := newTTLDistribution(cache.clock.Now())
+	for _, ent := range cache.items {
+		distribution.add(ent.expiresAt)
+	}
+	return distribution.result()
+}
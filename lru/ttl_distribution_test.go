@@ -0,0 +1,47 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsTTLDistribution(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLRUCache(10, WithClock(clock))
+	cache.Set("forever", 1)
+	assert.Nil(t, cache.Stats().TTLs, "Expected no distribution without expiring items")
+
+	cache.SetWithTTL("soon", 1, 300*time.Millisecond)
+	cache.SetWithTTL("later", 1, 20*time.Second)
+	cache.SetWithTTL("much later", 1, time.Hour)
+	ttls := cache.Stats().TTLs
+	assert.Len(t, ttls, len(ttlBuckets()))
+	assert.Equal(t, TTLBucket{UpperBound: 0.1, Count: 0}, ttls[0])
+	assert.Equal(t, TTLBucket{UpperBound: 0.5, Count: 1}, ttls[1])
+	assert.Equal(t, TTLBucket{UpperBound: 30, Count: 2}, ttls[6])
+	assert.Equal(t, TTLBucket{UpperBound: 60, Count: 2}, ttls[7], "Expected the items expiring past the last bucket not to be counted")
+
+	clock.Advance(time.Second) // "soon" expired
+	assert.Equal(t, 1, cache.Stats().TTLs[7].Count)
+}
+
+func TestShardedStatsTTLDistribution(t *testing.T) {
+	cache := NewShardedCache(4, 40)
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		cache.SetWithTTL(key, 1, time.Minute)
+	}
+	ttls := cache.Stats().TTLs
+	assert.Equal(t, 5, ttls[len(ttls)-1].Count)
+	assert.Equal(t, 0, ttls[len(ttls)-2].Count)
+}
+
+func TestSetTTLBuckets(t *testing.T) {
+	defer SetTTLBuckets(ttlBuckets())
+
+	SetTTLBuckets([]float64{3600, 60})
+	cache := NewLRUCache(2)
+	cache.SetWithTTL("key", 1, 10*time.Minute)
+	assert.Equal(t, []TTLBucket{{UpperBound: 60}, {UpperBound: 3600, Count: 1}}, cache.Stats().TTLs)
+}
@@ -0,0 +1,18 @@
+package lru
+
+import (
+	"time"
+)
+
+// TTLPolicy decides the ttl of the items written by Set, e.g. by key prefix or value type, so the TTLs are
+// decided in one place instead of at every call site. It receives the key after WithKeyTransform.
+// A ttl of zero or less keeps the item until it is removed.
+type TTLPolicy func(key string, value any) time.Duration
+
+// apply returns the ttl the policy gives to the item, zero without policy.
+func (policy TTLPolicy) apply(key string, value any) time.Duration {
+	if policy == nil {
+		return 0
+	}
+	return policy(key, value)
+}
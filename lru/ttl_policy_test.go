@@ -0,0 +1,92 @@
+package lru
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLPolicy(t *testing.T) {
+	policy := func(key string, value any) time.Duration {
+		if strings.HasPrefix(key, "session:") {
+			return time.Minute
+		}
+		if _, ok := value.(error); ok {
+			return time.Second // Negative caching
+		}
+		return 0
+	}
+	clock := NewManualClock(time.Now())
+	caches := map[string]Cache{
+		"lru":    NewSafeLRUCache(10, WithClock(clock), WithTTLPolicy(policy), WithoutMetrics()),
+		"lfu":    NewLFUCache(10, WithClock(clock), WithTTLPolicy(policy), WithoutMetrics()),
+		"lruk":   NewLRUKCache(10, WithClock(clock), WithTTLPolicy(policy), WithoutMetrics()),
+		"s3fifo": NewS3FIFOCache(10, WithClock(clock), WithTTLPolicy(policy), WithoutMetrics()),
+	}
+	for _, cache := range caches {
+		cache.Set("session:1", "alice")
+		cache.Set("user:1", "alice")
+		cache.Set("user:2", assert.AnError)
+		cache.SetWithTTL("session:2", "bob", time.Hour) // Takes precedence over the policy
+	}
+
+	clock.Advance(2 * time.Minute)
+	for name, cache := range caches {
+		_, found := cache.Get("session:1")
+		assert.False(t, found, name)
+		_, found = cache.Get("user:2")
+		assert.False(t, found, name)
+		_, found = cache.Get("user:1")
+		assert.True(t, found, name)
+		_, found = cache.Get("session:2")
+		assert.True(t, found, name)
+	}
+}
+
+func TestTTLPolicyWithMetadata(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLRUCache(2, WithClock(clock), WithTTLPolicy(func(string, any) time.Duration { return time.Minute }), WithoutMetrics())
+	cache.SetWithMetadata("key1", "value1", 0, "origin:db")
+	info, _ := cache.EntryInfo("key1")
+	assert.True(t, info.ExpiresAt.Equal(clock.Now().Add(time.Minute)))
+}
+
+func TestTTLPolicyWithSetAndReturnEvictedAndIncrement(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	policy := WithTTLPolicy(func(string, any) time.Duration { return time.Minute })
+	caches := map[string]interface {
+		Cache
+		EvictionReporter
+		Incrementer
+	}{
+		"lru": NewLRUCache(10, WithClock(clock), policy, WithoutMetrics()),
+		"lfu": NewLFUCache(10, WithClock(clock), policy, WithoutMetrics()),
+	}
+	for _, cache := range caches {
+		cache.SetAndReturnEvicted("key1", "value1")
+		cache.Increment("counter", 1, 0)
+	}
+
+	clock.Advance(2 * time.Minute)
+	for name, cache := range caches {
+		_, found := cache.Get("key1")
+		assert.False(t, found, name)
+		_, found = cache.Get("counter")
+		assert.False(t, found, name)
+	}
+}
+
+func TestTTLPolicyWithGetOrSetAndSwap(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLRUCache(10, WithClock(clock), WithTTLPolicy(func(string, any) time.Duration { return time.Minute }), WithoutMetrics())
+	cache.GetOrSet("key1", "value1")
+	cache.Swap("key2", "value2")
+
+	clock.Advance(2 * time.Minute)
+	_, found := cache.Get("key1")
+	assert.False(t, found)
+	_, found = cache.Get("key2")
+	assert.False(t, found)
+}
@@ -0,0 +1,110 @@
+package lru
+
+import (
+	"time"
+)
+
+// TTLReader is implemented by the caches able to tell the remaining time to live of their items,
+// e.g. so a client refreshes a value before it expires.
+type TTLReader interface {
+	// TTL returns the remaining time to live of the live item of the key, zero if it does not expire,
+	// and whether the item was found. The item is not promoted, and the read is not counted in the stats.
+	TTL(key string) (time.Duration, bool)
+}
+
+var _ TTLReader = (*LRUCache)(nil)        // Ensure LRUCache reports the TTLs
+var _ TTLReader = (*LFUCache)(nil)        // Ensure LFUCache reports the TTLs
+var _ TTLReader = (*LRUKCache)(nil)       // Ensure LRUKCache reports the TTLs
+var _ TTLReader = (*S3FIFOCache)(nil)     // Ensure S3FIFOCache reports the TTLs
+var _ TTLReader = (*SampledLRUCache)(nil) // Ensure SampledLRUCache reports the TTLs
+var _ TTLReader = (*SafeLRUCache)(nil)    // Ensure SafeLRUCache reports the TTLs
+var _ TTLReader = (*ShardedCache)(nil)    // Ensure ShardedCache reports the TTLs
+
+// remainingTTL returns the time to live of the entry at the given time, zero if it does not expire,
+// and false if it expired.
+func (e *entry) remainingTTL(now time.Time) (time.Duration, bool) {
+	if e.hasExpired(now) {
+		return 0, false
+	}
+	if e.expiresAt.IsZero() {
+		return 0, true
+	}
+	return e.expiresAt.Sub(now), true
+}
+
+// TTL returns the remaining time to live of the live item of the key, zero if it does not expire,
+// and whether the item was found, without promoting it.
+func (cache *LRUCache) TTL(key string) (time.Duration, bool) {
+	if elem, found := cache.items[cache.transform.apply(key)]; found {
+		return elem.Value.(*entry).remainingTTL(cache.clock.Now())
+	}
+	return 0, false
+}
+
+// TTL returns the remaining time to live of the live item of the key, zero if it does not expire,
+// and whether the item was found, without counting the access.
+func (cache *LFUCache) TTL(key string) (time.Duration, bool) {
+	if elem, found := cache.items[cache.transform.apply(key)]; found {
+		return elem.Value.(*lfuEntry).remainingTTL(cache.clock.Now())
+	}
+	return 0, false
+}
+
+// TTL returns the remaining time to live of the live item of the key, zero if it does not expire,
+// and whether the item was found, without recording a reference.
+func (cache *LRUKCache) TTL(key string) (time.Duration, bool) {
+	if ent, found := cache.items[cache.transform.apply(key)]; found {
+		return ent.remainingTTL(cache.clock.Now())
+	}
+	return 0, false
+}
+
+// TTL returns the remaining time to live of the live item of the key, zero if it does not expire,
+// and whether the item was found, without counting the access.
+func (cache *S3FIFOCache) TTL(key string) (time.Duration, bool) {
+	if ent, found := cache.items[cache.transform.apply(key)]; found {
+		return ent.remainingTTL(cache.clock.Now())
+	}
+	return 0, false
+}
+
+// TTL returns the remaining time to live of the live item of the key, zero if it does not expire,
+// and whether the item was found, without recording the access. It is thread-safe.
+func (cache *SampledLRUCache) TTL(key string) (time.Duration, bool) {
+	stored, found := cache.items.Load(key)
+	if !found {
+		return 0, false
+	}
+	ent := stored.(*sampledEntry)
+	now := cache.clock.Now()
+	switch {
+	case hasExpired(ent.expiresAt, now):
+		return 0, false
+	case ent.expiresAt.IsZero():
+		return 0, true
+	default:
+		return ent.expiresAt.Sub(now), true
+	}
+}
+
+// TTL returns the remaining time to live of the live item of the key in the wrapped cache, zero if it does not expire,
+// and whether the item was found. It returns false if the wrapped cache does not report the TTLs.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) TTL(key string) (time.Duration, bool) {
+	key = safeCache.transform.apply(key)
+	safeCache.readLock()
+	defer safeCache.mutex.RUnlock()
+
+	if reader, ok := safeCache.cache.(TTLReader); ok {
+		return reader.TTL(key)
+	}
+	return 0, false
+}
+
+// TTL returns the remaining time to live of the live item of the key in its shard, zero if it does not expire,
+// and whether the item was found.
+// It is thread-safe.
+func (sharded *ShardedCache) TTL(key string) (time.Duration, bool) {
+	key = sharded.transform.apply(key)
+	return sharded.shard(key).TTL(key)
+}
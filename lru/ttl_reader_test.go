@@ -0,0 +1,44 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTL(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	caches := map[string]Cache{
+		"lru":     NewLRUCache(10, WithoutMetrics(), WithClock(clock)),
+		"lfu":     NewLFUCache(10, WithoutMetrics(), WithClock(clock)),
+		"lruk":    NewLRUKCache(10, WithoutMetrics(), WithClock(clock)),
+		"s3fifo":  NewS3FIFOCache(10, WithoutMetrics(), WithClock(clock)),
+		"sampled": NewSampledLRUCache(10, WithoutMetrics(), WithClock(clock)),
+		"safe":    NewSafeLRUCache(10, WithoutMetrics(), WithClock(clock)),
+		"sharded": NewShardedCache(4, 10, WithoutMetrics(), WithClock(clock)),
+	}
+	for _, cache := range caches {
+		cache.SetWithTTL("expiring", "value", time.Minute)
+		cache.Set("forever", "value")
+	}
+	clock.Advance(20 * time.Second)
+
+	for name, cache := range caches {
+		reader := cache.(TTLReader)
+		ttl, found := reader.TTL("expiring")
+		assert.True(t, found, name)
+		assert.Equal(t, 40*time.Second, ttl, name)
+		ttl, found = reader.TTL("forever")
+		assert.True(t, found, name)
+		assert.Zero(t, ttl, name)
+		_, found = reader.TTL("missing")
+		assert.False(t, found, name)
+	}
+
+	clock.Advance(time.Minute)
+	for name, cache := range caches {
+		_, found := cache.(TTLReader).TTL("expiring")
+		assert.False(t, found, name+": expected an expired item to be missing")
+	}
+}
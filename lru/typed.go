@@ -0,0 +1,59 @@
+package lru
+
+import (
+	"time"
+)
+
+// Typed wraps a cache holding values of a single type, sparing the callers the type assertions.
+// Get is allocation-free. Set stores the value in an interface, which allocates for values
+// that are not pointers, unless they are small enough to be stored inline by the runtime.
+type Typed[V any] struct {
+	cache Cache // The underlying cache
+}
+
+// NewTyped wraps the cache. The cache should only be written through the returned Typed,
+// values of another type are reported as not found by Get.
+func NewTyped[V any](cache Cache) *Typed[V] {
+	return &Typed[V]{cache: cache}
+}
+
+// Get retrieves an item from the cache by its key.
+// It returns the value and a boolean indicating whether the item was found.
+func (typed *Typed[V]) Get(key string) (value V, found bool) {
+	stored, found := typed.cache.Get(key)
+	if !found {
+		return value, false
+	}
+	value, found = stored.(V)
+	return value, found
+}
+
+// Set adds or updates an item in the cache with no expiration.
+func (typed *Typed[V]) Set(key string, value V) SetResult {
+	return typed.cache.Set(key, value)
+}
+
+// SetWithTTL adds or updates an item in the cache with a specified expiration time.
+func (typed *Typed[V]) SetWithTTL(key string, value V, ttl time.Duration) SetResult {
+	return typed.cache.SetWithTTL(key, value, ttl)
+}
+
+// Remove deletes an item from the cache by key.
+func (typed *Typed[V]) Remove(key string) {
+	typed.cache.Remove(key)
+}
+
+// Len returns the number of items currently in the cache.
+func (typed *Typed[V]) Len() int {
+	return typed.cache.Len()
+}
+
+// Capacity returns the maximum number of items that can be stored in the cache.
+func (typed *Typed[V]) Capacity() int {
+	return typed.cache.Capacity()
+}
+
+// Cache returns the underlying cache.
+func (typed *Typed[V]) Cache() Cache {
+	return typed.cache
+}
@@ -0,0 +1,65 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	name string
+	age  int
+}
+
+func TestTyped(t *testing.T) {
+	cache := NewTyped[user](NewSafeLRUCache(5))
+	cache.Set("alice", user{name: "Alice", age: 30})
+	cache.SetWithTTL("bob", user{name: "Bob", age: 40}, time.Minute)
+
+	value, found := cache.Get("alice")
+	assert.True(t, found)
+	assert.Equal(t, user{name: "Alice", age: 30}, value)
+	assert.Equal(t, 2, cache.Len())
+	assert.Equal(t, 5, cache.Capacity())
+
+	cache.Remove("alice")
+	value, found = cache.Get("alice")
+	assert.False(t, found)
+	assert.Zero(t, value)
+}
+
+func TestTypedWrongType(t *testing.T) {
+	cache := NewTyped[int](NewLRUCache(5))
+	cache.Cache().Set("key1", "not an int")
+
+	value, found := cache.Get("key1")
+	assert.False(t, found)
+	assert.Zero(t, value)
+}
+
+// TestGetDoesNotAllocate guards the hot path of every cache against allocation regressions.
+func TestGetDoesNotAllocate(t *testing.T) {
+	lru := NewLRUCache(5)
+	lru.Set("key1", "value1")
+	safe := NewSafeLRUCache(5)
+	safe.Set("key1", "value1")
+	batched := NewSafeLRUCache(5, WithAccessBuffer(16))
+	batched.Set("key1", "value1")
+	sharded := NewShardedCache(4, 8)
+	sharded.Set("key1", "value1")
+	typed := NewTyped[string](NewSafeLRUCache(5, WithoutMetrics()))
+	typed.Set("key1", "value1")
+
+	cases := map[string]func(){
+		"LRUCache hit":                 func() { lru.Get("key1") },
+		"LRUCache miss":                func() { lru.Get("missing") },
+		"SafeLRUCache hit":             func() { safe.Get("key1") },
+		"SafeLRUCache with buffer hit": func() { batched.Get("key1") },
+		"ShardedCache hit":             func() { sharded.Get("key1") },
+		"Typed hit":                    func() { typed.Get("key1") },
+	}
+	for name, get := range cases {
+		assert.Zero(t, testing.AllocsPerRun(100, get), name)
+	}
+}
@@ -0,0 +1,166 @@
+package lru
+
+import (
+	"sort"
+)
+
+// IndexFunc extracts the terms a value is indexed under, e.g. the user ID of a session.
+// It must be fast and deterministic, as it runs under the lock of the cache on every Set.
+type IndexFunc func(value any) []string
+
+// IndexedCache is implemented by the caches able to find their items by the secondary indexes
+// registered with WithIndex.
+type IndexedCache interface {
+	// Lookup returns the keys of the live items whose value has the term in the named index.
+	Lookup(index string, term string) []string
+	// RemoveByIndex removes the items whose value has the term in the named index, and returns how many live items were removed.
+	RemoveByIndex(index string, term string) int
+}
+
+var _ IndexedCache = (*LRUCache)(nil)     // Ensure LRUCache supports secondary indexes
+var _ IndexedCache = (*SafeLRUCache)(nil) // Ensure SafeLRUCache supports secondary indexes
+var _ IndexedCache = (*ShardedCache)(nil) // Ensure ShardedCache supports secondary indexes
+
+// valueIndex maps the terms extracted from the values to the keys holding them.
+type valueIndex struct {
+	extract IndexFunc
+	keys    map[string]map[string]struct{} // Keys by term
+	terms   map[string][]string            // Terms by key, to unindex a key without its value
+}
+
+func newValueIndex(extract IndexFunc) *valueIndex {
+	return &valueIndex{
+		extract: extract,
+		keys:    make(map[string]map[string]struct{}),
+		terms:   make(map[string][]string),
+	}
+}
+
+// add indexes the key under the terms of its new value, replacing the terms of its previous value.
+func (index *valueIndex) add(key string, value any) {
+	index.remove(key)
+	terms := index.extract(value)
+	if len(terms) == 0 {
+		return
+	}
+	index.terms[key] = terms
+	for _, term := range terms {
+		keys, found := index.keys[term]
+		if !found {
+			keys = make(map[string]struct{})
+			index.keys[term] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// remove unindexes the key.
+func (index *valueIndex) remove(key string) {
+	for _, term := range index.terms[key] {
+		delete(index.keys[term], key)
+		if len(index.keys[term]) == 0 {
+			delete(index.keys, term)
+		}
+	}
+	delete(index.terms, key)
+}
+
+// lookup returns the keys indexed under the term, sorted.
+func (index *valueIndex) lookup(term string) []string {
+	keys := make([]string, 0, len(index.keys[term]))
+	for key := range index.keys[term] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// indexValue indexes the key under the terms of its value in every index.
+func (cache *LRUCache) indexValue(key string, value any) {
+	for _, index := range cache.indexes {
+		index.add(key, value)
+	}
+}
+
+// unindex removes the key from every index.
+func (cache *LRUCache) unindex(key string) {
+	for _, index := range cache.indexes {
+		index.remove(key)
+	}
+}
+
+// indexedKeys returns the keys indexed under the term in the named index, nil if there is no such index.
+func (cache *LRUCache) indexedKeys(name string, term string) []string {
+	index, found := cache.indexes[name]
+	if !found {
+		return nil
+	}
+	return index.lookup(term)
+}
+
+// Lookup returns the keys of the live items whose value has the term in the named index, sorted.
+// It returns nil if no index was registered with that name.
+func (cache *LRUCache) Lookup(index string, term string) []string {
+	keys := cache.indexedKeys(index, term)
+	now := cache.clock.Now()
+	live := keys[:0]
+	for _, key := range keys {
+		if !cache.items[key].Value.(*entry).hasExpired(now) {
+			live = append(live, key)
+		}
+	}
+	return live
+}
+
+// RemoveByIndex removes the items whose value has the term in the named index,
+// e.g. every session of a user, and returns how many live items were removed.
+func (cache *LRUCache) RemoveByIndex(index string, term string) int {
+	return cache.removeKeys(cache.indexedKeys(index, term))
+}
+
+// Lookup returns the keys of the live items whose value has the term in the named index, sorted.
+// It does not promote the items. It returns nil if the underlying cache is not an LRUCache.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) Lookup(index string, term string) []string {
+	safeCache.lock()
+	defer safeCache.mutex.Unlock()
+
+	if lru, ok := safeCache.cache.(*LRUCache); ok {
+		return lru.Lookup(index, term)
+	}
+	return nil
+}
+
+// RemoveByIndex removes the items whose value has the term in the named index, and returns how many live items were removed.
+// It is thread-safe.
+func (safeCache *SafeLRUCache) RemoveByIndex(index string, term string) int {
+	safeCache.lockOrdered()
+	defer safeCache.mutex.Unlock()
+
+	if lru, ok := safeCache.cache.(*LRUCache); ok {
+		return lru.RemoveByIndex(index, term)
+	}
+	return 0
+}
+
+// Lookup returns the keys of the live items of every shard whose value has the term in the named index, sorted.
+// It is thread-safe.
+func (sharded *ShardedCache) Lookup(index string, term string) []string {
+	var keys []string
+	for _, shard := range sharded.shards {
+		keys = append(keys, shard.Lookup(index, term)...)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RemoveByIndex removes the items of every shard whose value has the term in the named index,
+// and returns how many live items were removed.
+// It is thread-safe.
+func (sharded *ShardedCache) RemoveByIndex(index string, term string) int {
+	removed := 0
+	for _, shard := range sharded.shards {
+		removed += shard.RemoveByIndex(index, term)
+	}
+	return removed
+}
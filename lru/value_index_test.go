@@ -0,0 +1,57 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type session struct {
+	userID string
+	roles  []string
+}
+
+// sessionOptions indexes the sessions by user and by role.
+func sessionOptions() []Option {
+	return []Option{
+		WithoutMetrics(),
+		WithIndex("user", func(value any) []string { return []string{value.(session).userID} }),
+		WithIndex("role", func(value any) []string { return value.(session).roles }),
+	}
+}
+
+func TestValueIndex(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewLRUCache(3, append(sessionOptions(), WithClock(clock))...)
+	cache.Set("session1", session{userID: "ada", roles: []string{"admin"}})
+	cache.Set("session2", session{userID: "ada"})
+	cache.SetWithTTL("session3", session{userID: "alan", roles: []string{"admin"}}, time.Second)
+
+	assert.Equal(t, []string{"session1", "session2"}, cache.Lookup("user", "ada"))
+	assert.Equal(t, []string{"session1", "session3"}, cache.Lookup("role", "admin"))
+	assert.Nil(t, cache.Lookup("missing", "ada"))
+
+	cache.Set("session1", session{userID: "grace"}) // The update moves the session to another user
+	assert.Equal(t, []string{"session2"}, cache.Lookup("user", "ada"))
+	assert.Equal(t, []string{"session3"}, cache.Lookup("role", "admin"))
+
+	clock.Advance(2 * time.Second)
+	assert.Empty(t, cache.Lookup("user", "alan"), "expired items are not found")
+
+	cache.Set("session4", session{userID: "ada"}) // Evicts the expired session3
+	assert.Empty(t, cache.indexes["role"].keys, "removed items are unindexed")
+	assert.Equal(t, 2, cache.RemoveByIndex("user", "ada"))
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestShardedValueIndex(t *testing.T) {
+	cache := NewShardedCache(4, 100, sessionOptions()...)
+	cache.Set("session1", session{userID: "ada"})
+	cache.Set("session2", session{userID: "ada"})
+	cache.Set("session3", session{userID: "alan"})
+
+	assert.Equal(t, []string{"session1", "session2"}, cache.Lookup("user", "ada"))
+	assert.Equal(t, 2, cache.RemoveByIndex("user", "ada"))
+	assert.Equal(t, 1, cache.Len())
+}
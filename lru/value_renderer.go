@@ -0,0 +1,90 @@
+package lru
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ValueRenderer renders the value of a key for the state of an ObservableCache, e.g. to redact secrets
+// or to show the fields of a struct. It is called while the state is collected, so it must be fast.
+type ValueRenderer func(key string, value any) string
+
+// truncationMarker ends the values cut by WithMaxValueLength.
+const truncationMarker = "…"
+
+// WithValueRenderer renders the values of the state of an ObservableCache with the renderer
+// instead of formatting them with %v. Ignored by the other caches.
+func WithValueRenderer(renderer ValueRenderer) Option {
+	return func(o *options) {
+		o.valueRenderer = renderer
+	}
+}
+
+// WithMaxValueLength cuts the rendered values of the state of an ObservableCache to length bytes,
+// marking the cut with an ellipsis, so large values do not bloat the state. Zero for no limit. Ignored by the other caches.
+func WithMaxValueLength(length int) Option {
+	return func(o *options) {
+		o.maxValueLength = length
+	}
+}
+
+// RenderJSON is a ValueRenderer rendering the strings and the fmt.Stringer values as is,
+// and the other values as JSON, so the structs show the names of their fields.
+// The values JSON cannot encode, such as channels, are formatted with %v.
+func RenderJSON(key string, value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case fmt.Stringer:
+		return value.String()
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(encoded)
+}
+
+// Redact returns a ValueRenderer rendering the values of the keys matching redacted as a placeholder,
+// and the others with renderer, or %v if nil, e.g. to hide the tokens of a session cache in the visualizer.
+func Redact(renderer ValueRenderer, redacted func(key string) bool) ValueRenderer {
+	return func(key string, value any) string {
+		if redacted(key) {
+			return "[redacted]"
+		}
+		if renderer == nil {
+			return fmt.Sprintf("%v", value)
+		}
+		return renderer(key, value)
+	}
+}
+
+// valuePreview renders the values of the state of an ObservableCache.
+type valuePreview struct {
+	renderer  ValueRenderer // Renders the values, nil to format them with %v
+	maxLength int           // Maximum length of the rendered values in bytes, zero for no limit
+}
+
+// render returns the value rendered for the state, cut to the maximum length on a rune boundary.
+func (preview valuePreview) render(key string, value any) string {
+	var rendered string
+	if preview.renderer != nil {
+		rendered = preview.renderer(key, value)
+	} else {
+		rendered = fmt.Sprintf("%v", value)
+	}
+	if preview.maxLength <= 0 || len(rendered) <= preview.maxLength {
+		return rendered
+	}
+	cut := preview.maxLength
+	for cut > 0 && !utf8.RuneStart(rendered[cut]) {
+		cut--
+	}
+	return rendered[:cut] + truncationMarker
+}
+
+// RenderValue renders the value of the key as in the state, e.g. to show the value returned by a Get.
+func (observable *ObservableCache) RenderValue(key string, value any) string {
+	return observable.preview.render(key, value)
+}
@@ -0,0 +1,42 @@
+package lru
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderJSON(t *testing.T) {
+	type session struct {
+		User  string
+		Roles []string
+	}
+	assert.Equal(t, "plain", RenderJSON("key", "plain"))
+	assert.Equal(t, "1s", RenderJSON("key", time.Second), "Expected fmt.Stringer values to use String")
+	assert.Equal(t, `{"User":"ada","Roles":["admin"]}`, RenderJSON("key", session{User: "ada", Roles: []string{"admin"}}))
+	assert.NotEmpty(t, RenderJSON("key", make(chan int)), "Expected the values JSON cannot encode to be formatted")
+}
+
+func TestObservableStateRendersValues(t *testing.T) {
+	renderer := Redact(RenderJSON, func(key string) bool { return strings.HasPrefix(key, "token:") })
+	observable := NewObservableCache(5, WithoutMetrics(), WithValueRenderer(renderer), WithMaxValueLength(11))
+	observable.Cache.Set("token:1", "secret")
+	observable.Cache.Set("count", 42)
+	observable.Cache.Set("long", "ééééééé") // 14 bytes, cut back to the rune boundary before the 11th byte
+
+	values := map[string]string{}
+	for _, item := range observable.State().Items {
+		values[item.Key] = item.Value
+	}
+	assert.Equal(t, map[string]string{"token:1": "[redacted]", "count": "42", "long": "ééééé…"}, values)
+	assert.Equal(t, "[redacted]", observable.RenderValue("token:2", "secret"))
+}
+
+func TestShadowStateRendersValues(t *testing.T) {
+	observable := NewObservableCache(5, WithoutMetrics(), WithShadowState(0), WithMaxValueLength(3))
+	observable.State()
+	observable.Cache.Set("key", "value")
+	assert.Equal(t, "val…", observable.State().Items[0].Value)
+}
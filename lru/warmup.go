@@ -0,0 +1,274 @@
+package lru
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Record is an item to warm a cache with.
+type Record struct {
+	Key      string
+	Value    any
+	TTL      time.Duration // Zero or less for no expiration
+	Priority float64       // Records with the highest priority are kept when they don't all fit
+}
+
+// Warmup fills the cache with the records, so a restarted service does not have to load every key
+// from its database. When there are more records than the capacity of the cache, only the ones
+// with the highest priority are kept, ties going to the first records. They are inserted from the
+// least to the most important, which ends up the most recently used. It returns the number of records
+// stored, and stops at the first error of the records or when the context is done.
+func Warmup(ctx context.Context, cache Cache, records iter.Seq2[Record, error]) (int, error) {
+	capacity := cache.Capacity()
+	kept := &recordHeap{}
+	sequence := 0
+	for record, err := range records {
+		if err != nil {
+			return 0, err
+		}
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if capacity == 0 {
+			continue
+		}
+		heap.Push(kept, rankedRecord{Record: record, sequence: sequence})
+		sequence++
+		if isOver(kept.Len(), capacity) {
+			heap.Pop(kept) // The least important record
+		}
+	}
+
+	stored := 0
+	for kept.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return stored, err
+		}
+		record := heap.Pop(kept).(rankedRecord)
+		var result SetResult
+		if record.TTL > 0 {
+			result = cache.SetWithTTL(record.Key, record.Value, record.TTL)
+		} else {
+			result = cache.Set(record.Key, record.Value)
+		}
+		if result.Status != SetRejected && result.Status != SetThrottled && result.Status != SetDenied {
+			stored++
+		}
+	}
+	return stored, nil
+}
+
+// rankedRecord is a record with its position in the input, to break the ties of priority.
+type rankedRecord struct {
+	Record
+	sequence int
+}
+
+// recordHeap is a heap of records, the least important at the root.
+type recordHeap []rankedRecord
+
+func (h recordHeap) Len() int { return len(h) }
+
+func (h recordHeap) Less(i, j int) bool {
+	if h[i].Priority != h[j].Priority {
+		return h[i].Priority < h[j].Priority
+	}
+	return h[i].sequence > h[j].sequence // The later records lose the ties
+}
+
+func (h recordHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *recordHeap) Push(x any) { *h = append(*h, x.(rankedRecord)) }
+
+func (h *recordHeap) Pop() any {
+	old := *h
+	record := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return record
+}
+
+// jsonRecord is the JSON form of a Record, with the ttl as a Go duration such as "1h30m".
+type jsonRecord struct {
+	Key      string  `json:"key"`
+	Value    any     `json:"value"`
+	TTL      string  `json:"ttl,omitempty"`
+	Priority float64 `json:"priority,omitempty"`
+}
+
+// RecordsFromJSON reads records from a JSON array, or from a stream of JSON objects such as JSON lines:
+//
+//	[{"key": "user:1", "value": "Ada", "ttl": "1h", "priority": 10}, {"key": "user:2", "value": "Alan"}]
+//
+// The values are decoded like encoding/json does into an any.
+func RecordsFromJSON(r io.Reader) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		buffered := bufio.NewReader(r)
+		array, err := startsWithArray(buffered)
+		if err != nil {
+			yield(Record{}, err)
+			return
+		}
+		decoder := json.NewDecoder(buffered)
+		if array {
+			decoder.Token() // The opening bracket
+		}
+
+		for !array || decoder.More() {
+			var raw jsonRecord
+			err := decoder.Decode(&raw)
+			if errors.Is(err, io.EOF) && !array {
+				return
+			}
+			var record Record
+			if err == nil {
+				record, err = raw.record()
+			}
+			if !yield(record, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// startsWithArray returns whether the first non-space byte of the reader opens a JSON array, without consuming it.
+func startsWithArray(reader *bufio.Reader) (bool, error) {
+	for {
+		first, err := reader.Peek(1)
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		switch first[0] {
+		case ' ', '\t', '\r', '\n':
+			reader.Discard(1)
+		default:
+			return first[0] == '[', nil
+		}
+	}
+}
+
+// record converts the JSON record.
+func (raw jsonRecord) record() (Record, error) {
+	record := Record{Key: raw.Key, Value: raw.Value, Priority: raw.Priority}
+	if raw.TTL != "" {
+		ttl, err := time.ParseDuration(raw.TTL)
+		if err != nil {
+			return Record{}, fmt.Errorf("lru: invalid ttl of key %q: %w", raw.Key, err)
+		}
+		record.TTL = ttl
+	}
+	return record, nil
+}
+
+// RecordsFromCSV reads records from CSV rows of key, value and optionally ttl and priority,
+// the ttl being a Go duration such as "1h30m" and empty for no expiration. The values are strings.
+// A first row starting with a "key" column is taken as a header and skipped.
+func RecordsFromCSV(r io.Reader) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		for line := 1; ; line++ {
+			row, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err == nil && line == 1 && len(row) > 0 && row[0] == "key" {
+				continue // Header
+			}
+			var record Record
+			if err == nil {
+				record, err = csvRecord(row)
+			}
+			if err != nil {
+				yield(Record{}, fmt.Errorf("lru: line %d: %w", line, err))
+				return
+			}
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// csvRecord converts a CSV row.
+func csvRecord(row []string) (Record, error) {
+	if len(row) < 2 || len(row) > 4 {
+		return Record{}, fmt.Errorf("expected 2 to 4 columns, got %d", len(row))
+	}
+	record := Record{Key: row[0], Value: row[1]}
+	if len(row) > 2 && row[2] != "" {
+		ttl, err := time.ParseDuration(row[2])
+		if err != nil {
+			return Record{}, fmt.Errorf("invalid ttl: %w", err)
+		}
+		record.TTL = ttl
+	}
+	if len(row) > 3 && row[3] != "" {
+		priority, err := strconv.ParseFloat(row[3], 64)
+		if err != nil {
+			return Record{}, fmt.Errorf("invalid priority: %w", err)
+		}
+		record.Priority = priority
+	}
+	return record, nil
+}
+
+// Warmup loads the given keys, the most important first, so the cache is filled before serving traffic
+// instead of with the first requests. At most as many keys as the capacity of the cache are loaded,
+// by up to concurrency loaders at a time, least important first so the first keys end up the most recently used.
+// Keys already cached are not loaded again. It returns the number of keys loaded,
+// and the errors of the loader joined, after loading the other keys.
+func (loading *LoadingCache) Warmup(ctx context.Context, keys []string, concurrency int) (int, error) {
+	if capacity := loading.cache.Capacity(); capacity >= 0 {
+		keys = keys[:min(len(keys), capacity)]
+	}
+	work := make(chan string)
+	var mutex sync.Mutex
+	var loaded int
+	var errs []error
+	var wg sync.WaitGroup
+	for range max(concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range work {
+				if _, found := loading.cache.Get(key); found {
+					continue
+				}
+				_, err := loading.Get(ctx, key)
+				mutex.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("lru: loading %q: %w", key, err))
+				} else {
+					loaded++
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for i := len(keys) - 1; i >= 0 && ctx.Err() == nil; i-- {
+		select {
+		case work <- keys[i]:
+		case <-ctx.Done():
+		}
+	}
+	close(work)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return loaded, errors.Join(errs...)
+}
@@ -0,0 +1,78 @@
+package lru
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarmupKeepsTheMostImportantRecords(t *testing.T) {
+	cache := NewLRUCache(2, WithoutMetrics())
+	records := RecordsFromJSON(strings.NewReader(`[
+		{"key": "key1", "value": "value1", "priority": 1},
+		{"key": "key2", "value": "value2", "ttl": "1h", "priority": 3},
+		{"key": "key3", "value": "value3", "priority": 2}
+	]`))
+
+	stored, err := Warmup(context.Background(), cache, records)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stored)
+	_, found := cache.Get("key1")
+	assert.False(t, found, "the least important record does not fit")
+	assert.Equal(t, "key2", cache.usageOrder.Front().Value.(*entry).key, "the most important record is the most recently used")
+	assert.False(t, cache.items["key2"].Value.(*entry).expiresAt.IsZero())
+}
+
+func TestRecordsFromJSONLines(t *testing.T) {
+	var keys []string
+	for record, err := range RecordsFromJSON(strings.NewReader("{\"key\": \"key1\", \"value\": 1}\n{\"key\": \"key2\", \"value\": \"value2\"}\n")) {
+		assert.NoError(t, err)
+		keys = append(keys, record.Key)
+	}
+	assert.Equal(t, []string{"key1", "key2"}, keys)
+
+	for _, err := range RecordsFromJSON(strings.NewReader(`[{"key": "key1", "ttl": "soon"}]`)) {
+		assert.ErrorContains(t, err, "invalid ttl")
+	}
+}
+
+func TestRecordsFromCSV(t *testing.T) {
+	var records []Record
+	for record, err := range RecordsFromCSV(strings.NewReader("key,value,ttl,priority\nkey1,value1,,\nkey2,value2,30s,5\n")) {
+		assert.NoError(t, err)
+		records = append(records, record)
+	}
+	assert.Equal(t, []Record{
+		{Key: "key1", Value: "value1"},
+		{Key: "key2", Value: "value2", TTL: 30 * time.Second, Priority: 5},
+	}, records)
+
+	cache := NewLRUCache(10, WithoutMetrics())
+	_, err := Warmup(context.Background(), cache, RecordsFromCSV(strings.NewReader("key1\n")))
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestLoadingCacheWarmup(t *testing.T) {
+	var loads atomic.Int32
+	loading := NewLoadingCache(NewSafeLRUCache(3, WithoutMetrics()), func(ctx context.Context, key string) (any, time.Duration, error) {
+		loads.Add(1)
+		if key == "broken" {
+			return nil, 0, errors.New("unavailable")
+		}
+		return "value of " + key, 0, nil
+	})
+	loading.Cache().Set("cached", "value")
+
+	loaded, err := loading.Warmup(context.Background(), []string{"cached", "key1", "broken", "key2"}, 2)
+	assert.ErrorContains(t, err, "unavailable")
+	assert.Equal(t, 1, loaded, "only the keys fitting in the cache are loaded")
+	assert.Equal(t, int32(2), loads.Load(), "cached keys are not loaded again")
+	value, found := loading.Cache().Get("key1")
+	assert.True(t, found)
+	assert.Equal(t, "value of key1", value)
+}
@@ -0,0 +1,79 @@
+package lru
+
+// trimBatch is the number of items a SafeLRUCache evicts per lock acquisition when trimming
+// down to its low watermark, so the writers are not blocked for the whole trim.
+const trimBatch = 64
+
+// watermarks holds the soft capacity of a cache set by WithWatermarks. A nil *watermarks never signals.
+type watermarks struct {
+	low    float64       // Fraction of the capacity the cache is trimmed down to
+	high   float64       // Fraction of the capacity above which a trim is signaled
+	signal chan struct{} // Receives a value when the high watermark is crossed, buffered so signaling never blocks
+}
+
+// newWatermarks creates the watermarks at the given fractions of the capacity.
+func newWatermarks(low, high float64) *watermarks {
+	return &watermarks{low: low, high: high, signal: make(chan struct{}, 1)}
+}
+
+// check signals a trim if the length crossed the high watermark of the capacity.
+func (marks *watermarks) check(length, capacity int) {
+	if marks == nil || capacity < 0 || length <= int(marks.high*float64(capacity)) {
+		return
+	}
+	select {
+	case marks.signal <- struct{}{}:
+	default: // A trim is already pending
+	}
+}
+
+// target returns the number of items a trim leaves in a cache of the given capacity.
+func (marks *watermarks) target(capacity int) int {
+	return int(marks.low * float64(capacity))
+}
+
+// trim removes the expired items, then evicts the least recently used ones, at most limit of them,
+// until the cache is down to its low watermark. It returns whether the low watermark was reached.
+func (cache *LRUCache) trim(limit int) bool {
+	if cache.watermarks == nil || cache.capacity < 0 { // An unbounded cache has no watermark
+		return true
+	}
+	target := cache.watermarks.target(cache.capacity)
+	if cache.usageOrder.Len() > target {
+		cache.removeExpired()
+	}
+	for evicted := 0; cache.usageOrder.Len() > target; evicted++ {
+		if evicted == limit {
+			return false
+		}
+		cache.remove(cache.victim().Value.(*entry).key, metricReasonEvicted)
+	}
+	return true
+}
+
+// Trim evicts the least recently used items until the cache is down to its low watermark,
+// after removing the expired ones. A SafeLRUCache trims in the background when the high watermark
+// is crossed, an LRUCache used on its own must call Trim itself. It does nothing without WithWatermarks,
+// nor when the cache is unbounded.
+func (cache *LRUCache) Trim() {
+	cache.trim(-1)
+}
+
+// trimOnSignal trims the cache down to its low watermark in batches every time the high watermark
+// is crossed, until the cache is closed.
+func (safeCache *SafeLRUCache) trimOnSignal(marks *watermarks) {
+	defer close(safeCache.trimmerDone)
+
+	for {
+		select {
+		case <-marks.signal:
+			for done := false; !done; {
+				safeCache.lock()
+				done = safeCache.cache.(*LRUCache).trim(trimBatch)
+				safeCache.mutex.Unlock()
+			}
+		case <-safeCache.trimmer:
+			return
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package lru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUTrim(t *testing.T) {
+	cache := NewLRUCache(10, WithWatermarks(0.5, 0.8))
+	for i := range 10 {
+		cache.Set(fmt.Sprintf("key%d", i), i)
+	}
+	assert.Equal(t, 10, cache.Len(), "Expected an LRUCache used on its own not to trim by itself")
+
+	cache.Trim()
+	assert.Equal(t, []string{"key9", "key8", "key7", "key6", "key5"}, snapshotKeys(cache.Snapshot()))
+}
+
+func TestLRUTrimUnbounded(t *testing.T) {
+	cache := NewLRUCache(Unbounded, WithWatermarks(0.5, 0.8), WithoutMetrics())
+	for i := range 10 {
+		cache.Set(fmt.Sprintf("key%d", i), i)
+	}
+
+	cache.Trim()
+	assert.Equal(t, 10, cache.Len(), "Expected an unbounded cache not to be trimmed")
+}
+
+func TestLRUTrimBatches(t *testing.T) {
+	cache := NewLRUCache(10, WithWatermarks(0.2, 0.5))
+	for i := range 8 {
+		cache.Set(fmt.Sprintf("key%d", i), i)
+	}
+
+	assert.False(t, cache.trim(4), "Expected the trim to stop after a batch")
+	assert.Equal(t, 4, cache.Len())
+	assert.True(t, cache.trim(4))
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestSafeLRUCacheWatermarks(t *testing.T) {
+	cache := NewSafeLRUCache(10, WithWatermarks(0.5, 0.8))
+	defer cache.Close()
+
+	for i := range 8 {
+		cache.Set(fmt.Sprintf("key%d", i), i)
+	}
+	assert.Equal(t, 8, cache.Len(), "Expected no trim at the high watermark")
+
+	cache.Set("key8", 8)
+	assert.Eventually(t, func() bool { return cache.Len() == 5 }, time.Second, time.Millisecond,
+		"Expected a background trim down to the low watermark once the high watermark is crossed")
+	_, found := cache.Get("key8")
+	assert.True(t, found, "Expected the most recently used items to be kept")
+}
+
+func TestWatermarksIgnoredWhenInvalid(t *testing.T) {
+	assert.Nil(t, NewLRUCache(10, WithWatermarks(0.8, 0.5)).watermarks)
+	assert.Nil(t, NewLRUCache(10, WithWatermarks(0.5, 1.5)).watermarks)
+}
@@ -0,0 +1,117 @@
+package lru
+
+import (
+	"sync"
+	"time"
+)
+
+type writeOpKind int
+
+const (
+	writeOpSet writeOpKind = iota
+	writeOpSetWithTTL
+	writeOpSetWithMetadata
+	writeOpRemove
+	writeOpFlush
+)
+
+// writeOp is a write waiting in the buffer of a SafeLRUCache.
+type writeOp struct {
+	kind     writeOpKind
+	key      string
+	value    any
+	ttl      time.Duration
+	metadata any           // Only for writeOpSetWithMetadata
+	flushed  chan struct{} // Closed once every previous write is applied, only for writeOpFlush
+}
+
+// writeBuffer decouples the writers of a SafeLRUCache from the cache bookkeeping.
+// Writes are queued in a bounded channel, used as a ring buffer, and applied by a single
+// goroutine which drains as many pending writes as possible per lock acquisition.
+type writeBuffer struct {
+	ops     chan writeOp
+	mutex   sync.RWMutex  // Held for reading while sending, for writing while closing ops
+	closed  bool          // Whether the buffer was closed, writes are then applied synchronously
+	stopped chan struct{} // Closed once the goroutine has applied every pending write
+}
+
+func newWriteBuffer(size int) *writeBuffer {
+	return &writeBuffer{
+		ops:     make(chan writeOp, size),
+		stopped: make(chan struct{}),
+	}
+}
+
+// enqueue adds a write to the buffer, blocking while the buffer is full.
+// It returns false if the buffer is closed and the write must be applied synchronously.
+func (buffer *writeBuffer) enqueue(op writeOp) bool {
+	buffer.mutex.RLock()
+	defer buffer.mutex.RUnlock()
+
+	if buffer.closed {
+		return false
+	}
+	buffer.ops <- op
+	return true
+}
+
+// run applies the buffered writes to the cache until the buffer is closed.
+func (buffer *writeBuffer) run(safeCache *SafeLRUCache) {
+	defer close(buffer.stopped)
+
+	for op := range buffer.ops {
+		safeCache.lock()
+		buffer.apply(safeCache.cache, op)
+		for drained := false; !drained; {
+			select {
+			case next, open := <-buffer.ops:
+				if !open {
+					drained = true
+					break
+				}
+				buffer.apply(safeCache.cache, next)
+			default:
+				drained = true
+			}
+		}
+		safeCache.mutex.Unlock()
+	}
+}
+
+// apply performs a buffered write on the cache, which must be locked.
+func (buffer *writeBuffer) apply(cache Cache, op writeOp) {
+	switch op.kind {
+	case writeOpSet:
+		cache.Set(op.key, op.value)
+	case writeOpSetWithTTL:
+		cache.SetWithTTL(op.key, op.value, op.ttl)
+	case writeOpSetWithMetadata:
+		if metadataCache, ok := cache.(MetadataCache); ok {
+			metadataCache.SetWithMetadata(op.key, op.value, op.ttl, op.metadata)
+		}
+	case writeOpRemove:
+		cache.Remove(op.key)
+	case writeOpFlush:
+		close(op.flushed)
+	}
+}
+
+// flush blocks until every write enqueued before the call is applied.
+func (buffer *writeBuffer) flush() {
+	flushed := make(chan struct{})
+	if buffer.enqueue(writeOp{kind: writeOpFlush, flushed: flushed}) {
+		<-flushed
+	}
+}
+
+// close stops accepting writes and waits until the pending ones are applied.
+func (buffer *writeBuffer) close() {
+	buffer.mutex.Lock()
+	if !buffer.closed {
+		buffer.closed = true
+		close(buffer.ops)
+	}
+	buffer.mutex.Unlock()
+
+	<-buffer.stopped
+}
@@ -0,0 +1,150 @@
+package migrate
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"caching/lru"
+)
+
+// ImportMemcached walks the keys of a live memcached server with "lru_crawler metadump all", available
+// from memcached 1.4.31, and loads its values into the cache as strings with their remaining TTL, read
+// with get. The keys written while walking may be missed. ImportMemcached stops when ctx is done,
+// returning the Report of the entries imported until then.
+func ImportMemcached(ctx context.Context, addr string, cache lru.Cache, opts ...Option) (Report, error) {
+	imp := &importer{cache: cache, opts: newOptions(opts...)}
+	conn, err := dial(ctx, addr)
+	if err != nil {
+		return imp.report, err
+	}
+	defer conn.Close()
+
+	memcached := &memcachedConn{reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}
+	return imp.report, contextError(ctx, memcached.walk(ctx, imp))
+}
+
+// memcachedConn sends commands to a memcached server, with its text protocol.
+type memcachedConn struct {
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+// walk lists the keys with their expiration, then imports them by batches.
+func (memcached *memcachedConn) walk(ctx context.Context, imp *importer) error {
+	expirations, err := memcached.metadump(imp)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(expirations))
+	for key := range expirations {
+		keys = append(keys, key)
+	}
+
+	for len(keys) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch := keys[:min(imp.opts.count, len(keys))]
+		keys = keys[len(batch):]
+		if err := memcached.importKeys(imp, batch, expirations); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metadump returns the expiration of every key wanted, zero for the keys that do not expire.
+func (memcached *memcachedConn) metadump(imp *importer) (map[string]time.Time, error) {
+	if err := memcached.send("lru_crawler metadump all"); err != nil {
+		return nil, err
+	}
+	expirations := map[string]time.Time{}
+	for {
+		line, err := memcached.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "END" {
+			return expirations, nil
+		}
+		if !strings.HasPrefix(line, "key=") {
+			return nil, fmt.Errorf("migrate: lru_crawler metadump: %s", line)
+		}
+
+		var key string
+		var expiresAt time.Time
+		for _, field := range strings.Fields(line) {
+			name, value, _ := strings.Cut(field, "=")
+			switch name {
+			case "key":
+				key, err = url.QueryUnescape(value)
+			case "exp":
+				var exp int64
+				if exp, err = strconv.ParseInt(value, 10, 64); exp > 0 {
+					expiresAt = time.Unix(exp, 0)
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("migrate: lru_crawler metadump: %w", err)
+			}
+		}
+		if imp.wanted(key) {
+			expirations[key] = expiresAt
+		}
+	}
+}
+
+// importKeys reads the keys with a single get, and stores their values. The keys deleted since the metadump are ignored.
+func (memcached *memcachedConn) importKeys(imp *importer, keys []string, expirations map[string]time.Time) error {
+	if err := memcached.send("get " + strings.Join(keys, " ")); err != nil {
+		return err
+	}
+	for {
+		line, err := memcached.readLine()
+		if err != nil {
+			return err
+		}
+		if line == "END" {
+			return nil
+		}
+		fields := strings.Fields(line) // VALUE <key> <flags> <bytes>
+		if len(fields) < 4 || fields[0] != "VALUE" {
+			return fmt.Errorf("migrate: get: %s", line)
+		}
+		length, err := strconv.Atoi(fields[3])
+		if err != nil || length < 0 {
+			return fmt.Errorf("migrate: get: invalid length %q", fields[3])
+		}
+		data := make([]byte, length+2) // Followed by CRLF
+		if _, err := io.ReadFull(memcached.reader, data); err != nil {
+			return err
+		}
+		imp.store(fields[1], string(data[:length]), expirations[fields[1]])
+	}
+}
+
+// send writes a command line.
+func (memcached *memcachedConn) send(command string) error {
+	memcached.writer.WriteString(command)
+	memcached.writer.WriteString("\r\n")
+	return memcached.writer.Flush()
+}
+
+// readLine reads a line, without its terminator. The lines of metadump end with LF only.
+func (memcached *memcachedConn) readLine() (string, error) {
+	line, err := memcached.reader.ReadString('\n')
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
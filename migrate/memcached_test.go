@@ -0,0 +1,82 @@
+package migrate
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"caching/lru"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serveFakeMemcached serves the metadump lines and the values with get, returning its address.
+func serveFakeMemcached(t *testing.T, metadump []string, values map[string]string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader, writer := bufio.NewReader(conn), bufio.NewWriter(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "lru_crawler":
+				for _, line := range metadump {
+					fmt.Fprintf(writer, "%s\n", line)
+				}
+			case "get":
+				for _, key := range fields[1:] {
+					if value, found := values[key]; found {
+						fmt.Fprintf(writer, "VALUE %s 0 %d\r\n%s\r\n", key, len(value), value)
+					}
+				}
+			}
+			writer.WriteString("END\r\n")
+			writer.Flush()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestImportMemcached(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	addr := serveFakeMemcached(t, []string{
+		"key=key1 exp=-1 la=1767225600 cas=1 fetch=no cls=1 size=64",
+		fmt.Sprintf("key=key2 exp=%d la=1767225600 cas=2 fetch=no cls=1 size=64", now.Add(time.Minute).Unix()),
+		fmt.Sprintf("key=key3 exp=%d la=1767225600 cas=3 fetch=no cls=1 size=64", now.Add(-time.Second).Unix()),
+		"key=deleted exp=-1 la=1767225600 cas=4 fetch=no cls=1 size=64",
+	}, map[string]string{"key1": "value1", "key2": "value2", "key3": "value3"})
+	clock := lru.NewManualClock(now)
+	cache := lru.NewLRUCache(10, lru.WithoutMetrics(), lru.WithClock(clock))
+
+	report, err := ImportMemcached(context.Background(), addr, cache, WithScanCount(2), func(o *options) { o.clock = clock.Now })
+	assert.NoError(t, err)
+	assert.Equal(t, Report{Imported: 2, Expired: 1}, report, "Expected the keys deleted since the metadump to be ignored")
+
+	value, found := cache.Get("key1")
+	assert.True(t, found)
+	assert.Equal(t, "value1", value)
+	clock.Advance(time.Minute + time.Second)
+	_, found = cache.Get("key2")
+	assert.False(t, found, "Expected the remaining TTL to be kept")
+}
+
+func TestImportMemcachedUnsupported(t *testing.T) {
+	addr := serveFakeMemcached(t, []string{"ERROR"}, nil)
+	_, err := ImportMemcached(context.Background(), addr, lru.NewLRUCache(10, lru.WithoutMetrics()))
+	assert.ErrorContains(t, err, "metadump: ERROR")
+}
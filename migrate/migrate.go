@@ -0,0 +1,94 @@
+// Package migrate loads the entries of another cache into an lru.Cache, so a service switching over
+// starts with a warm cache: from a Redis RDB snapshot with ImportRDB, or by walking a live Redis server
+// with ImportRedis or a live memcached server with ImportMemcached. Only the strings are imported,
+// as strings, with their remaining TTL; the entries already expired are skipped.
+package migrate
+
+import (
+	"errors"
+	"time"
+
+	"caching/lru"
+)
+
+// ErrUnsupportedRDB is returned by ImportRDB when the snapshot holds data it cannot read, such as a stream
+// or a module type, or comes from a version of Redis newer than the ones it knows.
+var ErrUnsupportedRDB = errors.New("migrate: unsupported RDB data")
+
+// Report counts the entries met by an import.
+type Report struct {
+	Imported int // Strings written to the cache
+	Expired  int // Strings skipped as they expired before being imported
+	Skipped  int // Entries skipped as they are not strings, e.g. the lists and hashes of Redis
+}
+
+// options holds the optional configuration of the imports.
+type options struct {
+	filter   func(key string) bool // Keys imported, nil for every key
+	clock    func() time.Time      // Current time, to turn the expirations into TTLs
+	count    int                   // Keys requested per SCAN of ImportRedis, or per get of ImportMemcached
+	database int                   // Redis database imported
+	password string                // Password sent with AUTH by ImportRedis
+}
+
+// Option configures an import.
+type Option func(*options)
+
+// WithKeyFilter only imports the keys for which filter returns true, e.g. the keys of one namespace.
+// The other keys are not counted in the Report.
+func WithKeyFilter(filter func(key string) bool) Option {
+	return func(o *options) {
+		o.filter = filter
+	}
+}
+
+// WithDatabase imports the keys of the given Redis database, instead of database 0.
+// ImportRDB skips the keys of the other databases, ImportRedis selects it.
+func WithDatabase(db int) Option {
+	return func(o *options) {
+		o.database = db
+	}
+}
+
+// WithScanCount sets how many keys ImportRedis requests per SCAN, and ImportMemcached per get. Defaults to 1000.
+func WithScanCount(count int) Option {
+	return func(o *options) {
+		o.count = count
+	}
+}
+
+func newOptions(opts ...Option) options {
+	o := options{clock: time.Now, count: 1000}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// importer writes the entries read from the source to the cache, and counts them.
+type importer struct {
+	cache  lru.Cache
+	opts   options
+	report Report
+}
+
+// wanted returns whether the key passes the filter.
+func (imp *importer) wanted(key string) bool {
+	return imp.opts.filter == nil || imp.opts.filter(key)
+}
+
+// store writes the string to the cache, expiring at the given time, or never if it is zero.
+func (imp *importer) store(key string, value string, expiresAt time.Time) {
+	if expiresAt.IsZero() {
+		imp.cache.Set(key, value)
+		imp.report.Imported++
+		return
+	}
+	ttl := expiresAt.Sub(imp.opts.clock())
+	if ttl <= 0 {
+		imp.report.Expired++
+		return
+	}
+	imp.cache.SetWithTTL(key, value, ttl)
+	imp.report.Imported++
+}
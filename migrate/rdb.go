@@ -0,0 +1,396 @@
+package migrate
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"caching/lru"
+)
+
+// maxRDBVersion is the last version of the RDB format known, written by Redis 7.4.
+const maxRDBVersion = 12
+
+// Opcodes of the RDB format, read in place of the type of an entry.
+const (
+	rdbOpSlotInfo     = 0xF4
+	rdbOpModuleAux    = 0xF5
+	rdbOpFunction2    = 0xF6
+	rdbOpFunction     = 0xF7
+	rdbOpIdle         = 0xF8
+	rdbOpFreq         = 0xF9
+	rdbOpAux          = 0xFA
+	rdbOpResizeDB     = 0xFB
+	rdbOpExpireTimeMs = 0xFC
+	rdbOpExpireTime   = 0xFD
+	rdbOpSelectDB     = 0xFE
+	rdbOpEOF          = 0xFF
+)
+
+// Types of the entries of the RDB format.
+const (
+	rdbTypeString         = 0
+	rdbTypeList           = 1
+	rdbTypeSet            = 2
+	rdbTypeZSet           = 3
+	rdbTypeHash           = 4
+	rdbTypeZSet2          = 5
+	rdbTypeHashZipmap     = 9
+	rdbTypeListZiplist    = 10
+	rdbTypeSetIntset      = 11
+	rdbTypeZSetZiplist    = 12
+	rdbTypeHashZiplist    = 13
+	rdbTypeListQuicklist  = 14
+	rdbTypeHashListpack   = 16
+	rdbTypeZSetListpack   = 17
+	rdbTypeListQuicklist2 = 18
+	rdbTypeSetListpack    = 20
+)
+
+// Special encodings of the strings, after a length with the two top bits set.
+const (
+	rdbEncodingInt8  = 0
+	rdbEncodingInt16 = 1
+	rdbEncodingInt32 = 2
+	rdbEncodingLZF   = 3
+)
+
+const (
+	rdbLength32      = 0x80      // First byte of the lengths followed by 32 bits in big endian
+	rdbLength64      = 0x81      // First byte of the lengths followed by 64 bits in big endian
+	rdbZSetDoubleNaN = 253       // First length of the special scores of rdbTypeZSet, not followed by digits
+	rdbMaxAllocation = 512 << 20 // Largest string read, to fail on corrupted lengths instead of allocating them
+)
+
+// ImportRDB loads the strings of a Redis RDB snapshot, e.g. a dump.rdb file, into the cache with their remaining TTL.
+// The keys of the other types, such as lists and hashes, are skipped and counted in the Report. ImportRDB returns
+// ErrUnsupportedRDB if the snapshot holds a stream or module data, as they cannot be skipped, along with the Report
+// of the entries imported until then.
+func ImportRDB(r io.Reader, cache lru.Cache, opts ...Option) (Report, error) {
+	imp := &importer{cache: cache, opts: newOptions(opts...)}
+	reader := &rdbReader{reader: bufio.NewReader(r)}
+	if err := reader.readEntries(imp); err != nil {
+		return imp.report, fmt.Errorf("migrate: reading RDB: %w", err)
+	}
+	return imp.report, nil
+}
+
+// rdbReader reads the values of an RDB snapshot.
+type rdbReader struct {
+	reader *bufio.Reader
+}
+
+// readEntries reads the header and every entry of the snapshot, storing the strings of the database imported.
+func (reader *rdbReader) readEntries(imp *importer) error {
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(reader.reader, header); err != nil {
+		return err
+	}
+	if string(header[:5]) != "REDIS" {
+		return errors.New("not an RDB snapshot")
+	}
+	version, err := strconv.Atoi(string(header[5:]))
+	if err != nil || version > maxRDBVersion {
+		return fmt.Errorf("%w: version %q", ErrUnsupportedRDB, header[5:])
+	}
+
+	database := 0
+	var expiresAt time.Time
+	for {
+		kind, err := reader.reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case rdbOpEOF:
+			return nil // Followed by a checksum from version 5, not verified
+		case rdbOpSelectDB:
+			var db uint64
+			db, err = reader.readLength()
+			database = int(db)
+		case rdbOpResizeDB:
+			err = reader.skipLengths(2)
+		case rdbOpSlotInfo:
+			err = reader.skipLengths(3)
+		case rdbOpAux:
+			err = reader.skipStrings(2)
+		case rdbOpFunction2:
+			err = reader.skipStrings(1)
+		case rdbOpIdle:
+			err = reader.skipLengths(1)
+		case rdbOpFreq:
+			_, err = reader.reader.ReadByte()
+		case rdbOpExpireTime:
+			var seconds uint32
+			err = binary.Read(reader.reader, binary.LittleEndian, &seconds)
+			expiresAt = time.Unix(int64(seconds), 0)
+		case rdbOpExpireTimeMs:
+			var millis uint64
+			err = binary.Read(reader.reader, binary.LittleEndian, &millis)
+			expiresAt = time.UnixMilli(int64(millis))
+		case rdbOpModuleAux, rdbOpFunction:
+			return fmt.Errorf("%w: opcode %#x", ErrUnsupportedRDB, kind)
+		default:
+			err = reader.readEntry(imp, kind, database, expiresAt)
+			expiresAt = time.Time{}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readEntry reads a key and its value of the given type, storing it if it is a string of the database imported.
+func (reader *rdbReader) readEntry(imp *importer, kind byte, database int, expiresAt time.Time) error {
+	key, err := reader.readString()
+	if err != nil {
+		return err
+	}
+	imported := database == imp.opts.database && imp.wanted(string(key))
+	if kind == rdbTypeString {
+		value, err := reader.readString()
+		if err != nil {
+			return err
+		}
+		if imported {
+			imp.store(string(key), string(value), expiresAt)
+		}
+	} else {
+		if err := reader.skipValue(kind); err != nil {
+			return err
+		}
+		if imported {
+			imp.report.Skipped++
+		}
+	}
+	return nil
+}
+
+// skipValue reads a value of a type other than a string, without decoding it.
+func (reader *rdbReader) skipValue(kind byte) error {
+	switch kind {
+	case rdbTypeList, rdbTypeSet, rdbTypeListQuicklist:
+		length, err := reader.readLength()
+		if err != nil {
+			return err
+		}
+		return reader.skipStrings(length)
+	case rdbTypeHash:
+		length, err := reader.readLength()
+		if err != nil {
+			return err
+		}
+		return reader.skipStrings(2 * length)
+	case rdbTypeZSet:
+		length, err := reader.readLength()
+		if err != nil {
+			return err
+		}
+		for ; length > 0; length-- {
+			if err := reader.skipStrings(1); err != nil {
+				return err
+			}
+			size, err := reader.reader.ReadByte()
+			if err != nil {
+				return err
+			}
+			if size < rdbZSetDoubleNaN {
+				if _, err := reader.reader.Discard(int(size)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case rdbTypeZSet2:
+		length, err := reader.readLength()
+		if err != nil {
+			return err
+		}
+		for ; length > 0; length-- {
+			if err := reader.skipStrings(1); err != nil {
+				return err
+			}
+			if _, err := reader.reader.Discard(8); err != nil { // Binary double
+				return err
+			}
+		}
+		return nil
+	case rdbTypeListQuicklist2:
+		length, err := reader.readLength()
+		if err != nil {
+			return err
+		}
+		for ; length > 0; length-- {
+			if err := reader.skipLengths(1); err != nil { // Container of the node, plain or packed
+				return err
+			}
+			if err := reader.skipStrings(1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case rdbTypeHashZipmap, rdbTypeListZiplist, rdbTypeSetIntset, rdbTypeZSetZiplist, rdbTypeHashZiplist,
+		rdbTypeHashListpack, rdbTypeZSetListpack, rdbTypeSetListpack:
+		return reader.skipStrings(1) // Encoded in a single string
+	default:
+		return fmt.Errorf("%w: type %d", ErrUnsupportedRDB, kind)
+	}
+}
+
+// readLengthOrEncoding reads a length, or the id of a special encoding if encoded is true.
+func (reader *rdbReader) readLengthOrEncoding() (length uint64, encoded bool, err error) {
+	first, err := reader.reader.ReadByte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch first >> 6 {
+	case 0:
+		return uint64(first & 0x3F), false, nil
+	case 1:
+		next, err := reader.reader.ReadByte()
+		return uint64(first&0x3F)<<8 | uint64(next), false, err
+	case 2:
+		switch first {
+		case rdbLength32:
+			var length uint32
+			err := binary.Read(reader.reader, binary.BigEndian, &length)
+			return uint64(length), false, err
+		case rdbLength64:
+			err := binary.Read(reader.reader, binary.BigEndian, &length)
+			return length, false, err
+		}
+		return 0, false, fmt.Errorf("invalid length %#x", first)
+	default:
+		return uint64(first & 0x3F), true, nil
+	}
+}
+
+// readLength reads a length, failing on a special encoding.
+func (reader *rdbReader) readLength() (uint64, error) {
+	length, encoded, err := reader.readLengthOrEncoding()
+	if err == nil && encoded {
+		err = errors.New("unexpected string encoding")
+	}
+	return length, err
+}
+
+// readString reads a string, decoding the integers and decompressing the LZF strings.
+func (reader *rdbReader) readString() ([]byte, error) {
+	length, encoded, err := reader.readLengthOrEncoding()
+	if err != nil {
+		return nil, err
+	}
+	if !encoded {
+		return reader.readBytes(length)
+	}
+
+	switch length {
+	case rdbEncodingInt8:
+		n, err := reader.reader.ReadByte()
+		return strconv.AppendInt(nil, int64(int8(n)), 10), err
+	case rdbEncodingInt16:
+		var n int16
+		err := binary.Read(reader.reader, binary.LittleEndian, &n)
+		return strconv.AppendInt(nil, int64(n), 10), err
+	case rdbEncodingInt32:
+		var n int32
+		err := binary.Read(reader.reader, binary.LittleEndian, &n)
+		return strconv.AppendInt(nil, int64(n), 10), err
+	case rdbEncodingLZF:
+		compressedLength, err := reader.readLength()
+		if err != nil {
+			return nil, err
+		}
+		length, err := reader.readLength()
+		if err != nil {
+			return nil, err
+		}
+		if length > rdbMaxAllocation {
+			return nil, fmt.Errorf("string of %d bytes too large", length)
+		}
+		compressed, err := reader.readBytes(compressedLength)
+		if err != nil {
+			return nil, err
+		}
+		return decompressLZF(compressed, int(length))
+	default:
+		return nil, fmt.Errorf("unknown string encoding %d", length)
+	}
+}
+
+// readBytes reads the given number of bytes.
+func (reader *rdbReader) readBytes(length uint64) ([]byte, error) {
+	if length > rdbMaxAllocation {
+		return nil, fmt.Errorf("string of %d bytes too large", length)
+	}
+	data := make([]byte, length)
+	_, err := io.ReadFull(reader.reader, data)
+	return data, err
+}
+
+// skipStrings reads the given number of strings, discarding them.
+func (reader *rdbReader) skipStrings(n uint64) error {
+	for ; n > 0; n-- {
+		if _, err := reader.readString(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipLengths reads the given number of lengths, discarding them.
+func (reader *rdbReader) skipLengths(n int) error {
+	for ; n > 0; n-- {
+		if _, err := reader.readLength(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decompressLZF decompresses the data of an LZF string of the given length.
+func decompressLZF(compressed []byte, length int) ([]byte, error) {
+	corrupted := errors.New("corrupted LZF string")
+	data := make([]byte, 0, length)
+	for i := 0; i < len(compressed); {
+		control := int(compressed[i])
+		i++
+		if control < 1<<5 { // Literal run of control+1 bytes
+			end := i + control + 1
+			if end > len(compressed) || len(data)+control+1 > length {
+				return nil, corrupted
+			}
+			data = append(data, compressed[i:end]...)
+			i = end
+			continue
+		}
+
+		size := control >> 5 // Back reference of size+2 bytes
+		if size == 7 {
+			if i >= len(compressed) {
+				return nil, corrupted
+			}
+			size += int(compressed[i])
+			i++
+		}
+		if i >= len(compressed) {
+			return nil, corrupted
+		}
+		from := len(data) - (control&0x1F)<<8 - int(compressed[i]) - 1
+		i++
+		if from < 0 || len(data)+size+2 > length {
+			return nil, corrupted
+		}
+		for j := 0; j < size+2; j++ { // Byte by byte, as the reference may overlap the bytes copied
+			data = append(data, data[from+j])
+		}
+	}
+	if len(data) != length {
+		return nil, corrupted
+	}
+	return data, nil
+}
@@ -0,0 +1,135 @@
+package migrate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"caching/lru"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rdbWriter writes an RDB snapshot for the tests.
+type rdbWriter struct {
+	bytes.Buffer
+}
+
+func newRDBWriter() *rdbWriter {
+	writer := &rdbWriter{}
+	writer.WriteString("REDIS0011")
+	writer.WriteByte(rdbOpAux)
+	writer.writeString("redis-ver")
+	writer.writeString("7.2.4")
+	writer.WriteByte(rdbOpSelectDB)
+	writer.WriteByte(0)
+	writer.WriteByte(rdbOpResizeDB)
+	writer.Write([]byte{5, 1})
+	return writer
+}
+
+func (writer *rdbWriter) writeString(text string) {
+	if len(text) < 1<<6 {
+		writer.WriteByte(byte(len(text)))
+	} else {
+		writer.Write([]byte{0x40 | byte(len(text)>>8), byte(len(text))})
+	}
+	writer.WriteString(text)
+}
+
+func (writer *rdbWriter) writeExpiration(expiresAt time.Time) {
+	writer.WriteByte(rdbOpExpireTimeMs)
+	binary.Write(writer, binary.LittleEndian, uint64(expiresAt.UnixMilli()))
+}
+
+func (writer *rdbWriter) writeEOF() []byte {
+	writer.WriteByte(rdbOpEOF)
+	writer.Write(make([]byte, 8)) // Checksum
+	return writer.Bytes()
+}
+
+func TestImportRDB(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	writer := newRDBWriter()
+	writer.WriteByte(rdbTypeString)
+	writer.writeString("plain")
+	writer.writeString("value")
+	writer.writeExpiration(now.Add(time.Minute))
+	writer.WriteByte(rdbTypeString)
+	writer.writeString("ttl")
+	writer.writeString("expiring")
+	writer.writeExpiration(now.Add(-time.Second))
+	writer.WriteByte(rdbTypeString)
+	writer.writeString("expired")
+	writer.writeString("gone")
+	writer.WriteByte(rdbTypeString)
+	writer.writeString("number")
+	writer.Write([]byte{0xC0 | rdbEncodingInt16, 0x39, 0x30}) // 12345
+	writer.WriteByte(rdbTypeString)
+	writer.writeString("compressed")
+	writer.Write([]byte{0xC0 | rdbEncodingLZF, 5, 10, 0x00, 'a', 0xE0, 0x00, 0x00}) // "a", then 9 bytes from 1 back
+	writer.WriteByte(rdbTypeList)
+	writer.writeString("list")
+	writer.WriteByte(2)
+	writer.writeString("first")
+	writer.writeString("second")
+	writer.WriteByte(rdbTypeZSet2)
+	writer.writeString("zset")
+	writer.WriteByte(1)
+	writer.writeString("member")
+	binary.Write(writer, binary.LittleEndian, 1.5)
+	writer.WriteByte(rdbTypeHashListpack)
+	writer.writeString("hash")
+	writer.writeString("listpack bytes")
+	writer.WriteByte(rdbOpSelectDB)
+	writer.WriteByte(1)
+	writer.WriteByte(rdbTypeString)
+	writer.writeString("other")
+	writer.writeString("database")
+	data := writer.writeEOF()
+
+	clock := lru.NewManualClock(now)
+	cache := lru.NewLRUCache(10, lru.WithoutMetrics(), lru.WithClock(clock))
+	report, err := ImportRDB(bytes.NewReader(data), cache, func(o *options) { o.clock = clock.Now })
+	assert.NoError(t, err)
+	assert.Equal(t, Report{Imported: 4, Expired: 1, Skipped: 3}, report)
+
+	for key, expected := range map[string]string{"plain": "value", "ttl": "expiring", "number": "12345", "compressed": "aaaaaaaaaa"} {
+		value, found := cache.Get(key)
+		assert.True(t, found, key)
+		assert.Equal(t, expected, value, key)
+	}
+	_, found := cache.Get("other")
+	assert.False(t, found, "Expected the keys of the other databases to be skipped")
+
+	clock.Advance(2 * time.Minute)
+	_, found = cache.Get("ttl")
+	assert.False(t, found, "Expected the remaining TTL to be kept")
+
+	cache = lru.NewLRUCache(10, lru.WithoutMetrics())
+	report, err = ImportRDB(bytes.NewReader(data), cache, WithDatabase(1))
+	assert.NoError(t, err)
+	assert.Equal(t, Report{Imported: 1}, report)
+}
+
+func TestImportRDBErrors(t *testing.T) {
+	cache := lru.NewLRUCache(10, lru.WithoutMetrics())
+	_, err := ImportRDB(bytes.NewReader([]byte("REDIS0099")), cache)
+	assert.ErrorIs(t, err, ErrUnsupportedRDB)
+
+	writer := newRDBWriter()
+	writer.WriteByte(rdbTypeString)
+	writer.writeString("key")
+	writer.writeString("value")
+	writer.WriteByte(21) // Stream
+	writer.writeString("stream")
+	report, err := ImportRDB(bytes.NewReader(writer.writeEOF()), cache)
+	assert.ErrorIs(t, err, ErrUnsupportedRDB)
+	assert.Equal(t, Report{Imported: 1}, report)
+
+	_, err = ImportRDB(bytes.NewReader([]byte("REDIS0011\x00\x03key")), cache)
+	assert.Error(t, err, "Expected a truncated snapshot to fail")
+	_, err = ImportRDB(bytes.NewReader([]byte("RDB")), cache)
+	assert.Error(t, err)
+}
@@ -0,0 +1,164 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"caching/internal/resp"
+	"caching/lru"
+)
+
+// WithPassword authenticates to the Redis server with AUTH before walking its keys.
+func WithPassword(password string) Option {
+	return func(o *options) {
+		o.password = password
+	}
+}
+
+// ImportRedis walks the keys of a live Redis server with SCAN, and loads its strings into the cache
+// with their remaining TTL, read with GET and PTTL. The keys of the other types are skipped and counted
+// in the Report. The keys written while walking may be missed, as with SCAN. ImportRedis stops
+// when ctx is done, returning the Report of the entries imported until then.
+func ImportRedis(ctx context.Context, addr string, cache lru.Cache, opts ...Option) (Report, error) {
+	imp := &importer{cache: cache, opts: newOptions(opts...)}
+	conn, err := dial(ctx, addr)
+	if err != nil {
+		return imp.report, err
+	}
+	defer conn.Close()
+
+	redis := &redisConn{reader: resp.NewReader(conn), writer: resp.NewWriter(conn)}
+	return imp.report, contextError(ctx, redis.walk(ctx, imp))
+}
+
+// redisConn sends commands to a Redis server.
+type redisConn struct {
+	reader *resp.Reader
+	writer *resp.Writer
+}
+
+// call sends a command and reads its reply, returning the errors replied as errors.
+func (redis *redisConn) call(args ...string) (resp.Value, error) {
+	redis.writer.WriteCommand(args...)
+	if err := redis.writer.Flush(); err != nil {
+		return resp.Value{}, err
+	}
+	reply, err := redis.reader.ReadValue()
+	if err == nil && reply.Kind == resp.Error {
+		err = fmt.Errorf("migrate: %s: %s", args[0], reply.Str)
+	}
+	return reply, err
+}
+
+// walk authenticates and selects the database, then imports the keys returned by SCAN until its cursor is back to 0.
+func (redis *redisConn) walk(ctx context.Context, imp *importer) error {
+	if imp.opts.password != "" {
+		if _, err := redis.call("AUTH", imp.opts.password); err != nil {
+			return err
+		}
+	}
+	if imp.opts.database != 0 {
+		if _, err := redis.call("SELECT", strconv.Itoa(imp.opts.database)); err != nil {
+			return err
+		}
+	}
+
+	cursor := "0"
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		reply, err := redis.call("SCAN", cursor, "COUNT", strconv.Itoa(imp.opts.count))
+		if err != nil {
+			return err
+		}
+		if len(reply.Array) != 2 {
+			return errors.New("migrate: unexpected SCAN reply")
+		}
+		if err := redis.importKeys(imp, reply.Array[1].Array); err != nil {
+			return err
+		}
+		if cursor = reply.Array[0].Str; cursor == "0" {
+			return nil
+		}
+	}
+}
+
+// importKeys reads the keys returned by a SCAN with GET and PTTL, pipelined, and stores their strings.
+func (redis *redisConn) importKeys(imp *importer, keys []resp.Value) error {
+	wanted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if imp.wanted(key.Str) {
+			wanted = append(wanted, key.Str)
+		}
+	}
+	for _, key := range wanted {
+		redis.writer.WriteCommand("GET", key)
+		redis.writer.WriteCommand("PTTL", key)
+	}
+	if err := redis.writer.Flush(); err != nil {
+		return err
+	}
+
+	now := imp.opts.clock()
+	for _, key := range wanted {
+		value, err := redis.reader.ReadValue()
+		if err != nil {
+			return err
+		}
+		ttl, err := redis.reader.ReadValue()
+		if err != nil {
+			return err
+		}
+		switch {
+		case value.Kind == resp.Error && strings.HasPrefix(value.Str, "WRONGTYPE"):
+			imp.report.Skipped++
+		case value.Kind == resp.Error:
+			return fmt.Errorf("migrate: GET: %s", value.Str)
+		case value.Null || ttl.Int == -2: // Deleted or expired since the SCAN
+		case ttl.Int == -1:
+			imp.store(key, value.Str, time.Time{})
+		default:
+			imp.store(key, value.Str, now.Add(time.Duration(ttl.Int)*time.Millisecond))
+		}
+	}
+	return nil
+}
+
+// dial connects to the server, the connection expiring with ctx.
+func dial(ctx context.Context, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) }) // Unblocks the reads on cancellation
+	return &stoppingConn{Conn: conn, stop: stop}, nil
+}
+
+// stoppingConn stops watching its context once closed.
+type stoppingConn struct {
+	net.Conn
+	stop func() bool
+}
+
+func (conn *stoppingConn) Close() error {
+	conn.stop()
+	return conn.Conn.Close()
+}
+
+// contextError returns the error of ctx instead of err if ctx is done, as it made the reads fail.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+package migrate
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"caching/internal/resp"
+	"caching/lru"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedisKey is a key of fakeRedis.
+type fakeRedisKey struct {
+	value string
+	pttl  int64 // -1 if the key does not expire
+	list  bool  // Whether the key holds a list instead of a string
+}
+
+// serveFakeRedis serves the keys with SCAN, returning a single key per call, GET and PTTL, returning its address.
+func serveFakeRedis(t *testing.T, keys map[string]fakeRedisKey) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader, writer := resp.NewReader(conn), resp.NewWriter(conn)
+		for {
+			args, err := reader.ReadCommand()
+			if err != nil {
+				return
+			}
+			switch args[0] {
+			case "AUTH":
+				if args[1] == "secret" {
+					writer.WriteSimpleString("OK")
+				} else {
+					writer.WriteError("WRONGPASS invalid password")
+				}
+			case "SCAN":
+				cursor, _ := strconv.Atoi(args[1])
+				next := strconv.Itoa((cursor + 1) % (len(names) + 1))
+				writer.WriteArrayHeader(2)
+				writer.WriteBulkString(next)
+				if cursor < len(names) {
+					writer.WriteArrayHeader(1)
+					writer.WriteBulkString(names[cursor])
+				} else {
+					writer.WriteArrayHeader(0)
+				}
+			case "GET":
+				if key, found := keys[args[1]]; !found {
+					writer.WriteNull()
+				} else if key.list {
+					writer.WriteError("WRONGTYPE Operation against a key holding the wrong kind of value")
+				} else {
+					writer.WriteBulkString(key.value)
+				}
+			case "PTTL":
+				writer.WriteInteger(keys[args[1]].pttl)
+			}
+			writer.Flush()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestImportRedis(t *testing.T) {
+	addr := serveFakeRedis(t, map[string]fakeRedisKey{
+		"key1":  {value: "value1", pttl: -1},
+		"key2":  {value: "value2", pttl: 60_000},
+		"list":  {list: true},
+		"other": {value: "filtered", pttl: -1},
+	})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := lru.NewManualClock(now)
+	cache := lru.NewLRUCache(10, lru.WithoutMetrics(), lru.WithClock(clock))
+
+	filter := WithKeyFilter(func(key string) bool { return key != "other" })
+	report, err := ImportRedis(context.Background(), addr, cache, filter, WithPassword("secret"),
+		func(o *options) { o.clock = clock.Now })
+	assert.NoError(t, err)
+	assert.Equal(t, Report{Imported: 2, Skipped: 1}, report)
+
+	value, found := cache.Get("key1")
+	assert.True(t, found)
+	assert.Equal(t, "value1", value)
+	_, found = cache.Get("other")
+	assert.False(t, found)
+	clock.Advance(time.Minute + time.Second)
+	_, found = cache.Get("key2")
+	assert.False(t, found, "Expected the remaining TTL to be kept")
+}
+
+func TestImportRedisErrors(t *testing.T) {
+	addr := serveFakeRedis(t, map[string]fakeRedisKey{"key1": {value: "value1", pttl: -1}})
+	cache := lru.NewLRUCache(10, lru.WithoutMetrics())
+	_, err := ImportRedis(context.Background(), addr, cache, WithPassword("wrong"))
+	assert.ErrorContains(t, err, "WRONGPASS")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ImportRedis(ctx, addr, cache)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,489 @@
+package persist
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"caching/internal/resp"
+	"caching/lru"
+)
+
+// errClosed is returned when taking a snapshot of a closed log.
+var errClosed = errors.New("persist: log closed")
+
+// FsyncPolicy selects when the append-only log is flushed to the disk,
+// trading the mutations lost on a crash for the cost of the writes.
+type FsyncPolicy int
+
+const (
+	// FsyncEverySecond flushes the log once per second, losing at most a second of mutations.
+	FsyncEverySecond FsyncPolicy = iota
+	// FsyncAlways flushes the log after every mutation.
+	FsyncAlways
+	// FsyncNever leaves the flushes to the operating system, the log is only written once per second.
+	FsyncNever
+)
+
+// options holds the optional configuration of an AOF.
+type options struct {
+	fsync       FsyncPolicy // When the log is flushed to the disk
+	rewriteSize int64       // Size above which the log is rewritten, zero to never rewrite it automatically
+}
+
+// Option configures an AOF at construction time.
+type Option func(*options)
+
+// WithFsync sets when the log is flushed to the disk. Defaults to FsyncEverySecond.
+func WithFsync(policy FsyncPolicy) Option {
+	return func(o *options) {
+		o.fsync = policy
+	}
+}
+
+// WithAutoRewrite rewrites the log in the background once it is larger than minSize bytes
+// and twice as large as after the previous rewrite, so it does not grow without bounds.
+func WithAutoRewrite(minSize int64) Option {
+	return func(o *options) {
+		o.rewriteSize = minSize
+	}
+}
+
+// AOF is a Persister appending every mutation of the cache to a log, the way the Redis AOF does:
+// additions and updates are written as SET key value [PXAT milliseconds] commands, removals as DEL key.
+// Replaying the log rebuilds the items precisely, while Rewrite compacts it to the live items.
+// The values are encoded with a codec, a value it cannot encode is persisted as a removal.
+type AOF struct {
+	path  string
+	codec lru.Codec
+	opts  options
+
+	mutex      sync.Mutex
+	file       *os.File
+	writer     *resp.Writer
+	size       int64       // Size of the log, including the buffered writes
+	rewritten  int64       // Size of the log after the last rewrite
+	restoring  atomic.Bool // Whether Restore is running, its events are already in the log
+	compacting sync.Mutex  // Serializes Rewrite and Snapshot, which read the log without holding mutex
+	err        error       // First error writing the log
+	stop       chan struct{}
+	done       chan struct{}
+	closeMutex sync.Mutex // Serializes Close
+	closed     bool
+}
+
+var _ Persister = (*AOF)(nil) // Ensure AOF implements the Persister interface
+
+// OpenAOF opens the log at path, creating it if needed. Call Restore to replay it into a cache,
+// before registering Record as an event listener of the cache, then Close to flush it.
+func OpenAOF(path string, codec lru.Codec, opts ...Option) (*AOF, error) {
+	o := options{fsync: FsyncEverySecond}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	aof := &AOF{
+		path:  path,
+		codec: codec,
+		opts:  o,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if err := aof.open(); err != nil {
+		return nil, err
+	}
+	aof.rewritten = aof.size
+	go aof.run()
+	return aof, nil
+}
+
+// open opens the log for appending.
+func (aof *AOF) open() error {
+	file, err := os.OpenFile(aof.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	aof.file = file
+	aof.writer = resp.NewWriter(file)
+	aof.size = info.Size()
+	return nil
+}
+
+// Record appends the mutation to the log.
+func (aof *AOF) Record(event lru.Event) {
+	var args []string
+	switch event.Type {
+	case lru.EventAdded, lru.EventUpdated:
+		args = aof.setCommand(event.Key, event.Value, event.ExpiresAt)
+	case lru.EventRemoved:
+		args = []string{"DEL", event.Key}
+	default:
+		return
+	}
+
+	if aof.restoring.Load() {
+		return
+	}
+	aof.mutex.Lock()
+	defer aof.mutex.Unlock()
+
+	if aof.closed {
+		return
+	}
+	aof.append(args)
+	if aof.opts.fsync == FsyncAlways {
+		aof.sync()
+	}
+}
+
+// setCommand returns the command adding the item to the log, or removing it if its value cannot be encoded.
+func (aof *AOF) setCommand(key string, value any, expiresAt time.Time) []string {
+	data, err := aof.codec.Encode(value)
+	if err != nil {
+		return []string{"DEL", key} // Don't let an older value come back
+	}
+	if expiresAt.IsZero() {
+		return []string{"SET", key, string(data)}
+	}
+	return []string{"SET", key, string(data), "PXAT", strconv.FormatInt(expiresAt.UnixMilli(), 10)}
+}
+
+// append writes a command to the log. The caller must hold the mutex.
+func (aof *AOF) append(args []string) {
+	aof.writer.WriteCommand(args...)
+	aof.size += commandLength(args)
+}
+
+// sync flushes the buffered writes, and the log to the disk unless the policy is FsyncNever.
+// The caller must hold the mutex.
+func (aof *AOF) sync() {
+	err := aof.writer.Flush()
+	if err == nil && aof.opts.fsync != FsyncNever {
+		err = aof.file.Sync()
+	}
+	if err != nil && aof.err == nil {
+		aof.err = err
+	}
+}
+
+// Sync flushes the log to the disk, and returns the first error met writing it.
+func (aof *AOF) Sync() error {
+	aof.mutex.Lock()
+	defer aof.mutex.Unlock()
+
+	if !aof.closed {
+		aof.sync()
+	}
+	return aof.err
+}
+
+// run flushes the log every second, and rewrites it when it grew too much, until the AOF is closed.
+func (aof *AOF) run() {
+	defer close(aof.done)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			aof.mutex.Lock()
+			aof.sync()
+			rewrite := aof.opts.rewriteSize > 0 && aof.size > aof.opts.rewriteSize && aof.size >= 2*aof.rewritten
+			aof.mutex.Unlock()
+			if rewrite {
+				aof.Rewrite()
+			}
+		case <-aof.stop:
+			return
+		}
+	}
+}
+
+// Restore replays the log into the cache. Items that expired since they were written are skipped.
+// An incomplete command at the end of the log, left by a crash in the middle of a write, is dropped.
+func (aof *AOF) Restore(cache lru.Cache) error {
+	aof.mutex.Lock()
+	aof.sync()
+	aof.mutex.Unlock()
+
+	aof.restoring.Store(true) // Without holding the mutex, as the cache emits events while it is restored
+	valid, err := replay(aof.path, -1, func(args []string) error {
+		return apply(cache, aof.codec, args)
+	})
+	aof.restoring.Store(false)
+	if err != nil {
+		return err
+	}
+
+	aof.mutex.Lock()
+	defer aof.mutex.Unlock()
+	if valid < aof.size { // Truncated by a crash
+		if err := aof.file.Truncate(valid); err != nil {
+			return err
+		}
+		aof.size = valid
+	}
+	return nil
+}
+
+// apply runs a command of a log or a snapshot on the cache, decoding the values with the codec.
+func apply(cache lru.Cache, codec lru.Codec, args []string) error {
+	switch {
+	case len(args) == 2 && args[0] == "DEL":
+		cache.Remove(args[1])
+	case len(args) == 3 && args[0] == "SET":
+		value, err := codec.Decode([]byte(args[2]))
+		if err != nil {
+			return err
+		}
+		cache.Set(args[1], value)
+	case len(args) == 5 && args[0] == "SET" && args[3] == "PXAT":
+		expiresAt, err := strconv.ParseInt(args[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("persist: invalid expiration %q", args[4])
+		}
+		ttl := time.Until(time.UnixMilli(expiresAt))
+		if ttl <= 0 {
+			cache.Remove(args[1])
+			return nil
+		}
+		value, err := codec.Decode([]byte(args[2]))
+		if err != nil {
+			return err
+		}
+		cache.SetWithTTL(args[1], value, ttl)
+	default:
+		return fmt.Errorf("persist: unknown command %q", strings.Join(args, " "))
+	}
+	return nil
+}
+
+// Rewrite compacts the log to a single SET per live item, in the order they were last written.
+// Mutations keep being appended while the live items are computed, the lock is only held
+// to copy the ones appended meanwhile and to swap the files.
+func (aof *AOF) Rewrite() error {
+	aof.compacting.Lock()
+	defer aof.compacting.Unlock()
+
+	aof.mutex.Lock()
+	if aof.closed {
+		aof.mutex.Unlock()
+		return nil
+	}
+	aof.sync()
+	end := aof.size
+	aof.mutex.Unlock()
+
+	items, err := aof.liveItems(end)
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(aof.path), ".aof-rewrite-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name()) // Once renamed, this fails and the rewritten log is kept
+	writer := resp.NewWriter(temp)
+	for _, item := range items {
+		writer.WriteCommand(item...)
+	}
+	if err := writer.Flush(); err != nil {
+		temp.Close()
+		return err
+	}
+
+	aof.mutex.Lock()
+	defer aof.mutex.Unlock()
+	if aof.closed {
+		temp.Close()
+		return nil
+	}
+	aof.sync()
+	err = aof.copyTail(temp, end)
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), aof.path)
+	}
+	if err != nil {
+		return err
+	}
+	aof.file.Close()
+	if err := aof.open(); err != nil {
+		aof.err = err
+		return err
+	}
+	aof.rewritten = aof.size
+	return nil
+}
+
+// Snapshot writes the live items to w, as the SET commands a rewritten log would hold.
+// It reads the log without blocking the mutations, which are not included past the call.
+func (aof *AOF) Snapshot(w io.Writer) error {
+	aof.compacting.Lock()
+	defer aof.compacting.Unlock()
+
+	aof.mutex.Lock()
+	if aof.closed {
+		aof.mutex.Unlock()
+		return errClosed
+	}
+	aof.sync()
+	end := aof.size
+	aof.mutex.Unlock()
+
+	items, err := aof.liveItems(end)
+	if err != nil {
+		return err
+	}
+	writer := resp.NewWriter(w)
+	for _, item := range items {
+		writer.WriteCommand(item...)
+	}
+	return writer.Flush()
+}
+
+// liveItems returns the SET commands of the items alive in the first end bytes of the log, oldest first.
+func (aof *AOF) liveItems(end int64) ([][]string, error) {
+	type item struct {
+		args     []string
+		sequence int
+	}
+	live := make(map[string]item)
+	sequence := 0
+	now := time.Now().UnixMilli()
+	_, err := replay(aof.path, end, func(args []string) error {
+		sequence++
+		if args[0] == "DEL" {
+			delete(live, args[1])
+			return nil
+		}
+		if len(args) == 5 {
+			if expiresAt, err := strconv.ParseInt(args[4], 10, 64); err == nil && expiresAt <= now {
+				delete(live, args[1])
+				return nil
+			}
+		}
+		live[args[1]] = item{args: args, sequence: sequence}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]item, 0, len(live))
+	for _, item := range live {
+		items = append(items, item)
+	}
+	slices.SortFunc(items, func(a, b item) int { return a.sequence - b.sequence })
+	commands := make([][]string, len(items))
+	for i, item := range items {
+		commands[i] = item.args
+	}
+	return commands, nil
+}
+
+// copyTail appends the part of the log after offset to the file.
+func (aof *AOF) copyTail(file *os.File, offset int64) error {
+	source, err := os.Open(aof.path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	if _, err := source.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(file, source)
+	return err
+}
+
+// Close flushes the log to the disk and closes it. It returns the first error met writing the log.
+func (aof *AOF) Close() error {
+	aof.closeMutex.Lock()
+	defer aof.closeMutex.Unlock()
+
+	aof.mutex.Lock()
+	if aof.closed {
+		aof.mutex.Unlock()
+		return aof.err
+	}
+	aof.closed = true
+	close(aof.stop)
+	aof.mutex.Unlock()
+	<-aof.done
+
+	aof.mutex.Lock()
+	defer aof.mutex.Unlock()
+	aof.writer.Flush()
+	err := aof.file.Sync()
+	if closeErr := aof.file.Close(); err == nil {
+		err = closeErr
+	}
+	return errors.Join(aof.err, err)
+}
+
+// replay calls apply with every complete command of the log at path, stopping after limit bytes
+// unless limit is negative. It returns the length of the complete commands.
+func replay(path string, limit int64, apply func(args []string) error) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var source io.Reader = file
+	if limit >= 0 {
+		source = io.LimitReader(file, limit)
+	}
+	return replayFrom(source, apply)
+}
+
+// replayFrom calls apply with every complete command read from source.
+// It returns the length of the complete commands.
+func replayFrom(source io.Reader, apply func(args []string) error) (int64, error) {
+	reader := resp.NewReader(bufio.NewReader(source))
+	var valid int64
+	for {
+		args, err := reader.ReadCommand()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return valid, nil // The end of the log, or an incomplete command
+		}
+		if err != nil {
+			return valid, fmt.Errorf("persist: corrupted log at offset %d: %w", valid, err)
+		}
+		if len(args) < 2 {
+			return valid, fmt.Errorf("persist: invalid command at offset %d", valid)
+		}
+		if err := apply(args); err != nil {
+			return valid, err
+		}
+		valid += commandLength(args)
+	}
+}
+
+// commandLength returns the number of bytes of the command once written by a resp.Writer.
+func commandLength(args []string) int64 {
+	length := int64(len(strconv.Itoa(len(args))) + 3) // *<n>\r\n
+	for _, arg := range args {
+		length += int64(len(strconv.Itoa(len(arg))) + len(arg) + 5) // $<length>\r\n<arg>\r\n
+	}
+	return length
+}
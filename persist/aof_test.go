@@ -0,0 +1,116 @@
+package persist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"caching/lru"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// openCache opens the log at path and a cache recording its mutations to it, after restoring it.
+func openCache(t *testing.T, path string, opts ...Option) (*lru.LRUCache, *AOF) {
+	aof, err := OpenAOF(path, lru.StringCodec{}, opts...)
+	assert.NoError(t, err)
+	cache := lru.NewLRUCache(10, lru.WithoutMetrics(), lru.WithEventListener(aof.Record))
+	assert.NoError(t, aof.Restore(cache))
+	return cache, aof
+}
+
+func TestAOFRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	cache, aof := openCache(t, path, WithFsync(FsyncAlways))
+	cache.Set("key1", "value1")
+	cache.SetWithTTL("key2", "value2", time.Hour)
+	cache.SetWithTTL("key3", "value3", time.Millisecond)
+	cache.Set("key4", "value4")
+	cache.Set("key1", "updated")
+	cache.Remove("key4")
+	assert.NoError(t, aof.Close())
+
+	time.Sleep(5 * time.Millisecond)
+	restored, aof := openCache(t, path)
+	defer aof.Close()
+	assert.Equal(t, 2, restored.Len())
+	value, _ := restored.Get("key1")
+	assert.Equal(t, "updated", value)
+	value, _ = restored.Get("key2")
+	assert.Equal(t, "value2", value)
+	_, found := restored.Get("key3")
+	assert.False(t, found, "expired items are not restored")
+}
+
+func TestAOFRestoreTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	cache, aof := openCache(t, path)
+	cache.Set("key1", "value1")
+	assert.NoError(t, aof.Close())
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	assert.NoError(t, err)
+	file.WriteString("*3\r\n$3\r\nSET\r\n$4\r\nkey2\r\n$6\r\nval") // A crash in the middle of a write
+	file.Close()
+
+	restored, aof := openCache(t, path)
+	value, _ := restored.Get("key1")
+	assert.Equal(t, "value1", value)
+	assert.Equal(t, 1, restored.Len())
+	restored.Set("key3", "value3") // Appended after the complete commands
+	assert.NoError(t, aof.Close())
+
+	restored, aof = openCache(t, path)
+	defer aof.Close()
+	assert.Equal(t, 2, restored.Len())
+}
+
+func TestAOFRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	cache, aof := openCache(t, path)
+	for i := range 100 {
+		cache.Set("key1", "value"+string(rune('a'+i%26)))
+	}
+	cache.Set("key2", "value2")
+	cache.Set("key3", "value3")
+	cache.Remove("key3")
+	assert.NoError(t, aof.Sync())
+	before, _ := os.Stat(path)
+
+	assert.NoError(t, aof.Rewrite())
+	cache.Set("key4", "value4") // Appended to the rewritten log
+	assert.NoError(t, aof.Close())
+	after, _ := os.Stat(path)
+	assert.Less(t, after.Size(), before.Size()/10)
+
+	restored, aof := openCache(t, path)
+	defer aof.Close()
+	assert.Equal(t, 3, restored.Len())
+	value, _ := restored.Get("key1")
+	assert.Equal(t, "valuev", value)
+	_, found := restored.Get("key3")
+	assert.False(t, found)
+}
+
+func TestAOFEncrypted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	codec := lru.NewEncryptedCodec(lru.StringCodec{}, lru.StaticKeys{Keys: map[uint32][]byte{0: make([]byte, 32)}})
+	aof, err := OpenAOF(path, codec, WithFsync(FsyncAlways))
+	assert.NoError(t, err)
+	cache := lru.NewLRUCache(10, lru.WithoutMetrics(), lru.WithEventListener(aof.Record))
+	cache.Set("key1", "plaintext")
+	assert.NoError(t, aof.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "plaintext")
+
+	aof, err = OpenAOF(path, codec)
+	assert.NoError(t, err)
+	defer aof.Close()
+	restored := lru.NewLRUCache(10, lru.WithoutMetrics())
+	assert.NoError(t, aof.Restore(restored))
+	value, _ := restored.Get("key1")
+	assert.Equal(t, "plaintext", value)
+}
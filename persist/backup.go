@@ -0,0 +1,183 @@
+package persist
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"caching/lru"
+)
+
+// snapshotPrefix starts the names of the snapshots, followed by their UTC time so they sort chronologically.
+const snapshotPrefix = "snapshot-"
+
+// snapshotTimeFormat is the time layout of the snapshot names, of fixed width so the names sort like the times.
+const snapshotTimeFormat = "20060102T150405.000000000Z"
+
+// Snapshotter writes the live items of a cache, in the format read by Restore. AOF implements it.
+type Snapshotter interface {
+	Snapshot(w io.Writer) error
+}
+
+// backupOptions holds the optional configuration of a Backup.
+type backupOptions struct {
+	interval  time.Duration   // Interval between the snapshots
+	retention int             // Snapshots kept in the store
+	onError   func(err error) // Receives the errors of the scheduled backups
+}
+
+// BackupOption configures a Backup at construction time.
+type BackupOption func(*backupOptions)
+
+// WithBackupInterval sets the interval between the snapshots. Defaults to 1 hour.
+func WithBackupInterval(interval time.Duration) BackupOption {
+	return func(o *backupOptions) {
+		o.interval = interval
+	}
+}
+
+// WithRetention sets how many snapshots are kept in the store, the older ones are deleted
+// after every backup. Defaults to 24.
+func WithRetention(n int) BackupOption {
+	return func(o *backupOptions) {
+		o.retention = n
+	}
+}
+
+// OnBackupError registers a callback receiving the errors of the scheduled backups,
+// which are otherwise dropped as they have no caller to return them to.
+func OnBackupError(callback func(err error)) BackupOption {
+	return func(o *backupOptions) {
+		o.onError = callback
+	}
+}
+
+// Backup periodically writes snapshots of a cache to a blob store, so the items survive
+// the replacement of the node, and deletes the snapshots past the retention.
+type Backup struct {
+	source Snapshotter
+	store  BlobStore
+	opts   backupOptions
+
+	mutex    sync.Mutex // Serializes the backups
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+var _ io.Closer = (*Backup)(nil) // Ensure Backup can be closed
+
+// NewBackup starts writing a snapshot of the source to the store at every interval, until Close is called.
+func NewBackup(source Snapshotter, store BlobStore, opts ...BackupOption) *Backup {
+	o := backupOptions{
+		interval:  time.Hour,
+		retention: 24,
+		onError:   func(error) {},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	backup := &Backup{
+		source: source,
+		store:  store,
+		opts:   o,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go backup.run()
+	return backup
+}
+
+// run backs up at every interval until the backup is closed.
+func (backup *Backup) run() {
+	defer close(backup.done)
+
+	ticker := time.NewTicker(backup.opts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				select {
+				case <-backup.stop: // Don't let Close wait for a slow upload
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			if err := backup.Now(ctx); err != nil {
+				backup.opts.onError(err)
+			}
+			cancel()
+		case <-backup.stop:
+			return
+		}
+	}
+}
+
+// Now writes a snapshot to the store immediately, then deletes the snapshots past the retention.
+func (backup *Backup) Now(ctx context.Context) error {
+	backup.mutex.Lock()
+	defer backup.mutex.Unlock()
+
+	name := snapshotPrefix + time.Now().UTC().Format(snapshotTimeFormat)
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(backup.source.Snapshot(writer))
+	}()
+	err := backup.store.Put(ctx, name, reader)
+	reader.CloseWithError(errors.New("persist: upload stopped")) // Unblock the snapshot if the upload failed
+	if err != nil {
+		return err
+	}
+	return backup.prune(ctx)
+}
+
+// prune deletes the oldest snapshots past the retention.
+func (backup *Backup) prune(ctx context.Context) error {
+	names, err := backup.store.List(ctx, snapshotPrefix)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for len(names) > max(backup.opts.retention, 1) {
+		errs = append(errs, backup.store.Delete(ctx, names[0]))
+		names = names[1:]
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops the scheduled backups, waiting for the one in progress to be cancelled.
+func (backup *Backup) Close() error {
+	backup.stopOnce.Do(func() { close(backup.stop) })
+	<-backup.done
+	return nil
+}
+
+// Restore loads the latest snapshot of the source store into the cache, decoding the values with the codec.
+// Items that expired since the snapshot was taken are skipped. It returns ErrBlobNotFound if there is no snapshot.
+func Restore(ctx context.Context, source BlobStore, cache lru.Cache, codec lru.Codec) error {
+	names, err := source.List(ctx, snapshotPrefix)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return ErrBlobNotFound
+	}
+	blob, err := source.Get(ctx, names[len(names)-1])
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	_, err = replayFrom(blob, func(args []string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return apply(cache, codec, args)
+	})
+	return err
+}
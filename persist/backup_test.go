@@ -0,0 +1,123 @@
+package persist
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"caching/lru"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupRetentionAndRestore(t *testing.T) {
+	cache, aof := openCache(t, filepath.Join(t.TempDir(), "cache.aof"))
+	defer aof.Close()
+	store := NewDirStore(filepath.Join(t.TempDir(), "backups"))
+	backup := NewBackup(aof, store, WithRetention(2))
+	defer backup.Close()
+	ctx := context.Background()
+
+	for i := range 3 {
+		cache.Set("key1", "value"+string(rune('1'+i)))
+		assert.NoError(t, backup.Now(ctx))
+	}
+	names, err := store.List(ctx, snapshotPrefix)
+	assert.NoError(t, err)
+	assert.Len(t, names, 2, "the oldest snapshot is deleted")
+
+	restored := lru.NewLRUCache(10, lru.WithoutMetrics())
+	assert.NoError(t, Restore(ctx, store, restored, lru.StringCodec{}))
+	value, _ := restored.Get("key1")
+	assert.Equal(t, "value3", value, "the latest snapshot is restored")
+
+	assert.ErrorIs(t, Restore(ctx, NewDirStore(t.TempDir()), restored, lru.StringCodec{}), ErrBlobNotFound)
+}
+
+func TestBackupSchedule(t *testing.T) {
+	cache, aof := openCache(t, filepath.Join(t.TempDir(), "cache.aof"))
+	defer aof.Close()
+	cache.Set("key1", "value1")
+	store := NewDirStore(t.TempDir())
+	backup := NewBackup(aof, store, WithBackupInterval(10*time.Millisecond))
+
+	assert.Eventually(t, func() bool {
+		names, _ := store.List(context.Background(), snapshotPrefix)
+		return len(names) > 0
+	}, time.Second, 5*time.Millisecond)
+	assert.NoError(t, backup.Close())
+}
+
+// memoryS3 is an in-memory S3Client.
+type memoryS3 struct {
+	mutex   sync.Mutex
+	objects map[string][]byte // Content by bucket and key
+}
+
+func (s3 *memoryS3) PutObject(ctx context.Context, bucket, key string, body io.Reader) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	s3.mutex.Lock()
+	defer s3.mutex.Unlock()
+	s3.objects[bucket+"/"+key] = data
+	return nil
+}
+
+func (s3 *memoryS3) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	s3.mutex.Lock()
+	defer s3.mutex.Unlock()
+	data, found := s3.objects[bucket+"/"+key]
+	if !found {
+		return nil, ErrBlobNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s3 *memoryS3) ListObjects(ctx context.Context, bucket, prefix string) ([]string, error) {
+	s3.mutex.Lock()
+	defer s3.mutex.Unlock()
+	var keys []string
+	for name := range s3.objects {
+		if key, found := strings.CutPrefix(name, bucket+"/"); found && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (s3 *memoryS3) DeleteObject(ctx context.Context, bucket, key string) error {
+	s3.mutex.Lock()
+	defer s3.mutex.Unlock()
+	delete(s3.objects, bucket+"/"+key)
+	return nil
+}
+
+func TestS3Store(t *testing.T) {
+	client := &memoryS3{objects: make(map[string][]byte)}
+	store := NewS3Store(client, "bucket", "caches/node-1/")
+	ctx := context.Background()
+
+	assert.NoError(t, store.Put(ctx, "snapshot-2", strings.NewReader("second")))
+	assert.NoError(t, store.Put(ctx, "snapshot-1", strings.NewReader("first")))
+	assert.Contains(t, client.objects, "bucket/caches/node-1/snapshot-1")
+
+	names, err := store.List(ctx, "snapshot-")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"snapshot-1", "snapshot-2"}, names)
+
+	blob, err := store.Get(ctx, "snapshot-2")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(blob)
+	assert.Equal(t, "second", string(data))
+
+	assert.NoError(t, store.Delete(ctx, "snapshot-2"))
+	_, err = store.Get(ctx, "snapshot-2")
+	assert.ErrorIs(t, err, ErrBlobNotFound)
+}
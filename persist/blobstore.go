@@ -0,0 +1,157 @@
+package persist
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrBlobNotFound is returned by BlobStore.Get when there is no blob with the given name.
+var ErrBlobNotFound = errors.New("persist: blob not found")
+
+// BlobStore stores named blobs, e.g. the snapshots written by a Backup.
+type BlobStore interface {
+	// Put stores the content of r under name, replacing the blob with the same name if any.
+	Put(ctx context.Context, name string, r io.Reader) error
+	// Get opens the blob with the given name, or returns ErrBlobNotFound.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the names of the blobs starting with prefix, in lexicographic order.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Delete removes the blob with the given name. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, name string) error
+}
+
+// DirStore is a BlobStore keeping every blob in a file of a local directory.
+type DirStore struct {
+	dir string
+}
+
+var _ BlobStore = (*DirStore)(nil) // Ensure DirStore implements the BlobStore interface
+
+// NewDirStore creates a store in the directory, which is created if needed when the first blob is put.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{dir: dir}
+}
+
+// Put writes the blob to a temporary file, renamed once complete, so a crash never leaves a partial blob.
+func (store *DirStore) Put(ctx context.Context, name string, r io.Reader) error {
+	if err := os.MkdirAll(store.dir, 0o755); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(store.dir, ".put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name()) // Once renamed, this fails and the blob is kept
+
+	_, err = io.Copy(temp, r)
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), filepath.Join(store.dir, name))
+}
+
+// Get opens the file of the blob.
+func (store *DirStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(store.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return file, err
+}
+
+// List returns the names of the files starting with prefix, skipping the blobs being put.
+func (store *DirStore) List(ctx context.Context, prefix string) ([]string, error) {
+	entries, err := os.ReadDir(store.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries { // Sorted by name
+		if entry.Type().IsRegular() && strings.HasPrefix(entry.Name(), prefix) && !strings.HasPrefix(entry.Name(), ".put-") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// Delete removes the file of the blob.
+func (store *DirStore) Delete(ctx context.Context, name string) error {
+	err := os.Remove(filepath.Join(store.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// S3Client is the subset of an S3-compatible object storage API used by S3Store.
+// It is small enough to be implemented on top of the AWS SDK, or of the client of
+// another S3-compatible service such as MinIO or Cloudflare R2.
+type S3Client interface {
+	PutObject(ctx context.Context, bucket, key string, body io.Reader) error
+	// GetObject returns ErrBlobNotFound when the object does not exist.
+	GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+	// ListObjects returns the keys of the objects starting with prefix, in any order.
+	ListObjects(ctx context.Context, bucket, prefix string) ([]string, error)
+	DeleteObject(ctx context.Context, bucket, key string) error
+}
+
+// S3Store is a BlobStore keeping the blobs as objects of a bucket of an S3-compatible object storage.
+type S3Store struct {
+	client S3Client
+	bucket string
+	prefix string // Prepended to the names of the blobs, e.g. "caches/node-1/"
+}
+
+var _ BlobStore = (*S3Store)(nil) // Ensure S3Store implements the BlobStore interface
+
+// NewS3Store creates a store keeping the blobs in the bucket, under the given key prefix.
+func NewS3Store(client S3Client, bucket, prefix string) *S3Store {
+	return &S3Store{client: client, bucket: bucket, prefix: prefix}
+}
+
+// Put uploads the blob.
+func (store *S3Store) Put(ctx context.Context, name string, r io.Reader) error {
+	return store.client.PutObject(ctx, store.bucket, store.prefix+name, r)
+}
+
+// Get downloads the blob.
+func (store *S3Store) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return store.client.GetObject(ctx, store.bucket, store.prefix+name)
+}
+
+// List returns the names of the blobs starting with prefix, without the prefix of the store.
+func (store *S3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	keys, err := store.client.ListObjects(ctx, store.bucket, store.prefix+prefix)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = strings.TrimPrefix(key, store.prefix)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete removes the blob.
+func (store *S3Store) Delete(ctx context.Context, name string) error {
+	return store.client.DeleteObject(ctx, store.bucket, store.prefix+name)
+}
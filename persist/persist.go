@@ -0,0 +1,21 @@
+// Package persist saves the content of a cache to disk, so it can be rebuilt after a restart or a crash.
+// A Persister receives the mutations of the cache as events, registered with lru.WithEventListener,
+// and replays them into a new cache with Restore. A Backup periodically uploads snapshots of the cache
+// to a BlobStore, such as a local directory or an S3-compatible bucket, to be restored on another node.
+package persist
+
+import (
+	"io"
+
+	"caching/lru"
+)
+
+// Persister records the mutations of a cache and rebuilds a cache from them.
+type Persister interface {
+	// Record persists an event of the cache. Hits and misses are ignored.
+	// It is meant to be registered with lru.WithEventListener(persister.Record).
+	Record(event lru.Event)
+	// Restore loads the persisted items into the cache. The events emitted meanwhile are not recorded again.
+	Restore(cache lru.Cache) error
+	io.Closer
+}
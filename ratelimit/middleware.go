@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// KeyFunc extracts the rate limiting key of a request.
+type KeyFunc func(r *http.Request) string
+
+// ClientIP is a KeyFunc limiting requests by the IP address of the client.
+// It uses the remote address of the connection and ignores forwarding headers,
+// which can be forged by clients.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// middlewareOptions holds the optional configuration of Middleware.
+type middlewareOptions struct {
+	rejected http.Handler // Writes the responses to the rejected requests
+}
+
+// MiddlewareOption configures Middleware.
+type MiddlewareOption func(*middlewareOptions)
+
+// WithRejectedHandler sets the handler writing the responses to the rejected requests, after the limit headers are set,
+// e.g. to match the error format of an API. By default, the response is a plain text 429 Too Many Requests.
+func WithRejectedHandler(h http.Handler) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.rejected = h
+	}
+}
+
+// Middleware rejects the requests exceeding the limit with 429 Too Many Requests.
+// Every response includes the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers,
+// and rejected responses include Retry-After, all expressed in whole seconds.
+func Middleware(limiter Limiter, key KeyFunc, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	o := middlewareOptions{rejected: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "too many requests", http.StatusTooManyRequests)
+	})}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result := limiter.Allow(key(r))
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+				o.rejected.ServeHTTP(w, r)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Package ratelimit implements rate limiters storing their state in a cache from the lru package.
+// Counters and buckets are regular cache entries with a TTL, so idle clients are forgotten
+// automatically and the memory used by the limiter is bounded by the cache capacity.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"caching/lru"
+)
+
+// Store is the cache holding the limiter state, e.g. an lru.SafeLRUCache.
+// It must be thread-safe if the limiter is used concurrently.
+type Store interface {
+	Get(key string) (any, bool)
+	SetWithTTL(key string, value any, ttl time.Duration) lru.SetResult
+	lru.Incrementer
+}
+
+// Result describes the outcome of a rate limit check.
+type Result struct {
+	Allowed    bool          // Whether the request may proceed
+	Limit      int           // Maximum number of requests in a window
+	Remaining  int           // Requests left in the current window
+	RetryAfter time.Duration // How long to wait before retrying, when not allowed
+	Reset      time.Duration // Time until the limit fully resets
+}
+
+// Limiter decides whether a request identified by a key may proceed.
+type Limiter interface {
+	Allow(key string) Result
+}
+
+// options holds the optional configuration of the limiters.
+type options struct {
+	clock lru.Clock // Source of the current time, should match the clock of the store
+}
+
+// Option configures a limiter at construction time.
+type Option func(*options)
+
+func newOptions(opts ...Option) options {
+	o := options{clock: systemClock{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithClock sets the clock used by the limiter. It should be the same clock used by the store.
+func WithClock(clock lru.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SlidingWindow allows limit requests per window, approximating a sliding window
+// by weighting the counter of the previous fixed window by how much of it still overlaps.
+// Counters are kept in the store with Increment, so each key costs two cache entries at most.
+type SlidingWindow struct {
+	store  Store
+	limit  int           // Maximum number of requests per window
+	window time.Duration // Length of a window
+	clock  lru.Clock
+}
+
+var _ Limiter = (*SlidingWindow)(nil) // Ensure SlidingWindow implements the Limiter interface
+
+func NewSlidingWindow(store Store, limit int, window time.Duration, opts ...Option) *SlidingWindow {
+	o := newOptions(opts...)
+	return &SlidingWindow{
+		store:  store,
+		limit:  limit,
+		window: window,
+		clock:  o.clock,
+	}
+}
+
+// Allow counts a request for the key and reports whether it is within the limit.
+// Rejected requests are counted too, so clients hammering the limiter stay limited.
+func (limiter *SlidingWindow) Allow(key string) Result {
+	now := limiter.clock.Now()
+	start := now.Truncate(limiter.window)
+	elapsed := now.Sub(start)
+
+	// Counters live for two windows, so they can be used as the previous window
+	count, err := limiter.store.Increment(windowKey(key, start), 1, 2*limiter.window)
+	if err != nil {
+		return Result{Allowed: true, Limit: limiter.limit, Remaining: limiter.limit} // Fail open, the store is misused
+	}
+
+	previous := int64(0)
+	if value, found := limiter.store.Get(windowKey(key, start.Add(-limiter.window))); found {
+		previous, _ = value.(int64)
+	}
+
+	overlap := 1 - float64(elapsed)/float64(limiter.window)
+	estimate := float64(previous)*overlap + float64(count)
+
+	result := Result{
+		Allowed:   estimate <= float64(limiter.limit),
+		Limit:     limiter.limit,
+		Remaining: max(0, limiter.limit-int(math.Ceil(estimate))),
+		Reset:     limiter.window - elapsed,
+	}
+	if !result.Allowed {
+		result.RetryAfter = result.Reset
+	}
+	return result
+}
+
+// windowKey returns the store key of the counter of a window.
+func windowKey(key string, start time.Time) string {
+	return fmt.Sprintf("ratelimit:sw:%s:%d", key, start.UnixNano())
+}
+
+// bucket is the state of a token bucket as stored in the cache.
+type bucket struct {
+	tokens float64   // Tokens available at the time of the last update
+	last   time.Time // Time of the last update
+}
+
+// TokenBucket allows bursts of up to capacity requests, refilling at rate tokens per second.
+// Buckets are stored in the store with a TTL equal to the time to refill completely,
+// after which a missing bucket is equivalent to a full one.
+type TokenBucket struct {
+	store    Store
+	capacity int     // Maximum number of tokens in a bucket
+	rate     float64 // Tokens added per second
+	clock    lru.Clock
+	mutex    sync.Mutex // Makes the read-modify-write of a bucket atomic
+}
+
+var _ Limiter = (*TokenBucket)(nil) // Ensure TokenBucket implements the Limiter interface
+
+func NewTokenBucket(store Store, capacity int, rate float64, opts ...Option) *TokenBucket {
+	o := newOptions(opts...)
+	return &TokenBucket{
+		store:    store,
+		capacity: capacity,
+		rate:     rate,
+		clock:    o.clock,
+	}
+}
+
+// Allow takes a token from the bucket of the key, reporting whether one was available.
+func (limiter *TokenBucket) Allow(key string) Result {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	now := limiter.clock.Now()
+	storeKey := "ratelimit:tb:" + key
+
+	current := bucket{tokens: float64(limiter.capacity), last: now}
+	if value, found := limiter.store.Get(storeKey); found {
+		if stored, ok := value.(bucket); ok {
+			refilled := stored.tokens + now.Sub(stored.last).Seconds()*limiter.rate
+			current.tokens = math.Min(float64(limiter.capacity), refilled)
+		}
+	}
+
+	result := Result{Limit: limiter.capacity}
+	if current.tokens >= 1 {
+		current.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = limiter.secondsFor(1 - current.tokens)
+	}
+	result.Remaining = int(current.tokens)
+	result.Reset = limiter.secondsFor(float64(limiter.capacity) - current.tokens)
+
+	limiter.store.SetWithTTL(storeKey, current, max(result.Reset, time.Millisecond))
+	return result
+}
+
+// secondsFor returns how long the bucket takes to gain the given number of tokens.
+func (limiter *TokenBucket) secondsFor(tokens float64) time.Duration {
+	return time.Duration(tokens / limiter.rate * float64(time.Second))
+}
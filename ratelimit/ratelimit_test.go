@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"caching/lru"
+)
+
+func TestSlidingWindow(t *testing.T) {
+	clock := lru.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := lru.NewSafeLRUCache(10, lru.WithClock(clock))
+	limiter := NewSlidingWindow(store, 2, time.Minute, WithClock(clock))
+
+	assert.True(t, limiter.Allow("client").Allowed)
+	result := limiter.Allow("client")
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	result = limiter.Allow("client")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Minute, result.RetryAfter)
+	assert.True(t, limiter.Allow("other").Allowed, "Limits are tracked per key")
+
+	// Halfway through the next window, half of the previous window still counts
+	clock.Advance(90 * time.Second)
+	assert.False(t, limiter.Allow("client").Allowed) // 3 * 0.5 + 1 = 2.5
+	clock.Advance(30 * time.Second)
+	assert.True(t, limiter.Allow("client").Allowed)
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := lru.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := lru.NewSafeLRUCache(10, lru.WithClock(clock))
+	limiter := NewTokenBucket(store, 2, 1, WithClock(clock))
+
+	assert.True(t, limiter.Allow("client").Allowed)
+	assert.True(t, limiter.Allow("client").Allowed)
+	result := limiter.Allow("client")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+
+	clock.Advance(time.Second)
+	assert.True(t, limiter.Allow("client").Allowed)
+	assert.False(t, limiter.Allow("client").Allowed)
+
+	clock.Advance(time.Hour) // The bucket expired from the store, so it is full again
+	result = limiter.Allow("client")
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+}
+
+func TestMiddleware(t *testing.T) {
+	store := lru.NewSafeLRUCache(10)
+	limiter := NewTokenBucket(store, 1, 0.5)
+	handler := Middleware(limiter, ClientIP)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	assert.Equal(t, "1", response.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", response.Header().Get("X-RateLimit-Remaining"))
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.Equal(t, "2", response.Header().Get("Retry-After"))
+}
+
+func TestMiddlewareRejectedHandler(t *testing.T) {
+	limiter := NewTokenBucket(lru.NewSafeLRUCache(10), 1, 1)
+	rejected := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	handler := Middleware(limiter, ClientIP, WithRejectedHandler(rejected))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+	assert.Equal(t, "1", response.Header().Get("Retry-After"), "Expected the limit headers to be set before the rejected handler")
+}
+
+func TestClientIP(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	request.Header.Set("X-Forwarded-For", "198.51.100.1")
+	assert.Equal(t, "192.0.2.1", ClientIP(request))
+}
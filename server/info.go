@@ -0,0 +1,147 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/metrics"
+	"slices"
+	"strings"
+	"time"
+
+	"caching/lru"
+)
+
+// infoSections lists the sections of INFO in the order they are written.
+var infoSections = []string{"server", "clients", "memory", "stats", "keyspace"}
+
+// heapObjectsMetric is the runtime metric estimating the memory used: the bytes of the live and unswept heap objects.
+// Unlike runtime.ReadMemStats, reading it does not stop the world.
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// info runs INFO [section ...], replying the statistics of the server and of its cache in the format of Redis,
+// "field:value" lines grouped under "# Section" headers, so the tools parsing the INFO of Redis work unchanged.
+// Without a section, or with "all", "default" or "everything", every section is written.
+func (server *Server) info(session *session, args []string) {
+	sections := infoSections
+	if len(args) > 0 {
+		sections = nil
+		for _, arg := range args {
+			section := strings.ToLower(arg)
+			if section == "all" || section == "default" || section == "everything" {
+				sections = infoSections
+				break
+			}
+			if slices.Contains(infoSections, section) && !slices.Contains(sections, section) {
+				sections = append(sections, section)
+			}
+		}
+	}
+
+	var builder strings.Builder
+	for i, section := range sections {
+		if i > 0 {
+			builder.WriteString("\r\n")
+		}
+		fmt.Fprintf(&builder, "# %s\r\n", strings.ToUpper(section[:1])+section[1:])
+		for _, field := range server.infoSection(section) {
+			fmt.Fprintf(&builder, "%s:%s\r\n", field[0], field[1])
+		}
+	}
+	session.writer.WriteBulkString(builder.String())
+}
+
+// infoSection returns the fields of an INFO section, as name and value pairs.
+func (server *Server) infoSection(section string) [][2]string {
+	switch section {
+	case "server":
+		uptime := time.Since(server.started)
+		return [][2]string{
+			{"go_version", runtime.Version()},
+			{"process_id", fmt.Sprint(os.Getpid())},
+			{"uptime_in_seconds", fmt.Sprint(int64(uptime.Seconds()))},
+			{"uptime_in_days", fmt.Sprint(int64(uptime.Hours() / 24))},
+		}
+	case "clients":
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		return [][2]string{{"connected_clients", fmt.Sprint(len(server.sessions))}}
+	case "memory":
+		sample := []metrics.Sample{{Name: heapObjectsMetric}}
+		metrics.Read(sample)
+		var used uint64
+		if sample[0].Value.Kind() == metrics.KindUint64 {
+			used = sample[0].Value.Uint64()
+		}
+		return [][2]string{{"used_memory", fmt.Sprint(used)}, {"used_memory_human", humanBytes(used)}}
+	case "stats":
+		stats := server.stats()
+		server.mutex.Lock()
+		connections := server.lastID
+		server.mutex.Unlock()
+		return [][2]string{
+			{"total_connections_received", fmt.Sprint(connections)},
+			{"total_commands_processed", fmt.Sprint(server.commands.Load())},
+			{"keyspace_hits", fmt.Sprint(stats.Hits)},
+			{"keyspace_misses", fmt.Sprint(stats.Misses)},
+			{"keyspace_hit_ratio", fmt.Sprintf("%.4f", stats.HitRatio())},
+			{"evicted_keys", fmt.Sprint(stats.Evictions)},
+			{"expired_keys", fmt.Sprint(stats.Expirations)},
+			{"expired_keys_per_sec", fmt.Sprintf("%.2f", stats.ExpirationRate)},
+			{"upcoming_expired_keys", fmt.Sprint(stats.UpcomingExpirations)},
+			{"maxkeys", fmt.Sprint(stats.Capacity)},
+			{"loading_keys", fmt.Sprint(stats.Loading)},
+			{"load_waiters", fmt.Sprint(stats.LoadWaiters)},
+		}
+	case "keyspace":
+		fields := [][2]string{{"db0", fmt.Sprintf("keys=%d", server.cache.Len())}}
+		if server.namespaceOf == nil {
+			return fields
+		}
+		for namespace, keys := range server.namespaceCounts() {
+			fields = append(fields, [2]string{"namespace_" + namespace, fmt.Sprintf("keys=%d", keys)})
+		}
+		slices.SortFunc(fields[1:], func(a, b [2]string) int { return strings.Compare(a[0], b[0]) })
+		return fields
+	}
+	return nil
+}
+
+// stats returns the Stats of the cache, or only its length and capacity if it does not implement lru.StatsReporter,
+// along with the loads in progress with WithLoader.
+func (server *Server) stats() lru.Stats {
+	if server.loading != nil {
+		return server.loading.Stats()
+	}
+	if reporter, ok := server.cache.(lru.StatsReporter); ok {
+		return reporter.Stats()
+	}
+	return lru.Stats{Len: server.cache.Len(), Capacity: server.cache.Capacity()}
+}
+
+// namespaceCounts returns the number of live keys of every namespace, visiting the items of the cache,
+// or nothing if the cache does not implement lru.Iterator.
+func (server *Server) namespaceCounts() map[string]int {
+	counts := make(map[string]int)
+	if iterator, ok := server.cache.(lru.Iterator); ok {
+		iterator.Range(func(key string, _ any) bool {
+			counts[server.namespaceOf(key)]++
+			return true
+		})
+	}
+	return counts
+}
+
+// humanBytes formats a number of bytes like Redis does, e.g. 1.50M.
+func humanBytes(bytes uint64) string {
+	const units = "KMGTPE"
+	if bytes < 1024 {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	value, unit := float64(bytes)/1024, 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.2f%c", value, units[unit])
+}
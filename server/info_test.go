@@ -0,0 +1,59 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"caching/lru"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// parseInfo returns the fields of an INFO reply by name, and the section headers in order.
+func parseInfo(reply string) (fields map[string]string, sections []string) {
+	fields = make(map[string]string)
+	for _, line := range strings.Split(reply, "\r\n") {
+		if header, ok := strings.CutPrefix(line, "# "); ok {
+			sections = append(sections, header)
+		} else if name, value, ok := strings.Cut(line, ":"); ok {
+			fields[name] = value
+		}
+	}
+	return fields, sections
+}
+
+func TestServerInfo(t *testing.T) {
+	_, addr := startServer(t, lru.NewSafeLRUCache(2), WithNamespaces(func(key string) string {
+		namespace, _, _ := strings.Cut(key, ":")
+		return namespace
+	}))
+	client := dial(t, addr)
+	client.do("SET", "user:1", "a")
+	client.do("SET", "user:2", "b")
+	client.do("SET", "order:1", "c") // Evicts user:1
+	client.do("GET", "user:2")
+	client.do("GET", "user:1")
+
+	fields, sections := parseInfo(client.do("INFO").Str)
+	assert.Equal(t, []string{"Server", "Clients", "Memory", "Stats", "Keyspace"}, sections)
+	assert.Equal(t, "1", fields["connected_clients"])
+	assert.Equal(t, "1", fields["keyspace_hits"])
+	assert.Equal(t, "1", fields["keyspace_misses"])
+	assert.Equal(t, "0.5000", fields["keyspace_hit_ratio"])
+	assert.Equal(t, "1", fields["evicted_keys"])
+	assert.Equal(t, "keys=2", fields["db0"])
+	assert.Equal(t, "keys=1", fields["namespace_user"])
+	assert.Equal(t, "keys=1", fields["namespace_order"])
+	assert.NotEmpty(t, fields["used_memory"])
+	assert.Equal(t, "6", fields["total_commands_processed"]) // INFO included
+
+	fields, sections = parseInfo(client.do("INFO", "STATS", "keyspace").Str)
+	assert.Equal(t, []string{"Stats", "Keyspace"}, sections)
+	assert.NotContains(t, fields, "uptime_in_seconds")
+}
+
+func TestHumanBytes(t *testing.T) {
+	assert.Equal(t, "512B", humanBytes(512))
+	assert.Equal(t, "1.50K", humanBytes(1536))
+	assert.Equal(t, "2.00M", humanBytes(2<<20))
+}
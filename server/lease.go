@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Replies of LGET in the token field of a hit, and of a miss whose lease is held by another client.
+const (
+	leaseHit  = 0
+	leaseHeld = -1
+)
+
+// leases hands out memcached-style leases to prevent dogpiles: the first client missing a key gets
+// a token allowing it to fill the key, while the other clients are told to wait, or are given the
+// value deleted last if any. A write or deletion of the key revokes the lease, so a client holding
+// an outdated value cannot overwrite a newer one.
+type leases struct {
+	mutex     sync.Mutex
+	timeout   time.Duration     // How long a lease, or a deleted value, is kept
+	entries   map[string]*lease // Leases and deleted values by key
+	lastToken int64             // Token of the last lease
+	sweepAt   int               // Number of entries above which the expired ones are removed
+}
+
+// lease is the lease of a key, or the value it had before being deleted.
+type lease struct {
+	token    int64     // Token of the client allowed to fill the key, zero if no lease was handed out
+	stale    string    // Value deleted last, served while the key is being filled
+	hasStale bool      // Whether there is a deleted value
+	expires  time.Time // When the lease and the deleted value are dropped
+}
+
+func newLeases(timeout time.Duration) *leases {
+	return &leases{timeout: timeout, entries: make(map[string]*lease)}
+}
+
+// acquire hands out a lease for a missing key. It returns the token of the new lease,
+// or leaseHeld if another client holds one, along with the value deleted last if any.
+// The caller must hold the mutex, and check the key is still missing under it.
+func (leases *leases) acquire(key string) (token int64, stale string, hasStale bool) {
+	now := time.Now()
+	entry := leases.get(key, now)
+	if entry != nil && entry.token != 0 {
+		return leaseHeld, entry.stale, entry.hasStale
+	}
+	leases.lastToken++
+	if entry == nil {
+		leases.put(key, &lease{token: leases.lastToken, expires: now.Add(leases.timeout)}, now)
+	} else {
+		entry.token = leases.lastToken
+		entry.expires = now.Add(leases.timeout)
+	}
+	return leases.lastToken, "", false
+}
+
+// fill releases the lease of the key, and returns whether the token is the one of the current lease.
+// The caller must hold the mutex until the key is set, so no lease is handed out meanwhile.
+func (leases *leases) fill(key string, token int64) bool {
+	entry := leases.get(key, time.Now())
+	if entry == nil || entry.token != token {
+		return false
+	}
+	delete(leases.entries, key)
+	return true
+}
+
+// revoke drops the lease of a key written without token.
+// The caller must hold the mutex while writing the key, so no LSET lands between the write and the revocation.
+func (leases *leases) revoke(key string) {
+	delete(leases.entries, key)
+}
+
+// retain drops the lease of a deleted key and keeps its value, to be served while the key is filled again.
+// The caller must hold the mutex while removing the key, like for revoke.
+func (leases *leases) retain(key string, value string) {
+	now := time.Now()
+	leases.put(key, &lease{stale: value, hasStale: true, expires: now.Add(leases.timeout)}, now)
+}
+
+// get returns the entry of the key, or nil if there is none or it expired.
+func (leases *leases) get(key string, now time.Time) *lease {
+	entry := leases.entries[key]
+	if entry != nil && now.After(entry.expires) {
+		delete(leases.entries, key)
+		return nil
+	}
+	return entry
+}
+
+// put stores the entry of the key. The expired entries are removed whenever
+// the number of entries doubled since the last time, so keys never read again don't pile up.
+func (leases *leases) put(key string, entry *lease, now time.Time) {
+	leases.entries[key] = entry
+	if len(leases.entries) <= leases.sweepAt {
+		return
+	}
+	for key, entry := range leases.entries {
+		if now.After(entry.expires) {
+			delete(leases.entries, key)
+		}
+	}
+	leases.sweepAt = 2*len(leases.entries) + 64
+}
+
+// leaseGet runs LGET key. The reply is an array of the value and a token:
+//   - [value, 0] when the key was found;
+//   - [nil, token] when the client must fill the key with LSET key value token;
+//   - [nil or stale value, -1] when another client is filling the key, the caller should retry shortly
+//     or use the value deleted last.
+func (server *Server) leaseGet(session *session, args []string) {
+	writer := session.writer
+	if len(args) != 2 {
+		writeArityError(writer, "LGET")
+		return
+	}
+	if session.redirect != 0 { // Before the read, so a write racing with it is invalidated
+		server.tracking.track(args[1], session.redirect)
+	}
+	value, found := server.cache.Get(args[1])
+
+	var token int64
+	var stale string
+	var hasStale bool
+	if !found {
+		server.leases.mutex.Lock()
+		value, found = server.cache.Get(args[1]) // The key may have been filled since
+		if !found {
+			token, stale, hasStale = server.leases.acquire(args[1])
+		}
+		server.leases.mutex.Unlock()
+	}
+
+	writer.WriteArrayHeader(2)
+	if found {
+		writer.WriteBulkString(format(value))
+		writer.WriteInteger(leaseHit)
+		return
+	}
+	if hasStale {
+		writer.WriteBulkString(stale)
+	} else {
+		writer.WriteNull()
+	}
+	writer.WriteInteger(token)
+}
+
+// leaseSet runs LSET key value token [EX seconds | PX milliseconds], replying OK if the value was set,
+// or nil if the lease expired or was revoked by a write of the key since it was handed out.
+func (server *Server) leaseSet(ctx context.Context, session *session, args []string) {
+	writer := session.writer
+	if len(args) != 4 && len(args) != 6 {
+		writeArityError(writer, "LSET")
+		return
+	}
+	token, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		writer.WriteError("ERR invalid lease token")
+		return
+	}
+	ttl, ok := parseExpiration(writer, "lset", args[4:])
+	if !ok {
+		return
+	}
+	var write *pendingWrite
+	server.leases.mutex.Lock()
+	filled := server.leases.fill(args[1], token)
+	if filled && ttl > 0 {
+		server.cache.SetWithTTL(args[1], args[2], ttl)
+	} else if filled {
+		server.cache.Set(args[1], args[2])
+	}
+	if filled {
+		write = server.enqueue(ctx, session, append([]string{"SET", args[1], args[2]}, args[4:]...))
+	}
+	server.leases.mutex.Unlock()
+	if !filled {
+		writer.WriteNull()
+		return
+	}
+
+	server.invalidate(args[1:2])
+	if server.replicate(ctx, session, "LSET", write) {
+		writer.WriteSimpleString("OK")
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"caching/internal/resp"
+	"caching/lru"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLease(t *testing.T) {
+	_, addr := startServer(t, lru.NewSafeLRUCache(10))
+	first, second := dial(t, addr), dial(t, addr)
+
+	reply := first.do("LGET", "key1")
+	assert.True(t, reply.Array[0].Null)
+	token := reply.Array[1].Int
+	assert.Positive(t, token, "the first miss gets the lease")
+
+	reply = second.do("LGET", "key1")
+	assert.True(t, reply.Array[0].Null)
+	assert.Equal(t, int64(leaseHeld), reply.Array[1].Int, "the other clients wait")
+	assert.True(t, second.do("LSET", "key1", "value2", "42").Null, "a set requires the token")
+
+	assert.Equal(t, "OK", first.do("LSET", "key1", "value1", strconv.FormatInt(token, 10), "EX", "10").Str)
+	assert.True(t, first.do("LSET", "key1", "value1", strconv.FormatInt(token, 10)).Null, "a lease is used once")
+	reply = second.do("LGET", "key1")
+	assert.Equal(t, resp.Value{Kind: resp.BulkString, Str: "value1"}, reply.Array[0])
+	assert.Equal(t, int64(leaseHit), reply.Array[1].Int)
+}
+
+func TestLeaseServesDeletedValue(t *testing.T) {
+	_, addr := startServer(t, lru.NewSafeLRUCache(10))
+	first, second := dial(t, addr), dial(t, addr)
+
+	assert.Equal(t, "OK", first.do("SET", "key1", "value1").Str)
+	assert.Equal(t, int64(1), first.do("DEL", "key1").Int)
+	reply := first.do("LGET", "key1")
+	assert.True(t, reply.Array[0].Null)
+	token := reply.Array[1].Int
+	assert.Positive(t, token)
+
+	reply = second.do("LGET", "key1")
+	assert.Equal(t, "value1", reply.Array[0].Str, "the deleted value is served while the key is filled")
+	assert.Equal(t, int64(leaseHeld), reply.Array[1].Int)
+}
+
+func TestLeaseRevokedByWrites(t *testing.T) {
+	_, addr := startServer(t, lru.NewSafeLRUCache(10), WithLeaseTimeout(50*time.Millisecond))
+	client := dial(t, addr)
+
+	token := client.do("LGET", "key1").Array[1].Int
+	assert.Equal(t, "OK", client.do("SET", "key1", "value2").Str)
+	assert.True(t, client.do("LSET", "key1", "value1", strconv.FormatInt(token, 10)).Null, "an outdated value does not override a write")
+
+	assert.Equal(t, int64(1), client.do("DEL", "key1").Int)
+	token = client.do("LGET", "key1").Array[1].Int
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, client.do("LSET", "key1", "value1", strconv.FormatInt(token, 10)).Null, "the lease expired")
+	assert.Positive(t, client.do("LGET", "key1").Array[1].Int, "another client can get the lease")
+}
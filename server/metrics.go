@@ -0,0 +1,19 @@
+package server
+
+import (
+	"caching/lru"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// timeouts counts the commands answered with a TIMEOUT error, see WithCommandTimeout.
+var timeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: lru.DefaultMetricsNamespace,
+	Subsystem: "server",
+	Name:      "timeouts_total",
+	Help:      "Total number of commands that ran past their deadline, by command",
+}, []string{"command"})
+
+func init() {
+	prometheus.MustRegister(timeouts)
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"caching/internal/resp"
+	"caching/lru"
+)
+
+// Channels of the keyspace notifications, like the ones of Redis for the database 0:
+// the events of a key are published to keyspaceChannel+key, and the keys of an event to keyeventChannel+event.
+const (
+	keyspaceChannel = "__keyspace@0__:"
+	keyeventChannel = "__keyevent@0__:"
+)
+
+// Events published by the keyspace notifications, named after the ones of Redis.
+const (
+	notifySet     = "set"     // An item was added or updated
+	notifyDel     = "del"     // An item was removed, e.g. by DEL
+	notifyExpired = "expired" // An item was removed because it expired
+	notifyEvicted = "evicted" // An item was evicted to make room for another one
+)
+
+// notifications tracks the connections subscribed to the keyspace notifications, by channel and by channel pattern.
+type notifications struct {
+	mutex       sync.Mutex
+	channels    map[string]map[*session]struct{} // Subscribers by channel
+	patterns    map[string]map[*session]struct{} // Subscribers by channel pattern
+	subscribers atomic.Int64                     // Number of subscriptions, so the events are dropped fast without any
+}
+
+// Notify publishes a keyspace notification for the event, to the connections subscribed to its channels
+// with SUBSCRIBE or PSUBSCRIBE: "set" for the additions and updates, and "del", "expired" or "evicted"
+// for the removals. It is an lru.EventListener, to register with lru.WithEventListener on the served cache.
+// The notifications are queued, so it does not wait for the subscribers.
+func (server *Server) Notify(event lru.Event) {
+	if server.notifications.subscribers.Load() == 0 {
+		return
+	}
+	var name string
+	switch {
+	case event.Type == lru.EventAdded || event.Type == lru.EventUpdated:
+		name = notifySet
+	case event.Type == lru.EventRemoved && event.Reason == "expired":
+		name = notifyExpired
+	case event.Type == lru.EventRemoved && event.Reason == "evicted":
+		name = notifyEvicted
+	case event.Type == lru.EventRemoved:
+		name = notifyDel
+	default:
+		return
+	}
+	server.publish(keyspaceChannel+event.Key, name)
+	server.publish(keyeventChannel+name, event.Key)
+}
+
+// publish queues the message for the connections subscribed to the channel, or to a pattern matching it.
+func (server *Server) publish(channel string, message string) {
+	server.notifications.mutex.Lock()
+	defer server.notifications.mutex.Unlock()
+
+	for target := range server.notifications.channels[channel] {
+		server.push(target, pushMessage{fields: []string{"message", channel, message}})
+	}
+	for pattern, subscribers := range server.notifications.patterns {
+		if !lru.MatchPattern(pattern, channel) {
+			continue
+		}
+		for target := range subscribers {
+			server.push(target, pushMessage{fields: []string{"pmessage", pattern, channel, message}})
+		}
+	}
+}
+
+// subscribe runs SUBSCRIBE channel [channel ...] and PSUBSCRIBE pattern [pattern ...], replying
+// like Redis with the number of subscriptions of the connection after each one.
+func (server *Server) subscribe(session *session, name string, args []string) {
+	if len(args) == 0 {
+		writeArityError(session.writer, name)
+		return
+	}
+	pattern := name == "PSUBSCRIBE"
+	notifications := &server.notifications
+	counts := make([]int, len(args))
+	notifications.mutex.Lock()
+	for i, channel := range args {
+		notifications.add(session, pattern, channel)
+		counts[i] = len(session.channels) + len(session.patterns)
+	}
+	notifications.mutex.Unlock()
+
+	// Written without the mutex, so a client slow to read does not hold up the notifications
+	for i, channel := range args {
+		writeSubscription(session.writer, strings.ToLower(name), channel, counts[i])
+	}
+}
+
+// unsubscribe runs UNSUBSCRIBE [channel ...] and PUNSUBSCRIBE [pattern ...], unsubscribing from every
+// channel, or pattern, without arguments.
+func (server *Server) unsubscribe(session *session, name string, args []string) {
+	pattern := name == "PUNSUBSCRIBE"
+	notifications := &server.notifications
+	notifications.mutex.Lock()
+	_, own := notifications.subscriptions(session, pattern)
+	if len(args) == 0 {
+		for channel := range own {
+			args = append(args, channel)
+		}
+	}
+	counts := make([]int, len(args))
+	for i, channel := range args {
+		notifications.remove(session, pattern, channel)
+		counts[i] = len(session.channels) + len(session.patterns)
+	}
+	remaining := len(session.channels) + len(session.patterns)
+	notifications.mutex.Unlock()
+
+	if len(args) == 0 { // Redis replies with a null channel when there was no subscription
+		session.writer.WriteArrayHeader(3)
+		session.writer.WriteBulkString(strings.ToLower(name))
+		session.writer.WriteNull()
+		session.writer.WriteInteger(int64(remaining))
+		return
+	}
+	for i, channel := range args {
+		writeSubscription(session.writer, strings.ToLower(name), channel, counts[i])
+	}
+}
+
+// unsubscribeAll removes every subscription of a closed connection.
+func (server *Server) unsubscribeAll(session *session) {
+	notifications := &server.notifications
+	notifications.mutex.Lock()
+	defer notifications.mutex.Unlock()
+
+	for channel := range session.channels {
+		notifications.remove(session, false, channel)
+	}
+	for pattern := range session.patterns {
+		notifications.remove(session, true, pattern)
+	}
+}
+
+// subscriptions returns the subscribers by channel, or by pattern, and the ones of the connection,
+// creating them if needed. The caller must hold the mutex.
+func (notifications *notifications) subscriptions(session *session, pattern bool) (subscribers map[string]map[*session]struct{}, own map[string]struct{}) {
+	if pattern {
+		if session.patterns == nil {
+			session.patterns = make(map[string]struct{})
+		}
+		return notifications.patterns, session.patterns
+	}
+	if session.channels == nil {
+		session.channels = make(map[string]struct{})
+	}
+	return notifications.channels, session.channels
+}
+
+// add subscribes the connection to a channel, or a pattern. The caller must hold the mutex.
+func (notifications *notifications) add(target *session, pattern bool, channel string) {
+	subscribers, own := notifications.subscriptions(target, pattern)
+	if _, found := own[channel]; found {
+		return
+	}
+	own[channel] = struct{}{}
+	if subscribers[channel] == nil {
+		subscribers[channel] = make(map[*session]struct{})
+	}
+	subscribers[channel][target] = struct{}{}
+	target.subscriptions.Add(1)
+	notifications.subscribers.Add(1)
+}
+
+// remove unsubscribes the connection from a channel, or a pattern. The caller must hold the mutex.
+func (notifications *notifications) remove(session *session, pattern bool, channel string) {
+	subscribers, own := notifications.subscriptions(session, pattern)
+	if _, found := own[channel]; !found {
+		return
+	}
+	delete(own, channel)
+	delete(subscribers[channel], session)
+	if len(subscribers[channel]) == 0 {
+		delete(subscribers, channel)
+	}
+	session.subscriptions.Add(-1)
+	notifications.subscribers.Add(-1)
+}
+
+// subscribed reports whether the connection is subscribed to a channel or a pattern,
+// in which case it only accepts the pub/sub commands, PING and QUIT.
+func (session *session) subscribed() bool {
+	return session.subscriptions.Load() > 0
+}
+
+// writeSubscription writes the reply of a subscription change: its kind, the channel, and the number
+// of subscriptions of the connection afterwards.
+func writeSubscription(writer *resp.Writer, kind string, channel string, count int) {
+	writer.WriteArrayHeader(3)
+	writer.WriteBulkString(kind)
+	writer.WriteBulkString(channel)
+	writer.WriteInteger(int64(count))
+}
+
+// writeSubscribedError replies to a command other than the pub/sub ones, PING and QUIT on a subscribed connection.
+func writeSubscribedError(session *session, name string) {
+	session.writer.WriteError(fmt.Sprintf("ERR Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context", strings.ToLower(name)))
+}
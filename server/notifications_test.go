@@ -0,0 +1,58 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"caching/internal/resp"
+	"caching/lru"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fields returns the bulk strings of an array reply, joined by spaces.
+func fields(value resp.Value) string {
+	var fields []string
+	for _, element := range value.Array {
+		if element.Kind == resp.Integer {
+			fields = append(fields, strings.Repeat("#", int(element.Int)))
+		} else {
+			fields = append(fields, element.Str)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+func TestKeyspaceNotifications(t *testing.T) {
+	var server *Server
+	cache := lru.NewSafeLRUCache(1, lru.WithEventListener(func(event lru.Event) { server.Notify(event) }))
+	server, addr := startServer(t, cache)
+	subscriber, writer := dial(t, addr), dial(t, addr)
+
+	assert.Equal(t, "subscribe __keyevent@0__:evicted #", fields(subscriber.do("SUBSCRIBE", "__keyevent@0__:evicted")))
+	assert.Equal(t, "psubscribe __keyspace@0__:user:* ##", fields(subscriber.do("PSUBSCRIBE", "__keyspace@0__:user:*")))
+	assert.Equal(t, "ERR Can't execute 'get': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context",
+		subscriber.do("GET", "key").Str)
+
+	writer.do("SET", "user:1", "a")
+	writer.do("SET", "user:2", "b") // Evicts user:1
+	writer.do("SET", "other", "c")  // Evicts user:2, only published to the channel of the evictions
+	var messages []string
+	for range 6 {
+		message, err := subscriber.reader.ReadValue()
+		assert.NoError(t, err)
+		messages = append(messages, fields(message))
+	}
+	assert.ElementsMatch(t, []string{
+		"pmessage __keyspace@0__:user:* __keyspace@0__:user:1 set",
+		"pmessage __keyspace@0__:user:* __keyspace@0__:user:2 set",
+		"pmessage __keyspace@0__:user:* __keyspace@0__:user:1 evicted",
+		"message __keyevent@0__:evicted user:1",
+		"pmessage __keyspace@0__:user:* __keyspace@0__:user:2 evicted",
+		"message __keyevent@0__:evicted user:2",
+	}, messages)
+
+	assert.Equal(t, "unsubscribe __keyevent@0__:evicted #", fields(subscriber.do("UNSUBSCRIBE")))
+	assert.Equal(t, "punsubscribe __keyspace@0__:user:* ", fields(subscriber.do("PUNSUBSCRIBE", "__keyspace@0__:user:*")))
+	assert.Equal(t, "c", subscriber.do("GET", "other").Str) // Back to the regular commands
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"caching/internal/resp"
+)
+
+// replicationQueueSize is the number of writes waiting to be sent to a replica.
+// With FireAndForget consistency, writes are dropped for a replica whose queue is full.
+const replicationQueueSize = 1024
+
+// errNoQuorum is returned when too few replicas acknowledged a write in time.
+var errNoQuorum = errors.New("not enough replicas acknowledged the write")
+
+// Consistency selects when a replicated write is acknowledged to the client.
+type Consistency int
+
+const (
+	// FireAndForget acknowledges writes once applied locally, replicating them in the background.
+	FireAndForget Consistency = iota
+	// Quorum acknowledges writes once a majority of the copies, the local one included, applied them.
+	Quorum
+)
+
+// replicaOp is a write to send to a replica.
+type replicaOp struct {
+	args []string   // The command, as received from the client
+	ack  chan error // Receives the outcome of the write, nil when nobody waits for it
+}
+
+// replication forwards the writes of a server to its replicas.
+type replication struct {
+	links       []*replicaLink
+	consistency Consistency
+	timeout     time.Duration // How long a quorum write waits for the acknowledgements
+}
+
+func newReplication(addrs []string, consistency Consistency, timeout time.Duration, secret string) *replication {
+	replication := &replication{consistency: consistency, timeout: timeout}
+	for _, addr := range addrs {
+		link := &replicaLink{
+			addr:    addr,
+			secret:  secret,
+			ops:     make(chan replicaOp, replicationQueueSize),
+			timeout: timeout,
+			done:    make(chan struct{}),
+		}
+		go link.run()
+		replication.links = append(replication.links, link)
+	}
+	return replication
+}
+
+// pendingWrite is a write queued to the replicas, whose acknowledgements are awaited by wait.
+type pendingWrite struct {
+	acks  chan error  // Receives the outcome of the write on the replicas, nil with FireAndForget
+	timer *time.Timer // Fires when the replicas took too long to acknowledge the write, nil with FireAndForget
+	err   error       // Why the write could not be queued to every replica, nil if it was
+}
+
+// enqueue queues a write to every replica, which receive the writes in the order of the calls.
+// With FireAndForget, a replica whose queue is full misses the write. With Quorum, enqueue waits
+// for room in the queues until the timeout or the end of the context.
+func (replication *replication) enqueue(ctx context.Context, args []string) *pendingWrite {
+	if replication.consistency == FireAndForget {
+		for _, link := range replication.links {
+			select {
+			case link.ops <- replicaOp{args: args}:
+			default: // The replica is lagging too far behind, it will miss this write
+			}
+		}
+		return &pendingWrite{}
+	}
+
+	write := &pendingWrite{acks: make(chan error, len(replication.links)), timer: time.NewTimer(replication.timeout)}
+	for _, link := range replication.links {
+		select {
+		case link.ops <- replicaOp{args: args, ack: write.acks}:
+		case <-write.timer.C:
+			write.err = errNoQuorum
+			return write
+		case <-ctx.Done():
+			write.err = ctx.Err()
+			return write
+		}
+	}
+	return write
+}
+
+// wait returns once the write queued by enqueue is acknowledged. With FireAndForget, it returns immediately.
+// With Quorum, it waits until a majority of the copies applied the write, and returns errNoQuorum if that
+// does not happen before the timeout, or the context error if the context is done first.
+func (replication *replication) wait(ctx context.Context, write *pendingWrite) error {
+	if write.timer == nil {
+		return nil
+	}
+	defer write.timer.Stop()
+	if write.err != nil {
+		return write.err
+	}
+
+	needed := (len(replication.links) + 1) / 2 // The local copy counts towards the majority
+	succeeded, failed := 0, 0
+	for succeeded < needed {
+		select {
+		case err := <-write.acks:
+			if err != nil {
+				failed++
+			} else {
+				succeeded++
+			}
+			if failed > len(replication.links)-needed {
+				return errNoQuorum
+			}
+		case <-write.timer.C:
+			return errNoQuorum
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// close stops sending writes to the replicas, once the queued ones are sent.
+// No write may be queued after close.
+func (replication *replication) close() {
+	for _, link := range replication.links {
+		close(link.ops)
+	}
+	for _, link := range replication.links {
+		<-link.done
+	}
+}
+
+// replicaLink sends the writes to a replica in order, over a single connection.
+type replicaLink struct {
+	addr    string
+	secret  string         // Secret given to REPLICATE, as set with WithReplicationSecret
+	ops     chan replicaOp // Writes waiting to be sent, closed to stop the link
+	timeout time.Duration  // Timeout of the connection attempts and of every write
+	done    chan struct{}  // Closed when the link stopped
+
+	conn   net.Conn // Connection to the replica, nil until connected or after an error
+	reader *resp.Reader
+	writer *resp.Writer
+}
+
+func (link *replicaLink) run() {
+	defer close(link.done)
+	for op := range link.ops {
+		err := link.send(op.args)
+		if op.ack != nil {
+			op.ack <- err
+		}
+	}
+	if link.conn != nil {
+		link.conn.Close()
+	}
+}
+
+// send writes a command to the replica and waits for its reply, reconnecting if needed.
+func (link *replicaLink) send(args []string) error {
+	if link.conn == nil {
+		if err := link.connect(); err != nil {
+			return err
+		}
+	}
+
+	link.conn.SetDeadline(time.Now().Add(link.timeout))
+	link.writer.WriteCommand(args...)
+	reply, err := link.roundTrip()
+	if err != nil {
+		link.conn.Close()
+		link.conn = nil // Reconnect for the next write
+		return err
+	}
+	if reply.Kind == resp.Error {
+		return fmt.Errorf("replica %s: %s", link.addr, reply.Str)
+	}
+	return nil
+}
+
+// connect dials the replica and marks the connection as a replication stream with the shared secret,
+// so the replica does not forward the writes to its own replicas.
+func (link *replicaLink) connect() error {
+	conn, err := net.DialTimeout("tcp", link.addr, link.timeout)
+	if err != nil {
+		return err
+	}
+	link.conn, link.reader, link.writer = conn, resp.NewReader(conn), resp.NewWriter(conn)
+
+	link.conn.SetDeadline(time.Now().Add(link.timeout))
+	link.writer.WriteCommand("REPLICATE", link.secret)
+	reply, err := link.roundTrip()
+	if err == nil && reply.Kind == resp.Error {
+		err = fmt.Errorf("replica %s: %s", link.addr, reply.Str)
+	}
+	if err != nil {
+		link.conn.Close()
+		link.conn = nil
+		return err
+	}
+	return nil
+}
+
+// roundTrip flushes the buffered command and reads its reply.
+func (link *replicaLink) roundTrip() (resp.Value, error) {
+	if err := link.writer.Flush(); err != nil {
+		return resp.Value{}, err
+	}
+	return link.reader.ReadValue()
+}
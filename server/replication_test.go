@@ -0,0 +1,144 @@
+package server
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"caching/lru"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFireAndForgetReplication(t *testing.T) {
+	replicaCache := lru.NewSafeLRUCache(10)
+	_, replicaAddr := startServer(t, replicaCache, WithReplicationSecret("secret"))
+	_, primaryAddr := startServer(t, lru.NewSafeLRUCache(10), WithReplicas([]string{replicaAddr}, FireAndForget), WithReplicationSecret("secret"))
+	primary := dial(t, primaryAddr)
+
+	assert.Equal(t, "OK", primary.do("SET", "key1", "value1", "EX", "60").Str)
+	assert.Eventually(t, func() bool {
+		value, _ := replicaCache.Get("key1")
+		return value == "value1"
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, int64(1), primary.do("DEL", "key1").Int)
+	assert.Eventually(t, func() bool { return replicaCache.Len() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestQuorumReplication(t *testing.T) {
+	replicaCache := lru.NewSafeLRUCache(10)
+	_, replicaAddr := startServer(t, replicaCache, WithReplicationSecret("secret"))
+	_, primaryAddr := startServer(t, lru.NewSafeLRUCache(10), WithReplicas([]string{replicaAddr}, Quorum), WithReplicationSecret("secret"))
+	primary := dial(t, primaryAddr)
+
+	assert.Equal(t, "OK", primary.do("SET", "key1", "value1").Str)
+	value, found := replicaCache.Get("key1") // Acknowledged, so already applied by the replica
+	assert.True(t, found)
+	assert.Equal(t, "value1", value)
+}
+
+func TestQuorumNotReached(t *testing.T) {
+	primaryCache := lru.NewSafeLRUCache(10)
+	_, primaryAddr := startServer(t, primaryCache,
+		WithReplicas([]string{"127.0.0.1:1"}, Quorum), WithReplicationTimeout(100*time.Millisecond))
+	primary := dial(t, primaryAddr)
+
+	reply := primary.do("SET", "key1", "value1")
+	assert.Contains(t, reply.Str, "NOQUORUM")
+	_, found := primaryCache.Get("key1") // The local write is kept
+	assert.True(t, found)
+}
+
+func TestQuorumWriteTimeout(t *testing.T) {
+	stalled, err := net.Listen("tcp", "127.0.0.1:0") // Accepts the connection but never replies
+	assert.NoError(t, err)
+	defer stalled.Close()
+	_, primaryAddr := startServer(t, lru.NewSafeLRUCache(10), WithReplicas([]string{stalled.Addr().String()}, Quorum),
+		WithReplicationTimeout(time.Second), WithCommandTimeout(20*time.Millisecond))
+	primary := dial(t, primaryAddr)
+
+	start := time.Now()
+	assert.Equal(t, "TIMEOUT 'set' command exceeded its deadline", primary.do("SET", "key1", "value1").Str)
+	assert.Less(t, time.Since(start), time.Second) // Did not wait for the replication timeout
+}
+
+func TestMutualReplicationDoesNotLoop(t *testing.T) {
+	listenerA, _ := net.Listen("tcp", "127.0.0.1:0")
+	listenerB, _ := net.Listen("tcp", "127.0.0.1:0")
+	cacheA, cacheB := lru.NewSafeLRUCache(10), lru.NewSafeLRUCache(10)
+	serverA := New(cacheA, WithReplicas([]string{listenerB.Addr().String()}, Quorum), WithReplicationSecret("secret"))
+	serverB := New(cacheB, WithReplicas([]string{listenerA.Addr().String()}, Quorum), WithReplicationSecret("secret"))
+	go serverA.Serve(listenerA)
+	go serverB.Serve(listenerB)
+	defer serverA.Close()
+	defer serverB.Close()
+
+	assert.Equal(t, "OK", dial(t, listenerA.Addr().String()).do("SET", "key1", "a").Str)
+	assert.Equal(t, "OK", dial(t, listenerB.Addr().String()).do("SET", "key2", "b").Str)
+
+	for _, cache := range []lru.Cache{cacheA, cacheB} {
+		assert.Equal(t, 2, cache.Len())
+	}
+}
+
+func TestReplicationSecret(t *testing.T) {
+	replicaCache := lru.NewSafeLRUCache(10)
+	_, replicaAddr := startServer(t, replicaCache, WithReplicationSecret("secret"))
+	_, primaryAddr := startServer(t, lru.NewSafeLRUCache(10), WithReplicas([]string{replicaAddr}, Quorum),
+		WithReplicationSecret("wrong"), WithReplicationTimeout(100*time.Millisecond))
+
+	assert.Equal(t, "NOQUORUM not enough replicas acknowledged the write", dial(t, primaryAddr).do("SET", "key1", "value1").Str)
+	assert.Equal(t, 0, replicaCache.Len())
+
+	client := dial(t, replicaAddr)
+	assert.Equal(t, "WRONGPASS invalid replication secret", client.do("REPLICATE", "guess").Str)
+	assert.Equal(t, "OK", client.do("REPLICATE", "secret").Str)
+
+	_, addr := startServer(t, lru.NewSafeLRUCache(10))
+	assert.Equal(t, "ERR REPLICATE is disabled without a replication secret", dial(t, addr).do("REPLICATE", "secret").Str)
+}
+
+func TestConcurrentWritesReachTheReplicasInOrder(t *testing.T) {
+	replicaCache := lru.NewSafeLRUCache(10)
+	_, replicaAddr := startServer(t, replicaCache, WithReplicationSecret("secret"))
+	primaryCache := lru.NewSafeLRUCache(10)
+	_, primaryAddr := startServer(t, primaryCache, WithReplicas([]string{replicaAddr}, Quorum), WithReplicationSecret("secret"))
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := dial(t, primaryAddr)
+			for j := range 20 {
+				client.do("SET", "key1", strconv.Itoa(i*100+j))
+			}
+		}()
+	}
+	wg.Wait()
+
+	primaryValue, _ := primaryCache.Get("key1")
+	replicaValue, _ := replicaCache.Get("key1")
+	assert.Equal(t, primaryValue, replicaValue)
+}
+
+func TestRemoveByPrefixIsTrackedAndReplicated(t *testing.T) {
+	replicaCache := lru.NewSafeLRUCache(10)
+	_, replicaAddr := startServer(t, replicaCache, WithReplicationSecret("secret"))
+	primary, primaryAddr := startServer(t, lru.NewSafeLRUCache(10), WithReplicas([]string{replicaAddr}, Quorum), WithReplicationSecret("secret"))
+	client, reader := dial(t, primaryAddr), dial(t, primaryAddr)
+
+	assert.Equal(t, "OK", client.do("MSET", "report:1", "a", "report:2", "b", "user:1", "c").Str)
+	assert.Equal(t, "OK", reader.do("CLIENT", "TRACKING", "ON").Str)
+	assert.Equal(t, "a", reader.do("GET", "report:1").Str)
+
+	assert.Equal(t, 2, primary.RemoveByPrefix("report:"))
+	message, err := reader.reader.ReadValue()
+	assert.NoError(t, err)
+	assert.Equal(t, invalidation("report:1"), message)
+	assert.Equal(t, 1, replicaCache.Len(), "Expected the removal to be replicated")
+	assert.Equal(t, 0, primary.RemoveByPrefix("report:"))
+}
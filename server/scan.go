@@ -0,0 +1,119 @@
+package server
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"caching/lru"
+)
+
+const (
+	// defaultScanCount is the number of keys visited by SCAN without COUNT, the same as Redis.
+	defaultScanCount = 10
+	// maxScanCursors is the number of cursors remembered by a connection, the oldest being forgotten beyond it.
+	maxScanCursors = 64
+)
+
+// scans holds the positions of the scans in progress of a connection. Clients expect the cursor of SCAN
+// to be a number, so it is the id of a position kept by the server rather than the position itself.
+// Every connection has its own cursors, so the scans of a client cannot push out the cursors of another one.
+type scans struct {
+	mutex   sync.Mutex
+	lastID  uint64                    // Id of the last cursor handed out
+	cursors map[uint64]lru.ScanCursor // Positions of the scans in progress by cursor id
+}
+
+// save remembers the position of a scan and returns its cursor id, forgetting the oldest scan
+// when too many are in progress.
+func (scans *scans) save(cursor lru.ScanCursor) uint64 {
+	scans.mutex.Lock()
+	defer scans.mutex.Unlock()
+
+	if len(scans.cursors) >= maxScanCursors {
+		oldest := scans.lastID
+		for id := range scans.cursors {
+			oldest = min(oldest, id)
+		}
+		delete(scans.cursors, oldest)
+	}
+	if scans.cursors == nil {
+		scans.cursors = make(map[uint64]lru.ScanCursor)
+	}
+	scans.lastID++
+	scans.cursors[scans.lastID] = cursor
+	return scans.lastID
+}
+
+// load returns the position of the scan with the given cursor id, or false if it is unknown,
+// e.g. forgotten after too many other pages, or handed out to another connection.
+// The position is kept, so a client can retry a page.
+func (scans *scans) load(id uint64) (lru.ScanCursor, bool) {
+	scans.mutex.Lock()
+	defer scans.mutex.Unlock()
+
+	cursor, found := scans.cursors[id]
+	return cursor, found
+}
+
+// scan runs SCAN cursor [MATCH pattern] [COUNT count], replying the cursor of the next page, "0" once
+// the scan is complete, and the keys of the page. The cache must implement lru.KeyScanner, and only locks
+// its shards for one page at a time. The cursor is only valid on the connection it was handed out to.
+// Like Redis, the keys are filtered by MATCH after being visited, so a page may hold fewer keys than COUNT,
+// or none, before the scan is complete.
+func (server *Server) scan(session *session, args []string) {
+	writer := session.writer
+	if len(args) < 2 || len(args)%2 != 0 {
+		writeArityError(writer, "SCAN")
+		return
+	}
+	scanner, ok := server.cache.(lru.KeyScanner)
+	if !ok {
+		writer.WriteError("ERR SCAN is not supported by the cache")
+		return
+	}
+	id, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		writer.WriteError("ERR invalid cursor")
+		return
+	}
+	pattern, count := "", defaultScanCount
+	for i := 2; i < len(args); i += 2 {
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			if count, err = strconv.Atoi(args[i+1]); err != nil || count < 1 {
+				writer.WriteError("ERR value is not an integer or out of range")
+				return
+			}
+		default:
+			writer.WriteError("ERR syntax error")
+			return
+		}
+	}
+
+	var cursor lru.ScanCursor
+	if id != 0 {
+		if cursor, ok = session.scans.load(id); !ok {
+			writer.WriteError("ERR invalid cursor")
+			return
+		}
+	}
+	keys, next := scanner.ScanKeys(cursor, count)
+	if pattern != "" {
+		keys = slices.DeleteFunc(keys, func(key string) bool { return !lru.MatchPattern(pattern, key) })
+	}
+	nextID := uint64(0)
+	if !next.Done() {
+		nextID = session.scans.save(next)
+	}
+
+	writer.WriteArrayHeader(2)
+	writer.WriteBulkString(strconv.FormatUint(nextID, 10))
+	writer.WriteArrayHeader(len(keys))
+	for _, key := range keys {
+		writer.WriteBulkString(key)
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"caching/lru"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerScan(t *testing.T) {
+	_, addr := startServer(t, lru.NewShardedCache(4, 100))
+	client := dial(t, addr)
+	var expected []string
+	for i := range 30 {
+		key := fmt.Sprintf("user:%02d", i)
+		client.do("SET", key, "value")
+		expected = append(expected, key)
+	}
+	client.do("SET", "order:1", "value")
+
+	var keys []string
+	cursor := "0"
+	for pages := 0; pages == 0 || cursor != "0"; pages++ {
+		reply := client.do("SCAN", cursor, "MATCH", "user:*", "COUNT", "4")
+		cursor = reply.Array[0].Str
+		for _, key := range reply.Array[1].Array {
+			keys = append(keys, key.Str)
+		}
+		assert.Less(t, pages, 20)
+	}
+	assert.ElementsMatch(t, expected, keys)
+
+	assert.Equal(t, "ERR invalid cursor", client.do("SCAN", "123456").Str)
+	assert.Equal(t, "ERR value is not an integer or out of range", client.do("SCAN", "0", "COUNT", "0").Str)
+	assert.Equal(t, "ERR syntax error", client.do("SCAN", "0", "TYPE", "string").Str)
+
+	cursor = client.do("SCAN", "0", "COUNT", "4").Array[0].Str
+	other := dial(t, addr)
+	assert.Equal(t, "ERR invalid cursor", other.do("SCAN", cursor).Str, "Expected the cursors to belong to their connection")
+	assert.Len(t, client.do("SCAN", cursor, "COUNT", "4").Array, 2)
+}
+
+func TestScanCursorsAreBounded(t *testing.T) {
+	var scans scans
+	scans.cursors = make(map[uint64]lru.ScanCursor)
+	for range maxScanCursors + 1 {
+		scans.save(lru.ScanCursor{})
+	}
+	_, found := scans.load(1)
+	assert.False(t, found, "Expected the oldest scan to be forgotten")
+	_, found = scans.load(maxScanCursors + 1)
+	assert.True(t, found)
+	assert.Len(t, scans.cursors, maxScanCursors)
+}
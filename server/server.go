@@ -0,0 +1,728 @@
+// Package server exposes a cache over TCP with the Redis serialization protocol (RESP2),
+// so it can be used from redis-cli and the Redis client libraries for the supported commands:
+// PING, GET, SET (with EX or PX), MGET, MSET, DEL, SCAN, INFO, SLOWLOG, QUIT, SUBSCRIBE and PSUBSCRIBE for the keyspace
+// notifications, and CLIENT ID and CLIENT TRACKING for client-side caching.
+// LGET and LSET hand out memcached-style leases, so only one client recomputes a missing key.
+// With WithReplicas, the writes are forwarded to other servers, so their caches stay warm
+// when a server restarts and clients reading from replicas find the keys.
+// Pipelined commands are answered with a single write once the commands received are executed,
+// and MGET and MSET lock every shard of an lru.Batcher cache once for all their keys.
+// With WithCommandTimeout, a command running past its deadline is answered with a TIMEOUT error,
+// and the loads of WithLoader and the quorum writes are cancelled along with it.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"caching/internal/resp"
+	"caching/lru"
+)
+
+// ErrServerClosed is returned by Serve after Close.
+var ErrServerClosed = errors.New("server: closed")
+
+// subscribedCommands lists the commands accepted by a connection subscribed to a channel.
+var subscribedCommands = []string{"SUBSCRIBE", "PSUBSCRIBE", "UNSUBSCRIBE", "PUNSUBSCRIBE", "PING", "QUIT"}
+
+// ErrKeyNotFound is returned by the loader of WithLoader for a key missing from the backend, so GET replies null.
+var ErrKeyNotFound = errors.New("server: key not found")
+
+// Server serves a cache to RESP clients, one goroutine per connection.
+type Server struct {
+	cache          lru.Cache          // The served cache, must be thread-safe
+	loading        *lru.LoadingCache  // Loads the keys missed by GET, nil without a loader
+	replication    *replication       // Forwards the writes to the replicas, nil without replicas
+	secret         string             // Secret REPLICATE must be given, empty to refuse it
+	commandTimeout time.Duration      // How long a command may run, zero without limit
+	ctx            context.Context    // Parent of the contexts of the connections, done after Close
+	cancel         context.CancelFunc // Cancels ctx
+	namespaceOf    lru.TenantFunc     // Namespace of a key counted by INFO keyspace, nil to not count them
+	slowLog        *lru.SlowLog       // Records the slow commands, nil to not record them
+	started        time.Time          // When the server was created, for the uptime of INFO
+	commands       atomic.Uint64      // Number of commands executed
+
+	mutex     sync.Mutex
+	listeners map[net.Listener]struct{} // Listeners being served
+	sessions  map[int64]*session        // Open client connections by id
+	lastID    int64                     // Id of the last connection
+	closed    bool                      // Whether Close was called
+	wg        sync.WaitGroup            // Tracks the connection goroutines
+
+	tracking      tracking      // Keys read by the connections with client tracking enabled
+	notifications notifications // Connections subscribed to the keyspace notifications
+	leases        *leases       // Leases handed out by LGET
+}
+
+var _ io.Closer = (*Server)(nil)       // Ensure Server can be closed
+var _ lru.Invalidator = (*Server)(nil) // Ensure Server can be driven by an lru.InvalidationScheduler
+
+// options holds the optional configuration of a Server.
+type options struct {
+	replicas           []string       // Addresses of the servers receiving the writes
+	consistency        Consistency    // When replicated writes are acknowledged
+	replicationTimeout time.Duration  // Timeout of the writes sent to the replicas
+	secret             string         // Secret shared by the servers replicating to each other
+	leaseTimeout       time.Duration  // How long a lease, or a deleted value, is kept
+	commandTimeout     time.Duration  // How long a command may run, zero without limit
+	loader             lru.Loader     // Loads the keys missed by GET, nil to reply null
+	slowLog            *lru.SlowLog   // Records the slow commands and loads, nil to not record them
+	namespaceOf        lru.TenantFunc // Namespace of a key counted by INFO keyspace
+}
+
+// Option configures a Server at construction time.
+type Option func(*options)
+
+// WithReplicas forwards every SET and DEL to the servers at the given addresses, in order.
+// With FireAndForget, writes are acknowledged once applied locally and a replica that is down
+// or lagging misses them. With Quorum, writes are acknowledged once a majority of the copies,
+// the local one included, applied them, and a NOQUORUM error is returned otherwise; the local
+// write is kept either way. Writes received from another server are not forwarded again,
+// so servers can replicate to each other. The replicas must share the secret of WithReplicationSecret.
+func WithReplicas(addrs []string, consistency Consistency) Option {
+	return func(o *options) {
+		o.replicas = addrs
+		o.consistency = consistency
+	}
+}
+
+// WithReplicationSecret sets the secret shared by the servers replicating to each other. It is given
+// by the links of WithReplicas to the REPLICATE command of their replicas, and a connection must give it
+// to REPLICATE for its writes to be applied as replicated ones. Without it, REPLICATE is refused,
+// so a client cannot pose as another server.
+func WithReplicationSecret(secret string) Option {
+	return func(o *options) {
+		o.secret = secret
+	}
+}
+
+// WithReplicationTimeout sets how long a write may take to reach a replica, and how long
+// a Quorum write waits for the acknowledgements. Defaults to 1 second.
+func WithReplicationTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.replicationTimeout = timeout
+	}
+}
+
+// WithLeaseTimeout sets how long the lease handed out by LGET for a missing key is valid,
+// after which another client can get one, e.g. when the holder crashed. The value of a deleted key
+// is served to the clients waiting for a lease during the same time. Defaults to 10 seconds.
+func WithLeaseTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.leaseTimeout = timeout
+	}
+}
+
+// WithCommandTimeout sets how long a command may run, e.g. waiting for a loader or for the replicas
+// to acknowledge a Quorum write. The context given to the loader is cancelled at the deadline, and the
+// command is answered with a TIMEOUT error, counted by the cache_server_timeouts_total metric.
+// Defaults to no timeout: a command only stops when its connection is closed.
+func WithCommandTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.commandTimeout = timeout
+	}
+}
+
+// WithLoader loads the keys missed by GET, through an lru.LoadingCache storing them in the served cache,
+// so concurrent misses of a key run the loader once. The loader is given the context of the command,
+// which it must honor for WithCommandTimeout to stop it. It returns ErrKeyNotFound when the key is
+// missing from the backend, and the other errors are answered with an ERR reply.
+func WithLoader(loader lru.Loader) Option {
+	return func(o *options) {
+		o.loader = loader
+	}
+}
+
+// WithSlowLog records the commands slower than the threshold of the log, along with the loads of WithLoader,
+// and serves the log with SLOWLOG GET [count], SLOWLOG LEN and SLOWLOG RESET, like Redis. The log can also
+// be given to the served cache with lru.WithSlowLog, to record its slow encodings.
+func WithSlowLog(log *lru.SlowLog) Option {
+	return func(o *options) {
+		o.slowLog = log
+	}
+}
+
+// WithNamespaces lists the number of keys of every namespace in the keyspace section of INFO,
+// namespaceOf returning the namespace of a key, e.g. the part before its first ':'.
+// Counting them visits every item of the cache, which must implement lru.Iterator.
+func WithNamespaces(namespaceOf lru.TenantFunc) Option {
+	return func(o *options) {
+		o.namespaceOf = namespaceOf
+	}
+}
+
+// New creates a server for the cache, which must be thread-safe, e.g. an lru.SafeLRUCache or lru.ShardedCache.
+func New(cache lru.Cache, opts ...Option) *Server {
+	o := options{replicationTimeout: time.Second, leaseTimeout: 10 * time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	server := &Server{
+		cache:          cache,
+		secret:         o.secret,
+		commandTimeout: o.commandTimeout,
+		namespaceOf:    o.namespaceOf,
+		slowLog:        o.slowLog,
+		started:        time.Now(),
+		listeners:      make(map[net.Listener]struct{}),
+		sessions:       make(map[int64]*session),
+		tracking:       tracking{readers: make(map[string]map[int64]struct{})},
+		notifications: notifications{
+			channels: make(map[string]map[*session]struct{}),
+			patterns: make(map[string]map[*session]struct{}),
+		},
+		leases: newLeases(o.leaseTimeout),
+	}
+	server.ctx, server.cancel = context.WithCancel(context.Background())
+	if o.loader != nil {
+		server.loading = lru.NewLoadingCache(cache, o.loader, lru.WithSlowLog(o.slowLog))
+	}
+	if len(o.replicas) > 0 {
+		server.replication = newReplication(o.replicas, o.consistency, o.replicationTimeout, o.secret)
+	}
+	return server
+}
+
+// ListenAndServe listens on the TCP address and serves the connections until Close is called.
+func (server *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return server.Serve(listener)
+}
+
+// Serve accepts the connections of the listener until Close is called, then returns ErrServerClosed.
+func (server *Server) Serve(listener net.Listener) error {
+	server.mutex.Lock()
+	if server.closed {
+		server.mutex.Unlock()
+		listener.Close()
+		return ErrServerClosed
+	}
+	server.listeners[listener] = struct{}{}
+	server.mutex.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			server.mutex.Lock()
+			closed := server.closed
+			delete(server.listeners, listener)
+			server.mutex.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+		session := server.register(conn)
+		if session == nil {
+			conn.Close()
+			return ErrServerClosed
+		}
+		go server.serveConn(session)
+	}
+}
+
+// register creates the session of a new connection, unless the server is closed.
+func (server *Server) register(conn net.Conn) *session {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if server.closed {
+		return nil
+	}
+	server.lastID++
+	session := &session{
+		id:     server.lastID,
+		conn:   conn,
+		writer: resp.NewWriter(conn),
+		done:   make(chan struct{}),
+	}
+	session.ctx, session.cancel = context.WithCancel(server.ctx)
+	server.sessions[session.id] = session
+	server.wg.Add(1)
+	return session
+}
+
+// session returns the session of the connection with the given id, or nil if it is closed.
+func (server *Server) session(id int64) *session {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	return server.sessions[id]
+}
+
+// Close stops the listeners, closes the open connections and waits for their goroutines to return,
+// then sends the queued writes to the replicas.
+func (server *Server) Close() error {
+	server.mutex.Lock()
+	if server.closed {
+		server.mutex.Unlock()
+		return nil
+	}
+	server.closed = true
+	var errs []error
+	for listener := range server.listeners {
+		errs = append(errs, listener.Close())
+	}
+	for _, session := range server.sessions {
+		session.conn.Close()
+	}
+	server.mutex.Unlock()
+	server.cancel() // Stops the commands waiting for a loader or the replicas
+
+	server.wg.Wait()
+	if server.replication != nil {
+		server.replication.close()
+	}
+	return errors.Join(errs...)
+}
+
+// session is the state of a client connection.
+type session struct {
+	id          int64
+	conn        net.Conn
+	writer      *resp.Writer
+	writerMutex sync.Mutex // Guards the writer, shared by the replies and the pushed messages
+	replica     bool       // Whether the connection is the replication stream of another server
+
+	redirect   int64            // Id of the connection receiving the invalidations of the keys read, zero without tracking
+	pushes     chan pushMessage // Messages waiting to be pushed, nil until the first one
+	pushesOnce sync.Once        // Starts the goroutine pushing the messages
+	done       chan struct{}    // Closed when the connection is closed
+
+	ctx    context.Context    // Parent of the contexts of the commands, done when the connection is closed
+	cancel context.CancelFunc // Cancels ctx
+
+	channels      map[string]struct{} // Channels subscribed to, guarded by the mutex of the notifications
+	patterns      map[string]struct{} // Channel patterns subscribed to, guarded by the mutex of the notifications
+	subscriptions atomic.Int64        // Number of channels and patterns subscribed to, read without the mutex
+
+	scans scans // Positions of the SCAN in progress
+}
+
+// serveConn reads the commands of a connection and writes their replies, until the client leaves.
+func (server *Server) serveConn(session *session) {
+	defer func() {
+		session.conn.Close()
+		session.cancel()
+		server.unsubscribeAll(session)
+		close(session.done)
+		server.mutex.Lock()
+		delete(server.sessions, session.id)
+		server.mutex.Unlock()
+		server.wg.Done()
+	}()
+
+	reader := resp.NewReader(session.conn)
+	writer := session.writer
+	for {
+		args, err := reader.ReadCommand()
+		if errors.Is(err, resp.ErrProtocol) {
+			session.writerMutex.Lock()
+			writer.WriteError("ERR " + err.Error())
+			writer.Flush()
+			session.writerMutex.Unlock()
+			return
+		}
+		if err != nil {
+			return // The client left or the connection was closed
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		session.writerMutex.Lock()
+		quit := server.execute(session, args)
+		if quit || reader.Buffered() == 0 { // The replies of pipelined commands are sent together
+			err = writer.Flush()
+		}
+		session.writerMutex.Unlock()
+		if err != nil || quit {
+			return
+		}
+	}
+}
+
+// execute runs a command, writing its reply. It returns true when the client asked to close the connection.
+func (server *Server) execute(session *session, args []string) (quit bool) {
+	ctx := session.ctx
+	if server.commandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, server.commandTimeout)
+		defer cancel()
+	}
+
+	server.commands.Add(1)
+	writer := session.writer
+	name := strings.ToUpper(args[0])
+	if server.slowLog != nil {
+		defer server.slowLog.Observe(strings.ToLower(name), commandKey(args), time.Now(), 0, nil)
+	}
+	if session.subscribed() && !slices.Contains(subscribedCommands, name) {
+		writeSubscribedError(session, name)
+		return false
+	}
+	switch name {
+	case "PING":
+		if len(args) > 1 {
+			writer.WriteBulkString(args[1])
+		} else {
+			writer.WriteSimpleString("PONG")
+		}
+	case "GET":
+		if len(args) != 2 {
+			writeArityError(writer, name)
+			return false
+		}
+		if session.redirect != 0 { // Before the read, so a write racing with it is invalidated
+			server.tracking.track(args[1], session.redirect)
+		}
+		value, found, err := server.get(ctx, args[1])
+		switch {
+		case err != nil:
+			writeCommandError(writer, name, err)
+		case !found:
+			writer.WriteNull()
+		default:
+			writer.WriteBulkString(format(value))
+		}
+	case "SET":
+		if write, ok := server.set(ctx, session, args); ok {
+			server.invalidate(args[1:2])
+			if server.replicate(ctx, session, name, write) {
+				writer.WriteSimpleString("OK")
+			}
+		}
+	case "MGET":
+		server.getMany(ctx, session, args)
+	case "MSET":
+		if len(args) < 3 || len(args)%2 == 0 {
+			writeArityError(writer, name)
+			return false
+		}
+		keys := make([]string, 0, len(args)/2)
+		server.leases.mutex.Lock()
+		server.setMany(args[1:])
+		for i := 1; i < len(args); i += 2 {
+			server.leases.revoke(args[i])
+			keys = append(keys, args[i])
+		}
+		write := server.enqueue(ctx, session, args)
+		server.leases.mutex.Unlock()
+		server.invalidate(keys)
+		if server.replicate(ctx, session, name, write) {
+			writer.WriteSimpleString("OK")
+		}
+	case "DEL":
+		if len(args) < 2 {
+			writeArityError(writer, name)
+			return false
+		}
+		server.leases.mutex.Lock()
+		removed := server.remove(args[1:])
+		write := server.enqueue(ctx, session, args)
+		server.leases.mutex.Unlock()
+		server.invalidate(args[1:])
+		if server.replicate(ctx, session, name, write) {
+			writer.WriteInteger(int64(removed))
+		}
+	case "LGET":
+		server.leaseGet(session, args)
+	case "LSET":
+		server.leaseSet(ctx, session, args)
+	case "SCAN":
+		server.scan(session, args)
+	case "TTL", "PTTL":
+		server.ttl(session, name, args)
+	case "SLOWLOG":
+		server.slowlog(session, args)
+	case "INFO":
+		server.info(session, args[1:])
+	case "SUBSCRIBE", "PSUBSCRIBE":
+		server.subscribe(session, name, args[1:])
+	case "UNSUBSCRIBE", "PUNSUBSCRIBE":
+		server.unsubscribe(session, name, args[1:])
+	case "CLIENT":
+		server.client(session, args)
+	case "REPLICATE":
+		if len(args) != 2 {
+			writeArityError(writer, name)
+			return false
+		}
+		if server.secret == "" {
+			writer.WriteError("ERR REPLICATE is disabled without a replication secret")
+			return false
+		}
+		if subtle.ConstantTimeCompare([]byte(args[1]), []byte(server.secret)) != 1 {
+			writer.WriteError("WRONGPASS invalid replication secret")
+			return false
+		}
+		session.replica = true
+		writer.WriteSimpleString("OK")
+	case "QUIT":
+		writer.WriteSimpleString("OK")
+		return true
+	default:
+		writer.WriteError(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+	return false
+}
+
+// get returns the value of the key, loading it with the loader of WithLoader if it is missing.
+func (server *Server) get(ctx context.Context, key string) (value any, found bool, err error) {
+	if server.loading == nil {
+		value, found = server.cache.Get(key)
+		return value, found, nil
+	}
+	value, err = server.loading.Get(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, false, nil
+	}
+	return value, err == nil, err
+}
+
+// getMany runs MGET key [key ...], replying the values of the keys in order, null for the missing ones.
+// The keys missed are loaded one after the other with the loader of WithLoader.
+func (server *Server) getMany(ctx context.Context, session *session, args []string) {
+	writer := session.writer
+	if len(args) < 2 {
+		writeArityError(writer, "MGET")
+		return
+	}
+	keys := args[1:]
+	if session.redirect != 0 { // Before the reads, so a write racing with them is invalidated
+		for _, key := range keys {
+			server.tracking.track(key, session.redirect)
+		}
+	}
+	var values []any
+	var found []bool
+	if batcher, ok := server.cache.(lru.Batcher); ok {
+		values, found = batcher.GetMany(keys)
+	} else {
+		values, found = make([]any, len(keys)), make([]bool, len(keys))
+		for i, key := range keys {
+			values[i], found[i] = server.cache.Get(key)
+		}
+	}
+	if server.loading != nil {
+		for i, key := range keys {
+			if found[i] {
+				continue
+			}
+			var err error
+			if values[i], found[i], err = server.get(ctx, key); err != nil {
+				writeCommandError(writer, "MGET", err)
+				return
+			}
+		}
+	}
+	writer.WriteArrayHeader(len(keys))
+	for i, value := range values {
+		if found[i] {
+			writer.WriteBulkString(format(value))
+		} else {
+			writer.WriteNull()
+		}
+	}
+}
+
+// setMany sets the key value pairs of MSET, without expiration.
+func (server *Server) setMany(pairs []string) {
+	keys, values := make([]string, 0, len(pairs)/2), make([]any, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		keys, values = append(keys, pairs[i]), append(values, pairs[i+1])
+	}
+	if batcher, ok := server.cache.(lru.Batcher); ok {
+		batcher.SetMany(keys, values)
+		return
+	}
+	for i, key := range keys {
+		server.cache.Set(key, values[i])
+	}
+}
+
+// set runs SET key value [EX seconds | PX milliseconds], revoking the lease of the key under the same lock
+// as the write and queueing it to the replicas. It returns whether the value was set, and the write to wait
+// for with replicate; the reply is only written for errors.
+func (server *Server) set(ctx context.Context, session *session, args []string) (*pendingWrite, bool) {
+	if len(args) != 3 && len(args) != 5 {
+		writeArityError(session.writer, "SET")
+		return nil, false
+	}
+	ttl, ok := parseExpiration(session.writer, "set", args[3:])
+	if !ok {
+		return nil, false
+	}
+	server.leases.mutex.Lock()
+	defer server.leases.mutex.Unlock()
+
+	if ttl > 0 {
+		server.cache.SetWithTTL(args[1], args[2], ttl)
+	} else {
+		server.cache.Set(args[1], args[2])
+	}
+	server.leases.revoke(args[1])
+	return server.enqueue(ctx, session, args), true
+}
+
+// parseExpiration parses the optional EX seconds or PX milliseconds arguments of the named command.
+// It returns zero without expiration, and false after writing an error reply when they are invalid.
+func parseExpiration(writer *resp.Writer, name string, args []string) (time.Duration, bool) {
+	if len(args) == 0 {
+		return 0, true
+	}
+	amount, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || amount <= 0 {
+		writer.WriteError(fmt.Sprintf("ERR invalid expire time in '%s' command", name))
+		return 0, false
+	}
+	switch strings.ToUpper(args[0]) {
+	case "EX":
+		return time.Duration(amount) * time.Second, true
+	case "PX":
+		return time.Duration(amount) * time.Millisecond, true
+	default:
+		writer.WriteError("ERR syntax error")
+		return 0, false
+	}
+}
+
+// enqueue queues a write to the replicas, unless the server has none or the write was received from another
+// server, in which case it returns nil. The caller must hold leases.mutex since applying the write, so the
+// replicas apply the concurrent writes in the same order as the local copy. With Quorum, it may wait under
+// the lock for room in the queue of a lagging replica, until the replication timeout.
+func (server *Server) enqueue(ctx context.Context, session *session, args []string) *pendingWrite {
+	if server.replication == nil || (session != nil && session.replica) {
+		return nil
+	}
+	return server.replication.enqueue(ctx, args)
+}
+
+// replicate waits for the write queued by enqueue, outside of leases.mutex. It returns false after writing
+// an error reply for the named command when a Quorum write was not acknowledged in time, or before the
+// deadline of the command.
+func (server *Server) replicate(ctx context.Context, session *session, name string, write *pendingWrite) bool {
+	if write == nil {
+		return true
+	}
+	if err := server.replication.wait(ctx, write); err != nil {
+		if errors.Is(err, errNoQuorum) {
+			session.writer.WriteError("NOQUORUM " + err.Error())
+		} else {
+			writeCommandError(session.writer, name, err)
+		}
+		return false
+	}
+	return true
+}
+
+// remove removes the keys from the cache, keeping their values for the clients waiting for a lease,
+// and returns how many were found. The caller must hold leases.mutex.
+func (server *Server) remove(keys []string) int {
+	removed := 0
+	for _, key := range keys {
+		if value, found := server.take(key); found {
+			server.leases.retain(key, format(value))
+			removed++
+		}
+	}
+	return removed
+}
+
+// take removes the key and returns its live value, at once if the cache is an lru.GetAndRemover,
+// so a concurrent write to the key is either removed or kept whole. Otherwise the value is peeked,
+// if the cache is an lru.Peeker, so it is neither promoted nor counted as a hit.
+func (server *Server) take(key string) (any, bool) {
+	if remover, ok := server.cache.(lru.GetAndRemover); ok {
+		return remover.GetAndRemove(key)
+	}
+	read := server.cache.Get
+	if peeker, ok := server.cache.(lru.Peeker); ok {
+		read = peeker.Peek
+	}
+	value, found := read(key)
+	server.cache.Remove(key)
+	return value, found
+}
+
+// Remove removes the key like a DEL command: the clients tracking it are invalidated and the removal
+// is forwarded to the replicas. With Server.RemoveByPrefix, it lets an lru.InvalidationScheduler
+// invalidate the keys served without bypassing the clients and the replicas.
+func (server *Server) Remove(key string) {
+	server.removeKeys([]string{key})
+}
+
+// RemoveByPrefix removes every key starting with prefix like a DEL command of these keys, and returns
+// how many were found. The keys added while they are being removed may be kept. Nothing is removed
+// if the cache is not an lru.KeyspaceScanner.
+func (server *Server) RemoveByPrefix(prefix string) int {
+	scanner, ok := server.cache.(lru.KeyspaceScanner)
+	if !ok {
+		return 0
+	}
+	var keys []string
+	scanner.ScanPrefix(prefix, func(key string, _ any) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return server.removeKeys(keys)
+}
+
+// removeKeys removes the keys, invalidates them and forwards their removal to the replicas.
+// Like a DEL answered with NOQUORUM, the removal is kept if the replicas do not acknowledge it.
+func (server *Server) removeKeys(keys []string) int {
+	if len(keys) == 0 {
+		return 0
+	}
+	server.leases.mutex.Lock()
+	removed := server.remove(keys)
+	write := server.enqueue(server.ctx, nil, append([]string{"DEL"}, keys...))
+	server.leases.mutex.Unlock()
+	server.invalidate(keys)
+	if write != nil {
+		_ = server.replication.wait(server.ctx, write)
+	}
+	return removed
+}
+
+// format converts a cached value to the string sent to the client.
+// Values set through the server are strings, the others are formatted like fmt.Print does.
+func format(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// writeCommandError writes the reply of a command that failed with the given error:
+// a TIMEOUT error, counted by the timeouts metric, when the command ran past its deadline.
+func writeCommandError(writer *resp.Writer, name string, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		timeouts.WithLabelValues(strings.ToLower(name)).Inc()
+		writer.WriteError(fmt.Sprintf("TIMEOUT '%s' command exceeded its deadline", strings.ToLower(name)))
+		return
+	}
+	writer.WriteError("ERR " + err.Error())
+}
+
+func writeArityError(writer *resp.Writer, name string) {
+	writer.WriteError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"caching/internal/resp"
+	"caching/lru"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+// startServer serves a new cache on a random local port, returning the server and its address.
+func startServer(t *testing.T, cache lru.Cache, opts ...Option) (*Server, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server := New(cache, opts...)
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return server, listener.Addr().String()
+}
+
+// testConn sends commands to a server and reads their replies.
+type testConn struct {
+	t      *testing.T
+	reader *resp.Reader
+	writer *resp.Writer
+}
+
+func dial(t *testing.T, addr string) *testConn {
+	conn, err := net.Dial("tcp", addr)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &testConn{t: t, reader: resp.NewReader(conn), writer: resp.NewWriter(conn)}
+}
+
+func (conn *testConn) do(args ...string) resp.Value {
+	conn.writer.WriteCommand(args...)
+	assert.NoError(conn.t, conn.writer.Flush())
+	reply, err := conn.reader.ReadValue()
+	assert.NoError(conn.t, err)
+	return reply
+}
+
+func TestServerCommands(t *testing.T) {
+	_, addr := startServer(t, lru.NewSafeLRUCache(10))
+	client := dial(t, addr)
+
+	assert.Equal(t, "PONG", client.do("PING").Str)
+	assert.Equal(t, "OK", client.do("SET", "key1", "value1").Str)
+	assert.Equal(t, resp.Value{Kind: resp.BulkString, Str: "value1"}, client.do("get", "key1"))
+	assert.True(t, client.do("GET", "missing").Null)
+	assert.Equal(t, int64(1), client.do("DEL", "key1", "missing").Int)
+	assert.True(t, client.do("GET", "key1").Null)
+}
+
+func TestServerBatches(t *testing.T) {
+	_, addr := startServer(t, lru.NewShardedCache(4, 100))
+	client := dial(t, addr)
+
+	assert.Equal(t, "OK", client.do("MSET", "key1", "value1", "key2", "value2", "key3", "value3").Str)
+	reply := client.do("MGET", "key3", "missing", "key1")
+	assert.Equal(t, resp.Array, reply.Kind)
+	assert.Equal(t, []resp.Value{
+		{Kind: resp.BulkString, Str: "value3"},
+		{Kind: resp.BulkString, Null: true},
+		{Kind: resp.BulkString, Str: "value1"},
+	}, reply.Array)
+	assert.Equal(t, "ERR wrong number of arguments for 'mset' command", client.do("MSET", "key1").Str)
+	assert.Equal(t, "ERR wrong number of arguments for 'mget' command", client.do("MGET").Str)
+}
+
+func TestServerPipelining(t *testing.T) {
+	_, addr := startServer(t, lru.NewSafeLRUCache(10))
+	client := dial(t, addr)
+
+	client.writer.WriteCommand("SET", "key1", "value1")
+	client.writer.WriteCommand("GET", "key1")
+	client.writer.WriteCommand("DEL", "key1")
+	client.writer.WriteCommand("GET", "key1")
+	assert.NoError(t, client.writer.Flush()) // A single round trip for the four commands
+
+	var replies []resp.Value
+	for range 4 {
+		reply, err := client.reader.ReadValue()
+		assert.NoError(t, err)
+		replies = append(replies, reply)
+	}
+	assert.Equal(t, "OK", replies[0].Str)
+	assert.Equal(t, "value1", replies[1].Str)
+	assert.Equal(t, int64(1), replies[2].Int)
+	assert.True(t, replies[3].Null)
+}
+
+func TestServerSetWithExpiration(t *testing.T) {
+	clock := lru.NewManualClock(time.Now())
+	_, addr := startServer(t, lru.NewSafeLRUCache(10, lru.WithClock(clock)))
+	client := dial(t, addr)
+
+	assert.Equal(t, "OK", client.do("SET", "key1", "value1", "EX", "10").Str)
+	assert.Equal(t, "OK", client.do("SET", "key2", "value2", "PX", "500").Str)
+	clock.Advance(time.Second)
+
+	assert.Equal(t, "value1", client.do("GET", "key1").Str)
+	assert.True(t, client.do("GET", "key2").Null)
+
+	assert.Equal(t, "OK", client.do("SET", "key3", "value3").Str)
+	assert.Equal(t, int64(9), client.do("TTL", "key1").Int)
+	assert.Equal(t, int64(9000), client.do("PTTL", "key1").Int)
+	assert.Equal(t, int64(-1), client.do("TTL", "key3").Int, "Expected -1 for a key without expiration")
+	assert.Equal(t, int64(-2), client.do("PTTL", "key2").Int, "Expected -2 for a missing key")
+}
+
+func TestServerErrors(t *testing.T) {
+	_, addr := startServer(t, lru.NewSafeLRUCache(10))
+	client := dial(t, addr)
+
+	assert.Equal(t, resp.Error, client.do("NOPE").Kind)
+	assert.Equal(t, "ERR wrong number of arguments for 'get' command", client.do("GET").Str)
+	assert.Equal(t, "ERR invalid expire time in 'set' command", client.do("SET", "key", "value", "EX", "-1").Str)
+	assert.Equal(t, "ERR syntax error", client.do("SET", "key", "value", "XX", "1").Str)
+	assert.Equal(t, "PONG", client.do("PING").Str) // The connection is still usable
+}
+
+func TestServerClose(t *testing.T) {
+	server, addr := startServer(t, lru.NewSafeLRUCache(10))
+	client := dial(t, addr)
+	assert.Equal(t, "PONG", client.do("PING").Str)
+
+	assert.NoError(t, server.Close())
+	_, err := client.reader.ReadValue()
+	assert.Error(t, err) // The connection was closed
+	_, err = net.Dial("tcp", addr)
+	assert.Error(t, err)
+}
+
+func TestServerLoader(t *testing.T) {
+	loads := 0
+	_, addr := startServer(t, lru.NewSafeLRUCache(10), WithLoader(func(ctx context.Context, key string) (any, time.Duration, error) {
+		loads++
+		switch key {
+		case "missing":
+			return nil, 0, ErrKeyNotFound
+		case "broken":
+			return nil, 0, errors.New("backend unavailable")
+		}
+		return "loaded-" + key, 0, nil
+	}))
+	client := dial(t, addr)
+
+	assert.Equal(t, "loaded-key1", client.do("GET", "key1").Str)
+	assert.Equal(t, "loaded-key1", client.do("GET", "key1").Str) // Served from the cache
+	assert.True(t, client.do("GET", "missing").Null)
+	assert.Equal(t, "ERR backend unavailable", client.do("GET", "broken").Str)
+	assert.Equal(t, 3, loads)
+}
+
+// timeoutCount reads the number of timeouts of the command.
+func timeoutCount(command string) float64 {
+	var metric dto.Metric
+	timeouts.WithLabelValues(command).Write(&metric)
+	return metric.GetCounter().GetValue()
+}
+
+func TestServerCommandTimeout(t *testing.T) {
+	cancelled := make(chan error, 1)
+	_, addr := startServer(t, lru.NewSafeLRUCache(10), WithCommandTimeout(20*time.Millisecond),
+		WithLoader(func(ctx context.Context, key string) (any, time.Duration, error) {
+			<-ctx.Done() // A backend slower than the deadline
+			cancelled <- ctx.Err()
+			return nil, 0, ctx.Err()
+		}))
+	client := dial(t, addr)
+	before := timeoutCount("get")
+
+	reply := client.do("GET", "slow")
+	assert.Equal(t, resp.Error, reply.Kind)
+	assert.Equal(t, "TIMEOUT 'get' command exceeded its deadline", reply.Str)
+	assert.ErrorIs(t, <-cancelled, context.DeadlineExceeded) // The loader was cancelled
+	assert.Equal(t, before+1, timeoutCount("get"))
+	assert.Equal(t, "PONG", client.do("PING").Str) // The connection is still usable
+}
+
+func TestServerCloseCancelsCommands(t *testing.T) {
+	cancelled := make(chan error, 1)
+	server, addr := startServer(t, lru.NewSafeLRUCache(10), WithLoader(func(ctx context.Context, key string) (any, time.Duration, error) {
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return nil, 0, ctx.Err()
+	}))
+	client := dial(t, addr)
+	client.writer.WriteCommand("GET", "slow")
+	assert.NoError(t, client.writer.Flush())
+	time.Sleep(10 * time.Millisecond) // Let the load start
+
+	assert.NoError(t, server.Close()) // Returns once the load was cancelled
+	assert.ErrorIs(t, <-cancelled, context.Canceled)
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+)
+
+// defaultSlowlogCount is the number of entries returned by SLOWLOG GET without count, the same as Redis.
+const defaultSlowlogCount = 10
+
+// slowlog runs the SLOWLOG subcommands of WithSlowLog:
+//   - SLOWLOG GET [count] returns the latest entries, the newest first, all of them with a negative count.
+//     Every entry is an array of its id, its start as a unix timestamp, its duration in microseconds,
+//     and the operation and its key, like the arguments of the commands in Redis.
+//   - SLOWLOG LEN returns the number of entries.
+//   - SLOWLOG RESET removes the entries.
+func (server *Server) slowlog(session *session, args []string) {
+	writer := session.writer
+	if len(args) < 2 {
+		writeArityError(writer, "SLOWLOG")
+		return
+	}
+	if server.slowLog == nil {
+		writer.WriteError("ERR the slow log is disabled")
+		return
+	}
+
+	switch strings.ToUpper(args[1]) {
+	case "GET":
+		count := defaultSlowlogCount
+		if len(args) > 2 {
+			var err error
+			if count, err = strconv.Atoi(args[2]); err != nil {
+				writer.WriteError("ERR value is not an integer or out of range")
+				return
+			}
+		}
+		entries := server.slowLog.Entries(count)
+		writer.WriteArrayHeader(len(entries))
+		for _, entry := range entries {
+			writer.WriteArrayHeader(4)
+			writer.WriteInteger(entry.ID)
+			writer.WriteInteger(entry.Time.Unix())
+			writer.WriteInteger(entry.Duration.Microseconds())
+			writer.WriteArrayHeader(2)
+			writer.WriteBulkString(entry.Operation)
+			writer.WriteBulkString(entry.Key)
+		}
+	case "LEN":
+		writer.WriteInteger(int64(server.slowLog.Len()))
+	case "RESET":
+		server.slowLog.Reset()
+		writer.WriteSimpleString("OK")
+	default:
+		writer.WriteError("ERR unknown subcommand '" + args[1] + "'")
+	}
+}
+
+// commandKey returns the first argument of a command, usually its key, or an empty string if it has none.
+func commandKey(args []string) string {
+	if len(args) < 2 {
+		return ""
+	}
+	return args[1]
+}
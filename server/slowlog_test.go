@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"caching/lru"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerSlowLog(t *testing.T) {
+	log := lru.NewSlowLog(10, 10*time.Millisecond, 0)
+	_, addr := startServer(t, lru.NewSafeLRUCache(10), WithSlowLog(log), WithLoader(func(ctx context.Context, key string) (any, time.Duration, error) {
+		time.Sleep(20 * time.Millisecond)
+		return "loaded", 0, nil
+	}))
+	client := dial(t, addr)
+
+	client.do("SET", "fast", "value")
+	client.do("GET", "slow") // Records the load, then the command
+	assert.Equal(t, int64(2), client.do("SLOWLOG", "LEN").Int)
+
+	reply := client.do("SLOWLOG", "GET", "1")
+	assert.Len(t, reply.Array, 1)
+	entry := reply.Array[0].Array
+	assert.Equal(t, int64(2), entry[0].Int)
+	assert.GreaterOrEqual(t, entry[2].Int, int64(20000)) // Microseconds
+	assert.Equal(t, "get", entry[3].Array[0].Str)
+	assert.Equal(t, "slow", entry[3].Array[1].Str)
+	assert.Equal(t, lru.SlowLoad, client.do("SLOWLOG", "GET").Array[1].Array[3].Array[0].Str)
+
+	assert.Equal(t, "OK", client.do("SLOWLOG", "RESET").Str)
+	assert.Equal(t, int64(0), client.do("SLOWLOG", "LEN").Int)
+}
+
+func TestServerSlowLogDisabled(t *testing.T) {
+	_, addr := startServer(t, lru.NewSafeLRUCache(10))
+	assert.Equal(t, "ERR the slow log is disabled", dial(t, addr).do("SLOWLOG", "GET").Str)
+}
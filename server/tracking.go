@@ -0,0 +1,149 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// pushQueueSize is the number of invalidations and pub/sub messages waiting to be pushed to a connection.
+// A connection falling further behind is closed, so its client knows its near cache is stale.
+const pushQueueSize = 1024
+
+// tracking remembers which connections read which keys, the way Redis 6 client tracking does:
+// when a key changes, an invalidation is pushed to the connections that read it, then the key
+// is forgotten until they read it again.
+type tracking struct {
+	mutex   sync.Mutex
+	readers map[string]map[int64]struct{} // Ids of the connections to notify by key
+}
+
+// track records that the connection with the given id must be notified when the key changes.
+func (tracking *tracking) track(key string, id int64) {
+	tracking.mutex.Lock()
+	defer tracking.mutex.Unlock()
+
+	readers, found := tracking.readers[key]
+	if !found {
+		readers = make(map[int64]struct{})
+		tracking.readers[key] = readers
+	}
+	readers[id] = struct{}{}
+}
+
+// take returns the connections to notify for the key, and forgets them.
+func (tracking *tracking) take(key string) map[int64]struct{} {
+	tracking.mutex.Lock()
+	defer tracking.mutex.Unlock()
+
+	readers := tracking.readers[key]
+	delete(tracking.readers, key)
+	return readers
+}
+
+// invalidate pushes an invalidation of the keys to the connections that read them.
+// Connections closed since their read are skipped.
+func (server *Server) invalidate(keys []string) {
+	for _, key := range keys {
+		for id := range server.tracking.take(key) {
+			if target := server.session(id); target != nil {
+				server.push(target, pushMessage{invalidated: key})
+			}
+		}
+	}
+}
+
+// pushMessage is a message written to a connection unprompted: the invalidation of a key read
+// with client tracking, or a message of a channel the connection subscribed to.
+type pushMessage struct {
+	invalidated string   // Key pushed as ["invalidate", [key]], when fields is nil
+	fields      []string // Fields of a pub/sub message, written as an array of bulk strings
+}
+
+// push queues a message for the connection, closing it if it is too far behind.
+// The messages are written by a goroutine of their own, so a write never waits for
+// another connection, which could be waiting for this one.
+func (server *Server) push(target *session, message pushMessage) {
+	target.pushesOnce.Do(func() {
+		target.pushes = make(chan pushMessage, pushQueueSize)
+		server.wg.Add(1)
+		go server.pushMessages(target)
+	})
+
+	select {
+	case target.pushes <- message:
+	default:
+		target.conn.Close()
+	}
+}
+
+// pushMessages writes the queued messages of the connection until it is closed: the invalidations
+// as RESP3 push messages, ["invalidate", [key]], and the pub/sub messages as arrays, like Redis does for RESP2.
+func (server *Server) pushMessages(target *session) {
+	defer server.wg.Done()
+	for {
+		select {
+		case message := <-target.pushes:
+			target.writerMutex.Lock()
+			if message.fields == nil {
+				target.writer.WritePushHeader(2)
+				target.writer.WriteBulkString("invalidate")
+				target.writer.WriteArrayHeader(1)
+				target.writer.WriteBulkString(message.invalidated)
+			} else {
+				target.writer.WriteArrayHeader(len(message.fields))
+				for _, field := range message.fields {
+					target.writer.WriteBulkString(field)
+				}
+			}
+			target.writer.Flush()
+			target.writerMutex.Unlock()
+		case <-target.done:
+			return
+		}
+	}
+}
+
+// client runs the CLIENT subcommands:
+//   - CLIENT ID returns the id of the connection.
+//   - CLIENT TRACKING ON [REDIRECT id] notifies the connection, or the one with the given id,
+//     when a key read by this connection changes.
+//   - CLIENT TRACKING OFF stops the notifications.
+func (server *Server) client(session *session, args []string) {
+	writer := session.writer
+	if len(args) < 2 {
+		writeArityError(writer, "CLIENT")
+		return
+	}
+
+	switch strings.ToUpper(args[1]) {
+	case "ID":
+		writer.WriteInteger(session.id)
+	case "TRACKING":
+		server.clientTracking(session, args[2:])
+	default:
+		writer.WriteError("ERR unknown subcommand '" + args[1] + "'")
+	}
+}
+
+// clientTracking runs CLIENT TRACKING ON|OFF [REDIRECT id].
+func (server *Server) clientTracking(session *session, args []string) {
+	writer := session.writer
+	switch {
+	case len(args) == 1 && strings.EqualFold(args[0], "OFF"):
+		session.redirect = 0
+	case len(args) == 1 && strings.EqualFold(args[0], "ON"):
+		session.redirect = session.id
+	case len(args) == 3 && strings.EqualFold(args[0], "ON") && strings.EqualFold(args[1], "REDIRECT"):
+		id, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil || server.session(id) == nil {
+			writer.WriteError("ERR the client ID you want redirect to does not exist")
+			return
+		}
+		session.redirect = id
+	default:
+		writer.WriteError("ERR syntax error")
+		return
+	}
+	writer.WriteSimpleString("OK")
+}
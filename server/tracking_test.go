@@ -0,0 +1,65 @@
+package server
+
+import (
+	"strconv"
+	"testing"
+
+	"caching/internal/resp"
+	"caching/lru"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// invalidation is the push message sent when the key changes.
+func invalidation(key string) resp.Value {
+	return resp.Value{Kind: resp.Push, Array: []resp.Value{
+		{Kind: resp.BulkString, Str: "invalidate"},
+		{Kind: resp.Array, Array: []resp.Value{{Kind: resp.BulkString, Str: key}}},
+	}}
+}
+
+func TestClientTracking(t *testing.T) {
+	_, addr := startServer(t, lru.NewSafeLRUCache(10))
+	reader, writer := dial(t, addr), dial(t, addr)
+
+	assert.Equal(t, "OK", reader.do("CLIENT", "TRACKING", "ON").Str)
+	assert.True(t, reader.do("GET", "key1").Null)
+	assert.Equal(t, "OK", writer.do("SET", "key1", "value1").Str)
+
+	message, err := reader.reader.ReadValue()
+	assert.NoError(t, err)
+	assert.Equal(t, invalidation("key1"), message)
+
+	// The key is forgotten until it is read again
+	assert.Equal(t, "OK", writer.do("SET", "key1", "value2").Str)
+	assert.Equal(t, "value2", reader.do("GET", "key1").Str)
+	assert.Equal(t, int64(1), writer.do("DEL", "key1").Int)
+	message, err = reader.reader.ReadValue()
+	assert.NoError(t, err)
+	assert.Equal(t, invalidation("key1"), message)
+}
+
+func TestClientTrackingRedirect(t *testing.T) {
+	_, addr := startServer(t, lru.NewSafeLRUCache(10))
+	reader, invalidations := dial(t, addr), dial(t, addr)
+
+	id := invalidations.do("CLIENT", "ID")
+	assert.Equal(t, resp.Integer, id.Kind)
+	assert.Equal(t, "OK", reader.do("CLIENT", "TRACKING", "ON", "REDIRECT", strconv.FormatInt(id.Int, 10)).Str)
+	assert.True(t, reader.do("GET", "key1").Null)
+	assert.Equal(t, "OK", reader.do("SET", "key1", "value1").Str)
+
+	message, err := invalidations.reader.ReadValue()
+	assert.NoError(t, err)
+	assert.Equal(t, invalidation("key1"), message)
+}
+
+func TestClientTrackingErrors(t *testing.T) {
+	_, addr := startServer(t, lru.NewSafeLRUCache(10))
+	client := dial(t, addr)
+
+	assert.Equal(t, "ERR the client ID you want redirect to does not exist", client.do("CLIENT", "TRACKING", "ON", "REDIRECT", "42").Str)
+	assert.Equal(t, "ERR syntax error", client.do("CLIENT", "TRACKING", "MAYBE").Str)
+	assert.Equal(t, resp.Error, client.do("CLIENT", "NOPE").Kind)
+	assert.Equal(t, "OK", client.do("CLIENT", "TRACKING", "OFF").Str)
+}
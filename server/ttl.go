@@ -0,0 +1,33 @@
+package server
+
+import (
+	"time"
+
+	"caching/lru"
+)
+
+// ttl runs TTL key and PTTL key, replying the remaining time to live of the key in seconds, rounded like Redis,
+// or in milliseconds: -1 if the key does not expire, -2 if it is missing. The cache must implement lru.TTLReader.
+func (server *Server) ttl(session *session, name string, args []string) {
+	writer := session.writer
+	if len(args) != 2 {
+		writeArityError(writer, name)
+		return
+	}
+	reader, ok := server.cache.(lru.TTLReader)
+	if !ok {
+		writer.WriteError("ERR " + name + " is not supported by the cache")
+		return
+	}
+	ttl, found := reader.TTL(args[1])
+	switch {
+	case !found:
+		writer.WriteInteger(-2)
+	case ttl == 0:
+		writer.WriteInteger(-1)
+	case name == "PTTL":
+		writer.WriteInteger(max(ttl.Milliseconds(), 1)) // An item about to expire is not reported without expiration
+	default:
+		writer.WriteInteger(int64((ttl + time.Second/2) / time.Second))
+	}
+}
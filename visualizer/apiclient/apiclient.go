@@ -0,0 +1,88 @@
+// Package apiclient is a Go client of the API served by visualizer/server, for programmatic use of the demo or of an embedded handler.
+// The operations and their types in client.go are generated from the OpenAPI document served at /openapi.json.
+package apiclient
+
+//go:generate go run ../backend -openapi openapi.json
+//go:generate go run ./internal/apigen -spec openapi.json -out client.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls the operations of the visualizer backend API.
+type Client struct {
+	baseURL    string       // The address serving the API, e.g. http://localhost:8080
+	httpClient *http.Client // The client sending the requests
+}
+
+// New creates a Client for the API served at baseURL, sending the requests with httpClient,
+// or http.DefaultClient if nil.
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: httpClient}
+}
+
+// Error is returned when the API answers with an unexpected status, decoded from its JSON error body.
+type Error struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`              // Machine readable kind of the error, e.g. invalid_payload
+	Message    string `json:"message"`           // Human readable description of the error
+	Details    any    `json:"details,omitempty"` // Context of the error, e.g. the invalid field
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("api: %d %s: %s", err.StatusCode, err.Code, err.Message)
+}
+
+// do sends a request with the JSON encoded body if not nil, checks the status of the response,
+// and decodes its body into result if not nil.
+func (client *Client) do(ctx context.Context, method, path string, query url.Values, body any, status int, result any) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	address := client.baseURL + path
+	if len(query) > 0 {
+		address += "?" + query.Encode()
+	}
+	request, err := http.NewRequestWithContext(ctx, method, address, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != status {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		apiErr := &Error{StatusCode: response.StatusCode}
+		if json.Unmarshal(message, apiErr) != nil || apiErr.Code == "" { // Not an error of the API, e.g. from a proxy
+			apiErr.Code, apiErr.Message = "unknown", strings.TrimSpace(string(message))
+		}
+		return apiErr
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
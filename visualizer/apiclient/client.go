@@ -0,0 +1,315 @@
+// Code generated by apigen from openapi.json. DO NOT EDIT.
+
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// AddRequest is the AddRequest schema of the API.
+type AddRequest struct {
+	Key        string  `json:"key"`
+	TTLSeconds float64 `json:"ttl_seconds,omitempty"`
+	Value      string  `json:"value"`
+}
+
+// AdvanceClockRequest is the AdvanceClockRequest schema of the API.
+type AdvanceClockRequest struct {
+	Seconds float64 `json:"seconds"`
+}
+
+// ClockState is the ClockState schema of the API.
+type ClockState struct {
+	Now time.Time `json:"now"`
+}
+
+// ComparedPolicyState is the ComparedPolicyState schema of the API.
+type ComparedPolicyState struct {
+	HitRatio float64              `json:"hit_ratio"`
+	Hits     int                  `json:"hits"`
+	Misses   int                  `json:"misses"`
+	Name     string               `json:"name"`
+	State    ObservableCacheState `json:"state"`
+}
+
+// ComparisonState is the ComparisonState schema of the API.
+type ComparisonState struct {
+	Policies []ComparedPolicyState `json:"policies"`
+}
+
+// CurvePoint is the CurvePoint schema of the API.
+type CurvePoint struct {
+	Capacity int     `json:"capacity"`
+	HitRatio float64 `json:"hitRatio"`
+}
+
+// ErrorResponse is the ErrorResponse schema of the API.
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Details any    `json:"details,omitempty"`
+	Message string `json:"message"`
+}
+
+// Event is the Event schema of the API.
+type Event struct {
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	Key       string    `json:"key"`
+	Reason    string    `json:"reason,omitempty"`
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+}
+
+// ExpirationResponse is the ExpirationResponse schema of the API.
+type ExpirationResponse struct {
+	Expires    bool    `json:"expires"`
+	Found      bool    `json:"found"`
+	TTLSeconds float64 `json:"ttl_seconds,omitempty"`
+}
+
+// GetResponse is the GetResponse schema of the API.
+type GetResponse struct {
+	Diff  StateDiff            `json:"diff"`
+	Hit   bool                 `json:"hit"`
+	State ObservableCacheState `json:"state"`
+	Value string               `json:"value,omitempty"`
+}
+
+// HealthResponse is the HealthResponse schema of the API.
+type HealthResponse struct {
+	Reason string `json:"reason,omitempty"`
+	Status string `json:"status"`
+}
+
+// KeyRequest is the KeyRequest schema of the API.
+type KeyRequest struct {
+	Key string `json:"key"`
+}
+
+// ObservableCacheItem is the ObservableCacheItem schema of the API.
+type ObservableCacheItem struct {
+	DistanceFromEviction int       `json:"distance_from_eviction"`
+	Expired              bool      `json:"expired,omitempty"`
+	ExpiresAt            time.Time `json:"expires_at"`
+	Frequency            int       `json:"frequency,omitempty"`
+	Key                  string    `json:"key"`
+	Next                 string    `json:"next"`
+	Prev                 string    `json:"prev"`
+	Segment              string    `json:"segment,omitempty"`
+	Value                string    `json:"value,omitempty"`
+}
+
+// ObservableCacheState is the ObservableCacheState schema of the API.
+type ObservableCacheState struct {
+	Capacity int                   `json:"capacity"`
+	Items    []ObservableCacheItem `json:"items"`
+	Policy   string                `json:"policy"`
+	Total    int                   `json:"total"`
+	Version  uint64                `json:"version"`
+}
+
+// SimulationConfig is the SimulationConfig schema of the API.
+type SimulationConfig struct {
+	DurationSeconds float64  `json:"duration_seconds"`
+	Keys            int      `json:"keys"`
+	Pattern         string   `json:"pattern"`
+	Rate            int      `json:"rate"`
+	ReadRatio       *float64 `json:"read_ratio,omitempty"`
+	Trace           []string `json:"trace,omitempty"`
+	TTLSeconds      float64  `json:"ttl_seconds"`
+}
+
+// StateChange is the StateChange schema of the API.
+type StateChange struct {
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	Key       string    `json:"key"`
+	Reason    string    `json:"reason,omitempty"`
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	Version   uint64    `json:"version"`
+}
+
+// StateDiff is the StateDiff schema of the API.
+type StateDiff struct {
+	Added   []ObservableCacheItem `json:"added,omitempty"`
+	Changed []ObservableCacheItem `json:"changed,omitempty"`
+	Removed []string              `json:"removed,omitempty"`
+}
+
+// StateResponse is the StateResponse schema of the API.
+type StateResponse struct {
+	Changes []StateChange         `json:"changes"`
+	State   *ObservableCacheState `json:"state,omitempty"`
+	Version uint64                `json:"version"`
+}
+
+// StatsResponse is the StatsResponse schema of the API.
+type StatsResponse struct {
+	Capacity            int          `json:"capacity"`
+	Evictions           uint64       `json:"evictions"`
+	ExpirationRate      float64      `json:"expirationRate"`
+	Expirations         uint64       `json:"expirations"`
+	GhostHits           uint64       `json:"ghostHits"`
+	HitRatio            float64      `json:"hitRatio"`
+	HitRatioCurve       []CurvePoint `json:"hitRatioCurve,omitempty"`
+	Hits                uint64       `json:"hits"`
+	Len                 int          `json:"len"`
+	LoadWaiters         int          `json:"loadWaiters,omitempty"`
+	Loading             int          `json:"loading,omitempty"`
+	Misses              uint64       `json:"misses"`
+	TTLs                []TTLBucket  `json:"ttls,omitempty"`
+	UpcomingExpirations int          `json:"upcomingExpirations"`
+}
+
+// TTLBucket is the TTLBucket schema of the API.
+type TTLBucket struct {
+	Count      int     `json:"count"`
+	UpperBound float64 `json:"upperBound"`
+}
+
+// AddToCache adds or updates an item.
+func (client *Client) AddToCache(ctx context.Context, body AddRequest) error {
+	return client.do(ctx, "POST", "/add", nil, body, 204, nil)
+}
+
+// GetCacheStateParams are the query parameters of GetCacheState, sent when not nil.
+type GetCacheStateParams struct {
+	Prefix *string // Only return the keys starting with the prefix
+	Offset *int    // Number of matching items to skip
+	Limit  *int    // Maximum number of items to return
+	Values *bool   // Whether to return the values, true by default
+}
+
+// GetCacheState returns a page of the cache state.
+func (client *Client) GetCacheState(ctx context.Context, params GetCacheStateParams) (*ObservableCacheState, error) {
+	query := url.Values{}
+	if params.Prefix != nil {
+		query.Set("prefix", fmt.Sprint(*params.Prefix))
+	}
+	if params.Offset != nil {
+		query.Set("offset", fmt.Sprint(*params.Offset))
+	}
+	if params.Limit != nil {
+		query.Set("limit", fmt.Sprint(*params.Limit))
+	}
+	if params.Values != nil {
+		query.Set("values", fmt.Sprint(*params.Values))
+	}
+	var result ObservableCacheState
+	if err := client.do(ctx, "GET", "/cache", query, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetClock returns the time of the demo clock.
+func (client *Client) GetClock(ctx context.Context) (*ClockState, error) {
+	var result ClockState
+	if err := client.do(ctx, "GET", "/clock", nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AdvanceClock moves the demo clock forward.
+func (client *Client) AdvanceClock(ctx context.Context, body AdvanceClockRequest) (*ClockState, error) {
+	var result ClockState
+	if err := client.do(ctx, "POST", "/clock/advance", nil, body, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetComparison returns the state of the compared policies.
+func (client *Client) GetComparison(ctx context.Context) (*ComparisonState, error) {
+	var result ComparisonState
+	if err := client.do(ctx, "GET", "/compare", nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetFromComparison reads a key from every compared policy.
+func (client *Client) GetFromComparison(ctx context.Context, body KeyRequest) (*ComparisonState, error) {
+	var result ComparisonState
+	if err := client.do(ctx, "POST", "/compare/get", nil, body, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// StreamEvents is not generated, its response is not JSON.
+
+// GetFromCache reads a key, returning the hit and the state diff.
+func (client *Client) GetFromCache(ctx context.Context, body KeyRequest) (*GetResponse, error) {
+	var result GetResponse
+	if err := client.do(ctx, "POST", "/get", nil, body, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetHealth answers the liveness probe.
+func (client *Client) GetHealth(ctx context.Context) (*HealthResponse, error) {
+	var result HealthResponse
+	if err := client.do(ctx, "GET", "/healthz", nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetReadiness answers the readiness probe, failing until the snapshot is restored.
+func (client *Client) GetReadiness(ctx context.Context) (*HealthResponse, error) {
+	var result HealthResponse
+	if err := client.do(ctx, "GET", "/readyz", nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// StartSimulation starts a synthetic workload.
+func (client *Client) StartSimulation(ctx context.Context, body SimulationConfig) (*SimulationConfig, error) {
+	var result SimulationConfig
+	if err := client.do(ctx, "POST", "/simulate", nil, body, 202, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetStateChangesParams are the query parameters of GetStateChanges, sent when not nil.
+type GetStateChangesParams struct {
+	Since *int // The version of the state known by the client
+}
+
+// GetStateChanges returns the changes of the state since a version, or the full state.
+func (client *Client) GetStateChanges(ctx context.Context, params GetStateChangesParams) (*StateResponse, error) {
+	query := url.Values{}
+	if params.Since != nil {
+		query.Set("since", fmt.Sprint(*params.Since))
+	}
+	var result StateResponse
+	if err := client.do(ctx, "GET", "/state", query, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetStats returns the counters and the hit ratio curve of the cache.
+func (client *Client) GetStats(ctx context.Context) (*StatsResponse, error) {
+	var result StatsResponse
+	if err := client.do(ctx, "GET", "/stats", nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetTTL returns the remaining time to live of a key, without promoting it.
+func (client *Client) GetTTL(ctx context.Context, body KeyRequest) (*ExpirationResponse, error) {
+	var result ExpirationResponse
+	if err := client.do(ctx, "POST", "/ttl", nil, body, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
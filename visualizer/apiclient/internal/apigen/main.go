@@ -0,0 +1,258 @@
+// Command apigen generates the types and the operations of the apiclient package from an OpenAPI document.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// document is the subset of an OpenAPI document used by the generator.
+type document struct {
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	OperationID string      `json:"operationId"`
+	Summary     string      `json:"summary"`
+	Parameters  []parameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]media `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Content map[string]media `json:"content"`
+	} `json:"responses"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Schema      *schema `json:"schema"`
+}
+
+type media struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Items                *schema            `json:"items"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Nullable             bool               `json:"nullable"`
+	AdditionalProperties *schema            `json:"additionalProperties"`
+}
+
+// initialisms are the words of the property names written in capitals in Go.
+var initialisms = map[string]string{"id": "ID", "ttl": "TTL", "ttls": "TTLs", "url": "URL"}
+
+// goName converts a property or operation name, in snake or camel case, to an exported Go name.
+func goName(name string) string {
+	var builder strings.Builder
+	for word := range strings.SplitSeq(name, "_") {
+		if initialism, found := initialisms[word]; found {
+			builder.WriteString(initialism)
+			continue
+		}
+		runes := []rune(word)
+		if len(runes) > 0 {
+			runes[0] = unicode.ToUpper(runes[0])
+		}
+		builder.WriteString(string(runes))
+	}
+	return builder.String()
+}
+
+// generator writes the Go code of a document.
+type generator struct {
+	out     bytes.Buffer
+	imports map[string]bool
+}
+
+func (gen *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&gen.out, format, args...)
+}
+
+// goType returns the Go type of the schema, declaring the nested objects inline.
+func (gen *generator) goType(s *schema) string {
+	if s.Ref != "" {
+		return s.Ref[strings.LastIndex(s.Ref, "/")+1:]
+	}
+	switch s.Type {
+	case "boolean":
+		return "bool"
+	case "integer":
+		switch s.Format {
+		case "int64":
+			return "int64"
+		case "uint64":
+			return "uint64"
+		}
+		return "int"
+	case "number":
+		return "float64"
+	case "string":
+		switch s.Format {
+		case "date-time":
+			gen.imports["time"] = true
+			return "time.Time"
+		case "byte":
+			return "[]byte"
+		}
+		return "string"
+	case "array":
+		return "[]" + gen.goType(s.Items)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + gen.goType(s.AdditionalProperties)
+		}
+		return "struct {\n" + gen.fields(s) + "}"
+	}
+	return "any"
+}
+
+// fields returns the fields of an object schema, in the order of their names.
+func (gen *generator) fields(s *schema) string {
+	var fields strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+		tag := name
+		if !slices.Contains(s.Required, name) {
+			tag += ",omitempty"
+		}
+		fieldType := gen.goType(s.Properties[name])
+		if optional := strings.HasSuffix(tag, ",omitempty"); optional && fieldType == "time.Time" {
+			tag = name + ",omitzero"
+		} else if optional && (s.Properties[name].Ref != "" || s.Properties[name].Nullable) {
+			fieldType = "*" + fieldType // Absent objects and nullable values are nil
+		}
+		fmt.Fprintf(&fields, "%s %s `json:%q`\n", goName(name), fieldType, tag)
+	}
+	return fields.String()
+}
+
+// generate writes the types of the components and a method per JSON operation.
+func (gen *generator) generate(doc document) {
+	for _, name := range slices.Sorted(maps.Keys(doc.Components.Schemas)) {
+		gen.printf("// %s is the %s schema of the API.\n", name, name)
+		gen.printf("type %s %s\n\n", name, gen.goType(doc.Components.Schemas[name]))
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(doc.Paths)) {
+		for _, method := range slices.Sorted(maps.Keys(doc.Paths[path])) {
+			gen.operation(path, strings.ToUpper(method), doc.Paths[path][method])
+		}
+	}
+}
+
+// operation writes the method calling the operation, and the struct of its query parameters if any.
+// The operations not exchanging JSON, e.g. event streams, are skipped.
+func (gen *generator) operation(path, method string, op operation) {
+	name := goName(op.OperationID)
+
+	status, result := "", ""
+	for code, response := range op.Responses {
+		if code == "default" {
+			continue
+		}
+		status = code
+		if len(response.Content) > 0 {
+			content, found := response.Content["application/json"]
+			if !found {
+				gen.printf("// %s is not generated, its response is not JSON.\n\n", name)
+				return
+			}
+			result = gen.goType(content.Schema)
+		}
+	}
+
+	signature := "ctx context.Context"
+	query := "nil"
+	if len(op.Parameters) > 0 {
+		gen.printf("// %sParams are the query parameters of %s, sent when not nil.\n", name, name)
+		gen.printf("type %sParams struct {\n", name)
+		for _, parameter := range op.Parameters {
+			gen.printf("%s *%s // %s\n", goName(parameter.Name), gen.goType(parameter.Schema), parameter.Description)
+		}
+		gen.printf("}\n\n")
+		signature += fmt.Sprintf(", params %sParams", name)
+		query = "query"
+	}
+	body := "nil"
+	if op.RequestBody != nil {
+		signature += ", body " + gen.goType(op.RequestBody.Content["application/json"].Schema)
+		body = "body"
+	}
+
+	summary := strings.ToLower(op.Summary[:1]) + op.Summary[1:]
+	gen.printf("// %s %s.\n", name, strings.TrimSuffix(summary, "."))
+	if result != "" {
+		gen.printf("func (client *Client) %s(%s) (*%s, error) {\n", name, signature, result)
+	} else {
+		gen.printf("func (client *Client) %s(%s) error {\n", name, signature)
+	}
+	if query != "nil" {
+		gen.imports["fmt"] = true
+		gen.imports["net/url"] = true
+		gen.printf("query := url.Values{}\n")
+		for _, parameter := range op.Parameters {
+			gen.printf("if params.%s != nil {\nquery.Set(%q, fmt.Sprint(*params.%s))\n}\n", goName(parameter.Name), parameter.Name, goName(parameter.Name))
+		}
+	}
+	call := fmt.Sprintf("client.do(ctx, %q, %q, %s, %s, %s", method, path, query, body, status)
+	if result == "" {
+		gen.printf("return %s, nil)\n}\n\n", call)
+		return
+	}
+	gen.printf("var result %s\n", result)
+	gen.printf("if err := %s, &result); err != nil {\nreturn nil, err\n}\n", call)
+	gen.printf("return &result, nil\n}\n\n")
+}
+
+func main() {
+	specPath := flag.String("spec", "openapi.json", "The OpenAPI document to generate the client from")
+	outPath := flag.String("out", "client.go", "The Go file to write")
+	pkg := flag.String("package", "apiclient", "The package of the generated file")
+	flag.Parse()
+
+	spec, err := os.ReadFile(*specPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		log.Fatalf("invalid OpenAPI document: %v", err)
+	}
+
+	gen := &generator{imports: map[string]bool{"context": true}}
+	gen.generate(doc)
+
+	var file bytes.Buffer
+	fmt.Fprintf(&file, "// Code generated by apigen from %s. DO NOT EDIT.\n\npackage %s\n\nimport (\n", *specPath, *pkg)
+	for _, path := range slices.Sorted(maps.Keys(gen.imports)) {
+		fmt.Fprintf(&file, "%q\n", path)
+	}
+	fmt.Fprintf(&file, ")\n\n")
+	file.Write(gen.out.Bytes())
+
+	source, err := format.Source(file.Bytes())
+	if err != nil {
+		log.Fatalf("invalid generated code: %v\n%s", err, file.Bytes())
+	}
+	if err := os.WriteFile(*outPath, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,68 +1,124 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"caching/admin"
 	"caching/lru"
+	"caching/visualizer/server"
 )
 
-func withCORS(h http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Allow all origins (you can restrict this if needed)
-		// It is overly permissive, used only for demo purposes
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		// Handle preflight request
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
+func main() {
+	openAPIPath := flag.String("openapi", "", "Write the OpenAPI document of the API to this file and exit, e.g. to generate the client")
+	restoreDir := flag.String("restore-dir", "", "Directory of the snapshots written by cacheserver -backup-dir, the latest being restored at startup")
+	selfTest := flag.Bool("ready-self-test", false, "Make /readyz run a set, get and remove round trip on the cache")
+	debug := flag.Bool("debug", false, "Serve the pprof profiles under /debug/pprof/ and the cache internals under /debug/cache")
+	debugToken := flag.String("debug-token", "", "Bearer token required by the debug endpoints, empty to not require one")
+	shadowStaleness := flag.Duration("shadow-state", 0, "Serve the state from a copy of the items updated from the events, at most this stale, instead of locking the cache, zero to lock it")
+	keyStats := flag.Int("key-stats", 0, "Keys whose hits and evictions are retained and served under /debug/keys, zero to not track them")
+	dump := flag.Bool("dump", false, "Serve the items of the cache under /dump as JSON lines or CSV, restricted by -debug-token")
+	rateLimit := flag.Bool("rate-limit", true, "Limit the writes and simulations of every client, disable for local load tests")
+	flag.Parse()
 
-		h.ServeHTTP(w, r)
+	// The demo runs on a manual clock, so TTL expiry is driven through /clock/advance
+	clock := lru.NewManualClock(time.Now())
+	// Every key is sampled for the hit ratio curve, the demo cache being tiny
+	// The previews are cut, so a large value does not bloat every state of the visualizer
+	opts := []lru.Option{lru.WithClock(clock), lru.WithGhostList(50), lru.WithHitRatioCurve(1, 20), lru.WithValueRenderer(lru.RenderJSON), lru.WithMaxValueLength(256)}
+	if *shadowStaleness > 0 {
+		opts = append(opts, lru.WithShadowState(*shadowStaleness))
 	}
-}
+	var stats *lru.KeyStats
+	if *keyStats > 0 {
+		stats = lru.NewKeyStats(*keyStats, time.Hour, clock)
+		opts = append(opts, lru.WithEventListener(stats.Record))
+	}
+	observable := lru.NewObservableCache(5, opts...)
+
+	// Add a few example values
+	observable.Cache.Set("foo", "bar")
+	observable.Cache.SetWithTTL("baz", "qux", time.Minute)
 
-func cacheHandler(cache *lru.ObservableCache) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		state := cache.State()
+	// The demo cache is registered like any cache of an application, to show the registry in the visualizer
+	admin.Register("demo", observable.Cache)
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(state)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	serverOpts := []server.Option{
+		server.WithDemoClock(clock),
+		server.WithComparison(lru.WithClock(clock)),
+		server.WithSimulations(),
+		server.WithSnapshotDir(*restoreDir),
+		server.WithLogger(logger),
+		server.WithRegistry(admin.DefaultRegistry, admin.WithValueRenderer(lru.RenderJSON)),
 	}
-}
+	if *selfTest {
+		serverOpts = append(serverOpts, server.WithReadySelfTest())
+	}
+	if *debug {
+		serverOpts = append(serverOpts, server.WithDebug(*debugToken))
+	}
+	if stats != nil {
+		serverOpts = append(serverOpts, server.WithKeyStats(stats))
+	}
+	if *dump {
+		serverOpts = append(serverOpts, server.WithDump(*debugToken, lru.RenderJSON))
+	}
+	if !*rateLimit {
+		serverOpts = append(serverOpts, server.WithoutRateLimits())
+	}
+	handler := server.NewHandler(observable, serverOpts...)
 
-func addToCacheHandler(cache *lru.ObservableCache) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var payload struct {
-			Key   string `json:"key"`
-			Value string `json:"value"`
+	if *openAPIPath != "" {
+		document, err := handler.OpenAPI()
+		if err == nil {
+			err = os.WriteFile(*openAPIPath, document, 0o644)
 		}
-		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-			http.Error(w, "invalid payload", http.StatusBadRequest)
-			return
+		if err != nil {
+			slog.Error("failed to write the OpenAPI document", slog.Any("error", err))
+			os.Exit(1)
 		}
-		if payload.Key == "" || payload.Value == "" {
-			http.Error(w, "key and value must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	httpServer := &http.Server{Addr: ":8080", Handler: handler}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() { // Served meanwhile, /readyz failing until done
+		if err := handler.Restore(ctx); err != nil {
+			logger.Error("restoring the snapshot failed", slog.Any("error", err))
 			return
 		}
+		logger.Info("ready", slog.Int("items", observable.Cache.Len()))
+	}()
 
-		cache.Cache.Set(payload.Key, payload.Value)
-		w.WriteHeader(http.StatusNoContent)
-	}
-}
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		logger.Info("shutting down")
 
-func main() {
-	observable := lru.NewObservableCache(5)
+		// Close the event streams first, otherwise Shutdown would wait for them forever
+		handler.Close()
+		observable.Close()
 
-	// Add a few example values
-	observable.Cache.Set("foo", "bar")
-	observable.Cache.SetWithTTL("baz", "qux", time.Minute)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
 
-	http.HandleFunc("/cache", withCORS(cacheHandler(observable)))
-	http.HandleFunc("/add", withCORS(addToCacheHandler(observable)))
-	http.ListenAndServe(":8080", nil)
+	logger.Info("listening", slog.String("addr", httpServer.Addr))
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("server stopped", slog.Any("error", err))
+		os.Exit(1)
+	}
+	<-shutdownDone // Wait for the in-flight requests to complete
 }
@@ -1,15 +1,116 @@
-export async function fetchCacheState() {
-    const res = await fetch("http://localhost:8080/cache", { method: "GET" });
-    if (!res.ok) throw new Error("Failed to fetch cache");
+// apiError returns the error described by the JSON error body {code, message, details} of the response.
+async function apiError(res: Response, fallback: string) {
+    try {
+        const body = await res.json();
+        return new Error(body.message ? `${fallback}: ${body.message}` : fallback);
+    } catch {
+        return new Error(fallback);
+    }
+}
+
+export async function fetchCacheState(query: {
+    prefix?: string;
+    offset?: number;
+    limit?: number;
+    values?: boolean;
+} = {}) {
+    const params = new URLSearchParams();
+    for (const [name, value] of Object.entries(query)) {
+        if (value !== undefined) params.set(name, String(value));
+    }
+    const res = await fetch(`http://localhost:8080/cache?${params}`, { method: "GET" });
+    if (!res.ok) throw await apiError(res, "Failed to fetch cache");
+    return res.json();
+}
+
+export async function fetchStateChanges(since?: number) {
+    const query = since === undefined ? "" : `?since=${since}`;
+    const res = await fetch(`http://localhost:8080/state${query}`, { method: "GET" });
+    if (!res.ok) throw await apiError(res, "Failed to fetch state changes");
     return res.json();
 }
 
-export async function addToCache(key: string, value: any) {
+export async function addToCache(key: string, value: any, ttlSeconds?: number) {
     const res = await fetch("http://localhost:8080/add", {
         method: "POST",
         headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ key, value }),
+        body: JSON.stringify({ key, value, ttl_seconds: ttlSeconds }),
     });
-    if (!res.ok) throw new Error("Failed to add to cache");
+    if (!res.ok) throw await apiError(res, "Failed to add to cache");
     return;
-}
\ No newline at end of file
+}
+
+export async function getFromCache(key: string) {
+    const res = await fetch("http://localhost:8080/get", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ key }),
+    });
+    if (!res.ok) throw await apiError(res, "Failed to get from cache");
+    return res.json();
+}
+
+export async function fetchComparison() {
+    const res = await fetch("http://localhost:8080/compare", { method: "GET" });
+    if (!res.ok) throw await apiError(res, "Failed to fetch comparison");
+    return res.json();
+}
+
+export async function getFromComparison(key: string) {
+    const res = await fetch("http://localhost:8080/compare/get", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ key }),
+    });
+    if (!res.ok) throw await apiError(res, "Failed to get from comparison");
+    return res.json();
+}
+
+export async function advanceClock(seconds: number) {
+    const res = await fetch("http://localhost:8080/clock/advance", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ seconds }),
+    });
+    if (!res.ok) throw await apiError(res, "Failed to advance clock");
+    return res.json();
+}
+
+export async function startSimulation(config: {
+    pattern?: "uniform" | "zipf" | "scan" | "trace";
+    keys?: number;
+    trace?: string[];
+    rate?: number;
+    duration_seconds?: number;
+    read_ratio?: number;
+    ttl_seconds?: number;
+}) {
+    const res = await fetch("http://localhost:8080/simulate", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(config),
+    });
+    if (!res.ok) throw await apiError(res, "Failed to start simulation");
+    return res.json();
+}
+
+export function subscribeToEvents(onEvent: (event: { type: string; key: string; reason?: string; time: string }) => void) {
+    const source = new EventSource("http://localhost:8080/events");
+    const types = ["hit", "miss", "added", "updated", "removed"];
+    types.forEach((type) => source.addEventListener(type, (e) => onEvent(JSON.parse((e as MessageEvent).data))));
+    return () => source.close();
+}
+
+export async function fetchRegisteredCaches(): Promise<{ name: string; len: number; capacity: number; capabilities: string[] }[]> {
+    const res = await fetch("http://localhost:8080/caches");
+    if (!res.ok) throw await apiError(res, "Failed to fetch registered caches");
+    return res.json();
+}
+
+export async function fetchRegisteredCacheItems(name: string, query: { prefix?: string; offset?: number; limit?: number } = {}) {
+    const params = new URLSearchParams();
+    Object.entries(query).forEach(([key, value]) => value !== undefined && params.set(key, String(value)));
+    const res = await fetch(`http://localhost:8080/caches/${encodeURIComponent(name)}/items?${params}`);
+    if (!res.ok) throw await apiError(res, "Failed to fetch cache items");
+    return res.json();
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"caching/lru"
+)
+
+// withCORS allows the visualizer served from another origin to call the handler.
+func withCORS(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Allow all origins (you can restrict this if needed)
+		// It is overly permissive, used only for demo purposes
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Request-ID, If-None-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, ETag, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After")
+
+		// Handle preflight request
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	}
+}
+
+// cacheHandler returns the state of the cache, filtered by the prefix parameter and paginated by offset and limit.
+// Setting values=false omits the values of the items.
+func cacheHandler(cache *lru.ObservableCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		query := lru.StateQuery{Prefix: params.Get("prefix"), OmitValues: params.Get("values") == "false"}
+		for name, value := range map[string]*int{"offset": &query.Offset, "limit": &query.Limit} {
+			if params.Has(name) {
+				parsed, err := strconv.Atoi(params.Get(name))
+				if err != nil || parsed < 0 {
+					invalidParameter(w, r, name, name+" must be a non-negative integer")
+					return
+				}
+				*value = parsed
+			}
+		}
+		writeJSONWithETag(w, r, cache.StatePage(query))
+	}
+}
+
+// statsResponse is the response of statsHandler.
+type statsResponse struct {
+	lru.Stats
+	HitRatio float64 `json:"hitRatio"`
+}
+
+// statsHandler returns the counters of the cache, with its estimated hit ratio curve to guide its sizing.
+func statsHandler(cache *lru.ObservableCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := cache.Cache.Stats()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(statsResponse{stats, stats.HitRatio()})
+	}
+}
+
+// addRequest is the payload of addToCacheHandler.
+type addRequest struct {
+	Key        string  `json:"key"`
+	Value      string  `json:"value"`
+	TTLSeconds float64 `json:"ttl_seconds,omitempty"` // Optional TTL of the item, zero for no expiration
+}
+
+// addToCacheHandler adds or updates an item, mirroring the write into the compared policies if any.
+func addToCacheHandler(cache *lru.ObservableCache, comparison *policyComparison) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload addRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		var errs fieldErrors
+		errs.checkKey("key", payload.Key)
+		errs.check(payload.Value != "", "value", "value must not be empty")
+		errs.check(len(payload.Value) <= maxValueLength, "value", "value must not exceed %d bytes", maxValueLength)
+		errs.checkSeconds("ttl_seconds", payload.TTLSeconds, maxTTL)
+		if errs.write(w, r) {
+			return
+		}
+
+		if ttl := time.Duration(payload.TTLSeconds * float64(time.Second)); ttl > 0 {
+			cache.Cache.SetWithTTL(payload.Key, payload.Value, ttl)
+			comparison.SetWithTTL(payload.Key, payload.Value, ttl)
+		} else {
+			cache.Cache.Set(payload.Key, payload.Value)
+			comparison.Set(payload.Key, payload.Value) // Mirror the operation into the compared policies
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"caching/lru"
+)
+
+type clockState struct {
+	Now time.Time `json:"now"`
+}
+
+// advanceClockRequest is the payload of advanceClockHandler.
+type advanceClockRequest struct {
+	Seconds float64 `json:"seconds"`
+}
+
+// clockHandler returns the current time of the demo clock.
+func clockHandler(clock *lru.ManualClock) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(clockState{Now: clock.Now()})
+	}
+}
+
+// advanceClockHandler moves the demo clock forward, so TTL expiry can be shown instantly.
+func advanceClockHandler(clock *lru.ManualClock) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload advanceClockRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		var errs fieldErrors
+		errs.check(payload.Seconds > 0, "seconds", "seconds must be positive")
+		errs.checkSeconds("seconds", payload.Seconds, maxClockAdvance)
+		if errs.write(w, r) {
+			return
+		}
+
+		now := clock.Advance(time.Duration(payload.Seconds * float64(time.Second)))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(clockState{Now: now})
+	}
+}
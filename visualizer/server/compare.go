@@ -0,0 +1,175 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"caching/lru"
+)
+
+// comparedPolicy holds one of the caches of a policyComparison along with its hit statistics.
+type comparedPolicy struct {
+	name   string               // Name of the eviction policy, e.g. "lru"
+	cache  *lru.ObservableCache // The cache using this policy
+	hits   int                  // Number of Get operations that found the key
+	misses int                  // Number of Get operations that did not find the key
+}
+
+// policyComparison mirrors every operation into several caches with different eviction policies,
+// so the frontend can show how they diverge on the same input sequence.
+type policyComparison struct {
+	mutex    sync.Mutex // Keeps the caches and their statistics in step
+	policies []*comparedPolicy
+}
+
+type comparedPolicyState struct {
+	Name     string                   `json:"name"`
+	State    lru.ObservableCacheState `json:"state"`
+	Hits     int                      `json:"hits"`
+	Misses   int                      `json:"misses"`
+	HitRatio float64                  `json:"hit_ratio"`
+}
+
+type comparisonState struct {
+	Policies []comparedPolicyState `json:"policies"`
+}
+
+func newPolicyComparison(capacity int, opts ...lru.Option) *policyComparison {
+	return &policyComparison{
+		policies: []*comparedPolicy{
+			{name: "lru", cache: lru.NewObservableCache(capacity, opts...)},
+			{name: "lfu", cache: lru.NewObservableCacheFrom(lru.NewSafe(lru.NewLFUCache(capacity, opts...)))},
+			{name: "tlru", cache: lru.NewObservableCacheFrom(lru.NewSafe(lru.NewTLRUCache(capacity, opts...)))},
+			{name: "s3fifo", cache: lru.NewObservableCacheFrom(lru.NewSafe(lru.NewS3FIFOCache(capacity, opts...)))},
+		},
+	}
+}
+
+// Set adds or updates the key in every compared cache, if any.
+func (comparison *policyComparison) Set(key string, value any) {
+	if comparison == nil {
+		return
+	}
+	comparison.mutex.Lock()
+	defer comparison.mutex.Unlock()
+
+	for _, policy := range comparison.policies {
+		policy.cache.Cache.Set(key, value)
+	}
+}
+
+// SetWithTTL adds or updates the key expiring after ttl in every compared cache, if any.
+func (comparison *policyComparison) SetWithTTL(key string, value any, ttl time.Duration) {
+	if comparison == nil {
+		return
+	}
+	comparison.mutex.Lock()
+	defer comparison.mutex.Unlock()
+
+	for _, policy := range comparison.policies {
+		policy.cache.Cache.SetWithTTL(key, value, ttl)
+	}
+}
+
+// Get looks up the key in every compared cache, recording hits and misses per policy.
+func (comparison *policyComparison) Get(key string) {
+	comparison.mutex.Lock()
+	defer comparison.mutex.Unlock()
+
+	for _, policy := range comparison.policies {
+		if _, found := policy.cache.Cache.Get(key); found {
+			policy.hits++
+		} else {
+			policy.misses++
+		}
+	}
+}
+
+// Access replays one operation of a cache-aside workload in every compared cache, if any:
+// a read looks up the key, recording the hit or the miss, and fills it in on a miss, while a write sets it.
+func (comparison *policyComparison) Access(key string, value any, ttl time.Duration, read bool) {
+	if comparison == nil {
+		return
+	}
+	comparison.mutex.Lock()
+	defer comparison.mutex.Unlock()
+
+	for _, policy := range comparison.policies {
+		if read {
+			if _, found := policy.cache.Cache.Get(key); found {
+				policy.hits++
+				continue
+			}
+			policy.misses++
+		}
+		if ttl > 0 {
+			policy.cache.Cache.SetWithTTL(key, value, ttl)
+		} else {
+			policy.cache.Cache.Set(key, value)
+		}
+	}
+}
+
+// State returns the state and hit ratio of every compared cache.
+func (comparison *policyComparison) State() comparisonState {
+	comparison.mutex.Lock()
+	defer comparison.mutex.Unlock()
+
+	states := make([]comparedPolicyState, 0, len(comparison.policies))
+	for _, policy := range comparison.policies {
+		hitRatio := 0.0
+		if total := policy.hits + policy.misses; total > 0 {
+			hitRatio = float64(policy.hits) / float64(total)
+		}
+		states = append(states, comparedPolicyState{
+			Name:     policy.name,
+			State:    policy.cache.State(),
+			Hits:     policy.hits,
+			Misses:   policy.misses,
+			HitRatio: hitRatio,
+		})
+	}
+	return comparisonState{Policies: states}
+}
+
+// DebugInfo returns the sizes of the internal structures of every compared cache, nil without comparison.
+func (comparison *policyComparison) DebugInfo() []lru.DebugInfo {
+	if comparison == nil {
+		return nil
+	}
+	comparison.mutex.Lock()
+	defer comparison.mutex.Unlock()
+
+	infos := make([]lru.DebugInfo, 0, len(comparison.policies))
+	for _, policy := range comparison.policies {
+		infos = append(infos, policy.cache.DebugInfo())
+	}
+	return infos
+}
+
+func compareHandler(comparison *policyComparison) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(comparison.State())
+	}
+}
+
+func compareGetHandler(comparison *policyComparison) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload keyRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		var errs fieldErrors
+		errs.checkKey("key", payload.Key)
+		if errs.write(w, r) {
+			return
+		}
+
+		comparison.Get(payload.Key)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(comparison.State())
+	}
+}
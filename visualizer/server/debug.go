@@ -0,0 +1,140 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+
+	"caching/lru"
+)
+
+// debugResponse is the response of debugCacheHandler.
+type debugResponse struct {
+	Cache       lru.DebugInfo   `json:"cache"`
+	Comparison  []lru.DebugInfo `json:"comparison,omitempty"` // The caches of the compared policies, if any
+	Goroutines  int             `json:"goroutines"`
+	HeapAlloc   uint64          `json:"heap_alloc"`   // Bytes of allocated heap objects
+	HeapObjects uint64          `json:"heap_objects"` // Number of allocated heap objects
+	NumGC       uint32          `json:"num_gc"`       // Number of completed GC cycles
+}
+
+// debugCacheHandler returns the sizes of the internal structures of the caches along with the runtime memory statistics.
+func debugCacheHandler(cache *lru.ObservableCache, comparison *policyComparison) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var memory runtime.MemStats
+		runtime.ReadMemStats(&memory)
+		response := debugResponse{
+			Cache:       cache.DebugInfo(),
+			Comparison:  comparison.DebugInfo(),
+			Goroutines:  runtime.NumGoroutine(),
+			HeapAlloc:   memory.HeapAlloc,
+			HeapObjects: memory.HeapObjects,
+			NumGC:       memory.NumGC,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// debugSlowLogHandler returns the latest entries of the slow log, the newest first,
+// as many as the count query parameter if set.
+func debugSlowLogHandler(log *lru.SlowLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		count := -1
+		if r.URL.Query().Has("count") {
+			var err error
+			if count, err = strconv.Atoi(r.URL.Query().Get("count")); err != nil || count < 0 {
+				writeError(w, r, http.StatusBadRequest, errorResponse{Code: codeInvalidParameter, Message: "count must be a non-negative integer"})
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(log.Entries(count))
+	}
+}
+
+// debugKeysHandler returns the keys hit the most within the window of the key stats, or the keys evicted the most
+// with by=evictions, as many as the count query parameter, 100 by default.
+func debugKeysHandler(stats *lru.KeyStats) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		count := 100
+		if r.URL.Query().Has("count") {
+			var err error
+			if count, err = strconv.Atoi(r.URL.Query().Get("count")); err != nil || count < 0 {
+				writeError(w, r, http.StatusBadRequest, errorResponse{Code: codeInvalidParameter, Message: "count must be a non-negative integer"})
+				return
+			}
+		}
+		var keys []lru.KeyStat
+		switch r.URL.Query().Get("by") {
+		case "", "hits":
+			keys = stats.TopHits(count)
+		case "evictions":
+			keys = stats.TopEvictions(count)
+		default:
+			writeError(w, r, http.StatusBadRequest, errorResponse{Code: codeInvalidParameter, Message: "by must be hits or evictions"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(keys)
+	}
+}
+
+// dumpHandler writes the items of the cache as JSON lines, or as CSV with format=csv.
+func dumpHandler(cache *lru.ObservableCache, renderer lru.ValueRenderer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, contentType := lru.DumpJSONLines, "application/x-ndjson"
+		switch r.URL.Query().Get("format") {
+		case "", string(lru.DumpJSONLines):
+		case string(lru.DumpCSV):
+			format, contentType = lru.DumpCSV, "text/csv"
+		default:
+			writeError(w, r, http.StatusBadRequest, errorResponse{Code: codeInvalidParameter, Message: "format must be jsonl or csv"})
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", "attachment; filename=cache."+string(format))
+		cache.Cache.Snapshot().Dump(w, format, renderer) // The status is sent, a failure can only cut the body
+	}
+}
+
+// withDebugToken rejects the requests not bearing the token, unless it is empty.
+func withDebugToken(token string, h http.Handler) http.Handler {
+	if token == "" {
+		return h
+	}
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, r, http.StatusUnauthorized, errorResponse{Code: codeUnauthorized, Message: "missing or invalid bearer token"})
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// registerDebug serves the pprof profiles under /debug/pprof/, the cache internals under /debug/cache,
+// the slow log under /debug/slowlog and the key stats under /debug/keys if not nil, restricted to the requests
+// bearing the token if not empty.
+func registerDebug(mux *http.ServeMux, token string, cache *lru.ObservableCache, comparison *policyComparison, slowLog *lru.SlowLog, keyStats *lru.KeyStats) {
+	mux.Handle("/debug/pprof/", withDebugToken(token, http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", withDebugToken(token, http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", withDebugToken(token, http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", withDebugToken(token, http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", withDebugToken(token, http.HandlerFunc(pprof.Trace)))
+	mux.Handle("/debug/cache", withDebugToken(token, debugCacheHandler(cache, comparison)))
+	if slowLog != nil {
+		mux.Handle("/debug/slowlog", withDebugToken(token, debugSlowLogHandler(slowLog)))
+	}
+	if keyStats != nil {
+		mux.Handle("/debug/keys", withDebugToken(token, debugKeysHandler(keyStats)))
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"caching/lru"
+)
+
+func TestDump(t *testing.T) {
+	cache := lru.NewObservableCache(10, lru.WithoutMetrics())
+	cache.Cache.Set("user:1", "alice")
+	cache.Cache.Set("token:1", "secret")
+	renderer := lru.Redact(nil, func(key string) bool { return strings.HasPrefix(key, "token:") })
+	handler := NewHandler(cache, WithDump("token", renderer))
+
+	response := serve(handler, http.MethodGet, "/dump", "")
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.Equal(t, "Bearer", response.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, codeUnauthorized, decodeError(t, response).Code)
+
+	response = serve(handler, http.MethodGet, "/dump", "", "Authorization", "Bearer token")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "application/x-ndjson", response.Header().Get("Content-Type"))
+	assert.Contains(t, response.Body.String(), `"key":"user:1","value":"alice"`)
+	assert.Contains(t, response.Body.String(), `"key":"token:1","value":"[redacted]"`)
+	assert.NotContains(t, response.Body.String(), "secret")
+
+	response = serve(handler, http.MethodGet, "/dump?format=csv", "", "Authorization", "Bearer token")
+	assert.Equal(t, "text/csv", response.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(response.Body.String(), "key,value,expires_at\n"))
+
+	response = serve(handler, http.MethodGet, "/dump?format=xml", "", "Authorization", "Bearer token")
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Equal(t, codeInvalidParameter, decodeError(t, response).Code)
+}
+
+func TestDebugKeys(t *testing.T) {
+	clock := lru.NewManualClock(time.Now())
+	stats := lru.NewKeyStats(10, time.Minute, clock)
+	for _, key := range []string{"a", "b", "a"} {
+		stats.Record(lru.Event{Type: lru.EventHit, Key: key, Time: clock.Now()})
+	}
+	stats.Record(lru.Event{Type: lru.EventRemoved, Key: "b", Reason: "evicted", Time: clock.Now()})
+	handler := NewHandler(lru.NewObservableCache(10, lru.WithClock(clock), lru.WithoutMetrics()), WithDebug("token"), WithKeyStats(stats))
+
+	keys := func(query string) []lru.KeyStat {
+		response := serve(handler, http.MethodGet, "/debug/keys"+query, "", "Authorization", "Bearer token")
+		assert.Equal(t, http.StatusOK, response.Code)
+		var body []lru.KeyStat
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+		return body
+	}
+	hottest := keys("?count=1")
+	assert.Len(t, hottest, 1)
+	assert.Equal(t, "a", hottest[0].Key)
+	assert.Equal(t, uint64(2), hottest[0].Hits)
+	evicted := keys("?by=evictions")
+	assert.Equal(t, "b", evicted[0].Key)
+	assert.Equal(t, uint64(1), evicted[0].Evictions)
+
+	response := serve(handler, http.MethodGet, "/debug/keys?by=misses", "", "Authorization", "Bearer token")
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Equal(t, codeInvalidParameter, decodeError(t, response).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(handler, http.MethodGet, "/debug/keys", "").Code)
+}
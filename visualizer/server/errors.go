@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Error codes of errorResponse, one per kind of failure.
+const (
+	codeInvalidPayload       = "invalid_payload"        // 400, the body is not valid JSON of the expected shape
+	codeInvalidParameter     = "invalid_parameter"      // 400, a query parameter is malformed
+	codeValidation           = "validation_failed"      // 422, the request is well-formed but its values are not accepted, see fieldError
+	codeUnauthorized         = "unauthorized"           // 401, the credentials are missing or wrong
+	codeConflict             = "conflict"               // 409, the request conflicts with the current state
+	codeRateLimited          = "rate_limited"           // 429, the client exceeded its rate limit, see Retry-After
+	codePayloadTooLarge      = "payload_too_large"      // 413, the body exceeds maxBodyBytes
+	codeUnsupportedMediaType = "unsupported_media_type" // 415, the body is not JSON
+	codeInternal             = "internal"               // 500, the server failed
+)
+
+// errorResponse is the body of every error of the API.
+type errorResponse struct {
+	Code    string `json:"code"`              // Machine readable kind of the error, e.g. invalid_payload
+	Message string `json:"message"`           // Human readable description of the error
+	Details any    `json:"details,omitempty"` // Context of the error, e.g. the invalid field
+}
+
+// writeError writes the error with the status, as JSON unless the client only accepts plain text.
+func writeError(w http.ResponseWriter, r *http.Request, status int, response errorResponse) {
+	w.Header().Del("ETag")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if !acceptsJSON(r.Header.Get("Accept")) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "%s: %s\n", response.Code, response.Message)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// acceptsJSON returns whether the Accept header allows a JSON response, which is the default without header.
+func acceptsJSON(accept string) bool {
+	if accept == "" {
+		return true
+	}
+	for mediaRange := range strings.SplitSeq(accept, ",") {
+		mediaType, _, _ := strings.Cut(mediaRange, ";")
+		switch strings.TrimSpace(mediaType) {
+		case "application/json", "application/*", "*/*":
+			return true
+		}
+	}
+	return false
+}
+
+// invalidPayload writes the error of a body failing to decode.
+func invalidPayload(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, http.StatusBadRequest, errorResponse{Code: codeInvalidPayload, Message: "invalid payload", Details: err.Error()})
+}
+
+// invalidParameter writes the error of a malformed query parameter.
+func invalidParameter(w http.ResponseWriter, r *http.Request, name, message string) {
+	writeError(w, r, http.StatusBadRequest, errorResponse{Code: codeInvalidParameter, Message: message, Details: map[string]string{"parameter": name}})
+}
+
+// internalError writes the error of a failure of the server.
+func internalError(w http.ResponseWriter, r *http.Request, message string) {
+	writeError(w, r, http.StatusInternalServerError, errorResponse{Code: codeInternal, Message: message})
+}
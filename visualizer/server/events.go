@@ -0,0 +1,46 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"caching/lru"
+)
+
+// eventsHandler streams the cache events to the client as Server-Sent Events,
+// letting the frontend animate operations as they happen instead of polling.
+func eventsHandler(cache *lru.ObservableCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			internalError(w, r, "streaming unsupported")
+			return
+		}
+
+		events, unsubscribe := cache.Subscribe(64)
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		flusher.Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case event, open := <-events:
+				if !open {
+					return
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+				flusher.Flush()
+			}
+		}
+	}
+}
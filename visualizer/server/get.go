@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"caching/lru"
+)
+
+// stateDiff is the change of the cache state made by an operation, so the frontend can animate it.
+type stateDiff struct {
+	Added   []lru.ObservableCacheItem `json:"added,omitempty"`   // Items absent before the operation
+	Removed []string                  `json:"removed,omitempty"` // Keys absent after the operation, e.g. expired on access
+	Changed []lru.ObservableCacheItem `json:"changed,omitempty"` // Items moved or updated by the operation, in their new state
+}
+
+// diffStates returns the items added, removed and changed from the state before to the state after.
+func diffStates(before, after lru.ObservableCacheState) stateDiff {
+	previous := make(map[string]lru.ObservableCacheItem, len(before.Items))
+	for _, item := range before.Items {
+		previous[item.Key] = item
+	}
+
+	var diff stateDiff
+	for _, item := range after.Items {
+		old, found := previous[item.Key]
+		delete(previous, item.Key)
+		switch {
+		case !found:
+			diff.Added = append(diff.Added, item)
+		case !reflect.DeepEqual(old, item):
+			diff.Changed = append(diff.Changed, item)
+		}
+	}
+	for _, item := range before.Items { // Keep the order of the state
+		if _, removed := previous[item.Key]; removed {
+			diff.Removed = append(diff.Removed, item.Key)
+		}
+	}
+	return diff
+}
+
+// keyRequest is the payload of the handlers reading a key.
+type keyRequest struct {
+	Key string `json:"key"`
+}
+
+// getResponse is the response of getHandler.
+type getResponse struct {
+	Hit   bool                     `json:"hit"`
+	Value string                   `json:"value,omitempty"` // Rendered like the values of the state, see lru.WithValueRenderer
+	Diff  stateDiff                `json:"diff"`
+	State lru.ObservableCacheState `json:"state"`
+}
+
+// getHandler reads a key through the observable cache, returning whether it hit along with the state diff,
+// to demonstrate the promotion of the accessed items. Concurrent writes may show up in the diff.
+func getHandler(cache *lru.ObservableCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload keyRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		var errs fieldErrors
+		errs.checkKey("key", payload.Key)
+		if errs.write(w, r) {
+			return
+		}
+
+		before := cache.State()
+		value, found := cache.Cache.Get(payload.Key)
+		after := cache.State()
+
+		response := getResponse{Hit: found, Diff: diffStates(before, after), State: after}
+		if found {
+			response.Value = cache.RenderValue(payload.Key, value)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// expirationResponse is the response of ttlHandler.
+type expirationResponse struct {
+	Found      bool    `json:"found"`
+	Expires    bool    `json:"expires"`               // Whether the item has a ttl
+	TTLSeconds float64 `json:"ttl_seconds,omitempty"` // Remaining time to live of the item
+}
+
+// ttlHandler returns the remaining time to live of a key without promoting it, e.g. so a client refreshes
+// a value about to expire.
+func ttlHandler(cache *lru.ObservableCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload keyRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		var errs fieldErrors
+		errs.checkKey("key", payload.Key)
+		if errs.write(w, r) {
+			return
+		}
+
+		ttl, found := cache.Cache.TTL(payload.Key)
+		response := expirationResponse{Found: found, Expires: ttl > 0, TTLSeconds: ttl.Seconds()}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"caching/lru"
+)
+
+func TestGet(t *testing.T) {
+	cache := lru.NewObservableCache(10, lru.WithoutMetrics())
+	handler := NewHandler(cache)
+	cache.Cache.Set("a", "1")
+	cache.Cache.Set("b", "2")
+
+	response := serve(handler, http.MethodPost, "/get", `{"key":"a"}`)
+	assert.Equal(t, http.StatusOK, response.Code)
+	var hit getResponse
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&hit))
+	assert.True(t, hit.Hit)
+	assert.Equal(t, "1", hit.Value)
+	assert.Empty(t, hit.Diff.Added)
+	assert.Empty(t, hit.Diff.Removed)
+	assert.Equal(t, "a", hit.State.Items[0].Key, "Expected the read to promote the key")
+
+	response = serve(handler, http.MethodPost, "/get", `{"key":"missing"}`)
+	var miss getResponse
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&miss))
+	assert.False(t, miss.Hit)
+	assert.Empty(t, miss.Value)
+
+	response = serve(handler, http.MethodPost, "/get", `{"key":""}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+}
+
+func TestTTL(t *testing.T) {
+	clock := lru.NewManualClock(time.Now())
+	cache := lru.NewObservableCache(10, lru.WithClock(clock), lru.WithoutMetrics())
+	handler := NewHandler(cache)
+	cache.Cache.SetWithTTL("a", "1", time.Minute)
+	cache.Cache.Set("b", "2")
+	clock.Advance(15 * time.Second)
+
+	ttl := func(key string) expirationResponse {
+		response := serve(handler, http.MethodPost, "/ttl", `{"key":"`+key+`"}`)
+		assert.Equal(t, http.StatusOK, response.Code)
+		var body expirationResponse
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+		return body
+	}
+	assert.Equal(t, expirationResponse{Found: true, Expires: true, TTLSeconds: 45}, ttl("a"))
+	assert.Equal(t, expirationResponse{Found: true}, ttl("b"))
+	assert.Equal(t, expirationResponse{}, ttl("missing"))
+}
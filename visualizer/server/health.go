@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"caching/lru"
+	"caching/persist"
+)
+
+// selfTestKey is the key written and removed by the readiness self-test.
+const selfTestKey = "__readyz__"
+
+// healthResponse is the response of the health and readiness probes.
+type healthResponse struct {
+	Status string `json:"status"`           // "ok", or "unavailable" when not ready
+	Reason string `json:"reason,omitempty"` // Why the backend is not ready
+}
+
+// readiness tracks whether the backend can serve traffic: its snapshot restored, not shutting down,
+// and its cache answering the self-test if enabled.
+type readiness struct {
+	cache    *lru.ObservableCache
+	selfTest bool // Whether each probe runs a set, get and remove round trip on the cache
+
+	mutex    sync.Mutex // Protects the fields below
+	restored bool       // Whether the snapshot restore completed, successfully or not
+	err      error      // Why the restore failed, if it did
+	draining bool       // Whether the backend is shutting down
+}
+
+// restore loads the latest snapshot of the directory into the cache, the backend being ready once done.
+// Without directory, the backend is ready immediately.
+func (ready *readiness) restore(ctx context.Context, dir string) error {
+	var err error
+	if dir != "" {
+		err = persist.Restore(ctx, persist.NewDirStore(dir), ready.cache.Cache, lru.StringCodec{})
+		if errors.Is(err, persist.ErrBlobNotFound) {
+			err = nil // Nothing to restore yet
+		}
+	}
+
+	ready.mutex.Lock()
+	defer ready.mutex.Unlock()
+
+	ready.restored, ready.err = true, err
+	return err
+}
+
+// drain marks the backend as not ready, so the load balancers stop routing to it before it shuts down.
+func (ready *readiness) drain() {
+	ready.mutex.Lock()
+	defer ready.mutex.Unlock()
+
+	ready.draining = true
+}
+
+// check returns why the backend is not ready, or nil if it is.
+func (ready *readiness) check() error {
+	ready.mutex.Lock()
+	restored, err, draining := ready.restored, ready.err, ready.draining
+	ready.mutex.Unlock()
+
+	switch {
+	case draining:
+		return errors.New("shutting down")
+	case !restored:
+		return errors.New("restoring the snapshot")
+	case err != nil:
+		return fmt.Errorf("restoring the snapshot failed: %w", err)
+	case ready.selfTest:
+		return ready.roundTrip()
+	}
+	return nil
+}
+
+// roundTrip sets, gets and removes a key on the cache, checking it answers consistently.
+// The write may evict an item of a full cache, which is why the self-test is opt-in.
+func (ready *readiness) roundTrip() error {
+	value := strconv.FormatInt(time.Now().UnixNano(), 10)
+	defer ready.cache.Cache.Remove(selfTestKey)
+
+	if result := ready.cache.Cache.Set(selfTestKey, value); result.Status != lru.SetAdded && result.Status != lru.SetUpdated {
+		return fmt.Errorf("self-test set failed with status %v", result.Status)
+	}
+	if read, found := ready.cache.Cache.Get(selfTestKey); !found || read != value {
+		return errors.New("self-test get did not return the value set")
+	}
+	return nil
+}
+
+// healthHandler answers the liveness probe, the backend being alive as long as it serves requests.
+func healthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(healthResponse{Status: "ok"})
+	}
+}
+
+// readyHandler answers the readiness probe, with 503 Service Unavailable and the reason while not ready.
+func readyHandler(ready *readiness) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := ready.check(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(healthResponse{Status: "unavailable", Reason: err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(healthResponse{Status: "ok"})
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+
+	"caching/lru"
+	"caching/ratelimit"
+)
+
+const (
+	rateLimitClients = 10000 // Clients tracked at once, the least recently seen being forgotten first
+
+	addBurst      = 20       // Writes a client can make at once
+	addRate       = 5.0      // Writes per second a client can sustain
+	simulateBurst = 2        // Simulations a client can start at once
+	simulateRate  = 1.0 / 30 // Simulations per second a client can sustain
+)
+
+// rateLimits limits the requests of every client to the endpoints writing to the shared demo cache,
+// so a single client can't flood it. The limits use the system clock, not the demo clock.
+type rateLimits struct {
+	add      func(http.Handler) http.Handler // Limits /add
+	simulate func(http.Handler) http.Handler // Limits /simulate
+}
+
+func newRateLimits() *rateLimits {
+	store := lru.NewSafeLRUCache(rateLimitClients, lru.WithoutMetrics())
+	rejected := ratelimit.WithRejectedHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, http.StatusTooManyRequests, errorResponse{Code: codeRateLimited, Message: "too many requests, retry later"})
+	}))
+	return &rateLimits{
+		add:      ratelimit.Middleware(ratelimit.NewTokenBucket(store, addBurst, addRate), clientKey("add"), rejected),
+		simulate: ratelimit.Middleware(ratelimit.NewTokenBucket(store, simulateBurst, simulateRate), clientKey("simulate"), rejected),
+	}
+}
+
+// clientKey limits the requests by client IP, separately for every endpoint sharing the store.
+func clientKey(endpoint string) ratelimit.KeyFunc {
+	return func(r *http.Request) string {
+		return endpoint + ":" + ratelimit.ClientIP(r)
+	}
+}
+
+// unlimited is the middleware of the endpoints without rate limit.
+func unlimited(h http.Handler) http.Handler {
+	return h
+}
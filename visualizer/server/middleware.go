@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// requestIDFromContext returns the request ID stored by withRequestID, if any.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// withRequestID propagates the X-Request-ID header of the request, generating one if missing.
+// The ID is echoed in the response and stored in the request context for logging.
+func withRequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if id == "" {
+			id = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// newRequestID generates a random 16 character hexadecimal ID.
+func newRequestID() string {
+	buffer := make([]byte, 8)
+	rand.Read(buffer)
+	return hex.EncodeToString(buffer)
+}
+
+// statusRecorder captures the status code and size of a response for logging.
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int  // Status code written, defaults to 200
+	bytes   int  // Number of body bytes written
+	written bool // Whether the header was written, so no other status can be
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.written = true
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Write(data []byte) (int, error) {
+	recorder.written = true
+	n, err := recorder.ResponseWriter.Write(data)
+	recorder.bytes += n
+	return n, err
+}
+
+// Flush lets streaming handlers such as /events flush through the recorder.
+func (recorder *statusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
+// withLogging writes a structured log line for every request with its status and latency.
+func withLogging(logger *slog.Logger, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		h.ServeHTTP(recorder, r)
+
+		logger.LogAttrs(r.Context(), slog.LevelInfo, "request",
+			slog.String("request_id", requestIDFromContext(r.Context())),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", recorder.status),
+			slog.Int("bytes", recorder.bytes),
+			slog.Duration("latency", time.Since(start)),
+		)
+	})
+}
+
+// withRecovery turns a panic in a handler into a 500 response instead of crashing the server.
+// If the handler already started its response, the status can no longer change and the response is left as is.
+func withRecovery(logger *slog.Logger, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			if err := recover(); err != nil {
+				if err == http.ErrAbortHandler {
+					panic(err) // Let net/http abort the response as intended
+				}
+
+				logger.LogAttrs(r.Context(), slog.LevelError, "panic",
+					slog.String("request_id", requestIDFromContext(r.Context())),
+					slog.Any("error", err),
+					slog.String("stack", string(debug.Stack())),
+				)
+				if !recorder.written {
+					internalError(w, r, "internal server error")
+				}
+			}
+		}()
+
+		h.ServeHTTP(recorder, r)
+	})
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// apiParameter is a query parameter of an apiRoute.
+type apiParameter struct {
+	name        string
+	kind        string // The OpenAPI type of the parameter: "string", "integer" or "boolean"
+	description string
+}
+
+// apiRoute is an endpoint of the API along with the types it exchanges, from which the OpenAPI document is generated.
+type apiRoute struct {
+	method      string
+	path        string
+	operationID string // Name of the operation, e.g. the method of the generated client
+	summary     string
+	query       []apiParameter
+	request     any  // A value of the type of the JSON request body, nil without body
+	response    any  // A value of the type of the response body, nil without body
+	status      int  // The status of a successful response, http.StatusOK by default
+	stream      bool // Whether the response is a stream of Server-Sent Events, each holding a response
+}
+
+// apiRouter registers the routes on a mux, keeping their definitions to describe them in an OpenAPI document.
+type apiRouter struct {
+	mux    *http.ServeMux
+	routes []apiRoute
+}
+
+// handle registers the handler of the route, allowing cross-origin requests.
+func (router *apiRouter) handle(route apiRoute, handler http.HandlerFunc) {
+	router.mux.HandleFunc(route.path, withCORS(handler))
+	router.routes = append(router.routes, route)
+}
+
+// document returns the OpenAPI document describing the registered routes.
+func (router *apiRouter) document() map[string]any {
+	schemas := &schemaBuilder{components: make(map[string]any)}
+	paths := make(map[string]map[string]any)
+	for _, route := range router.routes {
+		operation := map[string]any{
+			"operationId": route.operationID,
+			"summary":     route.summary,
+		}
+
+		if len(route.query) > 0 {
+			parameters := make([]any, 0, len(route.query))
+			for _, parameter := range route.query {
+				parameters = append(parameters, map[string]any{
+					"name":        parameter.name,
+					"in":          "query",
+					"description": parameter.description,
+					"schema":      map[string]any{"type": parameter.kind},
+				})
+			}
+			operation["parameters"] = parameters
+		}
+		if route.request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": schemas.schema(reflect.TypeOf(route.request))}},
+			}
+		}
+
+		status := route.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		response := map[string]any{"description": http.StatusText(status)}
+		if route.response != nil {
+			contentType := "application/json"
+			if route.stream {
+				contentType = "text/event-stream"
+			}
+			response["content"] = map[string]any{contentType: map[string]any{"schema": schemas.schema(reflect.TypeOf(route.response))}}
+		}
+		operation["responses"] = map[string]any{
+			strconv.Itoa(status): response,
+			"default":            map[string]any{"description": "Error", "content": map[string]any{"application/json": map[string]any{"schema": schemas.schema(reflect.TypeFor[errorResponse]())}}},
+		}
+
+		if paths[route.path] == nil {
+			paths[route.path] = make(map[string]any)
+		}
+		paths[route.path][strings.ToLower(route.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi":    "3.0.3",
+		"info":       map[string]any{"title": "Cache visualizer API", "version": "1.0.0"},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas.components},
+	}
+}
+
+// openAPIHandler serves the OpenAPI document of the router.
+func openAPIHandler(router *apiRouter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(router.document())
+	}
+}
+
+// openAPIDocument encodes the OpenAPI document of the router, indented for the diffs of the generated files.
+func openAPIDocument(router *apiRouter) ([]byte, error) {
+	document, err := json.MarshalIndent(router.document(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(document, '\n'), nil
+}
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	durationType = reflect.TypeFor[time.Duration]()
+)
+
+// schemaBuilder derives the JSON schemas of Go types following the encoding/json rules,
+// collecting the named structs as components referenced by the other schemas.
+type schemaBuilder struct {
+	components map[string]any
+}
+
+// schema returns the schema of the type.
+func (builder *schemaBuilder) schema(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == durationType:
+		return map[string]any{"type": "integer", "format": "int64", "description": "Nanoseconds"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return builder.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "uint64", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": builder.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": builder.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return builder.object(t)
+		}
+		name := exportedName(t.Name())
+		if _, found := builder.components[name]; !found {
+			builder.components[name] = nil // Reserved first, for the recursive types
+			builder.components[name] = builder.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default: // Interfaces hold any value
+		return map[string]any{}
+	}
+}
+
+// object returns the schema of a struct, whose embedded structs are flattened.
+func (builder *schemaBuilder) object(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	builder.fields(t, properties, &required)
+
+	object := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
+
+// fields adds the properties of the fields of the struct, and their names to required unless omitted when empty.
+func (builder *schemaBuilder) fields(t reflect.Type, properties map[string]any, required *[]string) {
+	for _, field := range reflect.VisibleFields(t) {
+		if len(field.Index) > 1 || !field.IsExported() && !field.Anonymous {
+			continue // Promoted fields are added with their embedded struct
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			builder.fields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := builder.schema(field.Type)
+		if _, ref := property["$ref"]; field.Type.Kind() == reflect.Pointer && !ref {
+			property["nullable"] = true // Tells an omitted value apart from its zero value, e.g. a read ratio of 0
+		}
+		properties[name] = property
+		if !strings.Contains(options, "omitempty") && !strings.Contains(options, "omitzero") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// exportedName capitalizes the name of a type, e.g. clockState becomes ClockState.
+func exportedName(name string) string {
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(first)) + name[size:]
+}
@@ -0,0 +1,297 @@
+// Package server serves the visualizer and admin API of an lru.ObservableCache over HTTP:
+// its state and its changes, its stats, its events, reads and writes, and health probes.
+// NewHandler returns an http.Handler to mount into any mux, e.g. to inspect the caches of a production service:
+//
+//	mux.Handle("/cache-admin/", http.StripPrefix("/cache-admin", server.NewHandler(observable)))
+//
+// The demo features, such as the manual clock, the policy comparison and the simulations, are enabled by options.
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"caching/admin"
+	"caching/lru"
+)
+
+// options holds the optional configuration of a Handler.
+type options struct {
+	clock        *lru.ManualClock // Clock driven by /clock/advance, nil to not serve the clock endpoints
+	compare      bool             // Whether to serve the policy comparison
+	compareOpts  []lru.Option     // Options of the compared caches
+	simulations  bool             // Whether to serve /simulate
+	rateLimits   bool             // Whether to limit the writes and simulations of every client
+	debug        bool             // Whether to serve pprof and the cache internals
+	debugToken   string           // Bearer token required by the debug endpoints, empty for none
+	slowLog      *lru.SlowLog     // Served under /debug/slowlog with WithDebug, nil to not serve it
+	keyStats     *lru.KeyStats    // Served under /debug/keys with WithDebug, nil to not serve it
+	snapshotDir  string           // Directory of the snapshot restored by Restore, empty for none
+	selfTest     bool             // Whether /readyz runs a round trip on the cache
+	logger       *slog.Logger     // Logs every request, nil to not log them
+	registry     *admin.Registry  // Registered caches served under /caches, nil to not serve them
+	registryOpts []admin.Option   // Options of the handler serving the registered caches
+
+	dump       bool              // Whether to serve /dump
+	dumpToken  string            // Bearer token required by /dump, empty for none
+	dumpValues lru.ValueRenderer // Renders the values of /dump, nil to format them with %v
+}
+
+// Option configures a Handler.
+type Option func(*options)
+
+// WithDemoClock serves /clock and /clock/advance, moving the manual clock of the cache forward to show TTL expiry instantly.
+func WithDemoClock(clock *lru.ManualClock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithComparison serves /compare and /compare/get, mirroring the writes into an LRU and an LFU cache
+// of the capacity of the observed cache, created with opts, to show how the policies diverge.
+func WithComparison(opts ...lru.Option) Option {
+	return func(o *options) {
+		o.compare = true
+		o.compareOpts = opts
+	}
+}
+
+// WithSimulations serves /simulate, running synthetic workloads against the cache.
+func WithSimulations() Option {
+	return func(o *options) {
+		o.simulations = true
+	}
+}
+
+// WithoutRateLimits disables the per-client rate limits of /add and /simulate, e.g. for local load tests
+// or when the handler is only reachable by trusted clients.
+func WithoutRateLimits() Option {
+	return func(o *options) {
+		o.rateLimits = false
+	}
+}
+
+// WithDebug serves the pprof profiles under /debug/pprof/ and the cache internals under /debug/cache,
+// restricted to the requests bearing the token if not empty.
+func WithDebug(token string) Option {
+	return func(o *options) {
+		o.debug = true
+		o.debugToken = token
+	}
+}
+
+// WithSlowLog serves the latest entries of the slow log under /debug/slowlog, along with the other
+// debug endpoints of WithDebug, e.g. the log given to the caches and loaders with lru.WithSlowLog.
+func WithSlowLog(log *lru.SlowLog) Option {
+	return func(o *options) {
+		o.slowLog = log
+	}
+}
+
+// WithKeyStats serves the hottest and the most frequently evicted keys under /debug/keys, along with the other
+// debug endpoints of WithDebug. The stats must record the events of the cache, see lru.KeyStats.Record.
+func WithKeyStats(stats *lru.KeyStats) Option {
+	return func(o *options) {
+		o.keyStats = stats
+	}
+}
+
+// WithDump serves the items of the cache under /dump, as JSON lines or as CSV with format=csv, so support engineers
+// can capture the contents of the cache during an incident. It is restricted to the requests bearing the token
+// if not empty, and the values are rendered by renderer, e.g. lru.Redact to hide the sensitive ones, or with %v if nil.
+func WithDump(token string, renderer lru.ValueRenderer) Option {
+	return func(o *options) {
+		o.dump = true
+		o.dumpToken = token
+		o.dumpValues = renderer
+	}
+}
+
+// WithSnapshotDir makes /readyz fail until Restore loaded the latest snapshot of the directory,
+// as written by cacheserver -backup-dir.
+func WithSnapshotDir(dir string) Option {
+	return func(o *options) {
+		o.snapshotDir = dir
+	}
+}
+
+// WithReadySelfTest makes /readyz run a set, get and remove round trip on the cache.
+func WithReadySelfTest() Option {
+	return func(o *options) {
+		o.selfTest = true
+	}
+}
+
+// WithLogger logs every request with its status and latency, along with the panics of the handlers.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithRegistry serves the caches of the registry under /caches, see admin.NewHandler configured by opts,
+// so the visualizer can inspect the caches of the application along with the observed one.
+func WithRegistry(registry *admin.Registry, opts ...admin.Option) Option {
+	return func(o *options) {
+		o.registry = registry
+		o.registryOpts = opts
+	}
+}
+
+// Handler serves the API of an ObservableCache.
+type Handler struct {
+	handler     http.Handler
+	router      *apiRouter
+	ready       *readiness
+	sim         *simulator // Nil without WithSimulations
+	snapshotDir string
+}
+
+var _ http.Handler = (*Handler)(nil) // Ensure Handler can be mounted into a mux
+
+// NewHandler creates a Handler serving the API of the cache. The cache remains owned by the caller, who closes it.
+func NewHandler(cache *lru.ObservableCache, opts ...Option) *Handler {
+	o := options{rateLimits: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var comparison *policyComparison
+	if o.compare {
+		comparison = newPolicyComparison(cache.Cache.Capacity(), o.compareOpts...)
+	}
+	limits := &rateLimits{add: unlimited, simulate: unlimited}
+	if o.rateLimits {
+		limits = newRateLimits()
+	}
+	h := &Handler{
+		router:      &apiRouter{mux: http.NewServeMux()},
+		ready:       &readiness{cache: cache, selfTest: o.selfTest, restored: o.snapshotDir == ""},
+		snapshotDir: o.snapshotDir,
+	}
+
+	router := h.router
+	router.handle(apiRoute{
+		method: http.MethodGet, path: "/cache", operationID: "getCacheState", summary: "Returns a page of the cache state",
+		query: []apiParameter{
+			{name: "prefix", kind: "string", description: "Only return the keys starting with the prefix"},
+			{name: "offset", kind: "integer", description: "Number of matching items to skip"},
+			{name: "limit", kind: "integer", description: "Maximum number of items to return"},
+			{name: "values", kind: "boolean", description: "Whether to return the values, true by default"},
+		},
+		response: lru.ObservableCacheState{},
+	}, cacheHandler(cache))
+	router.handle(apiRoute{
+		method: http.MethodGet, path: "/state", operationID: "getStateChanges", summary: "Returns the changes of the state since a version, or the full state",
+		query:    []apiParameter{{name: "since", kind: "integer", description: "The version of the state known by the client"}},
+		response: stateResponse{},
+	}, stateHandler(cache))
+	router.handle(apiRoute{
+		method: http.MethodGet, path: "/stats", operationID: "getStats", summary: "Returns the counters and the hit ratio curve of the cache",
+		response: statsResponse{},
+	}, statsHandler(cache))
+	router.handle(apiRoute{
+		method: http.MethodPost, path: "/add", operationID: "addToCache", summary: "Adds or updates an item",
+		request: addRequest{}, status: http.StatusNoContent,
+	}, limits.add(addToCacheHandler(cache, comparison)).ServeHTTP)
+	router.handle(apiRoute{
+		method: http.MethodPost, path: "/get", operationID: "getFromCache", summary: "Reads a key, returning the hit and the state diff",
+		request: keyRequest{}, response: getResponse{},
+	}, getHandler(cache))
+	router.handle(apiRoute{
+		method: http.MethodPost, path: "/ttl", operationID: "getTTL", summary: "Returns the remaining time to live of a key, without promoting it",
+		request: keyRequest{}, response: expirationResponse{},
+	}, ttlHandler(cache))
+	if comparison != nil {
+		router.handle(apiRoute{
+			method: http.MethodGet, path: "/compare", operationID: "getComparison", summary: "Returns the state of the compared policies",
+			response: comparisonState{},
+		}, compareHandler(comparison))
+		router.handle(apiRoute{
+			method: http.MethodPost, path: "/compare/get", operationID: "getFromComparison", summary: "Reads a key from every compared policy",
+			request: keyRequest{}, response: comparisonState{},
+		}, compareGetHandler(comparison))
+	}
+	if o.clock != nil {
+		router.handle(apiRoute{
+			method: http.MethodGet, path: "/clock", operationID: "getClock", summary: "Returns the time of the demo clock",
+			response: clockState{},
+		}, clockHandler(o.clock))
+		router.handle(apiRoute{
+			method: http.MethodPost, path: "/clock/advance", operationID: "advanceClock", summary: "Moves the demo clock forward",
+			request: advanceClockRequest{}, response: clockState{},
+		}, advanceClockHandler(o.clock))
+	}
+	router.handle(apiRoute{
+		method: http.MethodGet, path: "/events", operationID: "streamEvents", summary: "Streams the cache events",
+		response: lru.Event{}, stream: true,
+	}, eventsHandler(cache))
+	if o.simulations {
+		h.sim = newSimulator(cache, comparison)
+		router.handle(apiRoute{
+			method: http.MethodPost, path: "/simulate", operationID: "startSimulation", summary: "Starts a synthetic workload",
+			request: simulationConfig{}, response: simulationConfig{}, status: http.StatusAccepted,
+		}, limits.simulate(simulateHandler(h.sim)).ServeHTTP)
+	}
+	router.handle(apiRoute{
+		method: http.MethodGet, path: "/healthz", operationID: "getHealth", summary: "Answers the liveness probe",
+		response: healthResponse{},
+	}, healthHandler())
+	router.handle(apiRoute{
+		method: http.MethodGet, path: "/readyz", operationID: "getReadiness", summary: "Answers the readiness probe, failing until the snapshot is restored",
+		response: healthResponse{},
+	}, readyHandler(h.ready))
+	router.mux.HandleFunc("/openapi.json", withCORS(openAPIHandler(router)))
+	if o.registry != nil {
+		registered := withCORS(admin.NewHandler(o.registry, o.registryOpts...).ServeHTTP)
+		router.mux.HandleFunc("/caches", registered)
+		router.mux.HandleFunc("/caches/", registered)
+	}
+	if o.debug {
+		registerDebug(router.mux, o.debugToken, cache, comparison, o.slowLog, o.keyStats)
+	}
+	if o.dump {
+		router.mux.Handle("/dump", withDebugToken(o.dumpToken, dumpHandler(cache, o.dumpValues)))
+	}
+
+	// Request IDs are assigned first so both the logs and the panic reports include them
+	logger := o.logger
+	if logger == nil {
+		logger = slog.Default() // The panics are always reported
+	}
+	var handler http.Handler = withRecovery(logger, router.mux)
+	if o.logger != nil {
+		handler = withLogging(o.logger, handler)
+	}
+	h.handler = withRequestID(handler)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+// Restore loads the latest snapshot of the directory set by WithSnapshotDir into the cache, /readyz succeeding once done.
+// It does nothing without snapshot directory.
+func (h *Handler) Restore(ctx context.Context) error {
+	if h.snapshotDir == "" {
+		return nil
+	}
+	return h.ready.restore(ctx, h.snapshotDir)
+}
+
+// OpenAPI returns the OpenAPI document of the served API, also served at /openapi.json.
+func (h *Handler) OpenAPI() ([]byte, error) {
+	return openAPIDocument(h.router)
+}
+
+// Close fails the readiness probe, so the load balancers stop routing to the handler before its server shuts down,
+// and stops the running simulation if any. The event streams end when the cache is closed.
+func (h *Handler) Close() error {
+	h.ready.drain()
+	if h.sim != nil {
+		return h.sim.Close()
+	}
+	return nil
+}
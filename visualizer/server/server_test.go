@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"caching/lru"
+)
+
+// serve sends a request to the handler, with a JSON body if not empty, and the headers given as name and value pairs.
+func serve(handler http.Handler, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		request.Header.Set(headers[i], headers[i+1])
+	}
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	return response
+}
+
+// decodeError decodes the structured error body of the response.
+func decodeError(t *testing.T, response *httptest.ResponseRecorder) errorResponse {
+	t.Helper()
+	var body errorResponse
+	assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+	return body
+}
+
+func TestCacheStateETag(t *testing.T) {
+	handler := NewHandler(lru.NewObservableCache(10, lru.WithoutMetrics()))
+
+	response := serve(handler, http.MethodGet, "/cache", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	etag := response.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	response = serve(handler, http.MethodGet, "/cache", "", "If-None-Match", etag)
+	assert.Equal(t, http.StatusNotModified, response.Code)
+	assert.Empty(t, response.Body.String())
+	response = serve(handler, http.MethodGet, "/cache", "", "If-None-Match", "W/"+etag)
+	assert.Equal(t, http.StatusNotModified, response.Code, "Expected a weak comparison")
+
+	assert.Equal(t, http.StatusNoContent, serve(handler, http.MethodPost, "/add", `{"key":"a","value":"1"}`).Code)
+	response = serve(handler, http.MethodGet, "/cache", "", "If-None-Match", etag)
+	assert.Equal(t, http.StatusOK, response.Code, "Expected a changed state to be sent again")
+	assert.NotEqual(t, etag, response.Header().Get("ETag"))
+}
+
+func TestValidationErrors(t *testing.T) {
+	handler := NewHandler(lru.NewObservableCache(10, lru.WithoutMetrics()))
+
+	response := serve(handler, http.MethodPost, "/add", `{"key":"","value":"1","ttl_seconds":-1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	body := decodeError(t, response)
+	assert.Equal(t, codeValidation, body.Code)
+	assert.Equal(t, "key must not be empty, and 1 more invalid fields", body.Message)
+	assert.Equal(t, []any{
+		map[string]any{"field": "key", "message": "key must not be empty"},
+		map[string]any{"field": "ttl_seconds", "message": "ttl_seconds must be between 0 and 86400"},
+	}, body.Details)
+
+	response = serve(handler, http.MethodPost, "/add", `{"key":"a","value":"1","unknown":true}`)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Equal(t, codeInvalidPayload, decodeError(t, response).Code)
+
+	response = serve(handler, http.MethodPost, "/get", `{"key":`+`"`+strings.Repeat("a", maxBodyBytes)+`"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+	assert.Equal(t, codePayloadTooLarge, decodeError(t, response).Code)
+
+	response = serve(handler, http.MethodGet, "/cache?limit=-1", "")
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	body = decodeError(t, response)
+	assert.Equal(t, codeInvalidParameter, body.Code)
+	assert.Equal(t, map[string]any{"parameter": "limit"}, body.Details)
+}
+
+func TestErrorBodies(t *testing.T) {
+	handler := NewHandler(lru.NewObservableCache(10, lru.WithoutMetrics()))
+
+	request := httptest.NewRequest(http.MethodPost, "/add", strings.NewReader(`{"key":"a","value":"1"}`))
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request) // No Content-Type
+	assert.Equal(t, http.StatusUnsupportedMediaType, response.Code)
+	assert.Equal(t, errorResponse{Code: codeUnsupportedMediaType, Message: "the body must be application/json"}, decodeError(t, response))
+	assert.Equal(t, "nosniff", response.Header().Get("X-Content-Type-Options"))
+
+	response = serve(handler, http.MethodPost, "/add", `{"key":""}`, "Accept", "text/plain")
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", response.Header().Get("Content-Type"))
+	assert.Equal(t, "validation_failed: key must not be empty, and 1 more invalid fields\n", response.Body.String())
+}
+
+func TestRateLimits(t *testing.T) {
+	handler := NewHandler(lru.NewObservableCache(10, lru.WithoutMetrics()))
+	for range addBurst {
+		assert.Equal(t, http.StatusNoContent, serve(handler, http.MethodPost, "/add", `{"key":"a","value":"1"}`).Code)
+	}
+
+	response := serve(handler, http.MethodPost, "/add", `{"key":"a","value":"1"}`)
+	assert.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.NotEmpty(t, response.Header().Get("Retry-After"))
+	assert.Equal(t, codeRateLimited, decodeError(t, response).Code)
+
+	unlimited := NewHandler(lru.NewObservableCache(10, lru.WithoutMetrics()), WithoutRateLimits())
+	for range addBurst + 1 {
+		assert.Equal(t, http.StatusNoContent, serve(unlimited, http.MethodPost, "/add", `{"key":"a","value":"1"}`).Code)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	handler := NewHandler(lru.NewObservableCache(10, lru.WithoutMetrics()), WithSnapshotDir(t.TempDir()), WithReadySelfTest())
+	readiness := func() (int, healthResponse) {
+		response := serve(handler, http.MethodGet, "/readyz", "")
+		var body healthResponse
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+		return response.Code, body
+	}
+
+	code, body := readiness()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthResponse{Status: "unavailable", Reason: "restoring the snapshot"}, body)
+
+	assert.NoError(t, handler.Restore(context.Background()), "Expected an empty directory to restore nothing")
+	code, body = readiness()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, healthResponse{Status: "ok"}, body)
+
+	assert.NoError(t, handler.Close())
+	code, body = readiness()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthResponse{Status: "unavailable", Reason: "shutting down"}, body)
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/healthz", "").Code, "Expected the backend to stay alive while draining")
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"caching/lru"
+)
+
+const (
+	patternUniform = "uniform"
+	patternZipf    = "zipf"
+	patternScan    = "scan"
+	patternTrace   = "trace"
+
+	maxSimulationRate     = 200              // Operations per second
+	maxSimulationDuration = 60 * time.Second // Longest simulation that can be requested
+	maxSimulationKeys     = 10000            // Largest key space that can be requested
+	maxSimulationTrace    = 100000           // Longest trace that can be replayed
+)
+
+// simulationConfig describes a synthetic workload run against the observable cache.
+type simulationConfig struct {
+	Pattern         string   `json:"pattern"`          // Key distribution: "uniform", "zipf", "scan" or "trace"
+	Keys            int      `json:"keys"`             // Number of distinct keys in the workload, ignored by the trace pattern
+	Trace           []string `json:"trace,omitempty"`  // Keys replayed in order, and from the start once exhausted, by the trace pattern
+	Rate            int      `json:"rate"`             // Operations per second
+	DurationSeconds float64  `json:"duration_seconds"` // How long the simulation runs
+	ReadRatio       *float64 `json:"read_ratio"`       // Fraction of operations that are reads, misses are then filled in; 0.8 if omitted, zero being write-only
+	TTLSeconds      float64  `json:"ttl_seconds"`      // Optional TTL of the written items, zero for no expiration
+}
+
+// validate checks the config, filling in defaults for the omitted fields.
+func (config *simulationConfig) validate() fieldErrors {
+	if config.Pattern == "" {
+		config.Pattern = patternZipf
+	}
+	if config.Keys == 0 {
+		config.Keys = 20
+	}
+	if config.Rate == 0 {
+		config.Rate = 10
+	}
+	if config.DurationSeconds == 0 {
+		config.DurationSeconds = 10
+	}
+	if config.ReadRatio == nil {
+		config.ReadRatio = new(float64)
+		*config.ReadRatio = 0.8
+	}
+
+	var errs fieldErrors
+	errs.check(config.Pattern == patternUniform || config.Pattern == patternZipf || config.Pattern == patternScan || config.Pattern == patternTrace,
+		"pattern", "pattern must be one of %q, %q, %q or %q", patternUniform, patternZipf, patternScan, patternTrace)
+	if config.Pattern == patternTrace {
+		errs.check(len(config.Trace) > 0 && len(config.Trace) <= maxSimulationTrace, "trace", "trace must hold between 1 and %d keys", maxSimulationTrace)
+		for i, key := range config.Trace {
+			errs.checkKey(fmt.Sprintf("trace[%d]", i), key)
+		}
+	}
+	errs.check(config.Keys >= 2 && config.Keys <= maxSimulationKeys, "keys", "keys must be between 2 and %d", maxSimulationKeys)
+	errs.check(config.Rate >= 1 && config.Rate <= maxSimulationRate, "rate", "rate must be between 1 and %d", maxSimulationRate)
+	errs.checkSeconds("duration_seconds", config.DurationSeconds, maxSimulationDuration)
+	errs.check(*config.ReadRatio >= 0 && *config.ReadRatio <= 1, "read_ratio", "read_ratio must be between 0 and 1")
+	errs.checkSeconds("ttl_seconds", config.TTLSeconds, maxTTL)
+	return errs
+}
+
+// keyGenerator returns a function producing the keys of the workload following its pattern.
+func (config simulationConfig) keyGenerator(random *rand.Rand) func() string {
+	switch config.Pattern {
+	case patternUniform:
+		return func() string {
+			return fmt.Sprintf("key%d", random.Intn(config.Keys))
+		}
+	case patternTrace:
+		next := 0
+		return func() string {
+			key := config.Trace[next]
+			next = (next + 1) % len(config.Trace)
+			return key
+		}
+	case patternScan:
+		next := 0
+		return func() string {
+			key := fmt.Sprintf("key%d", next)
+			next = (next + 1) % config.Keys
+			return key
+		}
+	default:
+		zipf := rand.NewZipf(random, 1.1, 1, uint64(config.Keys-1))
+		return func() string {
+			return fmt.Sprintf("key%d", zipf.Uint64())
+		}
+	}
+}
+
+// simulator runs at most one workload at a time against the observable cache,
+// and against the compared policies when the comparison is enabled.
+type simulator struct {
+	cache      *lru.ObservableCache
+	comparison *policyComparison  // Replays the workload with the other policies, nil without comparison
+	mutex      sync.Mutex         // Protects running, cancel and closed
+	running    bool               // Whether a simulation is in progress
+	cancel     context.CancelFunc // Stops the running simulation
+	closed     bool               // Whether Close was called, rejecting new simulations
+	wg         sync.WaitGroup     // Tracks the simulation goroutine
+}
+
+func newSimulator(cache *lru.ObservableCache, comparison *policyComparison) *simulator {
+	return &simulator{cache: cache, comparison: comparison}
+}
+
+// Start runs the simulation in the background.
+// It returns false if another simulation is already running.
+func (sim *simulator) Start(config simulationConfig) bool {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	if sim.running || sim.closed {
+		return false
+	}
+	sim.running = true
+
+	duration := time.Duration(config.DurationSeconds * float64(time.Second))
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	sim.cancel = cancel
+
+	sim.wg.Add(1)
+	go func() {
+		defer sim.wg.Done()
+		defer cancel()
+		sim.run(ctx, config)
+
+		sim.mutex.Lock()
+		sim.running = false
+		sim.mutex.Unlock()
+	}()
+	return true
+}
+
+// Close stops the running simulation, if any, and waits for it to return.
+func (sim *simulator) Close() error {
+	sim.mutex.Lock()
+	sim.closed = true
+	if sim.cancel != nil {
+		sim.cancel()
+	}
+	sim.mutex.Unlock()
+
+	sim.wg.Wait()
+	return nil
+}
+
+// run issues the workload operations at the configured rate until the context is done.
+// Reads that miss are filled in, mimicking a cache-aside application.
+func (sim *simulator) run(ctx context.Context, config simulationConfig) {
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	nextKey := config.keyGenerator(random)
+	ttl := time.Duration(config.TTLSeconds * float64(time.Second))
+
+	ticker := time.NewTicker(time.Second / time.Duration(config.Rate))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			key := nextKey()
+			read := random.Float64() < *config.ReadRatio
+			value := fmt.Sprintf("value-%d", random.Intn(1000))
+			sim.comparison.Access(key, value, ttl, read)
+			if read {
+				if _, found := sim.cache.Cache.Get(key); found {
+					continue
+				}
+			}
+
+			if ttl > 0 {
+				sim.cache.Cache.SetWithTTL(key, value, ttl)
+			} else {
+				sim.cache.Cache.Set(key, value)
+			}
+		}
+	}
+}
+
+func simulateHandler(sim *simulator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var config simulationConfig
+		if !decodeJSON(w, r, &config) {
+			return
+		}
+		if config.validate().write(w, r) {
+			return
+		}
+		if !sim.Start(config) {
+			writeError(w, r, http.StatusConflict, errorResponse{Code: codeConflict, Message: "a simulation is already running"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(config)
+	}
+}
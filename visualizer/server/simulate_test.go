@@ -0,0 +1,28 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"caching/lru"
+)
+
+func TestSimulationReadRatio(t *testing.T) {
+	var omitted simulationConfig
+	assert.Empty(t, omitted.validate())
+	assert.Equal(t, 0.8, *omitted.ReadRatio)
+
+	handler := NewHandler(lru.NewObservableCache(10, lru.WithoutMetrics()), WithSimulations())
+	defer handler.Close()
+	response := serve(handler, http.MethodPost, "/simulate", `{"read_ratio":0,"duration_seconds":1}`)
+	assert.Equal(t, http.StatusAccepted, response.Code)
+	var started simulationConfig
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&started))
+	assert.Equal(t, 0.0, *started.ReadRatio, "Expected a write-only workload to be kept")
+
+	response = serve(handler, http.MethodPost, "/simulate", `{"read_ratio":1.5}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+}
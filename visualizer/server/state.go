@@ -0,0 +1,42 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"caching/lru"
+)
+
+// stateResponse is the response of stateHandler.
+type stateResponse struct {
+	lru.StateChanges
+	State *lru.ObservableCacheState `json:"state,omitempty"` // The full state, when the changes are unavailable
+}
+
+// stateHandler returns the changes of the cache since the version of the since parameter, keeping the polling
+// payloads small and letting the frontend animate the transitions. Without since, or when the changes
+// since that version were already discarded, it returns the full state to resynchronize from.
+func stateHandler(cache *lru.ObservableCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var response stateResponse
+
+		ok := false
+		if since := r.URL.Query().Get("since"); since != "" {
+			version, err := strconv.ParseUint(since, 10, 64)
+			if err != nil {
+				invalidParameter(w, r, "since", "since must be a state version")
+				return
+			}
+			response.StateChanges, ok = cache.Changes(version)
+		}
+		if !ok {
+			state := cache.State()
+			response.StateChanges = lru.StateChanges{Version: state.Version}
+			response.State = &state
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+)
+
+const (
+	maxBodyBytes    = 64 << 10           // Largest request body accepted
+	maxKeyLength    = 256                // Longest key accepted, in bytes
+	maxValueLength  = 8 << 10            // Longest value accepted, in bytes
+	maxTTL          = 24 * time.Hour     // Longest TTL of the items written through the API
+	maxClockAdvance = 7 * 24 * time.Hour // Longest jump of the demo clock
+)
+
+// fieldError is an invalid field of a request, reported in the details of a validation error.
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// fieldErrors collects the invalid fields of a request, so they are all reported at once.
+type fieldErrors []fieldError
+
+// check adds an error for the field unless ok.
+func (errs *fieldErrors) check(ok bool, field, format string, args ...any) {
+	if !ok {
+		*errs = append(*errs, fieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// checkKey adds an error if the key is empty or too long.
+func (errs *fieldErrors) checkKey(field, key string) {
+	errs.check(key != "", field, "%s must not be empty", field)
+	errs.check(len(key) <= maxKeyLength, field, "%s must not exceed %d bytes", field, maxKeyLength)
+}
+
+// checkSeconds adds an error unless the duration in seconds is between zero and limit.
+func (errs *fieldErrors) checkSeconds(field string, seconds float64, limit time.Duration) {
+	errs.check(seconds >= 0 && seconds <= limit.Seconds(), field, "%s must be between 0 and %v", field, limit.Seconds())
+}
+
+// write writes the validation error if any field is invalid, returning whether it did.
+func (errs fieldErrors) write(w http.ResponseWriter, r *http.Request) bool {
+	if len(errs) == 0 {
+		return false
+	}
+	message := errs[0].Message
+	if len(errs) > 1 {
+		message = fmt.Sprintf("%s, and %d more invalid fields", message, len(errs)-1)
+	}
+	writeError(w, r, http.StatusUnprocessableEntity, errorResponse{Code: codeValidation, Message: message, Details: errs})
+	return true
+}
+
+// decodeJSON decodes the JSON body of the request into payload, rejecting the bodies that are not JSON,
+// larger than maxBodyBytes, or holding unknown fields. It writes the error and returns false if the body is rejected.
+func decodeJSON(w http.ResponseWriter, r *http.Request, payload any) bool {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, r, http.StatusUnsupportedMediaType, errorResponse{Code: codeUnsupportedMediaType, Message: "the body must be application/json"})
+		return false
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(payload); err != nil {
+		if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, errorResponse{Code: codePayloadTooLarge, Message: fmt.Sprintf("the body must not exceed %d bytes", maxBodyBytes)})
+			return false
+		}
+		invalidPayload(w, r, err)
+		return false
+	}
+	return true
+}